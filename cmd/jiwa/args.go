@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/commands"
+	flag "github.com/spf13/pflag"
+)
+
+// subcommands are all the names main knows, including aliases
+var subcommands = []string{
+	"activity", "apply", "archive", "backlog", "cat", "close", "comment", "commits", "completion", "component", "config", "create", "cycletime", "dashboard",
+	"diff", "edit", "estimate", "export", "filter", "flag", "grep", "history", "hooks", "import", "issue-type", "label", "link",
+	"links", "list", "ls", "migrate", "mine", "move", "move-project", "mv", "parent", "queue", "reassign", "recent", "restore", "search", "serve", "show",
+	"snippets", "sprint", "sync", "tail", "triage", "unflag", "whoami",
+}
+
+// splitArgs separates the global flags in front of the subcommand from the
+// subcommand and everything after it, which belongs to the subcommand's
+// own FlagSet. Flags that take a value consume the following argument
+// unless the value was attached with "=". Long names work with a single
+// dash too, like -profile work.
+func splitArgs(global *flag.FlagSet, args []string) ([]string, string, []string) {
+	globalArgs := make([]string, 0)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return globalArgs, args[i+1], args[i+2:]
+			}
+			return globalArgs, "", nil
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return globalArgs, arg, args[i+1:]
+		}
+
+		arg = longFlag(global, arg)
+		globalArgs = append(globalArgs, arg)
+		if takesValue(global, arg) && i+1 < len(args) {
+			globalArgs = append(globalArgs, args[i+1])
+			i++
+		}
+	}
+
+	return globalArgs, "", nil
+}
+
+// longFlag turns -name into --name for the flags of fs, pflag would read
+// it as a group of shorthands
+func longFlag(fs *flag.FlagSet, arg string) string {
+	if strings.HasPrefix(arg, "--") || len(arg) <= 2 {
+		return arg
+	}
+
+	name, _, _ := strings.Cut(arg[1:], "=")
+	if fs.Lookup(name) == nil {
+		return arg
+	}
+
+	return "-" + arg
+}
+
+// takesValue reports whether the flag needs the next argument as its value
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	var f *flag.Flag
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		name := strings.TrimPrefix(arg, "--")
+		if strings.Contains(name, "=") {
+			return false
+		}
+		f = fs.Lookup(name)
+	case len(arg) == 2:
+		f = fs.ShorthandLookup(arg[1:])
+	default:
+		// -pVALUE or a group of boolean shorthands
+		return false
+	}
+
+	return f != nil && f.NoOptDefVal == ""
+}
+
+// checkSubcommand fails for names that aren't subcommands, suggesting the
+// closest one for typos like "lss"
+func checkSubcommand(name string) error {
+	if slices.Contains(subcommands, name) || name == completeCommand {
+		return nil
+	}
+
+	msg := fmt.Sprintf("unknown command %q", name)
+	if s := commands.Suggest(name, subcommands); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+
+	return errors.New(msg)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	global := flag.NewFlagSet("jiwa", flag.ContinueOnError)
+	global.String("base-url", "", "")
+	global.String("project", "", "")
+	global.StringP("user", "u", "", "")
+	global.BoolP("verbose", "v", false, "")
+	global.Duration("timeout", 0, "")
+	global.String("profile", "", "")
+	global.Bool("dry-run", false, "")
+	global.Bool("yes", false, "")
+
+	testData := []struct {
+		Name          string
+		InArgs        []string
+		OutGlobal     []string
+		OutSubcommand string
+		OutRest       []string
+	}{
+		{
+			Name:          "NoGlobalFlags",
+			InArgs:        []string{"list", "--project", "JIWA"},
+			OutGlobal:     []string{},
+			OutSubcommand: "list",
+			OutRest:       []string{"--project", "JIWA"},
+		},
+		{
+			Name:          "SeparateValue",
+			InArgs:        []string{"--project", "OTHER", "list", "-s", "done"},
+			OutGlobal:     []string{"--project", "OTHER"},
+			OutSubcommand: "list",
+			OutRest:       []string{"-s", "done"},
+		},
+		{
+			Name:          "AttachedValue",
+			InArgs:        []string{"--base-url=https://other.atlassian.net", "cat", "JIWA-1"},
+			OutGlobal:     []string{"--base-url=https://other.atlassian.net"},
+			OutSubcommand: "cat",
+			OutRest:       []string{"JIWA-1"},
+		},
+		{
+			Name:          "ShorthandWithValue",
+			InArgs:        []string{"-u", "someone", "reassign", "JIWA-1", "me"},
+			OutGlobal:     []string{"-u", "someone"},
+			OutSubcommand: "reassign",
+			OutRest:       []string{"JIWA-1", "me"},
+		},
+		{
+			Name:          "BoolFlagDoesNotConsumeSubcommand",
+			InArgs:        []string{"-v", "list"},
+			OutGlobal:     []string{"-v"},
+			OutSubcommand: "list",
+			OutRest:       []string{},
+		},
+		{
+			Name:          "SeveralGlobalFlags",
+			InArgs:        []string{"--timeout", "30s", "-v", "--project=OTHER", "ls", "--status", "done"},
+			OutGlobal:     []string{"--timeout", "30s", "-v", "--project=OTHER"},
+			OutSubcommand: "ls",
+			OutRest:       []string{"--status", "done"},
+		},
+		{
+			Name:          "Profile",
+			InArgs:        []string{"--profile", "work", "ls"},
+			OutGlobal:     []string{"--profile", "work"},
+			OutSubcommand: "ls",
+			OutRest:       []string{},
+		},
+		{
+			Name:          "SingleDashProfile",
+			InArgs:        []string{"-profile", "work", "ls"},
+			OutGlobal:     []string{"--profile", "work"},
+			OutSubcommand: "ls",
+			OutRest:       []string{},
+		},
+		{
+			Name:          "SingleDashAttachedValue",
+			InArgs:        []string{"-profile=work", "-timeout", "30s", "ls"},
+			OutGlobal:     []string{"--profile=work", "--timeout", "30s"},
+			OutSubcommand: "ls",
+			OutRest:       []string{},
+		},
+		{
+			Name:          "DryRun",
+			InArgs:        []string{"--dry-run", "create", "--type", "Bug"},
+			OutGlobal:     []string{"--dry-run"},
+			OutSubcommand: "create",
+			OutRest:       []string{"--type", "Bug"},
+		},
+		{
+			Name:          "SingleDashDryRun",
+			InArgs:        []string{"-dry-run", "import", "issues.csv"},
+			OutGlobal:     []string{"--dry-run"},
+			OutSubcommand: "import",
+			OutRest:       []string{"issues.csv"},
+		},
+		{
+			Name:          "Yes",
+			InArgs:        []string{"--yes", "reassign", "--jql", "project = JIWA", "alice"},
+			OutGlobal:     []string{"--yes"},
+			OutSubcommand: "reassign",
+			OutRest:       []string{"--jql", "project = JIWA", "alice"},
+		},
+		{
+			Name:          "SingleDashYesAndVerbose",
+			InArgs:        []string{"-yes", "-v", "edit", "JIWA-1"},
+			OutGlobal:     []string{"--yes", "-v"},
+			OutSubcommand: "edit",
+			OutRest:       []string{"JIWA-1"},
+		},
+		{
+			Name:          "StdinDashIsNotAFlag",
+			InArgs:        []string{"-", "list"},
+			OutGlobal:     []string{},
+			OutSubcommand: "-",
+			OutRest:       []string{"list"},
+		},
+		{
+			Name:          "DoubleDashEndsGlobalFlags",
+			InArgs:        []string{"--project", "JIWA", "--", "search", "project = JIWA"},
+			OutGlobal:     []string{"--project", "JIWA"},
+			OutSubcommand: "search",
+			OutRest:       []string{"project = JIWA"},
+		},
+		{
+			Name:          "OnlyGlobalFlags",
+			InArgs:        []string{"--project", "JIWA"},
+			OutGlobal:     []string{"--project", "JIWA"},
+			OutSubcommand: "",
+			OutRest:       nil,
+		},
+		{
+			Name:          "Empty",
+			InArgs:        []string{},
+			OutGlobal:     []string{},
+			OutSubcommand: "",
+			OutRest:       nil,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			globalArgs, subcommand, rest := splitArgs(global, td.InArgs)
+
+			assert.Equal(t, td.OutGlobal, globalArgs)
+			assert.Equal(t, td.OutSubcommand, subcommand)
+			assert.Equal(t, td.OutRest, rest)
+		})
+	}
+}
+
+func TestCheckSubcommand(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		OutErrMsg string
+	}{
+		{Name: "Known", In: "list"},
+		{Name: "Alias", In: "mv"},
+		{Name: "Typo", In: "lss", OutErrMsg: `unknown command "lss", did you mean "ls"?`},
+		{Name: "TypoInLongerName", In: "cycltime", OutErrMsg: `unknown command "cycltime", did you mean "cycletime"?`},
+		{Name: "NothingClose", In: "deploy-everything", OutErrMsg: `unknown command "deploy-everything"`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			err := checkSubcommand(td.In)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/catouc/jiwa/internal/commands"
+	flag "github.com/spf13/pflag"
+)
+
+// completeCommand is the hidden subcommand the completion scripts run with
+// the words typed after jiwa, the last of them is the one being completed
+const completeCommand = "__complete"
+
+// completeTimeout bounds the requests of a completion, a slow instance
+// shouldn't hang the shell
+const completeTimeout = 2 * time.Second
+
+// bashCompletion asks jiwa for the candidates and falls back to completing
+// files when there are none
+const bashCompletion = `# bash completion for jiwa, load it with: source <(jiwa completion bash)
+_jiwa() {
+	local IFS=$'\n' out
+	out=$(jiwa __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) || return
+	COMPREPLY=($(compgen -W "$out" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _jiwa jiwa
+`
+
+// zshCompletion runs the bash completion through zsh's bashcompinit
+const zshCompletion = `# zsh completion for jiwa, load it with: source <(jiwa completion zsh)
+autoload -U +X bashcompinit && bashcompinit
+` + bashCompletion
+
+// runCompletion prints the completion script for the shell
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: jiwa completion {bash|zsh}")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	default:
+		fmt.Printf("there is no completion for %q, only for bash and zsh\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// completeSubcommands returns the subcommands starting with the prefix
+func completeSubcommands(prefix string) []string {
+	matches := make([]string, 0)
+	for _, s := range subcommands {
+		if strings.HasPrefix(s, prefix) {
+			matches = append(matches, s)
+		}
+	}
+
+	return matches
+}
+
+// completeArgs returns the candidates for the last of the arguments of the
+// subcommand. Only the labels of label are completed, from the labels in
+// use on the instance, everything else is left to the shell.
+func completeArgs(cmd commands.Command, subcommand string, args []string) ([]string, error) {
+	if subcommand != "label" || len(args) == 0 {
+		return nil, nil
+	}
+
+	word := args[len(args)-1]
+	if strings.HasPrefix(word, "-") || positionals(label, args[:len(args)-1]) == 0 && !hasFlag(label, args, "jql") {
+		return nil, nil
+	}
+
+	return cmd.CompleteLabels(word)
+}
+
+// positionals counts the arguments that aren't flags or their values
+func positionals(fs *flag.FlagSet, args []string) int {
+	n := 0
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return n + len(args) - i - 1
+		case strings.HasPrefix(args[i], "-") && args[i] != "-":
+			if takesValue(fs, args[i]) {
+				i++
+			}
+		default:
+			n++
+		}
+	}
+
+	return n
+}
+
+// hasFlag reports whether the flag was passed by its name or shorthand
+func hasFlag(fs *flag.FlagSet, args []string, name string) bool {
+	f := fs.Lookup(name)
+	for _, arg := range args {
+		arg, _, _ = strings.Cut(arg, "=")
+		if arg == "--"+name || f.Shorthand != "" && strings.HasPrefix(arg, "-"+f.Shorthand) && !strings.HasPrefix(arg, "--") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,90 +1,442 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
+	"github.com/andygrunwald/go-jira"
 	"github.com/catouc/jiwa/internal/commands"
 	"github.com/catouc/jiwa/internal/editor"
-	"github.com/catouc/jiwa/internal/jiwa"
+	"github.com/catouc/jiwa/internal/forge"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/internal/output"
+	"github.com/catouc/jiwa/internal/progress"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/internal/serve"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa"
 	flag "github.com/spf13/pflag"
 )
 
 var (
+	global = flag.NewFlagSet("jiwa", flag.ContinueOnError)
+
+	globalBaseURL = global.String("base-url", "", "Override the configured \"baseURL\"")
+	globalUser    = global.String("user", "", "Override the configured \"username\" and JIWA_USERNAME")
+	globalProject = global.String("project", "", "Override the configured \"defaultProject\"")
+	globalConfig  = global.String("config", "", "Read the configuration from this file instead, also settable through JIWA_CONFIG")
+	globalProfile = global.String("profile", "", "Read <profile>.json next to config.json and keep @last and the queued changes apart from other profiles, also settable through JIWA_PROFILE")
+	globalDryRun  = global.Bool("dry-run", false, "Print what apply, create, import, migrate and move-project would change without changing anything, other commands refuse it")
+	globalYes     = global.Bool("yes", false, "Answer the questions before changes with yes, like --yes of create and edit and --force of the bulk changes")
+	globalTimeout = global.Duration("timeout", 0, "Override the configured \"commandTimeout\", giving up on the whole command after this long, e.g. 2m")
+	globalReqTime = global.Duration("request-timeout", 0, "Override the configured \"timeout\" of each request to Jira, e.g. 30s")
+	globalOffline = global.Bool("offline", false, "Queue changes instead of sending them to Jira, \"jiwa sync\" sends them later")
+	globalOutput  = global.StringP("output", "o", "", "Set the output of every command that has one, e.g. json, unless the command's own --output is passed, commands fail on outputs they don't have")
+	globalVerbose = global.BoolP("verbose", "v", false, "Print every request to Jira on stderr and how many there were once the command is done")
+	globalLogFmt  = global.String("log-format", "text", "Write the logs on stderr as \"text\" or \"json\", one object per line with the command, issue key and duration")
+	globalQuiet   = global.BoolP("quiet", "q", false, "Don't show a spinner on stderr while waiting for Jira")
+	globalHTTP    = global.Bool("insecure-allow-http", false, "Send the credentials to a plain http \"baseURL\" that isn't localhost, they can be read by anyone on the way")
+	globalUTC     = global.Bool("utc", false, "Show timestamps in UTC instead of the configured \"timezone\"")
+	globalNoPerm  = global.Bool("skip-permission-check", false, "Don't check your permissions before changing issues, for instances that restrict the endpoint")
+	globalURL     = global.Bool("url", false, "Print the links of issues instead of their keys, every command reads both from stdin")
+)
+
+var (
+	activity  = flag.NewFlagSet("activity", flag.ContinueOnError)
+	apply     = flag.NewFlagSet("apply", flag.ContinueOnError)
+	archive   = flag.NewFlagSet("archive", flag.ContinueOnError)
+	backlog   = flag.NewFlagSet("backlog", flag.ContinueOnError)
 	cat       = flag.NewFlagSet("cat", flag.ContinueOnError)
+	closeCmd  = flag.NewFlagSet("close", flag.ContinueOnError)
 	comment   = flag.NewFlagSet("comment", flag.ContinueOnError)
+	commits   = flag.NewFlagSet("commits", flag.ContinueOnError)
+	component = flag.NewFlagSet("component", flag.ContinueOnError)
+	configCmd = flag.NewFlagSet("config", flag.ContinueOnError)
 	create    = flag.NewFlagSet("create", flag.ContinueOnError)
+	cycletime = flag.NewFlagSet("cycletime", flag.ContinueOnError)
+	dashboard = flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	diff      = flag.NewFlagSet("diff", flag.ContinueOnError)
 	edit      = flag.NewFlagSet("edit", flag.ContinueOnError)
+	estimate  = flag.NewFlagSet("estimate", flag.ContinueOnError)
+	export    = flag.NewFlagSet("export", flag.ContinueOnError)
+	flagCmd   = flag.NewFlagSet("flag", flag.ContinueOnError)
+	grep      = flag.NewFlagSet("grep", flag.ContinueOnError)
+	history   = flag.NewFlagSet("history", flag.ContinueOnError)
+	hooksCmd  = flag.NewFlagSet("hooks", flag.ContinueOnError)
+	importCmd = flag.NewFlagSet("import", flag.ContinueOnError)
 	issueType = flag.NewFlagSet("issue-type", flag.ContinueOnError)
 	label     = flag.NewFlagSet("label", flag.ContinueOnError)
+	link      = flag.NewFlagSet("link", flag.ContinueOnError)
+	links     = flag.NewFlagSet("links", flag.ContinueOnError)
 	list      = flag.NewFlagSet("list", flag.ContinueOnError)
+	migrate   = flag.NewFlagSet("migrate", flag.ContinueOnError)
+	mine      = flag.NewFlagSet("mine", flag.ContinueOnError)
 	move      = flag.NewFlagSet("move", flag.ContinueOnError)
+	moveProj  = flag.NewFlagSet("move-project", flag.ContinueOnError)
+	parent    = flag.NewFlagSet("parent", flag.ContinueOnError)
+	queue     = flag.NewFlagSet("queue", flag.ContinueOnError)
 	reassign  = flag.NewFlagSet("reassign", flag.ContinueOnError)
+	recent    = flag.NewFlagSet("recent", flag.ContinueOnError)
+	restore   = flag.NewFlagSet("restore", flag.ContinueOnError)
 	search    = flag.NewFlagSet("search", flag.ContinueOnError)
+	serveCmd  = flag.NewFlagSet("serve", flag.ContinueOnError)
+	snippets  = flag.NewFlagSet("snippets", flag.ContinueOnError)
+	sprint    = flag.NewFlagSet("sprint", flag.ContinueOnError)
+	syncCmd   = flag.NewFlagSet("sync", flag.ContinueOnError)
+	tail      = flag.NewFlagSet("tail", flag.ContinueOnError)
+	triage    = flag.NewFlagSet("triage", flag.ContinueOnError)
+	unflag    = flag.NewFlagSet("unflag", flag.ContinueOnError)
+	whoami    = flag.NewFlagSet("whoami", flag.ContinueOnError)
+
+	activityProject = activity.StringP("project", "p", "", "Show the activity in this project, defaults to your configured \"defaultProject\"")
+	activityIssue   = activity.StringP("issue", "i", "", "Show the history and comments of this issue instead of a project")
+	activitySince   = activity.StringP("since", "s", "", "Only show what happened within this long or since this day, e.g. 90m, 12h, 2d, yesterday or 2024-07-01, defaults to 24h for a project")
+	activityAuthor  = activity.StringP("author", "a", "", "Only show what this user did, matched against the user name, display name and e-mail")
+	activityOut     = activity.StringP("output", "o", "text", "Set the output to be either \"text\" or \"json\"")
+
+	applyUpdateFile = apply.Bool("update-file", false, "Write the key of every created issue into the manifest, so applying it again updates them instead")
+	applyDryRun     = apply.BoolP("dry-run", "n", false, "Check the manifest and print what would be created or updated without changing anything")
+
+	archiveForce = archive.BoolP("force", "f", false, "Don't ask before archiving more than 10 issues")
 
 	catComments = cat.BoolP("comments", "c", false, "Toggle to include comments in the printout or not")
+	catFields   = cat.StringSliceP("fields", "f", nil, "Comma separated fields to show in this order by ID or name, defaults to your configured \"viewFields\" or summary,description,parent")
+	catShort    = cat.Bool("short", false, "Print a single line with the key, status, assignee and summary")
+	catFull     = cat.Bool("full", false, "Also print the comments, links, sub-tasks, attachments and the latest 5 changes")
+
+	closeFields     = closeCmd.StringArrayP("field", "F", nil, "Set a field during the transition as <field>=<value>, can be passed multiple times")
+	closeResolution = closeCmd.StringP("resolution", "r", "", "Set the resolution during the transition")
+	closeComment    = closeCmd.StringP("comment", "m", "", "Add a comment with the transition, \"-\" reads it from stdin")
+
+	commentNoMentions = comment.Bool("no-mentions", false, "Keep @name as it is instead of turning it into a mention")
+	commentSnippet    = comment.StringP("snippet", "s", "", "Comment a snippet from the config or the snippets dir, \"jiwa snippets\" lists them")
+	commentMessage    = comment.StringP("message", "m", "", "Set the comment instead of opening $EDITOR, with --snippet it is added after the snippet")
+	commentVisibleTo  = comment.String("visible-to", "", "Restrict the comment to a project role or a group, e.g. role:Developers or group:jira-admins")
+
+	commitsRange       = commits.StringP("range", "r", "", "Record the commits of a revision range like origin/main..HEAD instead of the ones mentioning the issue")
+	commitsDescription = commits.Bool("description", false, "Append the list to the description instead of adding it as a comment")
 
 	createProject = create.StringP("project", "p", "", `Set the project to create the ticket in, if not set it will default to your
 configured "defaultProject"`)
 	createFile       = create.StringP("file", "f", "", "Point to a file that contains your ticket")
-	createTicketType = create.StringP("ticket-type", "t", "Task", "Sets the type of ticket to open, defaults to \"Task\"")
-	createComponent  = create.StringP("component", "c", "", "Set the component of your ticket")
-
-	listUser    = list.StringP("user", "u", "", "Set the user name to use in the list call, use \"empty\" to list unassigned tickets")
-	listStatus  = list.StringP("status", "s", "to do", "Set the status of the tickets you want to see")
-	listProject = list.StringP("project", "p", "", "Set the project to search in")
-	listOut     = list.StringP("output", "o", "raw", "Set the output to be either \"raw\" for piping or \"table\" for nice formatting")
-	listLabels  = list.StringArrayP("label", "l", nil, "Search for specific labels, all labels are joined by an OR")
+	createTicketType = create.StringP("ticket-type", "t", "Task", "Sets the type of ticket to open, defaults to \"Task\", --type does the same")
+	createComponents = create.StringArrayP("component", "c", nil, "Set a component of your ticket by name, can be passed multiple times")
+	createLabels     = create.StringArrayP("label", "l", nil, "Set a label of your ticket, can be passed multiple times")
+	createIn         = create.StringP("in", "i", "", `Create an issue for every block of the file, blocks are separated by "=== issue ===" lines, "-" reads stdin`)
+	createDryRun     = create.BoolP("dry-run", "n", false, "Print what would be created without creating it, hooks are skipped")
+	createParent     = create.String("parent", "", "Set the parent issue, required for sub-tasks")
+	createLinks      = create.StringArray("link", nil, `Link the new issue to an existing one, e.g. "blocks:PROJ-2", can be passed multiple times`)
+	createNoDupCheck = create.Bool("no-dup-check", false, "Skip searching for open issues with a similar summary before creating")
+	createYes        = create.BoolP("yes", "y", false, "Create the issue even if there are possible duplicates, they are still printed to stderr, --force does the same")
+	createEpicName   = create.String("epic-name", "", "Set the Epic Name of an epic on Jira Server, defaults to the summary")
+	createNoMentions = create.Bool("no-mentions", false, "Keep @name as it is instead of turning it into a mention")
+	createCheckDupes = create.Bool("check-dupes", false, "Search for possible duplicates even if disabled in the config, without a terminal to ask on finding any aborts unless --yes is passed")
+	createTemplate   = create.String("from-template", "", "Start from a template in \"templates\" in the config, it sets the type, labels and components the flags don't and pre-fills the editor")
+	createDue        = create.String("due", "", "Set the due date, e.g. tomorrow, next friday, 2024-07-01 or +2w")
+	createPrompt     = create.Bool("interactive", false, "Ask for the required fields of the project's create screen that the other flags don't set, select fields list their choices")
+	createSecurity   = create.String("security", "", "Set the security level by name, restricting who can see the issue")
+	createReporter   = create.String("reporter", "", "File the issue on behalf of this user, looked up like an @mention, needs the Modify Reporter permission unless it's \"me\"")
+	createMine       = create.Bool("mine", false, "Assign the issue to yourself as it is created, for work you are about to start")
+	createAutoSplit  = create.Bool("auto-split", false, "Cut a summary that is too long for Jira at a word boundary and move the rest to the top of the description instead of refusing it")
+
+	cycletimeOut = cycletime.StringP("output", "o", "table", "Set the output to be either \"table\", \"csv\" with hours for spreadsheets or \"json\"")
+
+	dashboardProject = dashboard.StringP("project", "p", "", "Show the dashboard of this project, defaults to your configured \"defaultProject\"")
+	dashboardOut     = dashboard.StringP("output", "o", "text", "Set the output to be either \"text\" or \"json\" with every issue of every section")
+
+	diffAll = diff.BoolP("all", "a", false, "Show the fields that are the same as well, the ones that differ are marked with a \"*\"")
+	diffOut = diff.StringP("output", "o", "text", "Set the output to be either \"text\" or \"json\" with every value")
+
+	editNoMentions = edit.Bool("no-mentions", false, "Keep @name as it is instead of turning it into a mention")
+	editAppend     = edit.StringP("append", "a", "", "Append the text to the description instead of opening an editor, \"-\" reads it from stdin")
+	editBulk       = edit.Bool("bulk", false, "Edit the summaries and descriptions of all the issues in one editor buffer, each under a \"=== KEY ===\" line as \"# KEY\" and \"---\" are markdown")
+	editReopen     = edit.Bool("reopen-if-closed", false, "If a closed issue can't be edited, reopen it, edit it and close it again")
+	editYes        = edit.BoolP("yes", "y", false, "Send the changes without asking, the diff is still printed to stderr")
+
+	estimateTime = estimate.BoolP("time", "t", false, "Set the original estimate of the time tracking, e.g. \"2d\" or \"4h\", instead of the story points")
+
+	exportProject = export.StringP("project", "p", "", "Set the project to export, defaults to your configured \"defaultProject\"")
+	exportStatus  = export.StringP("status", "s", "all", "Only export issues in this status, \"all\" exports every status")
+	exportJQL     = export.String("jql", "", "Export the issues matching this query instead of a project")
+	exportFields  = export.StringSliceP("fields", "f", nil, "Comma separated fields to export, defaults to everything Jira returns in searches")
+	exportOut     = export.StringP("output", "o", "-", "Write the issues to this file, one JSON object per line, \"-\" writes to stdout")
+
+	flagMessage = flagCmd.StringP("message", "m", "", "Comment why the issue is an impediment, in the same request that flags it")
+
+	grepProject  = grep.StringP("project", "p", "", "Set the project to search in, defaults to your configured \"defaultProject\"")
+	grepAll      = grep.BoolP("all", "a", false, "Search in all projects")
+	grepComments = grep.BoolP("comments", "c", false, "Also search and show matches in comments")
+
+	importFormat      = importCmd.String("format", "", "Set the format of the input to \"csv\" or \"ndjson\", defaults to csv for .csv files and ndjson otherwise")
+	importProject     = importCmd.StringP("project", "p", "", "Set the project for rows without one, defaults to your configured \"defaultProject\"")
+	importType        = importCmd.StringP("type", "t", "", "Set the issue type for rows without one")
+	importConcurrency = importCmd.Int("concurrency", 0, "Create this many issues at once, defaults to your configured \"concurrency\" or 4")
+	importDryRun      = importCmd.BoolP("dry-run", "n", false, "Check every row and print what would be created without creating anything, hooks are skipped")
+	importResults     = importCmd.String("results", "", "Write which line became which issue to this CSV file, defaults to <file>.results.csv")
+	importClose       = importCmd.Bool("close", false, "Close the issues that are closed on GitHub or GitLab after creating them, otherwise they stay open")
+	importMapping     = importCmd.String("mapping", "", "Keep which GitHub or GitLab issue became which Jira issue in this file, defaults to e.g. github-owner-repo.mapping.json")
+	importAPIURL      = importCmd.String("api-url", "", "Read from this GitHub or GitLab API instead of github.com or gitlab.com, e.g. https://gitlab.example.com/api/v4")
+
+	labelRemove  = label.BoolP("remove", "r", false, "Remove the labels instead of adding them")
+	labelProject = label.StringP("project", "p", "", "Set the project for issues given by their number only, defaults to your configured \"defaultProject\"")
+	labelJQL     = label.StringP("jql", "q", "", "Label every issue matching this query instead of the given ones")
+	labelForce   = label.BoolP("force", "f", false, "Don't ask before labelling the issues --jql matches")
+
+	linkRemove = link.BoolP("remove", "r", false, "Remove links instead, either by the IDs \"jiwa links\" lists or like they were added")
+
+	linksOut = links.StringP("output", "o", "text", "Set the output to be either \"text\" or \"json\"")
+
+	listUser        = list.StringP("user", "u", "", "Set the user name to use in the list call, use \"empty\" to list unassigned tickets and \"@me\" or \"me\" for your own")
+	listStatus      = list.StringP("status", "s", "to do", "Set the status of the tickets you want to see")
+	listProject     = list.StringP("project", "p", "", "Set the projects to search in, comma separated or @group from \"projectGroups\"")
+	listOut         = list.StringP("output", "o", "raw", "Set the output to be either \"raw\" for piping, \"table\" for nice formatting, \"json\", \"ndjson\" with one issue per line, \"csv\" or \"template=<text/template>\"")
+	listLabels      = list.StringArrayP("label", "l", nil, "Search for specific labels, all labels are joined by an OR")
+	listAll         = list.BoolP("all-projects", "a", false, "List issues from all projects, cannot be combined with --project")
+	listJQL         = list.StringP("jql", "q", "", "Add a JQL condition to the query, e.g. \"priority = High\"")
+	listCount       = list.BoolP("count", "c", false, "Only print the number of matching issues")
+	listReporter    = list.String("reporter", "", "Only list issues reported by this user, \"@me\" or \"me\" for yourself")
+	listReportedMe  = list.Bool("reported-by-me", false, "Only list issues you reported, the same as --reporter @me")
+	listReporterCol = list.Bool("show-reporter", false, "Add a reporter column to the table output")
+	listUpdatedCol  = list.Bool("show-updated", false, "Add a column with how long ago the issues were updated to the table output, e.g. \"2h ago\"")
+	listCommentedBy = list.String("commented-by", "", "Only list issues commented on by this user, \"@me\" for yourself, needs ScriptRunner")
+	listUpdatedByMe = list.Bool("updated-by-me", false, "Only list issues you changed, on Server that means their status or assignee")
+	listLimit       = list.Int("limit", 0, "Fetch at most this many issues, 0 fetches all of them up to \"listCap\" from the config")
+	listNoDefault   = list.Bool("no-default-project", false, "Don't fall back to your configured \"defaultProject\", --project or --all-projects has to be passed")
+	listWatch       = list.BoolP("watch", "w", false, "Run the query again every --interval and redraw the list until Ctrl-C")
+	listInterval    = list.Duration("interval", 30*time.Second, "How often --watch refreshes, at least 5s")
+	listFilter      = list.StringP("filter", "f", "", "Run a saved filter from \"filters\" in the config, the other flags narrow it down")
+	listMineWatch   = list.Bool("mine-and-watching", false, "Only list issues assigned to you or that you watch")
+	listUpdated     = list.String("updated-since", "", "Only list issues updated on or after this day, e.g. yesterday, last monday, 2024-07-01 or -3d")
+	listCreated     = list.String("created-since", "", "Only list issues created on or after this day, e.g. yesterday, last monday, 2024-07-01 or -3d")
+	listDueBefore   = list.String("due-before", "", "Only list issues due before this day, e.g. tomorrow, next friday, 2024-07-01 or +2w")
+
+	mineFlat = mine.Bool("flat", false, "Print a single table with a status column instead of grouping by status")
+	mineOut  = mine.StringP("output", "o", "table", "Set the output to be either \"table\" grouped by status or any output list takes")
+
+	migrateProject    = migrate.StringP("project", "p", "", "The project to move the issue to")
+	migrateClose      = migrate.Bool("close-original", false, "Close the original once everything was copied")
+	migrateResolution = migrate.StringP("resolution", "r", "", "Set the resolution when closing the original, e.g. \"Duplicate\"")
+	migrateFields     = migrate.StringArrayP("field", "F", nil, "Set a field when closing the original as <field>=<value>, can be passed multiple times")
+
+	moveProjRecreate   = moveProj.Bool("recreate", false, "Copy the issue into the project, link both and close the original like migrate --close-original, for instances that can't move issues through the API")
+	moveProjResolution = moveProj.StringP("resolution", "r", "", "Set the resolution when closing the original with --recreate, e.g. \"Duplicate\"")
+	moveProjFields     = moveProj.StringArrayP("field", "F", nil, "Set a field when closing the original with --recreate as <field>=<value>, can be passed multiple times")
+
+	moveFields     = move.StringArrayP("field", "F", nil, "Set a field during the transition as <field>=<value>, can be passed multiple times")
+	moveResolution = move.StringP("resolution", "r", "", "Set the resolution during the transition")
+	moveComment    = move.StringP("comment", "m", "", "Add a comment with the transition, \"-\" reads it from stdin")
+	moveNext       = move.Bool("next", false, "Move one step forward along the workflow instead of to a status")
+	movePrev       = move.Bool("prev", false, "Move one step back along the workflow instead of to a status")
+	moveJQL        = move.StringP("jql", "q", "", "Move every issue matching this query instead of the given ones")
+	moveForce      = move.BoolP("force", "f", false, "Don't ask before moving the issues --jql matches")
+	movePath       = move.Bool("path", false, "Print the transitions that lead to the status one after another instead of moving the issue")
+
+	queueFlat = queue.Bool("flat", false, "Print a single table with a status column instead of grouping by status")
+	queueOut  = queue.StringP("output", "o", "table", "Set the output to be either \"table\" grouped by status or any output list takes")
+
+	reassignForce   = reassign.BoolP("force", "f", false, "Skip checking that the user can be assigned issues in the project, saves a request per project, and don't ask before reassigning the issues --jql matches")
+	reassignJQL     = reassign.StringP("jql", "q", "", "Reassign every issue matching this query instead of the given ones")
+	reassignProject = reassign.StringP("project", "p", "", "Set the project for issues given by their number only, defaults to your configured \"defaultProject\"")
+	reassignRobin   = reassign.Bool("round-robin", false, "Give the issues to the users of \"triagePool\" in turn instead of to one user, carrying on where the last call stopped")
+
+	recentCount = recent.IntP("number", "n", 10, "Set how many recent issues to show, 0 shows all of them")
+
+	restoreForce = restore.BoolP("force", "f", false, "Don't ask before restoring more than 10 issues")
+
+	searchCount = search.BoolP("count", "c", false, "Only print the number of matching issues")
+	searchOut   = search.StringP("output", "o", "raw", "Set the output to be either \"raw\" for piping, \"table\" for nice formatting, \"json\", \"ndjson\" with one issue per line, \"csv\" or \"template=<text/template>\"")
+
+	serveListen = serveCmd.StringP("listen", "l", "127.0.0.1:7373", "Listen on this address, it has to be on the loopback interface")
+	serveSocket = serveCmd.String("socket", "", "Listen on a unix socket at this path instead, only you can connect to it")
+
+	syncList  = syncCmd.BoolP("list", "l", false, "Only list the queued changes and why they were held back")
+	syncForce = syncCmd.Bool("force", false, "Send changes that were held back because their issue changed on Jira or the last sync was interrupted")
+	syncDrop  = syncCmd.IntSlice("drop", nil, "Remove the queued changes with these numbers without sending them")
+
+	tailInterval = tail.Duration("interval", 30*time.Second, "How often the issue is polled, at least 5s")
+	tailExec     = tail.String("exec", "", "Run this command through sh for every new event, with the event in JIWA_* variables and as JSON on stdin")
+
+	triageProject = triage.StringP("project", "p", "", "Set the project to triage, defaults to your configured \"defaultProject\"")
+	triageJQL     = triage.StringP("jql", "q", "", "Triage the issues matching this query instead of the unassigned to do ones")
+
+	unflagMessage = unflag.StringP("message", "m", "", "Comment why the issue isn't an impediment anymore, in the same request that unflags it")
+
+	whoamiRaw = whoami.Bool("raw", false, "Print everything Jira knows about your account as JSON")
 )
 
+// dryRunCommands are the ones the global --dry-run works with, completing
+// one is fine too
+var dryRunCommands = []string{completeCommand, "apply", "create", "import", "migrate", "move-project"}
+
+// exitLinkFailed signals that an issue was created but could not be
+// linked, so scripts can tell that apart from the create failing.
+const exitLinkFailed = 3
+
 var cfg commands.Config
 
-func init() {
-	homeDir, err := os.UserHomeDir()
+// defaultProfile is the profile without --profile or JIWA_PROFILE, it
+// reads config.json
+const defaultProfile = "default"
+
+var profileRegEx = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// profile picks the profile from the --profile flag, then JIWA_PROFILE.
+// Each one has its own configuration file, state and journal, e.g. for a
+// second Jira.
+func profile() (string, error) {
+	name := *globalProfile
+	if name == "" {
+		name = os.Getenv("JIWA_PROFILE")
+	}
+	if name == "" {
+		return defaultProfile, nil
+	}
+	if !profileRegEx.MatchString(name) {
+		return "", fmt.Errorf("profile %q can only hold letters, digits, \"-\" and \"_\"", name)
+	}
+
+	return name, nil
+}
+
+// configPath picks the configuration file from the --config flag, then
+// JIWA_CONFIG and falls back to config.json in the user's config dir, or
+// <profile>.json next to it for any other profile.
+func configPath() (string, error) {
+	if *globalConfig != "" {
+		return *globalConfig, nil
+	}
+
+	if p, set := os.LookupEnv("JIWA_CONFIG"); set && p != "" {
+		return p, nil
+	}
+
+	name, err := profile()
 	if err != nil {
-		fmt.Printf("cannot locate user home dir, is `$HOME` set? Detailed error: %s\n", err)
-		os.Exit(1)
+		return "", err
 	}
+	path, err := commands.DefaultConfigPath()
+	if err != nil || name == defaultProfile {
+		return path, err
+	}
+
+	return filepath.Join(filepath.Dir(path), name+".json"), nil
+}
 
-	cfgFileLoc := path.Join(homeDir, ".config", "jiwa", "config.json")
+// runConfig reads and writes single keys of the configuration file without
+// loading the rest of it
+func runConfig(args []string) {
+	configUsage := "Usage: jiwa config {get <key>|set <key> <value>}"
+	err := configCmd.Parse(args)
+	if err != nil {
+		fmt.Println(configUsage)
+		exit(1)
+	}
 
-	cfgBytes, err := os.ReadFile(cfgFileLoc)
+	path, err := configPath()
 	if err != nil {
-		fmt.Printf("cannot locate configuration file, was it created under %s? Detailed error: %s\n", cfgFileLoc, err)
-		os.Exit(1)
+		fatal(err)
+	}
+
+	switch {
+	case configCmd.Arg(0) == "get" && configCmd.NArg() == 2:
+		value, err := commands.ConfigGet(path, configCmd.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(value)
+	case configCmd.Arg(0) == "set" && configCmd.NArg() == 3:
+		key := configCmd.Arg(1)
+		err := commands.ConfigSet(path, key, configCmd.Arg(2))
+		if err != nil {
+			fatal(err)
+		}
+		if commands.IsSecretConfigKey(key) {
+			fmt.Fprintf(os.Stderr, "%q is stored in plaintext in %s, consider setting JIWA_%s in the environment instead\n", key, path, strings.ToUpper(key))
+		}
+	default:
+		fmt.Println(configUsage)
+		exit(1)
 	}
+}
 
-	err = json.Unmarshal(cfgBytes, &cfg)
+// setupConfig reads the configuration file and layers the environment and
+// global flags on top of it, in that order of precedence. Without a file at
+// the default location everything has to come from the environment and the
+// flags, a file that was asked for with --config, JIWA_CONFIG or a profile has
+// to exist. It returns the profile the state and the journal belong to.
+func setupConfig() string {
+	name, err := profile()
+	if err != nil {
+		fatal(err)
+	}
+	cfgFileLoc, err := configPath()
 	if err != nil {
-		fmt.Printf("failed to read configuration file: %s\n", err)
-		os.Exit(1)
+		fatal(err)
+	}
+
+	cfgFile, err := os.Open(cfgFileLoc)
+	explicit := *globalConfig != "" || os.Getenv("JIWA_CONFIG") != "" || name != defaultProfile
+	noFile := errors.Is(err, fs.ErrNotExist) && !explicit
+	switch {
+	case noFile:
+		// everything comes from the environment, e.g. in CI
+	case err != nil:
+		fatal(fmt.Errorf("cannot locate configuration file, was it created under %s? Detailed error: %w", cfgFileLoc, err))
+	default:
+		defer cfgFile.Close()
+
+		cfg, err = commands.ParseConfig(cfgFile)
+		if err != nil {
+			fatal(fmt.Errorf("failed to read configuration file %s: %w", cfgFileLoc, err))
+		}
 	}
 
-	username, set := os.LookupEnv("JIWA_USERNAME")
-	if set {
-		cfg.Username = username
+	cfg.ApplyEnv(os.LookupEnv)
+
+	if *globalBaseURL != "" {
+		cfg.BaseURL = *globalBaseURL
 	}
-	password, set := os.LookupEnv("JIWA_PASSWORD")
-	if set {
-		cfg.Password = password
+	if *globalUser != "" {
+		cfg.Username = *globalUser
 	}
-	token, set := os.LookupEnv("JIWA_TOKEN")
-	if set {
-		cfg.Token = token
+	if *globalProject != "" {
+		cfg.DefaultProject = *globalProject
+	}
+	if *globalReqTime != 0 {
+		cfg.Timeout = *globalReqTime
+	}
+	if *globalTimeout != 0 {
+		cfg.CommandTimeout = *globalTimeout
+	}
+	if *globalHTTP {
+		cfg.InsecureAllowHTTP = true
+	}
+	if *globalUTC {
+		cfg.Timezone = "UTC"
 	}
 
-	valid := cfg.IsValid()
-	if !valid {
-		fmt.Printf(`Config is missing important values, \"baseURL\" and \"username\" + \"password\" or \"token\" need to be set.
-"username", "password" and "token" can be configured through their respective environment variables "JIWA_USERNAME", "JIWA_PASSWORD" and "JIWA_TOKEN".
-The configuration file is located at %s
-`, cfgFileLoc)
-		os.Exit(1)
+	err = cfg.Validate()
+	if err != nil {
+		if noFile {
+			fatal(fmt.Errorf("Config is missing important values: %w\nThere is no configuration file at %s, create one or set JIWA_BASE_URL, JIWA_USERNAME and JIWA_TOKEN or JIWA_PASSWORD", err, cfgFileLoc))
+		}
+		fatal(fmt.Errorf("Config is missing important values: %w\nThe configuration file is located at %s", err, cfgFileLoc))
 	}
 
 	if cfg.APIVersion == "" {
@@ -95,74 +447,581 @@ The configuration file is located at %s
 		cfg.Timeout = 5 * time.Second
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: jiwa {cat|comment|create|edit|issueType||label|list|move|reassign|search}\n")
-		os.Exit(1)
+	if cfg.HookTimeout == 0 {
+		cfg.HookTimeout = hooks.DefaultTimeout
 	}
 
+	return name
 }
 
+const usage = "Usage: jiwa [--base-url|--user|--project|--config|--profile|--dry-run|--yes|--timeout|--request-timeout|--offline|--output|--verbose|--log-format|--quiet|--insecure-allow-http|--utc|--skip-permission-check|--url] {activity|apply|archive|backlog|cat|close|comment|commits|completion|component|config|create|cycletime|dashboard|diff|edit|estimate|export|filter|flag|grep|history|hooks|import|issue-type|label|link|links|list|migrate|mine|move|move-project|parent|queue|reassign|recent|restore|search|serve|show|snippets|sprint|sync|tail|triage|unflag|whoami}"
+
+// exitUnknownCommand tells a typo in the subcommand apart from a command
+// that failed
+const exitUnknownCommand = 2
+
 func main() {
-	httpClient := http.DefaultClient
-	httpClient.Timeout = cfg.Timeout
+	globalArgs, subcommand, args := splitArgs(global, os.Args[1:])
+	err := global.Parse(globalArgs)
+	if err != nil || subcommand == "" {
+		fmt.Println(usage)
+		exit(1)
+	}
+
+	err = checkSubcommand(subcommand)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println(usage)
+		exit(exitUnknownCommand)
+	}
+
+	// config has to work on files that are incomplete or don't exist yet
+	if subcommand == "config" {
+		runConfig(args)
+		return
+	}
+
+	if subcommand == "completion" {
+		runCompletion(args)
+		return
+	}
+
+	// the completion script passes everything typed after jiwa, the
+	// subcommands are completed without a config
+	if subcommand == completeCommand {
+		completeGlobal, completing, completeRest := splitArgs(global, args)
+		if len(completeRest) == 0 {
+			fmt.Println(strings.Join(completeSubcommands(completing), "\n"))
+			return
+		}
+		_ = global.Parse(completeGlobal)
+	}
+
+	switch *globalLogFmt {
+	case "text", "json":
+	default:
+		fatal(fmt.Errorf("unknown --log-format %q, use \"text\" or \"json\"", *globalLogFmt))
+	}
+
+	// with --log-format json the errors end up in the log as well, the
+	// ones before there is a client with no requests in the stats
+	var logger *slog.Logger
+	stats := func() jiwa.Stats { return jiwa.Stats{} }
+	if *globalLogFmt == "json" {
+		logger = jsonLogger(os.Stderr, *globalVerbose).With("command", subcommand)
+		start := time.Now()
+		onExit = func(err error) {
+			if err != nil {
+				logger.Error("failed", slog.String("error", err.Error()))
+			}
+			logStats(logger, time.Since(start), stats())
+		}
+	}
+
+	profileName := setupConfig()
+
+	httpClient, err := jiwa.NewHTTPClient(cfg.Timeout, cfg.ClientCert, cfg.ClientKey)
+	if err != nil {
+		fatal(err)
+	}
+	if *globalOffline {
+		httpClient.Transport = offline.Unreachable{}
+	}
+
+	c, err := jiwa.NewClient(jiwa.Config{
+		BaseURL:        cfg.BaseURL,
+		EndpointPrefix: cfg.EndpointPrefix,
+		APIVersion:     cfg.APIVersion,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		Token:          cfg.Token,
+		HTTPClient:     httpClient,
+		MaxRetries:     cfg.MaxRetries,
+		Cache:          &jiwa.ResponseCache{},
+
+		InsecureAllowHTTP: cfg.InsecureAllowHTTP,
+	})
+	if errors.Is(err, jiwa.ErrPlainHTTP) {
+		fatal(fmt.Errorf("%w\nSet \"insecureAllowHTTP\" in the config or pass --insecure-allow-http if you accept that anyone on the network can read them", err))
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if jiwa.PlainHTTP(c.BaseURL) {
+		fmt.Fprintf(os.Stderr, "warning: %s is plain http, your credentials are sent in cleartext\n", c.BaseURL)
+	}
+	// the spinner is cleared before the request is logged, both are on
+	// stderr
+	spinner := progress.New(os.Stderr, *globalQuiet)
+	spinner.Start()
+	defer spinner.Close()
+	logRequest := func(string, string, int, time.Duration, error) {}
+	switch {
+	case logger != nil:
+		logRequest = slogRequestLogger(logger)
+		stats = c.Stats
+	case *globalVerbose:
+		logRequest = requestLogger(os.Stderr)
+		onExit = func(err error) {
+			if err != nil {
+				fmt.Println(err)
+			}
+			printStats(os.Stderr, c.Stats())
+		}
+	}
+	defer onExit(nil)
+	c.OnRequest = func(string, string) { spinner.Begin() }
+	c.OnResponse = func(method, path string, status int, dur time.Duration, err error) {
+		spinner.End()
+		logRequest(method, path, status, dur, err)
+	}
+
+	// Ctrl-C aborts requests that are in flight instead of waiting for them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if cfg.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.CommandTimeout)
+		defer cancel()
+	}
+
+	cmd := commands.Command{
+		Client:  c,
+		Config:  cfg,
+		Hooks:   hooks.Runner{Hooks: cfg.Hooks, Timeout: cfg.HookTimeout},
+		Context: ctx,
+
+		SkipPermissionCheck: *globalNoPerm,
+		PrintURLs:           *globalURL,
+		Yes:                 *globalYes,
+	}
+
+	// commands that don't know --dry-run would change Jira
+	if *globalDryRun && !slices.Contains(dryRunCommands, subcommand) {
+		fatal(fmt.Errorf("jiwa %s can't --dry-run, it would change Jira, only %s can", subcommand, strings.Join(dryRunCommands[1:], ", ")))
+	}
+	cmd.DryRun = *globalDryRun
+	cmd.Hooks.DryRun = *globalDryRun
+
+	statePath, err := state.DefaultPath(profileName)
+	if err != nil {
+		fmt.Printf("cannot locate state file, @last and friends will not work: %s\n", err)
+	} else {
+		cmd.State = &state.Store{Path: statePath}
+		if cfg.CacheResponses {
+			c.Cache.Dir = filepath.Join(filepath.Dir(statePath), "responses")
+		}
+	}
 
-	c := jiwa.Client{
-		Username:   cfg.Username,
-		Password:   cfg.Password,
-		Token:      cfg.Token,
-		BaseURL:    cfg.BaseURL + "/" + cfg.ReturnCleanEndpointPrefix(),
-		APIVersion: cfg.APIVersion,
-		HTTPClient: httpClient,
+	journalPath, err := offline.DefaultPath(profileName)
+	switch {
+	case err != nil && *globalOffline:
+		fatal(err)
+	case err != nil:
+		fmt.Printf("cannot locate the journal, changes will not be queued when Jira can't be reached: %s\n", err)
+	default:
+		cmd.Journal = &offline.Store{Path: journalPath}
+		// sync sends the queued changes itself, everything else queues
+		// changes that can't be sent or have to wait for queued ones
+		if subcommand != "sync" {
+			cmd.Client = &offline.Client{
+				API:                  c,
+				Journal:              cmd.Journal,
+				Offline:              *globalOffline,
+				QueueWhenUnreachable: cfg.QueueWhenUnreachable,
+				Log:                  os.Stderr,
+			}
+		}
 	}
 
-	cmd := commands.Command{Client: c, Config: cfg}
+	// snippets live next to the config file, e.g. ~/.config/jiwa/snippets
+	cfgFileLoc, err := configPath()
+	if err == nil {
+		cmd.ConfigDir = filepath.Dir(cfgFileLoc)
+		cmd.SnippetDir = filepath.Join(cmd.ConfigDir, "snippets")
+	}
 
 	stat, _ := os.Stdin.Stat()
 
-	switch os.Args[1] {
-	case "cat":
-		err := cat.Parse(os.Args[2:])
+	switch subcommand {
+	case completeCommand:
+		ctx, cancel := context.WithTimeout(cmd.Context, completeTimeout)
+		defer cancel()
+		cmd.Context = ctx
+
+		_, completing, completeRest := splitArgs(global, args)
+		candidates, err := completeArgs(cmd, completing, completeRest)
+		if err != nil {
+			exit(1)
+		}
+		for _, c := range candidates {
+			fmt.Println(c)
+		}
+	case "activity":
+		err := activity.Parse(args)
+		if err != nil || len(activity.Args()) != 0 {
+			fmt.Println("Usage: jiwa activity [--project <project>|--issue <issue-id>] [--since 24h] [--author <user>] [--output text|json]")
+			exit(1)
+		}
+
+		if *activityProject != "" && *activityIssue != "" {
+			fatal(errors.New("--project and --issue cannot be used together"))
+		}
+
+		activityInput := commands.ActivityInput{Author: *activityAuthor}
+		if *activitySince != "" {
+			activityInput.Since, err = commands.ParseSinceOrDate(*activitySince, time.Now().In(cmd.Location()))
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		switch {
+		case *activityIssue != "":
+			activityInput.Issue = parseIssueArg(cmd, *activityIssue)
+		case *activityProject != "":
+			activityInput.Project = strings.ToUpper(*activityProject)
+		default:
+			activityInput.Project = cmd.Config.DefaultProject
+		}
+
+		if activityInput.Issue == "" && activityInput.Project == "" {
+			fatal(errors.New("no project given, pass --project or set a default project"))
+		}
+
+		format, err := ownOutputFormat(subcommand, activity, *activityOut, "text", "json")
+		if err != nil {
+			fatal(err)
+		}
+
+		events, err := cmd.Activity(activityInput)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = printActivity(os.Stdout, events, format, time.Now().In(cmd.Location()))
+		if err != nil {
+			fatal(err)
+		}
+	case "apply":
+		err := apply.Parse(args)
+		if err != nil || len(apply.Args()) != 1 {
+			fmt.Println("Usage: jiwa apply [--update-file] [--dry-run] <manifest.yaml>")
+			exit(1)
+		}
+
+		path := apply.Arg(0)
+		manifest, err := commands.ReadManifest(path)
+		if err != nil {
+			fatal(err)
+		}
+
+		cmd.DryRun = cmd.DryRun || *applyDryRun
+		keys := make(map[string]string)
+		results := cmd.Apply(manifest, func(id, key string) {
+			keys[id] = key
+			if !*applyUpdateFile {
+				return
+			}
+
+			// written after every issue so a failure later on doesn't
+			// lose the keys of the ones that were created
+			err := commands.WriteManifestKeys(path, keys)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write %s into %s: %s\n", key, path, err)
+			}
+		})
+
+		failed := printApplyResults(os.Stdout, os.Stderr, manifest, results, cmd.DryRun)
+		if failed != 0 {
+			exit(1)
+		}
+	case "archive":
+		err := archive.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa archive [--force] <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa archive [--force]")
+			exit(1)
+		}
+
+		issues := issueArgs(cmd, stat, archive.Args(), "Usage: jiwa archive [--force] <issue-id>...")
+		if !*archiveForce {
+			err = cmd.ConfirmArchive("archive", issues)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		archived, err := cmd.Archive(issues)
+		exitWithArchived(cmd, archived, err)
+	case "backlog":
+		err := backlog.Parse(args)
 		if err != nil {
-			fmt.Println("jiwa cat <issue-id>")
-			fmt.Println("echo \"<issue-id>\" | jiwa cat <issue-id>")
-			os.Exit(1)
+			fmt.Println("jiwa backlog <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa backlog")
+			exit(1)
 		}
 
 		var issues []string
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
 			issues, err = cmd.ReadIssueListFromStdin()
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
+			}
+		} else {
+			if len(backlog.Args()) == 0 {
+				fmt.Println("Usage: jiwa backlog <issue-id>...")
+				exit(1)
+			}
+
+			for _, arg := range backlog.Args() {
+				issues = append(issues, parseIssueArg(cmd, arg))
+			}
+		}
+
+		movedIssues, err := cmd.Backlog(issues)
+		for _, issue := range movedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+
+		if err != nil {
+			fatal(err)
+		}
+	case "cat", "show":
+		err := cat.Parse(args)
+		if err != nil {
+			fmt.Printf("jiwa %s [--comments] [--fields <field>,...] [--short|--full] <issue-id>\n", subcommand)
+			fmt.Printf("echo \"<issue-id>\" | jiwa %s <issue-id>\n", subcommand)
+			fmt.Println("show always prints the description and comments rendered the way Jira shows them, cat the wiki markup as it is stored")
+			exit(1)
+		}
+
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
 			}
 		} else {
 			if len(cat.Args()) == 0 {
 				fmt.Println("Usage: jiwa cat <issue-id>")
-				os.Exit(1)
+				exit(1)
 			}
 
-			issues = []string{cmd.StripBaseURL(cat.Arg(0))}
+			issues = []string{parseIssueArg(cmd, cat.Arg(0))}
 		}
 
-		issue, err := cmd.Cat(issues[0])
+		if *catShort && *catFull {
+			fatal(errors.New("--short and --full can't be used together"))
+		}
+		detail, err := commands.ParseShowDetail(cmd.Config.ShowDetail)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
+		}
+		switch {
+		case *catShort:
+			detail = commands.ShowShort
+		case *catFull:
+			detail = commands.ShowFull
+		}
+
+		// the global --output writes the issue like list does instead of
+		// rendering it for reading
+		if *globalOutput != "" {
+			out, err := output.New(os.Stdout, *globalOutput, outputOptions(cmd, *globalOutput, false))
+			if err != nil {
+				fatal(err)
+			}
+
+			var issue jira.Issue
+			var changes []jira.ChangelogHistory
+			fetch := func() error {
+				issue, err = cmd.Cat(issues[0], jiwa.WithFields(output.ShowFields(out, detail)...))
+				return err
+			}
+			if detail == commands.ShowFull {
+				changes, err = cmd.WithLatestChanges(issues[0], fetch)
+			} else {
+				err = fetch()
+			}
+			if err == nil {
+				err = output.WriteShow(out, issue, detail, commands.NewFullDetail(issue, changes, cmd.Location()))
+			}
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fatal(err)
+			}
+			break
 		}
 
-		fmt.Println(issue.Fields.Summary+"\n"+issue.Fields.Description, nil)
+		if detail == commands.ShowShort {
+			issue, err := cmd.Cat(issues[0], jiwa.WithFields(commands.ShortShowFields...))
+			if err != nil {
+				fatal(err)
+			}
+			printShort(os.Stdout, issue)
+			break
+		}
 
+		var opts []jiwa.GetIssueOption
 		if *catComments {
-			for _, comment := range issue.Fields.Comments.Comments {
-				fmt.Printf("%s wrote on %s:\n%s\n", comment.Author.Name, comment.Created, comment.Body)
+			opts = append(opts, jiwa.WithFields("comment"))
+		}
+		if detail == commands.ShowFull {
+			opts = append(opts, jiwa.WithFields(commands.FullShowFields...))
+		}
+		// show is for reading, cat prints the wiki markup as it is stored
+		var flaggedField, pointsField string
+		if subcommand == "show" {
+			opts = append(opts, jiwa.WithRenderedFields(), jiwa.WithFields("timetracking", "security"))
+			flaggedField, _ = cmd.FlaggedField()
+			if flaggedField != "" {
+				opts = append(opts, jiwa.WithFields(flaggedField))
+			}
+			pointsField, _ = cmd.StoryPointsField()
+			if pointsField != "" {
+				opts = append(opts, jiwa.WithFields(pointsField))
+			}
+		}
+
+		var issue jira.Issue
+		var view []commands.ViewField
+		var changes []jira.ChangelogHistory
+		fetch := func() error {
+			issue, view, err = cmd.View(issues[0], *catFields, opts...)
+			return err
+		}
+		// the changelog of --full is a request of its own, made while the
+		// issue is fetched
+		if detail == commands.ShowFull {
+			changes, err = cmd.WithLatestChanges(issues[0], fetch)
+		} else {
+			err = fetch()
+		}
+		if err != nil {
+			fatal(err)
+		}
+
+		stdoutStat, _ := os.Stdout.Stat()
+		color := (stdoutStat.Mode() & os.ModeCharDevice) != 0
+		renderView(issue, view, color)
+		// before anything else, so nobody pastes it somewhere without
+		// noticing
+		if level := commands.SecurityLevelOf(issue); level != "" {
+			fmt.Println(securityMarker(color) + " Security Level: " + level)
+		}
+		if commands.IsFlagged(issue, flaggedField) {
+			fmt.Println(flaggedMarker(color) + " Flagged")
+		}
+		printEstimates(os.Stdout, issue, pointsField)
+		printView(view)
+
+		if *catComments || detail == commands.ShowFull {
+			printComments(os.Stdout, issue, color, cmd.Location())
+		}
+		if detail == commands.ShowFull {
+			printFullDetail(os.Stdout, commands.NewFullDetail(issue, changes, cmd.Location()), cmd.Location())
+		}
+	case "close":
+		err := closeCmd.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa close [--resolution|--field|--comment] <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa close [--resolution|--field|--comment]")
+			fmt.Println("echo \"<comment>\" | jiwa close --comment - <issue-id>...")
+			exit(1)
+		}
+
+		var issues []string
+		if (stat.Mode()&os.ModeCharDevice) == 0 && *closeComment != "-" {
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+		} else {
+			if len(closeCmd.Args()) == 0 {
+				fmt.Println("Usage: jiwa close <issue-id>...")
+				exit(1)
+			}
+
+			for _, arg := range closeCmd.Args() {
+				issues = append(issues, parseIssueArg(cmd, arg))
 			}
 		}
+
+		fields, err := parseTransitionFlags(*closeFields, *closeResolution)
+		if err != nil {
+			fatal(err)
+		}
+
+		closeText, err := transitionComment(*closeComment)
+		if err != nil {
+			fatal(err)
+		}
+
+		closedIssues, err := cmd.Close(issues, fields, closeText)
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range closedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
 	case "comment":
-		err := comment.Parse(os.Args[2:])
+		err := comment.Parse(args)
 		if err != nil {
 			fmt.Println("Usage: jiwa comment <issue-id> <comment>")
 			fmt.Println("echo \"<issue-id>\" | jiwa comment <comment>")
-			os.Exit(1)
+			exit(1)
+		}
+
+		cmd.NoMentions = *commentNoMentions
+		if *commentVisibleTo != "" {
+			visibility, err := commands.ParseVisibility(*commentVisibleTo)
+			if err != nil {
+				fatal(err)
+			}
+			cmd.VisibleTo = &visibility
+		}
+
+		// with a snippet or --message the text is given and the arguments
+		// are only the issue
+		if *commentSnippet != "" || comment.Changed("message") {
+			var issues []string
+			if (stat.Mode() & os.ModeCharDevice) == 0 {
+				if len(comment.Args()) != 0 {
+					fmt.Println("Usage: echo \"<issue-id>\" | jiwa comment --snippet <name> [--message <text>]")
+					exit(1)
+				}
+
+				issues, err = cmd.ReadIssueListFromStdin()
+				if err != nil {
+					fatal(err)
+				}
+			} else {
+				if len(comment.Args()) != 1 {
+					fmt.Println("Usage: jiwa comment --snippet <name> [--message <text>] <issue-id>")
+					exit(1)
+				}
+
+				issues = []string{parseIssueArg(cmd, comment.Arg(0))}
+			}
+
+			var commentedIssues []string
+			if *commentSnippet != "" {
+				commentedIssues, err = cmd.CommentSnippet(issues, *commentSnippet, *commentMessage)
+			} else {
+				commentedIssues, err = cmd.Comment(issues, *commentMessage)
+			}
+			if err != nil {
+				fatal(err)
+			}
+
+			for _, issue := range commentedIssues {
+				fmt.Println(cmd.IssueRef(issue))
+			}
+			return
 		}
 
 		var issues []string
@@ -171,29 +1030,34 @@ func main() {
 			if len(comment.Args()) > 1 {
 				fmt.Println("echo \"<issue-id>\" | jiwa comment <comment>")
 				fmt.Println("echo \"<issue-id>\" | jiwa comment (opens $EDITOR)")
-				os.Exit(1)
+				exit(1)
 			}
 
 			issues, err = cmd.ReadIssueListFromStdin()
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
 
 			if len(comment.Args()) == 1 {
 				commentStr = comment.Arg(0)
 			} else {
-				scanner, cleanup, err := editor.SetupTmpFileWithEditor("")	
+				exitOnMissingPermission(&cmd, jiwa.PermissionAddComments, issues...)
+				exitOnInvalidVisibility(&cmd, issues...)
+
+				key := ""
+				if len(issues) == 1 {
+					key = issues[0]
+				}
+
+				scanner, cleanup, err := editor.SetupTmpFileWithEditor(cmd.EditorFile("comment", key), "")	
 				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)					
+					fatal(err)					
 				}
 				defer cleanup()
 
 				text, err := commands.BuildCommentFromScanner(scanner)
 				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)
+					fatal(err)
 				}
 				commentStr = text
 			}
@@ -204,366 +1068,1951 @@ func main() {
 				if len(comment.Args()) < 1 {
 					fmt.Println("Usage: jiwa comment <issue-id> <comment>")
 					fmt.Println("jiwa comment <issue-id> (opens $EDITOR)")
-					os.Exit(1)
+					exit(1)
 				}
 			case 1:
-				scanner, cleanup, err := editor.SetupTmpFileWithEditor("")	
+				exitOnMissingPermission(&cmd, jiwa.PermissionAddComments, parseIssueArg(cmd, comment.Arg(0)))
+				exitOnInvalidVisibility(&cmd, parseIssueArg(cmd, comment.Arg(0)))
+
+				scanner, cleanup, err := editor.SetupTmpFileWithEditor(cmd.EditorFile("comment", parseIssueArg(cmd, comment.Arg(0))), "")	
 				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)					
+					fatal(err)					
 				}
 				defer cleanup()
 
 				text, err := commands.BuildCommentFromScanner(scanner)
 				if err != nil {
-					fmt.Println(err)
-					os.Exit(1)
+					fatal(err)
 				}
 				commentStr = text
 			case 2:
 				commentStr = comment.Arg(1)
 			}
 
-			issues = []string{cmd.StripBaseURL(comment.Arg(0))}
+			issues = []string{parseIssueArg(cmd, comment.Arg(0))}
 		}
 
 		commentedIssues, err := cmd.Comment(issues, commentStr)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
 		for _, issue := range commentedIssues {
-			fmt.Println(cmd.ConstructIssueURL(issue))
+			fmt.Println(cmd.IssueRef(issue))
 		}
-	case "create":
-		err := create.Parse(os.Args[2:])
-		if err != nil {
-			fmt.Println("Usage: jiwa create [-project]")
-			os.Exit(1)
+	case "commits":
+		err := commits.Parse(args)
+		if err != nil || len(commits.Args()) != 1 {
+			fmt.Println("Usage: jiwa commits [--range <revisions>] [--description] <issue-id>")
+			exit(1)
 		}
 
-		project, err := cmd.FishOutProject(*createProject)
+		issue := parseIssueArg(cmd, commits.Arg(0))
+		recorded, err := cmd.Commits(issue, commands.CommitsInput{Range: *commitsRange, Description: *commitsDescription})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		key, err := cmd.Create(project, *createFile, *createTicketType, *createComponent)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		noun := "commits"
+		if len(recorded) == 1 {
+			noun = "commit"
 		}
-
-		fmt.Println(cmd.ConstructIssueURL(key))
-	case "edit":
-		err := edit.Parse(os.Args[2:])
+		fmt.Fprintf(os.Stderr, "recorded %d %s on %s\n", len(recorded), noun, issue)
+		fmt.Println(cmd.IssueRef(issue))
+	case "component":
+		err := component.Parse(args)
 		if err != nil {
-			fmt.Println("jiwa edit <issue-id>")
-			fmt.Println("echo \"<issue-id>\" | jiwa edit")
-			os.Exit(1)
+			fmt.Println("jiwa component <issue ID> <component> <component>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa component <component> <component>...")
+			exit(1)
 		}
 
+		var components []string
 		var issues []string
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if len(component.Args()) == 0 {
+				fmt.Println("Usage: jiwa component <component> <component>...")
+				exit(1)
+			}
+
 			issues, err = cmd.ReadIssueListFromStdin()
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
+
+			components = component.Args()
 		} else {
-			if len(edit.Args()) == 0 {
-				fmt.Println("Usage: jiwa edit <issue ID>")
-				os.Exit(1)
+			if len(component.Args()) < 2 {
+				fmt.Println("Usage: jiwa component <issue ID> <component> <component>...")
+				exit(1)
 			}
 
-			issues = []string{cmd.StripBaseURL(edit.Arg(0))}
+			issues = []string{parseIssueArg(cmd, component.Arg(0))}
+			components = component.Args()[1:]
 		}
 
-		key, err := cmd.Edit(issues[0])
+		updatedIssues, err := cmd.SetComponents(issues, components)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		fmt.Println(cmd.ConstructIssueURL(key))
-	case "issue-type":
-		err := issueType.Parse(os.Args[2:])
-		if err != nil {
-			fmt.Println("jiwa issue-type <project-key>")
-			os.Exit(1)
+		for _, issue := range updatedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "create":
+		create.SetNormalizeFunc(func(_ *flag.FlagSet, name string) flag.NormalizedName {
+			switch name {
+			case "force":
+				name = "yes"
+			case "type":
+				name = "ticket-type"
+			}
+			return flag.NormalizedName(name)
+		})
+		err := create.Parse(args)
+		if err != nil {
+			fmt.Println("Usage: jiwa create [-project]")
+			exit(1)
 		}
 
-		if len(issueType.Args()) == 0 {
-			fmt.Println("jiwa issue-type <project-key>")
-			os.Exit(1)
+		cmd.NoMentions = *createNoMentions
+
+		project, err := cmd.FishOutProject(*createProject)
+		if err != nil {
+			fatal(err)
 		}
 
-		issueTypes, err := cmd.IssueTypes(issueType.Arg(0))
+		cmd.DryRun = cmd.DryRun || *createDryRun
+		cmd.Hooks.DryRun = cmd.DryRun
+
+		links, err := cmd.ResolveLinkSpecs(*createLinks)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, it := range issueTypes {
-			fmt.Println(it.Name)
+		parent := ""
+		if *createParent != "" {
+			parent = parseIssueArg(cmd, *createParent)
 		}
-	case "label":
-		err := label.Parse(os.Args[2:])
+
+		// the template's type wins over the default but not over --type
+		ticketType := *createTicketType
+		if !create.Changed("ticket-type") {
+			ticketType = ""
+		}
+
+		createInput := commands.CreateInput{
+			Project:    project,
+			File:       *createFile,
+			Type:       ticketType,
+			Labels:     *createLabels,
+			Components: *createComponents,
+			Parent:     parent,
+			EpicName:   *createEpicName,
+
+			SkipDuplicateCheck: *createNoDupCheck,
+			Yes:                *createYes || cmd.Yes,
+			CheckDuplicates:    *createCheckDupes,
+			Template:           *createTemplate,
+			AutoSplit:          *createAutoSplit,
+			Interactive:        *createPrompt,
+			Security:           *createSecurity,
+			Reporter:           *createReporter,
+			AssignToMe:         *createMine,
+		}
+
+		createInput.Due, err = dateFlag("due", *createDue, time.Now().In(cmd.Location()))
 		if err != nil {
-			fmt.Println("jiwa label <issue ID> <label> <label>...")
-			fmt.Println("echo \"<issue-id>\" | jiwa label <label> <label> ...")
-			os.Exit(1)
+			fatal(err)
 		}
 
-		var labels []string
-		var issues []string
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			if len(label.Args()) == 0 {
-				fmt.Println("Usage: jiwa label <label> <label> ...")
-				os.Exit(1)
+		if *createIn != "" {
+			if *createFile != "" {
+				fatal(errors.New("--in and --file cannot be used together"))
+			}
+			if *createPrompt {
+				fatal(errors.New("--in and --interactive cannot be used together"))
 			}
 
-			issues, err = cmd.ReadIssueListFromStdin()
+			var text []byte
+			if *createIn == "-" {
+				text, err = commands.ReadStdin()
+			} else {
+				text, err = os.ReadFile(*createIn)
+			}
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
 
-			labels = label.Args()
+			blocks, err := commands.ParseIssueBlocks(string(text))
+			if err != nil {
+				fatal(err)
+			}
+			if len(blocks) == 0 {
+				fatal(fmt.Errorf("there are no issues in %s", *createIn))
+			}
+
+			failed := 0
+			for _, r := range cmd.CreateBatch(createInput, blocks) {
+				if r.Err != nil {
+					fmt.Fprintf(os.Stderr, "failed to create %q from line %d: %s\n", r.Block.Summary, r.Block.Line, r.Err)
+					failed++
+					continue
+				}
+				if cmd.DryRun {
+					continue
+				}
+
+				fmt.Println(cmd.IssueRef(r.Key))
+				err = cmd.Link(r.Key, links)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s was created but linking failed: %s\n", r.Key, err)
+					failed++
+				}
+			}
+			if failed != 0 {
+				fmt.Fprintf(os.Stderr, "%d of %d issues failed\n", failed, len(blocks))
+				exit(1)
+			}
+			break
+		}
+
+		key, err := cmd.Create(createInput)
+		if err != nil {
+			fatal(err)
+		}
+
+		if cmd.DryRun {
+			break
+		}
+
+		fmt.Println(cmd.IssueRef(key))
+
+		err = cmd.Link(key, links)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "issue was created but linking failed: %s\n", err)
+			exit(exitLinkFailed)
+		}
+	case "cycletime":
+		err := cycletime.Parse(args)
+		if err != nil {
+			fmt.Println("Usage: jiwa cycletime [--output table|csv|json] <jql>")
+			fmt.Println("jiwa cycletime <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa cycletime")
+			exit(1)
+		}
+
+		var cycletimeInput commands.CycleTimeInput
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			cycletimeInput.Keys, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
 		} else {
-			if len(label.Args()) < 2 {
-				fmt.Println("Usage: jiwa label <issue ID> <label> <label>...")
-				os.Exit(1)
+			if len(cycletime.Args()) == 0 {
+				fmt.Println("Usage: jiwa cycletime <jql|issue-id...>")
+				exit(1)
 			}
 
-			issues = []string{cmd.StripBaseURL(label.Arg(0))}
-			labels = label.Args()[1:]
+			// all issues or a query, "status = Done" doesn't parse as keys
+			for _, arg := range cycletime.Args() {
+				key, err := cmd.ParseIssueArg(arg)
+				if err != nil {
+					cycletimeInput.Keys = nil
+					cycletimeInput.JQL = strings.Join(cycletime.Args(), " ")
+					break
+				}
+				cycletimeInput.Keys = append(cycletimeInput.Keys, key)
+			}
 		}
 
-		labelledIssues, err := cmd.Label(issues, labels)
+		format, err := ownOutputFormat(subcommand, cycletime, *cycletimeOut, "table", "csv", "json")
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, issue := range labelledIssues {
-			fmt.Println(cmd.ConstructIssueURL(issue))
+		report, err := cmd.CycleTime(cycletimeInput)
+		if err != nil {
+			fatal(err)
 		}
-	case "list":
-		err := list.Parse(os.Args[2:])
+
+		err = printCycleTime(os.Stdout, report, format)
 		if err != nil {
-			fmt.Println("Usage: jiwa list [--user|--status|--project|--label]")
-			os.Exit(1)
+			fatal(err)
+		}
+	case "dashboard":
+		err := dashboard.Parse(args)
+		if err != nil || len(dashboard.Args()) != 0 {
+			fmt.Println("Usage: jiwa dashboard [--project <project>] [--output text|json]")
+			exit(1)
 		}
 
-		listInput := commands.ListInput{
-			Assignee: *listUser,
-			Project:  *listProject,
-			Status:   *listStatus,
-			Labels:   *listLabels,
+		format, err := ownOutputFormat(subcommand, dashboard, *dashboardOut, "text", "json")
+		if err != nil {
+			fatal(err)
 		}
-		issues, err := cmd.List(listInput)
+
+		d, err := cmd.Dashboard(*dashboardProject)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		switch *listOut {
-		case "raw":
-			for _, i := range issues {
-				fmt.Println(cmd.ConstructIssueURL(i.Key))
-			}
-		case "table":
-			w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
-			fmt.Fprintf(w, "ID\tSummary\tURL\n")
-			for _, i := range issues {
-				fmt.Fprintf(w, "%s\t%s\t%s\n", i.Key, i.Fields.Summary, cmd.ConstructIssueURL(i.Key))
-			}
-			w.Flush()
-		default:
-			fmt.Printf("Usage: jiwa ls --out [table|raw]")
+		err = printDashboard(os.Stdout, d, format, cmd.ConstructIssueURL)
+		if err != nil {
+			fatal(err)
 		}
-	case "ls":
-		err := list.Parse(os.Args[2:])
+	case "diff":
+		err := diff.Parse(args)
+		if err != nil || len(diff.Args()) != 2 {
+			fmt.Println("Usage: jiwa diff [--all] [--output text|json] <issue-id> <issue-id>")
+			exit(1)
+		}
+		format, err := ownOutputFormat(subcommand, diff, *diffOut, "text", "json")
 		if err != nil {
-			fmt.Println("Usage: jiwa ls [--user|--status|--project|--label]")
-			os.Exit(1)
+			fatal(err)
 		}
 
-		listInput := commands.ListInput{
-			Assignee: *listUser,
-			Project:  *listProject,
-			Status:   *listStatus,
-			Labels:   *listLabels,
+		d, err := cmd.Diff(parseIssueArg(cmd, diff.Arg(0)), parseIssueArg(cmd, diff.Arg(1)), *diffAll)
+		if err != nil {
+			fatal(err)
+		}
+
+		stdoutStat, _ := os.Stdout.Stat()
+		color := (stdoutStat.Mode() & os.ModeCharDevice) != 0
+		err = printDiff(os.Stdout, d, format, color)
+		if err != nil {
+			fatal(err)
 		}
-		issues, err := cmd.List(listInput)
+	case "edit":
+		err := edit.Parse(args)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fmt.Println("jiwa edit [--append <text>] [--reopen-if-closed] [--yes] <issue-id>")
+			fmt.Println("echo \"<issue-id>\" | jiwa edit")
+			fmt.Println("echo \"<text>\" | jiwa edit --append - <issue-id>")
+			fmt.Println("jiwa edit --bulk <issue-id>...")
+			exit(1)
+		}
+
+		cmd.NoMentions = *editNoMentions
+		cmd.ReopenIfClosed = *editReopen
+
+		if *editBulk {
+			var issues []string
+			if (stat.Mode() & os.ModeCharDevice) == 0 {
+				issues, err = cmd.ReadIssueListFromStdin()
+				if err != nil {
+					fatal(err)
+				}
+			} else {
+				if len(edit.Args()) == 0 {
+					fmt.Println("Usage: jiwa edit --bulk <issue-id>...")
+					exit(1)
+				}
+
+				for _, arg := range edit.Args() {
+					issues = append(issues, parseIssueArg(cmd, arg))
+				}
+			}
+
+			result, err := cmd.BulkEdit(issues)
+			for _, key := range result.Updated {
+				fmt.Println(cmd.IssueRef(key))
+			}
+			for _, skipped := range result.Skipped {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", skipped)
+			}
+			if err != nil {
+				fatal(err)
+			}
+
+			fmt.Fprintf(os.Stderr, "%d updated, %d unchanged, %d skipped\n", len(result.Updated), len(result.Unchanged), len(result.Skipped))
+			return
 		}
 
-		switch *listOut {
-		case "raw":
-			for _, i := range issues {
-				fmt.Println(cmd.ConstructIssueURL(i.Key))
+		appendFromStdin := *editAppend == "-"
+
+		var issues []string
+		if (stat.Mode()&os.ModeCharDevice) == 0 && !appendFromStdin {
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
 			}
-		case "table":
-			w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
-			fmt.Fprintf(w, "ID\tSummary\tURL\n")
-			for _, i := range issues {
-				fmt.Fprintf(w, "%s\t%s\t%s\n", i.Key, i.Fields.Summary, cmd.ConstructIssueURL(i.Key))
+		} else {
+			if len(edit.Args()) == 0 {
+				fmt.Println("Usage: jiwa edit <issue ID>")
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, edit.Arg(0))}
+		}
+
+		var key string
+		switch {
+		case appendFromStdin:
+			var text []byte
+			text, err = commands.ReadStdin()
+			if err != nil {
+				fatal(err)
 			}
-			w.Flush()
+			key, err = cmd.AppendToDescription(issues[0], string(text))
+		case *editAppend != "":
+			key, err = cmd.AppendToDescription(issues[0], *editAppend)
 		default:
-			fmt.Printf("Usage: jiwa ls --out [table|raw]")
+			key, err = cmd.Edit(issues[0], *editYes || cmd.Yes)
 		}
-	case "move":
-		err := move.Parse(os.Args[2:])
 		if err != nil {
-			fmt.Println("jiwa move <issue-id> <status>")
-			fmt.Println("echo \"<issue-id>\" | jiwa move <status>")
-			os.Exit(1)
+			fatal(err)
 		}
 
-		var status string
-		var issues []string
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			if len(move.Args()) == 0 {
-				fmt.Println("Usage: jiwa move <status>")
-				os.Exit(1)
+		fmt.Println(cmd.IssueRef(key))
+	case "estimate":
+		err := estimate.Parse(args)
+		if err != nil || len(estimate.Args()) == 0 {
+			fmt.Println("Usage: jiwa estimate [--time] <issue-id>... <value>")
+			fmt.Println("echo \"<issue-id>\" | jiwa estimate [--time] <value>")
+			fmt.Println("jiwa estimate sum <jql>")
+			exit(1)
+		}
+
+		if estimate.Arg(0) == "sum" {
+			if len(estimate.Args()) < 2 {
+				fmt.Println("Usage: jiwa estimate sum <jql>")
+				exit(1)
 			}
 
-			issues, err = cmd.ReadIssueListFromStdin()
+			sum, err := cmd.SumEstimates(strings.Join(estimate.Args()[1:], " "))
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
 
-			status = move.Arg(0)
-		} else {
-			if len(move.Args()) < 2 {
-				fmt.Println("Usage: jiwa move <issueID> <status>")
-				os.Exit(1)
+			fmt.Fprintln(os.Stderr, sum)
+			fmt.Println(commands.FormatPoints(sum.Points))
+			return
+		}
+
+		value := estimate.Arg(len(estimate.Args()) - 1)
+		issues := issueArgs(cmd, stat, estimate.Args()[:len(estimate.Args())-1], "Usage: jiwa estimate [--time] <issue-id>... <value>")
+		estimated, err := cmd.Estimate(issues, commands.EstimateInput{Value: value, Time: *estimateTime})
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range estimated {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "export":
+		err := export.Parse(args)
+		if err != nil || len(export.Args()) != 0 {
+			fmt.Println("Usage: jiwa export [--project|--status|--jql|--fields] [--output <file>]")
+			exit(1)
+		}
+
+		exportInput := commands.ExportInput{
+			Project: *exportProject,
+			Status:  *exportStatus,
+			JQL:     *exportJQL,
+			Fields:  *exportFields,
+		}
+
+		total, err := cmd.ExportCount(exportInput)
+		if err != nil {
+			fatal(err)
+		}
+
+		out, err := createExportFile(*exportOut)
+		if err != nil {
+			fatal(err)
+		}
+
+		progress := newExportProgress(os.Stderr, total)
+		enc := json.NewEncoder(out)
+		err = cmd.Export(ctx, exportInput, func(page []jira.Issue) error {
+			for _, i := range page {
+				err := enc.Encode(i)
+				if err != nil {
+					return fmt.Errorf("failed to write %s: %w", i.Key, err)
+				}
 			}
+			progress.Add(len(page))
+			return nil
+		})
+		progress.Done()
+		if err != nil {
+			out.Abort()
+			exitStreamError(err)
+		}
 
-			issues = []string{cmd.StripBaseURL(move.Arg(0))}
-			status = move.Arg(1)
+		err = out.Commit()
+		if err != nil {
+			fatal(err)
+		}
+	case "flag":
+		err := flagCmd.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa flag [--message <reason>] <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa flag [--message <reason>]")
+			exit(1)
 		}
 
-		movedIssues, err := cmd.Move(issues, status)
+		issues := issueArgs(cmd, stat, flagCmd.Args(), "Usage: jiwa flag [--message <reason>] <issue-id>...")
+		flagged, err := cmd.Flag(issues, *flagMessage)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, issue := range movedIssues {
-			fmt.Println(cmd.ConstructIssueURL(issue))
+		for _, issue := range flagged {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "grep":
+		err := grep.Parse(args)
+		if err != nil || len(grep.Args()) == 0 {
+			fmt.Println("Usage: jiwa grep [--project|--all|--comments] <term>...")
+			exit(1)
+		}
+
+		input := commands.GrepInput{
+			Terms:       grep.Args(),
+			Project:     *grepProject,
+			AllProjects: *grepAll,
+			Comments:    *grepComments,
+		}
+		// the global --output writes the matching issues like list does
+		// instead of the excerpts
+		var out output.Writer
+		if *globalOutput != "" {
+			out, err = output.New(os.Stdout, *globalOutput, outputOptions(cmd, *globalOutput, *grepAll))
+			if err != nil {
+				fatal(err)
+			}
+			input.Fields = out.Fields()
 		}
-	case "mv":
-		err := move.Parse(os.Args[2:])
+
+		results, err := cmd.Grep(input)
 		if err != nil {
-			fmt.Println("jiwa mv <issue-id> <status>")
-			fmt.Println("echo \"<issue-id>\" | jiwa mv <status>")
-			os.Exit(1)
+			fatal(err)
 		}
 
-		var status string
-		var issues []string
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			if len(move.Args()) == 0 {
-				fmt.Println("Usage: jiwa mv <status>")
-				os.Exit(1)
+		if out != nil {
+			issues := make([]jira.Issue, 0, len(results))
+			for _, r := range results {
+				issues = append(issues, r.Issue)
 			}
+			err = out.WriteIssues(issues)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fatal(err)
+			}
+			break
+		}
 
+		stdoutStat, _ := os.Stdout.Stat()
+		color := (stdoutStat.Mode() & os.ModeCharDevice) != 0
+		for _, r := range results {
+			fmt.Println(cmd.FormatGrepResult(r, grep.Args(), color))
+		}
+	case "history":
+		err := history.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa history <issue-id>")
+			fmt.Println("echo \"<issue-id>\" | jiwa history")
+			exit(1)
+		}
+
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
 			issues, err = cmd.ReadIssueListFromStdin()
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
-
-			status = move.Arg(0)
 		} else {
-			if len(move.Args()) < 2 {
-				fmt.Println("Usage: jiwa mv <issueID> <status>")
-				os.Exit(1)
+			if len(history.Args()) == 0 {
+				fmt.Println("Usage: jiwa history <issue-id>")
+				exit(1)
 			}
 
-			issues = []string{cmd.StripBaseURL(move.Arg(0))}
-			status = move.Arg(1)
+			issues = []string{parseIssueArg(cmd, history.Arg(0))}
 		}
 
-		movedIssues, err := cmd.Move(issues, status)
+		_, err = ownOutputFormat(subcommand, history, "text", "text")
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, issue := range movedIssues {
-			fmt.Println(cmd.ConstructIssueURL(issue))
+		changes, err := cmd.History(issues[0])
+		if err != nil {
+			fatal(err)
 		}
-	case "reassign":
-		err := reassign.Parse(os.Args[2:])
+
+		loc := cmd.Location()
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "When\tWho\tField\tFrom\tTo\n")
+		for _, h := range changes {
+			who := h.Author.DisplayName
+			if who == "" {
+				who = h.Author.Name
+			}
+			for _, item := range h.Items {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", commands.FormatJiraTimestamp(h.Created, loc), who, item.Field, item.FromString, item.ToString)
+			}
+		}
+		w.Flush()
+	case "hooks":
+		err := hooksCmd.Parse(args)
+		if err != nil || hooksCmd.Arg(0) != "payload" {
+			fmt.Println("Usage: jiwa hooks payload")
+			exit(1)
+		}
+
+		out, err := json.MarshalIndent(hooks.ExamplePayload(), "", "  ")
 		if err != nil {
-			fmt.Println("jiwa reassign <issue-id> <username>")
-			fmt.Println("echo \"<issue-id>\" | jiwa reassign <username>")
-			os.Exit(1)
+			fatal(err)
 		}
 
-		var user string
-		var issues []string
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			if len(reassign.Args()) == 0 {
-				fmt.Println("Usage: jiwa reassign <username>")
-				os.Exit(1)
+		fmt.Fprintln(os.Stderr, "hooks are configured in the \"hooks\" config key, pre- hooks abort the command when exiting non-zero:")
+		for _, name := range hooks.Names {
+			fmt.Fprintln(os.Stderr, "  "+name)
+		}
+		fmt.Fprintln(os.Stderr, "the environment contains JIWA_HOOK, JIWA_ISSUE_KEY and JIWA_PROJECT and this is passed on stdin:")
+		fmt.Println(string(out))
+	case "import":
+		err := importCmd.Parse(args)
+		isSource := importCmd.Arg(0) == "github" || importCmd.Arg(0) == "gitlab"
+		if err != nil || (!isSource && len(importCmd.Args()) > 1) || (isSource && len(importCmd.Args()) != 2) {
+			fmt.Println("Usage: jiwa import [--format csv|ndjson] [--project|--type|--concurrency|--dry-run|--results] [<file>]")
+			fmt.Println("cat <file> | jiwa import --format csv|ndjson")
+			fmt.Println("jiwa import [--project|--type|--dry-run|--close|--mapping|--api-url] github <owner/repo>")
+			fmt.Println("jiwa import [--project|--type|--dry-run|--close|--mapping|--api-url] gitlab <group/project>")
+			exit(1)
+		}
+
+		if isSource {
+			kind, repo := importCmd.Arg(0), importCmd.Arg(1)
+			src, err := forge.New(kind, repo, forge.Config{
+				BaseURL: *importAPIURL,
+				Token:   os.Getenv(strings.ToUpper(kind) + "_TOKEN"),
+				Log:     os.Stderr,
+			})
+			if err != nil {
+				fatal(err)
 			}
 
-			issues, err = cmd.ReadIssueListFromStdin()
+			mappingPath := *importMapping
+			if mappingPath == "" {
+				mappingPath = kind + "-" + strings.ReplaceAll(strings.Trim(repo, "/"), "/", "-") + ".mapping.json"
+			}
+
+			cmd.DryRun = cmd.DryRun || *importDryRun
+			cmd.Hooks.DryRun = cmd.DryRun
+			created, skipped, failed := 0, 0, 0
+			err = cmd.ImportSource(src, commands.SourceImportInput{
+				Project:     *importProject,
+				Type:        *importType,
+				CloseClosed: *importClose,
+				MappingPath: mappingPath,
+			}, func(r commands.SourceImportResult) {
+				switch {
+				case r.Skipped:
+					skipped++
+				case r.Err != nil && r.Key != "":
+					created++
+					failed++
+					fmt.Println(cmd.IssueRef(r.Key))
+					fmt.Fprintf(os.Stderr, "#%d: created %s but %s\n", r.Number, r.Key, r.Err)
+				case r.Err != nil:
+					failed++
+					fmt.Fprintf(os.Stderr, "#%d: %s\n", r.Number, r.Err)
+				default:
+					// dry-runs don't get a key
+					created++
+					if r.Key != "" {
+						fmt.Println(cmd.IssueRef(r.Key))
+					}
+				}
+			})
 			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+				fatal(err)
 			}
 
-			user = reassign.Arg(0)
-		} else {
-			if len(reassign.Args()) < 2 {
-				fmt.Println("Usage: jiwa reassign <issue ID> <username>")
-				os.Exit(1)
+			if cmd.DryRun {
+				fmt.Fprintf(os.Stderr, "dry-run: %d issues would be created, %d were imported before, %d failed\n", created, skipped, failed)
+			} else {
+				fmt.Fprintf(os.Stderr, "created %d issues, %d were imported before, %d failed, the mapping is in %s\n", created, skipped, failed, mappingPath)
+			}
+			if failed != 0 {
+				exit(1)
 			}
+			break
+		}
 
-			issues = []string{cmd.StripBaseURL(reassign.Arg(0))}
-			user = reassign.Arg(1)
+		path := importCmd.Arg(0)
+		if path == "" && (stat.Mode()&os.ModeCharDevice) != 0 {
+			fmt.Println("Usage: jiwa import <file>")
+			exit(1)
+		}
+
+		input := io.Reader(os.Stdin)
+		if path != "" && path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				fatal(err)
+			}
+			defer f.Close()
+			input = f
+		}
+
+		format := *importFormat
+		if format == "" {
+			format = "ndjson"
+			if strings.EqualFold(filepath.Ext(path), ".csv") {
+				format = "csv"
+			}
 		}
 
-		reassignedIssues, err := cmd.Reassign(issues, user)
+		var rows []commands.ImportRow
+		switch format {
+		case "csv":
+			rows, err = commands.ParseImportCSV(input)
+		case "ndjson":
+			rows, err = commands.ParseImportNDJSON(input)
+		default:
+			err = fmt.Errorf("unknown format %q, use \"csv\" or \"ndjson\"", format)
+		}
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, issue := range reassignedIssues {
-			fmt.Println(cmd.ConstructIssueURL(issue))
+		if *importConcurrency > 0 {
+			cmd.Config.Concurrency = *importConcurrency
 		}
-	case "search":
-		err := search.Parse(os.Args[2:])
+		cmd.DryRun = cmd.DryRun || *importDryRun
+		cmd.Hooks.DryRun = cmd.DryRun
+		results := cmd.Import(rows, *importProject, *importType)
+		failed := printImportResults(os.Stdout, os.Stderr, results, cmd.DryRun, cmd.IssueRef)
+
+		resultsPath := *importResults
+		if resultsPath == "" && path != "" && path != "-" {
+			resultsPath = path + ".results.csv"
+		}
+		if resultsPath != "" && !cmd.DryRun {
+			err = writeImportResults(resultsPath, results)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		if failed != 0 {
+			exit(1)
+		}
+	case "issue-type":
+		err := issueType.Parse(args)
 		if err != nil {
-			fmt.Println("jiwa search \"<jql query>\"")
-			os.Exit(1)
+			fmt.Println("jiwa issue-type <project-key>")
+			exit(1)
 		}
 
-		if len(search.Args()) == 0 {
-			fmt.Println("jiwa search \"<jql query>\"")
-			os.Exit(1)
+		if len(issueType.Args()) == 0 {
+			fmt.Println("jiwa issue-type <project-key>")
+			exit(1)
 		}
 
-		issues, err := cmd.Search(search.Arg(0))
+		issueTypes, err := cmd.IssueTypes(issueType.Arg(0))
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		for _, i := range issues {
-			fmt.Println(cmd.ConstructIssueURL(i.Key))
+		for _, it := range issueTypes {
+			fmt.Println(it.Name)
 		}
-	}
+	case "label":
+		err := label.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa label [--remove] [--project <key>] <issue ID> <label> <label>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa label [--remove] [--project <key>] <label> <label> ...")
+			exit(1)
+		}
+
+		if *labelProject != "" {
+			cmd.Config.DefaultProject = *labelProject
+		}
+
+		if *labelJQL != "" {
+			if len(label.Args()) == 0 {
+				fmt.Println("Usage: jiwa label --jql <query> [--force] <label> <label>...")
+				exit(1)
+			}
+
+			labels := label.Args()
+			question := func(n int) string { return fmt.Sprintf("add %s to %d issues", strings.Join(labels, ", "), n) }
+			if *labelRemove {
+				question = func(n int) string { return fmt.Sprintf("remove %s from %d issues", strings.Join(labels, ", "), n) }
+			}
+
+			issues := queryIssues(cmd, *labelJQL, *labelForce, question)
+			results, err := cmd.ForEachIssue(issues, jiwa.PermissionEditIssues, func(key string) (bool, error) {
+				_, err := cmd.Label([]string{key}, labels, *labelRemove)
+				return err == nil, err
+			})
+			exitWithQueryResults(cmd, results, err, "labelled")
+		}
+
+		var labels []string
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if len(label.Args()) == 0 {
+				fmt.Println("Usage: jiwa label <label> <label> ...")
+				exit(1)
+			}
+
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			labels = label.Args()
+		} else {
+			if len(label.Args()) < 2 {
+				fmt.Println("Usage: jiwa label <issue ID> <label> <label>...")
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, label.Arg(0))}
+			labels = label.Args()[1:]
+		}
+
+		labelledIssues, err := cmd.Label(issues, labels, *labelRemove)
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range labelledIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "link":
+		err := link.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa link <issue-id> <relation>:<issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa link <relation>:<issue-id>...")
+			fmt.Println("jiwa link --remove <link-id>...")
+			exit(1)
+		}
+
+		// only IDs, which links lists, have no relation in them
+		if *linkRemove && len(link.Args()) != 0 && !slices.ContainsFunc(link.Args(), func(a string) bool { return strings.Contains(a, ":") }) {
+			err := cmd.RemoveLinks(link.Args())
+			if err != nil {
+				fatal(err)
+			}
+			return
+		}
+
+		var specs []string
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if len(link.Args()) == 0 {
+				fmt.Println("Usage: jiwa link [--remove] <relation>:<issue-id>...")
+				exit(1)
+			}
+
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			specs = link.Args()
+		} else {
+			if len(link.Args()) < 2 {
+				fmt.Println("Usage: jiwa link [--remove] <issue-id> <relation>:<issue-id>...")
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, link.Arg(0))}
+			specs = link.Args()[1:]
+		}
+
+		resolved, err := cmd.ResolveLinkSpecs(specs)
+		if err != nil {
+			fatal(err)
+		}
+
+		failed := false
+		for _, issue := range issues {
+			if *linkRemove {
+				err = cmd.Unlink(issue, resolved)
+			} else {
+				err = cmd.Link(issue, resolved)
+			}
+			if err != nil {
+				fmt.Println(err)
+				failed = true
+				continue
+			}
+
+			fmt.Println(cmd.IssueRef(issue))
+		}
+
+		if failed {
+			exit(1)
+		}
+	case "links":
+		err := links.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa links <issue-id>")
+			fmt.Println("echo \"<issue-id>\" | jiwa links")
+			exit(1)
+		}
+
+		var issue string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			issues, err := cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+			if len(issues) != 1 {
+				fmt.Println("jiwa links takes a single issue on stdin")
+				exit(1)
+			}
+			issue = issues[0]
+		} else {
+			if len(links.Args()) != 1 {
+				fmt.Println("Usage: jiwa links <issue-id>")
+				exit(1)
+			}
+			issue = parseIssueArg(cmd, links.Arg(0))
+		}
+
+		format, err := ownOutputFormat(subcommand, links, *linksOut, "text", "json")
+		if err != nil {
+			fatal(err)
+		}
+
+		issueLinks, err := cmd.Links(issue)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = printLinks(os.Stdout, issueLinks, format)
+		if err != nil {
+			fatal(err)
+		}
+	case "list", "ls", "filter":
+		err := list.Parse(args)
+		if err != nil {
+			fmt.Printf("Usage: jiwa %s [--user|--status|--project|--all-projects|--label|--jql|--filter|--mine-and-watching|--count|--reporter|--reported-by-me|--show-reporter|--commented-by|--updated-by-me|--updated-since|--created-since|--due-before|--limit|--no-default-project]\n", subcommand)
+			fmt.Println("jiwa filter [<name>] [list flags]")
+			exit(1)
+		}
+
+		// filter runs the saved filter named by its argument, without one
+		// it lists them
+		if subcommand == "filter" {
+			if list.NArg() == 0 {
+				printFilters(os.Stdout, cmd.Filters())
+				return
+			}
+			*listFilter = list.Arg(0)
+		}
+
+		if *listReportedMe {
+			if *listReporter != "" && !commands.IsMe(*listReporter) {
+				fatal(errors.New("--reported-by-me and --reporter can't be used together"))
+			}
+			*listReporter = "@me"
+		}
+
+		listInput := commands.ListInput{
+			Assignee: *listUser,
+			Project:  *listProject,
+			Status:   *listStatus,
+			Labels:   *listLabels,
+
+			AllProjects:      *listAll,
+			NoDefaultProject: *listNoDefault,
+			JQL:              *listJQL,
+			Filter:           *listFilter,
+			MineAndWatching:  *listMineWatch,
+
+			Reporter:    *listReporter,
+			CommentedBy: *listCommentedBy,
+			UpdatedByMe: *listUpdatedByMe,
+
+			Limit: *listLimit,
+		}
+
+		now := time.Now().In(cmd.Location())
+		listInput.UpdatedSince, err = dateFlag("updated-since", *listUpdated, now)
+		if err == nil {
+			listInput.CreatedSince, err = dateFlag("created-since", *listCreated, now)
+		}
+		if err == nil {
+			listInput.DueBefore, err = dateFlag("due-before", *listDueBefore, now)
+		}
+		if err != nil {
+			fatal(err)
+		}
+
+		// what you touched recently is rarely still to do and saved filters
+		// pick their statuses themselves, the default status only applies
+		// if it was asked for
+		activityFilter := *listReporter != "" || *listCommentedBy != "" || *listUpdatedByMe || *listMineWatch
+		if (activityFilter || *listFilter != "") && !list.Changed("status") {
+			listInput.Status = ""
+		}
+
+		if *listCount {
+			n, err := cmd.ListCount(listInput)
+			if err != nil {
+				fatal(err)
+			}
+
+			fmt.Println(n)
+			return
+		}
+
+		showProject := *listAll
+		if !showProject {
+			projects, _ := cmd.ListProjects(*listProject)
+			showProject = len(projects) > 1
+		}
+
+		format := outputFormat(list, *listOut)
+		opts := outputOptions(cmd, format, showProject)
+		opts.ShowReporter = *listReporterCol
+		opts.ShowUpdated = *listUpdatedCol
+		listTo := func(ctx context.Context, w io.Writer) error {
+			out, err := output.New(w, format, opts)
+			if err != nil {
+				return err
+			}
+
+			err = streamIssues(ctx, out, spinner, func(ctx context.Context, fn func(page []jira.Issue) error) error {
+				listInput.Fields = out.Fields()
+				return cmd.ListPages(ctx, listInput, fn)
+			})
+			if errors.Is(err, commands.ErrListCapped) {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+				return nil
+			}
+			return err
+		}
+
+		if *listWatch {
+			if *listInterval < minWatchInterval {
+				fatal(fmt.Errorf("--interval has to be at least %s", minWatchInterval))
+			}
+
+			// an unknown --output fails right away instead of on every refresh
+			_, err = output.New(io.Discard, format, opts)
+			if err != nil {
+				fatal(err)
+			}
+
+			stdoutStat, _ := os.Stdout.Stat()
+			clear := (stdoutStat.Mode() & os.ModeCharDevice) != 0
+			err = watchIssues(ctx, os.Stdout, os.Stderr, *listInterval, clear, listTo)
+			if err != nil {
+				fatal(err)
+			}
+			return
+		}
+
+		err = listTo(ctx, os.Stdout)
+		if err != nil {
+			exitStreamError(err)
+		}
+	case "mine":
+		err := mine.Parse(args)
+		if err != nil {
+			fmt.Println("Usage: jiwa mine [--flat] [--output <format>]")
+			exit(1)
+		}
+
+		groups, err := cmd.Queue("@me")
+		if err != nil {
+			fatal(err)
+		}
+
+		format := outputFormat(mine, *mineOut)
+		err = printQueue(os.Stdout, groups, *mineFlat, format, outputOptions(cmd, format, true))
+		if err != nil {
+			fatal(err)
+		}
+	case "migrate":
+		err := migrate.Parse(args)
+		if err != nil || len(migrate.Args()) != 1 || *migrateProject == "" {
+			fmt.Println("Usage: jiwa migrate --project <key> [--close-original [--resolution|--field]] <issue-id>")
+			exit(1)
+		}
+
+		fields, err := parseTransitionFlags(*migrateFields, *migrateResolution)
+		if err != nil {
+			fatal(err)
+		}
+		if len(fields) != 0 && !*migrateClose {
+			fatal(errors.New("--resolution and --field only apply with --close-original"))
+		}
+
+		result, err := cmd.Migrate(commands.MigrateInput{
+			Key:           parseIssueArg(cmd, migrate.Arg(0)),
+			Project:       strings.ToUpper(*migrateProject),
+			CloseOriginal: *migrateClose,
+			CloseFields:   fields,
+		})
+		if result.Key != "" {
+			fmt.Fprintf(os.Stderr, "copied %d comments and %d attachments of %s\n", result.Comments, len(result.Attachments), result.Original)
+			fmt.Println(cmd.IssueRef(result.Key))
+		}
+		if err != nil {
+			fatal(err)
+		}
+	case "move-project":
+		err := moveProj.Parse(args)
+		if err != nil || len(moveProj.Args()) != 2 {
+			fmt.Println("Usage: jiwa move-project [--recreate [--resolution|--field]] <issue-id> <project>")
+			exit(1)
+		}
+
+		fields, err := parseTransitionFlags(*moveProjFields, *moveProjResolution)
+		if err != nil {
+			fatal(err)
+		}
+		if len(fields) != 0 && !*moveProjRecreate {
+			fatal(errors.New("--resolution and --field only apply with --recreate"))
+		}
+
+		key := parseIssueArg(cmd, moveProj.Arg(0))
+		project := strings.ToUpper(moveProj.Arg(1))
+		if *moveProjRecreate {
+			result, err := cmd.Migrate(commands.MigrateInput{Key: key, Project: project, CloseOriginal: true, CloseFields: fields})
+			if result.Key != "" {
+				fmt.Fprintf(os.Stderr, "copied %d comments and %d attachments of %s\n", result.Comments, len(result.Attachments), result.Original)
+				fmt.Println(cmd.IssueRef(result.Key))
+			}
+			if err != nil {
+				fatal(err)
+			}
+			break
+		}
+
+		moved, err := cmd.MoveToProject(key, project)
+		if errors.Is(err, jiwa.ErrMovingUnavailable) {
+			fatal(fmt.Errorf("%w, pass --recreate to copy %s into %s, link both and close %s instead", err, key, project, key))
+		}
+		if err != nil {
+			fatal(err)
+		}
+		if moved != "" {
+			fmt.Println(cmd.IssueRef(moved))
+		}
+	case "move", "mv":
+		err := move.Parse(args)
+		if err != nil {
+			fmt.Printf("jiwa %s [--resolution|--field|--comment] <issue-id> <status>\n", subcommand)
+			fmt.Printf("jiwa %s [--resolution|--field|--comment] --next|--prev <issue-id>\n", subcommand)
+			fmt.Printf("echo \"<issue-id>\" | jiwa %s [--resolution|--field|--comment] <status>\n", subcommand)
+			fmt.Printf("echo \"<comment>\" | jiwa %s --comment - <issue-id> <status>\n", subcommand)
+			fmt.Printf("jiwa %s --path <issue-id> <status>\n", subcommand)
+			exit(1)
+		}
+
+		if *moveNext && *movePrev {
+			fatal(errors.New("--next and --prev can't be used together"))
+		}
+		step := *moveNext || *movePrev
+		if *movePath && (step || *moveJQL != "") {
+			fatal(errors.New("--path needs a status, it can't be used with --next, --prev or --jql"))
+		}
+
+		var status string
+		var issues []string
+		switch {
+		case *moveJQL != "":
+			if step && len(move.Args()) != 0 || !step && len(move.Args()) != 1 {
+				fmt.Printf("Usage: jiwa %s --jql <query> [--force] <status>|--next|--prev\n", subcommand)
+				exit(1)
+			}
+
+			status = move.Arg(0)
+		case (stat.Mode()&os.ModeCharDevice) == 0 && *moveComment != "-":
+			if len(move.Args()) == 0 && !step {
+				fmt.Printf("Usage: jiwa %s <status>\n", subcommand)
+				exit(1)
+			}
+
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			status = move.Arg(0)
+		default:
+			if step && len(move.Args()) != 1 {
+				fmt.Printf("Usage: jiwa %s --next|--prev <issueID>\n", subcommand)
+				exit(1)
+			}
+			if !step && len(move.Args()) < 2 {
+				fmt.Printf("Usage: jiwa %s <issueID> <status>\n", subcommand)
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, move.Arg(0))}
+			status = move.Arg(1)
+		}
+
+		if *movePath {
+			printMovePaths(cmd, issues, status)
+			return
+		}
+
+		fields, err := parseTransitionFlags(*moveFields, *moveResolution)
+		if err != nil {
+			fatal(err)
+		}
+
+		moveText, err := transitionComment(*moveComment)
+		if err != nil {
+			fatal(err)
+		}
+
+		if *moveJQL != "" {
+			moveQuery(cmd, *moveJQL, *moveForce, status, fields, moveText)
+		}
+
+		var movedIssues []string
+		if step {
+			movedIssues, err = cmd.Step(issues, *moveNext, fields, moveText)
+		} else {
+			movedIssues, err = cmd.Move(issues, status, fields, moveText)
+		}
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range movedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "parent":
+		err := parent.Parse(args)
+		if err != nil || len(parent.Args()) != 2 {
+			fmt.Println("Usage: jiwa parent <issue-id> <parent-id>")
+			fmt.Println("jiwa parent <issue-id> none")
+			exit(1)
+		}
+
+		issue := parseIssueArg(cmd, parent.Arg(0))
+		newParent := ""
+		if !strings.EqualFold(parent.Arg(1), "none") {
+			newParent = parseIssueArg(cmd, parent.Arg(1))
+		}
+
+		key, err := cmd.SetParent(issue, newParent)
+		if err != nil {
+			fatal(err)
+		}
+
+		fmt.Println(cmd.IssueRef(key))
+	case "queue":
+		err := queue.Parse(args)
+		if err != nil || len(queue.Args()) != 1 {
+			fmt.Println("Usage: jiwa queue [--flat] [--output <format>] <username>")
+			exit(1)
+		}
+
+		groups, err := cmd.Queue(queue.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+
+		format := outputFormat(queue, *queueOut)
+		err = printQueue(os.Stdout, groups, *queueFlat, format, outputOptions(cmd, format, true))
+		if err != nil {
+			fatal(err)
+		}
+	case "reassign":
+		err := reassign.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa reassign [--force] [--project <key>] <issue-id> <username>")
+			fmt.Println("echo \"<issue-id>\" | jiwa reassign <username>")
+			fmt.Println("jiwa reassign --round-robin <issue-id>...")
+			exit(1)
+		}
+
+		if *reassignProject != "" {
+			cmd.Config.DefaultProject = *reassignProject
+		}
+
+		if *reassignJQL != "" {
+			if *reassignRobin || len(reassign.Args()) != 1 {
+				fmt.Println("Usage: jiwa reassign --jql <query> [--force] <username>")
+				exit(1)
+			}
+
+			user := reassign.Arg(0)
+			issues := queryIssues(cmd, *reassignJQL, *reassignForce, func(n int) string { return fmt.Sprintf("reassign %d issues to %s", n, user) })
+			if !*reassignForce {
+				err = cmd.CheckAssignable(issues, user)
+				if err != nil {
+					fatal(err)
+				}
+			}
+
+			results, err := cmd.ForEachIssue(issues, jiwa.PermissionAssignIssues, func(key string) (bool, error) {
+				_, err := cmd.Reassign([]string{key}, user, true)
+				return err == nil, err
+			})
+			exitWithQueryResults(cmd, results, err, "reassigned")
+		}
+
+		if *reassignRobin {
+			issues := issueArgs(cmd, stat, reassign.Args(), "Usage: jiwa reassign --round-robin <issue-id>...")
+			assigned, err := cmd.ReassignRoundRobin(issues, *reassignForce)
+			for _, a := range assigned {
+				fmt.Fprintf(os.Stderr, "%s is assigned to %s\n", a.Key, a.Assignee)
+				fmt.Println(cmd.IssueRef(a.Key))
+			}
+			if err != nil {
+				fatal(err)
+			}
+			return
+		}
+
+		var user string
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if len(reassign.Args()) == 0 {
+				fmt.Println("Usage: jiwa reassign <username>")
+				exit(1)
+			}
+
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			user = reassign.Arg(0)
+		} else {
+			if len(reassign.Args()) < 2 {
+				fmt.Println("Usage: jiwa reassign <issue ID> <username>")
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, reassign.Arg(0))}
+			user = reassign.Arg(1)
+		}
+
+		reassignedIssues, err := cmd.Reassign(issues, user, *reassignForce)
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range reassignedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "snippets":
+		err := snippets.Parse(args)
+		if err != nil || len(snippets.Args()) != 0 {
+			fmt.Println("Usage: jiwa snippets")
+			exit(1)
+		}
+
+		all, err := cmd.Snippets()
+		if err != nil {
+			fatal(err)
+		}
+
+		printSnippets(os.Stdout, all, cmd.SnippetDir)
+	case "sprint":
+		err := sprint.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa sprint <issue-id> <sprint>")
+			fmt.Println("echo \"<issue-id>\" | jiwa sprint <sprint>")
+			exit(1)
+		}
+
+		var sprintName string
+		var issues []string
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if len(sprint.Args()) == 0 {
+				fmt.Println("Usage: jiwa sprint <sprint|current|next>")
+				exit(1)
+			}
+
+			issues, err = cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			sprintName = sprint.Arg(0)
+		} else {
+			if len(sprint.Args()) < 2 {
+				fmt.Println("Usage: jiwa sprint <issue ID> <sprint|current|next>")
+				exit(1)
+			}
+
+			issues = []string{parseIssueArg(cmd, sprint.Arg(0))}
+			sprintName = sprint.Arg(1)
+		}
+
+		sprintedIssues, err := cmd.Sprint(issues, sprintName)
+		if err != nil {
+			fatal(err)
+		}
+
+		if *globalOutput != "" {
+			sprinted := make([]jira.Issue, 0, len(sprintedIssues))
+			for _, key := range sprintedIssues {
+				sprinted = append(sprinted, jira.Issue{Key: key, Fields: &jira.IssueFields{}})
+			}
+			err = writeIssues(cmd, *globalOutput, sprinted)
+			if err != nil {
+				fatal(err)
+			}
+			break
+		}
+
+		for _, issue := range sprintedIssues {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "recent":
+		err := recent.Parse(args)
+		if err != nil {
+			fmt.Println("Usage: jiwa recent [--number]")
+			exit(1)
+		}
+
+		entries, err := cmd.Recent(*recentCount)
+		if err != nil {
+			fatal(err)
+		}
+
+		if *globalOutput != "" {
+			recentIssues := make([]jira.Issue, 0, len(entries))
+			for _, e := range entries {
+				recentIssues = append(recentIssues, jira.Issue{Key: e.Key, Fields: &jira.IssueFields{Summary: e.Summary}})
+			}
+			err = writeIssues(cmd, *globalOutput, recentIssues)
+			if err != nil {
+				fatal(err)
+			}
+			break
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', tabwriter.AlignRight)
+		fmt.Fprintf(w, "Ref\tID\tSummary\tURL\n")
+		for i, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", state.Ref(i), e.Key, e.Summary, cmd.ConstructIssueURL(e.Key))
+		}
+		w.Flush()
+	case "restore":
+		err := restore.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa restore [--force] <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa restore [--force]")
+			exit(1)
+		}
+
+		issues := issueArgs(cmd, stat, restore.Args(), "Usage: jiwa restore [--force] <issue-id>...")
+		if !*restoreForce {
+			err = cmd.ConfirmArchive("restore", issues)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		restored, err := cmd.Restore(issues)
+		exitWithArchived(cmd, restored, err)
+	case "search":
+		err := search.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa search [--count] [--output raw|table|json|ndjson] \"<jql query>\"")
+			exit(1)
+		}
+
+		if len(search.Args()) == 0 {
+			fmt.Println("jiwa search \"<jql query>\"")
+			exit(1)
+		}
+
+		if *searchCount {
+			n, err := cmd.Count(search.Arg(0))
+			if err != nil {
+				fatal(err)
+			}
+
+			fmt.Println(n)
+			return
+		}
+
+		format := outputFormat(search, *searchOut)
+		out, err := output.New(os.Stdout, format, outputOptions(cmd, format, true))
+		if err != nil {
+			fatal(err)
+		}
+
+		err = streamIssues(ctx, out, spinner, func(ctx context.Context, fn func(page []jira.Issue) error) error {
+			return cmd.SearchPages(ctx, search.Arg(0), fn, out.Fields())
+		})
+		if err != nil {
+			exitStreamError(err)
+		}
+	case "serve":
+		err := serveCmd.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa serve [--listen 127.0.0.1:7373|--socket <path>]")
+			exit(1)
+		}
+
+		l, err := serve.Listen(*serveListen, *serveSocket)
+		if err != nil {
+			fatal(err)
+		}
+
+		fmt.Fprintf(os.Stderr, "listening on %s, GET /help lists the routes, Ctrl-C stops\n", l.Addr())
+		err = serve.Serve(ctx, l, serve.Handler(cmd))
+		if err != nil {
+			fatal(err)
+		}
+	case "sync":
+		err := syncCmd.Parse(args)
+		if err != nil || len(syncCmd.Args()) != 0 {
+			fmt.Println("Usage: jiwa sync [--list|--force|--drop <number>,...]")
+			exit(1)
+		}
+
+		if cmd.Journal == nil {
+			fatal(errors.New("cannot locate the journal of queued changes"))
+		}
+
+		if len(*syncDrop) != 0 {
+			err = cmd.Journal.Update(func(j *offline.Journal) error {
+				return j.Drop(*syncDrop...)
+			})
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		if *syncList || len(*syncDrop) != 0 {
+			journal, err := cmd.Journal.Load()
+			if err != nil {
+				fatal(err)
+			}
+
+			printJournal(os.Stdout, journal.Entries, cmd.Location())
+			return
+		}
+
+		report, err := cmd.Sync(*syncForce)
+		printSyncReport(os.Stdout, os.Stderr, report, cmd.IssueRef)
+		if err != nil {
+			fatal(err)
+		}
+		if len(report.Held) != 0 {
+			exit(1)
+		}
+	case "tail":
+		err := tail.Parse(args)
+		if err != nil || len(tail.Args()) != 1 {
+			fmt.Println("Usage: jiwa tail [--interval 30s] [--exec <command>] <issue-id>")
+			exit(1)
+		}
+
+		if *tailInterval < minWatchInterval {
+			fatal(fmt.Errorf("--interval has to be at least %s", minWatchInterval))
+		}
+
+		key := parseIssueArg(cmd, tail.Arg(0))
+		loc := cmd.Location()
+		fmt.Fprintf(os.Stderr, "tailing %s, new comments and status changes show up below, Ctrl-C stops\n", key)
+		err = cmd.Tail(commands.TailInput{
+			Key:      key,
+			Interval: *tailInterval,
+			Exec:     *tailExec,
+		}, func(e commands.ActivityEvent) {
+			printTailEvent(os.Stdout, e, loc)
+		})
+		if err != nil {
+			fatal(err)
+		}
+	case "triage":
+		err := triage.Parse(args)
+		if err != nil {
+			fmt.Println("Usage: jiwa triage [--project|--jql]")
+			fmt.Println("echo \"<issue-id>\" | jiwa triage")
+			exit(1)
+		}
+
+		var issues []jira.Issue
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			keys, err := cmd.ReadIssueListFromStdin()
+			if err != nil {
+				fatal(err)
+			}
+
+			for _, k := range keys {
+				issue, err := cmd.Cat(k)
+				if err != nil {
+					fatal(err)
+				}
+				issues = append(issues, issue)
+			}
+		} else {
+			issues, err = cmd.TriageIssues(commands.TriageInput{Project: *triageProject, JQL: *triageJQL})
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		if len(issues) == 0 {
+			fmt.Fprintln(os.Stderr, "nothing to triage")
+			break
+		}
+
+		p, err := prompt.Open()
+		if err != nil {
+			fatal(err)
+		}
+		defer p.Close()
+
+		actions, err := cmd.Triage(issues, p)
+		for _, a := range actions {
+			fmt.Printf("%s\t%s\n", a.Key, a.Action)
+		}
+
+		if err != nil {
+			fatal(err)
+		}
+	case "unflag":
+		err := unflag.Parse(args)
+		if err != nil {
+			fmt.Println("jiwa unflag [--message <reason>] <issue-id>...")
+			fmt.Println("echo \"<issue-id>\" | jiwa unflag [--message <reason>]")
+			exit(1)
+		}
+
+		issues := issueArgs(cmd, stat, unflag.Args(), "Usage: jiwa unflag [--message <reason>] <issue-id>...")
+		unflagged, err := cmd.Unflag(issues, *unflagMessage)
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, issue := range unflagged {
+			fmt.Println(cmd.IssueRef(issue))
+		}
+	case "whoami":
+		err := whoami.Parse(args)
+		if err != nil || len(whoami.Args()) != 0 {
+			fmt.Println("Usage: jiwa whoami [--raw]")
+			exit(1)
+		}
+
+		account, info, err := cmd.Whoami()
+		if err != nil {
+			fatal(err)
+		}
+
+		if *whoamiRaw {
+			var raw bytes.Buffer
+			err = json.Indent(&raw, account.Raw, "", "  ")
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println(raw.String())
+			break
+		}
+
+		if *globalOutput != "" {
+			out, err := output.New(os.Stdout, *globalOutput, output.Options{})
+			if err == nil {
+				err = out.WriteKV(accountKV(account, info))
+			}
+			if err == nil {
+				err = out.Close()
+			}
+			if err != nil {
+				fatal(err)
+			}
+			break
+		}
+
+		err = printAccount(os.Stdout, account, info)
+		if err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// parseIssueArg resolves an issue argument to its key and exits if that
+// isn't possible, so commands never send garbage to the API.
+func parseIssueArg(cmd commands.Command, arg string) string {
+	key, err := cmd.ParseIssueArg(arg)
+	if err != nil {
+		fatal(err)
+	}
+
+	return key
+}
+
+// exitOnMissingPermission checks the permission before an editor is opened,
+// nobody wants to find out after writing the text. The check is cached, so
+// the command doesn't ask Jira again.
+func exitOnMissingPermission(cmd *commands.Command, permission string, issues ...string) {
+	err := cmd.RequirePermission(permission, issues...)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// exitOnInvalidVisibility checks the role of comment --visible-to before
+// the editor is opened
+func exitOnInvalidVisibility(cmd *commands.Command, issues ...string) {
+	err := cmd.CheckVisibility(issues...)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// queryIssues returns the issues the --jql query of a bulk change matches,
+// after asking whether to change them unless force is set. It exits if
+// nothing matches or the answer is no.
+func queryIssues(cmd commands.Command, jql string, force bool, question func(n int) string) []string {
+	issues, err := cmd.QueryKeys(jql)
+	if err != nil {
+		fatal(err)
+	}
+	if len(issues) == 0 {
+		fmt.Fprintln(os.Stderr, "no issues match the query, nothing to do")
+		exit(0)
+	}
+
+	if !force {
+		err = cmd.ConfirmQuery(question(len(issues)))
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	return issues
+}
+
+// moveQuery moves every issue the query matches to the status, or a step
+// along the workflow with --next and --prev, and exits
+func moveQuery(cmd commands.Command, jql string, force bool, status string, fields map[string]string, comment string) {
+	question := func(n int) string { return fmt.Sprintf("move %d issues to %s", n, status) }
+	if *moveNext || *movePrev {
+		direction := "forward"
+		if *movePrev {
+			direction = "back"
+		}
+		question = func(n int) string { return fmt.Sprintf("move %d issues a step %s", n, direction) }
+	}
+
+	issues := queryIssues(cmd, jql, force, question)
+	results, err := cmd.ForEachIssue(issues, jiwa.PermissionTransitionIssues, func(key string) (bool, error) {
+		var moved []string
+		var err error
+		if *moveNext || *movePrev {
+			moved, err = cmd.Step([]string{key}, *moveNext, fields, comment)
+		} else {
+			moved, err = cmd.Move([]string{key}, status, fields, comment)
+		}
+		return len(moved) != 0, err
+	})
+	exitWithQueryResults(cmd, results, err, "moved")
+}
+
+// printMovePaths prints the transitions from the status every issue is in
+// to the status for move --path, without moving any of them
+func printMovePaths(cmd commands.Command, issues []string, status string) {
+	for _, issue := range issues {
+		from, path, err := cmd.TransitionPath(issue, status)
+		if errors.Is(err, jiwa.ErrAlreadyInStatus) {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if err != nil {
+			fatal(err)
+		}
+
+		printTransitionPath(os.Stdout, issue, from, path)
+	}
+}
+
+// exitWithQueryResults prints the results of a bulk change and exits, with
+// 1 if any issue failed
+func exitWithQueryResults(cmd commands.Command, results []commands.QueryResult, err error, verb string) {
+	if err != nil {
+		fatal(err)
+	}
+
+	if printQueryResults(os.Stdout, os.Stderr, results, verb, cmd.IssueRef) != 0 {
+		exit(1)
+	}
+	exit(0)
+}
+
+// onExit reports the error jiwa exits with, once there is a client it
+// also writes the stats of its requests for -v and --log-format json
+var onExit = func(err error) {
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// fatal reports err and exits with 1
+func fatal(err error) {
+	onExit(err)
+	os.Exit(1)
+}
+
+// exit is os.Exit for everything after the client is set up, os.Exit
+// skips the deferred calls so the stats are written here
+func exit(code int) {
+	onExit(nil)
+	os.Exit(code)
+}
+
+// exitWithArchived prints the issues that were archived or restored before
+// the error, if any, so a bulk run can be picked up where it stopped
+func exitWithArchived(cmd commands.Command, done []string, err error) {
+	for _, issue := range done {
+		fmt.Println(cmd.IssueRef(issue))
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+	exit(0)
+}
+
+// issueArgs reads the issues from stdin if it is piped and from the
+// arguments otherwise, printing usage if there are none
+func issueArgs(cmd commands.Command, stat os.FileInfo, args []string, usage string) []string {
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		issues, err := cmd.ReadIssueListFromStdin()
+		if err != nil {
+			fatal(err)
+		}
+
+		return issues
+	}
+
+	if len(args) == 0 {
+		fmt.Println(usage)
+		exit(1)
+	}
+
+	issues := make([]string, 0, len(args))
+	for _, arg := range args {
+		issues = append(issues, parseIssueArg(cmd, arg))
+	}
+
+	return issues
+}
+
+// outputOptions looks up the Flagged and story points fields for every
+// output but raw, which only prints keys or links. Both are only
+// decoration, an instance that can't say which fields those are just
+// doesn't get them.
+func outputOptions(cmd commands.Command, format string, showProject bool) output.Options {
+	opts := output.Options{IssueURL: cmd.ConstructIssueURL, URLs: cmd.PrintURLs, ShowProject: showProject, Location: cmd.Location()}
+	if format == "raw" {
+		return opts
+	}
+
+	opts.FlaggedField, _ = cmd.FlaggedField()
+	opts.StoryPointsField, _ = cmd.StoryPointsField()
+	return opts
+}
+
+// dateFlag parses the day passed to the flag, the zero time if it wasn't
+func dateFlag(name, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := commands.ParseDate(value, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--%s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// outputFormat is the --output of the subcommand if it was passed, then the
+// global --output and otherwise the subcommand's default
+func outputFormat(fs *flag.FlagSet, local string) string {
+	if fs.Changed("output") || *globalOutput == "" {
+		return local
+	}
+
+	return *globalOutput
+}
+
+// writeIssues writes issues that were put together from what a subcommand
+// knows about them in the format, they only have the fields it knows
+func writeIssues(cmd commands.Command, format string, issues []jira.Issue) error {
+	out, err := output.New(os.Stdout, format, outputOptions(cmd, format, false))
+	if err != nil {
+		return err
+	}
+
+	err = out.WriteIssues(issues)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ownOutputFormat is outputFormat for the subcommands that write outputs of
+// their own instead of the issue outputs, formats starts with their output
+// for reading. A global --output of "raw" or "table" picks that one, any
+// other they don't have is an error instead of being ignored.
+func ownOutputFormat(subcommand string, fs *flag.FlagSet, local string, formats ...string) (string, error) {
+	format := outputFormat(fs, local)
+	if fs.Changed("output") || slices.Contains(formats, format) {
+		return format, nil
+	}
+	if format == "raw" || format == "table" {
+		return formats[0], nil
+	}
+
+	return "", fmt.Errorf("jiwa %s can't write --output %s, it writes %s", subcommand, format, strings.Join(formats, ", "))
+}
+
+// transitionComment reads the --comment of the transitioning commands from
+// stdin if it is "-".
+func transitionComment(flag string) (string, error) {
+	if flag != "-" {
+		return flag, nil
+	}
+
+	text, err := commands.ReadStdin()
+	if err != nil {
+		return "", err
+	}
+
+	comment := strings.TrimSpace(string(text))
+	if comment == "" {
+		return "", errors.New("the comment read from stdin is empty")
+	}
+
+	return comment, nil
+}
+
+// parseTransitionFlags merges the --field and --resolution flags of the
+// transitioning commands.
+func parseTransitionFlags(fieldFlags []string, resolution string) (map[string]string, error) {
+	fields, err := commands.ParseFieldFlags(fieldFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolution != "" {
+		fields["resolution"] = resolution
+	}
+
+	return fields, nil
 }
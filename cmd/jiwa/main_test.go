@@ -0,0 +1,2180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain lets the tests run the real main in a subprocess, so stdin,
+// stdout and the exit code can be controlled the same way a shell would.
+func TestMain(m *testing.M) {
+	if os.Getenv("JIWA_TEST_RUN_MAIN") == "1" {
+		for i, arg := range os.Args {
+			if arg == "--" {
+				os.Args = append([]string{"jiwa"}, os.Args[i+1:]...)
+				break
+			}
+		}
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+type result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// runJiwa runs jiwa against srv with a temporary config file and cache dir
+func runJiwa(t *testing.T, srv *jiratest.Server, stdin string, args ...string) result {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	cfgBytes, err := json.Marshal(map[string]any{
+		"baseURL":               srv.URL,
+		"username":              srv.Username,
+		"password":              srv.Password,
+		"defaultProject":        "JIWA",
+		"disableDuplicateCheck": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(cfgPath, cfgBytes, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return runJiwaEnv(t, dir, []string{"JIWA_CONFIG=" + cfgPath}, stdin, args...)
+}
+
+// runJiwaEnv runs jiwa with only the variables in env and home as its
+// home and cache dir
+func runJiwaEnv(t *testing.T, home string, env []string, stdin string, args ...string) result {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...)
+	cmd.Env = append([]string{
+		"JIWA_TEST_RUN_MAIN=1",
+		"HOME=" + home,
+		"XDG_CACHE_HOME=" + filepath.Join(home, "cache"),
+		"PATH=" + os.Getenv("PATH"),
+	}, env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+
+	return result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+}
+
+func TestSmoke(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InStdin     string
+		InArgs      []string
+		OutStdout   string
+		OutExitCode int
+		Check       func(t *testing.T, srv *jiratest.Server)
+	}{
+		{
+			Name:      "Cat",
+			InArgs:    []string{"cat", "JIWA-1"},
+			OutStdout: "Existing issue\nSome details",
+		},
+		{
+			Name:      "CatFields",
+			InArgs:    []string{"cat", "--fields", "status,summary", "JIWA-1"},
+			OutStdout: "Status: To Do\nExisting issue\n",
+		},
+		{
+			Name:      "CatPrintsTheMarkup",
+			InArgs:    []string{"--utc", "cat", "--comments", "JIWA-1"},
+			OutStdout: "Some details\nalice wrote on 2023-01-02 07:30:\n{{On}} it",
+		},
+		{
+			Name:      "ShowWithComments",
+			InArgs:    []string{"--utc", "show", "--comments", "JIWA-1"},
+			OutStdout: "alice wrote on 2023-01-02 07:30:\n`On` it",
+		},
+		{
+			Name:      "CatWithCommentsKeepsMarkup",
+			InArgs:    []string{"--utc", "cat", "--comments", "JIWA-1"},
+			OutStdout: "alice wrote on 2023-01-02 07:30:\n{{On}} it",
+		},
+		{
+			Name:      "CommentMessage",
+			InArgs:    []string{"comment", "-m", "looking into it", "JIWA-1"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "looking into it", issue.Fields.Comments.Comments[1].Body)
+			},
+		},
+		{
+			Name:        "CommentUnknownSnippet",
+			InArgs:      []string{"comment", "--snippet", "thanks", "JIWA-1"},
+			OutStdout:   `there is no snippet "thanks"`,
+			OutExitCode: 1,
+		},
+		{
+			Name:      "FiltersEmpty",
+			InArgs:    []string{"filter"},
+			OutStdout: "there are no saved filters",
+		},
+		{
+			Name:        "UnknownFilter",
+			InArgs:      []string{"list", "--filter", "triage"},
+			OutExitCode: 1,
+			OutStdout:   `unknown filter "triage", filters are set in "filters"`,
+		},
+		{
+			Name:        "PlainHTTPRefused",
+			InArgs:      []string{"--base-url", "http://jira.example.com", "cat", "JIWA-1"},
+			OutExitCode: 1,
+			OutStdout:   "Set \"insecureAllowHTTP\" in the config or pass --insecure-allow-http",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				assert.Empty(t, srv.Requests())
+			},
+		},
+		{
+			Name:      "SnippetsEmpty",
+			InArgs:    []string{"snippets"},
+			OutStdout: "there are no snippets",
+		},
+		{
+			Name:      "History",
+			InArgs:    []string{"history", "JIWA-1"},
+			OutStdout: "Alice\tstatus\tTo Do\tIn Progress",
+		},
+		{
+			Name:      "ActivityIssue",
+			InArgs:    []string{"activity", "--issue", "JIWA-1"},
+			OutStdout: "Alice moved JIWA-1 to In Progress\n",
+		},
+		{
+			Name:   "ActivityProjectDefaultsToADay",
+			InArgs: []string{"activity", "--author", "alice"},
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				query, err := url.ParseQuery(srv.Requests()[0].Query)
+				assert.NoError(t, err)
+				assert.Equal(t, "project=JIWA AND updated >= -1440m ORDER BY updated DESC", query.Get("jql"))
+			},
+		},
+		{
+			Name:      "Whoami",
+			InArgs:    []string{"whoami"},
+			OutStdout: "Account ID:",
+		},
+		{
+			Name:      "WhoamiJSON",
+			InArgs:    []string{"--output", "json", "whoami"},
+			OutStdout: "{\n  \"jiwaSchema\": 1,\n  \"displayName\": ",
+		},
+		{
+			Name:      "ShowCSV",
+			InArgs:    []string{"-o", "csv", "show", "JIWA-1"},
+			OutStdout: "key,project,summary,status,type,priority,assignee,reporter,labels,parent,flagged,storyPoints,created,updated,url\nJIWA-1,JIWA,Existing issue,",
+		},
+		{
+			Name:      "ShowShort",
+			InArgs:    []string{"show", "--short", "JIWA-1"},
+			OutStdout: "JIWA-1 [To Do] unassigned: Existing issue\n",
+		},
+		{
+			Name:      "ShowFull",
+			InArgs:    []string{"--utc", "show", "--full", "JIWA-1"},
+			OutStdout: "Some details\nalice wrote on 2023-01-02 07:30:\n`On` it\nLatest changes:\n",
+		},
+		{
+			Name:      "ShowFullJSON",
+			InArgs:    []string{"-o", "json", "show", "--full", "JIWA-1"},
+			OutStdout: "\"changes\": [\n    {\n      \"author\": \"Alice\",",
+		},
+		{
+			Name:        "ShowShortAndFull",
+			InArgs:      []string{"show", "--short", "--full", "JIWA-1"},
+			OutStdout:   "--short and --full can't be used together",
+			OutExitCode: 1,
+		},
+		{
+			Name:      "WhoamiRaw",
+			InArgs:    []string{"whoami", "--raw"},
+			OutStdout: `"name": "jiwa"`,
+		},
+		{
+			Name:      "CycleTimeCSV",
+			InArgs:    []string{"cycletime", "-o", "csv", "JIWA-1"},
+			OutStdout: "Key,Summary,To Do,In Progress\nJIWA-1,Existing issue,0.00,",
+		},
+		{
+			Name:      "GlobalOutputTableIsTheDefault",
+			InArgs:    []string{"-o", "table", "links", "JIWA-1"},
+			OutStdout: "",
+		},
+		{
+			Name:        "GlobalOutputTheCommandDoesNotHave",
+			InArgs:      []string{"-o", "ndjson", "dashboard"},
+			OutStdout:   "jiwa dashboard can't write --output ndjson, it writes text, json\n",
+			OutExitCode: 1,
+		},
+		{
+			Name:        "GlobalOutputHistory",
+			InArgs:      []string{"-o", "json", "history", "JIWA-1"},
+			OutStdout:   "jiwa history can't write --output json, it writes text\n",
+			OutExitCode: 1,
+		},
+		{
+			Name:      "GlobalOutputGrep",
+			InArgs:    []string{"-o", "csv", "grep", "Existing"},
+			OutStdout: "key,project,summary,status,type,priority,assignee,reporter,labels,parent,flagged,storyPoints,created,updated,url\nJIWA-1,JIWA,Existing issue,",
+		},
+		{
+			Name:      "CycleTimeJQL",
+			InArgs:    []string{"cycletime", "status", "=", "Done"},
+			OutStdout: "Median",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				query, err := url.ParseQuery(srv.Requests()[0].Query)
+				assert.NoError(t, err)
+				assert.Equal(t, "status = Done", query.Get("jql"))
+			},
+		},
+		{
+			Name:      "ListTable",
+			InArgs:    []string{"list", "--output", "table"},
+			OutStdout: "JIWA-1",
+		},
+		{
+			Name:      "ListTableMultipleProjects",
+			InArgs:    []string{"ls", "--project", "JIWA,OPS", "--output", "table"},
+			OutStdout: "Project",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				reqs := srv.Requests()
+				query, err := url.ParseQuery(reqs[len(reqs)-1].Query)
+				assert.NoError(t, err)
+				assert.Contains(t, query.Get("jql"), "project IN (JIWA,OPS)")
+			},
+		},
+		{
+			Name:      "ListCount",
+			InArgs:    []string{"list", "--count"},
+			OutStdout: "1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				for _, r := range srv.Requests() {
+					query, err := url.ParseQuery(r.Query)
+					assert.NoError(t, err)
+					assert.Equal(t, "0", query.Get("maxResults"), "fetched a page of issues")
+				}
+			},
+		},
+		{
+			Name:      "ListUpdatedByMe",
+			InArgs:    []string{"list", "--updated-by-me", "--count"},
+			OutStdout: "1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				reqs := srv.Requests()
+				query, err := url.ParseQuery(reqs[len(reqs)-1].Query)
+				assert.NoError(t, err)
+				assert.Equal(t, "project=JIWA AND issue in updatedBy(currentUser()) ORDER BY updated DESC, key DESC", query.Get("jql"))
+			},
+		},
+		{
+			Name:      "ListReportedByMe",
+			InArgs:    []string{"list", "--reported-by-me", "--count"},
+			OutStdout: "1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				reqs := srv.Requests()
+				query, err := url.ParseQuery(reqs[len(reqs)-1].Query)
+				assert.NoError(t, err)
+				assert.Contains(t, query.Get("jql"), "reporter=currentUser()")
+			},
+		},
+		{
+			Name:        "ListReportedByMeAndReporter",
+			InArgs:      []string{"list", "--reported-by-me", "--reporter", "alice"},
+			OutExitCode: 1,
+			OutStdout:   "--reported-by-me and --reporter can't be used together\n",
+		},
+		{
+			Name:      "ListShowReporter",
+			InArgs:    []string{"list", "--output", "table", "--show-reporter"},
+			OutStdout: "Reporter",
+		},
+		{
+			Name:      "GlobalFlagsBeforeSubcommand",
+			InArgs:    []string{"--request-timeout", "30s", "--project", "OTHER", "ls", "--count"},
+			OutStdout: "1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				reqs := srv.Requests()
+				query, err := url.ParseQuery(reqs[len(reqs)-1].Query)
+				assert.NoError(t, err)
+				assert.Contains(t, query.Get("jql"), "project=OTHER")
+			},
+		},
+		{
+			Name:      "Dashboard",
+			InArgs:    []string{"dashboard"},
+			OutStdout: "\nAssigned to you (1)\n  JIWA-1\tExisting issue\t",
+		},
+		{
+			Name:      "ImportCSV",
+			InStdin:   "summary,type\nImported issue,Task\n",
+			InArgs:    []string{"import", "--format", "csv"},
+			OutStdout: "JIWA-2\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, ok := srv.Issue("JIWA-2")
+				assert.True(t, ok)
+				assert.Equal(t, "Imported issue", issue.Fields.Summary)
+			},
+		},
+		{
+			Name:      "Export",
+			InArgs:    []string{"export", "--fields", "summary"},
+			OutStdout: `"key":"JIWA-1"`,
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				requests := srv.Requests()
+				query, err := url.ParseQuery(requests[len(requests)-1].Query)
+				assert.NoError(t, err)
+				assert.Equal(t, "project=JIWA ORDER BY key ASC", query.Get("jql"))
+				assert.Equal(t, "summary", query.Get("fields"))
+			},
+		},
+		{
+			Name:      "OfflineQueues",
+			InArgs:    []string{"--offline", "comment", "JIWA-1", "on it"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				assert.Empty(t, srv.Requests())
+			},
+		},
+		{
+			Name:      "SyncListEmpty",
+			InArgs:    []string{"sync", "--list"},
+			OutStdout: "nothing is queued",
+		},
+		{
+			Name:        "OverallTimeout",
+			InArgs:      []string{"--timeout", "1ns", "cat", "JIWA-1"},
+			OutStdout:   "context deadline exceeded",
+			OutExitCode: 1,
+		},
+		{
+			Name:        "UnknownCommand",
+			InArgs:      []string{"lss"},
+			OutStdout:   `unknown command "lss", did you mean "ls"?`,
+			OutExitCode: 2,
+		},
+		{
+			Name:      "SearchCount",
+			InArgs:    []string{"search", "--count", "project = JIWA"},
+			OutStdout: "1\n",
+		},
+		{
+			Name:      "Mine",
+			InArgs:    []string{"mine"},
+			OutStdout: "To Do (1)\n  JIWA-1",
+		},
+		{
+			Name:      "QueueFlat",
+			InArgs:    []string{"queue", "--flat", "alice"},
+			OutStdout: "Status",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				query, err := url.ParseQuery(srv.Requests()[0].Query)
+				assert.NoError(t, err)
+				assert.Equal(t, `assignee="alice" AND statusCategory != Done ORDER BY updated DESC`, query.Get("jql"))
+			},
+		},
+		{
+			Name:      "CreateFromStdin",
+			InStdin:   "New issue\n\nWith a description\n",
+			InArgs:    []string{"create"},
+			OutStdout: "JIWA-2\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, ok := srv.Issue("JIWA-2")
+				assert.True(t, ok)
+				assert.Equal(t, "New issue", issue.Fields.Summary)
+			},
+		},
+		{
+			Name:      "CreateFromStdinCRLF",
+			InStdin:   "Windows issue\r\n\r\nA hard break  \r\nthen more\r\n\t\r\n",
+			InArgs:    []string{"create"},
+			OutStdout: "JIWA-2\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-2")
+				assert.Equal(t, "Windows issue", issue.Fields.Summary)
+				assert.Equal(t, "\nA hard break  \nthen more", issue.Fields.Description)
+			},
+		},
+		{
+			Name:      "CreateFromStdinWithoutNewline",
+			InStdin:   "Piped issue\n\nNo newline at the end ",
+			InArgs:    []string{"create"},
+			OutStdout: "JIWA-2\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-2")
+				assert.Equal(t, "Piped issue", issue.Fields.Summary)
+				assert.Equal(t, "\nNo newline at the end ", issue.Fields.Description)
+			},
+		},
+		{
+			Name:        "IssueListLineTooLong",
+			InStdin:     strings.Repeat("JIWA", 20000) + "\n",
+			InArgs:      []string{"label", "release"},
+			OutStdout:   "failed to read in all tickets: bufio.Scanner: token too long\n",
+			OutExitCode: 1,
+		},
+		{
+			Name:        "CreateIn",
+			InStdin:     "First\nDetails\n=== issue ===\n" + strings.Repeat("long ", 60) + "\n=== issue ===\nThird\n",
+			InArgs:      []string{"create", "--in", "-", "--label", "plan"},
+			OutStdout:   "JIWA-2\nJIWA-3\n",
+			OutExitCode: 1,
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				first, _ := srv.Issue("JIWA-2")
+				assert.Equal(t, "First", first.Fields.Summary)
+				assert.Equal(t, "Details", first.Fields.Description)
+				assert.Equal(t, []string{"plan"}, first.Fields.Labels)
+				third, _ := srv.Issue("JIWA-3")
+				assert.Equal(t, "Third", third.Fields.Summary)
+				_, ok := srv.Issue("JIWA-4")
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name:        "CreateCheckDupes",
+			InStdin:     "Existing issue\n",
+			InArgs:      []string{"create", "--check-dupes"},
+			OutStdout:   "found possible duplicates",
+			OutExitCode: 1,
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				_, ok := srv.Issue("JIWA-2")
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name:      "CreateCheckDupesForce",
+			InStdin:   "Existing issue\n",
+			InArgs:    []string{"create", "--check-dupes", "--force"},
+			OutStdout: "JIWA-2\n",
+		},
+		{
+			Name:      "MoveFromStdin",
+			InStdin:   "JIWA-1\n",
+			InArgs:    []string{"move", "done"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "Done", issue.Fields.Status.Name)
+			},
+		},
+		{
+			Name:      "MovePath",
+			InArgs:    []string{"move", "--path", "JIWA-1", "done"},
+			OutStdout: "  1. \"Done\" to Done\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				for _, r := range srv.Requests() {
+					assert.NotEqual(t, "POST", r.Method, r.Path)
+				}
+			},
+		},
+		{
+			Name:      "MoveWithCommentFromStdin",
+			InStdin:   "Released in 1.2\n",
+			InArgs:    []string{"move", "--comment", "-", "JIWA-1", "done"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "Done", issue.Fields.Status.Name)
+				assert.Equal(t, "Released in 1.2", issue.Fields.Comments.Comments[1].Body)
+			},
+		},
+		{
+			Name:      "CloseWithComment",
+			InArgs:    []string{"close", "-m", "Duplicate of JIWA-7", "JIWA-1"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "Duplicate of JIWA-7", issue.Fields.Comments.Comments[1].Body)
+			},
+		},
+		{
+			Name:      "Comment",
+			InArgs:    []string{"comment", "JIWA-1", "looking into it"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "looking into it", issue.Fields.Comments.Comments[1].Body)
+			},
+		},
+		{
+			Name:      "EditAppendFromStdin",
+			InStdin:   "Progress note\n",
+			InArgs:    []string{"edit", "--append", "-", "JIWA-1"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, "Some details\nProgress note", issue.Fields.Description)
+			},
+		},
+		{
+			Name:      "LabelBareNumberWithProject",
+			InArgs:    []string{"label", "--project", "jiwa", "1", "urgent"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				issue, _ := srv.Issue("JIWA-1")
+				assert.Equal(t, []string{"urgent"}, issue.Fields.Labels)
+			},
+		},
+		{
+			Name:        "ReassignInvalidProject",
+			InArgs:      []string{"reassign", "--project", "1JIWA", "1", "alice"},
+			OutStdout:   `invalid issue key "1JIWA-1"`,
+			OutExitCode: 1,
+		},
+		{
+			Name:      "ParentNone",
+			InArgs:    []string{"parent", "JIWA-1", "none"},
+			OutStdout: "JIWA-1\n",
+			Check: func(t *testing.T, srv *jiratest.Server) {
+				reqs := srv.Requests()
+				assert.JSONEq(t, `{"fields": {"parent": null}}`, string(reqs[len(reqs)-1].Body))
+			},
+		},
+		{
+			Name:        "ParentMissing",
+			InArgs:      []string{"parent", "JIWA-1", "JIWA-2"},
+			OutStdout:   "failed to get the new parent JIWA-2",
+			OutExitCode: 1,
+		},
+		{
+			Name:        "MissingIssue",
+			InArgs:      []string{"cat", "JIWA-404"},
+			OutStdout:   "404",
+			OutExitCode: 1,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{
+				Key: "JIWA-1",
+				Fields: &jira.IssueFields{
+					Summary:     "Existing issue",
+					Description: "Some details",
+					Comments: &jira.Comments{Comments: []*jira.Comment{
+						{ID: "10000", Author: jira.User{Name: "alice"}, Created: "2023-01-02T09:30:00.000+0200", Body: "{{On}} it"},
+					}},
+				},
+				RenderedFields: &jira.IssueRenderedFields{
+					Description: "<p>Some details</p>",
+					Comments: &jira.Comments{Comments: []*jira.Comment{
+						{ID: "10000", Author: jira.User{Name: "alice"}, Created: "2023-01-02T09:30:00.000+0200", Body: "<p><tt>On</tt> it</p>"},
+					}},
+				},
+				Changelog: &jira.Changelog{Histories: []jira.ChangelogHistory{
+					{
+						Author:  jira.User{DisplayName: "Alice"},
+						Created: "2023-01-02",
+						Items:   []jira.ChangelogItems{{Field: "status", FromString: "To Do", ToString: "In Progress"}},
+					},
+				}},
+			})
+
+			res := runJiwa(t, srv, td.InStdin, td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stdout, td.OutStdout)
+			if td.Check != nil {
+				td.Check(t, srv)
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+	srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Migrate the database"}})
+
+	res := runJiwa(t, srv, "", "link", "JIWA-1", "blocks:JIWA-2")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+
+	res = runJiwa(t, srv, "", "links", "JIWA-1")
+	assert.Equal(t, "10001  blocks  JIWA-2  To Do  Migrate the database\n", res.Stdout)
+
+	res = runJiwa(t, srv, "JIWA-2\n", "links")
+	assert.Equal(t, "10001  is blocked by  JIWA-1  To Do  Deploy\n", res.Stdout)
+
+	res = runJiwa(t, srv, "", "link", "--remove", "JIWA-1", "is blocked by:JIWA-2")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stdout, `JIWA-1 doesn't have a link "is blocked by" to JIWA-2`)
+
+	res = runJiwa(t, srv, "", "link", "--remove", "JIWA-2", "is blocked by:JIWA-1")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+
+	res = runJiwa(t, srv, "", "links", "JIWA-1")
+	assert.Equal(t, "there are no links\n", res.Stdout)
+
+	res = runJiwa(t, srv, "", "link", "JIWA-1", "relates:JIWA-2")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+
+	res = runJiwa(t, srv, "", "link", "--remove", "10002")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+
+	res = runJiwa(t, srv, "", "links", "--output", "json", "JIWA-2")
+	assert.Equal(t, "[]\n", res.Stdout)
+}
+
+func TestApply(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+	manifest := filepath.Join(t.TempDir(), "breakdown.yaml")
+	err := os.WriteFile(manifest, []byte("issues:\n  - id: api\n    summary: Add the endpoint\n    blocks: [local:ui]\n  - id: ui\n    summary: Show it\n    blocks: [JIWA-1]\n"), 0o644)
+	assert.NoError(t, err)
+
+	res := runJiwa(t, srv, "", "apply", "--update-file", manifest)
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, "api: JIWA-2\nui: JIWA-3\n", res.Stdout)
+
+	b, err := os.ReadFile(manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, "issues:\n  - id: api\n    key: JIWA-2\n    summary: Add the endpoint\n    blocks: ['local:ui']\n  - id: ui\n    key: JIWA-3\n    summary: Show it\n    blocks: [JIWA-1]\n", string(b))
+
+	res = runJiwa(t, srv, "", "links", "JIWA-3")
+	assert.Equal(t, "10001  is blocked by  JIWA-2  To Do  Add the endpoint\n10002  blocks         JIWA-1  To Do  Deploy\n", res.Stdout)
+
+	// applying the annotated file again only changes what was edited
+	err = os.WriteFile(manifest, []byte(strings.Replace(string(b), "Show it", "Show the endpoint", 1)), 0o644)
+	assert.NoError(t, err)
+	res = runJiwa(t, srv, "", "apply", manifest)
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, "api: JIWA-2\nui: JIWA-3\n", res.Stdout)
+	issue, _ := srv.Issue("JIWA-3")
+	assert.Equal(t, "Show the endpoint", issue.Fields.Summary)
+
+	res = runJiwa(t, srv, "", "links", "JIWA-3")
+	assert.Equal(t, 2, strings.Count(res.Stdout, "\n"), res.Stdout)
+
+	res = runJiwa(t, srv, "", "apply", filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stdout, "failed to read the manifest")
+}
+
+func TestPermissionCheck(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+	srv.DenyPermissions("ADD_COMMENTS")
+
+	res := runJiwa(t, srv, "JIWA-1\n", "comment", "--message", "Done")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "you lack 'Add Comments' in project JIWA\n", res.Stdout)
+	for _, r := range srv.Requests() {
+		assert.NotEqual(t, "POST", r.Method, r.Path)
+	}
+
+	res = runJiwa(t, srv, "JIWA-1\n", "--skip-permission-check", "--url", "comment", "--message", "Done")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, srv.URL+"/browse/JIWA-1\n", res.Stdout)
+}
+
+func TestReassignQuery(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	for i := 1; i <= 3; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i), Fields: &jira.IssueFields{Assignee: &jira.User{Name: "bob"}}})
+	}
+	srv.AddIssue(jira.Issue{Key: "JIWA-4", Fields: &jira.IssueFields{Assignee: &jira.User{Name: "carol"}}})
+	srv.SetSearch(func(jql string, issues []jira.Issue) []jira.Issue {
+		return slices.DeleteFunc(issues, func(i jira.Issue) bool { return !strings.Contains(jql, "assignee="+i.Fields.Assignee.Name) })
+	})
+
+	// nobody is there to say yes
+	res := runJiwa(t, srv, "", "reassign", "--jql", "assignee=bob", "alice")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "cannot ask whether to reassign 3 issues to alice, pass --force to go ahead\n", res.Stdout)
+
+	res = runJiwa(t, srv, "", "reassign", "--jql", "assignee=bob", "-f", "alice")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, "JIWA-1\nJIWA-2\nJIWA-3\n", res.Stdout)
+	assert.Contains(t, res.Stderr, "reassigned 3 of 3 issues, 0 failed\n")
+	for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+		issue, _ := srv.Issue(key)
+		assert.Equal(t, "alice", issue.Fields.Assignee.Name, key)
+	}
+	issue, _ := srv.Issue("JIWA-4")
+	assert.Equal(t, "carol", issue.Fields.Assignee.Name)
+
+	res = runJiwa(t, srv, "", "reassign", "--jql", "assignee=bob", "-f", "alice")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, "no issues match the query, nothing to do\n", res.Stderr)
+
+	// the global --yes answers like --force, but alice has to be assignable
+	srv.AddUser(jira.User{Name: "alice", AccountID: "5b10a2844c20165700ede21g", DisplayName: "Alice Liddell"})
+	res = runJiwa(t, srv, "", "--yes", "reassign", "--jql", "assignee=carol", "alice")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Equal(t, "JIWA-4\n", res.Stdout)
+}
+
+func TestGlobalDryRun(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+
+	res := runJiwa(t, srv, "Crash on start\n", "-dry-run", "create")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	for _, r := range srv.Requests() {
+		assert.NotEqual(t, http.MethodPost, r.Method, r.Path)
+	}
+
+	// anything else would change Jira after all
+	res = runJiwa(t, srv, "", "--dry-run", "close", "JIWA-1")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "jiwa close can't --dry-run, it would change Jira, only apply, create, import, migrate, move-project can\n", res.Stdout)
+	for _, r := range srv.Requests() {
+		assert.NotEqual(t, http.MethodPost, r.Method, r.Path)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	home := t.TempDir()
+	cfgBytes, err := json.Marshal(map[string]any{
+		"baseURL":               srv.URL,
+		"username":              srv.Username,
+		"password":              srv.Password,
+		"defaultProject":        "JIWA",
+		"disableDuplicateCheck": true,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "jiwa"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".config", "jiwa", "work.json"), cfgBytes, 0o600))
+
+	res := runJiwaEnv(t, home, nil, "Crash on start\n", "-profile", "work", "create")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, "JIWA-1\n", res.Stdout)
+	assert.FileExists(t, filepath.Join(home, "cache", "jiwa", "work", "state.json"))
+	assert.NoDirExists(t, filepath.Join(home, "cache", "jiwa", "default"))
+
+	res = runJiwaEnv(t, home, []string{"JIWA_PROFILE=work"}, "", "cat", "@last")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Contains(t, res.Stdout, "Crash on start")
+
+	// the default profile has no config.json
+	res = runJiwaEnv(t, home, nil, "", "cat", "JIWA-1")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stdout, "There is no configuration file at "+filepath.Join(home, ".config", "jiwa", "config.json"))
+
+	res = runJiwaEnv(t, home, nil, "", "--profile", "../work", "cat", "JIWA-1")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "profile \"../work\" can only hold letters, digits, \"-\" and \"_\"\n", res.Stdout)
+}
+
+// TestPipeline chains commands the way a shell would, every command
+// prints one issue per line for the next to read, keys or --url links
+func TestPipeline(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddUser(jira.User{Name: "alice", AccountID: "5b10a2844c20165700ede21g", DisplayName: "Alice Liddell"})
+
+	res := runJiwa(t, srv, "Deploy\n=== issue ===\nVerify\n", "create", "--in", "-")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, "JIWA-1\nJIWA-2\n", res.Stdout)
+
+	res = runJiwa(t, srv, res.Stdout, "label", "release")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, "JIWA-1\nJIWA-2\n", res.Stdout)
+
+	res = runJiwa(t, srv, res.Stdout, "--url", "move", "done")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, fmt.Sprintf("%[1]s/browse/JIWA-1\n%[1]s/browse/JIWA-2\n", srv.URL), res.Stdout)
+
+	// links and keys mix
+	link, _, _ := strings.Cut(res.Stdout, "\n")
+	res = runJiwa(t, srv, link+"\nJIWA-2\n", "reassign", "alice")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, "JIWA-1\nJIWA-2\n", res.Stdout)
+
+	for _, key := range []string{"JIWA-1", "JIWA-2"} {
+		issue, _ := srv.Issue(key)
+		assert.Equal(t, []string{"release"}, issue.Fields.Labels, key)
+		assert.Equal(t, "Done", issue.Fields.Status.Name, key)
+		assert.Equal(t, "alice", issue.Fields.Assignee.Name, key)
+	}
+}
+
+func TestListStreamsPages(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InArgs      []string
+		OutStdout   string
+		OutRequests int
+		OutFields   string
+	}{
+		{
+			Name:        "Raw",
+			InArgs:      []string{"list"},
+			OutStdout:   "^JIWA-1\nJIWA-2\nJIWA-3\nJIWA-4\nJIWA-5\n$",
+			OutRequests: 3,
+			OutFields:   "key",
+		},
+		{
+			Name:        "RawURLs",
+			InArgs:      []string{"--url", "list"},
+			OutStdout:   "^http://.*/browse/JIWA-1\n(http://.*/browse/JIWA-[2-5]\n){4}$",
+			OutRequests: 3,
+			OutFields:   "key",
+		},
+		{
+			Name:        "Table",
+			InArgs:      []string{"ls", "--output", "table"},
+			OutStdout:   `ID\s+Summary\s+URL\n(.*JIWA-[1-5]\s+Issue [1-5]\s+\S+\n){5}$`,
+			OutRequests: 4,
+			OutFields:   "summary,security",
+		},
+		{
+			Name:        "JSON",
+			InArgs:      []string{"search", "--output", "json", "project = JIWA"},
+			OutStdout:   `^\[\n\{"jiwaSchema":1,"key":"JIWA-1".*\},\n(\{.*\},\n){3}\{.*"key":"JIWA-5".*\}\n\]\n$`,
+			OutRequests: 4,
+			OutFields:   "project,summary,status,issuetype,priority,assignee,reporter,labels,parent,created,updated,description",
+		},
+		{
+			Name:        "NDJSON",
+			InArgs:      []string{"list", "--output", "ndjson"},
+			OutStdout:   `^(\{"jiwaSchema":1,"key":"JIWA-[1-5]".*\}\n){5}$`,
+			OutRequests: 4,
+			OutFields:   "project,summary,status,issuetype,priority,assignee,reporter,labels,parent,created,updated,description",
+		},
+		{
+			Name:        "GlobalOutput",
+			InArgs:      []string{"-o", "template={{.Key}}: {{.Summary}}", "list"},
+			OutStdout:   "^JIWA-1: Issue 1\nJIWA-2: Issue 2\nJIWA-3: Issue 3\nJIWA-4: Issue 4\nJIWA-5: Issue 5\n$",
+			OutRequests: 4,
+			OutFields:   "project,summary,status,issuetype,priority,assignee,reporter,labels,parent,created,updated,description",
+		},
+		{
+			Name:        "OwnOutputWins",
+			InArgs:      []string{"-o", "json", "list", "-o", "csv"},
+			OutStdout:   "^key,project,summary,.*\nJIWA-1,JIWA,Issue 1,",
+			OutRequests: 4,
+			OutFields:   "project,summary,status,issuetype,priority,assignee,reporter,labels,parent,created,updated,description",
+		},
+		{
+			Name:        "Limit",
+			InArgs:      []string{"list", "--limit", "3"},
+			OutStdout:   "^JIWA-1\nJIWA-2\nJIWA-3\n$",
+			OutRequests: 2,
+			OutFields:   "key",
+		},
+		{
+			Name:        "LimitOnPageBoundary",
+			InArgs:      []string{"list", "--limit", "2"},
+			OutStdout:   "^JIWA-1\nJIWA-2\n$",
+			OutRequests: 1,
+			OutFields:   "key",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.PageSize = 2
+			for i := 1; i <= 5; i++ {
+				srv.AddIssue(jira.Issue{
+					Key:    fmt.Sprintf("JIWA-%d", i),
+					Fields: &jira.IssueFields{Summary: fmt.Sprintf("Issue %d", i)},
+				})
+			}
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Regexp(t, td.OutStdout, res.Stdout)
+			assert.Len(t, srv.Requests(), td.OutRequests)
+			for _, r := range srv.Requests() {
+				if strings.HasSuffix(r.Path, "/search") {
+					q, _ := url.ParseQuery(r.Query)
+					assert.Equal(t, td.OutFields, q.Get("fields"), "%s?%s", r.Path, r.Query)
+				}
+			}
+		})
+	}
+}
+
+func TestListOnlyFetchesPrintedFields(t *testing.T) {
+	responseSize := func(args ...string) int {
+		srv := jiratest.NewServer(t)
+		for i := 1; i <= 5; i++ {
+			srv.AddIssue(jira.Issue{
+				Key: fmt.Sprintf("JIWA-%d", i),
+				Fields: &jira.IssueFields{
+					Summary:     fmt.Sprintf("Issue %d", i),
+					Description: strings.Repeat("A long description. ", 50),
+				},
+			})
+		}
+
+		res := runJiwa(t, srv, "", args...)
+		assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+
+		size := 0
+		for _, r := range srv.Requests() {
+			size += r.ResponseSize
+		}
+		return size
+	}
+
+	everything := responseSize("list", "--output", "ndjson")
+	keys := responseSize("list")
+
+	assert.Less(t, keys*5, everything, "raw output should fetch a fraction of what ndjson fetches")
+}
+
+func TestListScope(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutStderr   string
+		OutJQL      string
+	}{
+		{
+			Name:      "DefaultProject",
+			InArgs:    []string{"list"},
+			OutStderr: "listing JIWA from \"defaultProject\", pass --project or --all-projects for others\njql: project=JIWA AND status=\"to do\" ORDER BY updated DESC, key DESC\n",
+			OutJQL:    `project=JIWA AND status="to do" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:   "Project",
+			InArgs: []string{"list", "--project", "OPS"},
+			OutJQL: `project=OPS AND status="to do" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:   "AllProjects",
+			InArgs: []string{"list", "--all-projects"},
+			OutJQL: `status="to do" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:        "NoDefaultProject",
+			InArgs:      []string{"list", "--no-default-project"},
+			OutExitCode: 1,
+			OutStdout:   "no project given and --no-default-project is set, pass --project or --all-projects\n",
+		},
+		{
+			Name:   "NoDefaultProjectWithAllProjects",
+			InArgs: []string{"list", "--no-default-project", "--all-projects"},
+			OutJQL: `status="to do" ORDER BY updated DESC, key DESC`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Equal(t, td.OutStderr, res.Stderr)
+			if td.OutExitCode != 0 {
+				assert.Equal(t, td.OutStdout, res.Stdout)
+				assert.Empty(t, srv.Requests())
+				return
+			}
+
+			var jql []string
+			for _, r := range srv.Requests() {
+				q, err := url.ParseQuery(r.Query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				jql = append(jql, q.Get("jql"))
+			}
+			assert.Equal(t, []string{td.OutJQL}, jql)
+		})
+	}
+}
+
+func TestFlag(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InFields    []jira.Field
+		InStdin     string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutFlagged  []string
+		OutComments map[string]string
+	}{
+		{
+			Name:        "PipedKeysWithReason",
+			InFields:    []jira.Field{flaggedField},
+			InStdin:     "JIWA-1\nJIWA-2\n",
+			InArgs:      []string{"flag", "-m", "Waiting on ops"},
+			OutStdout:   "^JIWA-1\nJIWA-2\n$",
+			OutFlagged:  []string{"JIWA-1", "JIWA-2", "JIWA-3"},
+			OutComments: map[string]string{"JIWA-1": "Waiting on ops", "JIWA-2": "Waiting on ops"},
+		},
+		{
+			Name:        "Unflag",
+			InFields:    []jira.Field{flaggedField},
+			InArgs:      []string{"unflag", "JIWA-3"},
+			OutStdout:   "^JIWA-3\n$",
+			OutFlagged:  []string{},
+			OutComments: map[string]string{},
+		},
+		{
+			Name:        "NoFlaggedField",
+			InArgs:      []string{"flag", "JIWA-1"},
+			OutExitCode: 1,
+			OutStdout:   `there is no Flagged field on this instance`,
+			OutFlagged:  []string{"JIWA-3"},
+			OutComments: map[string]string{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.SetFields(td.InFields...)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Migrate"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-3", Fields: &jira.IssueFields{
+				Summary:  "Blocked",
+				Unknowns: map[string]any{flaggedField.ID: []any{map[string]any{"value": "Impediment"}}},
+			}})
+
+			res := runJiwa(t, srv, td.InStdin, td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Regexp(t, td.OutStdout, res.Stdout)
+
+			flagged := make([]string, 0)
+			comments := make(map[string]string)
+			for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+				issue, _ := srv.Issue(key)
+				if commands.IsFlagged(issue, flaggedField.ID) {
+					flagged = append(flagged, key)
+				}
+				if issue.Fields.Comments != nil {
+					comments[key] = issue.Fields.Comments.Comments[0].Body
+				}
+			}
+			assert.Equal(t, td.OutFlagged, flagged)
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	many := strings.Repeat("JIWA-1\n", commands.ConfirmArchiveAbove+1)
+
+	testData := []struct {
+		Name        string
+		InArchiving bool
+		InStdin     string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutListed   string
+	}{
+		{
+			Name:        "PipedKeys",
+			InArchiving: true,
+			InStdin:     "JIWA-1\nhttps://jira.example.com/browse/JIWA-2\n",
+			InArgs:      []string{"archive"},
+			OutStdout:   "JIWA-1\nJIWA-2\n",
+			OutListed:   "",
+		},
+		{
+			Name:        "Restore",
+			InArchiving: true,
+			InArgs:      []string{"restore", "3"},
+			OutStdout:   "JIWA-3\n",
+			OutListed:   "JIWA-1\nJIWA-2\nJIWA-3\n",
+		},
+		{
+			Name:        "StopsAtMissingIssue",
+			InArchiving: true,
+			InArgs:      []string{"archive", "JIWA-1", "JIWA-9", "JIWA-2"},
+			OutExitCode: 1,
+			OutStdout:   "JIWA-1\nfailed to archive issue JIWA-9: failed to call API 404: ",
+			OutListed:   "JIWA-2\n",
+		},
+		{
+			Name:        "AsksAboveLimit",
+			InArchiving: true,
+			InStdin:     many,
+			InArgs:      []string{"archive"},
+			OutExitCode: 1,
+			OutStdout:   "cannot ask whether to archive 11 issues, pass --force to go ahead\n",
+			OutListed:   "JIWA-1\nJIWA-2\n",
+		},
+		{
+			Name:        "ForceAboveLimit",
+			InArchiving: true,
+			InStdin:     many,
+			InArgs:      []string{"archive", "--force"},
+			OutStdout:   many,
+			OutListed:   "JIWA-2\n",
+		},
+		{
+			Name:        "NoArchiving",
+			InArgs:      []string{"archive", "JIWA-1"},
+			OutExitCode: 1,
+			OutStdout:   "archiving not available on this Jira\n",
+			OutListed:   "JIWA-1\nJIWA-2\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.Archiving = true
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Migrate"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-3", Fields: &jira.IssueFields{Summary: "Old"}})
+			res := runJiwa(t, srv, "", "archive", "JIWA-3")
+			assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			srv.Archiving = td.InArchiving
+
+			res = runJiwa(t, srv, td.InStdin, td.InArgs...)
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.True(t, strings.HasPrefix(res.Stdout, td.OutStdout), res.Stdout)
+
+			res = runJiwa(t, srv, "", "list", "--status", "")
+			assert.Equal(t, td.OutListed, res.Stdout, "archived issues aren't listed")
+		})
+	}
+}
+
+func TestMoveProject(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InArgs       []string
+		OutExitCode  int
+		OutStdout    string
+		OutIssue     string
+		OutStatus    string
+	}{
+		{
+			Name:         "Move",
+			InDeployment: "Cloud",
+			InArgs:       []string{"move-project", "JIWA-1", "ops"},
+			OutStdout:    "^OPS-1\n$",
+			OutIssue:     "OPS-1",
+			OutStatus:    "To Do",
+		},
+		{
+			Name:         "ServerSuggestsRecreate",
+			InDeployment: "Server",
+			InArgs:       []string{"move-project", "JIWA-1", "OPS"},
+			OutExitCode:  1,
+			OutStdout:    "^moving issues between projects not available through the API of this Jira, pass --recreate to copy JIWA-1 into OPS, link both and close JIWA-1 instead\n$",
+		},
+		{
+			Name:         "Recreate",
+			InDeployment: "Server",
+			InArgs:       []string{"move-project", "--recreate", "JIWA-1", "OPS"},
+			OutStdout:    "^OPS-1\n$",
+			OutIssue:     "JIWA-1",
+			OutStatus:    "Done",
+		},
+		{
+			Name:        "FieldsNeedRecreate",
+			InArgs:      []string{"move-project", "-r", "Duplicate", "JIWA-1", "OPS"},
+			OutExitCode: 1,
+			OutStdout:   "--resolution and --field only apply with --recreate",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			if td.InDeployment != "" {
+				srv.DeploymentType = td.InDeployment
+			}
+			srv.AddProject(jira.Project{Key: "OPS", IssueTypes: []jira.IssueType{{ID: "10002", Name: "Task"}}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Project: jira.Project{Key: "JIWA"}, Summary: "Rotate the keys", Type: jira.IssueType{Name: "Task"}}})
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Regexp(t, td.OutStdout, res.Stdout)
+			if td.OutIssue == "" {
+				return
+			}
+			issue, ok := srv.Issue(td.OutIssue)
+			assert.True(t, ok)
+			assert.Equal(t, td.OutStatus, issue.Fields.Status.Name)
+		})
+	}
+}
+
+func TestListMarksFlagged(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.SetFields(flaggedField)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+	srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{
+		Summary:  "Blocked",
+		Unknowns: map[string]any{flaggedField.ID: []any{map[string]any{"value": "Impediment"}}},
+	}})
+
+	res := runJiwa(t, srv, "", "list", "--output", "table")
+
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Regexp(t, `JIWA-1\s+Deploy\s`, res.Stdout)
+	assert.Regexp(t, `JIWA-2\s+⚑ Blocked\s`, res.Stdout)
+}
+
+func TestVerbose(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InKey         string
+		InRateLimited int
+		OutExitCode   int
+		OutStdout     string
+		OutRequest    string
+		OutStats      string
+	}{
+		{
+			Name:       "Done",
+			InKey:      "JIWA-1",
+			OutStdout:  "Deploy",
+			OutRequest: `GET /rest/api/2/issue/JIWA-1 200 \S+`,
+			OutStats:   `0 failed, 0 retried`,
+		},
+		{
+			Name:        "Failed",
+			InKey:       "JIWA-9",
+			OutExitCode: 1,
+			OutStdout:   "Issue does not exist",
+			OutRequest:  `GET /rest/api/2/issue/JIWA-9 404 \S+`,
+			OutStats:    `1 failed, 0 retried`,
+		},
+		{
+			Name:          "RetriedUpToMaxRetries",
+			InKey:         "JIWA-1",
+			InRateLimited: 1,
+			OutStdout:     "Deploy",
+			OutRequest:    `GET /rest/api/2/issue/JIWA-1 429 \S+`,
+			OutStats:      `0 failed, 1 retried`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+			srv.RetryAfter = "0"
+			if td.InRateLimited != 0 {
+				srv.Fail(jiratest.FailRateLimited, td.InRateLimited)
+			}
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.json")
+			cfgBytes, err := json.Marshal(map[string]any{
+				"baseURL":    srv.URL,
+				"username":   srv.Username,
+				"password":   srv.Password,
+				"maxRetries": 1,
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(cfgPath, cfgBytes, 0o600))
+
+			res := runJiwaEnv(t, dir, []string{"JIWA_CONFIG=" + cfgPath}, "", "-v", "cat", td.InKey)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stdout, td.OutStdout)
+			assert.Regexp(t, `(?m)^`+td.OutRequest+`$`, res.Stderr)
+			assert.Regexp(t, fmt.Sprintf(`(?m)^%d requests to Jira in \S+, %s$`, len(srv.Requests()), td.OutStats), res.Stderr)
+		})
+	}
+}
+
+func TestLogFormat(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutMsgs     []string
+		OutError    string
+	}{
+		{
+			Name:    "JSONVerbose",
+			InArgs:  []string{"--log-format", "json", "-v", "cat", "JIWA-1"},
+			OutMsgs: []string{"request", "done"},
+		},
+		{
+			Name:    "JSONOnlyDone",
+			InArgs:  []string{"--log-format", "json", "cat", "JIWA-1"},
+			OutMsgs: []string{"done"},
+		},
+		{
+			Name:        "JSONFailed",
+			InArgs:      []string{"--log-format", "json", "cat", "JIWA-9"},
+			OutExitCode: 1,
+			OutMsgs:     []string{"failed", "done"},
+			OutError:    "Issue does not exist",
+		},
+		{
+			Name:        "JSONFailedBeforeTheClient",
+			InArgs:      []string{"--log-format", "json", "--config", "missing.json", "cat", "JIWA-1"},
+			OutExitCode: 1,
+			OutMsgs:     []string{"failed", "done"},
+			OutError:    "cannot locate configuration file",
+		},
+		{
+			Name:        "Unknown",
+			InArgs:      []string{"--log-format", "xml", "cat", "JIWA-1"},
+			OutExitCode: 1,
+			OutStdout:   "unknown --log-format \"xml\", use \"text\" or \"json\"\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			if td.OutExitCode != 0 {
+				assert.Equal(t, td.OutStdout, res.Stdout)
+				if td.OutMsgs == nil {
+					return
+				}
+			} else {
+				assert.Equal(t, "Deploy", strings.TrimSpace(res.Stdout))
+			}
+
+			var msgs, issues []string
+			for _, line := range strings.Split(strings.TrimSpace(res.Stderr), "\n") {
+				var entry map[string]any
+				err := json.Unmarshal([]byte(line), &entry)
+				if !assert.NoError(t, err, "not a JSON line: %s", line) {
+					continue
+				}
+				assert.Equal(t, "cat", entry["command"])
+				assert.Contains(t, entry, "level")
+				switch entry["msg"] {
+				case "request":
+					assert.Equal(t, "DEBUG", entry["level"])
+					assert.Contains(t, entry, "duration")
+				case "failed":
+					assert.Equal(t, "ERROR", entry["level"])
+					assert.Contains(t, entry["error"], td.OutError)
+				case "done":
+					assert.Contains(t, entry, "duration")
+				}
+				if issue, ok := entry["issue"].(string); ok {
+					issues = append(issues, issue)
+				}
+				msgs = append(msgs, entry["msg"].(string))
+			}
+			assert.Equal(t, td.OutMsgs, slices.Compact(msgs))
+			if slices.Contains(td.OutMsgs, "request") {
+				assert.Contains(t, issues, "JIWA-1")
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	pointsField := jira.Field{ID: "customfield_10016", Name: "Story Points", Custom: true}
+
+	testData := []struct {
+		Name        string
+		InStdin     string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutFields   map[string]any
+	}{
+		{
+			Name:      "PipedKeys",
+			InStdin:   "JIWA-1\nJIWA-2\n",
+			InArgs:    []string{"estimate", "5"},
+			OutStdout: "^JIWA-1\nJIWA-2\n$",
+			OutFields: map[string]any{"JIWA-1": 5.0, "JIWA-2": 5.0, "JIWA-3": 3.0},
+		},
+		{
+			Name:      "OriginalEstimate",
+			InArgs:    []string{"estimate", "--time", "JIWA-1", "1d4h"},
+			OutStdout: "^JIWA-1\n$",
+			OutFields: map[string]any{"JIWA-3": 3.0},
+		},
+		{
+			Name:        "DurationAsPoints",
+			InArgs:      []string{"estimate", "JIWA-1", "2d"},
+			OutExitCode: 1,
+			OutStdout:   "pass --time to set it as the original estimate",
+			OutFields:   map[string]any{"JIWA-3": 3.0},
+		},
+		{
+			Name:      "Sum",
+			InArgs:    []string{"estimate", "sum", "project = JIWA"},
+			OutStdout: "^3\n$",
+			OutFields: map[string]any{"JIWA-3": 3.0},
+		},
+		{
+			Name:      "ListColumn",
+			InArgs:    []string{"list", "--output", "table"},
+			OutStdout: `ID\s+Summary\s+Points\s+URL\n(.|\n)*JIWA-3\s+Sized\s+3\s`,
+			OutFields: map[string]any{"JIWA-3": 3.0},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.SetFields(pointsField)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Migrate"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-3", Fields: &jira.IssueFields{
+				Summary:  "Sized",
+				Unknowns: map[string]any{pointsField.ID: 3.0},
+			}})
+
+			res := runJiwa(t, srv, td.InStdin, td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Regexp(t, td.OutStdout, res.Stdout)
+
+			fields := make(map[string]any)
+			for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+				issue, _ := srv.Issue(key)
+				if v, ok := issue.Fields.Unknowns[pointsField.ID]; ok {
+					fields[key] = v
+				}
+			}
+			assert.Equal(t, td.OutFields, fields)
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+		OutStderr   string
+	}{
+		{
+			Name:      "Get",
+			InArgs:    []string{"config", "get", "defaultProject"},
+			OutStdout: "^JIWA\n$",
+		},
+		{
+			Name:      "SetPasswordWarns",
+			InArgs:    []string{"config", "set", "password", "hunter2"},
+			OutStdout: "^$",
+			OutStderr: `"password" is stored in plaintext in .*config.json, consider setting JIWA_PASSWORD in the environment instead`,
+		},
+		{
+			Name:        "UnknownKey",
+			InArgs:      []string{"config", "set", "baseUrl", "https://other.atlassian.net"},
+			OutExitCode: 1,
+			OutStdout:   `unknown key "baseUrl", did you mean "baseURL"\?`,
+		},
+		{
+			Name:        "MissingValue",
+			InArgs:      []string{"config", "set", "baseURL"},
+			OutExitCode: 1,
+			OutStdout:   `Usage: jiwa config`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Regexp(t, td.OutStdout, res.Stdout)
+			assert.Regexp(t, td.OutStderr, res.Stderr)
+			assert.Empty(t, srv.Requests())
+		})
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "OPS-1", Fields: &jira.IssueFields{Summary: "Rotate the keys"}})
+	env := []string{
+		"JIWA_BASE_URL=" + srv.URL,
+		"JIWA_USERNAME=" + srv.Username,
+		"JIWA_TOKEN=" + srv.Token,
+		"JIWA_DEFAULT_PROJECT=OPS",
+	}
+
+	// there is no configuration file at all
+	res := runJiwaEnv(t, t.TempDir(), env, "", "--url", "list")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, srv.URL+"/browse/OPS-1\n", res.Stdout)
+	search := srv.Requests()[len(srv.Requests())-1]
+	assert.Contains(t, search.Query, "project%3DOPS")
+
+	res = runJiwaEnv(t, t.TempDir(), env[1:], "", "list")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stdout, "create one or set JIWA_BASE_URL, JIWA_USERNAME and JIWA_TOKEN or JIWA_PASSWORD")
+
+	// a file that was asked for has to be there
+	res = runJiwaEnv(t, t.TempDir(), append(env, "JIWA_CONFIG=/nonexistent/config.json"), "", "list")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stdout, "cannot locate configuration file")
+}
+
+func TestCommandTimeout(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InTimeout   time.Duration
+		InArgs      []string
+		OutExitCode int
+	}{
+		{Name: "FromConfig", InTimeout: time.Nanosecond, InArgs: []string{"cat", "JIWA-1"}, OutExitCode: 1},
+		{Name: "FlagOverridesConfig", InTimeout: time.Nanosecond, InArgs: []string{"--timeout", "1m", "cat", "JIWA-1"}},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Existing issue"}})
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.json")
+			cfgBytes, err := json.Marshal(map[string]any{
+				"baseURL":        srv.URL,
+				"username":       srv.Username,
+				"password":       srv.Password,
+				"commandTimeout": td.InTimeout,
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(cfgPath, cfgBytes, 0o600))
+
+			res := runJiwaEnv(t, dir, []string{"JIWA_CONFIG=" + cfgPath}, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			if td.OutExitCode != 0 {
+				assert.Contains(t, res.Stdout, "context deadline exceeded")
+			}
+		})
+	}
+}
+
+func TestCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Login loops"}})
+	env := []string{
+		"JIWA_BASE_URL=" + srv.URL,
+		"JIWA_USERNAME=" + srv.Username,
+		"JIWA_TOKEN=" + srv.Token,
+		"JIWA_DEFAULT_PROJECT=JIWA",
+	}
+
+	// GIT_DIR keeps git from finding the repository the tests run in
+	repo := t.TempDir()
+	gitDir := "GIT_DIR=" + filepath.Join(repo, ".git")
+	res := runJiwaEnv(t, t.TempDir(), append(env, gitDir), "", "commits", "JIWA-1")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "not inside a git repository\n", res.Stdout)
+
+	for _, args := range [][]string{
+		{"init", "-q", repo},
+		{"-C", repo, "-c", "user.name=Alice", "-c", "user.email=alice@example.com", "commit", "-q", "--allow-empty", "-m", "Fix the redirect of JIWA-1"},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	res = runJiwaEnv(t, t.TempDir(), append(env, gitDir), "", "commits", "1")
+	assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+	assert.Equal(t, "JIWA-1\n", res.Stdout)
+	assert.Equal(t, "recorded 1 commit on JIWA-1\n", res.Stderr)
+	issue, _ := srv.Issue("JIWA-1")
+	assert.Regexp(t, `^Commits mentioning JIWA-1:\n\* \{\{[0-9a-f]{7,}\}\} Fix the redirect of JIWA-1\n$`, issue.Fields.Comments.Comments[0].Body)
+
+	res = runJiwaEnv(t, t.TempDir(), append(env, gitDir), "", "commits", "--range", "HEAD..HEAD", "JIWA-1")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "there are no commits in HEAD..HEAD, nothing to record on JIWA-1\n", res.Stdout)
+}
+
+func TestCompletion(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InArgs    []string
+		OutStdout string
+	}{
+		{
+			Name:      "Subcommand",
+			InArgs:    []string{"__complete", "li"},
+			OutStdout: "link\nlinks\nlist\n",
+		},
+		{
+			Name:      "SubcommandAfterGlobalFlags",
+			InArgs:    []string{"__complete", "--project", "OPS", "mi"},
+			OutStdout: "migrate\nmine\n",
+		},
+		{
+			Name:      "Label",
+			InArgs:    []string{"__complete", "label", "JIWA-1", "urgent", "o"},
+			OutStdout: "on-call\nops\n",
+		},
+		{
+			Name:      "LabelWithQuery",
+			InArgs:    []string{"__complete", "label", "--jql", "project = JIWA", ""},
+			OutStdout: "on-call\nops\nurgent\n",
+		},
+		{
+			Name:   "LabelIssue",
+			InArgs: []string{"__complete", "label", "-r", "o"},
+		},
+		{
+			Name:   "OtherCommand",
+			InArgs: []string{"__complete", "comment", "JIWA-1", "o"},
+		},
+		{
+			Name:      "Script",
+			InArgs:    []string{"completion", "bash"},
+			OutStdout: bashCompletion,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Labels: []string{"on-call", "ops", "urgent"}}})
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Equal(t, td.OutStdout, res.Stdout)
+		})
+	}
+}
+
+func TestWatchIssues(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InClear     bool
+		InFailFirst bool
+		OutStdout   string
+		OutLog      []string
+	}{
+		{
+			Name:      "Terminal",
+			InClear:   true,
+			OutStdout: regexp.QuoteMeta(clearScreen) + "every 1ms, last refresh \\d\\d:\\d\\d:\\d\\d, Ctrl-C stops\n\nrun 1\n",
+		},
+		{
+			Name:      "Piped",
+			OutStdout: "^run 1\nrun 2\nrun 3\n$",
+			OutLog:    []string{"every 1ms, last refresh", "every 1ms, last refresh", "every 1ms, last refresh"},
+		},
+		{
+			Name:        "FailedRefresh",
+			InFailFirst: true,
+			OutStdout:   "^run 2\nrun 3\n$",
+			OutLog:      []string{"every 1ms, last refresh", "refresh failed: Jira is down", "every 1ms, last refresh", "every 1ms, last refresh"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			runs := 0
+			var stdout, log bytes.Buffer
+			err := watchIssues(ctx, &stdout, &log, time.Millisecond, td.InClear, func(ctx context.Context, w io.Writer) error {
+				runs++
+				if runs == 4 {
+					// interrupted while fetching, nothing is drawn
+					cancel()
+					fmt.Fprintln(w, "half a page")
+					return ctx.Err()
+				}
+				if runs == 1 && td.InFailFirst {
+					return errors.New("Jira is down")
+				}
+				fmt.Fprintf(w, "run %d\n", runs)
+				return nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, 4, runs)
+			assert.Regexp(t, td.OutStdout, stdout.String())
+			assert.NotContains(t, stdout.String(), "half a page")
+
+			lines := make([]string, 0)
+			if log.Len() != 0 {
+				lines = strings.Split(strings.TrimSpace(log.String()), "\n")
+			}
+			assert.Len(t, lines, len(td.OutLog), log.String())
+			for i := range lines {
+				if i < len(td.OutLog) {
+					assert.True(t, strings.HasPrefix(lines[i], td.OutLog[i]), lines[i])
+				}
+			}
+		})
+	}
+}
+
+// flaggedField is the Flagged field of Jira Software
+var flaggedField = jira.Field{
+	ID:     "customfield_10021",
+	Name:   "Flagged",
+	Custom: true,
+	Schema: jira.FieldSchema{Custom: "com.atlassian.jira.plugin.system.customfieldtypes:multicheckboxes"},
+}
+
+func TestCreateEpic(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InArgs       []string
+		OutExitCode  int
+		OutStdout    string
+		OutEpicName  any
+	}{
+		{
+			Name:         "Cloud",
+			InDeployment: "Cloud",
+			InArgs:       []string{"create", "--type", "Epic"},
+			OutStdout:    "JIWA-1\n",
+			OutEpicName:  nil,
+		},
+		{
+			Name:         "ServerDefaultsToSummary",
+			InDeployment: "Server",
+			InArgs:       []string{"create", "--type", "Epic"},
+			OutStdout:    "JIWA-1\n",
+			OutEpicName:  "Migrate the database",
+		},
+		{
+			Name:         "ServerWithEpicName",
+			InDeployment: "Server",
+			InArgs:       []string{"create", "-t", "epic", "--epic-name", "DB migration"},
+			OutStdout:    "JIWA-1\n",
+			OutEpicName:  "DB migration",
+		},
+		{
+			Name:         "EpicNameOnTask",
+			InDeployment: "Server",
+			InArgs:       []string{"create", "--epic-name", "DB migration"},
+			OutExitCode:  1,
+			OutStdout:    `--epic-name only applies to epics, not to "Task"`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.DeploymentType = td.InDeployment
+			srv.SetFields(jira.Field{
+				ID:     "customfield_10011",
+				Name:   "Epic Name",
+				Custom: true,
+				Schema: jira.FieldSchema{Custom: "com.pyxis.greenhopper.jira:gh-epic-label"},
+			})
+
+			res := runJiwa(t, srv, "Migrate the database\n", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stdout, td.OutStdout)
+			if td.OutExitCode != 0 {
+				return
+			}
+
+			issue, ok := srv.Issue("JIWA-1")
+			assert.True(t, ok)
+			assert.Equal(t, td.OutEpicName, issue.Fields.Unknowns["customfield_10011"])
+		})
+	}
+}
+
+func TestCreateIssueType(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.SetCreateMeta("JIWA", "Task", nil)
+	srv.SetCreateMeta("JIWA", "Bug", map[string]jiratest.CreateField{
+		"summary":           {Name: "Summary", Required: true, Schema: map[string]string{"type": "string", "system": "summary"}},
+		"customfield_10050": {Name: "Platform", Required: true, Schema: map[string]string{"type": "string"}},
+	})
+
+	res := runJiwa(t, srv, "Crash on start\n", "create", "--type", "Story")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "project JIWA has no issue type \"Story\", it has Bug, Task\n", res.Stdout)
+	_, ok := srv.Issue("JIWA-1")
+	assert.False(t, ok)
+
+	res = runJiwa(t, srv, "Crash on start\n", "create", "--type", "Bug")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Contains(t, res.Stderr, "warning: JIWA requires Platform for a Bug, which jiwa doesn't send, pass --interactive to fill them in\n")
+
+	srv.SetCreateMeta("JIWA", "Task", map[string]jiratest.CreateField{
+		"description": {Name: "Description", Required: true, Schema: map[string]string{"type": "string", "system": "description"}},
+	})
+	res = runJiwa(t, srv, "Crash on start\n", "create")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Contains(t, res.Stderr, "warning: JIWA requires a description for a Task, Jira will likely refuse the issue without one\n")
+
+	res = runJiwa(t, srv, "Crash on start\nOn every start\n", "create")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.NotContains(t, res.Stderr, "requires a description")
+}
+
+func TestDiff(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InArgs      []string
+		OutExitCode int
+		OutStdout   string
+	}{
+		{
+			Name:   "Text",
+			InArgs: []string{"diff", "JIWA-1", "JIWA-2"},
+			OutStdout: "          JIWA-1           JIWA-2\n" +
+				"Status    To Do            In Progress\n" +
+				"Assignee  -                Alice\n" +
+				"Labels    backend, urgent  backend\n",
+		},
+		{
+			Name:   "All",
+			InArgs: []string{"diff", "--all", "JIWA-1", "JIWA-2"},
+			OutStdout: "                 JIWA-1           JIWA-2\n" +
+				"  Summary        Deploy the API   Deploy the API\n" +
+				"  Issue Type     Task             Task\n" +
+				"* Status         To Do            In Progress\n" +
+				"  Resolution     -                -\n",
+		},
+		{
+			Name:      "JSON",
+			InArgs:    []string{"diff", "-o", "json", "JIWA-1", "JIWA-2"},
+			OutStdout: "{\n  \"a\": \"JIWA-1\",\n  \"b\": \"JIWA-2\",\n  \"fields\": [\n    {\n      \"id\": \"status\",\n      \"name\": \"Status\",\n      \"a\": [\n        \"To Do\"\n      ],\n      \"b\": [\n        \"In Progress\"\n      ],\n      \"same\": false\n    },",
+		},
+		{
+			Name:   "GlobalTable",
+			InArgs: []string{"-o", "table", "diff", "JIWA-1", "JIWA-2"},
+			OutStdout: "          JIWA-1           JIWA-2\n" +
+				"Status    To Do            In Progress\n",
+		},
+		{
+			Name:        "GlobalCSV",
+			InArgs:      []string{"-o", "csv", "diff", "JIWA-1", "JIWA-2"},
+			OutExitCode: 1,
+			OutStdout:   "jiwa diff can't write --output csv, it writes text, json\n",
+		},
+		{
+			Name:      "Same",
+			InArgs:    []string{"diff", "JIWA-1", "JIWA-1"},
+			OutStdout: "JIWA-1 and JIWA-1 don't differ\n",
+		},
+		{
+			Name:        "OneIssue",
+			InArgs:      []string{"diff", "JIWA-1"},
+			OutExitCode: 1,
+			OutStdout:   "Usage: jiwa diff [--all] [--output text|json] <issue-id> <issue-id>\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Summary: "Deploy the API",
+				Type:    jira.IssueType{Name: "Task"},
+				Status:  &jira.Status{Name: "To Do"},
+				Labels:  []string{"urgent", "backend"},
+			}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{
+				Summary:  "Deploy the API",
+				Type:     jira.IssueType{Name: "Task"},
+				Status:   &jira.Status{Name: "In Progress"},
+				Assignee: &jira.User{DisplayName: "Alice"},
+				Labels:   []string{"backend"},
+			}})
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stdout, td.OutStdout)
+		})
+	}
+}
+
+func TestCreateMine(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InDeployment string
+		OutAssignee  map[string]any
+	}{
+		{
+			Name:         "Cloud",
+			InDeployment: "Cloud",
+			OutAssignee:  map[string]any{"accountId": "5b10a2844c20165700ede21g"},
+		},
+		{
+			Name:         "Server",
+			InDeployment: "Server",
+			OutAssignee:  map[string]any{"name": "jiwa"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.DeploymentType = td.InDeployment
+			srv.AddUser(jira.User{Name: srv.Username, AccountID: "5b10a2844c20165700ede21g", DisplayName: "Jiwa"})
+
+			res := runJiwa(t, srv, "Crash on start\n", "create", "--mine", "--type", "Bug", "--label", "crash")
+
+			assert.Equal(t, 0, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Equal(t, "JIWA-1\n", res.Stdout)
+			var created []map[string]any
+			for _, r := range srv.Requests() {
+				assert.NotEqual(t, http.MethodPut, r.Method, "assigned in a second request")
+				if r.Method == http.MethodPost && r.Path == "/rest/api/2/issue" {
+					var body struct {
+						Fields map[string]any `json:"fields"`
+					}
+					assert.NoError(t, json.Unmarshal(r.Body, &body))
+					created = append(created, body.Fields)
+				}
+			}
+			if assert.Len(t, created, 1) {
+				assert.Equal(t, td.OutAssignee, created[0]["assignee"])
+				assert.Equal(t, []any{"crash"}, created[0]["labels"])
+				assert.Equal(t, map[string]any{"name": "Bug"}, created[0]["issuetype"])
+			}
+		})
+	}
+}
+
+func TestSecurityLevel(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.SetSecurityLevels("JIWA", jiratest.SecurityLevel{ID: "10100", Name: "Public"}, jiratest.SecurityLevel{ID: "10101", Name: "Internal"})
+	srv.SetRoles("JIWA", "Developers", "Administrators")
+
+	res := runJiwa(t, srv, "Leaked key\n", "create", "--security", "Secret")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "project JIWA has no security level \"Secret\", it has Public, Internal\n", res.Stdout)
+
+	res = runJiwa(t, srv, "Leaked key\n", "create", "--no-dup-check", "--security", "internal")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+
+	res = runJiwa(t, srv, "", "show", "JIWA-1")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.True(t, strings.HasPrefix(res.Stdout, "🔒 Security Level: Internal\n"), res.Stdout)
+
+	res = runJiwa(t, srv, "", "list", "--output", "table")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	assert.Contains(t, res.Stdout, "🔒 Leaked key")
+
+	res = runJiwa(t, srv, "JIWA-1\n", "comment", "--visible-to", "role:Security", "the key is in the vault")
+	assert.Equal(t, 1, res.ExitCode)
+	assert.Equal(t, "project JIWA has no role \"Security\", it has Administrators, Developers\n", res.Stdout)
+
+	res = runJiwa(t, srv, "JIWA-1\n", "comment", "--visible-to", "role:developers", "the key is in the vault")
+	assert.Equal(t, 0, res.ExitCode, res.Stdout)
+	issue, _ := srv.Issue("JIWA-1")
+	assert.Equal(t, jira.CommentVisibility{Type: "role", Value: "Developers"}, issue.Fields.Comments.Comments[0].Visibility)
+}
+
+func TestComponents(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InStdin       string
+		InArgs        []string
+		OutExitCode   int
+		OutStdout     string
+		OutKey        string
+		OutComponents []string
+	}{
+		{
+			Name:          "Create",
+			InStdin:       "New issue\n",
+			InArgs:        []string{"create", "-c", "api", "--component", "FRONTEND"},
+			OutStdout:     "JIWA-2\n",
+			OutKey:        "JIWA-2",
+			OutComponents: []string{"api", "Frontend"},
+		},
+		{
+			Name:        "CreateUnknown",
+			InStdin:     "New issue\n",
+			InArgs:      []string{"create", "-c", "backend"},
+			OutExitCode: 1,
+			OutStdout:   `unknown component "backend" in JIWA, valid components are: Frontend,api`,
+		},
+		{
+			Name:          "SetFromStdin",
+			InStdin:       "JIWA-1\n",
+			InArgs:        []string{"component", "Frontend"},
+			OutStdout:     "JIWA-1\n",
+			OutKey:        "JIWA-1",
+			OutComponents: []string{"Frontend"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddProject(jira.Project{Key: "JIWA", Components: []jira.ProjectComponent{{Name: "api"}, {Name: "Frontend"}}})
+			srv.AddIssue(jira.Issue{
+				Key:    "JIWA-1",
+				Fields: &jira.IssueFields{Summary: "Existing issue", Components: []*jira.Component{{Name: "api"}}},
+			})
+
+			res := runJiwa(t, srv, td.InStdin, td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stdout, td.OutStdout)
+			if td.OutExitCode != 0 {
+				return
+			}
+
+			issue, ok := srv.Issue(td.OutKey)
+			assert.True(t, ok)
+			var components []string
+			for _, c := range issue.Fields.Components {
+				components = append(components, c.Name)
+			}
+			assert.Equal(t, td.OutComponents, components)
+		})
+	}
+}
+
+func TestCommentMentions(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InArgs       []string
+		OutExitCode  int
+		OutComment   string
+		OutStderr    string
+	}{
+		{
+			Name:         "Server",
+			InDeployment: "Server",
+			InArgs:       []string{"comment", "JIWA-1", "@alice please have a look"},
+			OutComment:   "[~alice] please have a look",
+		},
+		{
+			Name:         "Cloud",
+			InDeployment: "Cloud",
+			InArgs:       []string{"comment", "JIWA-1", "@alice please have a look"},
+			OutComment:   "[~accountid:5b10a2844c20165700ede21g] please have a look",
+		},
+		{
+			Name:         "NoMentions",
+			InDeployment: "Cloud",
+			InArgs:       []string{"comment", "--no-mentions", "JIWA-1", "@alice please have a look"},
+			OutComment:   "@alice please have a look",
+		},
+		{
+			Name:         "UnknownUser",
+			InDeployment: "Server",
+			InArgs:       []string{"comment", "JIWA-1", "@carol please have a look"},
+			OutComment:   "@carol please have a look",
+			OutStderr:    "warning: no user found for @carol, keeping it as it is",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.DeploymentType = td.InDeployment
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Existing issue"}})
+			srv.AddUser(jira.User{Name: "alice", AccountID: "5b10a2844c20165700ede21g", DisplayName: "Alice Liddell"})
+
+			res := runJiwa(t, srv, "", td.InArgs...)
+
+			assert.Equal(t, td.OutExitCode, res.ExitCode, "stdout: %s\nstderr: %s", res.Stdout, res.Stderr)
+			assert.Contains(t, res.Stderr, td.OutStderr)
+			issue, _ := srv.Issue("JIWA-1")
+			if td.OutExitCode != 0 {
+				assert.Nil(t, issue.Fields.Comments)
+				return
+			}
+			assert.Equal(t, td.OutComment, issue.Fields.Comments.Comments[0].Body)
+		})
+	}
+}
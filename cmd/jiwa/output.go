@@ -0,0 +1,949 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/internal/output"
+	"github.com/catouc/jiwa/internal/progress"
+	"github.com/catouc/jiwa/internal/render"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// streamIssues hands every page that fetch produces to out, the spinner
+// counts the issues fetched so far. Cancelling ctx with Ctrl-C stops the
+// search, out is closed either way so what was printed stays valid.
+func streamIssues(ctx context.Context, out output.Writer, spinner *progress.Spinner, fetch func(ctx context.Context, fn func(page []jira.Issue) error) error) error {
+	fetched := 0
+	err := fetch(ctx, func(page []jira.Issue) error {
+		fetched += len(page)
+		spinner.Status("fetched %d issues", fetched)
+		return out.WriteIssues(page)
+	})
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// minWatchInterval keeps --watch from hammering Jira
+const minWatchInterval = 5 * time.Second
+
+// watchIssues runs list every interval until ctx is cancelled, which is how
+// watching ends and not an error. On a terminal each refresh is rendered
+// off screen first and then replaces the last one, so the table doesn't
+// flicker while the next one is fetched. Piped output just gets the
+// refreshes one after the other with the time of each on log. A failed
+// refresh is shown and the next one tried.
+func watchIssues(ctx context.Context, w, log io.Writer, interval time.Duration, clear bool, list func(ctx context.Context, w io.Writer) error) error {
+	for {
+		var buf bytes.Buffer
+		err := list(ctx, &buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		header := fmt.Sprintf("every %s, last refresh %s, Ctrl-C stops", interval, time.Now().Format("15:04:05"))
+		if clear {
+			fmt.Fprintf(w, "%s%s\n\n", clearScreen, header)
+		} else {
+			fmt.Fprintln(log, header)
+		}
+		buf.WriteTo(w)
+		if err != nil {
+			fmt.Fprintf(log, "refresh failed: %s\n", err)
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// exitStreamError reports a failed streamIssues and exits, an interrupt
+// exits with 130 like other tools killed by SIGINT.
+func exitStreamError(err error) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "interrupted")
+		exit(130)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(os.Stderr, "gave up, --timeout was reached")
+		exit(1)
+	}
+
+	fatal(err)
+}
+
+// exportFile buffers an export on its way to a file, or to stdout for "-".
+// The file is written next to its destination and only replaces it once
+// the export is complete, so a failed run never clobbers the last backup.
+type exportFile struct {
+	*bufio.Writer
+	f    *os.File
+	path string
+}
+
+func createExportFile(path string) (*exportFile, error) {
+	if path == "-" {
+		return &exportFile{Writer: bufio.NewWriter(os.Stdout)}, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.partial")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	return &exportFile{Writer: bufio.NewWriter(f), f: f, path: path}, nil
+}
+
+// Commit flushes the export and moves it into place
+func (e *exportFile) Commit() error {
+	err := e.Flush()
+	if err != nil {
+		e.Abort()
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if e.f == nil {
+		return nil
+	}
+
+	err = e.f.Close()
+	if err != nil {
+		os.Remove(e.f.Name())
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return os.Rename(e.f.Name(), e.path)
+}
+
+// Abort throws away a partial export, stdout gets what was buffered so far
+func (e *exportFile) Abort() {
+	if e.f == nil {
+		e.Flush()
+		return
+	}
+
+	e.f.Close()
+	os.Remove(e.f.Name())
+}
+
+// exportProgress counts exported issues on w, rewriting a single line on a
+// terminal and printing a line per page otherwise
+type exportProgress struct {
+	w     io.Writer
+	tty   bool
+	total int
+	done  int
+}
+
+func newExportProgress(w *os.File, total int) *exportProgress {
+	stat, err := w.Stat()
+	tty := err == nil && (stat.Mode()&os.ModeCharDevice) != 0
+
+	return &exportProgress{w: w, tty: tty, total: total}
+}
+
+func (p *exportProgress) Add(n int) {
+	p.done += n
+	if p.tty {
+		fmt.Fprintf(p.w, "\rexported %d/%d issues", p.done, p.total)
+		return
+	}
+
+	fmt.Fprintf(p.w, "exported %d/%d issues\n", p.done, p.total)
+}
+
+// Done ends the progress line, it is called even if the export failed
+func (p *exportProgress) Done() {
+	if p.tty && p.done != 0 {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// printQueue prints the groups of mine and queue, flat drops the group
+// headers and prints the status as a column instead. Every other output
+// than the table writes the issues of all groups, the status is part of
+// the issue.
+func printQueue(w io.Writer, groups []commands.StatusGroup, flat bool, format string, opts output.Options) error {
+	if format != "table" {
+		out, err := output.New(w, format, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, g := range groups {
+			err = out.WriteIssues(g.Issues)
+			if err != nil {
+				return err
+			}
+		}
+		return out.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	if flat {
+		fmt.Fprintf(tw, "Status\tID\tSummary\tURL\n")
+	}
+	for _, g := range groups {
+		if !flat {
+			fmt.Fprintf(tw, "%s (%d)\n", g.Status, g.Count)
+		}
+		for _, i := range g.Issues {
+			if flat {
+				fmt.Fprintf(tw, "%s\t", g.Status)
+			} else {
+				fmt.Fprintf(tw, "  ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", i.Key, i.Fields.Summary, opts.IssueURL(i.Key))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// printDashboard prints the statuses and then every section with its
+// count and the issues that were fetched for it
+func printDashboard(w io.Writer, d commands.Dashboard, format string, issueURL func(key string) string) error {
+	switch format {
+	case "text":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown output %q, use \"text\" or \"json\"", format)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(tw, "%s\n", d.Project)
+	if len(d.Statuses) == 0 {
+		fmt.Fprintf(tw, "  nothing open\n")
+	}
+	for _, s := range d.Statuses {
+		fmt.Fprintf(tw, "  %s\t%d\n", s.Status, s.Count)
+	}
+
+	for _, s := range d.Sections {
+		fmt.Fprintf(tw, "\n%s (%d)\n", s.Title, s.Count)
+		for _, i := range s.Issues {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", i.Key, i.Fields.Summary, issueURL(i.Key))
+		}
+		if more := s.Count - len(s.Issues); more > 0 {
+			fmt.Fprintf(tw, "  ... and %d more\n", more)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// snippetPreviewLength is how much of the first line of a snippet is listed
+const snippetPreviewLength = 60
+
+// printSnippets lists the snippets with where they come from and the start
+// of their text
+func printSnippets(w io.Writer, snippets []commands.Snippet, dir string) {
+	if len(snippets) == 0 {
+		fmt.Fprintf(w, "there are no snippets, add them to \"snippets\" in the config or as files to %s\n", dir)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, s := range snippets {
+		preview, _, _ := strings.Cut(s.Text, "\n")
+		if r := []rune(preview); len(r) > snippetPreviewLength {
+			preview = string(r[:snippetPreviewLength-3]) + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Source, preview)
+	}
+	tw.Flush()
+}
+
+// printFilters lists the saved filters with their JQL
+func printFilters(w io.Writer, filters []commands.Filter) {
+	if len(filters) == 0 {
+		fmt.Fprintln(w, "there are no saved filters, add them to \"filters\" in the config")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, f := range filters {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Name, f.JQL)
+	}
+	tw.Flush()
+}
+
+// printTransitionPath prints the transitions of move --path numbered in the
+// order they are done, each with the status it ends in
+func printTransitionPath(w io.Writer, key string, from jira.Status, path []jiwa.IssueTransition) {
+	fmt.Fprintf(w, "%s is in %s:\n", key, from.Name)
+	for i, t := range path {
+		fmt.Fprintf(w, "  %d. %q to %s\n", i+1, t.Name, t.To.Name)
+	}
+}
+
+// printImportResults prints the created issues to out and the
+// failed rows with a summary to log, it returns how many rows failed
+func printImportResults(out, log io.Writer, results []commands.ImportResult, dryRun bool, issueRef func(key string) string) int {
+	created, failed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(log, "line %d: %s\n", r.Line, r.Err)
+		case r.Key != "":
+			created++
+			fmt.Fprintln(out, issueRef(r.Key))
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(log, "dry-run: %d rows would be created, %d rows failed\n", len(results)-failed, failed)
+	} else {
+		fmt.Fprintf(log, "created %d issues, %d rows failed\n", created, failed)
+	}
+
+	return failed
+}
+
+// printQueryResults prints the changed issues to out and the
+// failed ones with a summary to log, it returns how many issues failed
+func printQueryResults(out, log io.Writer, results []commands.QueryResult, verb string, issueRef func(key string) string) int {
+	changed, failed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(log, "%s: %s\n", r.Key, r.Err)
+		case r.Changed:
+			changed++
+			fmt.Fprintln(out, issueRef(r.Key))
+		}
+	}
+
+	fmt.Fprintf(log, "%s %d of %d issues, %d failed\n", verb, changed, len(results), failed)
+
+	return failed
+}
+
+// printApplyResults prints the key of every issue of the manifest as YAML
+// on out, so it can be kept as the mapping of the IDs to the keys, and the
+// failures and a summary on log. It returns the number of failed issues.
+func printApplyResults(out, log io.Writer, manifest commands.Manifest, results []commands.ApplyResult, dryRun bool) int {
+	counts := make(map[string]int)
+	failed := 0
+	for i, r := range results {
+		if r.Key != "" {
+			fmt.Fprintf(out, "%s: %s\n", r.ID, r.Key)
+		}
+
+		if r.Err != nil {
+			failed++
+			line := 0
+			if i < len(manifest.Issues) {
+				line = manifest.Issues[i].Line
+			}
+			fmt.Fprintf(log, "line %d: %s: %s\n", line, r.ID, r.Err)
+			continue
+		}
+		counts[r.Action]++
+	}
+
+	prefix, would := "", ""
+	if dryRun {
+		prefix, would = "dry-run: ", "would be "
+	}
+	fmt.Fprintf(log, "%s%d issues %screated, %d %supdated, %d unchanged, %d failed\n", prefix, counts[commands.ApplyCreated], would, counts[commands.ApplyUpdated], would, counts[commands.ApplyUnchanged], failed)
+
+	return failed
+}
+
+// writeImportResults writes which line of the input became which issue as
+// CSV, failed lines have the error instead of a key
+func writeImportResults(path string, results []commands.ImportResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"line", "key", "error"})
+	for _, r := range results {
+		errMsg := ""
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		w.Write([]string{strconv.Itoa(r.Line), r.Key, errMsg})
+	}
+	w.Flush()
+
+	err = w.Error()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	return nil
+}
+
+// printActivity prints one event per line, the date is left out for
+// events of the same day as now.
+func printActivity(w io.Writer, events []commands.ActivityEvent, format string, now time.Time) error {
+	switch format {
+	case "text":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	default:
+		return fmt.Errorf("unknown output %q, use \"text\" or \"json\"", format)
+	}
+
+	y, m, d := now.Date()
+	for _, e := range events {
+		t := e.Time.In(now.Location())
+		layout := "Jan 02 15:04"
+		if ty, tm, td := t.Date(); ty == y && tm == m && td == d {
+			layout = "15:04"
+		}
+		_, err := fmt.Fprintf(w, "%s %s\n", t.Format(layout), e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffCellWidth is how many characters of a value printDiff shows, json
+// has all of it
+const diffCellWidth = 50
+
+const (
+	colorRemoved = "\x1b[31m"
+	colorAdded   = "\x1b[32m"
+	colorReset   = "\x1b[0m"
+)
+
+// printDiff puts the fields of both issues next to each other, the values
+// of the first in red and of the second in green if color is set. With
+// fields that are the same among them, the ones that differ are marked
+// with a "*".
+func printDiff(w io.Writer, d commands.IssueDiff, format string, color bool) error {
+	switch format {
+	case "text":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown output %q, use \"text\" or \"json\"", format)
+	}
+
+	if len(d.Fields) == 0 {
+		_, err := fmt.Fprintf(w, "%s and %s don't differ\n", d.A, d.B)
+		return err
+	}
+
+	marked := slices.ContainsFunc(d.Fields, func(f commands.FieldDiff) bool { return f.Same })
+	rows := [][]string{{"", d.A, d.B}}
+	for _, f := range d.Fields {
+		rows = append(rows, []string{f.Name, diffCell(f.A), diffCell(f.B)})
+	}
+	widths := make([]int, 2)
+	for _, r := range rows {
+		for i := range widths {
+			widths[i] = max(widths[i], utf8.RuneCountInString(r[i]))
+		}
+	}
+
+	for i, r := range rows {
+		differs := i > 0 && !d.Fields[i-1].Same
+		a, b := pad(r[1], widths[1]), r[2]
+		if differs && color {
+			a, b = colorRemoved+a+colorReset, colorAdded+b+colorReset
+		}
+
+		line := pad(r[0], widths[0]) + "  " + a + "  " + b
+		if marked {
+			mark := "  "
+			if differs {
+				mark = "* "
+			}
+			line = mark + line
+		}
+		_, err := fmt.Fprintln(w, strings.TrimRight(line, " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffCell puts the values of a field on one line that is cut at
+// diffCellWidth, "-" stands for none
+func diffCell(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+
+	cell := strings.Join(strings.Fields(strings.Join(values, ", ")), " ")
+	if r := []rune(cell); len(r) > diffCellWidth {
+		cell = string(r[:diffCellWidth-1]) + "…"
+	}
+
+	return cell
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// printLinks lists the links of an issue with the ID that removes them
+func printLinks(w io.Writer, links []commands.IssueLink, format string) error {
+	switch format {
+	case "text":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(links)
+	default:
+		return fmt.Errorf("unknown output %q, use \"text\" or \"json\"", format)
+	}
+
+	if len(links) == 0 {
+		_, err := fmt.Fprintln(w, "there are no links")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, l := range links {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", l.ID, l.Description, l.Key, l.Status, l.Summary)
+	}
+	return tw.Flush()
+}
+
+// printTailEvent prints the event with the time it happened in loc,
+// comments follow in full and indented
+func printTailEvent(w io.Writer, e commands.ActivityEvent, loc *time.Location) {
+	t := e.Time.In(loc).Format("15:04:05")
+	if e.Kind != "comment" {
+		fmt.Fprintf(w, "%s %s\n", t, e)
+		return
+	}
+
+	fmt.Fprintf(w, "%s %s commented on %s:\n", t, e.Author, e.Key)
+	for _, line := range strings.Split(strings.TrimSpace(e.Comment), "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
+
+// printAccount lists what decides how Jira treats the user, Cloud only
+// knows users by account ID and may hide the e-mail address.
+func printAccount(w io.Writer, account jiwa.Account, info jiwa.ServerInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(tw, "Display name:\t%s\n", account.DisplayName)
+	if info.IsCloud() {
+		fmt.Fprintf(tw, "Account ID:\t%s\n", account.AccountID)
+	} else {
+		fmt.Fprintf(tw, "Username:\t%s\n", account.Name)
+		fmt.Fprintf(tw, "Key:\t%s\n", account.Key)
+	}
+
+	email := account.EmailAddress
+	if email == "" {
+		email = "hidden by the profile visibility settings"
+	}
+	fmt.Fprintf(tw, "E-mail:\t%s\n", email)
+
+	active := "yes"
+	if !account.Active {
+		active = "no, Jira won't let anyone assign issues to this account"
+	}
+	fmt.Fprintf(tw, "Active:\t%s\n", active)
+	fmt.Fprintf(tw, "Time zone:\t%s\n", account.TimeZone)
+	fmt.Fprintf(tw, "Groups:\t%s\n", orNone(strings.Join(account.Groups, ", ")))
+	fmt.Fprintf(tw, "Application roles:\t%s\n", orNone(strings.Join(account.ApplicationRoles, ", ")))
+	fmt.Fprintf(tw, "Jira:\t%s (%s %s)\n", info.BaseURL, info.DeploymentType, info.Version)
+
+	return tw.Flush()
+}
+
+// accountKV is the account for --output, with the values as Jira sends
+// them instead of the explanations printAccount adds
+func accountKV(account jiwa.Account, info jiwa.ServerInfo) []output.KV {
+	return []output.KV{
+		{Key: "displayName", Label: "Display name", Value: account.DisplayName},
+		{Key: "accountId", Label: "Account ID", Value: account.AccountID},
+		{Key: "name", Label: "Username", Value: account.Name},
+		{Key: "key", Label: "Key", Value: account.Key},
+		{Key: "emailAddress", Label: "E-mail", Value: account.EmailAddress},
+		{Key: "active", Label: "Active", Value: strconv.FormatBool(account.Active)},
+		{Key: "timeZone", Label: "Time zone", Value: account.TimeZone},
+		{Key: "groups", Label: "Groups", Value: strings.Join(account.Groups, ", ")},
+		{Key: "applicationRoles", Label: "Application roles", Value: strings.Join(account.ApplicationRoles, ", ")},
+		{Key: "baseURL", Label: "Jira", Value: info.BaseURL},
+		{Key: "deploymentType", Label: "Deployment type", Value: info.DeploymentType},
+		{Key: "version", Label: "Version", Value: info.Version},
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none visible"
+	}
+
+	return s
+}
+
+// printCycleTime prints a row per issue with a column per status and the
+// mean and median at the bottom. CSV has the durations in hours so
+// spreadsheets can do math on them.
+func printCycleTime(w io.Writer, report commands.CycleTimeReport, format string) error {
+	switch format {
+	case "table", "csv":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("unknown output %q, use \"table\", \"csv\" or \"json\"", format)
+	}
+
+	statuses := make([]string, 0, len(report.Statuses))
+	for _, s := range report.Statuses {
+		statuses = append(statuses, s.Status)
+	}
+
+	rows := [][]string{append([]string{"Key", "Summary"}, statuses...)}
+	for _, i := range report.Issues {
+		spent := make(map[string]time.Duration, len(i.Durations))
+		for _, d := range i.Durations {
+			spent[d.Status] = d.Duration
+		}
+
+		row := []string{i.Key, i.Summary}
+		for _, s := range statuses {
+			d, ok := spent[s]
+			row = append(row, cycleTimeCell(d, ok, format))
+		}
+		rows = append(rows, row)
+	}
+
+	mean, median := []string{"Mean", ""}, []string{"Median", ""}
+	for _, s := range report.Statuses {
+		mean = append(mean, cycleTimeCell(s.Mean, true, format))
+		median = append(median, cycleTimeCell(s.Median, true, format))
+	}
+	rows = append(rows, mean, median)
+
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		err := cw.WriteAll(rows)
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func cycleTimeCell(d time.Duration, ok bool, format string) string {
+	switch {
+	case !ok && format == "csv":
+		return ""
+	case !ok:
+		return "-"
+	case format == "csv":
+		return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+	default:
+		return formatDuration(d)
+	}
+}
+
+// formatDuration keeps the two largest units, "3d 4h" or "2h 5m"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// printView prints the summary and description as they are and the other
+// fields as "Name: value", fields without a value are left out
+func printView(view []commands.ViewField) {
+	for _, f := range view {
+		switch {
+		case f.Value == "":
+		case f.ID == "summary" || f.ID == "description":
+			fmt.Println(f.Value)
+		default:
+			fmt.Printf("%s: %s\n", f.Name, f.Value)
+		}
+	}
+}
+
+// flaggedMarker is put in front of flagged issues, red on a terminal
+func flaggedMarker(color bool) string {
+	if color {
+		return "\x1b[31m⚑\x1b[39m"
+	}
+
+	return "⚑"
+}
+
+// securityMarker is put in front of the security level, yellow on a
+// terminal
+func securityMarker(color bool) string {
+	if color {
+		return "\x1b[33m🔒\x1b[39m"
+	}
+
+	return "🔒"
+}
+
+// renderView replaces the description with the text of the HTML Jira
+// rendered it to, so "h2." and "{code}" don't show up in the terminal. The
+// view is left alone if the issue wasn't fetched with its rendered fields.
+func renderView(issue jira.Issue, view []commands.ViewField, color bool) {
+	if issue.RenderedFields == nil || issue.RenderedFields.Description == "" {
+		return
+	}
+
+	for i := range view {
+		if view[i].ID == "description" {
+			view[i].Value = render.JiraHTML(issue.RenderedFields.Description, render.Options{Color: color})
+		}
+	}
+}
+
+// printEstimates prints the story points and original estimate of the
+// issue, if it has them
+func printEstimates(w io.Writer, issue jira.Issue, pointsField string) {
+	if points := commands.FormatStoryPoints(issue, pointsField); points != "" {
+		fmt.Fprintf(w, "Story Points: %s\n", points)
+	}
+	if estimate := commands.OriginalEstimate(issue); estimate != "" {
+		fmt.Fprintf(w, "Original Estimate: %s\n", estimate)
+	}
+}
+
+// printComments prints the comments of the issue, rendered like the
+// description if Jira rendered them, with their time in loc
+func printComments(w io.Writer, issue jira.Issue, color bool, loc *time.Location) {
+	if issue.Fields == nil || issue.Fields.Comments == nil {
+		return
+	}
+
+	rendered := make(map[string]string)
+	if issue.RenderedFields != nil && issue.RenderedFields.Comments != nil {
+		for _, c := range issue.RenderedFields.Comments.Comments {
+			rendered[c.ID] = render.JiraHTML(c.Body, render.Options{Color: color})
+		}
+	}
+
+	for _, comment := range issue.Fields.Comments.Comments {
+		body, ok := rendered[comment.ID]
+		if !ok {
+			body = comment.Body
+		}
+		fmt.Fprintf(w, "%s wrote on %s:\n%s\n", comment.Author.Name, commands.FormatJiraTimestamp(comment.Created, loc), body)
+	}
+}
+
+// printFullDetail prints what show --full adds after the comments, a
+// section for every part that isn't empty
+func printFullDetail(w io.Writer, d commands.FullDetail, loc *time.Location) {
+	if len(d.Links) > 0 {
+		fmt.Fprintln(w, "Links:")
+		for _, l := range d.Links {
+			fmt.Fprintf(w, "  %s %s [%s] %s\n", l.Description, l.Key, l.Status, l.Summary)
+		}
+	}
+	if len(d.Subtasks) > 0 {
+		fmt.Fprintln(w, "Sub-tasks:")
+		for _, s := range d.Subtasks {
+			fmt.Fprintf(w, "  %s [%s] %s\n", s.Key, s.Status, s.Summary)
+		}
+	}
+	if len(d.Attachments) > 0 {
+		fmt.Fprintln(w, "Attachments:")
+		for _, a := range d.Attachments {
+			fmt.Fprintf(w, "  %s (%s, %d bytes) by %s on %s\n", a.Filename, a.MimeType, a.Size, a.Author, a.Created.In(loc).Format("2006-01-02 15:04"))
+		}
+	}
+	if len(d.Changes) > 0 {
+		fmt.Fprintln(w, "Latest changes:")
+		for _, c := range d.Changes {
+			for _, item := range c.Items {
+				fmt.Fprintf(w, "  %s %s changed %s from %q to %q\n", c.Created.In(loc).Format("2006-01-02 15:04"), c.Author, item.Field, item.From, item.To)
+			}
+		}
+	}
+}
+
+// printShort prints the single line of show --short
+func printShort(w io.Writer, issue jira.Issue) {
+	var status, assignee, summary string
+	if f := issue.Fields; f != nil {
+		summary = f.Summary
+		if f.Status != nil {
+			status = f.Status.Name
+		}
+		if f.Assignee != nil {
+			assignee = f.Assignee.DisplayName
+		}
+	}
+	if assignee == "" {
+		assignee = "unassigned"
+	}
+
+	fmt.Fprintf(w, "%s [%s] %s: %s\n", issue.Key, status, assignee, summary)
+}
+
+// printJournal lists the queued changes in the order they are sent, with
+// the reason the last sync held them back
+func printJournal(w io.Writer, entries []offline.Entry, loc *time.Location) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "nothing is queued")
+		return
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s (queued %s)\n", e, e.QueuedAt.In(loc).Format("2006-01-02 15:04"))
+		if e.Held != "" {
+			fmt.Fprintf(w, "    held: %s\n", e.Held)
+		}
+	}
+}
+
+// printSyncReport prints the issues that were changed to out like every
+// other command does, what happened to the queued changes goes to log
+func printSyncReport(out, log io.Writer, report offline.Report, issueRef func(key string) string) {
+	for _, a := range report.Applied {
+		if a.Entry.Op == offline.OpCreate {
+			fmt.Fprintf(log, "%s is %s\n", a.Entry.Placeholder, a.Key)
+		}
+		fmt.Fprintln(out, issueRef(a.Key))
+	}
+
+	for _, e := range report.Held {
+		fmt.Fprintf(log, "held %s: %s\n", e, e.Held)
+	}
+
+	fmt.Fprintf(log, "%d sent, %d still queued\n", len(report.Applied), len(report.Held))
+}
+
+// requestLogger prints every request to Jira as it finishes for --verbose,
+// requests run in parallel so the lines are written whole
+func requestLogger(w io.Writer) func(method, path string, status int, dur time.Duration, err error) {
+	var mu sync.Mutex
+	return func(method, path string, status int, dur time.Duration, err error) {
+		result := strconv.Itoa(status)
+		if err != nil {
+			result = err.Error()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%s %s %s %s\n", method, path, result, dur.Round(time.Millisecond))
+	}
+}
+
+func printStats(w io.Writer, stats jiwa.Stats) {
+	fmt.Fprintf(w, "%d requests to Jira in %s, %d failed, %d retried\n", stats.Requests, stats.Latency.Round(time.Millisecond), stats.Errors, stats.Retries)
+}
+
+// jsonLogger writes a JSON object per line for --log-format json, the
+// requests only show up with --verbose like in the text format
+func jsonLogger(w io.Writer, verbose bool) *slog.Logger {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+var pathIssueKeyRegEx = regexp.MustCompile(`/issue/([A-Z][A-Z0-9_]*-[0-9]+)\b`)
+
+// slogRequestLogger is requestLogger for --log-format json, requests
+// that got no response at all are errors and logged without --verbose too
+func slogRequestLogger(logger *slog.Logger) func(method, path string, status int, dur time.Duration, err error) {
+	return func(method, path string, status int, dur time.Duration, err error) {
+		level := slog.LevelDebug
+		attrs := []slog.Attr{
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.Int("status", status),
+			slog.Duration("duration", dur),
+		}
+		if m := pathIssueKeyRegEx.FindStringSubmatch(path); m != nil {
+			attrs = append(attrs, slog.String("issue", m[1]))
+		}
+		if err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		logger.LogAttrs(context.Background(), level, "request", attrs...)
+	}
+}
+
+// logStats is printStats for --log-format json, along with how long the
+// whole command took
+func logStats(logger *slog.Logger, dur time.Duration, stats jiwa.Stats) {
+	logger.Info("done",
+		slog.Duration("duration", dur),
+		slog.Int64("requests", stats.Requests),
+		slog.Int64("failed", stats.Errors),
+		slog.Int64("retried", stats.Retries),
+	)
+}
@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// DefaultActivitySince is how far back the activity of a project goes
+const DefaultActivitySince = 24 * time.Hour
+
+// jiraTimeLayouts are tried in order, the REST API uses the first one
+var jiraTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+type ActivityInput struct {
+	// Project or Issue, the project feed only looks at the issues that
+	// were updated within Since
+	Project string
+	Issue   string
+	// Since limits the feed to recent events, 0 means everything for an
+	// issue and DefaultActivitySince for a project
+	Since time.Duration
+	// Author only keeps events by this user, matched against the user
+	// name, display name and e-mail
+	Author string
+	// Now defaults to the current time
+	Now time.Time
+}
+
+// ActivityEvent is a change or a comment in the activity feed
+type ActivityEvent struct {
+	Time   time.Time `json:"time"`
+	Author string    `json:"author"`
+	Key    string    `json:"key"`
+	// Kind is either "change" or "comment"
+	Kind    string `json:"kind"`
+	Field   string `json:"field,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// String describes the event in a sentence without the time, like
+// "alice moved JIWA-9 to In Review"
+func (e ActivityEvent) String() string {
+	if e.Kind == "comment" {
+		return fmt.Sprintf("%s commented on %s: %s", e.Author, e.Key, commentPreview(e.Comment))
+	}
+
+	switch {
+	case e.Field == "status":
+		return fmt.Sprintf("%s moved %s to %s", e.Author, e.Key, e.To)
+	case e.Field == "assignee" && e.To == "":
+		return fmt.Sprintf("%s unassigned %s", e.Author, e.Key)
+	case e.Field == "assignee":
+		return fmt.Sprintf("%s assigned %s to %s", e.Author, e.Key, e.To)
+	case e.From == "":
+		return fmt.Sprintf("%s set %s of %s to %s", e.Author, e.Field, e.Key, e.To)
+	case e.To == "":
+		return fmt.Sprintf("%s cleared %s of %s", e.Author, e.Field, e.Key)
+	default:
+		return fmt.Sprintf("%s changed %s of %s from %s to %s", e.Author, e.Field, e.Key, e.From, e.To)
+	}
+}
+
+// commentPreview keeps the first line of a comment and cuts it short
+func commentPreview(comment string) string {
+	line, rest, _ := strings.Cut(strings.TrimSpace(comment), "\n")
+	runes := []rune(line)
+	if len(runes) > 60 {
+		return string(runes[:60]) + "…"
+	}
+	if rest != "" {
+		return line + " …"
+	}
+
+	return line
+}
+
+// Activity returns the changes and comments of the issue, or of all
+// recently updated issues in the project, newest first.
+func (c *Command) Activity(input ActivityInput) ([]ActivityEvent, error) {
+	if (input.Project == "") == (input.Issue == "") {
+		return nil, errors.New("need either a project or an issue for the activity")
+	}
+
+	now := input.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	since := input.Since
+	if since == 0 && input.Project != "" {
+		since = DefaultActivitySince
+	}
+
+	keys := []string{input.Issue}
+	if input.Project != "" {
+		jql := fmt.Sprintf("project=%s AND updated >= -%dm ORDER BY updated DESC", input.Project, int(since.Minutes()))
+		// only the keys are needed, the history is fetched per issue
+		issues, err := c.Client.Search(c.ctx(), jql, jiwa.WithFields("key"))
+		if err != nil {
+			return nil, fmt.Errorf("could not find recently updated issues: %w", err)
+		}
+
+		keys = keys[:0]
+		for _, i := range issues {
+			keys = append(keys, i.Key)
+		}
+	}
+
+	var cutoff time.Time
+	if since != 0 {
+		cutoff = now.Add(-since)
+	}
+
+	events := make([]ActivityEvent, 0)
+	for _, key := range keys {
+		issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("comment"), jiwa.WithExpand("changelog"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the activity of %s: %w", key, err)
+		}
+
+		for _, e := range issueEvents(issue) {
+			if !cutoff.IsZero() && e.Time.Before(cutoff) {
+				continue
+			}
+			if input.Author != "" && !matchesAuthor(e, issue, input.Author) {
+				continue
+			}
+			events = append(events, e)
+		}
+	}
+
+	sortEvents(events)
+
+	return events, nil
+}
+
+// issueEvents flattens the changelog and comments of an issue into events,
+// every item of a change becomes its own event.
+func issueEvents(issue jira.Issue) []ActivityEvent {
+	events := make([]ActivityEvent, 0)
+	if issue.Changelog != nil {
+		for _, h := range issue.Changelog.Histories {
+			for _, item := range h.Items {
+				events = append(events, ActivityEvent{
+					Time:   parseJiraTime(h.Created),
+					Author: userName(h.Author),
+					Key:    issue.Key,
+					Kind:   "change",
+					Field:  item.Field,
+					From:   item.FromString,
+					To:     item.ToString,
+				})
+			}
+		}
+	}
+
+	if issue.Fields != nil && issue.Fields.Comments != nil {
+		for _, comment := range issue.Fields.Comments.Comments {
+			if comment == nil {
+				continue
+			}
+			events = append(events, ActivityEvent{
+				Time:    parseJiraTime(comment.Created),
+				Author:  userName(comment.Author),
+				Key:     issue.Key,
+				Kind:    "comment",
+				Comment: comment.Body,
+			})
+		}
+	}
+
+	return events
+}
+
+// sortEvents puts the newest event first, events at the same time keep the
+// order of their issue and the order they were made in.
+func sortEvents(events []ActivityEvent) {
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].Time.After(events[b].Time)
+	})
+}
+
+// matchesAuthor compares the author against everything Jira knows about
+// the user who made the change, the event only keeps the display name.
+func matchesAuthor(e ActivityEvent, issue jira.Issue, author string) bool {
+	if strings.EqualFold(e.Author, author) {
+		return true
+	}
+
+	users := make([]jira.User, 0)
+	if issue.Changelog != nil {
+		for _, h := range issue.Changelog.Histories {
+			users = append(users, h.Author)
+		}
+	}
+	if issue.Fields != nil && issue.Fields.Comments != nil {
+		for _, comment := range issue.Fields.Comments.Comments {
+			if comment != nil {
+				users = append(users, comment.Author)
+			}
+		}
+	}
+
+	for _, u := range users {
+		if userName(u) != e.Author {
+			continue
+		}
+		for _, v := range []string{u.Name, u.DisplayName, u.EmailAddress, u.AccountID} {
+			if v != "" && strings.EqualFold(v, author) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func userName(u jira.User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+
+	return u.Name
+}
+
+// parseJiraTime returns the zero time for timestamps it doesn't understand,
+// those events end up at the bottom of the feed.
+func parseJiraTime(s string) time.Time {
+	for _, layout := range jiraTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// ParseSince parses a duration like time.ParseDuration does, with "d" for
+// days and "w" for weeks on top since that is what activity is looked at in.
+func ParseSince(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q, use something like 90m, 12h, 2d or 1w", s)
+		}
+		return d, nil
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q, use something like 90m, 12h, 2d or 1w", s)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// ParseSinceOrDate takes a duration like ParseSince or a day like
+// ParseDate, a day is how long ago it started at now
+func ParseSinceOrDate(s string, now time.Time) (time.Duration, error) {
+	d, err := ParseSince(s)
+	if err == nil {
+		return d, nil
+	}
+
+	t, err := ParseDate(s, now)
+	switch {
+	case err != nil:
+		return 0, err
+	case t.After(now):
+		return 0, fmt.Errorf("%q is in the future, nothing happened since then yet", s)
+	default:
+		return now.Sub(t), nil
+	}
+}
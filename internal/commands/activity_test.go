@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	alice = jira.User{Name: "alice", DisplayName: "Alice", EmailAddress: "alice@example.com"}
+	bob   = jira.User{Name: "bob", DisplayName: "Bob"}
+)
+
+func change(created string, author jira.User, field, from, to string) jira.ChangelogHistory {
+	return jira.ChangelogHistory{
+		Author:  author,
+		Created: created,
+		Items:   []jira.ChangelogItems{{Field: field, FromString: from, ToString: to}},
+	}
+}
+
+func issueWithActivity(key string, histories []jira.ChangelogHistory, comments ...*jira.Comment) jira.Issue {
+	return jira.Issue{
+		Key:       key,
+		Fields:    &jira.IssueFields{Comments: &jira.Comments{Comments: comments}},
+		Changelog: &jira.Changelog{Histories: histories},
+	}
+}
+
+func TestCommand_Activity(t *testing.T) {
+	now := time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC)
+	issues := []jira.Issue{
+		issueWithActivity("JIWA-3",
+			[]jira.ChangelogHistory{
+				change("2024-05-02T13:00:00.000+0000", alice, "assignee", "", "Bob"),
+				change("2024-04-20T09:00:00.000+0000", bob, "summary", "old", "new"),
+			},
+			&jira.Comment{Author: bob, Body: "looks good\nmerging it", Created: "2024-05-02T13:50:00.000+0000"},
+		),
+		issueWithActivity("JIWA-9",
+			[]jira.ChangelogHistory{
+				change("2024-05-02T14:02:00.000+0200", alice, "status", "To Do", "In Review"),
+				change("2024-05-02T14:02:00.000+0000", alice, "status", "In Review", "Done"),
+			},
+		),
+	}
+
+	testData := []struct {
+		Name      string
+		InInput   ActivityInput
+		OutJQL    string
+		OutFeed   []string
+		OutErrMsg string
+	}{
+		{
+			Name:    "Project",
+			InInput: ActivityInput{Project: "JIWA"},
+			OutJQL:  "project=JIWA AND updated >= -1440m ORDER BY updated DESC",
+			OutFeed: []string{
+				"Alice moved JIWA-9 to Done",
+				"Bob commented on JIWA-3: looks good …",
+				"Alice assigned JIWA-3 to Bob",
+				"Alice moved JIWA-9 to In Review",
+			},
+		},
+		{
+			Name:    "ProjectSince",
+			InInput: ActivityInput{Project: "JIWA", Since: 90 * time.Minute},
+			OutJQL:  "project=JIWA AND updated >= -90m ORDER BY updated DESC",
+			OutFeed: []string{
+				"Alice moved JIWA-9 to Done",
+				"Bob commented on JIWA-3: looks good …",
+			},
+		},
+		{
+			Name:    "IssueHasNoDefaultSince",
+			InInput: ActivityInput{Issue: "JIWA-3"},
+			OutFeed: []string{
+				"Bob commented on JIWA-3: looks good …",
+				"Alice assigned JIWA-3 to Bob",
+				"Bob changed summary of JIWA-3 from old to new",
+			},
+		},
+		{
+			Name:    "AuthorByEmail",
+			InInput: ActivityInput{Project: "JIWA", Author: "ALICE@example.com"},
+			OutJQL:  "project=JIWA AND updated >= -1440m ORDER BY updated DESC",
+			OutFeed: []string{
+				"Alice moved JIWA-9 to Done",
+				"Alice assigned JIWA-3 to Bob",
+				"Alice moved JIWA-9 to In Review",
+			},
+		},
+		{
+			Name:    "AuthorByName",
+			InInput: ActivityInput{Issue: "JIWA-3", Author: "bob"},
+			OutFeed: []string{
+				"Bob commented on JIWA-3: looks good …",
+				"Bob changed summary of JIWA-3 from old to new",
+			},
+		},
+		{
+			Name:      "NeitherProjectNorIssue",
+			OutErrMsg: "need either a project or an issue for the activity",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var jql string
+			fake := jiwafake.New()
+			fake.SearchFunc = func(q string) ([]jira.Issue, error) {
+				jql = q
+				return issues, nil
+			}
+			for _, i := range issues {
+				fake.Issues[i.Key] = i
+			}
+			c := Command{Client: fake}
+
+			td.InInput.Now = now
+			events, err := c.Activity(td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutJQL, jql)
+
+			feed := make([]string, 0, len(events))
+			for _, e := range events {
+				feed = append(feed, e.String())
+			}
+			assert.Equal(t, td.OutFeed, feed)
+		})
+	}
+}
+
+func TestActivityEvent_String(t *testing.T) {
+	testData := []struct {
+		Name    string
+		InEvent ActivityEvent
+		Out     string
+	}{
+		{
+			Name:    "Unassigned",
+			InEvent: ActivityEvent{Author: "bob", Key: "JIWA-1", Kind: "change", Field: "assignee", From: "Alice"},
+			Out:     "bob unassigned JIWA-1",
+		},
+		{
+			Name:    "Set",
+			InEvent: ActivityEvent{Author: "bob", Key: "JIWA-1", Kind: "change", Field: "labels", To: "infra"},
+			Out:     "bob set labels of JIWA-1 to infra",
+		},
+		{
+			Name:    "Cleared",
+			InEvent: ActivityEvent{Author: "bob", Key: "JIWA-1", Kind: "change", Field: "labels", From: "infra"},
+			Out:     "bob cleared labels of JIWA-1",
+		},
+		{
+			Name:    "LongComment",
+			InEvent: ActivityEvent{Author: "bob", Key: "JIWA-1", Kind: "comment", Comment: "this comment goes on and on and on and on and on and on and on and on"},
+			Out:     "bob commented on JIWA-1: this comment goes on and on and on and on and on and on and …",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, td.InEvent.String())
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		Out       time.Duration
+		OutErrMsg string
+	}{
+		{Name: "Hours", In: "12h", Out: 12 * time.Hour},
+		{Name: "Days", In: "2d", Out: 48 * time.Hour},
+		{Name: "Weeks", In: "1w", Out: 7 * 24 * time.Hour},
+		{Name: "Invalid", In: "xd", OutErrMsg: `invalid duration "xd", use something like 90m, 12h, 2d or 1w`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			d, err := ParseSince(td.In)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, d)
+		})
+	}
+}
+
+func TestParseSinceOrDate(t *testing.T) {
+	now := time.Date(2024, 7, 3, 15, 30, 0, 0, time.UTC)
+
+	testData := []struct {
+		Name      string
+		In        string
+		Out       time.Duration
+		OutErrMsg string
+	}{
+		{Name: "Duration", In: "2d", Out: 48 * time.Hour},
+		{Name: "Yesterday", In: "yesterday", Out: 39*time.Hour + 30*time.Minute},
+		{Name: "Date", In: "2024-07-01", Out: 63*time.Hour + 30*time.Minute},
+		{Name: "Future", In: "tomorrow", OutErrMsg: `"tomorrow" is in the future, nothing happened since then yet`},
+		{Name: "Invalid", In: "soon", OutErrMsg: `invalid date "soon", use e.g. today, yesterday, tomorrow, next friday, last monday, 2024-07-01, -3d or +2w`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			d, err := ParseSinceOrDate(td.In, now)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, d)
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// What Apply did to an issue of the manifest
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+)
+
+// ApplyResult is what became of an issue of the manifest. Key is set once
+// the issue exists, Err can be set along with it when the parent or a link
+// couldn't be set afterwards.
+type ApplyResult struct {
+	ID     string
+	Key    string
+	Action string
+	Err    error
+}
+
+// Apply creates the issues of the manifest that don't have a key yet and
+// updates the fields of the ones that do, so applying the same manifest
+// twice doesn't create anything twice. Parents are handled before their
+// children and the blocks links are added once all issues exist, links
+// are only ever added. onKey is called for every created issue, to record
+// the key before anything else can fail. The results are in the order of
+// the manifest.
+func (c *Command) Apply(m Manifest, onKey func(id, key string)) []ApplyResult {
+	order, err := m.order()
+	if err != nil {
+		return []ApplyResult{{Err: err}}
+	}
+
+	index := make(map[string]int, len(m.Issues))
+	results := make([]ApplyResult, len(m.Issues))
+	for i, issue := range m.Issues {
+		index[issue.ID] = i
+		results[i] = ApplyResult{ID: issue.ID, Key: issue.Key}
+	}
+
+	a := applier{c: c, ctx: c.ctx(), m: m, index: index, results: results}
+	for _, i := range order {
+		issue := m.Issues[i]
+		if issue.Key == "" {
+			results[i].Action = ApplyCreated
+			results[i].Key, results[i].Err = a.create(issue)
+			if results[i].Key != "" && onKey != nil {
+				onKey(issue.ID, results[i].Key)
+			}
+			continue
+		}
+
+		results[i].Action, results[i].Err = a.update(issue)
+	}
+
+	for i, issue := range m.Issues {
+		if results[i].Key == "" || len(issue.Blocks) == 0 {
+			continue
+		}
+
+		err := a.link(results[i].Key, issue.Blocks)
+		if err != nil {
+			results[i].Err = errors.Join(results[i].Err, err)
+		}
+	}
+
+	return results
+}
+
+// applier keeps the keys of the issues that were applied so far
+type applier struct {
+	c       *Command
+	ctx     context.Context
+	m       Manifest
+	index   map[string]int
+	results []ApplyResult
+}
+
+// resolve turns a reference of the manifest into the key of the issue
+func (a applier) resolve(ref string) (string, error) {
+	id, local := strings.CutPrefix(ref, localRefPrefix)
+	if !local {
+		return a.c.ParseIssueArg(ref)
+	}
+
+	key := a.results[a.index[id]].Key
+	if key == "" && !a.c.DryRun {
+		return "", fmt.Errorf("%s doesn't exist, it failed", ref)
+	}
+
+	return key, nil
+}
+
+// isEpicRef tells whether the parent is an epic, those are set after the
+// creation since Server puts issues into epics through the Epic Link
+func (a applier) isEpicRef(ref, key string) (bool, error) {
+	if id, local := strings.CutPrefix(ref, localRefPrefix); local {
+		return isEpic(a.m.Issues[a.index[id]].Type), nil
+	}
+
+	parent, err := a.c.Client.GetIssue(a.ctx, key, jiwa.WithFields("issuetype"))
+	if err != nil {
+		return false, fmt.Errorf("failed to get the parent %s: %w", key, err)
+	}
+
+	return isEpic(parent.Fields.Type.Name), nil
+}
+
+func (a applier) create(issue ManifestIssue) (string, error) {
+	project := issue.Project
+	if project == "" {
+		project = a.m.Project
+	}
+	if project == "" {
+		project = a.c.Config.DefaultProject
+	}
+
+	input := jiwa.CreateIssueInput{
+		Project:     strings.ToUpper(project),
+		Summary:     issue.Summary,
+		Description: issue.Description,
+		Labels:      issue.Labels,
+		Assignee:    issue.Assignee,
+		Type:        issue.Type,
+		Fields:      make(map[string]any),
+	}
+	if input.Type == "" {
+		input.Type = "Task"
+	}
+
+	parent, epic := "", false
+	if issue.Parent != "" {
+		var err error
+		parent, err = a.resolve(issue.Parent)
+		if err != nil {
+			return "", fmt.Errorf("not created, its parent %w", err)
+		}
+
+		if !a.c.DryRun {
+			epic, err = a.isEpicRef(issue.Parent, parent)
+			if err != nil {
+				return "", err
+			}
+		}
+		if !epic {
+			input.Parent = parent
+		}
+	}
+
+	if isEpic(input.Type) {
+		fields, err := a.c.epicFields(a.ctx, "", issue.Summary)
+		if err != nil {
+			return "", err
+		}
+		for id, v := range fields {
+			input.Fields[id] = v
+		}
+	}
+
+	err := a.estimateFields(issue.Estimate, input.Fields)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := a.c.importRow(a.ctx, ImportRow{Line: issue.Line, Input: input})
+	if err != nil || key == "" {
+		return "", err
+	}
+	a.c.rememberIssue(key, issue.Summary)
+
+	if epic {
+		_, err = a.c.SetParent(key, parent)
+		if err != nil {
+			return key, fmt.Errorf("created but not put into %s: %w", parent, err)
+		}
+	}
+
+	return key, nil
+}
+
+// estimateFields adds the fields that set the estimate, story points go
+// into the story points field and times into the original estimate
+func (a applier) estimateFields(estimate string, fields map[string]any) error {
+	if estimate == "" {
+		return nil
+	}
+
+	points, duration, err := parseManifestEstimate(estimate)
+	if err != nil {
+		return err
+	}
+
+	if duration != "" {
+		fields["timetracking"] = map[string]string{"originalEstimate": duration}
+		return nil
+	}
+
+	field, err := a.c.StoryPointsField()
+	if err != nil {
+		return err
+	}
+	if field == "" {
+		return fmt.Errorf("there is no story points field on this instance for the estimate %q, set \"storyPointsField\" in the config to its ID or use a time like 2d", estimate)
+	}
+	fields[field] = points
+
+	return nil
+}
+
+// update sends the fields of the manifest that differ from the issue, the
+// ones the manifest leaves out aren't touched
+func (a applier) update(issue ManifestIssue) (string, error) {
+	pointsField := ""
+	if issue.Estimate != "" {
+		if _, duration, _ := parseManifestEstimate(issue.Estimate); duration == "" {
+			var err error
+			pointsField, err = a.c.StoryPointsField()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	current, err := a.c.Client.GetIssue(a.ctx, issue.Key, jiwa.WithFields("summary", "description", "labels", "assignee", "issuetype", "parent", "timetracking", pointsField))
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", issue.Key, err)
+	}
+
+	input, err := a.updateInput(issue, current, pointsField)
+	if err != nil {
+		return "", err
+	}
+
+	parent := ""
+	if issue.Parent != "" {
+		parent, err = a.resolve(issue.Parent)
+		if err != nil {
+			return "", fmt.Errorf("not updated, its parent %w", err)
+		}
+
+		currentParent, err := a.c.ParentOf(current)
+		if err != nil {
+			return "", err
+		}
+		if parent == currentParent {
+			parent = ""
+		}
+	}
+
+	changed := input.Summary != nil || input.Description != nil || input.Labels != nil || input.Assignee != nil || len(input.Fields) != 0
+	if !changed && parent == "" {
+		return ApplyUnchanged, nil
+	}
+
+	if a.c.DryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: line %d would update %s\n", issue.Line, issue.Key)
+		return ApplyUpdated, nil
+	}
+
+	if changed {
+		payload := hooks.Payload{Key: issue.Key, Summary: issue.Summary, Description: issue.Description}
+		err = a.c.runPreHook("pre-edit", payload)
+		if err != nil {
+			return "", err
+		}
+
+		err = a.c.updateIssue(a.ctx, issue.Key, input)
+		if err != nil {
+			return "", fmt.Errorf("failed to update %s: %w", issue.Key, err)
+		}
+
+		a.c.runPostHook("post-edit", payload)
+		a.c.rememberIssue(issue.Key, issue.Summary)
+	}
+
+	if parent != "" {
+		_, err = a.c.SetParent(issue.Key, parent)
+		if err != nil {
+			return ApplyUpdated, err
+		}
+	}
+
+	return ApplyUpdated, nil
+}
+
+func (a applier) updateInput(issue ManifestIssue, current jira.Issue, pointsField string) (jiwa.UpdateIssueInput, error) {
+	var input jiwa.UpdateIssueInput
+	f := current.Fields
+	if f == nil {
+		f = &jira.IssueFields{}
+	}
+
+	if issue.Summary != f.Summary {
+		input.Summary = &issue.Summary
+	}
+
+	if issue.Description != "" && strings.TrimRight(issue.Description, "\n") != strings.TrimRight(f.Description, "\n") {
+		input.Description = &issue.Description
+	}
+
+	if issue.Labels != nil && !sameLabels(issue.Labels, f.Labels) {
+		input.Labels = issue.Labels
+	}
+
+	if issue.Assignee != "" && (f.Assignee == nil || !slices.Contains([]string{f.Assignee.Name, f.Assignee.AccountID, f.Assignee.EmailAddress}, issue.Assignee)) {
+		input.Assignee = &issue.Assignee
+	}
+
+	if issue.Estimate != "" {
+		points, duration, err := parseManifestEstimate(issue.Estimate)
+		if err != nil {
+			return input, err
+		}
+
+		currentPoints, ok := StoryPoints(current, pointsField)
+		if duration != "" && duration != OriginalEstimate(current) || duration == "" && (!ok || currentPoints != points) {
+			input.Fields = make(map[string]any)
+			err = a.estimateFields(issue.Estimate, input.Fields)
+			if err != nil {
+				return input, err
+			}
+		}
+	}
+
+	return input, nil
+}
+
+func sameLabels(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
+
+// link adds the blocks links the issue doesn't have yet
+func (a applier) link(key string, blocks []string) error {
+	specs := make([]string, 0, len(blocks))
+	for _, ref := range blocks {
+		target, err := a.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("not linked to %w", err)
+		}
+		if target != "" {
+			specs = append(specs, "blocks:"+target)
+		}
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+
+	links, err := a.c.ResolveLinkSpecs(specs)
+	if err != nil {
+		return err
+	}
+
+	if a.c.DryRun {
+		for _, l := range links {
+			fmt.Fprintf(os.Stderr, "dry-run: would link %s %s %s\n", key, l.Description, l.Target)
+		}
+		return nil
+	}
+
+	existing, err := a.c.Links(key)
+	if err != nil {
+		return err
+	}
+
+	missing := slices.DeleteFunc(links, func(l LinkSpec) bool {
+		return slices.ContainsFunc(existing, func(e IssueLink) bool {
+			return e.Type == l.Type && e.Key == l.Target && strings.EqualFold(e.Description, l.Description)
+		})
+	})
+
+	return a.c.Link(key, missing)
+}
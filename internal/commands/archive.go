@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConfirmArchiveAbove is the number of issues archive and restore change
+// without asking first
+const ConfirmArchiveAbove = 10
+
+// ConfirmArchive asks whether to archive or restore the issues if there are
+// more than ConfirmArchiveAbove of them, a long list from stdin is easily
+// the wrong one
+func (c *Command) ConfirmArchive(action string, issues []string) error {
+	if len(issues) <= ConfirmArchiveAbove {
+		return nil
+	}
+
+	return c.ConfirmQuery(fmt.Sprintf("%s %d issues", action, len(issues)))
+}
+
+// Archive archives the issues one after another and returns the ones that
+// were archived before anything failed. An instance without archiving
+// fails on the first issue with jiwa.ErrArchivingUnavailable.
+func (c *Command) Archive(issues []string) ([]string, error) {
+	return c.setArchived(issues, c.Client.ArchiveIssue)
+}
+
+// Restore brings back the archived issues like Archive archives them
+func (c *Command) Restore(issues []string) ([]string, error) {
+	return c.setArchived(issues, c.Client.RestoreIssue)
+}
+
+func (c *Command) setArchived(issues []string, fn func(ctx context.Context, key string) error) ([]string, error) {
+	ctx := c.ctx()
+	done := make([]string, 0, len(issues))
+	for _, key := range issues {
+		err := fn(ctx, key)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, key)
+	}
+
+	return done, nil
+}
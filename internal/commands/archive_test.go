@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Archive(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InIssues      []string
+		InRestore     bool
+		InNoArchiving bool
+		OutDone       []string
+		OutArchived   map[string]bool
+		OutErrMsg     string
+	}{
+		{
+			Name:        "Archive",
+			InIssues:    []string{"JIWA-1", "JIWA-2"},
+			OutDone:     []string{"JIWA-1", "JIWA-2"},
+			OutArchived: map[string]bool{"JIWA-1": true, "JIWA-2": true},
+		},
+		{
+			Name:        "Restore",
+			InIssues:    []string{"JIWA-1"},
+			InRestore:   true,
+			OutDone:     []string{"JIWA-1"},
+			OutArchived: map[string]bool{"JIWA-1": false},
+		},
+		{
+			Name:        "StopsAtMissingIssue",
+			InIssues:    []string{"JIWA-1", "JIWA-3", "JIWA-2"},
+			OutDone:     []string{"JIWA-1"},
+			OutArchived: map[string]bool{"JIWA-1": true},
+			OutErrMsg:   "failed to get issue: issue JIWA-3 does not exist",
+		},
+		{
+			Name:          "NoArchiving",
+			InIssues:      []string{"JIWA-1", "JIWA-2"},
+			InNoArchiving: true,
+			OutDone:       []string{},
+			OutArchived:   map[string]bool{},
+			OutErrMsg:     "archiving not available on this Jira",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1"}
+			fake.Issues["JIWA-2"] = jira.Issue{Key: "JIWA-2"}
+			fake.NoArchiving = td.InNoArchiving
+			c := Command{Client: fake}
+
+			var done []string
+			var err error
+			if td.InRestore {
+				fake.Archived["JIWA-1"] = true
+				done, err = c.Restore(td.InIssues)
+			} else {
+				done, err = c.Archive(td.InIssues)
+			}
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			if td.InNoArchiving {
+				assert.ErrorIs(t, err, jiwa.ErrArchivingUnavailable)
+			}
+			assert.Equal(t, td.OutDone, done)
+			assert.Equal(t, td.OutArchived, fake.Archived)
+		})
+	}
+}
+
+func TestCommand_ConfirmArchive(t *testing.T) {
+	c := Command{Client: jiwafake.New(), NoPrompt: true}
+
+	issues := strings.Fields(strings.Repeat("JIWA-1 ", ConfirmArchiveAbove))
+	assert.NoError(t, c.ConfirmArchive("archive", issues))
+
+	err := c.ConfirmArchive("archive", append(issues, "JIWA-2"))
+	assert.EqualError(t, err, "cannot ask whether to archive 11 issues, pass --force to go ahead")
+}
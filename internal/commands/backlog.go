@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/catouc/jiwa/internal/hooks"
+)
+
+// Backlog moves every issue out of its sprint one by one, an issue that
+// fails does not stop the rest from being moved. It returns the issues that
+// were moved and all the errors that happened along the way.
+func (c *Command) Backlog(issues []string) ([]string, error) {
+	moved := make([]string, 0, len(issues))
+	var errs []error
+	for _, issue := range issues {
+		payload := hooks.Payload{Key: issue}
+		err := c.runPreHook("pre-backlog", payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", issue, err))
+			continue
+		}
+
+		err = c.Client.MoveToBacklog(c.ctx(), issue)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", issue, err))
+			continue
+		}
+
+		c.runPostHook("post-backlog", payload)
+		c.remember(issue)
+		moved = append(moved, issue)
+	}
+
+	return moved, errors.Join(errs...)
+}
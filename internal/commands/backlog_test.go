@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Backlog(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InIssues  []string
+		OutMoved  []string
+		OutErrMsg string
+	}{
+		{
+			Name:     "All",
+			InIssues: []string{"JIWA-1", "JIWA-3"},
+			OutMoved: []string{"JIWA-1", "JIWA-3"},
+		},
+		{
+			Name:      "ContinuesPastFailures",
+			InIssues:  []string{"JIWA-1", "JIWA-2", "JIWA-3", "JIWA-4"},
+			OutMoved:  []string{"JIWA-1", "JIWA-3"},
+			OutErrMsg: "JIWA-2: failed to get issue: issue JIWA-2 does not exist\nJIWA-4: failed to get issue: issue JIWA-4 does not exist",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1"}
+			fake.Issues["JIWA-3"] = jira.Issue{Key: "JIWA-3"}
+			c := Command{Client: fake}
+
+			moved, err := c.Backlog(td.InIssues)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.OutMoved, moved)
+			assert.Equal(t, td.OutMoved, fake.Backlog)
+		})
+	}
+}
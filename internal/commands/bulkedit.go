@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/editor"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// bulkHeaderRegEx matches the "=== PROJ-1 ===" line that starts every
+// block. Neither wiki markup nor markdown give such a line a meaning, unlike
+// "---" or "# PROJ-1", so it doesn't show up in descriptions by accident.
+var bulkHeaderRegEx = regexp.MustCompile(`^===\s*([A-Za-z][A-Za-z0-9_]*-[0-9]+)\s*===$`)
+
+// editBlock is an issue in the bulk edit buffer
+type editBlock struct {
+	Key         string
+	Summary     string
+	Description string
+}
+
+// BulkEditResult lists what happened to each issue, Skipped holds why a
+// block of the buffer was ignored
+type BulkEditResult struct {
+	Updated   []string
+	Unchanged []string
+	Skipped   []string
+}
+
+// BulkEdit opens the summaries and descriptions of all issues in a single
+// editor buffer and only updates the issues that were changed.
+func (c *Command) BulkEdit(issueIDs []string) (BulkEditResult, error) {
+	return c.bulkEdit(issueIDs, func(buffer string) (string, error) {
+		scanner, cleanup, err := editor.SetupTmpFileWithEditor(c.EditorFile("bulk-edit", ""), buffer)
+		defer cleanup()
+		if err != nil {
+			return "", err
+		}
+
+		var b strings.Builder
+		for scanner.Scan() {
+			b.WriteString(scanner.Text())
+			b.WriteString("\n")
+		}
+
+		return b.String(), scanner.Err()
+	})
+}
+
+// bulkEdit hands the buffer to edit and applies what comes back
+func (c *Command) bulkEdit(issueIDs []string, edit func(buffer string) (string, error)) (BulkEditResult, error) {
+	if len(issueIDs) == 0 {
+		return BulkEditResult{}, errors.New("no issues to edit")
+	}
+
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issueIDs...)
+	if err != nil {
+		return BulkEditResult{}, err
+	}
+
+	originals := make([]editBlock, 0, len(issueIDs))
+	for _, id := range issueIDs {
+		issue, err := c.Client.GetIssue(c.ctx(), id, jiwa.WithFields("summary", "description"))
+		if err != nil {
+			return BulkEditResult{}, fmt.Errorf("failed to get summary and description of %s: %w", id, err)
+		}
+
+		originals = append(originals, editBlock{Key: issue.Key, Summary: issue.Fields.Summary, Description: issue.Fields.Description})
+	}
+
+	edited, err := edit(formatBulkEdit(originals))
+	if err != nil {
+		return BulkEditResult{}, fmt.Errorf("failed to set up scanner on tmpFile: %w", err)
+	}
+
+	blocks, warnings, err := parseBulkEdit(edited, originals)
+	if err != nil {
+		return BulkEditResult{}, fmt.Errorf("%w, nothing was updated", err)
+	}
+	result := BulkEditResult{Skipped: warnings}
+
+	seen := make(map[string]bool, len(blocks))
+	for _, b := range blocks {
+		seen[b.Key] = true
+	}
+
+	for _, orig := range originals {
+		if !seen[orig.Key] {
+			result.Unchanged = append(result.Unchanged, orig.Key)
+		}
+	}
+
+	for _, b := range blocks {
+		orig := findBlock(originals, b.Key)
+
+		// the editor round trip adds and drops blank lines around the
+		// description, that isn't a change
+		description := b.Description
+		if strings.Trim(description, "\n") == strings.Trim(orig.Description, "\n") {
+			description = orig.Description
+		}
+
+		description, err = c.withMentions(mentionScope{IssueKey: b.Key}, description, orig.Description)
+		if err != nil {
+			return result, err
+		}
+
+		input, changed := editInput(orig.Summary, orig.Description, b.Summary, description)
+		if !changed {
+			result.Unchanged = append(result.Unchanged, b.Key)
+			continue
+		}
+
+		payload := hooks.Payload{Key: b.Key, Summary: b.Summary, Description: description}
+		err = c.runPreHook("pre-edit", payload)
+		if err != nil {
+			return result, err
+		}
+
+		err = c.updateIssue(c.ctx(), b.Key, input)
+		if err != nil {
+			return result, fmt.Errorf("failed to update %s: %w", b.Key, err)
+		}
+
+		c.runPostHook("post-edit", payload)
+		c.rememberIssue(b.Key, b.Summary)
+		result.Updated = append(result.Updated, b.Key)
+	}
+
+	return result, nil
+}
+
+// formatBulkEdit writes a "=== KEY ===" header, the summary line and the
+// description for every issue
+func formatBulkEdit(blocks []editBlock) string {
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "=== %s ===\n%s\n", block.Key, block.Summary)
+		if description := strings.Trim(block.Description, "\n"); description != "" {
+			b.WriteString(description + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// parseBulkEdit reads the blocks back, every block runs from its header to
+// the next one and blank lines around headers, summaries and descriptions
+// don't matter. Text that would be lost, before the first header or under
+// a header for an issue that isn't edited or shows up twice, fails the
+// whole edit instead of being dropped. Blocks without a summary or with one
+// that is too long are skipped with a warning.
+func parseBulkEdit(text string, originals []editBlock) ([]editBlock, []string, error) {
+	type chunk struct {
+		key   string
+		lines []string
+	}
+	var chunks []chunk
+	var before []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if m := bulkHeaderRegEx.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			chunks = append(chunks, chunk{key: strings.ToUpper(m[1])})
+			continue
+		}
+		if len(chunks) == 0 {
+			before = append(before, line)
+			continue
+		}
+		chunks[len(chunks)-1].lines = append(chunks[len(chunks)-1].lines, line)
+	}
+
+	if lines := trimBlankLines(before); len(lines) != 0 {
+		return nil, nil, fmt.Errorf("%q is above the first \"=== KEY ===\" header, put it under the issue it belongs to", strings.TrimSpace(lines[0]))
+	}
+
+	blocks := make([]editBlock, 0, len(chunks))
+	warnings := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, c := range chunks {
+		switch {
+		case findBlock(originals, c.key).Key == "":
+			return nil, nil, fmt.Errorf("%s isn't one of the issues being edited", c.key)
+		case seen[c.key]:
+			return nil, nil, fmt.Errorf("%s has more than one block", c.key)
+		}
+		seen[c.key] = true
+
+		rest := trimLeadingBlankLines(c.lines)
+		if len(rest) == 0 {
+			warnings = append(warnings, fmt.Sprintf("skipped %s, the summary line is empty", c.key))
+			continue
+		}
+
+		summary := strings.TrimSpace(rest[0])
+		if err := checkSummaryLength(summary); err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s, %s", c.key, err))
+			continue
+		}
+
+		blocks = append(blocks, editBlock{Key: c.key, Summary: summary, Description: strings.Join(trimBlankLines(rest[1:]), "\n")})
+	}
+
+	return blocks, warnings, nil
+}
+
+func trimBlankLines(lines []string) []string {
+	lines = trimLeadingBlankLines(lines)
+	for len(lines) != 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func trimLeadingBlankLines(lines []string) []string {
+	for len(lines) != 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	return lines
+}
+
+func findBlock(blocks []editBlock, key string) editBlock {
+	for _, b := range blocks {
+		if b.Key == key {
+			return b
+		}
+	}
+
+	return editBlock{}
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/stretchr/testify/assert"
+)
+
+var bulkOriginals = []editBlock{
+	{Key: "JIWA-1", Summary: "First", Description: "Line one\nLine two"},
+	{Key: "JIWA-2", Summary: "Second"},
+	{Key: "JIWA-3", Summary: "Third", Description: "Before\n\n---\n\nAfter"},
+}
+
+func TestParseBulkEdit(t *testing.T) {
+	testData := []struct {
+		Name        string
+		In          string
+		OutBlocks   []editBlock
+		OutWarnings []string
+		OutErrMsg   string
+	}{
+		{
+			Name:      "RoundTrip",
+			In:        formatBulkEdit(bulkOriginals),
+			OutBlocks: bulkOriginals,
+		},
+		{
+			Name: "BlankLinesDontMatter",
+			In:   "\n\n=== JIWA-1 ===\n\nFirst\n\nLine one\nLine two\n\n\n\n=== JIWA-2 ===\nSecond\n\n",
+			OutBlocks: []editBlock{
+				{Key: "JIWA-1", Summary: "First", Description: "Line one\nLine two"},
+				{Key: "JIWA-2", Summary: "Second"},
+			},
+		},
+		{
+			Name: "HeaderSpellings",
+			In:   "===jiwa-1===\nFirst\n  ===   JIWA-2   ===  \nSecond\n",
+			OutBlocks: []editBlock{
+				{Key: "JIWA-1", Summary: "First"},
+				{Key: "JIWA-2", Summary: "Second"},
+			},
+		},
+		{
+			Name: "WindowsLineEndings",
+			In:   "=== JIWA-1 ===\r\nFirst\r\nLine one\r\n=== JIWA-2 ===\r\nSecond\r\n",
+			OutBlocks: []editBlock{
+				{Key: "JIWA-1", Summary: "First", Description: "Line one"},
+				{Key: "JIWA-2", Summary: "Second"},
+			},
+		},
+		{
+			Name: "MarkupThatLooksLikeSeparators",
+			In:   "=== JIWA-1 ===\nFirst\nBefore\n---\n# JIWA-2\nAfter\n=== JIWA-2 ===\nSecond\n",
+			OutBlocks: []editBlock{
+				{Key: "JIWA-1", Summary: "First", Description: "Before\n---\n# JIWA-2\nAfter"},
+				{Key: "JIWA-2", Summary: "Second"},
+			},
+		},
+		{
+			Name:      "DeletedFirstHeader",
+			In:        "First\nmore text\n=== JIWA-2 ===\nSecond\n",
+			OutErrMsg: `"First" is above the first "=== KEY ===" header, put it under the issue it belongs to`,
+		},
+		{
+			Name:      "UnknownKey",
+			In:        "=== JIWA-1 ===\nFirst\n=== JIWA-9 ===\nSomething else\n",
+			OutErrMsg: "JIWA-9 isn't one of the issues being edited",
+		},
+		{
+			Name:      "DuplicateKey",
+			In:        "=== JIWA-1 ===\nFirst\n=== JIWA-1 ===\nFirst again\n",
+			OutErrMsg: "JIWA-1 has more than one block",
+		},
+		{
+			Name:        "EmptySummary",
+			In:          "=== JIWA-1 ===\n\n\n=== JIWA-2 ===\nSecond\n",
+			OutBlocks:   []editBlock{{Key: "JIWA-2", Summary: "Second"}},
+			OutWarnings: []string{"skipped JIWA-1, the summary line is empty"},
+		},
+		{
+			Name:        "SummaryTooLong",
+			In:          "=== JIWA-1 ===\n" + strings.Repeat("a", 256) + "\n=== JIWA-2 ===\nSecond\n",
+			OutBlocks:   []editBlock{{Key: "JIWA-2", Summary: "Second"}},
+			OutWarnings: []string{"skipped JIWA-1, the summary is 256 characters long but Jira only takes 255, move the rest into the description"},
+		},
+		{
+			Name:      "EmptyBuffer",
+			In:        "\n",
+			OutBlocks: []editBlock{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			blocks, warnings, err := parseBulkEdit(td.In, bulkOriginals)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutBlocks, blocks)
+			if td.OutWarnings == nil {
+				td.OutWarnings = []string{}
+			}
+			assert.Equal(t, td.OutWarnings, warnings)
+		})
+	}
+}
+
+func TestCommand_BulkEdit(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InEdit    func(buffer string) (string, error)
+		OutResult BulkEditResult
+		OutPuts   []string
+		OutErrMsg string
+	}{
+		{
+			Name:      "NothingChanged",
+			InEdit:    func(buffer string) (string, error) { return buffer, nil },
+			OutResult: BulkEditResult{Unchanged: []string{"JIWA-1", "JIWA-2"}, Skipped: []string{}},
+		},
+		{
+			Name: "OnlyChangedIssuesAreUpdated",
+			InEdit: func(buffer string) (string, error) {
+				return strings.Replace(buffer, "Second", "Second, reworded", 1), nil
+			},
+			OutResult: BulkEditResult{Updated: []string{"JIWA-2"}, Unchanged: []string{"JIWA-1"}, Skipped: []string{}},
+			OutPuts:   []string{"/rest/api/2/issue/JIWA-2"},
+		},
+		{
+			Name: "DeletedHeaderFailsEverything",
+			InEdit: func(string) (string, error) {
+				return "=== JIWA-1 ===\nFirst\nLine one\nLine two, edited\n=== JIWA-3 ===\nSecond, reworded\n", nil
+			},
+			OutErrMsg: "JIWA-3 isn't one of the issues being edited, nothing was updated",
+		},
+		{
+			Name:      "EditorFails",
+			InEdit:    func(string) (string, error) { return "", errors.New("editor exited with 1") },
+			OutErrMsg: "failed to set up scanner on tmpFile: editor exited with 1",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "First", Description: "Line one\nLine two\n"}})
+			srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Second"}})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+
+			c := Command{Client: client, Config: Config{BaseURL: srv.URL}}
+			result, err := c.bulkEdit([]string{"JIWA-1", "JIWA-2"}, td.InEdit)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				for _, r := range srv.Requests() {
+					assert.NotEqual(t, http.MethodPut, r.Method)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutResult, result)
+
+			var puts []string
+			for _, r := range srv.Requests() {
+				if r.Method == http.MethodPut {
+					puts = append(puts, r.Path)
+				}
+			}
+			assert.Equal(t, td.OutPuts, puts)
+		})
+	}
+}
@@ -1,16 +1,19 @@
 package commands
 
 import (
-	"context"
-
 	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Cat(issueID string) (jira.Issue, error) {
-	issue, err := c.Client.GetIssue(context.TODO(), issueID)
+// Cat fetches the issue, opts can be used to only get the fields that are
+// going to be shown.
+func (c *Command) Cat(issueID string, opts ...jiwa.GetIssueOption) (jira.Issue, error) {
+	issue, err := c.Client.GetIssue(c.ctx(), issueID, opts...)
 	if err != nil {
 		return jira.Issue{}, err
 	}
 
+	c.rememberIssue(issue.Key, issue.Fields.Summary)
+
 	return issue, nil
 }
@@ -6,43 +6,176 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/andygrunwald/go-jira"
 	"github.com/catouc/jiwa/internal/editor"
-	"github.com/catouc/jiwa/internal/jiwa"
+	"github.com/catouc/jiwa/internal/git"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/internal/wiki"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
 type Command struct {
 	Config Config
-	Client jiwa.Client
+	Client jiwa.API
+	Hooks  hooks.Runner
+	// Git is the repository jiwa commits reads the commits of
+	Git    git.Repo
+	DryRun bool
+	// Yes answers the questions before changes with yes, like --force
+	Yes   bool
+	State *state.Store
+	// Journal holds the changes that were queued while offline
+	Journal *offline.Store
+	// Context is passed to every request, main cancels it on Ctrl-C
+	Context context.Context
+	// NoMentions keeps @name in comments and descriptions as it is
+	NoMentions bool
+	// VisibleTo restricts the comments that are added to a project role
+	// or a group
+	VisibleTo *jira.CommentVisibility
+	// ReopenIfClosed lets edits of closed issues reopen them, edit them and
+	// close them again
+	ReopenIfClosed bool
+	// SnippetDir holds a file per comment snippet, named like the snippet
+	SnippetDir string
+	// ConfigDir is the directory of the config file, relative paths in the
+	// config are resolved against it
+	ConfigDir string
+	// NoPrompt fails where the user would be asked on the terminal, for
+	// callers nobody is sitting in front of
+	NoPrompt bool
+	// SkipPermissionCheck sends changes without checking the permissions
+	// first, for instances that restrict the endpoint
+	SkipPermissionCheck bool
+	// PrintURLs prints the links of issues instead of their keys where
+	// they are printed for the next command in a pipe
+	PrintURLs bool
+
+	// mentions caches the users @names were resolved to, nil for the
+	// names nobody was found for
+	mentions map[string]*jira.User
+	// permissions caches the permissions of the user by project
+	permissions map[string]map[string]jiwa.Permission
+	// createMeta caches the fields of the create screens by
+	// "<project>/<type>"
+	createMeta map[string]map[string]jiwa.CreateField
+	// roles caches the names of the roles by project
+	roles map[string][]string
+	// self caches the field that assigns an issue to the current user
+	self map[string]string
+}
+
+// ctx returns the Context, tests and other callers that don't set one
+// get a background context
+func (c *Command) ctx() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+
+	return c.Context
 }
 
 type Config struct {
-	BaseURL        string        `json:"baseURL"`
-	APIVersion     string        `json:"apiVersion"`
-	EndpointPrefix string        `json:"endpointPrefix"`
-	Username       string        `json:"username"`
-	Password       string        `json:"password"`
-	Token          string        `json:"token"`
-	Timeout        time.Duration `json:"timeout"`
-	DefaultProject string        `json:"defaultProject"`
+	BaseURL        string            `json:"baseURL"`
+	APIVersion     string            `json:"apiVersion"`
+	EndpointPrefix string            `json:"endpointPrefix"`
+	Username       string            `json:"username"`
+	Password       string            `json:"password"`
+	Token          string            `json:"token"`
+	Timeout        time.Duration     `json:"timeout"`
+	DefaultProject string            `json:"defaultProject"`
+	Hooks          map[string]string `json:"hooks"`
+	HookTimeout    time.Duration     `json:"hookTimeout"`
+
+	DisableDuplicateCheck bool `json:"disableDuplicateCheck"`
+
+	// CommandTimeout gives up on the whole command after this long, where
+	// Timeout bounds each request. Nothing is bounded when it is 0.
+	CommandTimeout time.Duration `json:"commandTimeout"`
+
+	// ProjectGroups names sets of projects that list can be pointed at
+	// with --project @name
+	ProjectGroups map[string][]string `json:"projectGroups"`
+
+	// ViewFields picks the fields cat shows and their order, by ID or name
+	ViewFields []string `json:"viewFields"`
+	// ShowDetail is how much show prints without --short or --full,
+	// "short", "default" or "full"
+	ShowDetail string `json:"showDetail"`
+	// EditorFileExtension is given to the files opened in the editor,
+	// defaults to "md"
+	EditorFileExtension string `json:"editorFileExtension"`
+	// MoveForwardKeywords and MoveBackwardKeywords tell move --next and
+	// --prev which way a transition goes by its name
+	MoveForwardKeywords  []string `json:"moveForwardKeywords"`
+	MoveBackwardKeywords []string `json:"moveBackwardKeywords"`
+	// QueueWhenUnreachable queues changes in the journal when Jira can't
+	// be reached instead of failing, "jiwa sync" sends them later
+	QueueWhenUnreachable bool `json:"queueWhenUnreachable"`
+	// CacheResponses keeps the responses Jira can revalidate on disk next
+	// to the state, so that the next command asks whether they changed
+	// instead of fetching them again
+	CacheResponses bool `json:"cacheResponses"`
+	// Concurrency caps the requests commands like dashboard run at once,
+	// defaults to 4
+	Concurrency int `json:"concurrency"`
+	// DashboardQueries replaces the JQL of dashboard sections by their
+	// name, "{project}" stands for the project clause
+	DashboardQueries map[string]string `json:"dashboardQueries"`
+	// ListCap is the most issues list fetches without --limit, defaults
+	// to 1000
+	ListCap int `json:"listCap"`
+	// MaxRetries is how often a rate limited request is sent again after
+	// the Retry-After Jira asked for, none by default
+	MaxRetries int `json:"maxRetries"`
+	// Snippets are canned comments by name, see CommentSnippet for the
+	// placeholders they can use
+	Snippets map[string]string `json:"snippets"`
+	// DescriptionFormat is "wiki" or "markdown", Markdown descriptions are
+	// converted to wiki markup before they are sent to a v2 API
+	DescriptionFormat string `json:"descriptionFormat"`
+	// FlaggedField is the ID of the field boards flag impediments with,
+	// it is looked up by the name "Flagged" if it isn't set
+	FlaggedField string `json:"flaggedField"`
+	// StoryPointsField is the ID of the field estimate sets, it is looked
+	// up by the names "Story Points" and "Story point estimate" if it
+	// isn't set
+	StoryPointsField string `json:"storyPointsField"`
+	// Filters are saved JQL queries by name that list --filter runs, @me
+	// stands for whoever runs them
+	Filters map[string]string `json:"filters"`
+	// TriagePool are the users reassign --round-robin takes turns with
+	TriagePool []string `json:"triagePool"`
+	// Templates are the issue shapes create --from-template starts from,
+	// by name
+	Templates map[string]IssueTemplate `json:"templates"`
+	// Timezone is the IANA name of the zone timestamps are shown in, like
+	// "Europe/Berlin", defaults to the local one
+	Timezone string `json:"timezone"`
+	// FallbackIssueType is what migrate creates the copy as when the
+	// project doesn't have the type of the original, defaults to "Task"
+	FallbackIssueType string `json:"fallbackIssueType"`
+
+	ClientCert string `json:"clientCert"`
+	ClientKey  string `json:"clientKey"`
+	// InsecureAllowHTTP lets a "baseURL" on plain http through, the
+	// credentials are sent to it in cleartext
+	InsecureAllowHTTP bool `json:"insecureAllowHTTP"`
 }
 
 func (c *Config) IsValid() bool {
-	switch {
-	case c.BaseURL == "":
-		return false
-	case c.Username == "":
-		return false
-	case c.Token == "" && c.Password == "":
-		return false
-	default:
-		return true
-	}
+	return c.Validate() == nil
 }
 
 func (c *Config) ReturnCleanEndpointPrefix() string {
@@ -62,84 +195,263 @@ func BuildCommentFromScanner(scanner *bufio.Scanner) (string, error) {
 	return commentBuilder.String(), scanner.Err()
 }
 
+// EditorFile names the file that is opened in the editor for the command,
+// key is left empty if the text isn't for a single issue.
+func (c *Command) EditorFile(command, key string) editor.File {
+	return editor.File{Command: command, Key: key, Extension: c.Config.EditorFileExtension}
+}
+
 // CreateIssueSummaryDescription takes care of creating an empty tmp file
 // and opening an editor on that, reading the result once the editor is closed
-// then shoving that into a title and a description.
+// then shoving that into a title and a description. A buffer that can't be
+// used, like one with a summary that is too long, is opened again with the
+// problem on top.
 // SetupTmpFileWithEditor is what you're looking for to just get the file
 // thing.
-func CreateIssueSummaryDescription(prefill string) (string, string, error) {
-	scanner, cleanup, err := editor.SetupTmpFileWithEditor(prefill)
+func CreateIssueSummaryDescription(file editor.File, prefill string, autoSplit bool) (string, string, error) {
+	var title, description string
+	_, err := editor.EditUntilValid(file, prefill, func(text string) error {
+		var err error
+		title, description, err = summaryDescription(bufio.NewScanner(strings.NewReader(text)), autoSplit)
+		if err != nil {
+			return err
+		}
+		if title == "" {
+			return errors.New("the summary line needs to be filled at least")
+		}
+
+		return nil
+	})
+	switch {
+	case errors.Is(err, editor.ErrEmpty):
+		return "", "", errors.New("the summary line needs to be filled at least")
+	case err != nil:
+		return "", "", err
+	}
+
+	return title, description, nil
+}
+
+// SummarySeparator can go on a line of its own below the summary, so the
+// summary can span several lines or start further down the buffer
+const SummarySeparator = "---"
+
+// summarySeparatorLines is how far down the separator is looked for, a
+// "---" further down is a rule in the description
+const summarySeparatorLines = 5
+
+// maxSummaryLength is the longest summary Jira accepts
+const maxSummaryLength = 255
+
+// BuildSummaryAndDescriptionFromScanner splits the text into the summary
+// and the description. If one of the first lines is only "---", the lines
+// above it are joined into the summary and everything below is the
+// description. Otherwise the first line that isn't blank is the summary.
+// Trailing blank lines are dropped from the description.
+func BuildSummaryAndDescriptionFromScanner(scanner *bufio.Scanner) (string, string, error) {
+	summary, description, err := splitSummaryDescription(scanner)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to set up scanner on tmpFile: %w", err)
+		return "", "", err
 	}
-	defer cleanup()
 
-	title, description, err := BuildSummaryAndDescriptionFromScanner(scanner)
+	err = checkSummaryLength(summary)
 	if err != nil {
-		return "", "", fmt.Errorf("scanner failure: %w", err)
+		return "", "", err
 	}
 
-	if title == "" {
-		return "", "", errors.New("the summary line needs to be filled at least")
+	return summary, description, nil
+}
+
+// summaryDescription is BuildSummaryAndDescriptionFromScanner, but with
+// autoSplit a summary that is too long is cut by SplitLongSummary instead
+// of refused
+func summaryDescription(scanner *bufio.Scanner, autoSplit bool) (string, string, error) {
+	if !autoSplit {
+		return BuildSummaryAndDescriptionFromScanner(scanner)
 	}
 
-	return title, description, nil
+	summary, description, err := splitSummaryDescription(scanner)
+	if err != nil {
+		return "", "", err
+	}
+
+	if n := utf8.RuneCountInString(summary); n > maxSummaryLength {
+		fmt.Fprintf(os.Stderr, "the summary is %d characters long, moved what doesn't fit into %d to the top of the description\n", n, maxSummaryLength)
+	}
+	summary, description = SplitLongSummary(summary, description)
+
+	return summary, description, nil
 }
 
-func BuildSummaryAndDescriptionFromScanner(scanner *bufio.Scanner) (string, string, error) {
-	var title string
-	descriptionBuilder := strings.Builder{}
+func splitSummaryDescription(scanner *bufio.Scanner) (string, string, error) {
+	lines := make([]string, 0)
 	for scanner.Scan() {
-		if title == "" {
-			title = scanner.Text()
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// blank lines before the summary don't count
+		if len(lines) == 0 && strings.TrimSpace(line) == "" {
 			continue
 		}
-		descriptionBuilder.WriteString(scanner.Text())
-		descriptionBuilder.WriteString("\n")
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	if len(lines) == 0 {
+		return "", "", nil
+	}
+
+	summary, body := lines[0], lines[1:]
+	if sep := summarySeparatorIndex(lines); sep != -1 {
+		if sep == 0 {
+			return "", "", fmt.Errorf("there is nothing above the %s line, the summary goes there", SummarySeparator)
+		}
+		summary = strings.Join(strings.Fields(strings.Join(lines[:sep], " ")), " ")
+		body = lines[sep+1:]
 	}
 
-	return title, descriptionBuilder.String(), scanner.Err()
+	for len(body) != 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+
+	return summary, strings.Join(body, "\n"), nil
 }
 
-func GetIssueIntoEditor(c jiwa.Client, key string) (string, string, error) {
-	issue, err := c.GetIssue(context.TODO(), key)
-	if err != nil {
-		return "", "", err
+func summarySeparatorIndex(lines []string) int {
+	for i, line := range lines {
+		if i == summarySeparatorLines {
+			break
+		}
+		if strings.TrimSpace(line) == SummarySeparator {
+			return i
+		}
 	}
 
-	return CreateIssueSummaryDescription(issue.Fields.Summary + "\n" + issue.Fields.Description)
+	return -1
 }
 
-func ReadStdin() ([]byte, error) {
-	var buf []byte
-	scanner := bufio.NewScanner(os.Stdin)
+// SplitLongSummary cuts a summary that is too long for Jira at the last
+// word boundary that fits and ends it with an ellipsis. The rest goes on top
+// of the description behind an ellipsis of its own, so it reads on.
+func SplitLongSummary(summary, description string) (string, string) {
+	runes := []rune(summary)
+	if len(runes) <= maxSummaryLength {
+		return summary, description
+	}
 
-	for scanner.Scan() {
-		buf = append(buf, scanner.Bytes()...)
-		buf = append(buf, 10) // add the newline back into the buffer
+	// one rune is left for the ellipsis, a word without any space in
+	// reach is cut in the middle
+	cut := maxSummaryLength - 1
+	for i := cut; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
 	}
 
-	err := scanner.Err()
+	head := strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+	rest := strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace)
+
+	overflow := "…" + rest
+	if description != "" {
+		overflow += "\n\n" + description
+	}
+
+	return head + "…", overflow
+}
+
+func checkSummaryLength(summary string) error {
+	if n := utf8.RuneCountInString(summary); n > maxSummaryLength {
+		return fmt.Errorf("the summary is %d characters long but Jira only takes %d, move the rest into the description", n, maxSummaryLength)
+	}
+
+	return nil
+}
+
+// fromDescriptionFormat converts a description written in Markdown to wiki
+// markup if "descriptionFormat" asks for it. v3 takes descriptions as they
+// are, so does edit, which starts from the wiki markup Jira has.
+func (c *Command) fromDescriptionFormat(description string) string {
+	if c.Config.DescriptionFormat != "markdown" || c.Config.APIVersion == "3" {
+		return description
+	}
+
+	return wiki.FromMarkdown(description)
+}
+
+// FormatSummaryDescription is the reverse of
+// BuildSummaryAndDescriptionFromScanner, the separator is only added when
+// the description would otherwise be mistaken for part of the summary.
+func FormatSummaryDescription(summary, description string) string {
+	lines := append([]string{summary}, strings.Split(description, "\n")...)
+	if summarySeparatorIndex(lines) == -1 {
+		return summary + "\n" + description
+	}
+
+	return summary + "\n" + SummarySeparator + "\n" + description
+}
+
+// ReadStdin returns stdin exactly as it was piped in, including whatever
+// whitespace and newline there is or isn't at the end
+func ReadStdin() ([]byte, error) {
+	b, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read stdin: %v", err)
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
 	}
 
-	return buf, nil
+	return b, nil
 }
 
-func (c *Command) StripBaseURL(url string) string {
-	issueRegEx, _ := regexp.Compile("^[A-Z]*-[0-9]*$")
+var (
+	issueKeyRegEx = regexp.MustCompile(`^[A-Z][A-Z0-9_]*-[0-9]+$`)
+	digitsRegEx   = regexp.MustCompile(`^[0-9]+$`)
+)
 
-	if issueRegEx.MatchString(url) {
+// StripBaseURL returns the key of a browse link, whatever comes after the
+// key like a query, a fragment pointing at a comment or a trailing slash is
+// dropped. Anything that isn't a browse link is empty.
+func (c *Command) StripBaseURL(url string) string {
+	if issueKeyRegEx.MatchString(url) {
 		return url
 	}
 
-	urlSplit := strings.Split(url, "/browse/")
-	if len(urlSplit) != 2 {
+	_, rest, found := strings.Cut(url, "/browse/")
+	if !found {
 		return ""
 	}
 
-	return urlSplit[1]
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// normalizeIssueKey turns user input into a valid issue key before it is
+// sent anywhere. Keys are uppercased, browse URLs stripped and bare issue
+// numbers prefixed with the default project so `jiwa edit 57` works.
+func (c *Command) normalizeIssueKey(input string) (string, error) {
+	key := strings.TrimSpace(input)
+	if strings.Contains(key, "/browse/") {
+		key = c.StripBaseURL(key)
+	}
+
+	// a bare number is reported with the project it got, that is the
+	// part that is wrong
+	shown := input
+	if digitsRegEx.MatchString(key) {
+		if c.Config.DefaultProject == "" {
+			return "", fmt.Errorf("issue \"%s\" has no project, either pass the full key, pass --project or set a default project", input)
+		}
+		key = c.Config.DefaultProject + "-" + key
+		shown = strings.ToUpper(key)
+	}
+
+	key = strings.ToUpper(key)
+	if !issueKeyRegEx.MatchString(key) {
+		return "", fmt.Errorf("invalid issue key \"%s\", expected something like PROJ-123", shown)
+	}
+
+	return key, nil
 }
 
 func (c *Command) FishOutProject(projectFlag string) (string, error) {
@@ -162,20 +474,37 @@ func (c *Command) ReadIssueListFromStdin() ([]string, error) {
 	issues := make([]string, 0)
 	scanner := bufio.NewScanner(bytes.NewBuffer(in))
 	for scanner.Scan() {
-		issues = append(issues, c.StripBaseURL(scanner.Text()))
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		issue, err := c.ParseIssueArg(line)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
 	}
-	if scanner.Err() != nil {
+	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("failed to read in all tickets: %w", err)
 	}
 
 	return issues, nil
 }
 
-func (c *Command) ConstructIssueURL(issueKey string) string {
-	// this cannot ever fail to compile? Unless I'm mistaken here...
-	issueRegEx, _ := regexp.Compile("^[A-Z]*-[0-9]*$")
+// IssueRef is how an issue is printed for the next command in a pipe, one
+// per line: its key, or its link with PrintURLs. Every command that takes
+// issues reads both.
+func (c *Command) IssueRef(issueKey string) string {
+	if c.PrintURLs {
+		return c.ConstructIssueURL(issueKey)
+	}
+
+	return issueKey
+}
 
-	if !issueRegEx.MatchString(issueKey) {
+func (c *Command) ConstructIssueURL(issueKey string) string {
+	if !issueKeyRegEx.MatchString(issueKey) {
 		return ""
 	}
 
@@ -186,3 +515,10 @@ func (c *Command) ConstructIssueURL(issueKey string) string {
 
 	return path
 }
+
+// jqlQuote quotes s as a JQL string literal
+func jqlQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
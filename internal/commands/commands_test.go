@@ -1,8 +1,18 @@
 package commands
 
 import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/pkg/jiwa"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -165,6 +175,28 @@ func TestCommand_StripBaseURL(t *testing.T) {
 			InURL:     "JIWA-001",
 			OutString: "JIWA-001",
 		},
+		{
+			Name: "CommentFragment",
+			InCommand: Command{
+				Config: Config{
+					BaseURL:    "https://catouc.atlassian.net",
+					APIVersion: "2",
+				},
+			},
+			InURL:     "https://catouc.atlassian.net/browse/JIWA-001?focusedCommentId=10000#comment-10000",
+			OutString: "JIWA-001",
+		},
+		{
+			Name: "TrailingSlash",
+			InCommand: Command{
+				Config: Config{
+					BaseURL:    "https://catouc.atlassian.net",
+					APIVersion: "2",
+				},
+			},
+			InURL:     "https://catouc.atlassian.net/browse/JIWA-001/",
+			OutString: "JIWA-001",
+		},
 	}
 
 	for _, td := range testData {
@@ -177,3 +209,611 @@ func TestCommand_StripBaseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestCommand_ParseIssueArg(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InCommand Command
+		InArg     string
+		OutKey    string
+		OutErrMsg string
+	}{
+		{
+			Name:      "Key",
+			InCommand: Command{},
+			InArg:     "JIWA-1",
+			OutKey:    "JIWA-1",
+		},
+		{
+			Name:      "LowercaseKey",
+			InCommand: Command{},
+			InArg:     "jiwa-12",
+			OutKey:    "JIWA-12",
+		},
+		{
+			Name:      "KeyWithDigitsAndUnderscore",
+			InCommand: Command{},
+			InArg:     "AB2_C-7",
+			OutKey:    "AB2_C-7",
+		},
+		{
+			Name:      "SurroundingWhitespace",
+			InCommand: Command{},
+			InArg:     "  JIWA-1\t",
+			OutKey:    "JIWA-1",
+		},
+		{
+			Name:      "BrowseURL",
+			InCommand: Command{},
+			InArg:     "https://catouc.atlassian.net/browse/JIWA-1",
+			OutKey:    "JIWA-1",
+		},
+		{
+			Name:      "BrowseURLWithQuery",
+			InCommand: Command{},
+			InArg:     "https://catouc.atlassian.net/browse/JIWA-1?focusedCommentId=10",
+			OutKey:    "JIWA-1",
+		},
+		{
+			Name:      "DigitsWithDefaultProject",
+			InCommand: Command{Config: Config{DefaultProject: "JIWA"}},
+			InArg:     "57",
+			OutKey:    "JIWA-57",
+		},
+		{
+			Name:      "DigitsWithLowercaseProject",
+			InCommand: Command{Config: Config{DefaultProject: "other"}},
+			InArg:     "123",
+			OutKey:    "OTHER-123",
+		},
+		{
+			Name:      "DigitsWithInvalidProject",
+			InCommand: Command{Config: Config{DefaultProject: "1OTHER"}},
+			InArg:     "123",
+			OutErrMsg: `invalid issue key "1OTHER-123", expected something like PROJ-123`,
+		},
+		{
+			Name:      "DigitsWithoutDefaultProject",
+			InCommand: Command{},
+			InArg:     "57",
+			OutErrMsg: `issue "57" has no project, either pass the full key, pass --project or set a default project`,
+		},
+		{
+			Name:      "MissingDash",
+			InCommand: Command{Config: Config{DefaultProject: "JIWA"}},
+			InArg:     "proj123",
+			OutErrMsg: `invalid issue key "proj123", expected something like PROJ-123`,
+		},
+		{
+			Name:      "Empty",
+			InCommand: Command{},
+			InArg:     "",
+			OutErrMsg: `invalid issue key "", expected something like PROJ-123`,
+		},
+		{
+			Name:      "ProjectStartingWithDigit",
+			InCommand: Command{},
+			InArg:     "1JIWA-1",
+			OutErrMsg: `invalid issue key "1JIWA-1", expected something like PROJ-123`,
+		},
+		{
+			Name:      "ShellQuotingMistake",
+			InCommand: Command{},
+			InArg:     "JIWA-1 JIWA-2",
+			OutErrMsg: `invalid issue key "JIWA-1 JIWA-2", expected something like PROJ-123`,
+		},
+		{
+			Name:      "NotAnIssueURL",
+			InCommand: Command{},
+			InArg:     "https://catouc.atlassian.net/jira/software/projects/JIWA/boards/1",
+			OutErrMsg: `invalid issue key "https://catouc.atlassian.net/jira/software/projects/JIWA/boards/1", expected something like PROJ-123`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			key, err := td.InCommand.ParseIssueArg(td.InArg)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutKey, key)
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InConfig  string
+		OutConfig Config
+		OutErrMsg string
+	}{
+		{
+			Name:      "Valid",
+			InConfig:  `{"baseURL": "https://catouc.atlassian.net", "username": "me", "token": "t"}`,
+			OutConfig: Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t"},
+		},
+		{
+			Name:      "WrongCase",
+			InConfig:  `{"baseUrl": "https://catouc.atlassian.net"}`,
+			OutErrMsg: `unknown key "baseUrl", did you mean "baseURL"?`,
+		},
+		{
+			Name:      "Typo",
+			InConfig:  `{"defaultProjetc": "JIWA"}`,
+			OutErrMsg: `unknown key "defaultProjetc", did you mean "defaultProject"?`,
+		},
+		{
+			Name:      "UnknownWithoutSuggestion",
+			InConfig:  `{"colour": "red"}`,
+			OutErrMsg: `unknown key "colour"`,
+		},
+		{
+			Name:      "WrongType",
+			InConfig:  `{"timeout": "5s"}`,
+			OutErrMsg: `"timeout" needs to be a time.Duration but is a JSON string`,
+		},
+		{
+			Name:      "SyntaxError",
+			InConfig:  "{\n  \"baseURL\": \"https://catouc.atlassian.net\",\n}",
+			OutErrMsg: "invalid JSON at line 3, column 1: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			Name:      "Empty",
+			InConfig:  "",
+			OutErrMsg: "the file is empty",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := ParseConfig(strings.NewReader(td.InConfig))
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutConfig, cfg)
+		})
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InConfig  Config
+		InEnv     map[string]string
+		OutConfig Config
+	}{
+		{
+			Name: "EnvOnly",
+			InEnv: map[string]string{
+				"JIWA_BASE_URL":        "https://catouc.atlassian.net",
+				"JIWA_API_VERSION":     "3",
+				"JIWA_ENDPOINT_PREFIX": "jira",
+				"JIWA_DEFAULT_PROJECT": "JIWA",
+				"JIWA_USERNAME":        "ci",
+				"JIWA_TOKEN":           "t",
+			},
+			OutConfig: Config{BaseURL: "https://catouc.atlassian.net", APIVersion: "3", EndpointPrefix: "jira", DefaultProject: "JIWA", Username: "ci", Token: "t"},
+		},
+		{
+			Name:      "EnvWinsOverFile",
+			InConfig:  Config{BaseURL: "https://old.example.com", DefaultProject: "OPS", Username: "me", Password: "p"},
+			InEnv:     map[string]string{"JIWA_BASE_URL": "https://catouc.atlassian.net", "JIWA_PASSWORD": ""},
+			OutConfig: Config{BaseURL: "https://catouc.atlassian.net", DefaultProject: "OPS", Username: "me"},
+		},
+		{
+			Name:      "NothingSet",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t"},
+			OutConfig: Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			cfg := td.InConfig
+			cfg.ApplyEnv(func(key string) (string, bool) {
+				v, ok := td.InEnv[key]
+				return v, ok
+			})
+			assert.Equal(t, td.OutConfig, cfg)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InConfig  Config
+		OutErrMsg string
+	}{
+		{
+			Name:     "PasswordAuth",
+			InConfig: Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Password: "p"},
+		},
+		{
+			Name:      "MissingBaseURL",
+			InConfig:  Config{Username: "me", Password: "p"},
+			OutErrMsg: `"baseURL" needs to be set to the address of your Jira, either in the config or through JIWA_BASE_URL, e.g. https://example.atlassian.net`,
+		},
+		{
+			Name:      "MissingUsername",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Token: "t"},
+			OutErrMsg: `"username" needs to be set, either in the config or through JIWA_USERNAME`,
+		},
+		{
+			Name:      "MissingCredentials",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me"},
+			OutErrMsg: `either "password" or "token" needs to be set, either in the config or through JIWA_PASSWORD or JIWA_TOKEN`,
+		},
+		{
+			Name:      "UnknownDescriptionFormat",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t", DescriptionFormat: "md"},
+			OutErrMsg: `"descriptionFormat" is "md" but needs to be "wiki" or "markdown"`,
+		},
+		{
+			Name:      "UnknownShowDetail",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t", ShowDetail: "verbose"},
+			OutErrMsg: `"showDetail" is "verbose" but needs to be "short", "default" or "full"`,
+		},
+		{
+			Name:     "Timezone",
+			InConfig: Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t", Timezone: "America/New_York"},
+		},
+		{
+			Name:      "UnknownTimezone",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me", Token: "t", Timezone: "CEST"},
+			OutErrMsg: `"timezone" is "CEST" but needs to be an IANA name like "Europe/Berlin", "UTC" or "Local"`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			err := td.InConfig.Validate()
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InGOOS      string
+		InConfigDir string
+		InHomeDir   string
+		InExisting  []string
+		Out         string
+	}{
+		{
+			Name:        "WindowsUsesAppData",
+			InGOOS:      "windows",
+			InConfigDir: filepath.Join("C:", "Users", "me", "AppData", "Roaming"),
+			InHomeDir:   filepath.Join("C:", "Users", "me"),
+			Out:         filepath.Join("C:", "Users", "me", "AppData", "Roaming", "jiwa", "config.json"),
+		},
+		{
+			Name:        "LinuxKeepsDotConfig",
+			InGOOS:      "linux",
+			InConfigDir: filepath.Join("/xdg", "config"),
+			InHomeDir:   filepath.Join("/home", "me"),
+			Out:         filepath.Join("/home", "me", ".config", "jiwa", "config.json"),
+		},
+		{
+			Name:        "LinuxXDGConfigHome",
+			InGOOS:      "linux",
+			InConfigDir: filepath.Join("/xdg", "config"),
+			InHomeDir:   filepath.Join("/home", "me"),
+			InExisting:  []string{filepath.Join("/xdg", "config", "jiwa", "config.json")},
+			Out:         filepath.Join("/xdg", "config", "jiwa", "config.json"),
+		},
+		{
+			Name:        "DarwinApplicationSupport",
+			InGOOS:      "darwin",
+			InConfigDir: filepath.Join("/Users", "me", "Library", "Application Support"),
+			InHomeDir:   filepath.Join("/Users", "me"),
+			InExisting:  []string{filepath.Join("/Users", "me", "Library", "Application Support", "jiwa", "config.json")},
+			Out:         filepath.Join("/Users", "me", "Library", "Application Support", "jiwa", "config.json"),
+		},
+		{
+			Name:      "NoConfigDir",
+			InGOOS:    "windows",
+			InHomeDir: filepath.Join("C:", "Users", "me"),
+			Out:       filepath.Join("C:", "Users", "me", ".config", "jiwa", "config.json"),
+		},
+		{
+			Name:        "NoHomeDir",
+			InGOOS:      "linux",
+			InConfigDir: filepath.Join("/xdg", "config"),
+			Out:         filepath.Join("/xdg", "config", "jiwa", "config.json"),
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			exists := func(p string) bool { return slices.Contains(td.InExisting, p) }
+			assert.Equal(t, td.Out, defaultConfigPath(td.InGOOS, td.InConfigDir, td.InHomeDir, exists))
+		})
+	}
+}
+
+func TestConfigSetGet(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InKey     string
+		InValue   string
+		Out       string
+		OutErrMsg string
+	}{
+		{Name: "String", InKey: "baseURL", InValue: "https://other.atlassian.net", Out: "https://other.atlassian.net"},
+		{Name: "Duration", InKey: "timeout", InValue: "30s", Out: "30s"},
+		{Name: "Bool", InKey: "disableDuplicateCheck", InValue: "true", Out: "true"},
+		{Name: "Int", InKey: "concurrency", InValue: "8", Out: "8"},
+		{Name: "List", InKey: "triagePool", InValue: `["alice", "bob"]`, Out: `["alice","bob"]`},
+		{Name: "Map", InKey: "projectGroups", InValue: `{"infra": ["OPS", "NET"]}`, Out: `{"infra":["OPS","NET"]}`},
+		{Name: "UnknownKey", InKey: "defaultProjetc", InValue: "OPS", OutErrMsg: `unknown key "defaultProjetc", did you mean "defaultProject"?`},
+		{Name: "NotADuration", InKey: "timeout", InValue: "5", OutErrMsg: `"timeout" needs to be a duration like 30s or 2m, got "5"`},
+		{Name: "NotJSON", InKey: "triagePool", InValue: "alice", OutErrMsg: `"triagePool" needs to be given as JSON: invalid JSON at line 1, column 1: invalid character 'a' looking for beginning of value`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config.json")
+			err := os.WriteFile(path, []byte("{\n    \"username\": \"me\",\n    \"hooks\": {\"post-create\": \"notify\"}\n}\n"), 0o640)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ConfigSet(path, td.InKey, td.InValue)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			value, err := ConfigGet(path, td.InKey)
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, value)
+
+			username, err := ConfigGet(path, "username")
+			assert.NoError(t, err)
+			assert.Equal(t, "me", username)
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		})
+	}
+}
+
+func TestConfigSet_KeepsFormatting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jiwa", "config.json")
+
+	err := ConfigSet(path, "baseURL", "https://catouc.atlassian.net")
+	assert.NoError(t, err)
+	err = ConfigSet(path, "username", "me")
+	assert.NoError(t, err)
+	err = ConfigSet(path, "baseURL", "https://other.atlassian.net")
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"baseURL\": \"https://other.atlassian.net\",\n  \"username\": \"me\"\n}\n", string(b))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = ConfigGet(path, "token")
+	assert.EqualError(t, err, `"token" is not set in `+path)
+}
+
+func TestCommand_Context(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1"})
+	client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := Command{Client: client, Context: ctx}
+
+	_, err = c.Cat("JIWA-1")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, srv.Requests())
+}
+
+func TestBuildSummaryAndDescriptionFromScanner(t *testing.T) {
+	testData := []struct {
+		Name           string
+		In             string
+		OutSummary     string
+		OutDescription string
+		OutErrMsg      string
+	}{
+		{
+			Name:           "FirstLine",
+			In:             "Summary\nFirst line\n\nSecond line\n",
+			OutSummary:     "Summary",
+			OutDescription: "First line\n\nSecond line",
+		},
+		{
+			Name:           "LeadingBlankLines",
+			In:             "\n  \nSummary\nDescription\n",
+			OutSummary:     "Summary",
+			OutDescription: "Description",
+		},
+		{
+			Name:           "TrailingBlankLines",
+			In:             "Summary\nDescription\n\n \n\n",
+			OutSummary:     "Summary",
+			OutDescription: "Description",
+		},
+		{
+			Name:           "CRLF",
+			In:             "Summary\r\nFirst line\r\n---\r\nSecond line\r\n",
+			OutSummary:     "Summary First line",
+			OutDescription: "Second line",
+		},
+		{
+			Name:           "SeparatorJoinsWrappedSummary",
+			In:             "A summary that\n   wraps  over\nthree lines\n---\nDescription\n---\nwith a rule\n",
+			OutSummary:     "A summary that wraps over three lines",
+			OutDescription: "Description\n---\nwith a rule",
+		},
+		{
+			Name:           "SeparatorSkipsHeading",
+			In:             "Summary\n---\n# Heading\nDescription\n",
+			OutSummary:     "Summary",
+			OutDescription: "# Heading\nDescription",
+		},
+		{
+			Name:           "SeparatorTooFarDownIsARule",
+			In:             "Summary\n1\n2\n3\n4\n---\n5\n",
+			OutSummary:     "Summary",
+			OutDescription: "1\n2\n3\n4\n---\n5",
+		},
+		{
+			Name:      "SeparatorAtTheTop",
+			In:        "\n---\nSummary\nDescription\n",
+			OutErrMsg: "there is nothing above the --- line, the summary goes there",
+		},
+		{
+			Name:       "OnlySummary",
+			In:         "Summary",
+			OutSummary: "Summary",
+		},
+		{
+			Name: "Empty",
+			In:   "\n\n",
+		},
+		{
+			Name:      "SummaryTooLong",
+			In:        strings.Repeat("ä", 256) + "\nDescription\n",
+			OutErrMsg: "the summary is 256 characters long but Jira only takes 255, move the rest into the description",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			summary, description, err := BuildSummaryAndDescriptionFromScanner(bufio.NewScanner(strings.NewReader(td.In)))
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutSummary, summary)
+			assert.Equal(t, td.OutDescription, description)
+		})
+	}
+}
+
+func TestFormatSummaryDescription(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InSummary     string
+		InDescription string
+		Out           string
+	}{
+		{
+			Name:          "Plain",
+			InSummary:     "Summary",
+			InDescription: "Description",
+			Out:           "Summary\nDescription",
+		},
+		{
+			Name:          "RuleNearTheTop",
+			InSummary:     "Summary",
+			InDescription: "Intro\n---\nDetails",
+			Out:           "Summary\n---\nIntro\n---\nDetails",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out := FormatSummaryDescription(td.InSummary, td.InDescription)
+			assert.Equal(t, td.Out, out)
+
+			summary, description, err := BuildSummaryAndDescriptionFromScanner(bufio.NewScanner(strings.NewReader(out)))
+			assert.NoError(t, err)
+			assert.Equal(t, td.InSummary, summary)
+			assert.Equal(t, td.InDescription, description)
+		})
+	}
+}
+
+func TestSplitLongSummary(t *testing.T) {
+	words := strings.TrimSpace(strings.Repeat("word ", 60))
+
+	testData := []struct {
+		Name           string
+		InSummary      string
+		InDescription  string
+		OutSummary     string
+		OutDescription string
+	}{
+		{
+			Name:           "Short",
+			InSummary:      "Summary",
+			InDescription:  "Description",
+			OutSummary:     "Summary",
+			OutDescription: "Description",
+		},
+		{
+			Name:       "AtTheLimit",
+			InSummary:  strings.Repeat("x", 255),
+			OutSummary: strings.Repeat("x", 255),
+		},
+		{
+			Name:           "WordBoundary",
+			InSummary:      words,
+			InDescription:  "Description",
+			OutSummary:     words[:254] + "…",
+			OutDescription: "…" + words[255:] + "\n\nDescription",
+		},
+		{
+			Name:           "NoSpace",
+			InSummary:      strings.Repeat("ä", 300),
+			OutSummary:     strings.Repeat("ä", 254) + "…",
+			OutDescription: "…" + strings.Repeat("ä", 46),
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			summary, description := SplitLongSummary(td.InSummary, td.InDescription)
+			assert.Equal(t, td.OutSummary, summary)
+			assert.Equal(t, td.OutDescription, description)
+			assert.LessOrEqual(t, utf8.RuneCountInString(summary), maxSummaryLength)
+		})
+	}
+}
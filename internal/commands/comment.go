@@ -1,12 +1,23 @@
 package commands
 
 import (
-	"context"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
 func (c *Command) Comment(issues []string, comment string) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionAddComments, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.CheckVisibility(issues...)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, i := range issues {
-		err := c.Client.CommentOnIssue(context.TODO(), i, comment)
+		err := c.comment(i, comment)
 		if err != nil {
 			return nil, err
 		}
@@ -14,3 +25,30 @@ func (c *Command) Comment(issues []string, comment string) ([]string, error) {
 
 	return issues, nil
 }
+
+func (c *Command) comment(key, comment string) error {
+	text, err := c.withMentions(mentionScope{IssueKey: key}, comment, "")
+	if err != nil {
+		return err
+	}
+
+	payload := hooks.Payload{Key: key, Comment: text}
+	err = c.runPreHook("pre-comment", payload)
+	if err != nil {
+		return err
+	}
+
+	if c.VisibleTo != nil {
+		err = c.Client.CommentOnIssueVisibleTo(c.ctx(), key, text, *c.VisibleTo)
+	} else {
+		err = c.Client.CommentOnIssue(c.ctx(), key, text)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.runPostHook("post-comment", payload)
+	c.remember(payload.Key)
+
+	return nil
+}
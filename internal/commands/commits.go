@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/git"
+)
+
+// CommitsInput says which commits jiwa commits records and where
+type CommitsInput struct {
+	// Range is a revision range like origin/main..HEAD, without it the
+	// commits mentioning the issue key are recorded
+	Range string
+	// Description appends the list to the description instead of adding
+	// it as a comment
+	Description bool
+}
+
+// Commits records the commits of the range, or the ones mentioning the
+// issue, on the issue as a list of short hashes and subjects. It fails
+// outside a git repository and if there are no commits. The subjects are
+// sent as they are, @names in them aren't turned into mentions.
+func (c *Command) Commits(key string, input CommitsInput) ([]git.Commit, error) {
+	var commits []git.Commit
+	var err error
+	if input.Range != "" {
+		commits, err = c.Git.Log(c.ctx(), input.Range)
+	} else {
+		commits, err = c.Git.Mentioning(c.ctx(), key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(commits) == 0 {
+		if input.Range != "" {
+			return nil, fmt.Errorf("there are no commits in %s, nothing to record on %s", input.Range, key)
+		}
+		return nil, fmt.Errorf("no commit mentions %s, pass --range to record a range of commits instead", key)
+	}
+
+	heading := "Commits mentioning " + key
+	if input.Range != "" {
+		heading = "Commits in " + input.Range
+	}
+	text := formatCommits(heading, commits)
+
+	noMentions := c.NoMentions
+	c.NoMentions = true
+	defer func() { c.NoMentions = noMentions }()
+
+	if input.Description {
+		_, err = c.appendToDescription(key, text)
+	} else {
+		_, err = c.Comment([]string{key}, text)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// commitEscaper keeps braces and brackets in subjects from being read as
+// wiki markup macros and links
+var commitEscaper = strings.NewReplacer("{", `\{`, "}", `\}`, "[", `\[`, "]", `\]`)
+
+// formatCommits lists the commits in wiki markup, one bullet with the
+// short hash and subject per commit, oldest first like they were made
+func formatCommits(heading string, commits []git.Commit) string {
+	var sb strings.Builder
+	sb.WriteString(heading + ":\n")
+	for i := len(commits) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "* {{%s}} %s\n", commits[i].ShortHash, commitEscaper.Replace(commits[i].Subject))
+	}
+
+	return sb.String()
+}
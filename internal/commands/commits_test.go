@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/git"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Commits(t *testing.T) {
+	log := "2b3c4d5e\x1f2b3c4d5\x1fBump @types/node to {20}\x1f\x1e\n" +
+		"1a2b3c4d\x1f1a2b3c4\x1fJIWA-1 Fix the redirect\x1f\x1e\n"
+
+	testData := []struct {
+		Name           string
+		InInput        CommitsInput
+		InLog          string
+		OutComment     string
+		OutDescription string
+		OutErrMsg      string
+	}{
+		{
+			Name:       "RangeAsComment",
+			InInput:    CommitsInput{Range: "origin/main..HEAD"},
+			InLog:      log,
+			OutComment: "Commits in origin/main..HEAD:\n* {{1a2b3c4}} JIWA-1 Fix the redirect\n* {{2b3c4d5}} Bump @types/node to \\{20\\}\n",
+		},
+		{
+			Name:           "MentionsToDescription",
+			InInput:        CommitsInput{Description: true},
+			InLog:          log,
+			OutDescription: "Some details\nCommits mentioning JIWA-1:\n* {{1a2b3c4}} JIWA-1 Fix the redirect",
+		},
+		{
+			Name:      "EmptyRange",
+			InInput:   CommitsInput{Range: "HEAD..HEAD"},
+			OutErrMsg: "there are no commits in HEAD..HEAD, nothing to record on JIWA-1",
+		},
+		{
+			Name:      "NoMentions",
+			OutErrMsg: "no commit mentions JIWA-1, pass --range to record a range of commits instead",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Login loops", Description: "Some details"}}
+
+			c := Command{Client: fake, NoPrompt: true, Git: git.Repo{Run: func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				if args[0] == "log" {
+					return []byte(td.InLog), nil
+				}
+				return nil, nil
+			}}}
+			_, err := c.Commits("JIWA-1", td.InInput)
+			issue := fake.Issues["JIWA-1"]
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Nil(t, issue.Fields.Comments)
+				return
+			}
+			assert.NoError(t, err)
+			assert.False(t, c.NoMentions, "mentions are only left alone for the commits")
+
+			if td.OutComment != "" {
+				assert.Equal(t, td.OutComment, issue.Fields.Comments.Comments[0].Body)
+			}
+			if td.OutDescription != "" {
+				assert.Equal(t, td.OutDescription, issue.Fields.Description)
+				assert.Nil(t, issue.Fields.Comments)
+			}
+		})
+	}
+}
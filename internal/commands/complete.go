@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/catouc/jiwa/internal/state"
+)
+
+// labelSuggestionTTL is how long the labels suggested for a prefix are
+// completed from the state before the instance is asked again
+const labelSuggestionTTL = 5 * time.Minute
+
+// CompleteLabels returns the labels in use on the instance that start with
+// the prefix, for shell completion. Each prefix is asked for once every
+// few minutes, pressing tab again is answered from the state.
+func (c *Command) CompleteLabels(prefix string) ([]string, error) {
+	now := time.Now()
+	if c.State != nil {
+		st, err := c.State.Load()
+		if s, ok := st.LabelSuggestions[prefix]; err == nil && ok && now.Sub(s.At) < labelSuggestionTTL {
+			return s.Values, nil
+		}
+	}
+
+	labels, err := c.Client.SuggestLabels(c.ctx(), prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// completion has to stay quiet, failing to cache only costs a request
+	if c.State != nil {
+		_ = c.State.Update(func(st *state.State) error {
+			for p, s := range st.LabelSuggestions {
+				if now.Sub(s.At) >= labelSuggestionTTL {
+					delete(st.LabelSuggestions, p)
+				}
+			}
+			if st.LabelSuggestions == nil {
+				st.LabelSuggestions = make(map[string]state.Suggestions)
+			}
+			st.LabelSuggestions[prefix] = state.Suggestions{Values: labels, At: now}
+			return nil
+		})
+	}
+
+	return labels, nil
+}
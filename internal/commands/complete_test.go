@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_CompleteLabels(t *testing.T) {
+	fake := jiwafake.New()
+	fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Labels: []string{"on-call", "ops", "urgent"}}}
+	store := &state.Store{Path: filepath.Join(t.TempDir(), "state.json")}
+	c := Command{Client: fake, State: store}
+
+	labels, err := c.CompleteLabels("o")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"on-call", "ops"}, labels)
+
+	// the instance isn't asked again for the same prefix
+	fake.Errors["SuggestLabels"] = errors.New("unreachable")
+	labels, err = c.CompleteLabels("o")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"on-call", "ops"}, labels)
+
+	_, err = c.CompleteLabels("u")
+	assert.EqualError(t, err, "unreachable")
+
+	// until the suggestions are too old
+	err = store.Update(func(st *state.State) error {
+		st.LabelSuggestions["o"] = state.Suggestions{Values: []string{"ops"}, At: time.Now().Add(-labelSuggestionTTL)}
+		return nil
+	})
+	assert.NoError(t, err)
+	delete(fake.Errors, "SuggestLabels")
+	labels, err = c.CompleteLabels("o")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"on-call", "ops"}, labels)
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// SetComponents replaces the components of the issues, the names are
+// looked up in the project of each issue.
+func (c *Command) SetComponents(issues, components []string) ([]string, error) {
+	if len(components) == 0 {
+		return nil, errors.New("need to supply at least one component")
+	}
+
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string][]string)
+	for _, issue := range issues {
+		project := projectOf(issue)
+		names, ok := resolved[project]
+		if !ok {
+			var err error
+			names, err = c.resolveComponents(c.ctx(), project, components)
+			if err != nil {
+				return nil, err
+			}
+			resolved[project] = names
+		}
+
+		payload := hooks.Payload{Key: issue, Component: names[0], Components: names}
+		err := c.runPreHook("pre-component", payload)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.Client.UpdateIssue(c.ctx(), issue, jiwa.UpdateIssueInput{Components: names})
+		if err != nil {
+			return nil, err
+		}
+
+		c.runPostHook("post-component", payload)
+		c.remember(payload.Key)
+	}
+
+	return issues, nil
+}
+
+// resolveComponents maps the names onto the project's components ignoring
+// case, so they are sent the way they are spelled in Jira.
+func (c *Command) resolveComponents(ctx context.Context, project string, names []string) ([]string, error) {
+	components, err := c.Client.ListComponents(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]string, 0, len(components))
+	for _, pc := range components {
+		valid = append(valid, pc.Name)
+	}
+	sort.Strings(valid)
+
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		match := ""
+		for _, v := range valid {
+			if strings.EqualFold(v, name) {
+				match = v
+				break
+			}
+		}
+
+		if match == "" {
+			return nil, unknownComponentError(project, name, valid)
+		}
+		resolved = append(resolved, match)
+	}
+
+	return resolved, nil
+}
+
+func unknownComponentError(project, name string, valid []string) error {
+	if len(valid) == 0 {
+		return fmt.Errorf("unknown component %q, %s has no components", name, project)
+	}
+
+	msg := fmt.Sprintf("unknown component %q in %s", name, project)
+	if s := Suggest(name, valid); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+
+	return fmt.Errorf("%s, valid components are: %s", msg, strings.Join(valid, ","))
+}
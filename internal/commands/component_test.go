@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_SetComponents(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InIssues      []string
+		InComponents  []string
+		OutComponents map[string][]string
+		OutErrMsg     string
+	}{
+		{
+			Name:         "IgnoresCase",
+			InIssues:     []string{"JIWA-1"},
+			InComponents: []string{"API", "frontend"},
+			OutComponents: map[string][]string{
+				"JIWA-1": {"api", "Frontend"},
+			},
+		},
+		{
+			Name:         "EachIssueInItsProject",
+			InIssues:     []string{"JIWA-1", "OPS-1"},
+			InComponents: []string{"api"},
+			OutComponents: map[string][]string{
+				"JIWA-1": {"api"},
+				"OPS-1":  {"API"},
+			},
+		},
+		{
+			Name:         "Typo",
+			InIssues:     []string{"JIWA-1"},
+			InComponents: []string{"fronted"},
+			OutErrMsg:    `unknown component "fronted" in JIWA, did you mean "Frontend"?, valid components are: Frontend,api`,
+		},
+		{
+			Name:         "ProjectWithoutComponents",
+			InIssues:     []string{"DOCS-1"},
+			InComponents: []string{"api"},
+			OutErrMsg:    `unknown component "api", DOCS has no components`,
+		},
+		{
+			Name:      "NoComponents",
+			InIssues:  []string{"JIWA-1"},
+			OutErrMsg: "need to supply at least one component",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Projects["JIWA"] = jira.Project{Key: "JIWA", Components: []jira.ProjectComponent{{Name: "api"}, {Name: "Frontend"}}}
+			fake.Projects["OPS"] = jira.Project{Key: "OPS", Components: []jira.ProjectComponent{{Name: "API"}}}
+			fake.Projects["DOCS"] = jira.Project{Key: "DOCS"}
+			for _, p := range []string{"JIWA", "OPS", "DOCS"} {
+				_, err := fake.CreateIssue(context.Background(), jiwa.CreateIssueInput{Project: p, Summary: "Test"})
+				assert.NoError(t, err)
+			}
+			c := Command{Client: fake}
+
+			updated, err := c.SetComponents(td.InIssues, td.InComponents)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.InIssues, updated)
+
+			for key, want := range td.OutComponents {
+				var got []string
+				for _, component := range fake.Issues[key].Fields.Components {
+					got = append(got, component.Name)
+				}
+				assert.Equal(t, want, got, key)
+			}
+		})
+	}
+}
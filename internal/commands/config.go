@@ -0,0 +1,476 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseConfig decodes the configuration file, unlike json.Unmarshal it
+// rejects keys it doesn't know so typos like "baseUrl" surface right away
+// instead of as confusing API errors later on. Keys have to match their
+// documented spelling exactly, encoding/json would otherwise quietly accept
+// them in any casing.
+func ParseConfig(r io.Reader) (Config, error) {
+	var cfg Config
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return cfg, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&cfg)
+	if err != nil {
+		return cfg, configDecodeError(b, err)
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return cfg, configDecodeError(b, err)
+	}
+
+	keys := ConfigKeys()
+	for k := range raw {
+		if !slices.Contains(keys, k) {
+			return cfg, unknownKeyError(k)
+		}
+	}
+
+	return cfg, nil
+}
+
+func configDecodeError(b []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, col := lineAndColumn(b, syntaxErr.Offset)
+		return fmt.Errorf("invalid JSON at line %d, column %d: %w", line, col, err)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%q needs to be a %s but is a JSON %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return unknownKeyError(strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`))
+	case errors.Is(err, io.EOF):
+		return errors.New("the file is empty")
+	default:
+		return err
+	}
+}
+
+func unknownKeyError(key string) error {
+	msg := fmt.Sprintf("unknown key %q", key)
+	if s := Suggest(key, ConfigKeys()); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+
+	return errors.New(msg)
+}
+
+func lineAndColumn(b []byte, offset int64) (int, int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n') - 1
+
+	return line, col
+}
+
+// DefaultConfigPath returns where the configuration file is looked for
+// when neither --config nor JIWA_CONFIG are set.
+func DefaultConfigPath() (string, error) {
+	configDir, configErr := os.UserConfigDir()
+	homeDir, homeErr := os.UserHomeDir()
+	if configErr != nil && homeErr != nil {
+		return "", fmt.Errorf("cannot locate user config dir, is `$HOME` set? Detailed error: %w", configErr)
+	}
+
+	return defaultConfigPath(runtime.GOOS, configDir, homeDir, func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}), nil
+}
+
+// defaultConfigPath puts the file in the config dir of the OS, e.g.
+// %AppData%\jiwa\config.json on Windows. Everywhere else
+// $HOME/.config/jiwa/config.json, where jiwa always looked for it, is
+// still used unless there is a file in the config dir.
+func defaultConfigPath(goos, configDir, homeDir string, exists func(string) bool) string {
+	path := ""
+	if configDir != "" {
+		path = filepath.Join(configDir, "jiwa", "config.json")
+	}
+
+	if path != "" && (goos == "windows" || homeDir == "" || exists(path)) {
+		return path
+	}
+
+	return filepath.Join(homeDir, ".config", "jiwa", "config.json")
+}
+
+// ConfigKeys returns all the keys that the configuration file accepts
+func ConfigKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+
+	return keys
+}
+
+// ApplyEnv overrides the values the environment sets, e.g. in CI where
+// there is no configuration file at all. A variable that is set wins over
+// the file even if it is empty.
+func (c *Config) ApplyEnv(lookup func(key string) (string, bool)) {
+	vars := []struct {
+		Name  string
+		Value *string
+	}{
+		{"JIWA_BASE_URL", &c.BaseURL},
+		{"JIWA_API_VERSION", &c.APIVersion},
+		{"JIWA_ENDPOINT_PREFIX", &c.EndpointPrefix},
+		{"JIWA_DEFAULT_PROJECT", &c.DefaultProject},
+		{"JIWA_USERNAME", &c.Username},
+		{"JIWA_PASSWORD", &c.Password},
+		{"JIWA_TOKEN", &c.Token},
+	}
+	for _, v := range vars {
+		if value, set := lookup(v.Name); set {
+			*v.Value = value
+		}
+	}
+}
+
+// Validate reports the first required value that is missing
+func (c *Config) Validate() error {
+	switch {
+	case c.BaseURL == "":
+		return errors.New("\"baseURL\" needs to be set to the address of your Jira, either in the config or through JIWA_BASE_URL, e.g. https://example.atlassian.net")
+	case c.Username == "":
+		return errors.New("\"username\" needs to be set, either in the config or through JIWA_USERNAME")
+	case c.Token == "" && c.Password == "":
+		return errors.New("either \"password\" or \"token\" needs to be set, either in the config or through JIWA_PASSWORD or JIWA_TOKEN")
+	case c.DescriptionFormat != "" && c.DescriptionFormat != "wiki" && c.DescriptionFormat != "markdown":
+		return fmt.Errorf("\"descriptionFormat\" is %q but needs to be \"wiki\" or \"markdown\"", c.DescriptionFormat)
+	case c.ShowDetail != "" && c.ShowDetail != string(ShowShort) && c.ShowDetail != string(ShowDefault) && c.ShowDetail != string(ShowFull):
+		return fmt.Errorf("\"showDetail\" is %q but needs to be \"short\", \"default\" or \"full\"", c.ShowDetail)
+	case c.Timezone != "" && !validTimezone(c.Timezone):
+		return fmt.Errorf("\"timezone\" is %q but needs to be an IANA name like \"Europe/Berlin\", \"UTC\" or \"Local\"", c.Timezone)
+	default:
+		return nil
+	}
+}
+
+func validTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Suggest returns the candidate closest to input if it is close enough to
+// be a plausible typo, and an empty string otherwise.
+func Suggest(input string, candidates []string) string {
+	best := ""
+	bestDistance := len(input)/2 + 1
+	for _, c := range candidates {
+		if strings.EqualFold(input, c) {
+			return c
+		}
+
+		d := levenshtein(strings.ToLower(input), strings.ToLower(c))
+		if d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// ConfigGet returns the value of key in the configuration file the way
+// ConfigSet takes it: strings without quotes, durations like "30s" and
+// lists and maps as compact JSON.
+func ConfigGet(path, key string) (string, error) {
+	field, err := configField(key)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := parseConfigEntries(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	i := slices.IndexFunc(entries, func(e configEntry) bool { return e.Key == key })
+	if i == -1 {
+		return "", fmt.Errorf("%q is not set in %s", key, path)
+	}
+	raw := entries[i].Value
+
+	switch field.Type {
+	case reflect.TypeOf(""):
+		var s string
+		err = json.Unmarshal(raw, &s)
+		if err != nil {
+			return "", configDecodeError(raw, err)
+		}
+		return s, nil
+	case reflect.TypeOf(time.Duration(0)):
+		var d time.Duration
+		err = json.Unmarshal(raw, &d)
+		if err != nil {
+			return "", configDecodeError(raw, err)
+		}
+		return d.String(), nil
+	default:
+		var out bytes.Buffer
+		err = json.Compact(&out, raw)
+		if err != nil {
+			return "", configDecodeError(raw, err)
+		}
+		return out.String(), nil
+	}
+}
+
+// ConfigSet sets key in the configuration file to value, creating the file
+// if there is none yet. Durations take "30s" instead of nanoseconds, lists
+// and maps are given as JSON. Every other key keeps its place and value as
+// it was written, and the file keeps its permissions, 0600 if it is new.
+func ConfigSet(path, key, value string) error {
+	field, err := configField(key)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(path)
+	mode := os.FileMode(0o600)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		b = []byte("{}\n")
+	case err != nil:
+		return err
+	default:
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	}
+
+	entries, err := parseConfigEntries(b)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	indent := configIndent(b)
+	raw, err := configValue(field, key, value, indent)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(entries, func(e configEntry) bool { return e.Key == key })
+	if i == -1 {
+		entries = append(entries, configEntry{Key: key, Value: raw})
+	} else {
+		entries[i].Value = raw
+	}
+
+	out := formatConfigEntries(entries, indent)
+	_, err = ParseConfig(bytes.NewReader(out))
+	if err != nil {
+		return fmt.Errorf("refusing to write %s: %w", path, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	// an interrupted write leaves the config as it was
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, out, mode)
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// IsSecretConfigKey tells the keys whose values should rather come from
+// the environment than sit in the file in plaintext
+func IsSecretConfigKey(key string) bool {
+	return key == "password" || key == "token"
+}
+
+func configField(key string) (reflect.StructField, error) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == key {
+			return t.Field(i), nil
+		}
+	}
+
+	return reflect.StructField{}, unknownKeyError(key)
+}
+
+// configValue turns what was passed on the command line into the JSON the
+// field decodes from
+func configValue(field reflect.StructField, key, value, indent string) (json.RawMessage, error) {
+	var v any
+	switch field.Type.Kind() {
+	case reflect.String:
+		v = value
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q needs to be true or false, got %q", key, value)
+		}
+		v = b
+	case reflect.Int, reflect.Int64:
+		if field.Type == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("%q needs to be a duration like 30s or 2m, got %q", key, value)
+			}
+			v = d
+			break
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q needs to be a number, got %q", key, value)
+		}
+		v = n
+	default:
+		ptr := reflect.New(field.Type)
+		err := json.Unmarshal([]byte(value), ptr.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("%q needs to be given as JSON: %w", key, configDecodeError([]byte(value), err))
+		}
+		v = ptr.Elem().Interface()
+	}
+
+	return json.MarshalIndent(v, indent, indent)
+}
+
+type configEntry struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// parseConfigEntries reads the top level keys in the order they are
+// written, with their values exactly as they are in the file
+func parseConfigEntries(b []byte) ([]configEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, configDecodeError(b, err)
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("the configuration needs to be a JSON object")
+	}
+
+	entries := make([]configEntry, 0)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, configDecodeError(b, err)
+		}
+		key, _ := tok.(string)
+
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return nil, configDecodeError(b, err)
+		}
+		entries = append(entries, configEntry{Key: key, Value: raw})
+	}
+
+	_, err = dec.Token()
+	if err != nil {
+		return nil, configDecodeError(b, err)
+	}
+
+	return entries, nil
+}
+
+var configIndentRegEx = regexp.MustCompile(`\n([ \t]+)"`)
+
+// configIndent is the indentation of the first key, two spaces if the
+// file has none yet
+func configIndent(b []byte) string {
+	m := configIndentRegEx.FindSubmatch(b)
+	if m == nil {
+		return "  "
+	}
+
+	return string(m[1])
+}
+
+func formatConfigEntries(entries []configEntry, indent string) []byte {
+	var out bytes.Buffer
+	out.WriteString("{\n")
+	for i, e := range entries {
+		key, _ := json.Marshal(e.Key)
+		out.WriteString(indent)
+		out.Write(key)
+		out.WriteString(": ")
+		out.Write(e.Value)
+		if i < len(entries)-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}\n")
+
+	return out.Bytes()
+}
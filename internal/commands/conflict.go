@@ -0,0 +1,282 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/catouc/jiwa/internal/editor"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// issueText is the part of an issue edit works on
+type issueText struct {
+	Summary     string
+	Description string
+}
+
+// changedSince returns the summary and description of the issue if it was
+// updated after the given time. Only the timestamp is fetched if it
+// wasn't, which is most of the time.
+func (c *Command) changedSince(key string, updated time.Time) (issueText, time.Time, bool, error) {
+	issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("updated"))
+	if err != nil {
+		return issueText{}, time.Time{}, false, fmt.Errorf("failed to check whether %s was changed: %w", key, err)
+	}
+	if time.Time(issue.Fields.Updated).Equal(updated) {
+		return issueText{}, updated, false, nil
+	}
+
+	issue, err = c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("summary", "description", "updated"))
+	if err != nil {
+		return issueText{}, time.Time{}, false, fmt.Errorf("failed to get the changes to %s: %w", key, err)
+	}
+
+	return issueText{Summary: issue.Fields.Summary, Description: issue.Fields.Description}, time.Time(issue.Fields.Updated), true, nil
+}
+
+// conflicts reports whether they changed a field the input changes as
+// well, to something else. Comments, transitions and the other fields
+// update the issue too but don't get in the way of an edit.
+func conflicts(base, theirs issueText, input jiwa.UpdateIssueInput) bool {
+	if input.Summary != nil && theirs.Summary != base.Summary && theirs.Summary != *input.Summary {
+		return true
+	}
+
+	trim := func(s string) string { return strings.TrimRight(s, "\n") }
+	return input.Description != nil && trim(theirs.Description) != trim(base.Description) && trim(theirs.Description) != trim(*input.Description)
+}
+
+// formatConflict shows what both sides did to the fields that conflict,
+// each compared to what was opened in the editor
+func formatConflict(key string, base, mine, theirs issueText) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s was changed by someone else while you were editing it\n", key)
+
+	if _, conflict := mergeField(base.Summary, mine.Summary, theirs.Summary); conflict {
+		fmt.Fprintf(&b, "summary:\n  original: %s\n  mine:     %s\n  theirs:   %s\n", base.Summary, mine.Summary, theirs.Summary)
+	}
+
+	if _, conflict := mergeLines(splitLines(base.Description), splitLines(mine.Description), splitLines(theirs.Description)); conflict {
+		b.WriteString("description, mine:\n")
+		writeLineDiff(&b, base.Description, mine.Description)
+		b.WriteString("description, theirs:\n")
+		writeLineDiff(&b, base.Description, theirs.Description)
+	}
+
+	return b.String()
+}
+
+// conflictChoice is how a conflicting edit is dealt with
+type conflictChoice int
+
+const (
+	conflictAbort conflictChoice = iota
+	conflictMerge
+	conflictOverwrite
+)
+
+// askConflict asks on the terminal what to do about a conflicting edit.
+// Without one the edit is refused, a script can't be asked and shouldn't
+// overwrite anybody.
+func askConflict(key string) (conflictChoice, error) {
+	p, err := prompt.Open()
+	if err != nil {
+		return conflictAbort, fmt.Errorf("%w: not overwriting the changes to %s without a terminal to ask on", ErrAbortedByUser, key)
+	}
+	defer p.Close()
+
+	return chooseConflict(p)
+}
+
+func chooseConflict(p *prompt.Prompter) (conflictChoice, error) {
+	for {
+		answer, err := p.Ask("[m]erge in the editor, [o]verwrite their changes or [a]bort? ")
+		if err != nil {
+			return conflictAbort, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "m", "merge":
+			return conflictMerge, nil
+		case "o", "overwrite":
+			return conflictOverwrite, nil
+		case "a", "abort", "":
+			return conflictAbort, nil
+		}
+		p.Printf("please answer m, o or a\n")
+	}
+}
+
+// mergeField merges a field that isn't split into lines, the summary. On a
+// conflict mine is kept.
+func mergeField(base, mine, theirs string) (string, bool) {
+	switch {
+	case mine == theirs || theirs == base:
+		return mine, false
+	case mine == base:
+		return theirs, false
+	default:
+		return mine, true
+	}
+}
+
+const (
+	conflictMine   = "<<<<<<< mine"
+	conflictSep    = "======="
+	conflictTheirs = ">>>>>>> theirs"
+)
+
+// lineEdit replaces the lines [Start, End) of the original with Lines
+type lineEdit struct {
+	Start int
+	End   int
+	Lines []string
+}
+
+// lineEdits turns the diff of a and b into the edits that make b out of a
+func lineEdits(a, b []string) []lineEdit {
+	edits := make([]lineEdit, 0)
+	var cur *lineEdit
+	i := 0
+	for _, l := range diffLines(a, b) {
+		if l.Op == ' ' {
+			if cur != nil {
+				edits = append(edits, *cur)
+				cur = nil
+			}
+			i++
+			continue
+		}
+
+		if cur == nil {
+			cur = &lineEdit{Start: i, End: i}
+		}
+		if l.Op == '-' {
+			i++
+			cur.End = i
+		} else {
+			cur.Lines = append(cur.Lines, l.Text)
+		}
+	}
+	if cur != nil {
+		edits = append(edits, *cur)
+	}
+
+	return edits
+}
+
+// mergeLines is a three-way merge of the lines, changes to different parts
+// of base are both taken. Where the changes overlap or touch and differ,
+// both are kept between conflict markers like git does.
+func mergeLines(base, mine, theirs []string) ([]string, bool) {
+	ours, others := lineEdits(base, mine), lineEdits(base, theirs)
+
+	out := make([]string, 0, len(base))
+	conflict := false
+	pos := 0
+	for len(ours) != 0 || len(others) != 0 {
+		start := len(base)
+		if len(ours) != 0 {
+			start = ours[0].Start
+		}
+		if len(others) != 0 && others[0].Start < start {
+			start = others[0].Start
+		}
+
+		// the edits of either side that overlap the chunk make it grow
+		// until nothing does anymore
+		end := start
+		var o, t []lineEdit
+		for grew := true; grew; {
+			grew = false
+			if len(ours) != 0 && ours[0].Start <= end {
+				o, end, ours, grew = append(o, ours[0]), max(end, ours[0].End), ours[1:], true
+			}
+			if len(others) != 0 && others[0].Start <= end {
+				t, end, others, grew = append(t, others[0]), max(end, others[0].End), others[1:], true
+			}
+		}
+
+		out = append(out, base[pos:start]...)
+		mineChunk, theirChunk := applyEdits(base, start, end, o), applyEdits(base, start, end, t)
+		switch {
+		case len(t) == 0 || slices.Equal(mineChunk, theirChunk):
+			out = append(out, mineChunk...)
+		case len(o) == 0:
+			out = append(out, theirChunk...)
+		default:
+			conflict = true
+			out = append(out, conflictMine)
+			out = append(out, mineChunk...)
+			out = append(out, conflictSep)
+			out = append(out, theirChunk...)
+			out = append(out, conflictTheirs)
+		}
+		pos = end
+	}
+
+	return append(out, base[pos:]...), conflict
+}
+
+// applyEdits returns the lines [start, end) of base with the edits made
+func applyEdits(base []string, start, end int, edits []lineEdit) []string {
+	out := make([]string, 0, end-start)
+	pos := start
+	for _, e := range edits {
+		out = append(out, base[pos:e.Start]...)
+		out = append(out, e.Lines...)
+		pos = e.End
+	}
+
+	return append(out, base[pos:end]...)
+}
+
+// mergeBuffer is what the editor is opened with to merge the edit with
+// their changes, what couldn't be merged is explained on top
+func mergeBuffer(key string, base, mine, theirs issueText) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s was changed while you were editing it, this is both merged\n", editor.ErrorPrefix, key)
+
+	summary, conflict := mergeField(base.Summary, mine.Summary, theirs.Summary)
+	if conflict {
+		fmt.Fprintf(&b, "%syour summary is kept, theirs is: %s\n", editor.ErrorPrefix, theirs.Summary)
+	}
+
+	lines, conflict := mergeLines(splitLines(base.Description), splitLines(mine.Description), splitLines(theirs.Description))
+	if conflict {
+		fmt.Fprintf(&b, "%spick between the %q and %q lines of the description\n", editor.ErrorPrefix, conflictMine, conflictTheirs)
+	}
+
+	b.WriteString(FormatSummaryDescription(summary, strings.Join(lines, "\n")))
+	return b.String()
+}
+
+var conflictMarkerRegEx = regexp.MustCompile(`(?m)^(<<<<<<<|>>>>>>>)( |$)`)
+
+// editMerge opens the merge buffer until no conflict markers are left
+func (c *Command) editMerge(key, buffer string) (issueText, error) {
+	var merged issueText
+	_, err := editor.EditUntilValid(c.EditorFile("edit", key), buffer, func(text string) error {
+		if loc := conflictMarkerRegEx.FindStringIndex(text); loc != nil {
+			return fmt.Errorf("there is still a conflict marker on line %d, keep what should stay and remove the marker lines", strings.Count(text[:loc[0]], "\n")+1)
+		}
+
+		var err error
+		merged.Summary, merged.Description, err = summaryDescription(bufio.NewScanner(strings.NewReader(text)), false)
+		if err == nil && merged.Summary == "" {
+			err = errors.New("the summary line needs to be filled at least")
+		}
+		return err
+	})
+	if err != nil {
+		return issueText{}, fmt.Errorf("failed to merge the changes to %s: %w", key, err)
+	}
+
+	return merged, nil
+}
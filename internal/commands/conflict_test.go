@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeLines(t *testing.T) {
+	base := []string{"one", "two", "three", "four", "five"}
+
+	testData := []struct {
+		Name        string
+		InMine      []string
+		InTheirs    []string
+		Out         []string
+		OutConflict bool
+	}{
+		{
+			Name:     "Unchanged",
+			InMine:   base,
+			InTheirs: base,
+			Out:      base,
+		},
+		{
+			Name:     "OnlyMine",
+			InMine:   []string{"one", "TWO", "three", "four", "five"},
+			InTheirs: base,
+			Out:      []string{"one", "TWO", "three", "four", "five"},
+		},
+		{
+			Name:     "OnlyTheirs",
+			InMine:   base,
+			InTheirs: []string{"one", "two", "three", "four", "five", "six"},
+			Out:      []string{"one", "two", "three", "four", "five", "six"},
+		},
+		{
+			Name:     "DifferentParts",
+			InMine:   []string{"zero", "one", "two", "three", "four", "five"},
+			InTheirs: []string{"one", "two", "three", "five"},
+			Out:      []string{"zero", "one", "two", "three", "five"},
+		},
+		{
+			Name:     "SameChange",
+			InMine:   []string{"one", "2", "three", "four", "five"},
+			InTheirs: []string{"one", "2", "three", "four", "five"},
+			Out:      []string{"one", "2", "three", "four", "five"},
+		},
+		{
+			Name:        "Conflict",
+			InMine:      []string{"one", "mine", "three", "four", "five"},
+			InTheirs:    []string{"one", "theirs", "three", "four", "FIVE"},
+			Out:         []string{"one", "<<<<<<< mine", "mine", "=======", "theirs", ">>>>>>> theirs", "three", "four", "FIVE"},
+			OutConflict: true,
+		},
+		{
+			Name:        "InsertedAtTheSameSpot",
+			InMine:      []string{"one", "two", "three", "mine", "four", "five"},
+			InTheirs:    []string{"one", "two", "three", "theirs", "four", "five"},
+			Out:         []string{"one", "two", "three", "<<<<<<< mine", "mine", "=======", "theirs", ">>>>>>> theirs", "four", "five"},
+			OutConflict: true,
+		},
+		{
+			Name:        "DeletedWhatTheyChanged",
+			InMine:      []string{"one", "five"},
+			InTheirs:    []string{"one", "two", "THREE", "four", "five"},
+			Out:         []string{"one", "<<<<<<< mine", "=======", "two", "THREE", "four", ">>>>>>> theirs", "five"},
+			OutConflict: true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out, conflict := mergeLines(base, td.InMine, td.InTheirs)
+			assert.Equal(t, td.Out, out)
+			assert.Equal(t, td.OutConflict, conflict)
+		})
+	}
+}
+
+func TestMergeField(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InMine      string
+		InTheirs    string
+		Out         string
+		OutConflict bool
+	}{
+		{Name: "Unchanged", InMine: "base", InTheirs: "base", Out: "base"},
+		{Name: "OnlyMine", InMine: "mine", InTheirs: "base", Out: "mine"},
+		{Name: "OnlyTheirs", InMine: "base", InTheirs: "theirs", Out: "theirs"},
+		{Name: "Same", InMine: "both", InTheirs: "both", Out: "both"},
+		{Name: "Conflict", InMine: "mine", InTheirs: "theirs", Out: "mine", OutConflict: true},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out, conflict := mergeField("base", td.InMine, td.InTheirs)
+			assert.Equal(t, td.Out, out)
+			assert.Equal(t, td.OutConflict, conflict)
+		})
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	base := issueText{Summary: "Summary", Description: "Description"}
+	summary, description := "My summary", "My description\n"
+
+	testData := []struct {
+		Name     string
+		InTheirs issueText
+		InInput  jiwa.UpdateIssueInput
+		Out      bool
+	}{
+		{Name: "TheyChangedNothing", InTheirs: base, InInput: jiwa.UpdateIssueInput{Summary: &summary}},
+		{Name: "TheyChangedTheOtherField", InTheirs: issueText{Summary: "Summary", Description: "Theirs"}, InInput: jiwa.UpdateIssueInput{Summary: &summary}},
+		{Name: "SameSummary", InTheirs: issueText{Summary: "My summary", Description: "Description"}, InInput: jiwa.UpdateIssueInput{Summary: &summary}},
+		{Name: "SameDescriptionButNewline", InTheirs: issueText{Summary: "Summary", Description: "My description"}, InInput: jiwa.UpdateIssueInput{Description: &description}},
+		{Name: "Summary", InTheirs: issueText{Summary: "Their summary", Description: "Description"}, InInput: jiwa.UpdateIssueInput{Summary: &summary}, Out: true},
+		{Name: "Description", InTheirs: issueText{Summary: "Summary", Description: "Theirs"}, InInput: jiwa.UpdateIssueInput{Description: &description}, Out: true},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, conflicts(base, td.InTheirs, td.InInput))
+		})
+	}
+}
+
+func TestFormatConflict(t *testing.T) {
+	base := issueText{Summary: "Summary", Description: "one\ntwo"}
+	mine := issueText{Summary: "Mine", Description: "one\nmine"}
+	theirs := issueText{Summary: "Theirs", Description: "one\ntheirs"}
+
+	out := formatConflict("JIWA-1", base, mine, theirs)
+
+	assert.Equal(t, "JIWA-1 was changed by someone else while you were editing it\n"+
+		"summary:\n  original: Summary\n  mine:     Mine\n  theirs:   Theirs\n"+
+		"description, mine:\n  one\n- two\n+ mine\n"+
+		"description, theirs:\n  one\n- two\n+ theirs\n", out)
+}
+
+func TestMergeBuffer(t *testing.T) {
+	testData := []struct {
+		Name     string
+		InMine   issueText
+		InTheirs issueText
+		Out      string
+	}{
+		{
+			Name:     "Merged",
+			InMine:   issueText{Summary: "Mine", Description: "ONE\ntwo\nthree"},
+			InTheirs: issueText{Summary: "Summary", Description: "one\ntwo\nthree\nfour"},
+			Out:      "#jiwa: JIWA-1 was changed while you were editing it, this is both merged\nMine\nONE\ntwo\nthree\nfour",
+		},
+		{
+			Name:     "Conflicts",
+			InMine:   issueText{Summary: "Mine", Description: "one\nmine\nthree"},
+			InTheirs: issueText{Summary: "Theirs", Description: "one\ntheirs\nthree"},
+			Out: "#jiwa: JIWA-1 was changed while you were editing it, this is both merged\n" +
+				"#jiwa: your summary is kept, theirs is: Theirs\n" +
+				"#jiwa: pick between the \"<<<<<<< mine\" and \">>>>>>> theirs\" lines of the description\n" +
+				"Mine\none\n<<<<<<< mine\nmine\n=======\ntheirs\n>>>>>>> theirs\nthree",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out := mergeBuffer("JIWA-1", issueText{Summary: "Summary", Description: "one\ntwo\nthree"}, td.InMine, td.InTheirs)
+			assert.Equal(t, td.Out, out)
+		})
+	}
+}
+
+func TestChooseConflict(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InAnswers string
+		Out       conflictChoice
+		OutErr    error
+	}{
+		{Name: "Merge", InAnswers: "m\n", Out: conflictMerge},
+		{Name: "Overwrite", InAnswers: "Overwrite\n", Out: conflictOverwrite},
+		{Name: "AbortByDefault", InAnswers: "\n", Out: conflictAbort},
+		{Name: "AskedAgain", InAnswers: "x\na\n", Out: conflictAbort},
+		{Name: "InputEnds", InAnswers: "", Out: conflictAbort, OutErr: prompt.ErrAborted},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			choice, err := chooseConflict(prompt.New(strings.NewReader(td.InAnswers), io.Discard))
+			assert.Equal(t, td.OutErr, err)
+			assert.Equal(t, td.Out, choice)
+		})
+	}
+}
+
+func TestCommand_ChangedSince(t *testing.T) {
+	opened := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	testData := []struct {
+		Name      string
+		InUpdated time.Time
+		Out       issueText
+		OutMoved  bool
+	}{
+		{Name: "Untouched", InUpdated: opened},
+		{
+			Name:      "Changed",
+			InUpdated: opened.Add(time.Minute),
+			Out:       issueText{Summary: "Theirs", Description: "Their description"},
+			OutMoved:  true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Summary:     "Theirs",
+				Description: "Their description",
+				Updated:     jira.Time(td.InUpdated),
+			}})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+			c := Command{Client: client}
+
+			theirs, updated, moved, err := c.changedSince("JIWA-1", opened)
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutMoved, moved)
+			assert.Equal(t, td.Out, theirs)
+			assert.True(t, updated.Equal(td.InUpdated))
+
+			fetches := 1
+			if td.OutMoved {
+				fetches = 2
+			}
+			assert.Len(t, srv.Requests(), fetches, "the description is only fetched when the issue moved")
+		})
+	}
+}
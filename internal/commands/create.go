@@ -4,19 +4,132 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/catouc/jiwa/internal/jiwa"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Create(project, srcFilePath, ticketType, component string) (string, error) {
+type CreateInput struct {
+	Project string
+	File    string
+	// Type defaults to the template's type and then to "Task"
+	Type   string
+	Labels []string
+	// Components are matched against the project's components ignoring
+	// case
+	Components []string
+	Parent     string
+	// EpicName is only used for epics on Server and Data Center, it
+	// defaults to the summary
+	EpicName string
+	// SkipDuplicateCheck disables searching for similar open issues
+	SkipDuplicateCheck bool
+	// CheckDuplicates searches for similar open issues even if that is
+	// disabled in the config, when nobody can be asked whether to go ahead
+	// finding any aborts the creation unless Yes is set
+	CheckDuplicates bool
+	// Yes answers all questions with yes, duplicates are still reported
+	Yes bool
+	// Template names the template from the config that fills in what
+	// isn't set and pre-fills the editor
+	Template string
+	// AutoSplit cuts a summary that is too long for Jira at a word boundary
+	// and moves the rest to the top of the description
+	AutoSplit bool
+	// Due is the due date, none is set if it's zero
+	Due time.Time
+	// Security is the name of the security level to set, checked against
+	// the levels of the project
+	Security string
+	// Reporter files the issue on behalf of this user, looked up like a
+	// mention, which needs the Modify Reporter permission
+	Reporter string
+	// AssignToMe assigns the issue to the current user in the same request
+	// that creates it
+	AssignToMe bool
+	// Interactive asks on the terminal for the required fields of the
+	// project's create screen the other fields don't set
+	Interactive bool
+	// Summary and Description are used as they are when Summary is set,
+	// instead of reading them from File, the editor or stdin
+	Summary     string
+	Description string
+}
+
+func (c *Command) Create(input CreateInput) (string, error) {
 	stat, _ := os.Stdin.Stat()
 
+	prefill := ""
+	if input.Template != "" {
+		t, body, err := c.Template(input.Template)
+		if err != nil {
+			return "", err
+		}
+		input = applyTemplate(input, t)
+		prefill = body
+	}
+	if input.Type == "" {
+		input.Type = "Task"
+	}
+
+	err := c.checkIssueType(input)
+	if err != nil {
+		return "", err
+	}
+
+	var security jiwa.SecurityLevel
+	if input.Security != "" {
+		security, err = c.securityLevel(input.Project, input.Security)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var reporter map[string]string
+	if input.Reporter != "" {
+		reporter, err = c.reporterField(input.Project, input.Reporter)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var assignee map[string]string
+	if input.AssignToMe {
+		assignee, err = c.selfAssignee(input.Project)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// asked before the editor opens, a field that can't be answered
+	// doesn't throw away a written description
+	var prompted map[string]any
+	if input.Interactive {
+		prompted, err = c.PromptCreateFields(input)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	var summary, description string
 	switch {
-	case srcFilePath != "":
-		fBytes, err := os.ReadFile(srcFilePath)
+	case input.Summary != "":
+		summary, description = input.Summary, input.Description
+		if input.AutoSplit {
+			summary, description = SplitLongSummary(summary, description)
+		}
+		err = checkSummaryLength(summary)
+		if err != nil {
+			return "", err
+		}
+	case input.File != "":
+		fBytes, err := os.ReadFile(input.File)
 		if err != nil {
 			fmt.Printf("failed to read file contents: %s", err)
 			os.Exit(1)
@@ -24,13 +137,13 @@ func (c *Command) Create(project, srcFilePath, ticketType, component string) (st
 
 		scanner := bufio.NewScanner(bytes.NewBuffer(fBytes))
 
-		summary, description, err = BuildSummaryAndDescriptionFromScanner(scanner)
+		summary, description, err = summaryDescription(scanner, input.AutoSplit)
 		if err != nil {
 			return "", fmt.Errorf("failed to get summary and description: %w", err)
 		}
 	case (stat.Mode() & os.ModeCharDevice) != 0:
 		var err error
-		summary, description, err = CreateIssueSummaryDescription("")
+		summary, description, err = CreateIssueSummaryDescription(c.EditorFile("create", ""), prefill, input.AutoSplit)
 		if err != nil {
 			return "", fmt.Errorf("failed to get summary and description: %w", err)
 		}
@@ -42,23 +155,287 @@ func (c *Command) Create(project, srcFilePath, ticketType, component string) (st
 		}
 
 		scanner := bufio.NewScanner(bytes.NewBuffer(in))
-		summary, description, err = BuildSummaryAndDescriptionFromScanner(scanner)
+		summary, description, err = summaryDescription(scanner, input.AutoSplit)
 		if err != nil {
 			return "", fmt.Errorf("failed to get summary and description: %w", err)
 		}
 	}
 
-	issue, err := c.Client.CreateIssue(context.TODO(), jiwa.CreateIssueInput{
-		Project:     project,
+	c.warnMissingDescription(input, description)
+
+	if input.EpicName != "" && !isEpic(input.Type) {
+		return "", fmt.Errorf("--epic-name only applies to epics, not to %q", input.Type)
+	}
+
+	if input.CheckDuplicates && input.SkipDuplicateCheck {
+		return "", errors.New("--check-dupes and --no-dup-check cannot be used together")
+	}
+
+	if input.CheckDuplicates || (!input.SkipDuplicateCheck && !c.Config.DisableDuplicateCheck) {
+		interactive := (stat.Mode()&os.ModeCharDevice) != 0 && !input.Yes
+		strict := input.CheckDuplicates && !input.Yes
+		err := c.checkDuplicates(input.Project, summary, interactive, strict)
+		switch {
+		case offline.IsUnreachable(err):
+			fmt.Fprintln(os.Stderr, "warning: skipped looking for duplicates, Jira can't be reached")
+		case err != nil:
+			return "", err
+		}
+	}
+
+	description, err = c.withMentions(mentionScope{Project: input.Project}, c.fromDescriptionFormat(description), "")
+	if err != nil {
+		return "", err
+	}
+
+	var components []string
+	if len(input.Components) != 0 {
+		components, err = c.resolveComponents(c.ctx(), input.Project, input.Components)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	payload := hooks.Payload{
+		Project:     input.Project,
+		Summary:     summary,
+		Description: description,
+		Type:        input.Type,
+		Components:  components,
+		Labels:      input.Labels,
+	}
+	if len(components) != 0 {
+		payload.Component = components[0]
+	}
+	err = c.runPreHook("pre-create", payload)
+	if err != nil {
+		return "", fmt.Errorf("aborting create: %w", err)
+	}
+
+	if c.DryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: would create %s in %s: %s\n", input.Type, input.Project, summary)
+		return "", nil
+	}
+
+	var fields map[string]any
+	if isEpic(input.Type) {
+		fields, err = c.epicFields(c.ctx(), input.EpicName, summary)
+		if err != nil {
+			return "", err
+		}
+	}
+	if !input.Due.IsZero() {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["duedate"] = input.Due.Format("2006-01-02")
+	}
+	if security.ID != "" {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["security"] = map[string]string{"id": security.ID}
+	}
+	if reporter != nil {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["reporter"] = reporter
+	}
+	if assignee != nil {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["assignee"] = assignee
+	}
+	for id, v := range prompted {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields[id] = v
+	}
+
+	issue, err := c.Client.CreateIssue(c.ctx(), jiwa.CreateIssueInput{
+		Project:     input.Project,
 		Summary:     summary,
 		Description: description,
-		Labels:      nil,
-		Type:        ticketType,
-		Component:   component,
+		Labels:      input.Labels,
+		Type:        input.Type,
+		Components:  components,
+		Parent:      input.Parent,
+		Fields:      fields,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to create issue: %w", err)
 	}
 
+	payload.Key = issue.Key
+	c.runPostHook("post-create", payload)
+	c.rememberIssue(issue.Key, summary)
+
 	return issue.Key, nil
 }
+
+// IssueBlock is one issue of the text create --in reads
+type IssueBlock struct {
+	Summary     string
+	Description string
+	// Line is where the summary is in the text, to point at the block
+	// in messages
+	Line int
+}
+
+// IssueBlockSeparator is the line between the issues of the text create
+// --in reads. Neither wiki markup nor markdown give it a meaning, unlike
+// "---", so descriptions can have rules and the summary can be split from
+// the description with SummarySeparator like in a single issue.
+const IssueBlockSeparator = "=== issue ==="
+
+// ParseIssueBlocks splits the text into issues at IssueBlockSeparator
+// lines. The first line of a block is the summary and the rest is the
+// description, unless a SummarySeparator line close to the top splits
+// them. Blocks without any text are skipped.
+func ParseIssueBlocks(text string) ([]IssueBlock, error) {
+	blocks := make([]IssueBlock, 0)
+	var chunk []string
+	start := 1
+	flush := func() error {
+		lines := trimLeadingBlankLines(chunk)
+		if len(lines) == 0 {
+			return nil
+		}
+
+		line := start + len(chunk) - len(lines)
+		summary, body := strings.TrimSpace(lines[0]), lines[1:]
+		if sep := summarySeparatorIndex(lines); sep != -1 {
+			if sep == 0 {
+				return fmt.Errorf("there is nothing above the %s line on line %d, the summary goes there", SummarySeparator, line)
+			}
+			summary = strings.Join(strings.Fields(strings.Join(lines[:sep], " ")), " ")
+			body = lines[sep+1:]
+		}
+
+		blocks = append(blocks, IssueBlock{
+			Summary:     summary,
+			Description: strings.Join(trimBlankLines(body), "\n"),
+			Line:        line,
+		})
+		return nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == IssueBlockSeparator {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+			chunk, start = nil, i+2
+			continue
+		}
+		chunk = append(chunk, line)
+	}
+
+	err := flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// CreateResult is what became of one block of CreateBatch, either Key or
+// Err is set
+type CreateResult struct {
+	Block IssueBlock
+	Key   string
+	Err   error
+}
+
+// CreateBatch creates an issue for every block in order, all of them with
+// the project, type, labels and the rest of input. A block that fails
+// doesn't stop the ones after it.
+func (c *Command) CreateBatch(input CreateInput, blocks []IssueBlock) []CreateResult {
+	results := make([]CreateResult, 0, len(blocks))
+	for _, b := range blocks {
+		result := CreateResult{Block: b}
+
+		in := input
+		in.Summary, in.Description = b.Summary, b.Description
+		if input.AutoSplit {
+			in.Summary, in.Description = SplitLongSummary(b.Summary, b.Description)
+		}
+
+		result.Err = checkSummaryLength(in.Summary)
+		if result.Err == nil {
+			result.Key, result.Err = c.Create(in)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// selfAssignee is the assignee field of create --mine, the current user is
+// looked up once for all the issues a command creates
+func (c *Command) selfAssignee(project string) (map[string]string, error) {
+	err := c.RequireProjectPermission(jiwa.PermissionAssignIssues, project)
+	if err != nil {
+		return nil, fmt.Errorf("assigning the issue to yourself: %w", err)
+	}
+
+	if c.self == nil {
+		account, err := c.Client.Myself(c.ctx())
+		if err != nil {
+			return nil, err
+		}
+		c.self, err = c.userField(account.User)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.self, nil
+}
+
+// epicNameSchema identifies Jira Software's Epic Name custom field, its ID
+// differs between instances
+const epicNameSchema = "com.pyxis.greenhopper.jira:gh-epic-label"
+
+func isEpic(issueType string) bool {
+	return strings.EqualFold(issueType, "epic")
+}
+
+// epicFields returns the extra fields an epic needs. Server and Data Center
+// refuse to create an epic without an Epic Name, Cloud has no such field.
+func (c *Command) epicFields(ctx context.Context, epicName, summary string) (map[string]any, error) {
+	info, err := c.Client.ServerInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsCloud() {
+		if epicName != "" {
+			fmt.Fprintln(os.Stderr, "ignoring --epic-name, Jira Cloud doesn't use it")
+		}
+		return nil, nil
+	}
+
+	fields, err := c.Client.ListFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.Schema.Custom != epicNameSchema {
+			continue
+		}
+
+		if epicName == "" {
+			epicName = summary
+		}
+		return map[string]any{f.ID: epicName}, nil
+	}
+
+	return nil, errors.New("cannot create an epic, there is no Epic Name field on this instance")
+}
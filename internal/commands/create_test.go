@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_EpicFields(t *testing.T) {
+	epicName := jira.Field{ID: "customfield_10011", Name: "Epic Name", Schema: jira.FieldSchema{Custom: epicNameSchema}}
+	other := jira.Field{ID: "customfield_10010", Name: "Story Points", Schema: jira.FieldSchema{Custom: "com.atlassian.jira.plugin.system.customfieldtypes:float"}}
+
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InFields     []jira.Field
+		InEpicName   string
+		OutFields    map[string]any
+		OutErrMsg    string
+	}{
+		{
+			Name:         "Cloud",
+			InDeployment: "Cloud",
+			InFields:     []jira.Field{epicName},
+			InEpicName:   "Ignored",
+			OutFields:    nil,
+		},
+		{
+			Name:         "ServerDefaultsToSummary",
+			InDeployment: "Server",
+			InFields:     []jira.Field{other, epicName},
+			OutFields:    map[string]any{"customfield_10011": "Migrate the database"},
+		},
+		{
+			Name:         "DataCenterWithEpicName",
+			InDeployment: "DataCenter",
+			InFields:     []jira.Field{epicName},
+			InEpicName:   "DB migration",
+			OutFields:    map[string]any{"customfield_10011": "DB migration"},
+		},
+		{
+			Name:         "ServerWithoutEpicNameField",
+			InDeployment: "Server",
+			InFields:     []jira.Field{other},
+			OutErrMsg:    "cannot create an epic, there is no Epic Name field on this instance",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Info = jiwa.ServerInfo{DeploymentType: td.InDeployment}
+			fake.Fields = td.InFields
+			c := Command{Client: fake}
+
+			fields, err := c.epicFields(context.Background(), td.InEpicName, "Migrate the database")
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutFields, fields)
+		})
+	}
+}
+
+func TestParseIssueBlocks(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		Out       []IssueBlock
+		OutErrMsg string
+	}{
+		{
+			Name: "Single",
+			In:   "Summary\n\nDescription\n",
+			Out:  []IssueBlock{{Summary: "Summary", Description: "Description", Line: 1}},
+		},
+		{
+			Name: "Several",
+			In:   "First\nDetails\n=== issue ===\n\nSecond\n\n  indented\n\n=== issue ===\nThird\n",
+			Out: []IssueBlock{
+				{Summary: "First", Description: "Details", Line: 1},
+				{Summary: "Second", Description: "  indented", Line: 5},
+				{Summary: "Third", Line: 10},
+			},
+		},
+		{
+			Name: "RuleInTheDescription",
+			In:   "First\nAbove\n\n\n\n---\nBelow\n=== issue ===\nSecond\n",
+			Out: []IssueBlock{
+				{Summary: "First", Description: "Above\n\n\n\n---\nBelow", Line: 1},
+				{Summary: "Second", Line: 9},
+			},
+		},
+		{
+			Name: "SummarySeparator",
+			In:   "A long\nsummary\n---\nDetails\n=== issue ===\nSecond\n",
+			Out: []IssueBlock{
+				{Summary: "A long summary", Description: "Details", Line: 1},
+				{Summary: "Second", Line: 6},
+			},
+		},
+		{
+			Name:      "NothingAboveTheSummarySeparator",
+			In:        "First\n=== issue ===\n\n---\nDetails\n",
+			OutErrMsg: "there is nothing above the --- line on line 4, the summary goes there",
+		},
+		{
+			Name: "EmptyBlocksSkipped",
+			In:   "=== issue ===\n\n=== issue ===\nOnly one\n=== issue ===\n  \n",
+			Out:  []IssueBlock{{Summary: "Only one", Line: 4}},
+		},
+		{
+			Name: "CRLF",
+			In:   "First\r\nDetails\r\n === issue === \r\nSecond\r\n",
+			Out: []IssueBlock{
+				{Summary: "First", Description: "Details", Line: 1},
+				{Summary: "Second", Line: 4},
+			},
+		},
+		{
+			Name: "Empty",
+			In:   "\n",
+			Out:  []IssueBlock{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			blocks, err := ParseIssueBlocks(td.In)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, blocks)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// FieldPrompt is a required field create --interactive asks for
+type FieldPrompt struct {
+	ID     string
+	Name   string
+	Schema jiwa.FieldSchema
+	// Choices are the values the field allows, the answer picks from them
+	Choices []jiwa.AllowedValue
+}
+
+// createOwnFields are filled in by create itself from the flags, the
+// editor or the config, they are never asked for
+var createOwnFields = map[string]bool{
+	"project":     true,
+	"issuetype":   true,
+	"summary":     true,
+	"description": true,
+	"reporter":    true,
+}
+
+// createFieldPrompts returns the required fields of the create screen that
+// have no default and aren't in set, sorted by name
+func createFieldPrompts(fields map[string]jiwa.CreateField, set map[string]bool) []FieldPrompt {
+	prompts := make([]FieldPrompt, 0)
+	for id, f := range fields {
+		// the Epic Name is filled in from the summary
+		if !f.Required || f.HasDefaultValue || createOwnFields[id] || set[id] || f.Schema.Custom == epicNameSchema {
+			continue
+		}
+
+		prompts = append(prompts, FieldPrompt{ID: id, Name: f.Name, Schema: f.Schema, Choices: f.AllowedValues})
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+
+	return prompts
+}
+
+// createFieldsSet are the fields the flags of create already set
+func createFieldsSet(input CreateInput) map[string]bool {
+	return map[string]bool{
+		"labels":     len(input.Labels) != 0,
+		"components": len(input.Components) != 0,
+		"parent":     input.Parent != "",
+		"duedate":    !input.Due.IsZero(),
+		"security":   input.Security != "",
+		"assignee":   input.AssignToMe,
+	}
+}
+
+// createFields returns the fields of the create screen of the issue type in
+// the project, they are only fetched once per project and type
+func (c *Command) createFields(project, issueType string) (map[string]jiwa.CreateField, error) {
+	key := project + "/" + issueType
+	if fields, ok := c.createMeta[key]; ok {
+		return fields, nil
+	}
+
+	fields, err := c.Client.GetCreateMeta(c.ctx(), project, issueType)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.createMeta == nil {
+		c.createMeta = make(map[string]map[string]jiwa.CreateField)
+	}
+	c.createMeta[key] = fields
+
+	return fields, nil
+}
+
+// checkIssueType fails if the project has no such issue type, listing the
+// ones it has, and warns about the required fields create won't send
+// unless they are asked for. Jira would only refuse the issue once it's
+// written. Any other error is left to the create to report, like a
+// project that doesn't exist or an instance that can't be reached.
+func (c *Command) checkIssueType(input CreateInput) error {
+	fields, err := c.createFields(input.Project, input.Type)
+	var unknown *jiwa.UnknownIssueTypeError
+	switch {
+	case errors.As(err, &unknown):
+		return err
+	case err != nil || input.Interactive:
+		return nil
+	}
+
+	prompts := createFieldPrompts(fields, createFieldsSet(input))
+	if len(prompts) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(prompts))
+	for _, p := range prompts {
+		names = append(names, p.Name)
+	}
+	fmt.Fprintf(os.Stderr, "warning: %s requires %s for a %s, which jiwa doesn't send, pass --interactive to fill them in\n", input.Project, strings.Join(names, ", "), input.Type)
+
+	return nil
+}
+
+// warnMissingDescription warns about an empty description if the create
+// screen of the issue type requires one, Jira would refuse the issue with
+// an error that doesn't say which field it wants
+func (c *Command) warnMissingDescription(input CreateInput, description string) {
+	if strings.TrimSpace(description) != "" {
+		return
+	}
+
+	fields, err := c.createFields(input.Project, input.Type)
+	if err != nil || !fields["description"].Required {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "warning: %s requires a description for a %s, Jira will likely refuse the issue without one\n", input.Project, input.Type)
+}
+
+// PromptCreateFields asks on the terminal for the required fields of the
+// project's create screen for the issue type that the flags don't set and
+// returns them ready to be sent
+func (c *Command) PromptCreateFields(input CreateInput) (map[string]any, error) {
+	meta, err := c.createFields(input.Project, input.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := createFieldPrompts(meta, createFieldsSet(input))
+	if len(prompts) == 0 {
+		return nil, nil
+	}
+	if c.NoPrompt {
+		return nil, errors.New("cannot ask for the required fields, prompts are disabled")
+	}
+
+	p, err := prompt.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	return askCreateFields(p, prompts, time.Now().In(c.Location()))
+}
+
+// askCreateFields asks for every field until it gets a valid answer, dates
+// take what ParseDate does
+func askCreateFields(p *prompt.Prompter, prompts []FieldPrompt, now time.Time) (map[string]any, error) {
+	fields := make(map[string]any, len(prompts))
+	for _, f := range prompts {
+		var (
+			value any
+			err   error
+		)
+		if len(f.Choices) != 0 {
+			value, err = askChoice(p, f)
+		} else {
+			value, err = askText(p, f, now)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields[f.ID] = value
+	}
+
+	return fields, nil
+}
+
+func askChoice(p *prompt.Prompter, f FieldPrompt) (any, error) {
+	labels := make([]string, 0, len(f.Choices))
+	for _, c := range f.Choices {
+		labels = append(labels, c.Label())
+	}
+
+	if f.Schema.Type != "array" {
+		p.Printf("%s:\n", f.Name)
+		for {
+			i, err := p.Choose(fmt.Sprintf("%s (required): ", f.Name), labels)
+			if err != nil {
+				return nil, err
+			}
+			if i >= 0 {
+				return map[string]string{"id": f.Choices[i].ID}, nil
+			}
+			p.Printf("%s is required\n", f.Name)
+		}
+	}
+
+	p.Printf("%s, pick one or more:\n", f.Name)
+	for i, l := range labels {
+		p.Printf("%3d) %s\n", i+1, l)
+	}
+	for {
+		answer, err := p.Ask(fmt.Sprintf("%s (required, e.g. 1,3): ", f.Name))
+		if err != nil {
+			return nil, err
+		}
+
+		picked, err := parseChoices(answer, len(labels))
+		if err != nil {
+			p.Printf("%s\n", err)
+			continue
+		}
+
+		value := make([]map[string]string, 0, len(picked))
+		for _, i := range picked {
+			value = append(value, map[string]string{"id": f.Choices[i].ID})
+		}
+		return value, nil
+	}
+}
+
+// parseChoices reads numbers separated by commas, the indexes it returns
+// start at 0
+func parseChoices(answer string, n int) ([]int, error) {
+	picked := make([]int, 0)
+	for _, part := range strings.Split(answer, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 1 || i > n {
+			return nil, fmt.Errorf("please pick numbers between 1 and %d", n)
+		}
+		picked = append(picked, i-1)
+	}
+
+	if len(picked) == 0 {
+		return nil, errors.New("pick at least one, the field is required")
+	}
+
+	return picked, nil
+}
+
+func askText(p *prompt.Prompter, f FieldPrompt, now time.Time) (any, error) {
+	hint := ""
+	switch {
+	case f.Schema.Type == "date":
+		hint = ", e.g. tomorrow or 2024-07-01"
+	case f.Schema.Type == "array":
+		hint = ", separated by commas"
+	}
+
+	for {
+		answer, err := p.Ask(fmt.Sprintf("%s (required%s): ", f.Name, hint))
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			p.Printf("%s is required\n", f.Name)
+			continue
+		}
+
+		if f.Schema.Type == "date" {
+			day, err := ParseDate(answer, now)
+			if err != nil {
+				p.Printf("%s\n", err)
+				continue
+			}
+			answer = day.Format("2006-01-02")
+		}
+
+		value, err := jiwa.FieldValue(f.Schema, answer)
+		if err != nil {
+			p.Printf("%s\n", err)
+			continue
+		}
+
+		return value, nil
+	}
+}
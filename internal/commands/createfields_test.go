@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCreateMeta is the fields of a Bug as createmeta returns them
+const testCreateMeta = `{
+	"summary": {"required": true, "name": "Summary", "hasDefaultValue": false, "schema": {"type": "string", "system": "summary"}},
+	"issuetype": {"required": true, "name": "Issue Type", "hasDefaultValue": false, "schema": {"type": "issuetype", "system": "issuetype"}},
+	"reporter": {"required": true, "name": "Reporter", "hasDefaultValue": true, "schema": {"type": "user", "system": "reporter"}},
+	"priority": {"required": true, "name": "Priority", "hasDefaultValue": true, "schema": {"type": "priority", "system": "priority"},
+		"allowedValues": [{"id": "1", "name": "High"}, {"id": "3", "name": "Medium"}]},
+	"labels": {"required": true, "name": "Labels", "hasDefaultValue": false, "schema": {"type": "array", "items": "string", "system": "labels"}},
+	"components": {"required": false, "name": "Components", "hasDefaultValue": false, "schema": {"type": "array", "items": "component", "system": "components"},
+		"allowedValues": [{"id": "10000", "name": "API"}]},
+	"customfield_10050": {"required": true, "name": "Platform", "hasDefaultValue": false,
+		"schema": {"type": "option", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:select"},
+		"allowedValues": [{"id": "20", "value": "Web"}, {"id": "21", "value": "iOS"}, {"id": "22", "value": "Android"}]},
+	"customfield_10051": {"required": true, "name": "Browsers", "hasDefaultValue": false,
+		"schema": {"type": "array", "items": "option", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:multicheckboxes"},
+		"allowedValues": [{"id": "30", "value": "Firefox"}, {"id": "31", "value": "Chrome"}, {"id": "32", "value": "Safari"}]},
+	"customfield_10052": {"required": true, "name": "Impact", "hasDefaultValue": false, "schema": {"type": "number", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:float"}},
+	"customfield_10053": {"required": true, "name": "Found on", "hasDefaultValue": false, "schema": {"type": "date", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:datepicker"}},
+	"customfield_10011": {"required": true, "name": "Epic Name", "hasDefaultValue": false, "schema": {"type": "string", "custom": "com.pyxis.greenhopper.jira:gh-epic-label"}}
+}`
+
+func testCreateFields(t *testing.T) map[string]jiwa.CreateField {
+	var fields map[string]jiwa.CreateField
+	err := json.Unmarshal([]byte(testCreateMeta), &fields)
+	assert.NoError(t, err)
+
+	return fields
+}
+
+func TestCreateFieldPrompts(t *testing.T) {
+	fields := testCreateFields(t)
+	fields["security"] = jiwa.CreateField{Name: "Security Level", Required: true, Schema: jiwa.FieldSchema{Type: "securitylevel", System: "security"}}
+	fields["assignee"] = jiwa.CreateField{Name: "Assignee", Required: true, Schema: jiwa.FieldSchema{Type: "user", System: "assignee"}}
+
+	testData := []struct {
+		Name  string
+		InSet map[string]bool
+		OutID []string
+	}{
+		{
+			Name:  "RequiredWithoutDefault",
+			OutID: []string{"assignee", "customfield_10051", "customfield_10053", "customfield_10052", "labels", "customfield_10050", "security"},
+		},
+		{
+			Name:  "SetByFlags",
+			InSet: createFieldsSet(CreateInput{Labels: []string{"ops"}, Due: time.Now(), Security: "Internal", AssignToMe: true}),
+			OutID: []string{"customfield_10051", "customfield_10053", "customfield_10052", "customfield_10050"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			prompts := createFieldPrompts(fields, td.InSet)
+
+			ids := make([]string, 0, len(prompts))
+			for _, p := range prompts {
+				ids = append(ids, p.ID)
+			}
+			assert.Equal(t, td.OutID, ids)
+		})
+	}
+}
+
+func TestAskCreateFields(t *testing.T) {
+	prompts := createFieldPrompts(testCreateFields(t), nil)
+	now := time.Date(2024, 7, 3, 15, 0, 0, 0, time.UTC)
+
+	// browsers: nothing and then 1,3; found on: a bare weekday and then
+	// yesterday; impact: not a number and then 2.5; labels: two of them;
+	// platform: out of range, nothing and then iOS
+	answers := "\n1, 3\nfriday\nyesterday\nlots\n2.5\nweb,mobile\n7\n\n2\n"
+	var out bytes.Buffer
+	fields, err := askCreateFields(prompt.New(strings.NewReader(answers), &out), prompts, now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"customfield_10051": []map[string]string{{"id": "30"}, {"id": "32"}},
+		"customfield_10053": "2024-07-02",
+		"customfield_10052": 2.5,
+		"labels":            []string{"web", "mobile"},
+		"customfield_10050": map[string]string{"id": "21"},
+	}, fields)
+
+	assert.Contains(t, out.String(), "Browsers, pick one or more:\n  1) Firefox\n  2) Chrome\n  3) Safari\n")
+	assert.Contains(t, out.String(), "pick at least one, the field is required\n")
+	assert.Contains(t, out.String(), `"friday" is ambiguous, say "next friday" or "last friday"`)
+	assert.Contains(t, out.String(), `"lots" is not a number`)
+	assert.Contains(t, out.String(), "please pick a number between 1 and 3\n")
+	assert.Contains(t, out.String(), "Platform is required\n")
+
+	_, err = askCreateFields(prompt.New(strings.NewReader("1\n"), &out), prompts, now)
+	assert.ErrorIs(t, err, prompt.ErrAborted)
+}
+
+func TestCommand_PromptCreateFields(t *testing.T) {
+	fake := jiwafake.New()
+	fake.CreateMeta = map[string]map[string]jiwa.CreateField{
+		"JIWA/Task": {"summary": {Name: "Summary", Required: true}},
+		"JIWA/Bug":  testCreateFields(t),
+	}
+	c := Command{Client: fake, NoPrompt: true}
+
+	fields, err := c.PromptCreateFields(CreateInput{Project: "JIWA", Type: "Task"})
+	assert.NoError(t, err)
+	assert.Nil(t, fields, "nothing to ask doesn't need a terminal")
+
+	_, err = c.PromptCreateFields(CreateInput{Project: "JIWA", Type: "Bug"})
+	assert.EqualError(t, err, "cannot ask for the required fields, prompts are disabled")
+
+	_, err = c.PromptCreateFields(CreateInput{Project: "JIWA", Type: "Story"})
+	assert.EqualError(t, err, `project JIWA has no issue type "Story", it has Bug, Task`)
+}
+
+func TestCommand_Create_IssueType(t *testing.T) {
+	fake := jiwafake.New()
+	fake.CreateMeta = map[string]map[string]jiwa.CreateField{
+		"JIWA/Task": {"summary": {Name: "Summary", Required: true}},
+		"JIWA/Bug":  {"summary": {Name: "Summary", Required: true}},
+	}
+	c := Command{Client: fake, Config: Config{DisableDuplicateCheck: true}}
+
+	_, err := c.Create(CreateInput{Project: "JIWA", Type: "Story", Summary: "Crash on start"})
+	assert.EqualError(t, err, `project JIWA has no issue type "Story", it has Bug, Task`)
+	assert.Empty(t, fake.Issues)
+
+	key, err := c.Create(CreateInput{Project: "JIWA", Type: "Bug", Summary: "Crash on start"})
+	assert.NoError(t, err)
+	assert.Equal(t, "JIWA-1", key)
+
+	// projects the instance doesn't describe are left to the create
+	key, err = c.Create(CreateInput{Project: "OPS", Summary: "Rotate the keys"})
+	assert.NoError(t, err)
+	assert.Equal(t, "OPS-1", key)
+}
+
+func TestCommand_Create_SummaryLength(t *testing.T) {
+	fake := jiwafake.New()
+	c := Command{Client: fake, Config: Config{DisableDuplicateCheck: true}}
+	long := strings.Repeat("word ", 60)
+
+	_, err := c.Create(CreateInput{Project: "JIWA", Summary: long, Description: "Details"})
+	assert.EqualError(t, err, "the summary is 300 characters long but Jira only takes 255, move the rest into the description")
+	assert.Empty(t, fake.Issues)
+
+	key, err := c.Create(CreateInput{Project: "JIWA", Summary: long, Description: "Details", AutoSplit: true})
+	assert.NoError(t, err)
+	summary, description := SplitLongSummary(long, "Details")
+	assert.Equal(t, summary, fake.Issues[key].Fields.Summary)
+	assert.Equal(t, description, fake.Issues[key].Fields.Description)
+}
@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// StatusChange is a move from one status to another in the changelog
+type StatusChange struct {
+	At   time.Time
+	From string
+	To   string
+}
+
+// StatusDuration is the time an issue spent in a status, added up over
+// every time it was in there
+type StatusDuration struct {
+	Status   string
+	Duration time.Duration
+}
+
+func (s StatusDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Status  string  `json:"status"`
+		Seconds float64 `json:"seconds"`
+	}{s.Status, s.Duration.Seconds()})
+}
+
+// TimeInStatus adds up how long an issue spent in each status between
+// created and now, in the order the statuses were first entered. The issue
+// is in the From of the first change until then, or in current if it never
+// moved. Changes are walked in time order and if one doesn't start from the
+// status the issue was in, the time goes to the status it was known to be in.
+func TimeInStatus(created time.Time, current string, changes []StatusChange, now time.Time) []StatusDuration {
+	changes = append([]StatusChange(nil), changes...)
+	sort.SliceStable(changes, func(a, b int) bool {
+		return changes[a].At.Before(changes[b].At)
+	})
+
+	durations := make([]StatusDuration, 0)
+	index := make(map[string]int)
+	add := func(status string, from, to time.Time) {
+		n, ok := index[status]
+		if !ok {
+			n = len(durations)
+			index[status] = n
+			durations = append(durations, StatusDuration{Status: status})
+		}
+
+		// clocks and changelogs aren't perfect, an interval never takes
+		// time away from a status
+		if d := to.Sub(from); d > 0 {
+			durations[n].Duration += d
+		}
+	}
+
+	status, since := current, created
+	if len(changes) != 0 {
+		status = changes[0].From
+		if created.IsZero() || created.After(changes[0].At) {
+			since = changes[0].At
+		}
+	}
+
+	for _, c := range changes {
+		add(status, since, c.At)
+		status, since = c.To, c.At
+	}
+	add(status, since, now)
+
+	return durations
+}
+
+type CycleTimeInput struct {
+	// JQL selects the issues, Keys are used instead if it is empty
+	JQL  string
+	Keys []string
+	// Now is when the time in the current status ends, defaults to the
+	// current time
+	Now time.Time
+}
+
+// IssueCycleTime is the time in status of a single issue
+type IssueCycleTime struct {
+	Key       string           `json:"key"`
+	Summary   string           `json:"summary"`
+	Status    string           `json:"status"`
+	Durations []StatusDuration `json:"durations"`
+}
+
+// StatusStats aggregates the time spent in a status over the issues that
+// were in it at some point
+type StatusStats struct {
+	Status string
+	Issues int
+	Mean   time.Duration
+	Median time.Duration
+}
+
+func (s StatusStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Status        string  `json:"status"`
+		Issues        int     `json:"issues"`
+		MeanSeconds   float64 `json:"meanSeconds"`
+		MedianSeconds float64 `json:"medianSeconds"`
+	}{s.Status, s.Issues, s.Mean.Seconds(), s.Median.Seconds()})
+}
+
+type CycleTimeReport struct {
+	Issues   []IssueCycleTime `json:"issues"`
+	Statuses []StatusStats    `json:"statuses"`
+}
+
+// CycleTime computes the time in status of every issue from its changelog
+func (c *Command) CycleTime(input CycleTimeInput) (CycleTimeReport, error) {
+	now := input.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	keys := input.Keys
+	if input.JQL != "" {
+		issues, err := c.Client.Search(c.ctx(), input.JQL, jiwa.WithFields("key"))
+		if err != nil {
+			return CycleTimeReport{}, fmt.Errorf("could not find the issues: %w", err)
+		}
+
+		keys = make([]string, 0, len(issues))
+		for _, i := range issues {
+			keys = append(keys, i.Key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return CycleTimeReport{}, errors.New("no issues to compute the cycle time of")
+	}
+
+	report := CycleTimeReport{Issues: make([]IssueCycleTime, 0, len(keys))}
+	for _, key := range keys {
+		issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("summary", "status", "created"), jiwa.WithExpand("changelog"))
+		if err != nil {
+			return CycleTimeReport{}, fmt.Errorf("failed to get the history of %s: %w", key, err)
+		}
+
+		report.Issues = append(report.Issues, issueCycleTime(issue, now))
+	}
+	report.Statuses = statusStats(report.Issues)
+
+	return report, nil
+}
+
+func issueCycleTime(issue jira.Issue, now time.Time) IssueCycleTime {
+	ct := IssueCycleTime{Key: issue.Key}
+
+	var created time.Time
+	if issue.Fields != nil {
+		ct.Summary = issue.Fields.Summary
+		created = time.Time(issue.Fields.Created)
+		if issue.Fields.Status != nil {
+			ct.Status = issue.Fields.Status.Name
+		}
+	}
+
+	changes := make([]StatusChange, 0)
+	if issue.Changelog != nil {
+		for _, h := range issue.Changelog.Histories {
+			for _, item := range h.Items {
+				if item.Field != "status" {
+					continue
+				}
+				changes = append(changes, StatusChange{
+					At:   parseJiraTime(h.Created),
+					From: item.FromString,
+					To:   item.ToString,
+				})
+			}
+		}
+	}
+
+	ct.Durations = TimeInStatus(created, ct.Status, changes, now)
+
+	return ct
+}
+
+// statusStats orders the statuses by when they first show up in the issues
+func statusStats(issues []IssueCycleTime) []StatusStats {
+	order := make([]string, 0)
+	durations := make(map[string][]time.Duration)
+	for _, i := range issues {
+		for _, d := range i.Durations {
+			if _, ok := durations[d.Status]; !ok {
+				order = append(order, d.Status)
+			}
+			durations[d.Status] = append(durations[d.Status], d.Duration)
+		}
+	}
+
+	stats := make([]StatusStats, 0, len(order))
+	for _, status := range order {
+		ds := durations[status]
+		sort.Slice(ds, func(a, b int) bool { return ds[a] < ds[b] })
+
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+		}
+
+		median := ds[len(ds)/2]
+		if len(ds)%2 == 0 {
+			median = (ds[len(ds)/2-1] + ds[len(ds)/2]) / 2
+		}
+
+		stats = append(stats, StatusStats{
+			Status: status,
+			Issues: len(ds),
+			Mean:   total / time.Duration(len(ds)),
+			Median: median,
+		})
+	}
+
+	return stats
+}
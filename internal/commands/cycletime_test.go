@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestTimeInStatus(t *testing.T) {
+	created := at("2024-05-01T09:00:00Z")
+	now := at("2024-05-03T09:00:00Z")
+
+	testData := []struct {
+		Name      string
+		InCreated time.Time
+		InCurrent string
+		InChanges []StatusChange
+		Out       []StatusDuration
+	}{
+		{
+			Name:      "NeverMoved",
+			InCreated: created,
+			InCurrent: "To Do",
+			Out:       []StatusDuration{{"To Do", 48 * time.Hour}},
+		},
+		{
+			Name:      "CurrentStatusCountsUntilNow",
+			InCreated: created,
+			InCurrent: "In Review",
+			InChanges: []StatusChange{
+				{At: at("2024-05-01T10:00:00Z"), From: "To Do", To: "In Progress"},
+				{At: at("2024-05-02T10:00:00Z"), From: "In Progress", To: "In Review"},
+			},
+			Out: []StatusDuration{
+				{"To Do", time.Hour},
+				{"In Progress", 24 * time.Hour},
+				{"In Review", 23 * time.Hour},
+			},
+		},
+		{
+			Name:      "BouncedBackAndForth",
+			InCreated: created,
+			InCurrent: "Done",
+			InChanges: []StatusChange{
+				{At: at("2024-05-01T10:00:00Z"), From: "In Progress", To: "In Review"},
+				{At: at("2024-05-01T12:00:00Z"), From: "In Review", To: "In Progress"},
+				{At: at("2024-05-01T13:00:00Z"), From: "In Progress", To: "In Review"},
+				{At: at("2024-05-01T16:00:00Z"), From: "In Review", To: "Done"},
+			},
+			Out: []StatusDuration{
+				{"In Progress", 2 * time.Hour},
+				{"In Review", 5 * time.Hour},
+				{"Done", 41 * time.Hour},
+			},
+		},
+		{
+			Name:      "SkippedStatus",
+			InCreated: created,
+			InCurrent: "Done",
+			InChanges: []StatusChange{
+				{At: at("2024-05-02T09:00:00Z"), From: "To Do", To: "Done"},
+			},
+			Out: []StatusDuration{
+				{"To Do", 24 * time.Hour},
+				{"Done", 24 * time.Hour},
+			},
+		},
+		{
+			Name:      "TimeZonesAndOrder",
+			InCreated: created,
+			InCurrent: "Done",
+			InChanges: []StatusChange{
+				// 12:00 UTC, listed before the earlier change
+				{At: at("2024-05-01T14:00:00+02:00"), From: "In Progress", To: "Done"},
+				{At: at("2024-05-01T05:00:00-05:00"), From: "To Do", To: "In Progress"},
+			},
+			Out: []StatusDuration{
+				{"To Do", time.Hour},
+				{"In Progress", 2 * time.Hour},
+				{"Done", 45 * time.Hour},
+			},
+		},
+		{
+			Name:      "MissingChangeKeepsKnownStatus",
+			InCreated: created,
+			InCurrent: "Done",
+			InChanges: []StatusChange{
+				{At: at("2024-05-01T10:00:00Z"), From: "To Do", To: "In Progress"},
+				{At: at("2024-05-01T12:00:00Z"), From: "In Review", To: "Done"},
+			},
+			Out: []StatusDuration{
+				{"To Do", time.Hour},
+				{"In Progress", 2 * time.Hour},
+				{"Done", 45 * time.Hour},
+			},
+		},
+		{
+			Name:      "UnknownCreation",
+			InCurrent: "Done",
+			InChanges: []StatusChange{
+				{At: at("2024-05-02T09:00:00Z"), From: "To Do", To: "Done"},
+			},
+			Out: []StatusDuration{
+				{"To Do", 0},
+				{"Done", 24 * time.Hour},
+			},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, TimeInStatus(td.InCreated, td.InCurrent, td.InChanges, now))
+		})
+	}
+}
+
+func TestCommand_CycleTime(t *testing.T) {
+	now := at("2024-05-03T09:00:00Z")
+	issue := func(key, created, current string, histories ...jira.ChangelogHistory) jira.Issue {
+		return jira.Issue{
+			Key: key,
+			Fields: &jira.IssueFields{
+				Summary: key + " summary",
+				Created: jira.Time(at(created)),
+				Status:  &jira.Status{Name: current},
+			},
+			Changelog: &jira.Changelog{Histories: histories},
+		}
+	}
+
+	fake := jiwafake.New()
+	for _, i := range []jira.Issue{
+		issue("JIWA-1", "2024-05-02T09:00:00Z", "In Review",
+			change("2024-05-02T10:00:00.000+0000", alice, "status", "To Do", "In Review"),
+		),
+		issue("JIWA-2", "2024-05-01T09:00:00Z", "Done",
+			change("2024-05-01T10:00:00.000+0000", bob, "assignee", "", "Bob"),
+			change("2024-05-01T11:00:00.000+0000", bob, "status", "To Do", "In Review"),
+			change("2024-05-01T13:00:00.000+0000", bob, "status", "In Review", "Done"),
+		),
+		issue("JIWA-3", "2024-05-03T07:00:00Z", "In Review",
+			change("2024-05-03T08:00:00.000+0000", bob, "status", "To Do", "In Review"),
+		),
+	} {
+		fake.Issues[i.Key] = i
+	}
+	c := Command{Client: fake}
+
+	report, err := c.CycleTime(CycleTimeInput{Keys: []string{"JIWA-1", "JIWA-2", "JIWA-3"}, Now: now})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []StatusDuration{{"To Do", time.Hour}, {"In Review", 23 * time.Hour}}, report.Issues[0].Durations)
+	assert.Equal(t, []StatusStats{
+		{Status: "To Do", Issues: 3, Mean: 4 * time.Hour / 3, Median: time.Hour},
+		{Status: "In Review", Issues: 3, Mean: 26 * time.Hour / 3, Median: 2 * time.Hour},
+		{Status: "Done", Issues: 1, Mean: 44 * time.Hour, Median: 44 * time.Hour},
+	}, report.Statuses)
+
+	fake.SearchFunc = func(string) ([]jira.Issue, error) { return nil, nil }
+	_, err = c.CycleTime(CycleTimeInput{JQL: "project = NONE", Now: now})
+	assert.EqualError(t, err, "no issues to compute the cycle time of")
+}
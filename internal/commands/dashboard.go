@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// DashboardSection is a titled list of issues on the dashboard, Issues
+// are the first DashboardSectionIssues of the Count
+type DashboardSection struct {
+	Name   string       `json:"name"`
+	Title  string       `json:"title"`
+	JQL    string       `json:"jql"`
+	Count  int          `json:"count"`
+	Issues []jira.Issue `json:"issues"`
+}
+
+// StatusCount is how many issues are in a status
+type StatusCount struct {
+	Status   string `json:"status"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// Dashboard is the overview of a project
+type Dashboard struct {
+	Project  string             `json:"project"`
+	Statuses []StatusCount      `json:"statuses"`
+	Sections []DashboardSection `json:"sections"`
+}
+
+// dashboardQuery is a section of the dashboard and its default JQL,
+// "{project}" is replaced by the project clause
+type dashboardQuery struct {
+	Name  string
+	Title string
+	JQL   string
+}
+
+// statusesQuery is counted per status instead of being shown as a section
+const statusesQuery = "statuses"
+
+// DashboardSectionIssues is how many issues of a section are fetched, the
+// rest are only counted
+const DashboardSectionIssues = 10
+
+// errDashboardSectionFull stops SearchPages once a section has its issues
+var errDashboardSectionFull = errors.New("dashboard section is full")
+
+var dashboardQueries = []dashboardQuery{
+	{statusesQuery, "Statuses", "{project} AND statusCategory != Done"},
+	{"mine", "Assigned to you", "{project} AND assignee = currentUser() AND statusCategory != Done ORDER BY priority DESC, updated DESC"},
+	{"unassigned", "Unassigned", "{project} AND assignee is EMPTY AND statusCategory != Done ORDER BY priority DESC, created ASC"},
+	{"updated", "Updated in the last 24h", "{project} AND updated >= -24h ORDER BY updated DESC"},
+	{"overdue", "Overdue", "{project} AND duedate < now() AND statusCategory != Done ORDER BY duedate ASC"},
+}
+
+// DashboardSectionNames are the names "dashboardQueries" takes
+func DashboardSectionNames() []string {
+	names := make([]string, 0, len(dashboardQueries))
+	for _, q := range dashboardQueries {
+		names = append(names, q.Name)
+	}
+
+	return names
+}
+
+// Dashboard runs the searches for the overview of the project, or the
+// "defaultProject", at the same time
+func (c *Command) Dashboard(project string) (Dashboard, error) {
+	projects, err := c.ListProjects(project)
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	var clause string
+	switch len(projects) {
+	case 0:
+		return Dashboard{}, errors.New("no project given, set --project or \"defaultProject\"")
+	case 1:
+		clause = "project=" + projects[0]
+	default:
+		clause = "project IN (" + strings.Join(projects, ",") + ")"
+	}
+
+	queries, err := c.dashboardQueries(clause)
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	d := Dashboard{
+		Project:  strings.Join(projects, ","),
+		Statuses: make([]StatusCount, 0),
+		Sections: make([]DashboardSection, 0, len(queries)-1),
+	}
+	var statuses dashboardQuery
+	for _, q := range queries {
+		if q.Name == statusesQuery {
+			statuses = q
+			continue
+		}
+		d.Sections = append(d.Sections, DashboardSection{Name: q.Name, Title: q.Title, JQL: q.JQL, Issues: make([]jira.Issue, 0)})
+	}
+
+	// Jira counts the issues of a section, only the ones that are shown
+	// are fetched
+	err = c.parallel(len(d.Sections), func(ctx context.Context, i int) error {
+		s := &d.Sections[i]
+		count, err := c.Client.Count(ctx, s.JQL)
+		if err != nil {
+			return fmt.Errorf("could not get %q: %w", s.Title, err)
+		}
+		s.Count = count
+		if count == 0 {
+			return nil
+		}
+
+		err = c.Client.SearchPages(ctx, s.JQL, func(page []jira.Issue) error {
+			s.Issues = append(s.Issues, page[:min(len(page), DashboardSectionIssues-len(s.Issues))]...)
+			if len(s.Issues) == DashboardSectionIssues {
+				return errDashboardSectionFull
+			}
+			return nil
+		}, jiwa.WithFields("summary", "status", "assignee", "priority", "duedate", "updated"))
+		if err != nil && !errors.Is(err, errDashboardSectionFull) {
+			return fmt.Errorf("could not get %q: %w", s.Title, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	d.Statuses, err = c.countByStatus(projects, statuses)
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	return d, nil
+}
+
+// countByStatus asks Jira how many issues of the query are in each status
+// of the projects' workflows, statuses without any are left out
+func (c *Command) countByStatus(projects []string, q dashboardQuery) ([]StatusCount, error) {
+	ctx := c.ctx()
+	var statuses []jira.Status
+	for _, project := range projects {
+		listed, err := c.Client.ListStatuses(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("could not get %q: %w", q.Title, err)
+		}
+		for _, s := range listed {
+			if !slices.ContainsFunc(statuses, func(o jira.Status) bool { return o.Name == s.Name }) {
+				statuses = append(statuses, s)
+			}
+		}
+	}
+
+	condition, _ := splitOrderBy(q.JQL)
+	counts := make([]StatusCount, len(statuses))
+	err := c.parallel(len(statuses), func(ctx context.Context, i int) error {
+		s := statuses[i]
+		count, err := c.Client.Count(ctx, fmt.Sprintf("(%s) AND status = %s", condition, jqlQuote(s.Name)))
+		if err != nil {
+			return fmt.Errorf("could not get %q: %w", q.Title, err)
+		}
+		counts[i] = StatusCount{Status: s.Name, Category: s.StatusCategory.Key, Count: count}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts = slices.DeleteFunc(counts, func(s StatusCount) bool { return s.Count == 0 })
+	sort.SliceStable(counts, func(a, b int) bool {
+		return categoryRank(counts[a].Category) < categoryRank(counts[b].Category)
+	})
+
+	return counts, nil
+}
+
+// dashboardQueries applies "dashboardQueries" to the defaults and fills in
+// the project clause
+func (c *Command) dashboardQueries(clause string) ([]dashboardQuery, error) {
+	names := DashboardSectionNames()
+	overrides := make([]string, 0, len(c.Config.DashboardQueries))
+	for name := range c.Config.DashboardQueries {
+		overrides = append(overrides, name)
+	}
+	sort.Strings(overrides)
+
+	for _, name := range overrides {
+		if !slices.Contains(names, name) {
+			msg := fmt.Sprintf("unknown dashboard section %q in \"dashboardQueries\"", name)
+			if s := Suggest(name, names); s != "" {
+				msg += fmt.Sprintf(", did you mean %q?", s)
+			}
+			return nil, fmt.Errorf("%s, sections are %s", msg, strings.Join(names, ", "))
+		}
+	}
+
+	queries := make([]dashboardQuery, 0, len(dashboardQueries))
+	for _, q := range dashboardQueries {
+		if jql, ok := c.Config.DashboardQueries[q.Name]; ok {
+			q.JQL = jql
+		}
+		q.JQL = strings.ReplaceAll(q.JQL, "{project}", clause)
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
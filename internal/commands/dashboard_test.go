@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Dashboard(t *testing.T) {
+	issue := func(key, status, category string) jira.Issue {
+		return jira.Issue{Key: key, Fields: &jira.IssueFields{
+			Status: &jira.Status{Name: status, StatusCategory: jira.StatusCategory{Key: category}},
+		}}
+	}
+
+	testData := []struct {
+		Name         string
+		InProject    string
+		InQueries    map[string]string
+		InSearchErr  error
+		InErrors     map[string]error
+		OutStatuses  []StatusCount
+		OutSections  map[string][]string
+		OutCounts    map[string]int
+		OutJQL       map[string]string
+		OutErrPrefix string
+	}{
+		{
+			Name: "Defaults",
+			OutStatuses: []StatusCount{
+				{Status: "To Do", Category: "new", Count: 2},
+				{Status: "In Progress", Category: "indeterminate", Count: 1},
+			},
+			OutSections: map[string][]string{
+				"mine":       {"JIWA-2"},
+				"unassigned": {"JIWA-1", "JIWA-3"},
+				"updated":    {"JIWA-2"},
+				"overdue":    {},
+			},
+			OutJQL: map[string]string{
+				"mine":    "project=JIWA AND assignee = currentUser() AND statusCategory != Done ORDER BY priority DESC, updated DESC",
+				"overdue": "project=JIWA AND duedate < now() AND statusCategory != Done ORDER BY duedate ASC",
+			},
+		},
+		{
+			Name:      "OverriddenQueryForSeveralProjects",
+			InProject: "JIWA,OPS",
+			InQueries: map[string]string{"overdue": "{project} AND duedate < startOfDay()"},
+			OutStatuses: []StatusCount{
+				{Status: "To Do", Category: "new", Count: 2},
+				{Status: "In Progress", Category: "indeterminate", Count: 1},
+			},
+			OutSections: map[string][]string{
+				"mine":       {"JIWA-2"},
+				"unassigned": {"JIWA-1", "JIWA-3"},
+				"updated":    {"JIWA-2"},
+				"overdue":    {"JIWA-3"},
+			},
+			OutJQL: map[string]string{
+				"overdue": "project IN (JIWA,OPS) AND duedate < startOfDay()",
+			},
+		},
+		{
+			Name:      "OnlyTheShownIssuesAreFetched",
+			InQueries: map[string]string{"updated": "{project} AND updated >= -7d"},
+			OutStatuses: []StatusCount{
+				{Status: "To Do", Category: "new", Count: 2},
+				{Status: "In Progress", Category: "indeterminate", Count: 1},
+			},
+			OutSections: map[string][]string{
+				"mine":       {"JIWA-2"},
+				"unassigned": {"JIWA-1", "JIWA-3"},
+				"updated":    {"JIWA-1", "JIWA-2", "JIWA-3", "JIWA-4", "JIWA-5", "JIWA-6", "JIWA-7", "JIWA-8", "JIWA-9", "JIWA-10"},
+				"overdue":    {},
+			},
+			OutCounts: map[string]int{"updated": 12},
+			OutJQL:    map[string]string{},
+		},
+		{
+			Name:         "StatusesFail",
+			InErrors:     map[string]error{"ListStatuses": errors.New("failed to call API 403")},
+			OutErrPrefix: `could not get "Statuses"`,
+		},
+		{
+			Name:         "UnknownSection",
+			InQueries:    map[string]string{"overdeu": "{project}"},
+			OutErrPrefix: `unknown dashboard section "overdeu" in "dashboardQueries", did you mean "overdue"?`,
+		},
+		{
+			Name:         "SearchFails",
+			InSearchErr:  errors.New("failed to call API 400"),
+			OutErrPrefix: "could not get",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.SearchFunc = func(jql string) ([]jira.Issue, error) {
+				switch {
+				case td.InSearchErr != nil:
+					return nil, td.InSearchErr
+				case strings.Contains(jql, "updated >= -7d"):
+					many := make([]jira.Issue, 0, 12)
+					for n := 1; n <= 12; n++ {
+						many = append(many, issue(fmt.Sprintf("JIWA-%d", n), "To Do", "new"))
+					}
+					return many, nil
+				case strings.Contains(jql, "currentUser()"), strings.Contains(jql, "updated >="):
+					return []jira.Issue{issue("JIWA-2", "In Progress", "indeterminate")}, nil
+				case strings.Contains(jql, "is EMPTY"):
+					return []jira.Issue{issue("JIWA-1", "To Do", "new"), issue("JIWA-3", "To Do", "new")}, nil
+				case strings.Contains(jql, "startOfDay()"):
+					return []jira.Issue{issue("JIWA-3", "To Do", "new")}, nil
+				case strings.Contains(jql, "duedate"):
+					return nil, nil
+				}
+
+				open := []jira.Issue{
+					issue("JIWA-1", "To Do", "new"),
+					issue("JIWA-2", "In Progress", "indeterminate"),
+					issue("JIWA-3", "To Do", "new"),
+				}
+				if _, status, ok := strings.Cut(jql, " AND status = "); ok {
+					return slices.DeleteFunc(open, func(i jira.Issue) bool { return `"`+i.Fields.Status.Name+`"` != status }), nil
+				}
+				return open, nil
+			}
+			// the statuses are counted one by one, Done and Blocked have
+			// no open issues
+			fake.Statuses["JIWA"] = []jira.Status{
+				{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}},
+				{Name: "In Progress", StatusCategory: jira.StatusCategory{Key: "indeterminate"}},
+				{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new"}},
+			}
+			fake.Statuses["OPS"] = []jira.Status{
+				{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new"}},
+				{Name: "Blocked", StatusCategory: jira.StatusCategory{Key: "indeterminate"}},
+			}
+			for method, err := range td.InErrors {
+				fake.Errors[method] = err
+			}
+			c := Command{Client: fake, Config: Config{DefaultProject: "JIWA", DashboardQueries: td.InQueries}}
+
+			d, err := c.Dashboard(td.InProject)
+
+			if td.OutErrPrefix != "" {
+				assert.ErrorContains(t, err, td.OutErrPrefix)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutStatuses, d.Statuses)
+
+			sections := make(map[string][]string)
+			counts := make(map[string]int)
+			jqls := make(map[string]string)
+			for _, s := range d.Sections {
+				keys := make([]string, 0)
+				for _, i := range s.Issues {
+					keys = append(keys, i.Key)
+				}
+				sections[s.Name] = keys
+				counts[s.Name] = s.Count
+				if _, ok := td.OutJQL[s.Name]; ok {
+					jqls[s.Name] = s.JQL
+				}
+			}
+			assert.Equal(t, td.OutSections, sections)
+			for name, keys := range sections {
+				want, ok := td.OutCounts[name]
+				if !ok {
+					want = len(keys)
+				}
+				assert.Equal(t, want, counts[name], name)
+			}
+			assert.Equal(t, td.OutJQL, jqls)
+		})
+	}
+}
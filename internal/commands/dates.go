@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateExamples is what the errors of ParseDate suggest instead
+const dateExamples = "today, yesterday, tomorrow, next friday, last monday, 2024-07-01, -3d or +2w"
+
+// relativeDateRegEx matches days and weeks from today, the sign is required
+// so "3d" isn't read as either direction
+var relativeDateRegEx = regexp.MustCompile(`^([+-])(\d+)([dw])$`)
+
+// ParseDate turns what people type for a date into the start of that day
+// in the location of now. It takes "today", "yesterday" and "tomorrow",
+// "next <weekday>" and "last <weekday>", dates like 2024-07-01 and days or
+// weeks from today like -3d or +2w. Anything that could mean more than one
+// day, like a bare "friday" or 07/01/2024, is refused instead of guessed.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	in := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch in {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if m := relativeDateRegEx.FindStringSubmatch(in); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return time.Time{}, invalidDateError(s)
+		}
+		if m[3] == "w" {
+			n *= 7
+		}
+		if m[1] == "-" {
+			n = -n
+		}
+		return today.AddDate(0, 0, n), nil
+	}
+
+	if direction, name, ok := strings.Cut(in, " "); ok && (direction == "next" || direction == "last") {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return time.Time{}, invalidDateError(s)
+		}
+
+		// next and last never mean today, next friday on a friday is a
+		// week away
+		diff := (int(day) - int(today.Weekday()) + 7) % 7
+		if direction == "last" {
+			diff = -((int(today.Weekday()) - int(day) + 7) % 7)
+		}
+		if diff == 0 {
+			diff = 7
+			if direction == "last" {
+				diff = -7
+			}
+		}
+		return today.AddDate(0, 0, diff), nil
+	}
+
+	if _, ok := parseWeekday(in); ok {
+		return time.Time{}, fmt.Errorf("%q is ambiguous, say \"next %s\" or \"last %s\"", s, in, in)
+	}
+
+	if strings.ContainsAny(in, "/.") {
+		return time.Time{}, fmt.Errorf("%q is ambiguous, write dates as YYYY-MM-DD, e.g. 2024-07-01", s)
+	}
+
+	if relativeDateRegEx.MatchString("-" + in) {
+		return time.Time{}, fmt.Errorf("%q is ambiguous, say -%s for the past or +%s for the future", s, in, in)
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", in, now.Location())
+	if err != nil {
+		return time.Time{}, invalidDateError(s)
+	}
+
+	return t, nil
+}
+
+func invalidDateError(s string) error {
+	return fmt.Errorf("invalid date %q, use e.g. %s", s, dateExamples)
+}
+
+// parseWeekday takes the full English names and their first three letters
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+
+	return 0, false
+}
+
+// Location is where the configured "timezone" says timestamps are shown
+// in, the local one if it isn't set
+func (c *Command) Location() *time.Location {
+	if c.Config.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(c.Config.Timezone)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
+
+// FormatJiraTimestamp shows a timestamp of the API, like the ones of
+// comments and history entries, in loc. Timestamps it doesn't understand
+// are returned as they are.
+func FormatJiraTimestamp(s string, loc *time.Location) string {
+	t := parseJiraTime(s)
+	if t.IsZero() {
+		return s
+	}
+
+	return formatJiraTime(t, loc)
+}
+
+func formatJiraTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.In(loc).Format("2006-01-02 15:04")
+}
+
+// FormatRelative shows how long before now t was in the largest unit that
+// fits, like "5m ago", "2h ago", "3d ago" or "2w ago". Anything older than
+// eight weeks is shown as its day in loc instead, a count of weeks says
+// little by then. Times after now can only come from clocks that are off
+// and are "just now" too.
+func FormatRelative(t, now time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", d/time.Hour)
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", d/(24*time.Hour))
+	case d < 8*7*24*time.Hour:
+		return fmt.Sprintf("%dw ago", d/(7*24*time.Hour))
+	}
+
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	return t.Format("2006-01-02")
+}
+
+// jqlDate writes the day in the way JQL compares dates
+func jqlDate(t time.Time) string {
+	return `"` + t.Format("2006-01-02") + `"`
+}
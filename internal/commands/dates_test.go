@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// a Wednesday
+	now := time.Date(2024, 7, 3, 15, 30, 0, 0, berlin)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, berlin) }
+
+	testData := []struct {
+		Name      string
+		In        string
+		Out       time.Time
+		OutErrMsg string
+	}{
+		{Name: "Today", In: "today", Out: day(2024, 7, 3)},
+		{Name: "Yesterday", In: "Yesterday", Out: day(2024, 7, 2)},
+		{Name: "Tomorrow", In: " tomorrow ", Out: day(2024, 7, 4)},
+		{Name: "NextFriday", In: "next friday", Out: day(2024, 7, 5)},
+		{Name: "NextSameWeekday", In: "next wednesday", Out: day(2024, 7, 10)},
+		{Name: "LastMonday", In: "last  Mon", Out: day(2024, 7, 1)},
+		{Name: "LastSameWeekday", In: "last wed", Out: day(2024, 6, 26)},
+		{Name: "Date", In: "2024-07-01", Out: day(2024, 7, 1)},
+		{Name: "DaysAgo", In: "-3d", Out: day(2024, 6, 30)},
+		{Name: "WeeksAhead", In: "+2w", Out: day(2024, 7, 17)},
+		{Name: "BareWeekday", In: "friday", OutErrMsg: `"friday" is ambiguous, say "next friday" or "last friday"`},
+		{Name: "SlashedDate", In: "07/01/2024", OutErrMsg: `"07/01/2024" is ambiguous, write dates as YYYY-MM-DD, e.g. 2024-07-01`},
+		{Name: "UnsignedDays", In: "3d", OutErrMsg: `"3d" is ambiguous, say -3d for the past or +3d for the future`},
+		{Name: "NextNonsense", In: "next week", OutErrMsg: `invalid date "next week", use e.g. today, yesterday, tomorrow, next friday, last monday, 2024-07-01, -3d or +2w`},
+		{Name: "ImpossibleDate", In: "2024-02-30", OutErrMsg: `invalid date "2024-02-30", use e.g. today, yesterday, tomorrow, next friday, last monday, 2024-07-01, -3d or +2w`},
+		{Name: "Empty", In: "", OutErrMsg: `invalid date "", use e.g. today, yesterday, tomorrow, next friday, last monday, 2024-07-01, -3d or +2w`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out, err := ParseDate(td.In, now)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, out)
+		})
+	}
+}
+
+func TestFormatJiraTimestamp(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	testData := []struct {
+		Name       string
+		In         string
+		InLocation *time.Location
+		Out        string
+	}{
+		{Name: "UTC", In: "2024-07-01T22:15:00.000+0200", InLocation: time.UTC, Out: "2024-07-01 20:15"},
+		{Name: "Tokyo", In: "2024-07-01T22:15:00.000+0200", InLocation: tokyo, Out: "2024-07-02 05:15"},
+		{Name: "Unknown", In: "yesterday-ish", InLocation: time.UTC, Out: "yesterday-ish"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, FormatJiraTimestamp(td.In, td.InLocation))
+		})
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	now := time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC)
+
+	testData := []struct {
+		Name       string
+		In         time.Time
+		InLocation *time.Location
+		Out        string
+	}{
+		{Name: "Unset", In: time.Time{}, Out: ""},
+		{Name: "Seconds", In: now.Add(-59 * time.Second), Out: "just now"},
+		{Name: "Future", In: now.Add(2 * time.Minute), Out: "just now"},
+		{Name: "Minutes", In: now.Add(-5*time.Minute - 30*time.Second), Out: "5m ago"},
+		{Name: "Hours", In: now.Add(-2*time.Hour - 59*time.Minute), Out: "2h ago"},
+		{Name: "OtherOffset", In: time.Date(2024, 7, 1, 20, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), Out: "2h ago"},
+		{Name: "Days", In: now.AddDate(0, 0, -3), Out: "3d ago"},
+		{Name: "Weeks", In: now.AddDate(0, 0, -15), Out: "2w ago"},
+		{Name: "OlderUTC", In: time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC), InLocation: time.UTC, Out: "2024-03-01"},
+		{Name: "OlderTokyo", In: time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC), InLocation: tokyo, Out: "2024-03-02"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, FormatRelative(td.In, now, td.InLocation))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// diffFields are what diff compares besides the custom fields from the
+// config, created and updated are left out since they differ for any two
+// issues
+var diffFields = []string{
+	"summary", "issuetype", "status", "resolution", "priority", "assignee", "reporter",
+	"labels", "components", "fixVersions", "parent", "duedate", "description",
+}
+
+// FieldDiff is a field of two issues side by side. The values of fields
+// that hold a list are sorted, unset fields and empty values have none.
+type FieldDiff struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	A    []string `json:"a"`
+	B    []string `json:"b"`
+	Same bool     `json:"same"`
+}
+
+// IssueDiff compares the fields of the issues A and B
+type IssueDiff struct {
+	A      string      `json:"a"`
+	B      string      `json:"b"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+// Diff fetches both issues and compares their diffFields, the
+// "viewFields", "storyPointsField" and "flaggedField" from the config.
+// The order of lists doesn't matter and an unset field is the same as an
+// empty one. Unless all is set only the fields that differ are kept.
+func (c *Command) Diff(a, b string, all bool) (IssueDiff, error) {
+	names := slices.Clone(diffFields)
+	names = append(names, c.Config.ViewFields...)
+	for _, id := range []string{c.Config.StoryPointsField, c.Config.FlaggedField} {
+		if id != "" {
+			names = append(names, id)
+		}
+	}
+
+	ctx := c.ctx()
+	resolved, err := c.resolveViewFields(ctx, names)
+	if err != nil {
+		return IssueDiff{}, err
+	}
+	fields := make([]jira.Field, 0, len(resolved))
+	ids := make([]string, 0, len(resolved))
+	for _, f := range resolved {
+		if !slices.Contains(ids, f.ID) {
+			fields = append(fields, f)
+			ids = append(ids, f.ID)
+		}
+	}
+
+	keys := []string{a, b}
+	issues := make([]jira.Issue, len(keys))
+	err = c.parallel(len(keys), func(ctx context.Context, i int) error {
+		var err error
+		issues[i], err = c.Client.GetIssue(ctx, keys[i], jiwa.WithFields(ids...))
+		return err
+	})
+	if err != nil {
+		return IssueDiff{}, err
+	}
+
+	loc := c.Location()
+	diff := IssueDiff{A: issues[0].Key, B: issues[1].Key}
+	for _, f := range fields {
+		values := make([][]string, len(issues))
+		for i, issue := range issues {
+			if f.ID != "parent" {
+				values[i] = diffValues(issue, f.ID, loc)
+				continue
+			}
+			// an epic on Server is the parent through the Epic Link
+			parent, err := c.ParentOf(issue)
+			if err != nil {
+				return IssueDiff{}, err
+			}
+			if parent != "" {
+				values[i] = []string{parent}
+			}
+		}
+
+		same := slices.Equal(values[0], values[1])
+		if same && !all {
+			continue
+		}
+		diff.Fields = append(diff.Fields, FieldDiff{ID: f.ID, Name: f.Name, A: values[0], B: values[1], Same: same})
+	}
+
+	return diff, nil
+}
+
+// diffValues are the values of the field to compare, sorted if the field
+// holds a list. Unset fields, empty strings and empty lists all have none.
+func diffValues(issue jira.Issue, id string, loc *time.Location) []string {
+	f := issue.Fields
+	if f == nil {
+		return nil
+	}
+
+	var values []string
+	list := true
+	switch id {
+	case "labels":
+		values = slices.Clone(f.Labels)
+	case "components":
+		for _, c := range f.Components {
+			values = append(values, c.Name)
+		}
+	case "fixVersions":
+		for _, v := range f.FixVersions {
+			values = append(values, v.Name)
+		}
+	default:
+		if vs, ok := f.Unknowns[id].([]any); ok {
+			for _, v := range vs {
+				values = append(values, formatFieldValue(v))
+			}
+			break
+		}
+		list = false
+		values = []string{fieldValue(issue, id, loc)}
+	}
+
+	values = slices.DeleteFunc(values, func(v string) bool { return strings.TrimSpace(v) == "" })
+	if len(values) == 0 {
+		return nil
+	}
+	if list {
+		slices.Sort(values)
+	}
+
+	return values
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+func TestCommand_Diff(t *testing.T) {
+	base := func() *jira.IssueFields {
+		return &jira.IssueFields{
+			Summary:    "Deploy the API",
+			Type:       jira.IssueType{Name: "Task"},
+			Status:     &jira.Status{Name: "To Do"},
+			Labels:     []string{"backend", "urgent"},
+			Components: []*jira.Component{{Name: "API"}, {Name: "Auth"}},
+			Unknowns: tcontainer.MarshalMap{
+				"customfield_10016": 3.0,
+				"customfield_10030": []any{map[string]any{"value": "Linux"}, map[string]any{"value": "macOS"}},
+			},
+		}
+	}
+
+	testData := []struct {
+		Name       string
+		InChange   func(f *jira.IssueFields)
+		InAll      bool
+		InConfig   Config
+		OutFields  []FieldDiff
+		OutChanged []string
+	}{
+		{
+			Name: "Same",
+		},
+		{
+			Name: "ListsInAnyOrder",
+			InChange: func(f *jira.IssueFields) {
+				f.Labels = []string{"urgent", "backend"}
+				f.Components = []*jira.Component{{Name: "Auth"}, {Name: "API"}}
+				f.Unknowns["customfield_10030"] = []any{map[string]any{"value": "macOS"}, map[string]any{"value": "Linux"}}
+			},
+			InConfig: Config{ViewFields: []string{"Platforms"}},
+		},
+		{
+			Name: "UnsetIsEmpty",
+			InChange: func(f *jira.IssueFields) {
+				f.Description = "  "
+				f.FixVersions = []*jira.FixVersion{}
+				f.Priority = &jira.Priority{}
+				f.Unknowns["customfield_10040"] = ""
+				f.Unknowns["customfield_10041"] = []any{}
+			},
+			InConfig: Config{ViewFields: []string{"customfield_10040", "customfield_10041"}},
+		},
+		{
+			Name: "Differences",
+			InChange: func(f *jira.IssueFields) {
+				f.Status = &jira.Status{Name: "In Progress"}
+				f.Type = jira.IssueType{Name: "Bug"}
+				f.Labels = []string{"backend"}
+				f.Assignee = &jira.User{DisplayName: "Alice"}
+			},
+			OutFields: []FieldDiff{
+				{ID: "issuetype", Name: "Issue Type", A: []string{"Task"}, B: []string{"Bug"}},
+				{ID: "status", Name: "Status", A: []string{"To Do"}, B: []string{"In Progress"}},
+				{ID: "assignee", Name: "Assignee", B: []string{"Alice"}},
+				{ID: "labels", Name: "Labels", A: []string{"backend", "urgent"}, B: []string{"backend"}},
+			},
+		},
+		{
+			Name: "CustomFieldsFromTheConfig",
+			InChange: func(f *jira.IssueFields) {
+				f.Unknowns["customfield_10016"] = 5.0
+				f.Unknowns["customfield_10030"] = []any{map[string]any{"value": "Linux"}}
+			},
+			InConfig: Config{StoryPointsField: "customfield_10016", ViewFields: []string{"platforms"}},
+			OutFields: []FieldDiff{
+				{ID: "customfield_10030", Name: "Platforms", A: []string{"Linux", "macOS"}, B: []string{"Linux"}},
+				{ID: "customfield_10016", Name: "Story Points", A: []string{"3"}, B: []string{"5"}},
+			},
+		},
+		{
+			Name: "All",
+			InChange: func(f *jira.IssueFields) {
+				f.Status = &jira.Status{Name: "Done"}
+			},
+			InAll:      true,
+			OutChanged: []string{"status"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Fields = []jira.Field{
+				{ID: "customfield_10016", Name: "Story Points", Custom: true},
+				{ID: "customfield_10030", Name: "Platforms", Custom: true},
+				{ID: "customfield_10040", Name: "Team", Custom: true},
+				{ID: "customfield_10041", Name: "Sprint", Custom: true},
+			}
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: base()}
+			other := base()
+			if td.InChange != nil {
+				td.InChange(other)
+			}
+			fake.Issues["JIWA-2"] = jira.Issue{Key: "JIWA-2", Fields: other}
+			c := Command{Client: fake, Config: td.InConfig}
+
+			d, err := c.Diff("JIWA-1", "JIWA-2", td.InAll)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "JIWA-1", d.A)
+			assert.Equal(t, "JIWA-2", d.B)
+			if !td.InAll {
+				assert.Equal(t, td.OutFields, d.Fields)
+				return
+			}
+			var changed []string
+			for _, f := range d.Fields {
+				if !f.Same {
+					changed = append(changed, f.ID)
+				}
+			}
+			assert.Len(t, d.Fields, len(diffFields))
+			assert.Equal(t, td.OutChanged, changed)
+		})
+	}
+}
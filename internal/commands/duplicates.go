@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/browser"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+const maxDuplicates = 5
+
+// ErrAbortedByUser is returned when the user decided against going ahead
+var ErrAbortedByUser = errors.New("aborted")
+
+var wordRegEx = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// duplicateJQL builds a text search for open issues in the project whose
+// summary shares the words of the given summary. Only the words are kept
+// since quotes and the likes would need escaping in JQL and Lucene.
+func duplicateJQL(project, summary string) string {
+	words := wordRegEx.FindAllString(summary, -1)
+	if len(words) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"project = %s AND summary ~ \"%s\" AND statusCategory != Done",
+		project,
+		strings.Join(words, " "),
+	)
+}
+
+// FindDuplicates returns up to five open issues in the project that look
+// like they could be the same as an issue with the given summary.
+func (c *Command) FindDuplicates(project, summary string) ([]jira.Issue, error) {
+	jql := duplicateJQL(project, summary)
+	if jql == "" {
+		return nil, nil
+	}
+
+	issues, err := c.Client.Search(c.ctx(), jql, jiwa.WithFields("summary", "status"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for duplicates: %w", err)
+	}
+
+	if len(issues) > maxDuplicates {
+		issues = issues[:maxDuplicates]
+	}
+
+	return issues, nil
+}
+
+// checkDuplicates warns about possible duplicates and, when interactive,
+// asks whether to create the issue anyway. Answering "o <n>" opens the
+// n-th duplicate in the browser instead of creating the issue. When nobody
+// can be asked strict turns the warning into an error.
+func (c *Command) checkDuplicates(project, summary string, interactive, strict bool) error {
+	dupes, err := c.FindDuplicates(project, summary)
+	if err != nil {
+		return err
+	}
+
+	if len(dupes) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "found %d possible duplicates of %q:\n", len(dupes), summary)
+	for i, d := range dupes {
+		status := ""
+		if d.Fields.Status != nil {
+			status = d.Fields.Status.Name
+		}
+		fmt.Fprintf(os.Stderr, "%3d) %s [%s] %s\n", i+1, d.Key, status, d.Fields.Summary)
+	}
+
+	errNotAsked := fmt.Errorf("%w: found possible duplicates, pass --yes to create the issue anyway", ErrAbortedByUser)
+	if !interactive {
+		if strict {
+			return errNotAsked
+		}
+		return nil
+	}
+
+	p, err := prompt.Open()
+	if err != nil {
+		if strict {
+			return errNotAsked
+		}
+		return nil
+	}
+	defer p.Close()
+
+	for {
+		answer, err := p.Ask("create anyway? [y/N/o(pen #)] ")
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(strings.ToLower(answer))
+		switch {
+		case len(fields) == 0, fields[0] == "n", fields[0] == "no":
+			return fmt.Errorf("%w: not creating a possible duplicate", ErrAbortedByUser)
+		case fields[0] == "y", fields[0] == "yes":
+			return nil
+		case fields[0] == "o" && len(fields) == 2:
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 || n > len(dupes) {
+				p.Printf("pick a duplicate between 1 and %d\n", len(dupes))
+				continue
+			}
+
+			err = browser.Open(c.ConstructIssueURL(dupes[n-1].Key))
+			if err != nil {
+				return err
+			}
+
+			return fmt.Errorf("%w: opened %s instead of creating a new issue", ErrAbortedByUser, dupes[n-1].Key)
+		default:
+			p.Printf("answer y, n or o followed by the number of the duplicate\n")
+		}
+	}
+}
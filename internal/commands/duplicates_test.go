@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateJQL(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InSummary string
+		OutJQL    string
+	}{
+		{
+			Name:      "PlainWords",
+			InSummary: "TLS handshake fails",
+			OutJQL:    `project = JIWA AND summary ~ "TLS handshake fails" AND statusCategory != Done`,
+		},
+		{
+			Name:      "SpecialCharactersAreDropped",
+			InSummary: `"jiwa ls" crashes: nil-pointer (again!)`,
+			OutJQL:    `project = JIWA AND summary ~ "jiwa ls crashes nil pointer again" AND statusCategory != Done`,
+		},
+		{
+			Name:      "NoWords",
+			InSummary: "!!!",
+			OutJQL:    "",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.OutJQL, duplicateJQL("JIWA", td.InSummary))
+		})
+	}
+}
+
+func TestCommand_CheckDuplicates(t *testing.T) {
+	testData := []struct {
+		Name     string
+		InDupes  []jira.Issue
+		InStrict bool
+		OutErr   error
+	}{
+		{
+			Name:     "NoDuplicatesStrict",
+			InStrict: true,
+		},
+		{
+			Name:    "DuplicatesOnlyWarn",
+			InDupes: []jira.Issue{{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "TLS handshake fails"}}},
+		},
+		{
+			Name:     "DuplicatesStrict",
+			InDupes:  []jira.Issue{{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "TLS handshake fails"}}},
+			InStrict: true,
+			OutErr:   ErrAbortedByUser,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.SearchFunc = func(jql string) ([]jira.Issue, error) {
+				return td.InDupes, nil
+			}
+
+			c := Command{Client: fake}
+			err := c.checkDuplicates("JIWA", "TLS handshake fails", false, td.InStrict)
+
+			if td.OutErr != nil {
+				assert.ErrorIs(t, err, td.OutErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -2,27 +2,376 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Edit(issueID string) (string, error) {
-	summary, description, err := GetIssueIntoEditor(c.Client, issueID)
+// Edit opens the summary and description of the issue in the editor and
+// sends what was changed. The changes are shown as a diff and, unless yes is
+// set or there is no terminal to ask on, have to be confirmed first. If
+// somebody else changed the same fields in the meantime, their changes can
+// be merged in the editor, overwritten or the edit aborted.
+func (c *Command) Edit(issueID string, yes bool) (string, error) {
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issueID)
+	if err != nil {
+		return "", err
+	}
+
+	issue, err := c.Client.GetIssue(c.ctx(), issueID, jiwa.WithFields("summary", "description", "updated"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get summary and description: %w", err)
+	}
+	base := issueText{Summary: issue.Fields.Summary, Description: issue.Fields.Description}
+	updated := time.Time(issue.Fields.Updated)
+
+	summary, description, err := CreateIssueSummaryDescription(c.EditorFile("edit", issueID), FormatSummaryDescription(base.Summary, base.Description), false)
 	if err != nil {
 		return "", fmt.Errorf("failed to get summary and description: %w", err)
 	}
 
-	err = c.Client.UpdateIssue(context.TODO(), jira.Issue{
-		Key: issueID,
-		Fields: &jira.IssueFields{
-			Summary:     summary,
-			Description: description,
-		},
-	})
+	for {
+		description, err = c.withMentions(mentionScope{IssueKey: issueID}, description, base.Description)
+		if err != nil {
+			return "", err
+		}
+
+		input, changed := editInput(base.Summary, base.Description, summary, description)
+		if !changed {
+			fmt.Fprintf(os.Stderr, "nothing changed in %s\n", issueID)
+			return issueID, nil
+		}
+
+		fmt.Fprint(os.Stderr, formatEditDiff(base.Summary, base.Description, input))
+		if !yes {
+			err = confirmEdit(issueID)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		theirs, theirUpdate, moved, err := c.changedSince(issueID, updated)
+		if err != nil {
+			return "", err
+		}
+		if !moved || !conflicts(base, theirs, input) {
+			break
+		}
+
+		mine := issueText{Summary: summary, Description: description}
+		fmt.Fprint(os.Stderr, formatConflict(issueID, base, mine, theirs))
+		choice, err := askConflict(issueID)
+		if err != nil {
+			return "", err
+		}
+
+		if choice == conflictOverwrite {
+			break
+		}
+		if choice == conflictAbort {
+			return "", fmt.Errorf("%w: not updating %s", ErrAbortedByUser, issueID)
+		}
+
+		merged, err := c.editMerge(issueID, mergeBuffer(issueID, base, mine, theirs))
+		if err != nil {
+			return "", err
+		}
+		summary, description = merged.Summary, merged.Description
+		base, updated = theirs, theirUpdate
+	}
+
+	input, _ := editInput(base.Summary, base.Description, summary, description)
+	payload := hooks.Payload{Key: issueID, Summary: summary, Description: description}
+	err = c.runPreHook("pre-edit", payload)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.updateIssue(c.ctx(), issueID, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to update issue: %w", err)
+	}
+
+	c.runPostHook("post-edit", payload)
+	c.rememberIssue(issueID, summary)
+
+	return issueID, nil
+}
+
+// confirmEdit asks whether the changes should be sent, without a terminal
+// they are sent right away
+func confirmEdit(issueID string) error {
+	p, err := prompt.Open()
+	if err != nil {
+		return nil
+	}
+	defer p.Close()
+
+	ok, err := p.Confirm(fmt.Sprintf("update %s?", issueID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: not updating %s", ErrAbortedByUser, issueID)
+	}
+
+	return nil
+}
+
+// editDiffContext is how many unchanged lines are shown around a change of
+// the description
+const editDiffContext = 2
+
+// formatEditDiff shows the fields the input changes, the summary as a
+// whole and the description line by line
+func formatEditDiff(oldSummary, oldDescription string, input jiwa.UpdateIssueInput) string {
+	var b strings.Builder
+	if input.Summary != nil {
+		fmt.Fprintf(&b, "summary:\n- %s\n+ %s\n", oldSummary, *input.Summary)
+	}
+
+	if input.Description != nil {
+		b.WriteString("description:\n")
+		writeLineDiff(&b, oldDescription, *input.Description)
+	}
+
+	return b.String()
+}
+
+// writeLineDiff writes the changed lines and a few around them, the rest
+// is cut short to "..."
+func writeLineDiff(b *strings.Builder, oldText, newText string) {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	skipped := false
+	for i, l := range lines {
+		if l.Op == ' ' && !nearChange(lines, i, editDiffContext) {
+			if !skipped {
+				b.WriteString("  ...\n")
+			}
+			skipped = true
+			continue
+		}
+		skipped = false
+		fmt.Fprintf(b, "%c %s\n", l.Op, l.Text)
+	}
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// diffLine is a line of a diff, Op is ' ' for lines in both, '-' for
+// removed and '+' for added lines
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// diffLines is a line diff along the longest common subsequence of a and
+// b, removals come before additions where lines were replaced
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// nearChange reports whether a line within n lines of i was changed
+func nearChange(lines []diffLine, i, n int) bool {
+	for k := max(0, i-n); k <= min(len(lines)-1, i+n); k++ {
+		if lines[k].Op != ' ' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateIssue sends the edit, if that fails and ReopenIfClosed is set it
+// checks whether the issue is closed. Closed issues are moved to a status
+// that isn't done, edited and moved back to the status they were in.
+func (c *Command) updateIssue(ctx context.Context, key string, input jiwa.UpdateIssueInput) error {
+	err := c.Client.UpdateIssue(ctx, key, input)
+	if err == nil || !c.ReopenIfClosed {
+		return err
+	}
+
+	issue, getErr := c.Client.GetIssue(ctx, key, jiwa.WithFields("status"))
+	if getErr != nil || issue.Fields.Status == nil || issue.Fields.Status.StatusCategory.Key != "done" {
+		return err
+	}
+	closed := issue.Fields.Status.Name
+
+	transitions, listErr := c.Client.ListIssueTransitions(ctx, key)
+	if listErr != nil {
+		return fmt.Errorf("could not list the transitions to reopen %s: %v: %w", key, listErr, err)
+	}
+
+	reopen := ""
+	for _, t := range transitions {
+		if t.To.StatusCategory.Key != "done" {
+			reopen = t.Name
+			break
+		}
+	}
+	if reopen == "" {
+		return fmt.Errorf("there is no transition to reopen %s with: %w", key, err)
+	}
+
+	err = c.Client.Transition(ctx, key, jiwa.TransitionInput{Status: reopen, Current: issue.Fields.Status})
+	if err != nil {
+		return fmt.Errorf("could not reopen %s: %w", key, err)
+	}
+	fmt.Fprintf(os.Stderr, "reopened %s with %q to edit it\n", key, reopen)
+
+	err = c.Client.UpdateIssue(ctx, key, input)
+
+	closeErr := c.closeAgain(ctx, key, closed)
+	if closeErr != nil {
+		if err != nil {
+			return fmt.Errorf("%s is left open, %v: %w", key, closeErr, err)
+		}
+		return fmt.Errorf("edited %s but it is left open: %w", key, closeErr)
+	}
+
+	return err
+}
+
+// closeAgain moves the issue back into the status it was in before it was
+// reopened
+func (c *Command) closeAgain(ctx context.Context, key, status string) error {
+	transitions, err := c.Client.ListIssueTransitions(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transitions {
+		if !strings.EqualFold(t.To.Name, status) {
+			continue
+		}
+
+		err = c.Client.Transition(ctx, key, jiwa.TransitionInput{Status: t.Name})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "moved %s back to %s with %q\n", key, status, t.Name)
+
+		return nil
+	}
+
+	return fmt.Errorf("no transition leads back to %s", status)
+}
+
+// editInput only includes what was changed in the editor so a concurrent
+// edit of the other field isn't overwritten. Trailing newlines are ignored
+// since the editor round trip tends to add one.
+func editInput(oldSummary, oldDescription, summary, description string) (jiwa.UpdateIssueInput, bool) {
+	var input jiwa.UpdateIssueInput
+	if summary != oldSummary {
+		input.Summary = &summary
+	}
+
+	if strings.TrimRight(description, "\n") != strings.TrimRight(oldDescription, "\n") {
+		input.Description = &description
+	}
+
+	return input, input.Summary != nil || input.Description != nil
+}
+
+// AppendToDescription adds text to the end of the issue's description and
+// only sends the description back, the summary is left alone.
+func (c *Command) AppendToDescription(issueID string, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("nothing to append, the text is empty")
+	}
+
+	return c.appendToDescription(issueID, c.fromDescriptionFormat(text))
+}
+
+// appendToDescription is AppendToDescription for text that already is
+// wiki markup
+func (c *Command) appendToDescription(issueID string, text string) (string, error) {
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issueID)
+	if err != nil {
+		return "", err
+	}
+
+	issue, err := c.Client.GetIssue(c.ctx(), issueID, jiwa.WithFields("summary", "description"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get description: %w", err)
+	}
+
+	text, err = c.withMentions(mentionScope{IssueKey: issueID}, text, "")
+	if err != nil {
+		return "", err
+	}
+
+	description := appendDescription(issue.Fields.Description, text)
+
+	payload := hooks.Payload{Key: issueID, Summary: issue.Fields.Summary, Description: description}
+	err = c.runPreHook("pre-edit", payload)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.updateIssue(c.ctx(), issueID, jiwa.UpdateIssueInput{Description: &description})
 	if err != nil {
 		return "", fmt.Errorf("failed to update issue: %w", err)
 	}
 
+	c.runPostHook("post-edit", payload)
+	c.rememberIssue(issueID, issue.Fields.Summary)
+
 	return issueID, nil
 }
+
+func appendDescription(description, text string) string {
+	text = strings.TrimRight(text, "\n")
+	description = strings.TrimRight(description, "\n")
+	if description == "" {
+		return text
+	}
+
+	return description + "\n" + text
+}
@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_AppendToDescription(t *testing.T) {
+	testData := []struct {
+		Name           string
+		InDescription  string
+		InText         string
+		InFormat       string
+		OutDescription string
+		OutErrMsg      string
+	}{
+		{
+			Name:           "Append",
+			InDescription:  "Investigating.\n",
+			InText:         "Found the cause.\n",
+			OutDescription: "Investigating.\nFound the cause.",
+		},
+		{
+			Name:           "EmptyDescription",
+			InText:         "First note",
+			OutDescription: "First note",
+		},
+		{
+			Name:           "MarkdownOnlyConvertsTheNewText",
+			InDescription:  "*Steps*",
+			InText:         "## Cause\n- **found** it",
+			InFormat:       "markdown",
+			OutDescription: "*Steps*\nh2. Cause\n* *found* it",
+		},
+		{
+			Name:          "EmptyText",
+			InDescription: "Investigating.",
+			InText:        "\n",
+			OutErrMsg:     "nothing to append, the text is empty",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{
+				Key:    "JIWA-1",
+				Fields: &jira.IssueFields{Summary: "Keep me", Description: td.InDescription},
+			})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+
+			c := Command{Client: client, Config: Config{BaseURL: srv.URL, DescriptionFormat: td.InFormat}}
+			_, err = c.AppendToDescription("JIWA-1", td.InText)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			issue, _ := srv.Issue("JIWA-1")
+			assert.Equal(t, td.OutDescription, issue.Fields.Description)
+			assert.Equal(t, "Keep me", issue.Fields.Summary)
+
+			puts := 0
+			for _, r := range srv.Requests() {
+				if r.Method != http.MethodPut {
+					continue
+				}
+				puts++
+
+				var body struct {
+					Fields map[string]any `json:"fields"`
+				}
+				assert.NoError(t, json.Unmarshal(r.Body, &body))
+				assert.Equal(t, map[string]any{"description": td.OutDescription}, body.Fields)
+			}
+			assert.Equal(t, 1, puts)
+		})
+	}
+}
+
+func TestCommand_AppendToDescriptionReopenIfClosed(t *testing.T) {
+	done := jira.Status{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}}
+	reopen := jiratest.Transition{ID: "11", Name: "Reopen", To: jira.Status{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new"}}}
+	close := jiratest.Transition{ID: "31", Name: "Close", To: done}
+
+	testData := []struct {
+		Name           string
+		InStatus       jira.Status
+		InReopen       bool
+		InTransitions  []jiratest.Transition
+		OutDescription string
+		OutTransitions []string
+		OutErrMsg      string
+	}{
+		{
+			Name:           "OpenIssueIsNotMoved",
+			InStatus:       jira.Status{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new"}},
+			InReopen:       true,
+			InTransitions:  []jiratest.Transition{reopen, close},
+			OutDescription: "Old\nNew",
+		},
+		{
+			Name:           "ClosedWithoutFlag",
+			InStatus:       done,
+			InTransitions:  []jiratest.Transition{reopen, close},
+			OutDescription: "Old",
+			OutErrMsg:      "failed to update issue: failed to update JIWA-1: failed to call API 400",
+		},
+		{
+			Name:           "ClosedIsReopenedAndClosedAgain",
+			InStatus:       done,
+			InReopen:       true,
+			InTransitions:  []jiratest.Transition{close, reopen},
+			OutDescription: "Old\nNew",
+			OutTransitions: []string{`{"transition":{"id":"11"}}`, `{"transition":{"id":"31"}}`},
+		},
+		{
+			Name:           "NoWayToReopen",
+			InStatus:       done,
+			InReopen:       true,
+			InTransitions:  []jiratest.Transition{close},
+			OutDescription: "Old",
+			OutErrMsg:      "failed to update issue: there is no transition to reopen JIWA-1 with: failed to update JIWA-1",
+		},
+		{
+			Name:           "NoWayBack",
+			InStatus:       done,
+			InReopen:       true,
+			InTransitions:  []jiratest.Transition{reopen},
+			OutDescription: "Old\nNew",
+			OutTransitions: []string{`{"transition":{"id":"11"}}`},
+			OutErrMsg:      "failed to update issue: edited JIWA-1 but it is left open: no transition leads back to Done",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.LockClosed = true
+			srv.SetTransitions(td.InTransitions...)
+			srv.AddIssue(jira.Issue{
+				Key:    "JIWA-1",
+				Fields: &jira.IssueFields{Summary: "Keep me", Description: "Old", Status: &td.InStatus},
+			})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+
+			c := Command{Client: client, Config: Config{BaseURL: srv.URL}, ReopenIfClosed: td.InReopen}
+			_, err = c.AppendToDescription("JIWA-1", "New")
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			issue, _ := srv.Issue("JIWA-1")
+			assert.Equal(t, td.OutDescription, issue.Fields.Description)
+
+			var transitions []string
+			for _, r := range srv.Requests() {
+				if r.Method == http.MethodPost && strings.HasSuffix(r.Path, "/transitions") {
+					transitions = append(transitions, string(r.Body))
+				}
+			}
+			assert.Equal(t, td.OutTransitions, transitions)
+		})
+	}
+}
+
+func TestEditInput(t *testing.T) {
+	testData := []struct {
+		Name           string
+		InSummary      string
+		InDescription  string
+		OutSummary     *string
+		OutDescription *string
+		OutChanged     bool
+	}{
+		{
+			Name:          "Unchanged",
+			InSummary:     "Summary",
+			InDescription: "Description\n",
+		},
+		{
+			Name:          "OnlySummary",
+			InSummary:     "Better summary",
+			InDescription: "Description\n",
+			OutSummary:    strPtr("Better summary"),
+			OutChanged:    true,
+		},
+		{
+			Name:           "OnlyDescription",
+			InSummary:      "Summary",
+			InDescription:  "More details\n",
+			OutDescription: strPtr("More details\n"),
+			OutChanged:     true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			input, changed := editInput("Summary", "Description", td.InSummary, td.InDescription)
+
+			assert.Equal(t, td.OutChanged, changed)
+			assert.Equal(t, td.OutSummary, input.Summary)
+			assert.Equal(t, td.OutDescription, input.Description)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestFormatEditDiff(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InDescription string
+		InInput       jiwa.UpdateIssueInput
+		Out           string
+	}{
+		{
+			Name:    "Summary",
+			InInput: jiwa.UpdateIssueInput{Summary: strPtr("Better summary")},
+			Out:     "summary:\n- Summary\n+ Better summary\n",
+		},
+		{
+			Name:          "ChangedLine",
+			InDescription: "one\ntwo\nthree\n",
+			InInput:       jiwa.UpdateIssueInput{Description: strPtr("one\n2\nthree\nfour")},
+			Out:           "description:\n  one\n- two\n+ 2\n  three\n+ four\n",
+		},
+		{
+			Name:          "UnchangedLinesFarFromChangesAreSkipped",
+			InDescription: "1\n2\n3\n4\n5\n6\n7\n8",
+			InInput:       jiwa.UpdateIssueInput{Description: strPtr("1\n2\n3\n4\n5\n6\n7\neight")},
+			Out:           "description:\n  ...\n  6\n  7\n- 8\n+ eight\n",
+		},
+		{
+			Name:          "Both",
+			InDescription: "",
+			InInput:       jiwa.UpdateIssueInput{Summary: strPtr("New"), Description: strPtr("details")},
+			Out:           "summary:\n- Summary\n+ New\ndescription:\n+ details\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, formatEditDiff("Summary", td.InDescription, td.InInput))
+		})
+	}
+}
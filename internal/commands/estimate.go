@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// storyPointsFieldNames are what Jira Software calls the field, in order of
+// preference. Company-managed projects use "Story Points", team-managed ones
+// "Story point estimate".
+var storyPointsFieldNames = []string{"Story Points", "Story point estimate"}
+
+// StoryPointsField returns the ID of the story points field. The
+// "storyPointsField" config wins, otherwise it is looked up by name once and
+// kept in the state. An empty ID means the instance has none.
+func (c *Command) StoryPointsField() (string, error) {
+	return c.customField("storyPoints", "Story Points", c.Config.StoryPointsField)
+}
+
+func pickStoryPointsField(fields []jira.Field) string {
+	for _, name := range storyPointsFieldNames {
+		for _, f := range fields {
+			if f.Custom && strings.EqualFold(f.Name, name) {
+				return f.ID
+			}
+		}
+	}
+
+	return ""
+}
+
+type EstimateInput struct {
+	Value string
+	// Time sets the original estimate of the time tracking, e.g. "2d",
+	// instead of the story points
+	Time bool
+}
+
+// Estimate sets the story points or, with input.Time, the original
+// estimate of all issues to the same value. The value is checked before
+// anything is changed.
+func (c *Command) Estimate(issues []string, input EstimateInput) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		fields map[string]any
+		value  string
+	)
+	if input.Time {
+		duration, err := ParseJiraDuration(input.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields = map[string]any{"timetracking": map[string]string{"originalEstimate": duration}}
+		value = duration
+	} else {
+		points, err := ParseStoryPoints(input.Value)
+		if err != nil {
+			return nil, err
+		}
+		field, err := c.StoryPointsField()
+		if err != nil {
+			return nil, err
+		}
+		if field == "" {
+			return nil, errors.New("there is no story points field on this instance, set \"storyPointsField\" in the config to its ID or pass --time to set the original estimate")
+		}
+		fields = map[string]any{field: points}
+		value = FormatPoints(points)
+	}
+
+	ctx := c.ctx()
+	done := make([]string, 0, len(issues))
+	for _, key := range issues {
+		payload := hooks.Payload{Key: key, Estimate: value}
+		err := c.runPreHook("pre-estimate", payload)
+		if err != nil {
+			return done, err
+		}
+
+		err = c.updateIssue(ctx, key, jiwa.UpdateIssueInput{Fields: fields})
+		if err != nil {
+			return done, fmt.Errorf("failed to estimate %s: %w", key, err)
+		}
+
+		c.runPostHook("post-estimate", payload)
+		c.remember(key)
+		done = append(done, key)
+	}
+
+	return done, nil
+}
+
+// ParseStoryPoints reads story points, which are a plain number like 3 or
+// 0.5
+func ParseStoryPoints(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	points, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(points, 0) || math.IsNaN(points) {
+		if jiraDurationRegEx.MatchString(strings.ToLower(value)) {
+			return 0, fmt.Errorf("%q is not a number of story points, pass --time to set it as the original estimate", value)
+		}
+		return 0, fmt.Errorf("%q is not a number of story points", value)
+	}
+	if points < 0 {
+		return 0, fmt.Errorf("story points can't be negative, got %s", value)
+	}
+
+	return points, nil
+}
+
+var (
+	jiraDurationRegEx     = regexp.MustCompile(`^\s*(\d+(\.\d+)?\s*[wdhm]\s*)+$`)
+	jiraDurationPartRegEx = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([wdhm])`)
+)
+
+// ParseJiraDuration checks a duration the way Jira's time tracking writes
+// them, weeks, days, hours and minutes like "1w 2d" or "4h30m", and
+// returns it normalized to "4h 30m"
+func ParseJiraDuration(value string) (string, error) {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	if !jiraDurationRegEx.MatchString(lower) {
+		if _, err := strconv.ParseFloat(lower, 64); err == nil {
+			return "", fmt.Errorf("%q needs a unit, e.g. %sh, durations look like \"2d\", \"4h\" or \"1w 2d\"", value, lower)
+		}
+		return "", fmt.Errorf("%q is not a duration, durations look like \"2d\", \"4h\" or \"1w 2d\"", value)
+	}
+
+	parts := make([]string, 0)
+	for _, m := range jiraDurationPartRegEx.FindAllStringSubmatch(lower, -1) {
+		parts = append(parts, m[1]+m[2])
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// StoryPoints returns the story points of an issue that was fetched with
+// the field, ok is false if they aren't set
+func StoryPoints(issue jira.Issue, field string) (points float64, ok bool) {
+	if field == "" || issue.Fields == nil {
+		return 0, false
+	}
+
+	v, ok := issue.Fields.Unknowns[field].(float64)
+	return v, ok
+}
+
+// OriginalEstimate returns the original estimate of an issue that was
+// fetched with the timetracking field, like "2d"
+func OriginalEstimate(issue jira.Issue) string {
+	if issue.Fields == nil || issue.Fields.TimeTracking == nil {
+		return ""
+	}
+
+	return issue.Fields.TimeTracking.OriginalEstimate
+}
+
+// FormatPoints leaves off the decimals of whole points
+func FormatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+// FormatStoryPoints prints the story points of the issue, empty if there
+// are none
+func FormatStoryPoints(issue jira.Issue, field string) string {
+	points, ok := StoryPoints(issue, field)
+	if !ok {
+		return ""
+	}
+
+	return FormatPoints(points)
+}
+
+// EstimateSum adds up the story points of the issues a query found
+type EstimateSum struct {
+	Points float64
+	Issues int
+	// Unestimated are the keys of the issues without story points
+	Unestimated []string
+}
+
+// SumEstimates adds up the story points of all issues the JQL finds
+func (c *Command) SumEstimates(jql string) (EstimateSum, error) {
+	sum := EstimateSum{Unestimated: make([]string, 0)}
+
+	field, err := c.StoryPointsField()
+	if err != nil {
+		return sum, err
+	}
+	if field == "" {
+		return sum, errors.New("there is no story points field on this instance, set \"storyPointsField\" in the config to its ID")
+	}
+
+	err = c.Client.SearchPages(c.ctx(), jql, func(page []jira.Issue) error {
+		for _, issue := range page {
+			sum.Issues++
+			points, ok := StoryPoints(issue, field)
+			if !ok {
+				sum.Unestimated = append(sum.Unestimated, issue.Key)
+				continue
+			}
+			sum.Points += points
+		}
+		return nil
+	}, jiwa.WithFields(field))
+	if err != nil {
+		return sum, fmt.Errorf("failed to search for %q: %w", jql, err)
+	}
+
+	return sum, nil
+}
+
+// String reads like "13 points in 5 issues, 2 without an estimate"
+func (s EstimateSum) String() string {
+	out := fmt.Sprintf("%s points in %d issues", FormatPoints(s.Points), s.Issues)
+	if len(s.Unestimated) != 0 {
+		out += fmt.Sprintf(", %d without an estimate: %s", len(s.Unestimated), strings.Join(s.Unestimated, ", "))
+	}
+
+	return out
+}
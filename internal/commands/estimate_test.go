@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStoryPoints(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		Out       float64
+		OutErrMsg string
+	}{
+		{Name: "Whole", In: "3", Out: 3},
+		{Name: "Fraction", In: " 0.5 ", Out: 0.5},
+		{Name: "Zero", In: "0", Out: 0},
+		{Name: "Duration", In: "2d", OutErrMsg: `"2d" is not a number of story points, pass --time to set it as the original estimate`},
+		{Name: "Negative", In: "-1", OutErrMsg: "story points can't be negative, got -1"},
+		{Name: "Garbage", In: "lots", OutErrMsg: `"lots" is not a number of story points`},
+		{Name: "Infinite", In: "Inf", OutErrMsg: `"Inf" is not a number of story points`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			points, err := ParseStoryPoints(td.In)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, points)
+		})
+	}
+}
+
+func TestParseJiraDuration(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		Out       string
+		OutErrMsg string
+	}{
+		{Name: "Days", In: "2d", Out: "2d"},
+		{Name: "Hours", In: "4H", Out: "4h"},
+		{Name: "Combined", In: "1w 2d", Out: "1w 2d"},
+		{Name: "CombinedWithoutSpaces", In: "4h30m", Out: "4h 30m"},
+		{Name: "SpaceBeforeUnit", In: "1.5 h", Out: "1.5h"},
+		{Name: "NoUnit", In: "4", OutErrMsg: `"4" needs a unit, e.g. 4h, durations look like "2d", "4h" or "1w 2d"`},
+		{Name: "UnknownUnit", In: "2y", OutErrMsg: `"2y" is not a duration, durations look like "2d", "4h" or "1w 2d"`},
+		{Name: "Empty", In: "", OutErrMsg: `"" is not a duration, durations look like "2d", "4h" or "1w 2d"`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			duration, err := ParseJiraDuration(td.In)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, duration)
+		})
+	}
+}
+
+func TestCommand_Estimate(t *testing.T) {
+	storyPoints := jira.Field{ID: "customfield_10016", Name: "Story point estimate", Custom: true}
+
+	testData := []struct {
+		Name      string
+		InInput   EstimateInput
+		InFields  []jira.Field
+		InConfig  Config
+		InErrors  map[string]error
+		OutDone   []string
+		OutFields map[string]any
+		OutErrMsg string
+	}{
+		{
+			Name:      "StoryPoints",
+			InInput:   EstimateInput{Value: "5"},
+			InFields:  []jira.Field{{ID: "summary", Name: "Summary"}, storyPoints},
+			OutDone:   []string{"JIWA-1", "JIWA-2"},
+			OutFields: map[string]any{"customfield_10016": 5.0},
+		},
+		{
+			Name:      "StoryPointsFieldFromConfig",
+			InInput:   EstimateInput{Value: "0.5"},
+			InConfig:  Config{StoryPointsField: "customfield_10002"},
+			OutDone:   []string{"JIWA-1", "JIWA-2"},
+			OutFields: map[string]any{"customfield_10002": 0.5},
+		},
+		{
+			Name:      "OriginalEstimate",
+			InInput:   EstimateInput{Value: "1w2d", Time: true},
+			OutDone:   []string{"JIWA-1", "JIWA-2"},
+			OutFields: map[string]any{"timetracking": map[string]string{"originalEstimate": "1w 2d"}},
+		},
+		{
+			Name:      "NoStoryPointsField",
+			InInput:   EstimateInput{Value: "3"},
+			InFields:  []jira.Field{{ID: "summary", Name: "Summary"}},
+			OutErrMsg: `there is no story points field on this instance, set "storyPointsField" in the config to its ID or pass --time to set the original estimate`,
+		},
+		{
+			Name:      "DurationWithoutTime",
+			InInput:   EstimateInput{Value: "4h"},
+			InFields:  []jira.Field{storyPoints},
+			OutErrMsg: `"4h" is not a number of story points, pass --time to set it as the original estimate`,
+		},
+		{
+			Name:      "UpdateFails",
+			InInput:   EstimateInput{Value: "2d", Time: true},
+			InErrors:  map[string]error{"UpdateIssue": errors.New("field timetracking is not on the screen")},
+			OutDone:   []string{},
+			OutErrMsg: "failed to estimate JIWA-1: field timetracking is not on the screen",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Fields = td.InFields
+			for _, key := range []string{"JIWA-1", "JIWA-2"} {
+				fake.Issues[key] = jira.Issue{Key: key, Fields: &jira.IssueFields{Summary: "Estimate me"}}
+			}
+			for k, v := range td.InErrors {
+				fake.Errors[k] = v
+			}
+
+			c := Command{Client: fake, Config: td.InConfig}
+			done, err := c.Estimate([]string{"JIWA-1", "JIWA-2"}, td.InInput)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.OutDone, done)
+
+			for _, key := range done {
+				assert.Equal(t, td.OutFields, map[string]any(fake.Issues[key].Fields.Unknowns))
+			}
+		})
+	}
+}
+
+func TestCommand_SumEstimates(t *testing.T) {
+	fake := jiwafake.New()
+	fake.Fields = []jira.Field{{ID: "customfield_10016", Name: "Story Points", Custom: true}}
+	fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Unknowns: map[string]any{"customfield_10016": 3.0}}}
+	fake.Issues["JIWA-2"] = jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Unknowns: map[string]any{"customfield_10016": 0.5}}}
+	fake.Issues["JIWA-3"] = jira.Issue{Key: "JIWA-3", Fields: &jira.IssueFields{}}
+
+	c := Command{Client: fake}
+	sum, err := c.SumEstimates("sprint in openSprints()")
+
+	assert.NoError(t, err)
+	assert.Equal(t, EstimateSum{Points: 3.5, Issues: 3, Unestimated: []string{"JIWA-3"}}, sum)
+	assert.Equal(t, "3.5 points in 3 issues, 1 without an estimate: JIWA-3", sum.String())
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// ExportInput scopes an export, either by project and status or by JQL
+type ExportInput struct {
+	// Project takes the same values as ListInput.Project
+	Project string
+	// Status limits the export to one status, "all" or empty exports
+	// every status
+	Status string
+	// JQL replaces the project and status as the scope
+	JQL string
+	// Fields limits what is exported of every issue, everything Jira
+	// shows in searches if empty
+	Fields []string
+}
+
+// Export fetches every issue in scope page by page, fn gets each page as
+// soon as it arrives so nothing is kept around.
+func (c *Command) Export(ctx context.Context, input ExportInput, fn func(page []jira.Issue) error) error {
+	jql, err := c.exportJQL(input)
+	if err != nil {
+		return err
+	}
+
+	err = c.Client.SearchPages(ctx, jql, fn, fieldsOption(input.Fields)...)
+	if err != nil {
+		return fmt.Errorf("could not export issues: %w", err)
+	}
+
+	return nil
+}
+
+// ExportCount returns how many issues Export would fetch
+func (c *Command) ExportCount(input ExportInput) (int, error) {
+	jql, err := c.exportJQL(input)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.Client.Count(c.ctx(), jql)
+	if err != nil {
+		return 0, fmt.Errorf("could not count issues: %w", err)
+	}
+
+	return n, nil
+}
+
+func (c *Command) exportJQL(input ExportInput) (string, error) {
+	status := input.Status
+	if strings.EqualFold(status, "all") {
+		status = ""
+	}
+
+	// an export takes many pages, sorting by key keeps issues that are
+	// changed in the meantime from moving between pages
+	const order = " ORDER BY key ASC"
+
+	if input.JQL != "" {
+		if input.Project != "" || status != "" {
+			return "", errors.New("--jql replaces --project and --status, use one or the other")
+		}
+		if strings.Contains(strings.ToUpper(input.JQL), "ORDER BY") {
+			return input.JQL, nil
+		}
+		return input.JQL + order, nil
+	}
+
+	projects, err := c.ListProjects(input.Project)
+	if err != nil {
+		return "", err
+	}
+
+	var jql string
+	switch len(projects) {
+	case 0:
+		return "", errors.New("no project given, set --project, \"defaultProject\" or use --jql")
+	case 1:
+		jql = "project=" + projects[0]
+	default:
+		jql = "project IN (" + strings.Join(projects, ",") + ")"
+	}
+
+	if status != "" {
+		jql += " AND status=" + jqlQuote(status)
+	}
+
+	return jql + order, nil
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_ExportJQL(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InInput   ExportInput
+		OutJQL    string
+		OutErrMsg string
+	}{
+		{
+			Name:    "DefaultProjectAllStatuses",
+			InInput: ExportInput{Status: "all"},
+			OutJQL:  "project=JIWA ORDER BY key ASC",
+		},
+		{
+			Name:    "ProjectsAndStatus",
+			InInput: ExportInput{Project: "ops,sre", Status: "Done"},
+			OutJQL:  `project IN (OPS,SRE) AND status="Done" ORDER BY key ASC`,
+		},
+		{
+			Name:    "JQL",
+			InInput: ExportInput{JQL: "labels = backup", Status: "all"},
+			OutJQL:  "labels = backup ORDER BY key ASC",
+		},
+		{
+			Name:    "JQLWithOrder",
+			InInput: ExportInput{JQL: "project = OPS order by created"},
+			OutJQL:  "project = OPS order by created",
+		},
+		{
+			Name:      "JQLWithProject",
+			InInput:   ExportInput{JQL: "labels = backup", Project: "OPS"},
+			OutErrMsg: "--jql replaces --project and --status, use one or the other",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c := Command{Client: jiwafake.New(), Config: Config{DefaultProject: "JIWA"}}
+			jql, err := c.exportJQL(td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutJQL, jql)
+		})
+	}
+}
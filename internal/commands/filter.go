@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Filter is a saved query from "filters" in the config
+type Filter struct {
+	Name string
+	JQL  string
+}
+
+// Filters returns the saved filters sorted by name
+func (c *Command) Filters() []Filter {
+	filters := make([]Filter, 0, len(c.Config.Filters))
+	for name, jql := range c.Config.Filters {
+		filters = append(filters, Filter{Name: name, JQL: jql})
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Name < filters[j].Name })
+
+	return filters
+}
+
+// SavedFilter returns the JQL of the filter with @me expanded, a filter is
+// shared by a team so it can't name whoever runs it
+func (c *Command) SavedFilter(name string) (string, error) {
+	jql, ok := c.Config.Filters[name]
+	if !ok {
+		names := make([]string, 0, len(c.Config.Filters))
+		for n := range c.Config.Filters {
+			names = append(names, n)
+		}
+
+		msg := fmt.Sprintf("unknown filter %q", name)
+		if s := Suggest(name, names); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		return "", errors.New(msg + ", filters are set in \"filters\"")
+	}
+
+	jql = strings.TrimSpace(ExpandMe(jql))
+	if jql == "" {
+		return "", fmt.Errorf("filter %q has no JQL", name)
+	}
+
+	return jql, nil
+}
+
+// meRegEx matches @me on its own or quoted, but not inside e-mail
+// addresses like bob@me.com
+var meRegEx = regexp.MustCompile(`"@me"|'@me'|\B@me\b`)
+
+// ExpandMe replaces @me in JQL with currentUser(), the way --user @me
+// works
+func ExpandMe(jql string) string {
+	return meRegEx.ReplaceAllString(jql, "currentUser()")
+}
+
+var orderByRegEx = regexp.MustCompile(`(?is)\s*\border\s+by\s+(.*)$`)
+
+// splitOrderBy separates the ORDER BY of a filter from its condition, the
+// condition is AND-ed with the other flags and the order replaces the one
+// list sorts by
+func splitOrderBy(jql string) (string, string) {
+	loc := orderByRegEx.FindStringSubmatchIndex(jql)
+	if loc == nil {
+		return jql, ""
+	}
+
+	return strings.TrimSpace(jql[:loc[0]]), strings.TrimSpace(jql[loc[2]:loc[3]])
+}
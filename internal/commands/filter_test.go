@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandMe(t *testing.T) {
+	testData := []struct {
+		Name string
+		In   string
+		Out  string
+	}{
+		{Name: "Bare", In: "assignee = @me", Out: "assignee = currentUser()"},
+		{Name: "Quoted", In: `reporter = "@me" OR watcher = '@me'`, Out: "reporter = currentUser() OR watcher = currentUser()"},
+		{Name: "InFunction", In: "assignee was @me", Out: "assignee was currentUser()"},
+		{Name: "Email", In: `reporter = "bob@me.com"`, Out: `reporter = "bob@me.com"`},
+		{Name: "LongerName", In: "assignee = @meg", Out: "assignee = @meg"},
+		{Name: "CurrentUserUntouched", In: "assignee = currentUser()", Out: "assignee = currentUser()"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, ExpandMe(td.In))
+		})
+	}
+}
+
+func TestCommand_SavedFilter(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InName    string
+		OutJQL    string
+		OutErrMsg string
+	}{
+		{Name: "ExpandsMe", InName: "triage", OutJQL: "project = OPS AND (assignee = currentUser() OR assignee is EMPTY)"},
+		{Name: "Empty", InName: "blank", OutErrMsg: `filter "blank" has no JQL`},
+		{Name: "Unknown", InName: "oncall", OutErrMsg: `unknown filter "oncall", filters are set in "filters"`},
+	}
+
+	c := Command{Config: Config{Filters: map[string]string{
+		"triage": " project = OPS AND (assignee = @me OR assignee is EMPTY)",
+		"blank":  " ",
+	}}}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			jql, err := c.SavedFilter(td.InName)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutJQL, jql)
+		})
+	}
+
+	assert.Equal(t, []Filter{{Name: "blank", JQL: " "}, {Name: "triage", JQL: " project = OPS AND (assignee = @me OR assignee is EMPTY)"}}, c.Filters())
+}
+
+func TestSplitOrderBy(t *testing.T) {
+	testData := []struct {
+		Name         string
+		In           string
+		OutCondition string
+		OutOrder     string
+	}{
+		{Name: "None", In: "project = OPS", OutCondition: "project = OPS"},
+		{Name: "Order", In: "project = OPS ORDER BY created DESC", OutCondition: "project = OPS", OutOrder: "created DESC"},
+		{Name: "LowerCaseOverLines", In: "project = OPS\norder by\n rank", OutCondition: "project = OPS", OutOrder: "rank"},
+		{Name: "OnlyOrder", In: "ORDER BY rank", OutOrder: "rank"},
+		{Name: "BorderNotOrder", In: `labels = border`, OutCondition: `labels = border`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			condition, order := splitOrderBy(td.In)
+			assert.Equal(t, td.OutCondition, condition)
+			assert.Equal(t, td.OutOrder, order)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// flaggedSchema is the type of Jira Software's Flagged field, a checkbox
+// with the single option "Impediment"
+const flaggedSchema = "com.atlassian.jira.plugin.system.customfieldtypes:multicheckboxes"
+
+// flaggedValue is what boards set the Flagged field to
+var flaggedValue = []map[string]string{{"value": "Impediment"}}
+
+// FlaggedField returns the ID of the Flagged field. "flaggedField" in the
+// config wins, otherwise it is looked up by name once and kept in the
+// state. An empty ID means the instance has no Flagged field.
+func (c *Command) FlaggedField() (string, error) {
+	return c.customField("flagged", "Flagged", c.Config.FlaggedField)
+}
+
+func pickFlaggedField(fields []jira.Field) string {
+	for _, f := range fields {
+		if f.Custom && strings.EqualFold(f.Name, "Flagged") && f.Schema.Custom == flaggedSchema {
+			return f.ID
+		}
+	}
+
+	return ""
+}
+
+// customFieldPickers find the custom fields jiwa uses among all fields of
+// the instance, keyed by what they are used for
+var customFieldPickers = map[string]func(fields []jira.Field) string{
+	"flagged":     pickFlaggedField,
+	"storyPoints": pickStoryPointsField,
+}
+
+// customField returns the ID of the custom field that is used for purpose,
+// a key of customFieldPickers. configured wins, otherwise all of them are
+// looked up at once and kept in the state, so listing the fields is paid
+// for a single time. An empty ID means the instance has no such field.
+func (c *Command) customField(purpose, name, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	if c.State != nil {
+		st, err := c.State.Load()
+		if err == nil {
+			if id, ok := st.Fields[purpose]; ok {
+				return id, nil
+			}
+		}
+	}
+
+	fields, err := c.Client.ListFields(c.ctx())
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the %s field: %w", name, err)
+	}
+
+	ids := make(map[string]string, len(customFieldPickers))
+	for p, pick := range customFieldPickers {
+		ids[p] = pick(fields)
+	}
+
+	if c.State != nil {
+		err = c.State.Update(func(st *state.State) error {
+			if st.Fields == nil {
+				st.Fields = make(map[string]string)
+			}
+			for p, id := range ids {
+				st.Fields[p] = id
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remember the %s field: %s\n", name, err)
+		}
+	}
+
+	return ids[purpose], nil
+}
+
+// IsFlagged reports whether the issue was fetched with the Flagged field
+// and it is set
+func IsFlagged(issue jira.Issue, field string) bool {
+	if field == "" || issue.Fields == nil {
+		return false
+	}
+
+	v, ok := issue.Fields.Unknowns[field]
+	if !ok || v == nil {
+		return false
+	}
+	if list, ok := v.([]any); ok {
+		return len(list) != 0
+	}
+
+	return true
+}
+
+// Flag marks the issues as impediments, reason is added as a comment in
+// the same request
+func (c *Command) Flag(issues []string, reason string) ([]string, error) {
+	return c.setFlagged(issues, flaggedValue, reason, "flag")
+}
+
+// Unflag clears the Flagged field of the issues, reason is added as a
+// comment in the same request
+func (c *Command) Unflag(issues []string, reason string) ([]string, error) {
+	return c.setFlagged(issues, nil, reason, "unflag")
+}
+
+func (c *Command) setFlagged(issues []string, value any, reason, action string) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := c.FlaggedField()
+	if err != nil {
+		return nil, err
+	}
+	if field == "" {
+		return nil, errors.New("there is no Flagged field on this instance, set \"flaggedField\" in the config to the ID of the field boards flag issues with")
+	}
+
+	ctx := c.ctx()
+	done := make([]string, 0, len(issues))
+	for _, key := range issues {
+		err = c.flagIssue(ctx, key, field, value, reason, action)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, key)
+	}
+
+	return done, nil
+}
+
+// flagIssue runs the pre- and post-flag or -unflag hooks around the update
+func (c *Command) flagIssue(ctx context.Context, key, field string, value any, reason, action string) error {
+	input := jiwa.UpdateIssueInput{Fields: map[string]any{field: value}}
+	payload := hooks.Payload{Key: key}
+	if strings.TrimSpace(reason) != "" {
+		text, err := c.withMentions(mentionScope{IssueKey: key}, reason, "")
+		if err != nil {
+			return err
+		}
+		input.Comment = text
+		payload.Comment = text
+	}
+
+	err := c.runPreHook("pre-"+action, payload)
+	if err != nil {
+		return err
+	}
+
+	err = c.updateIssue(ctx, key, input)
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", action, key, err)
+	}
+
+	c.runPostHook("post-"+action, payload)
+	c.remember(key)
+
+	return nil
+}
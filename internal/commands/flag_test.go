@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Flag(t *testing.T) {
+	flagged := jira.Field{
+		ID:     "customfield_10021",
+		Name:   "Flagged",
+		Custom: true,
+		Schema: jira.FieldSchema{Custom: flaggedSchema},
+	}
+
+	testData := []struct {
+		Name       string
+		InFields   []jira.Field
+		InConfig   string
+		InUnflag   bool
+		InReason   string
+		OutField   string
+		OutFlagged bool
+		OutComment string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "FlagWithReason",
+			InFields:   []jira.Field{flagged},
+			InReason:   "Waiting on ops",
+			OutField:   "customfield_10021",
+			OutFlagged: true,
+			OutComment: "Waiting on ops",
+		},
+		{
+			Name:     "Unflag",
+			InFields: []jira.Field{flagged},
+			InUnflag: true,
+			OutField: "customfield_10021",
+		},
+		{
+			Name:       "ConfiguredField",
+			InFields:   []jira.Field{flagged},
+			InConfig:   "customfield_10100",
+			OutField:   "customfield_10100",
+			OutFlagged: true,
+		},
+		{
+			Name:      "NameOnlyIsNotEnough",
+			InFields:  []jira.Field{{ID: "customfield_10030", Name: "Flagged", Custom: true}},
+			OutErrMsg: `there is no Flagged field on this instance, set "flaggedField" in the config to the ID of the field boards flag issues with`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Fields = td.InFields
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{}}
+			if td.InUnflag {
+				fake.Issues["JIWA-1"].Fields.Unknowns = map[string]any{td.OutField: []any{map[string]any{"value": "Impediment"}}}
+			}
+			c := Command{Client: fake, Config: Config{FlaggedField: td.InConfig}}
+
+			var err error
+			if td.InUnflag {
+				_, err = c.Unflag([]string{"JIWA-1"}, td.InReason)
+			} else {
+				_, err = c.Flag([]string{"JIWA-1"}, td.InReason)
+			}
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			issue := fake.Issues["JIWA-1"]
+			assert.Equal(t, td.OutFlagged, IsFlagged(issue, td.OutField))
+			if td.OutComment == "" {
+				assert.Nil(t, issue.Fields.Comments)
+				return
+			}
+			if assert.NotNil(t, issue.Fields.Comments) {
+				assert.Equal(t, td.OutComment, issue.Fields.Comments.Comments[0].Body)
+			}
+		})
+	}
+}
+
+func TestCommand_FlaggedFieldIsCached(t *testing.T) {
+	fake := jiwafake.New()
+	fake.Fields = []jira.Field{{
+		ID:     "customfield_10021",
+		Name:   "Flagged",
+		Custom: true,
+		Schema: jira.FieldSchema{Custom: flaggedSchema},
+	}}
+	c := Command{Client: fake, State: &state.Store{Path: filepath.Join(t.TempDir(), "state.json")}}
+
+	id, err := c.FlaggedField()
+	assert.NoError(t, err)
+	assert.Equal(t, "customfield_10021", id)
+
+	fake.Errors = map[string]error{"ListFields": errors.New("boom")}
+	id, err = c.FlaggedField()
+	assert.NoError(t, err)
+	assert.Equal(t, "customfield_10021", id)
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+const (
+	// grepCommentResults is how many of the top results get their comments
+	// fetched when searching comments
+	grepCommentResults = 10
+	// grepContext is how many characters are shown around a match
+	grepContext = 40
+
+	colorMatch = "\x1b[1;31m"
+	colorReset = "\x1b[0m"
+)
+
+type GrepInput struct {
+	Terms       []string
+	Project     string
+	AllProjects bool
+	Comments    bool
+	// Fields are fetched on top of the summary and description, for
+	// printing the issues instead of the excerpts
+	Fields []string
+}
+
+type GrepMatch struct {
+	Field   string
+	Excerpt string
+}
+
+type GrepResult struct {
+	Issue   jira.Issue
+	Matches []GrepMatch
+}
+
+// Grep runs a full text search and returns the issues in Jira's relevance
+// order together with excerpts of where the terms matched.
+func (c *Command) Grep(input GrepInput) ([]GrepResult, error) {
+	if len(input.Terms) == 0 {
+		return nil, fmt.Errorf("need at least one term to search for")
+	}
+
+	query := jqlQuote(strings.Join(input.Terms, " "))
+	jql := fmt.Sprintf("(summary ~ %s OR description ~ %s)", query, query)
+	if input.Comments {
+		jql = "text ~ " + query
+	}
+
+	if !input.AllProjects {
+		project, err := c.FishOutProject(input.Project)
+		if err != nil {
+			return nil, err
+		}
+		jql = fmt.Sprintf("project = %s AND %s", project, jql)
+	}
+
+	fields := append([]string{"summary", "description"}, input.Fields...)
+	issues, err := c.Client.Search(c.ctx(), jql, jiwa.WithFields(fields...))
+	if err != nil {
+		return nil, fmt.Errorf("could not search issues: %w", err)
+	}
+
+	results := make([]GrepResult, 0, len(issues))
+	for i, issue := range issues {
+		if input.Comments && i < grepCommentResults {
+			full, err := c.Client.GetIssue(c.ctx(), issue.Key, jiwa.WithFields(append(fields, "comment")...))
+			if err != nil {
+				return nil, err
+			}
+			issue = full
+		}
+
+		r := GrepResult{Issue: issue}
+		if e := excerpt(issue.Fields.Summary, input.Terms, grepContext); e != "" {
+			r.Matches = append(r.Matches, GrepMatch{Field: "summary", Excerpt: e})
+		}
+		if e := excerpt(issue.Fields.Description, input.Terms, grepContext); e != "" {
+			r.Matches = append(r.Matches, GrepMatch{Field: "description", Excerpt: e})
+		}
+		if input.Comments && issue.Fields.Comments != nil {
+			for _, comment := range issue.Fields.Comments.Comments {
+				if e := excerpt(comment.Body, input.Terms, grepContext); e != "" {
+					r.Matches = append(r.Matches, GrepMatch{Field: "comment by " + comment.Author.DisplayName, Excerpt: e})
+				}
+			}
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// termsRegEx matches any of the terms regardless of case, longer terms
+// first so that the longest one wins where they overlap. The matches are
+// found in the text itself since lowercasing can change the length of a
+// string, e.g. for ẞ. It returns nil when there are no terms.
+func termsRegEx(terms []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t != "" {
+			quoted = append(quoted, regexp.QuoteMeta(t))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	sort.SliceStable(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+	return regexp.MustCompile("(?i)(?:" + strings.Join(quoted, "|") + ")")
+}
+
+// excerpt returns the text around the first match of any of the terms,
+// with whitespace collapsed and "..." marking where it was cut. It returns
+// an empty string when none of the terms match.
+func excerpt(text string, terms []string, context int) string {
+	re := termsRegEx(terms)
+	if re == nil {
+		return ""
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+	start, end := loc[0], loc[1]
+
+	from := start
+	for n := 0; from > 0 && n < context; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := end
+	for n := 0; to < len(text) && n < context; n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	result := text[from:to]
+	if from > 0 {
+		result = "..." + result
+	}
+	if to < len(text) {
+		result += "..."
+	}
+
+	return result
+}
+
+// highlight wraps every case insensitive occurrence of the terms in ANSI
+// color codes.
+func highlight(text string, terms []string) string {
+	re := termsRegEx(terms)
+	if re == nil {
+		return text
+	}
+
+	return re.ReplaceAllStringFunc(text, func(m string) string {
+		return colorMatch + m + colorReset
+	})
+}
+
+// FormatGrepResult renders a result the way ripgrep would, with the issue
+// on the first line and indented excerpts below.
+func (c *Command) FormatGrepResult(r GrepResult, terms []string, color bool) string {
+	var b strings.Builder
+	b.WriteString(c.ConstructIssueURL(r.Issue.Key) + " " + r.Issue.Fields.Summary + "\n")
+	for _, m := range r.Matches {
+		e := m.Excerpt
+		if color {
+			e = highlight(e, terms)
+		}
+		b.WriteString(fmt.Sprintf("  %s: %s\n", m.Field, e))
+	}
+
+	return b.String()
+}
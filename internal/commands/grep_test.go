@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExcerpt(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InText     string
+		InTerms    []string
+		InWidth    int
+		OutExcerpt string
+	}{
+		{
+			Name:       "ShortTextIsNotCut",
+			InText:     "the TLS handshake fails",
+			InTerms:    []string{"handshake"},
+			InWidth:    40,
+			OutExcerpt: "the TLS handshake fails",
+		},
+		{
+			Name:       "CutOnBothSides",
+			InText:     "0123456789 handshake 0123456789",
+			InTerms:    []string{"HANDSHAKE"},
+			InWidth:    3,
+			OutExcerpt: "...89 handshake 01...",
+		},
+		{
+			Name:       "FirstMatchOfAnyTerm",
+			InText:     "a b c tls d e f handshake",
+			InTerms:    []string{"handshake", "tls"},
+			InWidth:    2,
+			OutExcerpt: "...c tls d...",
+		},
+		{
+			Name:       "WhitespaceIsCollapsed",
+			InText:     "line one\n\n  tls\tline",
+			InTerms:    []string{"tls"},
+			InWidth:    3,
+			OutExcerpt: "...ne tls li...",
+		},
+		{
+			Name:       "MultiByteRunes",
+			InText:     "äöü tls äöü",
+			InTerms:    []string{"tls"},
+			InWidth:    2,
+			OutExcerpt: "...ü tls ä...",
+		},
+		{
+			Name:       "LongerWhenLowercased",
+			InText:     "ẞẞẞẞ TLS",
+			InTerms:    []string{"tls"},
+			InWidth:    2,
+			OutExcerpt: "...ẞ TLS",
+		},
+		{
+			Name:       "NoMatch",
+			InText:     "nothing to see",
+			InTerms:    []string{"tls"},
+			InWidth:    2,
+			OutExcerpt: "",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.OutExcerpt, excerpt(td.InText, td.InTerms, td.InWidth))
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	testData := []struct {
+		Name    string
+		InText  string
+		InTerms []string
+		Out     string
+	}{
+		{
+			Name:    "AnyCase",
+			InText:  "TLS and tls handshake",
+			InTerms: []string{"tls", "hand"},
+			Out:     "\x1b[1;31mTLS\x1b[0m and \x1b[1;31mtls\x1b[0m \x1b[1;31mhand\x1b[0mshake",
+		},
+		{
+			Name:    "LongestTerm",
+			InText:  "handshake",
+			InTerms: []string{"hand", "handshake"},
+			Out:     "\x1b[1;31mhandshake\x1b[0m",
+		},
+		{
+			Name:    "LongerWhenLowercased",
+			InText:  "ẞẞẞẞ tls",
+			InTerms: []string{"tls"},
+			Out:     "ẞẞẞẞ \x1b[1;31mtls\x1b[0m",
+		},
+		{
+			Name:    "FoldedNonASCII",
+			InText:  "Straße STRAẞE",
+			InTerms: []string{"straße"},
+			Out:     "\x1b[1;31mStraße\x1b[0m \x1b[1;31mSTRAẞE\x1b[0m",
+		},
+		{
+			Name:    "MetaCharacters",
+			InText:  "a+b and ab",
+			InTerms: []string{"a+b"},
+			Out:     "\x1b[1;31ma+b\x1b[0m and ab",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, highlight(td.InText, td.InTerms))
+		})
+	}
+}
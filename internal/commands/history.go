@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// History returns the changes that were made to the issue, oldest first
+func (c *Command) History(issueID string) ([]jira.ChangelogHistory, error) {
+	issue, err := c.Client.GetIssue(c.ctx(), issueID, jiwa.WithFields("summary"), jiwa.WithExpand("changelog"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	c.rememberIssue(issue.Key, issue.Fields.Summary)
+
+	if issue.Changelog == nil {
+		return nil, nil
+	}
+
+	return issue.Changelog.Histories, nil
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/catouc/jiwa/internal/hooks"
+)
+
+func (c *Command) runPreHook(name string, payload hooks.Payload) error {
+	if payload.URL == "" {
+		payload.URL = c.ConstructIssueURL(payload.Key)
+	}
+
+	if payload.Project == "" {
+		payload.Project = projectFromIssueKey(payload.Key)
+	}
+
+	return c.Hooks.Run(c.ctx(), name, payload)
+}
+
+// runPostHook only warns about failures since the change has already
+// been made in Jira and there is nothing left to abort.
+func (c *Command) runPostHook(name string, payload hooks.Payload) {
+	if payload.URL == "" {
+		payload.URL = c.ConstructIssueURL(payload.Key)
+	}
+
+	if payload.Project == "" {
+		payload.Project = projectFromIssueKey(payload.Key)
+	}
+
+	err := c.Hooks.Run(c.ctx(), name, payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+	}
+}
@@ -0,0 +1,381 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// ImportRow is a record of the import, Line is where it starts in the input
+// and Err why it can't be imported
+type ImportRow struct {
+	Line  int
+	Input jiwa.CreateIssueInput
+	Err   error
+}
+
+// ImportResult is what happened to a row, Key is empty if it wasn't created
+type ImportResult struct {
+	Line int
+	Key  string
+	Err  error
+}
+
+// importColumns maps the column and key names import understands to the
+// field they set, anything else is passed on to Jira as a field ID
+var importColumns = map[string]string{
+	"project":     "project",
+	"summary":     "summary",
+	"description": "description",
+	"type":        "type",
+	"issuetype":   "type",
+	"issue type":  "type",
+	"labels":      "labels",
+	"label":       "labels",
+	"components":  "components",
+	"component":   "components",
+	"parent":      "parent",
+}
+
+// ParseImportCSV reads issues from CSV, the header names the field of each
+// column. Labels and components are separated by commas, empty cells are
+// left out.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the CSV is empty, it needs a header naming the fields")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	rows := make([]ImportRow, 0)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		row := ImportRow{Line: line}
+		if len(record) > len(header) {
+			row.Err = fmt.Errorf("has %d columns but the header only names %d", len(record), len(header))
+			rows = append(rows, row)
+			continue
+		}
+
+		for i, value := range record {
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			err = setImportField(&row.Input, header[i], value)
+			if err != nil {
+				row.Err = err
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ParseImportNDJSON reads one issue per line, either as an object of
+// fields like {"summary": "...", "labels": ["a"]} or as jiwa export writes
+// them. Exported issues only keep the fields that can be set on creation.
+func ParseImportNDJSON(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	// exported issues with long descriptions easily exceed a line of 64KiB
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	rows := make([]ImportRow, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		row := ImportRow{Line: line}
+		row.Input, row.Err = parseImportObject(scanner.Bytes())
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read line %d: %w", line+1, err)
+	}
+
+	return rows, nil
+}
+
+func parseImportObject(b []byte) (jiwa.CreateIssueInput, error) {
+	var input jiwa.CreateIssueInput
+
+	var object map[string]any
+	err := json.Unmarshal(b, &object)
+	if err != nil {
+		return input, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	fields, exported := object["fields"].(map[string]any)
+	if !exported {
+		for name, value := range object {
+			err = setImportField(&input, name, value)
+			if err != nil {
+				return input, err
+			}
+		}
+		return input, nil
+	}
+
+	name := func(v any) any {
+		if m, ok := v.(map[string]any); ok {
+			if key, ok := m["key"]; ok {
+				return key
+			}
+			return m["name"]
+		}
+		return v
+	}
+
+	for field, value := range fields {
+		switch field {
+		case "project", "issuetype", "parent":
+			value = name(value)
+		case "components":
+			list, _ := value.([]any)
+			names := make([]any, 0, len(list))
+			for _, c := range list {
+				names = append(names, name(c))
+			}
+			value = names
+		case "summary", "description", "labels":
+		default:
+			continue
+		}
+
+		err = setImportField(&input, field, value)
+		if err != nil {
+			return input, err
+		}
+	}
+
+	return input, nil
+}
+
+// setImportField sets the field of the input that the column or key name
+// stands for, value is a string from CSV or whatever JSON held
+func setImportField(input *jiwa.CreateIssueInput, name string, value any) error {
+	if value == nil {
+		return nil
+	}
+
+	field, ok := importColumns[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		if input.Fields == nil {
+			input.Fields = make(map[string]any)
+		}
+		input.Fields[strings.TrimSpace(name)] = value
+		return nil
+	}
+
+	if field == "labels" || field == "components" {
+		list, err := importList(name, value)
+		if err != nil {
+			return err
+		}
+		if field == "labels" {
+			input.Labels = append(input.Labels, list...)
+		} else {
+			input.Components = append(input.Components, list...)
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%q needs to be a string", name)
+	}
+	s = strings.TrimSpace(s)
+
+	switch field {
+	case "project":
+		input.Project = strings.ToUpper(s)
+	case "summary":
+		input.Summary = s
+	case "description":
+		input.Description = s
+	case "type":
+		input.Type = s
+	case "parent":
+		input.Parent = strings.ToUpper(s)
+	}
+
+	return nil
+}
+
+// importList takes a comma separated string or a list of strings
+func importList(name string, value any) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%q needs to be a list of strings", name)
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("%q needs to be a list of strings", name)
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list, nil
+}
+
+// validateImport reports the first required field the row is missing
+func validateImport(input jiwa.CreateIssueInput) error {
+	switch {
+	case input.Project == "":
+		return errors.New("project is missing, add a project column or pass --project")
+	case input.Summary == "":
+		return errors.New("summary is missing")
+	case input.Type == "":
+		return errors.New("type is missing, add a type column or pass --type")
+	default:
+		return checkSummaryLength(input.Summary)
+	}
+}
+
+// Import creates an issue for every row on the worker pool, project and
+// issueType fill in rows that don't set them. Rows that can't be parsed or
+// miss a required field aren't sent, a failed row doesn't stop the others.
+// The results are in the order of the rows.
+func (c *Command) Import(rows []ImportRow, project, issueType string) []ImportResult {
+	if project == "" {
+		project = c.Config.DefaultProject
+	}
+
+	results := make([]ImportResult, len(rows))
+	for i, row := range rows {
+		results[i] = ImportResult{Line: row.Line, Err: errors.New("not sent, the import was interrupted")}
+	}
+
+	// the errors are collected in the results, the pool only stops when
+	// the context is cancelled
+	_ = c.parallel(len(rows), func(ctx context.Context, i int) error {
+		row := rows[i]
+		if row.Input.Project == "" {
+			row.Input.Project = strings.ToUpper(project)
+		}
+		if row.Input.Type == "" {
+			row.Input.Type = issueType
+		}
+
+		key, err := c.importRow(ctx, row)
+		results[i] = ImportResult{Line: row.Line, Key: key, Err: err}
+		return nil
+	})
+
+	return results
+}
+
+// ErrInvalidIssue is returned by CreateFromJSON for issues that weren't
+// sent because they can't be created
+var ErrInvalidIssue = errors.New("invalid issue")
+
+// CreateFromJSON creates an issue from an object with the keys import
+// understands, the project defaults to the configured "defaultProject"
+// and the type to Task
+func (c *Command) CreateFromJSON(b []byte) (string, error) {
+	input, err := parseImportObject(b)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidIssue, err)
+	}
+
+	if input.Project == "" {
+		input.Project = c.Config.DefaultProject
+	}
+	input.Project = strings.ToUpper(input.Project)
+	if input.Type == "" {
+		input.Type = "Task"
+	}
+
+	err = validateImport(input)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidIssue, err)
+	}
+
+	key, err := c.importRow(c.ctx(), ImportRow{Input: input})
+	if err != nil {
+		return "", err
+	}
+
+	c.rememberIssue(key, input.Summary)
+	return key, nil
+}
+
+func (c *Command) importRow(ctx context.Context, row ImportRow) (string, error) {
+	if row.Err != nil {
+		return "", row.Err
+	}
+
+	err := validateImport(row.Input)
+	if err != nil {
+		return "", err
+	}
+
+	// hooks can have side effects of their own, a dry run doesn't call them
+	if c.DryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: line %d would create %s in %s: %s\n", row.Line, row.Input.Type, row.Input.Project, row.Input.Summary)
+		return "", nil
+	}
+
+	payload := hooks.Payload{
+		Project:     row.Input.Project,
+		Summary:     row.Input.Summary,
+		Description: row.Input.Description,
+		Type:        row.Input.Type,
+		Components:  row.Input.Components,
+		Labels:      row.Input.Labels,
+		Parent:      row.Input.Parent,
+	}
+	if len(row.Input.Components) != 0 {
+		payload.Component = row.Input.Components[0]
+	}
+	err = c.runPreHook("pre-create", payload)
+	if err != nil {
+		return "", fmt.Errorf("aborting create: %w", err)
+	}
+
+	issue, err := c.Client.CreateIssue(ctx, row.Input)
+	if err != nil {
+		return "", err
+	}
+
+	payload.Key = issue.Key
+	c.runPostHook("post-create", payload)
+
+	return issue.Key, nil
+}
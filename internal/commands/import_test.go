@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImport(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InFormat  string
+		InData    string
+		OutRows   []ImportRow
+		OutErrMsg string
+	}{
+		{
+			Name:     "CSV",
+			InFormat: "csv",
+			InData: "Summary,Issue Type,labels,customfield_10010\n" +
+				"First,Bug,\"ops, urgent\",\n" +
+				"\"Second\nline\",Task,,42\n",
+			OutRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First", Type: "Bug", Labels: []string{"ops", "urgent"}}},
+				{Line: 3, Input: jiwa.CreateIssueInput{Summary: "Second\nline", Type: "Task", Fields: map[string]any{"customfield_10010": "42"}}},
+			},
+		},
+		{
+			Name:     "CSVTooManyColumns",
+			InFormat: "csv",
+			InData:   "summary\nFirst,extra\n",
+			OutRows: []ImportRow{
+				{Line: 2, Err: errors.New("has 2 columns but the header only names 1")},
+			},
+		},
+		{
+			Name:      "CSVEmpty",
+			InFormat:  "csv",
+			OutErrMsg: "the CSV is empty, it needs a header naming the fields",
+		},
+		{
+			Name:     "NDJSON",
+			InFormat: "ndjson",
+			InData: `{"project": "ops", "summary": "First", "type": "Bug", "components": ["api"], "parent": "ops-1"}` + "\n\n" +
+				`{"summary": 3}` + "\n",
+			OutRows: []ImportRow{
+				{Line: 1, Input: jiwa.CreateIssueInput{Project: "OPS", Summary: "First", Type: "Bug", Components: []string{"api"}, Parent: "OPS-1"}},
+				{Line: 3, Err: errors.New(`"summary" needs to be a string`)},
+			},
+		},
+		{
+			Name:     "NDJSONFromExport",
+			InFormat: "ndjson",
+			InData:   `{"key": "JIWA-1", "fields": {"project": {"key": "JIWA"}, "summary": "First", "issuetype": {"name": "Story"}, "components": [{"name": "api"}], "status": {"name": "Done"}}}` + "\n",
+			OutRows: []ImportRow{
+				{Line: 1, Input: jiwa.CreateIssueInput{Project: "JIWA", Summary: "First", Type: "Story", Components: []string{"api"}}},
+			},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			parse := ParseImportNDJSON
+			if td.InFormat == "csv" {
+				parse = ParseImportCSV
+			}
+
+			rows, err := parse(strings.NewReader(td.InData))
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutRows, rows)
+		})
+	}
+}
+
+func TestCommand_Import(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InRows     []ImportRow
+		InProject  string
+		InType     string
+		InDryRun   bool
+		InHooks    map[string]string
+		OutKeys    []string
+		OutErrs    []string
+		OutCreated int
+	}{
+		{
+			Name: "DefaultsFillInRows",
+			InRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First"}},
+				{Line: 3, Input: jiwa.CreateIssueInput{Project: "OPS", Summary: "Second", Type: "Bug"}},
+			},
+			InType:     "Task",
+			OutKeys:    []string{"JIWA-1", "OPS-1"},
+			OutErrs:    []string{"", ""},
+			OutCreated: 2,
+		},
+		{
+			Name: "FailedRowsDontStopTheOthers",
+			InRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First"}},
+				{Line: 3, Err: errors.New("invalid JSON")},
+				{Line: 4, Input: jiwa.CreateIssueInput{Summary: "Third", Type: "Bug"}},
+				{Line: 5, Input: jiwa.CreateIssueInput{Type: "Bug"}},
+			},
+			InProject:  "ops",
+			OutKeys:    []string{"", "", "OPS-1", ""},
+			OutErrs:    []string{"type is missing, add a type column or pass --type", "invalid JSON", "", "summary is missing"},
+			OutCreated: 1,
+		},
+		{
+			Name: "DryRun",
+			InRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First", Type: "Task"}},
+				{Line: 3, Input: jiwa.CreateIssueInput{Type: "Task"}},
+			},
+			InDryRun: true,
+			OutKeys:  []string{"", ""},
+			OutErrs:  []string{"", "summary is missing"},
+		},
+		{
+			Name: "DryRunSkipsHooks",
+			InRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First", Type: "Task"}},
+			},
+			InDryRun: true,
+			InHooks:  map[string]string{"pre-create": "echo no >&2; exit 1"},
+			OutKeys:  []string{""},
+			OutErrs:  []string{""},
+		},
+		{
+			Name: "PreCreateHookAborts",
+			InRows: []ImportRow{
+				{Line: 2, Input: jiwa.CreateIssueInput{Summary: "First", Type: "Task"}},
+			},
+			InHooks: map[string]string{"pre-create": "echo no >&2; exit 1"},
+			OutKeys: []string{""},
+			OutErrs: []string{"aborting create: hook pre-create failed: exit status 1: no"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			c := Command{Client: fake, DryRun: td.InDryRun, Config: Config{DefaultProject: "JIWA", Concurrency: 2}}
+			c.Hooks.Hooks = td.InHooks
+
+			results := c.Import(td.InRows, td.InProject, td.InType)
+
+			keys := make([]string, 0, len(results))
+			errs := make([]string, 0, len(results))
+			for i, r := range results {
+				assert.Equal(t, td.InRows[i].Line, r.Line)
+				keys = append(keys, r.Key)
+				errMsg := ""
+				if r.Err != nil {
+					errMsg = r.Err.Error()
+				}
+				errs = append(errs, errMsg)
+			}
+			assert.Equal(t, td.OutKeys, keys)
+			assert.Equal(t, td.OutErrs, errs)
+			assert.Len(t, fake.Issues, td.OutCreated)
+		})
+	}
+}
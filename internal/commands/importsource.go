@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/forge"
+	"github.com/catouc/jiwa/internal/wiki"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// ImportMapping remembers which issue of the source became which Jira
+// issue, a second run of the import skips the issues in it
+type ImportMapping struct {
+	Source string `json:"source"`
+	// Issues maps the number of the issue at the source to its key
+	Issues map[string]string `json:"issues"`
+}
+
+// LoadImportMapping reads the mapping at path, a missing file is an empty
+// mapping. A mapping of another source is refused so two imports don't
+// end up in the same file.
+func LoadImportMapping(path, source string) (ImportMapping, error) {
+	m := ImportMapping{Source: source, Issues: make(map[string]string)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read the mapping: %w", err)
+	}
+
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return m, fmt.Errorf("failed to read the mapping %s: %w", path, err)
+	}
+	if m.Source != source {
+		return m, fmt.Errorf("%s is the mapping of %s, not of %s, pass another --mapping", path, m.Source, source)
+	}
+	if m.Issues == nil {
+		m.Issues = make(map[string]string)
+	}
+
+	return m, nil
+}
+
+// Save writes the mapping to a file next to path and moves it into place,
+// an interrupted write leaves the last mapping as it was
+func (m ImportMapping) Save(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the mapping: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write the mapping: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type SourceImportInput struct {
+	Project string
+	// Type defaults to Task
+	Type string
+	// CloseClosed moves the issues that are closed at the source to a
+	// status of the done category, otherwise they stay in To Do
+	CloseClosed bool
+	// MappingPath is where the mapping is kept, it is written after every
+	// issue so an interrupted import picks up where it stopped
+	MappingPath string
+}
+
+// SourceImportResult is what happened to an issue of the source. Skipped
+// issues were imported by an earlier run, Key is set for them too. An issue
+// that was created but couldn't be closed has both Key and Err.
+type SourceImportResult struct {
+	Number  int
+	Key     string
+	Skipped bool
+	Err     error
+}
+
+// ImportSource creates a Jira issue for every issue of the source, oldest
+// first so the keys keep their order. fn is called with the result of each.
+// Failing to create an issue doesn't stop the others, failing to read the
+// source or to save the mapping does.
+func (c *Command) ImportSource(src forge.Source, input SourceImportInput, fn func(SourceImportResult)) error {
+	if input.Project == "" {
+		input.Project = c.Config.DefaultProject
+	}
+	if input.Type == "" {
+		input.Type = "Task"
+	}
+
+	mapping, err := LoadImportMapping(input.MappingPath, src.String())
+	if err != nil {
+		return err
+	}
+
+	return src.Issues(c.ctx(), func(page []forge.Issue) error {
+		for _, issue := range page {
+			number := strconv.Itoa(issue.Number)
+			if key, ok := mapping.Issues[number]; ok {
+				fn(SourceImportResult{Number: issue.Number, Key: key, Skipped: true})
+				continue
+			}
+
+			row := ImportRow{Line: issue.Number, Input: c.sourceIssueInput(issue, input)}
+			if c.DryRun {
+				err := validateImport(row.Input)
+				if err == nil {
+					fmt.Fprintf(os.Stderr, "dry-run: #%d would create %s in %s: %s\n", issue.Number, row.Input.Type, row.Input.Project, row.Input.Summary)
+				}
+				fn(SourceImportResult{Number: issue.Number, Err: err})
+				continue
+			}
+
+			key, err := c.importRow(c.ctx(), row)
+			if err != nil {
+				fn(SourceImportResult{Number: issue.Number, Err: err})
+				continue
+			}
+
+			mapping.Issues[number] = key
+			err = mapping.Save(input.MappingPath)
+			if err != nil {
+				return fmt.Errorf("created %s for #%d but %w", key, issue.Number, err)
+			}
+
+			result := SourceImportResult{Number: issue.Number, Key: key}
+			if issue.Closed && input.CloseClosed {
+				_, err = c.Close([]string{key}, nil, "")
+				if err != nil {
+					result.Err = fmt.Errorf("failed to close it: %w", err)
+				}
+			}
+			fn(result)
+		}
+
+		return nil
+	})
+}
+
+// sourceIssueInput maps the issue to what Jira takes, the description links
+// back to where the issue came from
+func (c *Command) sourceIssueInput(issue forge.Issue, input SourceImportInput) jiwa.CreateIssueInput {
+	description := strings.TrimSpace(issue.Body)
+	if c.Config.APIVersion != "3" {
+		description = wiki.FromMarkdown(description)
+	}
+	if issue.URL != "" {
+		if description != "" {
+			description += "\n\n"
+		}
+		description += "Imported from " + issue.URL
+	}
+
+	// labels can't have spaces, "good first issue" becomes
+	// "good-first-issue"
+	labels := make([]string, 0, len(issue.Labels))
+	for _, l := range issue.Labels {
+		if l = strings.Join(strings.Fields(l), "-"); l != "" {
+			labels = append(labels, l)
+		}
+	}
+
+	return jiwa.CreateIssueInput{
+		Project:     strings.ToUpper(input.Project),
+		Summary:     strings.TrimSpace(issue.Title),
+		Description: description,
+		Type:        input.Type,
+		Labels:      labels,
+	}
+}
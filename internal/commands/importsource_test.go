@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/catouc/jiwa/internal/forge"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSource struct {
+	pages [][]forge.Issue
+}
+
+func (s fakeSource) String() string {
+	return "github:catouc/jiwa"
+}
+
+func (s fakeSource) Issues(_ context.Context, fn func(page []forge.Issue) error) error {
+	for _, p := range s.pages {
+		err := fn(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestCommand_ImportSource(t *testing.T) {
+	src := fakeSource{pages: [][]forge.Issue{
+		{
+			{Number: 1, Title: "Crash on start", Body: "**Steps**\n\n1. start it", Labels: []string{"bug", "good first issue"}, URL: "https://github.com/catouc/jiwa/issues/1"},
+			{Number: 2, Title: "Old idea", Closed: true, URL: "https://github.com/catouc/jiwa/issues/2"},
+		},
+		{
+			{Number: 4, Title: "", URL: "https://github.com/catouc/jiwa/issues/4"},
+		},
+	}}
+
+	testData := []struct {
+		Name          string
+		InMapping     *ImportMapping
+		InClose       bool
+		InDryRun      bool
+		OutResults    []SourceImportResult
+		OutMapping    map[string]string
+		OutStatus     map[string]string
+		OutLabels     []string
+		OutDesc       string
+		OutErrMsg     string
+		OutResultErrs map[int]string
+	}{
+		{
+			Name: "Import",
+			OutResults: []SourceImportResult{
+				{Number: 1, Key: "JIWA-1"},
+				{Number: 2, Key: "JIWA-2"},
+				{Number: 4},
+			},
+			OutResultErrs: map[int]string{4: "summary is missing"},
+			OutMapping:    map[string]string{"1": "JIWA-1", "2": "JIWA-2"},
+			OutStatus:     map[string]string{"JIWA-1": "To Do", "JIWA-2": "To Do"},
+			OutLabels:     []string{"bug", "good-first-issue"},
+			OutDesc:       "*Steps*\n\n# start it\n\nImported from https://github.com/catouc/jiwa/issues/1",
+		},
+		{
+			Name:    "CloseClosed",
+			InClose: true,
+			OutResults: []SourceImportResult{
+				{Number: 1, Key: "JIWA-1"},
+				{Number: 2, Key: "JIWA-2"},
+				{Number: 4},
+			},
+			OutResultErrs: map[int]string{4: "summary is missing"},
+			OutMapping:    map[string]string{"1": "JIWA-1", "2": "JIWA-2"},
+			OutStatus:     map[string]string{"JIWA-1": "To Do", "JIWA-2": "Done"},
+		},
+		{
+			Name:      "SkipsImported",
+			InMapping: &ImportMapping{Source: "github:catouc/jiwa", Issues: map[string]string{"1": "JIWA-7"}},
+			OutResults: []SourceImportResult{
+				{Number: 1, Key: "JIWA-7", Skipped: true},
+				{Number: 2, Key: "JIWA-1"},
+				{Number: 4},
+			},
+			OutResultErrs: map[int]string{4: "summary is missing"},
+			OutMapping:    map[string]string{"1": "JIWA-7", "2": "JIWA-1"},
+			OutStatus:     map[string]string{"JIWA-1": "To Do"},
+		},
+		{
+			Name:     "DryRun",
+			InDryRun: true,
+			OutResults: []SourceImportResult{
+				{Number: 1},
+				{Number: 2},
+				{Number: 4},
+			},
+			OutResultErrs: map[int]string{4: "summary is missing"},
+			OutStatus:     map[string]string{},
+		},
+		{
+			Name:      "MappingOfAnotherSource",
+			InMapping: &ImportMapping{Source: "gitlab:catouc/jiwa", Issues: map[string]string{"1": "JIWA-7"}},
+			OutErrMsg: "is the mapping of gitlab:catouc/jiwa, not of github:catouc/jiwa, pass another --mapping",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "mapping.json")
+			if td.InMapping != nil {
+				err := td.InMapping.Save(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := jiwafake.New()
+			c := Command{Client: fake, DryRun: td.InDryRun, Config: Config{DefaultProject: "jiwa"}}
+
+			results := make([]SourceImportResult, 0)
+			errs := make(map[int]string)
+			err := c.ImportSource(src, SourceImportInput{CloseClosed: td.InClose, MappingPath: path}, func(r SourceImportResult) {
+				if r.Err != nil {
+					errs[r.Number] = r.Err.Error()
+					r.Err = nil
+				}
+				results = append(results, r)
+			})
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutResults, results)
+			assert.Equal(t, td.OutResultErrs, errs)
+
+			status := make(map[string]string)
+			for key, issue := range fake.Issues {
+				status[key] = issue.Fields.Status.Name
+				assert.Equal(t, "Task", issue.Fields.Type.Name)
+			}
+			assert.Equal(t, td.OutStatus, status)
+			if td.OutLabels != nil {
+				assert.Equal(t, td.OutLabels, fake.Issues["JIWA-1"].Fields.Labels)
+			}
+			if td.OutDesc != "" {
+				assert.Equal(t, td.OutDesc, fake.Issues["JIWA-1"].Fields.Description)
+			}
+
+			if td.OutMapping == nil {
+				_, err = os.Stat(path)
+				assert.Equal(t, td.InMapping == nil, os.IsNotExist(err))
+				return
+			}
+			b, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			var m ImportMapping
+			assert.NoError(t, json.Unmarshal(b, &m))
+			assert.Equal(t, ImportMapping{Source: "github:catouc/jiwa", Issues: td.OutMapping}, m)
+		})
+	}
+}
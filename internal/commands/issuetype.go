@@ -1,13 +1,11 @@
 package commands
 
 import (
-	"context"
-
 	"github.com/andygrunwald/go-jira"
 )
 
 func (c *Command) IssueTypes(projectKey string) ([]jira.IssueType, error) {
-	project, err := c.Client.GetProject(context.TODO(), projectKey)
+	project, err := c.Client.GetProject(c.ctx(), projectKey)
 	if err != nil {
 		return nil, err
 	}
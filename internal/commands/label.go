@@ -1,15 +1,43 @@
 package commands
 
 import (
-	"context"
+	"errors"
+
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Label(issues, labels []string) ([]string, error) {
+// Label adds labels to the issues, or removes them if remove is set, the
+// other labels an issue has are kept.
+func (c *Command) Label(issues, labels []string, remove bool) ([]string, error) {
+	if len(labels) == 0 {
+		return nil, errors.New("need to supply at least one label")
+	}
+
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	input := jiwa.UpdateIssueInput{AddLabels: labels}
+	if remove {
+		input = jiwa.UpdateIssueInput{RemoveLabels: labels}
+	}
+
 	for _, issue := range issues {
-		err := c.Client.LabelIssue(context.TODO(), issue, labels...)
+		payload := hooks.Payload{Key: issue, Labels: labels}
+		err := c.runPreHook("pre-label", payload)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.Client.UpdateIssue(c.ctx(), issue, input)
 		if err != nil {
 			return nil, err
 		}
+
+		c.runPostHook("post-label", payload)
+		c.remember(payload.Key)
 	}
 
 	return issues, nil
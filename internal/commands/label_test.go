@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Label(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InLabels  []string
+		InRemove  bool
+		OutBody   string
+		OutLabels []string
+	}{
+		{
+			Name:      "Add",
+			InLabels:  []string{"on-call", "urgent"},
+			OutBody:   `{"update":{"labels":[{"add":"on-call"},{"add":"urgent"}]}}`,
+			OutLabels: []string{"from-web-ui", "on-call", "urgent"},
+		},
+		{
+			Name:      "Remove",
+			InLabels:  []string{"from-web-ui"},
+			InRemove:  true,
+			OutBody:   `{"update":{"labels":[{"remove":"from-web-ui"}]}}`,
+			OutLabels: []string{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Labels: []string{"from-web-ui"}}})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+
+			c := Command{Client: client, Config: Config{BaseURL: srv.URL}}
+			_, err = c.Label([]string{"JIWA-1"}, td.InLabels, td.InRemove)
+			assert.NoError(t, err)
+
+			// the permissions are checked first
+			reqs := srv.Requests()
+			assert.JSONEq(t, td.OutBody, string(reqs[len(reqs)-1].Body))
+
+			issue, _ := srv.Issue("JIWA-1")
+			assert.ElementsMatch(t, td.OutLabels, issue.Fields.Labels)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// LinkSpec is a resolved link from some issue to Target, read as
+// "<issue> <Description> <Target>".
+type LinkSpec struct {
+	Type        string
+	Description string
+	Target      string
+	// Inward is set when Description is the inward one of the link type,
+	// i.e. for "is blocked by" the target is the blocking issue.
+	Inward bool
+}
+
+// ResolveLinkSpecs turns specs like "blocks:PROJ-2" or "is blocked by:PROJ-2"
+// into links by matching them against the link types of the instance.
+// This is done before anything is changed so typos don't leave half
+// finished work behind.
+func (c *Command) ResolveLinkSpecs(specs []string) ([]LinkSpec, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	types, err := c.Client.ListIssueLinkTypes(c.ctx())
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]LinkSpec, 0, len(specs))
+	for _, spec := range specs {
+		l, err := resolveLinkSpec(spec, types)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := c.ParseIssueArg(l.Target)
+		if err != nil {
+			return nil, err
+		}
+		l.Target = target
+
+		links = append(links, l)
+	}
+
+	return links, nil
+}
+
+func resolveLinkSpec(spec string, types []jira.IssueLinkType) (LinkSpec, error) {
+	relation, target, found := strings.Cut(spec, ":")
+	relation = strings.TrimSpace(relation)
+	target = strings.TrimSpace(target)
+	if !found || relation == "" || target == "" {
+		return LinkSpec{}, fmt.Errorf("link %q needs to look like <relation>:<issue-id>, e.g. blocks:PROJ-2", spec)
+	}
+
+	valid := make([]string, 0, len(types)*2)
+	for _, t := range types {
+		switch {
+		case strings.EqualFold(relation, t.Outward), strings.EqualFold(relation, t.Name):
+			return LinkSpec{Type: t.Name, Description: t.Outward, Target: target}, nil
+		case strings.EqualFold(relation, t.Inward):
+			return LinkSpec{Type: t.Name, Description: t.Inward, Target: target, Inward: true}, nil
+		}
+		valid = append(valid, t.Outward, t.Inward)
+	}
+
+	return LinkSpec{}, fmt.Errorf("unknown link relation %q, valid relations are: %s", relation, strings.Join(valid, ","))
+}
+
+// Link adds all links to the issue, a failing link doesn't stop the
+// remaining ones from being added.
+func (c *Command) Link(key string, links []LinkSpec) error {
+	var errs []error
+	for _, l := range links {
+		inward, outward := key, l.Target
+		if l.Inward {
+			inward, outward = l.Target, key
+		}
+
+		err := c.Client.LinkIssues(c.ctx(), l.Type, inward, outward)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s %s: %w", key, l.Description, l.Target, err))
+		}
+	}
+
+	c.remember(key)
+
+	return errors.Join(errs...)
+}
+
+// IssueLink is a link as seen from the issue it was listed for, read as
+// "<issue> <Description> <Key>"
+type IssueLink struct {
+	// ID is what removing the link takes
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Key         string `json:"key"`
+	Summary     string `json:"summary"`
+	Status      string `json:"status"`
+}
+
+// Links returns the inward and outward links of the issue
+func (c *Command) Links(key string) ([]IssueLink, error) {
+	links, err := c.Client.ListIssueLinks(c.ctx(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.remember(key)
+
+	return issueLinks(links), nil
+}
+
+// issueLinks turns the links of an issue into how they read from it
+func issueLinks(links []*jira.IssueLink) []IssueLink {
+	result := make([]IssueLink, 0, len(links))
+	for _, l := range links {
+		if l == nil {
+			continue
+		}
+
+		// the issue is the inward one when the other one is outward
+		other, description := l.OutwardIssue, l.Type.Outward
+		if other == nil {
+			other, description = l.InwardIssue, l.Type.Inward
+		}
+		if other == nil {
+			continue
+		}
+
+		view := IssueLink{ID: l.ID, Type: l.Type.Name, Description: description, Key: other.Key}
+		if other.Fields != nil {
+			view.Summary = other.Fields.Summary
+			if other.Fields.Status != nil {
+				view.Status = other.Fields.Status.Name
+			}
+		}
+		result = append(result, view)
+	}
+
+	return result
+}
+
+// RemoveLinks removes the links by their IDs, as Links lists them. A
+// failing removal doesn't stop the remaining ones.
+func (c *Command) RemoveLinks(ids []string) error {
+	var errs []error
+	for _, id := range ids {
+		err := c.Client.DeleteIssueLink(c.ctx(), id)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Unlink removes the links of the issue that match the specs, the way Link
+// would have added them. A spec that matches no link is an error, the
+// others are still removed.
+func (c *Command) Unlink(key string, specs []LinkSpec) error {
+	links, err := c.Links(key)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range specs {
+		found := false
+		for _, l := range links {
+			if l.Type != s.Type || l.Key != s.Target || !strings.EqualFold(l.Description, s.Description) {
+				continue
+			}
+			found = true
+
+			err := c.Client.DeleteIssueLink(c.ctx(), l.ID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s %s: %w", key, s.Description, s.Target, err))
+			}
+		}
+
+		if !found {
+			errs = append(errs, fmt.Errorf("%s doesn't have a link %q to %s, \"jiwa links %s\" lists them", key, s.Description, s.Target, key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveLinkSpec(t *testing.T) {
+	types := []jira.IssueLinkType{
+		{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+		{Name: "Relates", Inward: "relates to", Outward: "relates to"},
+	}
+
+	testData := []struct {
+		Name      string
+		InSpec    string
+		OutLink   LinkSpec
+		OutErrMsg string
+	}{
+		{
+			Name:    "OutwardDescription",
+			InSpec:  "blocks:PROJ-2",
+			OutLink: LinkSpec{Type: "Blocks", Description: "blocks", Target: "PROJ-2"},
+		},
+		{
+			Name:    "InwardDescription",
+			InSpec:  "is blocked by:PROJ-2",
+			OutLink: LinkSpec{Type: "Blocks", Description: "is blocked by", Target: "PROJ-2", Inward: true},
+		},
+		{
+			Name:    "TypeNameCaseInsensitive",
+			InSpec:  "RELATES: PROJ-2",
+			OutLink: LinkSpec{Type: "Relates", Description: "relates to", Target: "PROJ-2"},
+		},
+		{
+			Name:      "MissingTarget",
+			InSpec:    "blocks",
+			OutErrMsg: `link "blocks" needs to look like <relation>:<issue-id>, e.g. blocks:PROJ-2`,
+		},
+		{
+			Name:      "UnknownRelation",
+			InSpec:    "clones:PROJ-2",
+			OutErrMsg: `unknown link relation "clones", valid relations are: blocks,is blocked by,relates to,relates to`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			link, err := resolveLinkSpec(td.InSpec, types)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutLink, link)
+		})
+	}
+}
+
+func TestCommand_Unlink(t *testing.T) {
+	blocks := jira.IssueLinkType{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"}
+	relates := jira.IssueLinkType{Name: "Relates", Inward: "relates to", Outward: "relates to"}
+
+	testData := []struct {
+		Name      string
+		InSpecs   []LinkSpec
+		OutLinks  []IssueLink
+		OutErrMsg string
+	}{
+		{
+			Name:     "Outward",
+			InSpecs:  []LinkSpec{{Type: "Blocks", Description: "blocks", Target: "JIWA-2"}},
+			OutLinks: []IssueLink{{ID: "10002", Type: "Relates", Description: "relates to", Key: "JIWA-3"}},
+		},
+		{
+			Name:     "SymmetricFromTheOtherSide",
+			InSpecs:  []LinkSpec{{Type: "Relates", Description: "relates to", Target: "JIWA-3"}},
+			OutLinks: []IssueLink{{ID: "10001", Type: "Blocks", Description: "blocks", Key: "JIWA-2"}},
+		},
+		{
+			Name:      "WrongDirection",
+			InSpecs:   []LinkSpec{{Type: "Blocks", Description: "is blocked by", Target: "JIWA-2", Inward: true}},
+			OutLinks:  []IssueLink{{ID: "10001", Type: "Blocks", Description: "blocks", Key: "JIWA-2"}, {ID: "10002", Type: "Relates", Description: "relates to", Key: "JIWA-3"}},
+			OutErrMsg: `JIWA-1 doesn't have a link "is blocked by" to JIWA-2, "jiwa links JIWA-1" lists them`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.LinkTypes = []jira.IssueLinkType{blocks, relates}
+			for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+				fake.Issues[key] = jira.Issue{Key: key, Fields: &jira.IssueFields{}}
+			}
+			assert.NoError(t, fake.LinkIssues(context.Background(), "Blocks", "JIWA-1", "JIWA-2"))
+			assert.NoError(t, fake.LinkIssues(context.Background(), "Relates", "JIWA-3", "JIWA-1"))
+			c := Command{Client: fake}
+
+			err := c.Unlink("JIWA-1", td.InSpecs)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			links, err := c.Links("JIWA-1")
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutLinks, links)
+		})
+	}
+}
@@ -2,45 +2,356 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/andygrunwald/go-jira"
 )
 
 type ListInput struct {
 	Assignee string
-	Project  string
-	Status   string
-	Labels   []string
+	// Project is a comma separated list of project keys and "@name"
+	// references to groups from "projectGroups", see ListProjects
+	Project string
+	Status  string
+	Labels  []string
+	// AllProjects drops the project clause so issues from every project
+	// the user can see are listed
+	AllProjects bool
+	// NoDefaultProject makes a missing Project an error instead of
+	// falling back to the configured "defaultProject"
+	NoDefaultProject bool
+	// JQL is added to the generated query as an extra condition
+	JQL string
+	// Filter is the name of a saved filter from "filters", the other
+	// fields narrow it down. Without Project it isn't scoped to the
+	// "defaultProject", a filter names its projects itself.
+	Filter string
+	// MineAndWatching lists the issues assigned to or watched by the
+	// current user
+	MineAndWatching bool
+
+	// Reporter and CommentedBy take a user like Assignee, commenters can
+	// only be searched for with ScriptRunner's issueFunction
+	Reporter    string
+	CommentedBy string
+	// UpdatedByMe lists the issues the current user changed, on Server and
+	// Data Center that is approximated by changes of status and assignee
+	UpdatedByMe bool
+
+	// UpdatedSince, CreatedSince and DueBefore narrow the list down by
+	// day, zero ones are left out. Jira compares them in the timezone of
+	// the user's profile.
+	UpdatedSince time.Time
+	CreatedSince time.Time
+	DueBefore    time.Time
+
+	// Limit is the most issues ListPages fetches, 0 fetches all of them
+	// up to the configured "listCap"
+	Limit int
+	// Fields are the fields ListPages fetches of every issue, all of them
+	// if empty
+	Fields []string
 }
 
-func (c *Command) List(input ListInput) ([]jira.Issue, error) {
-	var user string
-	switch input.Assignee {
-	case "empty":
-		user = "AND assignee is EMPTY"
-	case "":
-		user = ""
-	default:
-		user = "AND assignee= \"" + input.Assignee + "\""
-	}
+// defaultListCap bounds list without a limit, so a query that matches the
+// whole instance doesn't page through it
+const defaultListCap = 1000
 
-	var labelsString string
-	if len(input.Labels) != 0 {
-		labelsString = "AND labels in (" + strings.Join(input.Labels, ",") + ")"
+// ErrListCapped is returned by ListPages after it handed over the first
+// "listCap" issues of a larger result
+var ErrListCapped = errors.New("the list was cut short")
+
+// errListLimit stops SearchPages once enough issues were handed over
+var errListLimit = errors.New("list limit reached")
+
+// ListCap is the most issues a list without a limit fetches, "listCap"
+// from the config or 1000
+func (c *Command) ListCap() int {
+	if c.Config.ListCap > 0 {
+		return c.Config.ListCap
 	}
 
-	project := c.Config.DefaultProject
-	if input.Project != "" {
-		project = input.Project
+	return defaultListCap
+}
+
+func (c *Command) List(input ListInput) ([]jira.Issue, error) {
+	jql, err := c.listJQL(input)
+	if err != nil {
+		return nil, err
 	}
 
-	jql := fmt.Sprintf("project=%s AND status=\"%s\" %s %s", project, input.Status, user, labelsString)
-	issues, err := c.Client.Search(context.TODO(), jql)
+	issues, err := c.Client.Search(c.ctx(), jql)
 	if err != nil {
 		return nil, fmt.Errorf("could not list issues: %w", err)
 	}
 
 	return issues, nil
 }
+
+// ListPages is List for large results, fn gets every page of issues as
+// soon as it arrives. It stops after input.Limit issues, without a limit
+// it stops at the cap and returns ErrListCapped if there were more.
+func (c *Command) ListPages(ctx context.Context, input ListInput, fn func(page []jira.Issue) error) error {
+	if input.Limit < 0 {
+		return errors.New("--limit can't be negative, use 0 to list everything")
+	}
+
+	jql, err := c.listJQL(input)
+	if err != nil {
+		return err
+	}
+
+	limit, capped := input.Limit, false
+	if limit == 0 {
+		limit, capped = c.ListCap(), true
+	}
+
+	handed, truncated := 0, false
+	err = c.Client.SearchPages(ctx, jql, func(page []jira.Issue) error {
+		// a page after the cap was reached means it cut the result short,
+		// an explicit limit doesn't wait for it
+		if handed == limit {
+			truncated = true
+			return errListLimit
+		}
+		if handed+len(page) > limit {
+			page = page[:limit-handed]
+			truncated = true
+		}
+		handed += len(page)
+
+		err := fn(page)
+		if err != nil {
+			return err
+		}
+		if truncated || (handed == limit && !capped) {
+			return errListLimit
+		}
+		return nil
+	}, fieldsOption(input.Fields)...)
+	if err != nil && !errors.Is(err, errListLimit) {
+		return fmt.Errorf("could not list issues: %w", err)
+	}
+
+	if capped && truncated {
+		return fmt.Errorf("%w after %d issues, pass --limit for more or raise \"listCap\" in the config", ErrListCapped, limit)
+	}
+
+	return nil
+}
+
+// ListCount returns how many issues List would return
+func (c *Command) ListCount(input ListInput) (int, error) {
+	jql, err := c.listJQL(input)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := c.Client.Count(c.ctx(), jql)
+	if err != nil {
+		return 0, fmt.Errorf("could not count issues: %w", err)
+	}
+
+	return n, nil
+}
+
+func (c *Command) listJQL(input ListInput) (string, error) {
+	if input.AllProjects && input.Project != "" {
+		return "", errors.New("--project and --all-projects cannot be used together")
+	}
+
+	defaulted := !input.AllProjects && input.Project == "" && input.Filter == ""
+	if defaulted && input.NoDefaultProject {
+		return "", errors.New("no project given and --no-default-project is set, pass --project or --all-projects")
+	}
+
+	clauses := make([]string, 0, 5)
+	if !input.AllProjects && (input.Project != "" || input.Filter == "") {
+		projects, err := c.ListProjects(input.Project)
+		if err != nil {
+			return "", err
+		}
+
+		switch len(projects) {
+		case 0:
+			return "", errors.New("no project given, set --project, \"defaultProject\" or use --all-projects")
+		case 1:
+			clauses = append(clauses, "project="+projects[0])
+		default:
+			clauses = append(clauses, "project IN ("+strings.Join(projects, ",")+")")
+		}
+	}
+
+	if input.Status != "" {
+		clauses = append(clauses, "status="+jqlQuote(input.Status))
+	}
+
+	if input.Assignee != "" {
+		clauses = append(clauses, userClause("assignee", input.Assignee))
+	}
+
+	if input.Reporter != "" {
+		clauses = append(clauses, userClause("reporter", input.Reporter))
+	}
+
+	if input.CommentedBy != "" {
+		user := input.CommentedBy
+		if user == "@me" {
+			user = "currentUser()"
+		}
+		clauses = append(clauses, "issueFunction in commented("+jqlQuote("by "+user)+")")
+	}
+
+	if input.MineAndWatching {
+		clauses = append(clauses, "(assignee=currentUser() OR watcher=currentUser())")
+	}
+
+	if input.UpdatedByMe {
+		info, err := c.Client.ServerInfo(c.ctx())
+		if err != nil {
+			return "", fmt.Errorf("could not tell how to search for your updates: %w", err)
+		}
+
+		if info.IsCloud() {
+			clauses = append(clauses, "issue in updatedBy(currentUser())")
+		} else {
+			clauses = append(clauses, "(status changed by currentUser() OR assignee changed by currentUser())")
+		}
+	}
+
+	if !input.UpdatedSince.IsZero() {
+		clauses = append(clauses, "updated >= "+jqlDate(input.UpdatedSince))
+	}
+
+	if !input.CreatedSince.IsZero() {
+		clauses = append(clauses, "created >= "+jqlDate(input.CreatedSince))
+	}
+
+	if !input.DueBefore.IsZero() {
+		clauses = append(clauses, "duedate < "+jqlDate(input.DueBefore))
+	}
+
+	if len(input.Labels) != 0 {
+		clauses = append(clauses, "labels in ("+strings.Join(input.Labels, ",")+")")
+	}
+
+	if input.JQL != "" {
+		clauses = append(clauses, "("+input.JQL+")")
+	}
+
+	order := "updated DESC, key DESC"
+	if input.Filter != "" {
+		filter, err := c.SavedFilter(input.Filter)
+		if err != nil {
+			return "", err
+		}
+
+		condition, filterOrder := splitOrderBy(filter)
+		if condition != "" {
+			clauses = append(clauses, "("+condition+")")
+		}
+		if filterOrder != "" {
+			order = filterOrder
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "", errors.New("refusing to list every issue in every project, add a filter like --status or --user")
+	}
+
+	// issues from different projects would otherwise come back grouped
+	// by project, the key keeps the order stable for equal timestamps
+	jql := strings.Join(clauses, " AND ") + " ORDER BY " + order
+
+	// being scoped to a project nobody asked for is surprising, say so
+	if defaulted {
+		fmt.Fprintf(os.Stderr, "listing %s from \"defaultProject\", pass --project or --all-projects for others\njql: %s\n", c.Config.DefaultProject, jql)
+	}
+
+	return jql, nil
+}
+
+// userClause matches the user field against "empty", "@me" or a user name
+func userClause(field, user string) string {
+	switch {
+	case user == "empty":
+		return field + " is EMPTY"
+	case IsMe(user):
+		return field + "=currentUser()"
+	default:
+		return field + "=" + jqlQuote(user)
+	}
+}
+
+// IsMe reports whether a user flag means whoever runs jiwa, "@me" or just
+// "me"
+func IsMe(user string) bool {
+	return user == "@me" || user == "me"
+}
+
+// ListProjects resolves the --project value of list into project keys,
+// falling back to "defaultProject". The value can be a comma separated list
+// like "INFRA,SRE" and "@name" expands to the keys of a group in
+// "projectGroups". Duplicates are dropped, the order is kept.
+func (c *Command) ListProjects(project string) ([]string, error) {
+	if project == "" {
+		project = c.Config.DefaultProject
+	}
+
+	projects := make([]string, 0, 1)
+	for _, p := range strings.Split(project, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(p, "@") {
+			projects = appendProject(projects, p)
+			continue
+		}
+
+		group, ok := c.Config.ProjectGroups[p[1:]]
+		if !ok {
+			return nil, unknownProjectGroupError(p[1:], c.Config.ProjectGroups)
+		}
+		if len(group) == 0 {
+			return nil, fmt.Errorf("project group %q has no projects", p)
+		}
+
+		for _, gp := range group {
+			projects = appendProject(projects, gp)
+		}
+	}
+
+	return projects, nil
+}
+
+func appendProject(projects []string, project string) []string {
+	project = strings.ToUpper(strings.TrimSpace(project))
+	if project == "" || slices.Contains(projects, project) {
+		return projects
+	}
+
+	return append(projects, project)
+}
+
+func unknownProjectGroupError(name string, groups map[string][]string) error {
+	names := make([]string, 0, len(groups))
+	for n := range groups {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	msg := fmt.Sprintf("unknown project group %q", "@"+name)
+	if s := Suggest(name, names); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", "@"+s)
+	}
+
+	return errors.New(msg + ", groups are set in \"projectGroups\"")
+}
@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_ListJQL(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InInput      ListInput
+		InDeployment string
+		OutJQL       string
+		OutErrMsg    string
+	}{
+		{
+			Name:    "DefaultProject",
+			InInput: ListInput{Status: "to do"},
+			OutJQL:  `project=JIWA AND status="to do" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "ExplicitProject",
+			InInput: ListInput{Project: "OTHER", Status: "to do", Assignee: "empty"},
+			OutJQL:  `project=OTHER AND status="to do" AND assignee is EMPTY ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "CommaSeparatedProjects",
+			InInput: ListInput{Project: "infra, deploy,SRE,INFRA"},
+			OutJQL:  `project IN (INFRA,DEPLOY,SRE) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "ProjectGroup",
+			InInput: ListInput{Project: "@platform,JIWA"},
+			OutJQL:  `project IN (INFRA,DEPLOY,SRE,JIWA) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:      "UnknownProjectGroup",
+			InInput:   ListInput{Project: "@platfrom"},
+			OutErrMsg: `unknown project group "@platfrom", did you mean "@platform"?, groups are set in "projectGroups"`,
+		},
+		{
+			Name:      "EmptyProjectGroup",
+			InInput:   ListInput{Project: "@empty"},
+			OutErrMsg: `project group "@empty" has no projects`,
+		},
+		{
+			Name:    "AllProjects",
+			InInput: ListInput{AllProjects: true, Status: "in progress", Assignee: "@me"},
+			OutJQL:  `status="in progress" AND assignee=currentUser() ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "AllProjectsWithLabelsAndJQL",
+			InInput: ListInput{AllProjects: true, Assignee: "me@example.com", Labels: []string{"ops", "oncall"}, JQL: "priority = High OR priority = Highest"},
+			OutJQL:  `assignee="me@example.com" AND labels in (ops,oncall) AND (priority = High OR priority = Highest) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "ReporterMe",
+			InInput: ListInput{Reporter: "@me"},
+			OutJQL:  `project=JIWA AND reporter=currentUser() ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "MeWithoutTheAt",
+			InInput: ListInput{Reporter: "me", Assignee: "me"},
+			OutJQL:  `project=JIWA AND assignee=currentUser() AND reporter=currentUser() ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "ReporterByName",
+			InInput: ListInput{Reporter: "jdoe", Assignee: "empty"},
+			OutJQL:  `project=JIWA AND assignee is EMPTY AND reporter="jdoe" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "CommentedByMe",
+			InInput: ListInput{CommentedBy: "@me"},
+			OutJQL:  `project=JIWA AND issueFunction in commented("by currentUser()") ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "CommentedByName",
+			InInput: ListInput{AllProjects: true, CommentedBy: "jdoe"},
+			OutJQL:  `issueFunction in commented("by jdoe") ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:         "UpdatedByMeCloud",
+			InInput:      ListInput{UpdatedByMe: true},
+			InDeployment: "Cloud",
+			OutJQL:       `project=JIWA AND issue in updatedBy(currentUser()) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:         "UpdatedByMeServer",
+			InInput:      ListInput{AllProjects: true, UpdatedByMe: true},
+			InDeployment: "Server",
+			OutJQL:       `(status changed by currentUser() OR assignee changed by currentUser()) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name: "Dates",
+			InInput: ListInput{
+				UpdatedSince: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				CreatedSince: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				DueBefore:    time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+			},
+			OutJQL: `project=JIWA AND updated >= "2024-07-01" AND created >= "2024-06-01" AND duedate < "2024-08-01" ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:      "AllProjectsWithProject",
+			InInput:   ListInput{AllProjects: true, Project: "OTHER"},
+			OutErrMsg: "--project and --all-projects cannot be used together",
+		},
+		{
+			Name:      "NoDefaultProject",
+			InInput:   ListInput{NoDefaultProject: true, Status: "to do"},
+			OutErrMsg: "no project given and --no-default-project is set, pass --project or --all-projects",
+		},
+		{
+			Name:    "NoDefaultProjectWithProject",
+			InInput: ListInput{NoDefaultProject: true, Project: "OTHER"},
+			OutJQL:  `project=OTHER ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "MineAndWatching",
+			InInput: ListInput{MineAndWatching: true},
+			OutJQL:  `project=JIWA AND (assignee=currentUser() OR watcher=currentUser()) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "SavedFilter",
+			InInput: ListInput{Filter: "triage"},
+			OutJQL:  `(project = OPS AND assignee is EMPTY) ORDER BY updated DESC, key DESC`,
+		},
+		{
+			Name:    "SavedFilterNarrowed",
+			InInput: ListInput{Filter: "mine", Project: "OPS", Status: "in progress"},
+			OutJQL:  `project=OPS AND status="in progress" AND (assignee = currentUser()) ORDER BY priority DESC`,
+		},
+		{
+			Name:      "UnknownSavedFilter",
+			InInput:   ListInput{Filter: "triag"},
+			OutErrMsg: `unknown filter "triag", did you mean "triage"?, filters are set in "filters"`,
+		},
+		{
+			Name:      "AllProjectsWithoutFilter",
+			InInput:   ListInput{AllProjects: true},
+			OutErrMsg: "refusing to list every issue in every project, add a filter like --status or --user",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Info = jiwa.ServerInfo{DeploymentType: td.InDeployment}
+			c := Command{Client: fake, Config: Config{
+				DefaultProject: "JIWA",
+				ProjectGroups: map[string][]string{
+					"platform": {"INFRA", "DEPLOY", "SRE"},
+					"empty":    {},
+				},
+				Filters: map[string]string{
+					"triage": "project = OPS AND assignee is EMPTY",
+					"mine":   "assignee = @me order by priority DESC",
+				},
+			}}
+			jql, err := c.listJQL(td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutJQL, jql)
+		})
+	}
+}
+
+func TestCommand_ListPagesLimit(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InLimit   int
+		InCap     int
+		OutKeys   []string
+		OutCapped bool
+		OutErrMsg string
+	}{
+		{
+			Name:    "Limit",
+			InLimit: 2,
+			OutKeys: []string{"JIWA-1", "JIWA-2"},
+		},
+		{
+			Name:    "LimitAboveResults",
+			InLimit: 10,
+			OutKeys: []string{"JIWA-1", "JIWA-2", "JIWA-3"},
+		},
+		{
+			Name:    "LimitAboveCap",
+			InLimit: 3,
+			InCap:   2,
+			OutKeys: []string{"JIWA-1", "JIWA-2", "JIWA-3"},
+		},
+		{
+			Name:    "NoLimitBelowCap",
+			OutKeys: []string{"JIWA-1", "JIWA-2", "JIWA-3"},
+		},
+		{
+			Name:      "NoLimitHitsCap",
+			InCap:     2,
+			OutKeys:   []string{"JIWA-1", "JIWA-2"},
+			OutCapped: true,
+		},
+		{
+			Name:      "NegativeLimit",
+			InLimit:   -1,
+			OutErrMsg: "--limit can't be negative, use 0 to list everything",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.SearchFunc = func(string) ([]jira.Issue, error) {
+				return []jira.Issue{{Key: "JIWA-1"}, {Key: "JIWA-2"}, {Key: "JIWA-3"}}, nil
+			}
+			c := Command{Client: fake, Config: Config{DefaultProject: "JIWA", ListCap: td.InCap}}
+
+			keys := make([]string, 0)
+			err := c.ListPages(context.Background(), ListInput{Limit: td.InLimit}, func(page []jira.Issue) error {
+				for _, i := range page {
+					keys = append(keys, i.Key)
+				}
+				return nil
+			})
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			if td.OutCapped {
+				assert.ErrorIs(t, err, ErrListCapped)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.OutKeys, keys)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the work breakdown jiwa apply turns into issues. Issues refer
+// to each other by their ID with "local:<id>":
+//
+//	project: JIWA
+//	issues:
+//	  - id: epic
+//	    type: Epic
+//	    summary: Payments v2
+//	  - id: api
+//	    summary: Add the refund endpoint
+//	    parent: local:epic
+//	    blocks: [local:ui]
+//	  - id: ui
+//	    summary: Show refunds
+//	    parent: local:epic
+type Manifest struct {
+	// Project is used for the issues that don't name one, it defaults to
+	// the configured "defaultProject"
+	Project string          `yaml:"project"`
+	Issues  []ManifestIssue `yaml:"issues"`
+}
+
+// ManifestIssue is an issue of the manifest, fields that are left out
+// aren't touched when the issue is updated
+type ManifestIssue struct {
+	ID string `yaml:"id"`
+	// Key is the issue that was created for the ID, apply updates it
+	// instead of creating another one
+	Key         string   `yaml:"key"`
+	Project     string   `yaml:"project"`
+	Type        string   `yaml:"type"`
+	Summary     string   `yaml:"summary"`
+	Description string   `yaml:"description"`
+	Labels      []string `yaml:"labels"`
+	Assignee    string   `yaml:"assignee"`
+	// Estimate is story points like 3 or a time like 2d
+	Estimate string `yaml:"estimate"`
+	// Parent and Blocks take "local:<id>" or the key of an existing issue
+	Parent string   `yaml:"parent"`
+	Blocks []string `yaml:"blocks"`
+
+	// Line is where the issue starts in the manifest
+	Line int `yaml:"-"`
+}
+
+// localRefPrefix marks references to issues of the manifest
+const localRefPrefix = "local:"
+
+var manifestIDRegEx = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ParseManifest reads and checks the manifest, every problem in it is
+// reported at once with the line of the issue
+func ParseManifest(b []byte) (Manifest, error) {
+	var m Manifest
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err := dec.Decode(&m)
+	switch {
+	case errors.Is(err, io.EOF):
+		return m, errors.New("the manifest is empty, it needs a list of \"issues\"")
+	case err != nil:
+		return m, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	seq, err := manifestIssueNodes(b)
+	if err != nil {
+		return m, err
+	}
+	for i := range m.Issues {
+		if i < len(seq) {
+			m.Issues[i].Line = seq[i].Line
+		}
+	}
+
+	err = m.validate()
+	if err != nil {
+		return m, err
+	}
+
+	_, err = m.order()
+	return m, err
+}
+
+// ReadManifest reads the manifest from a file
+func ReadManifest(path string) (Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read the manifest: %w", err)
+	}
+
+	m, err := ParseManifest(b)
+	if err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+func (m Manifest) validate() error {
+	if len(m.Issues) == 0 {
+		return errors.New("the manifest has no \"issues\"")
+	}
+
+	var errs []error
+	seen := make(map[string]int, len(m.Issues))
+	for _, issue := range m.Issues {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("line %d: %s", issue.Line, fmt.Sprintf(format, args...)))
+		}
+
+		switch {
+		case issue.ID == "":
+			fail("the issue needs an \"id\" to be referred to and matched on the next run")
+		case !manifestIDRegEx.MatchString(issue.ID):
+			fail("id %q can only have letters, digits, \"-\", \"_\" and \".\"", issue.ID)
+		case seen[issue.ID] != 0:
+			fail("id %q is already used on line %d", issue.ID, seen[issue.ID])
+		default:
+			seen[issue.ID] = issue.Line
+		}
+
+		if strings.TrimSpace(issue.Summary) == "" {
+			fail("%q has no summary", issue.ID)
+		} else if err := checkSummaryLength(issue.Summary); err != nil {
+			fail("%q: %s", issue.ID, err)
+		}
+
+		if issue.Estimate != "" {
+			if _, _, err := parseManifestEstimate(issue.Estimate); err != nil {
+				fail("%q: %s", issue.ID, err)
+			}
+		}
+	}
+
+	for _, issue := range m.Issues {
+		refs := append([]string{issue.Parent}, issue.Blocks...)
+		for _, ref := range refs {
+			id, local := strings.CutPrefix(ref, localRefPrefix)
+			switch {
+			case !local:
+			case id == issue.ID:
+				errs = append(errs, fmt.Errorf("line %d: %q refers to itself", issue.Line, issue.ID))
+			case seen[id] == 0:
+				msg := fmt.Sprintf("line %d: %q refers to %q, which isn't in the manifest", issue.Line, issue.ID, ref)
+				ids := make([]string, 0, len(seen))
+				for s := range seen {
+					ids = append(ids, s)
+				}
+				if s := Suggest(id, ids); s != "" {
+					msg += fmt.Sprintf(", did you mean %q?", localRefPrefix+s)
+				}
+				errs = append(errs, errors.New(msg))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// order returns the indexes of the issues with every parent before its
+// children and otherwise in the order of the manifest. Blocks don't order
+// anything, links are added once all issues exist.
+func (m Manifest) order() ([]int, error) {
+	index := make(map[string]int, len(m.Issues))
+	for i, issue := range m.Issues {
+		index[issue.ID] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(m.Issues))
+	order := make([]int, 0, len(m.Issues))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("line %d: the parents of %q go in a circle: %s", m.Issues[i].Line, m.Issues[i].ID, strings.Join(append(path, m.Issues[i].ID), " -> "))
+		}
+
+		state[i] = visiting
+		if id, ok := strings.CutPrefix(m.Issues[i].Parent, localRefPrefix); ok {
+			if p, ok := index[id]; ok {
+				err := visit(p, append(path, m.Issues[i].ID))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = done
+		order = append(order, i)
+
+		return nil
+	}
+
+	for i := range m.Issues {
+		err := visit(i, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// parseManifestEstimate tells story points from a time estimate, a plain
+// number is story points
+func parseManifestEstimate(value string) (float64, string, error) {
+	points, err := ParseStoryPoints(value)
+	if err == nil {
+		return points, "", nil
+	}
+
+	duration, durErr := ParseJiraDuration(value)
+	if durErr != nil {
+		return 0, "", fmt.Errorf("estimate %q is neither story points like 3 nor a time like 2d", value)
+	}
+
+	return 0, duration, nil
+}
+
+// manifestIssueNodes returns the nodes of the issues in the manifest
+func manifestIssueNodes(b []byte) ([]*yaml.Node, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	issues := mappingValue(&doc, "issues")
+	if issues == nil || issues.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+
+	return issues.Content, nil
+}
+
+// mappingValue returns the value of the key in the mapping node, or in the
+// mapping of a document node
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) == 1 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// AnnotateManifest writes the keys of the issues into the manifest next to
+// their IDs, so the next apply updates them instead of creating them
+// again. Comments are kept, the indentation is normalized to two spaces
+// and some values may end up quoted.
+func AnnotateManifest(b []byte, keys map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	issues := mappingValue(&doc, "issues")
+	if issues == nil || issues.Kind != yaml.SequenceNode {
+		return nil, errors.New("the manifest has no \"issues\"")
+	}
+
+	for _, issue := range issues.Content {
+		id := mappingValue(issue, "id")
+		if id == nil || keys[id.Value] == "" {
+			continue
+		}
+
+		if key := mappingValue(issue, "key"); key != nil {
+			key.Value = keys[id.Value]
+			continue
+		}
+
+		// the key goes right below the ID
+		for i := 0; i < len(issue.Content); i += 2 {
+			if issue.Content[i].Value == "id" {
+				pair := []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "key"},
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[id.Value]},
+				}
+				issue.Content = append(issue.Content[:i+2], append(pair, issue.Content[i+2:]...)...)
+				break
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	err = enc.Encode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the manifest: %w", err)
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the manifest: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// WriteManifestKeys annotates the manifest at path with the keys, the file
+// is replaced in one go so an interrupted write leaves it as it was
+func WriteManifestKeys(path string, keys map[string]string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the manifest: %w", err)
+	}
+
+	annotated, err := AnnotateManifest(b, keys)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, annotated, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to write the manifest: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
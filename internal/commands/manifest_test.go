@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `project: jiwa
+issues:
+  # the epic goes first even though it's listed last
+  - id: api
+    summary: Add the refund endpoint
+    parent: local:epic
+    labels: [backend]
+    estimate: 3
+    blocks: [local:ui]
+  - id: ui
+    summary: Show refunds
+    parent: local:epic
+    estimate: 2d
+  - id: epic
+    type: Epic
+    summary: Payments v2
+`
+
+func TestParseManifest(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		OutIssues []ManifestIssue
+		OutErrMsg string
+	}{
+		{
+			Name: "Valid",
+			In:   testManifest,
+			OutIssues: []ManifestIssue{
+				{ID: "api", Summary: "Add the refund endpoint", Parent: "local:epic", Labels: []string{"backend"}, Estimate: "3", Blocks: []string{"local:ui"}, Line: 4},
+				{ID: "ui", Summary: "Show refunds", Parent: "local:epic", Estimate: "2d", Line: 10},
+				{ID: "epic", Type: "Epic", Summary: "Payments v2", Line: 14},
+			},
+		},
+		{
+			Name:      "Empty",
+			In:        "",
+			OutErrMsg: `the manifest is empty, it needs a list of "issues"`,
+		},
+		{
+			Name:      "NoIssues",
+			In:        "project: JIWA\n",
+			OutErrMsg: `the manifest has no "issues"`,
+		},
+		{
+			Name:      "UnknownField",
+			In:        "issues:\n  - id: a\n    sumary: typo\n",
+			OutErrMsg: "invalid manifest: yaml: unmarshal errors:\n  line 3: field sumary not found in type commands.ManifestIssue",
+		},
+		{
+			Name: "EveryProblemAtOnce",
+			In: "issues:\n" +
+				"  - summary: no id\n" +
+				"  - id: a\n    summary: first\n" +
+				"  - id: a\n" +
+				"  - id: b c\n    summary: spaces\n" +
+				"  - id: d\n    summary: estimated\n    estimate: soon\n",
+			OutErrMsg: "line 2: the issue needs an \"id\" to be referred to and matched on the next run\n" +
+				"line 5: id \"a\" is already used on line 3\n" +
+				"line 5: \"a\" has no summary\n" +
+				"line 6: id \"b c\" can only have letters, digits, \"-\", \"_\" and \".\"\n" +
+				"line 8: \"d\": estimate \"soon\" is neither story points like 3 nor a time like 2d",
+		},
+		{
+			Name:      "UnknownReference",
+			In:        "issues:\n  - id: epic\n    summary: Epic\n  - id: task\n    summary: Task\n    parent: local:epik\n",
+			OutErrMsg: `line 4: "task" refers to "local:epik", which isn't in the manifest, did you mean "local:epic"?`,
+		},
+		{
+			Name:      "SelfReference",
+			In:        "issues:\n  - id: task\n    summary: Task\n    blocks: [local:task]\n",
+			OutErrMsg: `line 2: "task" refers to itself`,
+		},
+		{
+			Name: "ParentCycle",
+			In: "issues:\n" +
+				"  - id: a\n    summary: A\n    parent: local:b\n" +
+				"  - id: b\n    summary: B\n    parent: local:a\n",
+			OutErrMsg: `line 2: the parents of "a" go in a circle: a -> b -> a`,
+		},
+		{
+			Name: "BlocksCanGoBothWays",
+			In: "issues:\n" +
+				"  - id: a\n    key: JIWA-1\n    summary: A\n    blocks: [local:b]\n" +
+				"  - id: b\n    summary: B\n    blocks: [local:a, OPS-7]\n",
+			OutIssues: []ManifestIssue{
+				{ID: "a", Key: "JIWA-1", Summary: "A", Blocks: []string{"local:b"}, Line: 2},
+				{ID: "b", Summary: "B", Blocks: []string{"local:a", "OPS-7"}, Line: 6},
+			},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			m, err := ParseManifest([]byte(td.In))
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutIssues, m.Issues)
+		})
+	}
+}
+
+func TestManifest_Order(t *testing.T) {
+	m := Manifest{Issues: []ManifestIssue{
+		{ID: "subtask", Parent: "local:story"},
+		{ID: "story", Parent: "local:epic"},
+		{ID: "other"},
+		{ID: "epic"},
+		{ID: "external", Parent: "JIWA-1"},
+	}}
+
+	order, err := m.order()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 0, 2, 4}, order)
+}
+
+func TestAnnotateManifest(t *testing.T) {
+	testData := []struct {
+		Name   string
+		In     string
+		InKeys map[string]string
+		Out    string
+	}{
+		{
+			Name:   "KeyGoesBelowTheID",
+			In:     "project: JIWA\nissues:\n    # the epic\n    - id: epic\n      summary: Payments v2\n    - id: api\n      summary: Refunds\n",
+			InKeys: map[string]string{"epic": "JIWA-1"},
+			Out:    "project: JIWA\nissues:\n  # the epic\n  - id: epic\n    key: JIWA-1\n    summary: Payments v2\n  - id: api\n    summary: Refunds\n",
+		},
+		{
+			Name:   "ExistingKeyIsReplaced",
+			In:     "issues:\n  - summary: Refunds\n    id: api\n    key: JIWA-1\n",
+			InKeys: map[string]string{"api": "JIWA-2"},
+			Out:    "issues:\n  - summary: Refunds\n    id: api\n    key: JIWA-2\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			out, err := AnnotateManifest([]byte(td.In), td.InKeys)
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, string(out))
+		})
+	}
+}
+
+func newApplyCommand() (Command, *jiwafake.Client) {
+	fake := jiwafake.New()
+	fake.Info = jiwa.ServerInfo{DeploymentType: "Cloud"}
+	fake.LinkTypes = []jira.IssueLinkType{{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"}}
+
+	return Command{Client: fake, Config: Config{StoryPointsField: "customfield_10016"}}, fake
+}
+
+func TestCommand_Apply(t *testing.T) {
+	c, fake := newApplyCommand()
+	m, err := ParseManifest([]byte(testManifest))
+	assert.NoError(t, err)
+
+	keys := make(map[string]string)
+	results := c.Apply(m, func(id, key string) { keys[id] = key })
+	assert.Equal(t, []ApplyResult{
+		{ID: "api", Key: "JIWA-2", Action: ApplyCreated},
+		{ID: "ui", Key: "JIWA-3", Action: ApplyCreated},
+		{ID: "epic", Key: "JIWA-1", Action: ApplyCreated},
+	}, results)
+	assert.Equal(t, map[string]string{"epic": "JIWA-1", "api": "JIWA-2", "ui": "JIWA-3"}, keys)
+
+	api := fake.Issues["JIWA-2"]
+	assert.Equal(t, "JIWA-1", api.Fields.Parent.Key)
+	assert.Equal(t, "Task", api.Fields.Type.Name)
+	assert.Equal(t, []string{"backend"}, api.Fields.Labels)
+	assert.Equal(t, 3.0, api.Fields.Unknowns["customfield_10016"])
+	assert.Equal(t, "2d", fake.Issues["JIWA-3"].Fields.TimeTracking.OriginalEstimate)
+	links, err := c.Links("JIWA-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []IssueLink{{ID: "10001", Type: "Blocks", Description: "blocks", Key: "JIWA-3"}}, links)
+
+	// with the keys written back nothing is created or linked again
+	for i := range m.Issues {
+		m.Issues[i].Key = keys[m.Issues[i].ID]
+	}
+	results = c.Apply(m, func(id, key string) { t.Errorf("%s was created again as %s", id, key) })
+	for _, r := range results {
+		assert.Equal(t, ApplyUnchanged, r.Action, r.ID)
+		assert.NoError(t, r.Err, r.ID)
+	}
+	links, err = c.Links("JIWA-2")
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+
+	// changed fields are updated, the ones left out are kept
+	m.Issues[0].Summary = "Add the refund and void endpoints"
+	m.Issues[0].Labels = []string{"backend", "api"}
+	m.Issues[1].Estimate = "3d"
+	results = c.Apply(m, nil)
+	assert.Equal(t, []ApplyResult{
+		{ID: "api", Key: "JIWA-2", Action: ApplyUpdated},
+		{ID: "ui", Key: "JIWA-3", Action: ApplyUpdated},
+		{ID: "epic", Key: "JIWA-1", Action: ApplyUnchanged},
+	}, results)
+	api = fake.Issues["JIWA-2"]
+	assert.Equal(t, "Add the refund and void endpoints", api.Fields.Summary)
+	assert.Equal(t, []string{"backend", "api"}, api.Fields.Labels)
+	assert.Equal(t, 3.0, api.Fields.Unknowns["customfield_10016"])
+	assert.Equal(t, "3d", fake.Issues["JIWA-3"].Fields.TimeTracking.OriginalEstimate)
+}
+
+func TestCommand_Apply_Failures(t *testing.T) {
+	c, fake := newApplyCommand()
+	fake.Errors = map[string]error{"CreateIssue": errors.New("project JIWA doesn't exist")}
+	m, err := ParseManifest([]byte(testManifest))
+	assert.NoError(t, err)
+
+	results := c.Apply(m, nil)
+	assert.Len(t, results, 3)
+	assert.EqualError(t, results[0].Err, "not created, its parent local:epic doesn't exist, it failed")
+	assert.EqualError(t, results[1].Err, "not created, its parent local:epic doesn't exist, it failed")
+	assert.EqualError(t, results[2].Err, "project JIWA doesn't exist")
+	for _, r := range results {
+		assert.Empty(t, r.Key)
+	}
+}
+
+func TestCommand_Apply_DryRun(t *testing.T) {
+	c, fake := newApplyCommand()
+	c.DryRun = true
+	m, err := ParseManifest([]byte(testManifest))
+	assert.NoError(t, err)
+
+	results := c.Apply(m, nil)
+	for _, r := range results {
+		assert.Equal(t, ApplyCreated, r.Action)
+		assert.Empty(t, r.Key)
+		assert.NoError(t, r.Err)
+	}
+	assert.Empty(t, fake.Issues)
+}
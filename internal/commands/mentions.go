@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// mentionRegEx matches @name at the start of a word, so e-mail addresses
+// are left alone. A trailing dot ends the sentence, not the name.
+var mentionRegEx = regexp.MustCompile(`(^|[\s(\[{,;:])@([\p{L}\p{N}_.-]*[\p{L}\p{N}_])`)
+
+// verbatimRegEx matches the markup whose text Jira shows as it is,
+// {code} and {noformat} blocks and {{monospaced}} text. An @ in there is
+// code like @Override, not a mention. A block that isn't closed runs to
+// the end, like Jira renders it.
+var verbatimRegEx = regexp.MustCompile(`(?s)\{(code|noformat)(?::[^}]*)?\}(?:.*?\{(?:code|noformat)\}|.*$)|\{\{.*?\}\}`)
+
+// mentionScope is where to look for users first, the issue that is being
+// commented on or the project a new issue goes into
+type mentionScope struct {
+	IssueKey string
+	Project  string
+}
+
+// withMentions replaces @name with Jira's mention markup for the user,
+// unless NoMentions is set. Only what was written since before is
+// expanded, lines that are in before already were saved like this and
+// are left alone. Ambiguous names are asked about on the terminal,
+// without one they fail and list the candidates.
+func (c *Command) withMentions(scope mentionScope, text, before string) (string, error) {
+	if c.NoMentions {
+		return text, nil
+	}
+
+	var p *prompt.Prompter
+	openPrompt := func() (*prompt.Prompter, error) {
+		if p != nil {
+			return p, nil
+		}
+		if c.NoPrompt {
+			return nil, errors.New("not asking, prompts are disabled")
+		}
+
+		var err error
+		p, err = prompt.Open()
+		return p, err
+	}
+
+	text, err := c.expandMentions(c.ctx(), scope, text, before, openPrompt)
+	if p != nil {
+		p.Close()
+	}
+
+	return text, err
+}
+
+// expandMentions only calls openPrompt when a name is ambiguous. Names
+// nobody is found for are kept as they are with a warning.
+func (c *Command) expandMentions(ctx context.Context, scope mentionScope, text, before string, openPrompt func() (*prompt.Prompter, error)) (string, error) {
+	skip := mentionSkipped(text, before)
+	matches := make([][]int, 0)
+	for _, m := range mentionRegEx.FindAllStringSubmatchIndex(text, -1) {
+		// m[4]:m[5] is the name, the @ sits right in front of it
+		if !inRanges(skip, m[4]-1) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	info, err := c.Client.ServerInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		name := text[m[4]:m[5]]
+		user, err := c.mentionUser(ctx, scope, name, openPrompt)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			continue
+		}
+
+		b.WriteString(text[last : m[4]-1])
+		b.WriteString(mentionMarkup(*user, info.IsCloud()))
+		last = m[5]
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), nil
+}
+
+// mentionSkipped returns the byte ranges of text that mentions aren't
+// looked for in, the verbatim markup and the lines that are in before
+func mentionSkipped(text, before string) [][]int {
+	skip := verbatimRegEx.FindAllStringIndex(text, -1)
+	if before == "" {
+		return skip
+	}
+
+	saved := make(map[string]bool)
+	for _, line := range strings.Split(before, "\n") {
+		saved[line] = true
+	}
+	start := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if saved[strings.TrimSuffix(line, "\n")] {
+			skip = append(skip, []int{start, start + len(line)})
+		}
+		start += len(line)
+	}
+
+	return skip
+}
+
+func inRanges(ranges [][]int, i int) bool {
+	for _, r := range ranges {
+		if i >= r[0] && i < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mentionMarkup is the wiki markup for a mention. The v2 API that jiwa
+// talks to turns it into a mention node on Cloud, where users are only
+// known by their account ID.
+func mentionMarkup(user jira.User, cloud bool) string {
+	if cloud {
+		return "[~accountid:" + user.AccountID + "]"
+	}
+
+	return "[~" + user.Name + "]"
+}
+
+// mentionUser resolves the name once per command, so mentioning the same
+// person again or on several issues only asks once. It returns nil when
+// nobody goes by the name, that is warned about once as well.
+func (c *Command) mentionUser(ctx context.Context, scope mentionScope, name string, openPrompt func() (*prompt.Prompter, error)) (*jira.User, error) {
+	key := strings.ToLower(name)
+	if u, ok := c.mentions[key]; ok {
+		return u, nil
+	}
+
+	users, err := c.mentionCandidates(ctx, scope, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *jira.User
+	candidates := mentionCandidatesFor(name, users)
+	switch {
+	case len(candidates) == 0:
+		fmt.Fprintf(os.Stderr, "warning: no user found for @%s, keeping it as it is\n", name)
+	case len(candidates) == 1:
+		user = &candidates[0]
+	default:
+		p, err := openPrompt()
+		if err != nil {
+			return nil, ambiguousMentionError(name, candidates)
+		}
+
+		options := make([]string, 0, len(candidates))
+		for _, u := range candidates {
+			options = append(options, userLabel(u))
+		}
+
+		n, err := p.Choose(fmt.Sprintf("who is @%s? ", name), options)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%w: no user picked for @%s", ErrAbortedByUser, name)
+		}
+		user = &candidates[n]
+	}
+
+	if c.mentions == nil {
+		c.mentions = make(map[string]*jira.User)
+	}
+	c.mentions[key] = user
+
+	return user, nil
+}
+
+// mentionCandidates prefers users that can be assigned to the issue, they
+// are the likeliest to be meant, and falls back to everyone.
+func (c *Command) mentionCandidates(ctx context.Context, scope mentionScope, name string) ([]jira.User, error) {
+	if scope.IssueKey != "" || scope.Project != "" {
+		users, err := c.Client.SearchAssignableUsers(ctx, jiwa.AssignableUsersInput{
+			IssueKey: scope.IssueKey,
+			Project:  scope.Project,
+			Query:    name,
+		})
+		if err == nil && len(users) != 0 {
+			return users, nil
+		}
+	}
+
+	users, err := c.Client.SearchUsers(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up @%s: %w", name, err)
+	}
+
+	return users, nil
+}
+
+// mentionCandidatesFor narrows the users down to those whose username,
+// display name or e-mail exactly match the name if there are any.
+func mentionCandidatesFor(name string, users []jira.User) []jira.User {
+	exact := make([]jira.User, 0)
+	for _, u := range users {
+		local, _, _ := strings.Cut(u.EmailAddress, "@")
+		if strings.EqualFold(u.Name, name) || strings.EqualFold(u.DisplayName, name) || strings.EqualFold(local, name) {
+			exact = append(exact, u)
+		}
+	}
+
+	if len(exact) != 0 {
+		return exact
+	}
+
+	return users
+}
+
+func userLabel(u jira.User) string {
+	id := u.Name
+	if id == "" {
+		id = u.AccountID
+	}
+
+	if u.EmailAddress != "" {
+		return fmt.Sprintf("%s (%s, %s)", u.DisplayName, id, u.EmailAddress)
+	}
+
+	return fmt.Sprintf("%s (%s)", u.DisplayName, id)
+}
+
+func ambiguousMentionError(name string, users []jira.User) error {
+	labels := make([]string, 0, len(users))
+	for _, u := range users {
+		labels = append(labels, userLabel(u))
+	}
+
+	return fmt.Errorf("@%s matches several users, be more specific: %s", name, strings.Join(labels, "; "))
+}
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_ExpandMentions(t *testing.T) {
+	alice := jira.User{Name: "alice", AccountID: "5b10a2844c20165700ede21g", DisplayName: "Alice Liddell", EmailAddress: "alice@example.com"}
+	alicia := jira.User{Name: "alicia", AccountID: "5b10ac8d82e05b22cc7d4ef5", DisplayName: "Alicia Keys"}
+	bob := jira.User{Name: "bob", AccountID: "5b109f2e9729b51b54dc274d", DisplayName: "Bob"}
+
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InUsers      []jira.User
+		InAssignable []jira.User
+		InText       string
+		InBefore     string
+		InAnswer     string
+		InNoMentions bool
+		OutText      string
+		OutErrMsg    string
+	}{
+		{
+			Name:         "Server",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, bob},
+			InText:       "@alice can you pair with @bob? cc @alice.",
+			OutText:      "[~alice] can you pair with [~bob]? cc [~alice].",
+		},
+		{
+			Name:         "Cloud",
+			InDeployment: "Cloud",
+			InUsers:      []jira.User{alice, bob},
+			InText:       "ping @bob",
+			OutText:      "ping [~accountid:5b109f2e9729b51b54dc274d]",
+		},
+		{
+			Name:         "EmailIsNotAMention",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice},
+			InText:       "mail alice@example.com (@alice)",
+			OutText:      "mail alice@example.com ([~alice])",
+		},
+		{
+			Name:         "ExactMatchWins",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, alicia},
+			InText:       "@alice",
+			OutText:      "[~alice]",
+		},
+		{
+			Name:         "AssignableFirst",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, alicia},
+			InAssignable: []jira.User{alicia},
+			InText:       "@ali",
+			OutText:      "[~alicia]",
+		},
+		{
+			Name:         "AmbiguousWithoutTerminal",
+			InDeployment: "Cloud",
+			InUsers:      []jira.User{alice, alicia},
+			InText:       "@ali",
+			OutErrMsg:    "@ali matches several users, be more specific: Alice Liddell (alice, alice@example.com); Alicia Keys (alicia)",
+		},
+		{
+			Name:         "AmbiguousAsked",
+			InDeployment: "Cloud",
+			InUsers:      []jira.User{alice, alicia},
+			InText:       "@ali",
+			InAnswer:     "2\n",
+			OutText:      "[~accountid:5b10ac8d82e05b22cc7d4ef5]",
+		},
+		{
+			Name:         "Unknown",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice},
+			InText:       "@carol and @alice",
+			OutText:      "@carol and [~alice]",
+		},
+		{
+			Name:         "CodeIsNotAMention",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, {Name: "override", DisplayName: "Override"}},
+			InText:       "@alice\n{code:java}\n@Override\npublic void run() {}\n{code}\n{noformat}@Override{noformat} {{@Override}}",
+			OutText:      "[~alice]\n{code:java}\n@Override\npublic void run() {}\n{code}\n{noformat}@Override{noformat} {{@Override}}",
+		},
+		{
+			Name:         "UnclosedCodeBlock",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, {Name: "override", DisplayName: "Override"}},
+			InText:       "@alice\n{code}\n@Override",
+			OutText:      "[~alice]\n{code}\n@Override",
+		},
+		{
+			Name:         "OnlyNewLines",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice, bob},
+			InText:       "kept @bob as text\nask @alice\n",
+			InBefore:     "kept @bob as text\n",
+			OutText:      "kept @bob as text\nask [~alice]\n",
+		},
+		{
+			Name:         "NoMentions",
+			InDeployment: "Server",
+			InUsers:      []jira.User{alice},
+			InText:       "@alice @Override",
+			InNoMentions: true,
+			OutText:      "@alice @Override",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Info = jiwa.ServerInfo{DeploymentType: td.InDeployment}
+			fake.Users = td.InUsers
+			fake.AssignableUsers = td.InAssignable
+			c := Command{Client: fake, NoMentions: td.InNoMentions}
+
+			openPrompt := func() (*prompt.Prompter, error) {
+				if td.InAnswer == "" {
+					return nil, errors.New("no terminal")
+				}
+				return prompt.New(strings.NewReader(td.InAnswer), &bytes.Buffer{}), nil
+			}
+
+			var text string
+			var err error
+			if td.InNoMentions {
+				text, err = c.withMentions(mentionScope{IssueKey: "JIWA-1"}, td.InText, td.InBefore)
+			} else {
+				text, err = c.expandMentions(context.Background(), mentionScope{IssueKey: "JIWA-1"}, td.InText, td.InBefore, openPrompt)
+			}
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutText, text)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// migrateLinkTypes are tried in order to link the copy to the original
+var migrateLinkTypes = []string{"Cloners", "Relates"}
+
+type MigrateInput struct {
+	Key     string
+	Project string
+	// CloseOriginal closes the original once everything was copied, with
+	// CloseFields like the resolution set on the transition
+	CloseOriginal bool
+	CloseFields   map[string]string
+}
+
+// MigrateResult tells what made it over to the copy, Key is empty when the
+// copy wasn't created at all
+type MigrateResult struct {
+	Original    string
+	Key         string
+	Comments    int
+	Attachments []string
+	Closed      bool
+}
+
+// Migrate moves the issue into another project the way that works on every
+// instance: it creates a copy in the project with the summary, description,
+// labels and type of the original, quotes its comments, uploads its
+// attachments again, links both issues and leaves a comment on the original
+// pointing to the copy.
+//
+// Once the copy exists every remaining step is tried even if one fails, the
+// returned error names each failed step so nothing is lost without notice.
+// The original is only closed when nothing failed.
+func (c *Command) Migrate(input MigrateInput) (MigrateResult, error) {
+	result := MigrateResult{Original: input.Key}
+
+	issue, err := c.Client.GetIssue(c.ctx(), input.Key, jiwa.WithFields("project", "summary", "description", "labels", "issuetype", "comment", "attachment"))
+	if err != nil {
+		return result, fmt.Errorf("failed to get %s: %w", input.Key, err)
+	}
+	if issue.Fields == nil {
+		issue.Fields = &jira.IssueFields{}
+	}
+	if strings.EqualFold(issue.Fields.Project.Key, input.Project) {
+		return result, fmt.Errorf("%s already is in %s", input.Key, input.Project)
+	}
+
+	linkType, err := c.migrateLinkType()
+	if err != nil {
+		return result, err
+	}
+
+	var comments []*jira.Comment
+	if issue.Fields.Comments != nil {
+		comments = issue.Fields.Comments.Comments
+	}
+
+	issueType, err := c.migrateIssueType(input, issue.Fields.Type.Name)
+	if err != nil {
+		return result, err
+	}
+
+	if c.DryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: would copy %s to %s as a %s with %d comments and %d attachments\n", input.Key, input.Project, issueType, len(comments), len(issue.Fields.Attachments))
+		return result, nil
+	}
+
+	created, err := c.Client.CreateIssue(c.ctx(), jiwa.CreateIssueInput{
+		Project:     input.Project,
+		Summary:     issue.Fields.Summary,
+		Description: issue.Fields.Description,
+		Labels:      issue.Fields.Labels,
+		Type:        issueType,
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to create the copy of %s in %s: %w", input.Key, input.Project, err)
+	}
+	result.Key = created.Key
+	c.rememberIssue(created.Key, issue.Fields.Summary)
+
+	var errs []error
+	for _, comment := range comments {
+		if comment == nil {
+			continue
+		}
+		err := c.Client.CommentOnIssue(c.ctx(), created.Key, quoteComment(comment))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("comment %s by %s: %w", comment.ID, userName(comment.Author), err))
+			continue
+		}
+		result.Comments++
+	}
+
+	for _, a := range issue.Fields.Attachments {
+		if a == nil {
+			continue
+		}
+		content, err := c.Client.DownloadAttachment(c.ctx(), *a)
+		if err == nil {
+			_, err = c.Client.AddAttachment(c.ctx(), created.Key, a.Filename, content)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attachment %s: %w", a.Filename, err))
+			continue
+		}
+		result.Attachments = append(result.Attachments, a.Filename)
+	}
+
+	// the copy clones the original, Relates reads the same either way
+	err = c.Client.LinkIssues(c.ctx(), linkType, created.Key, input.Key)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s link: %w", linkType, err))
+	}
+
+	notice := fmt.Sprintf("Migrated to %s", created.Key)
+	if u := c.ConstructIssueURL(created.Key); u != "" {
+		notice += ": " + u
+	}
+	err = c.Client.CommentOnIssue(c.ctx(), input.Key, notice)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("migration notice on %s: %w", input.Key, err))
+	}
+
+	if len(errs) != 0 {
+		if input.CloseOriginal {
+			errs = append(errs, fmt.Errorf("left %s open because of the failures above", input.Key))
+		}
+		return result, fmt.Errorf("created %s but failed to copy everything of %s:\n%w", created.Key, input.Key, errors.Join(errs...))
+	}
+
+	if input.CloseOriginal {
+		_, err = c.Close([]string{input.Key}, input.CloseFields, "")
+		if err != nil {
+			return result, fmt.Errorf("copied everything to %s but failed to close %s: %w", created.Key, input.Key, err)
+		}
+		result.Closed = true
+	}
+	c.remember(input.Key)
+
+	return result, nil
+}
+
+// migrateLinkType picks the first of migrateLinkTypes the instance has,
+// before anything is created
+func (c *Command) migrateLinkType() (string, error) {
+	types, err := c.Client.ListIssueLinkTypes(c.ctx())
+	if err != nil {
+		return "", err
+	}
+
+	for _, want := range migrateLinkTypes {
+		for _, t := range types {
+			if strings.EqualFold(t.Name, want) {
+				return t.Name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("the instance has neither of the link types %s to link the copy", strings.Join(migrateLinkTypes, " or "))
+}
+
+// migrateIssueType keeps the type of the original if the project has it
+// and warns when the copy falls back to the configured fallbackIssueType
+func (c *Command) migrateIssueType(input MigrateInput, issueType string) (string, error) {
+	if issueType == "" {
+		issueType = "Task"
+	}
+
+	_, err := c.createFields(input.Project, issueType)
+	var unknown *jiwa.UnknownIssueTypeError
+	if !errors.As(err, &unknown) {
+		// anything else is left to the create to report
+		return issueType, nil
+	}
+
+	return c.fallbackIssueType(input.Project, input.Key, issueType, "copying", unknown.Types)
+}
+
+// fallbackIssueType picks the configured fallbackIssueType out of the
+// types of a project that lacks the issue type of key, verb is what
+// the warning says happens to the issue
+func (c *Command) fallbackIssueType(project, key, issueType, verb string, types []string) (string, error) {
+	fallback := c.Config.FallbackIssueType
+	if fallback == "" {
+		fallback = "Task"
+	}
+	i := slices.IndexFunc(types, func(t string) bool { return strings.EqualFold(t, fallback) })
+	if i < 0 {
+		return "", fmt.Errorf("project %s has neither the issue type %q of %s nor the fallback %q, set \"fallbackIssueType\" in the config to one of %s", project, issueType, key, fallback, strings.Join(types, ", "))
+	}
+
+	fmt.Fprintf(os.Stderr, "warning: %s has no issue type %q, %s %s as a %s\n", project, issueType, verb, key, types[i])
+	return types[i], nil
+}
+
+// quoteComment keeps who wrote the comment and when, the copy's comments
+// are all by whoever migrates it
+func quoteComment(comment *jira.Comment) string {
+	header := userName(comment.Author)
+	if t := parseJiraTime(comment.Created); !t.IsZero() {
+		header += " on " + t.Format("2006-01-02 15:04")
+	}
+
+	return fmt.Sprintf("%s wrote:\n{quote}\n%s\n{quote}", header, strings.TrimSpace(comment.Body))
+}
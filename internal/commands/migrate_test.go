@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Migrate(t *testing.T) {
+	cloners := jira.IssueLinkType{Name: "Cloners", Inward: "is cloned by", Outward: "clones"}
+	relates := jira.IssueLinkType{Name: "Relates", Inward: "relates to", Outward: "relates to"}
+
+	testData := []struct {
+		Name           string
+		InProject      string
+		InClose        bool
+		InLinkTypes    []jira.IssueLinkType
+		InErrors       map[string]error
+		InDryRun       bool
+		InTypes        []string
+		InFallback     string
+		OutResult      MigrateResult
+		OutComments    []string
+		OutStatus      string
+		OutLink        string
+		OutErrMsg      string
+		OutNoCopy      bool
+		OutType        string
+		OutAttachments map[string]string
+	}{
+		{
+			Name:        "MigrateAndClose",
+			InProject:   "OPS",
+			InClose:     true,
+			InLinkTypes: []jira.IssueLinkType{relates, cloners},
+			OutResult:   MigrateResult{Original: "JIWA-1", Key: "OPS-1", Comments: 2, Attachments: []string{"trace.log"}, Closed: true},
+			OutComments: []string{
+				"Alice on 2024-03-01 10:00 wrote:\n{quote}\nSeen it twice\n{quote}",
+				"bob wrote:\n{quote}\nThat's ops\n{quote}",
+			},
+			OutStatus:      "Done",
+			OutLink:        "Cloners",
+			OutAttachments: map[string]string{"trace.log": "panic: boom"},
+		},
+		{
+			Name:           "FallsBackToRelates",
+			InProject:      "OPS",
+			InLinkTypes:    []jira.IssueLinkType{relates},
+			OutResult:      MigrateResult{Original: "JIWA-1", Key: "OPS-1", Comments: 2, Attachments: []string{"trace.log"}},
+			OutStatus:      "To Do",
+			OutLink:        "Relates",
+			OutAttachments: map[string]string{"trace.log": "panic: boom"},
+		},
+		{
+			Name:           "FailedAttachmentKeepsOriginalOpen",
+			InProject:      "OPS",
+			InClose:        true,
+			InLinkTypes:    []jira.IssueLinkType{cloners},
+			InErrors:       map[string]error{"AddAttachment": errors.New("file too large")},
+			OutResult:      MigrateResult{Original: "JIWA-1", Key: "OPS-1", Comments: 2},
+			OutStatus:      "To Do",
+			OutLink:        "Cloners",
+			OutErrMsg:      "created OPS-1 but failed to copy everything of JIWA-1:\nattachment trace.log: file too large\nleft JIWA-1 open because of the failures above",
+			OutAttachments: map[string]string{},
+		},
+		{
+			Name:        "SameProject",
+			InProject:   "JIWA",
+			InLinkTypes: []jira.IssueLinkType{cloners},
+			OutResult:   MigrateResult{Original: "JIWA-1"},
+			OutErrMsg:   "JIWA-1 already is in JIWA",
+			OutNoCopy:   true,
+		},
+		{
+			Name:      "NoLinkType",
+			InProject: "OPS",
+			OutResult: MigrateResult{Original: "JIWA-1"},
+			OutErrMsg: "the instance has neither of the link types Cloners or Relates to link the copy",
+			OutNoCopy: true,
+		},
+		{
+			Name:           "FallsBackToTask",
+			InProject:      "OPS",
+			InLinkTypes:    []jira.IssueLinkType{cloners},
+			InTypes:        []string{"Story", "Task"},
+			OutResult:      MigrateResult{Original: "JIWA-1", Key: "OPS-1", Comments: 2, Attachments: []string{"trace.log"}},
+			OutStatus:      "To Do",
+			OutLink:        "Cloners",
+			OutType:        "Task",
+			OutAttachments: map[string]string{"trace.log": "panic: boom"},
+		},
+		{
+			Name:           "ConfiguredFallback",
+			InProject:      "OPS",
+			InLinkTypes:    []jira.IssueLinkType{cloners},
+			InTypes:        []string{"Story", "Task"},
+			InFallback:     "story",
+			OutResult:      MigrateResult{Original: "JIWA-1", Key: "OPS-1", Comments: 2, Attachments: []string{"trace.log"}},
+			OutStatus:      "To Do",
+			OutLink:        "Cloners",
+			OutType:        "Story",
+			OutAttachments: map[string]string{"trace.log": "panic: boom"},
+		},
+		{
+			Name:        "NoFallback",
+			InProject:   "OPS",
+			InLinkTypes: []jira.IssueLinkType{cloners},
+			InTypes:     []string{"Story"},
+			OutResult:   MigrateResult{Original: "JIWA-1"},
+			OutErrMsg:   `project OPS has neither the issue type "Bug" of JIWA-1 nor the fallback "Task", set "fallbackIssueType" in the config to one of Story`,
+			OutNoCopy:   true,
+		},
+		{
+			Name:        "DryRun",
+			InProject:   "OPS",
+			InLinkTypes: []jira.IssueLinkType{cloners},
+			InDryRun:    true,
+			OutResult:   MigrateResult{Original: "JIWA-1"},
+			OutNoCopy:   true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.LinkTypes = td.InLinkTypes
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Project:     jira.Project{Key: "JIWA"},
+				Summary:     "Crash on start",
+				Description: "It *crashes*",
+				Labels:      []string{"bug"},
+				Type:        jira.IssueType{Name: "Bug"},
+				Status:      &jira.Status{Name: "To Do"},
+				Comments: &jira.Comments{Comments: []*jira.Comment{
+					{ID: "1", Author: jira.User{DisplayName: "Alice"}, Created: "2024-03-01T10:00:00.000+0000", Body: "Seen it twice\n"},
+					{ID: "2", Author: jira.User{Name: "bob"}, Body: "That's ops"},
+				}},
+			}}
+			_, err := fake.AddAttachment(context.Background(), "JIWA-1", "trace.log", []byte("panic: boom"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fake.Errors = td.InErrors
+			for _, name := range td.InTypes {
+				if fake.CreateMeta == nil {
+					fake.CreateMeta = make(map[string]map[string]jiwa.CreateField)
+				}
+				fake.CreateMeta[td.InProject+"/"+name] = nil
+			}
+
+			c := Command{Client: fake, DryRun: td.InDryRun, Config: Config{BaseURL: "https://jira.example.com", FallbackIssueType: td.InFallback}}
+			result, err := c.Migrate(MigrateInput{Key: "JIWA-1", Project: td.InProject, CloseOriginal: td.InClose, CloseFields: map[string]string{"resolution": "Duplicate"}})
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.OutResult, result)
+
+			original := fake.Issues["JIWA-1"]
+			if td.OutNoCopy {
+				assert.Len(t, fake.Issues, 1)
+				assert.Nil(t, original.Fields.IssueLinks)
+				return
+			}
+
+			copied := fake.Issues["OPS-1"]
+			assert.Equal(t, "Crash on start", copied.Fields.Summary)
+			assert.Equal(t, "It *crashes*", copied.Fields.Description)
+			assert.Equal(t, []string{"bug"}, copied.Fields.Labels)
+			if td.OutType == "" {
+				td.OutType = "Bug"
+			}
+			assert.Equal(t, td.OutType, copied.Fields.Type.Name)
+			if td.OutComments != nil {
+				comments := make([]string, 0)
+				for _, comment := range copied.Fields.Comments.Comments {
+					comments = append(comments, comment.Body)
+				}
+				assert.Equal(t, td.OutComments, comments)
+			}
+
+			attachments := make(map[string]string)
+			for _, a := range copied.Fields.Attachments {
+				attachments[a.Filename] = string(fake.Attachments[a.Content])
+			}
+			assert.Equal(t, td.OutAttachments, attachments)
+
+			assert.Len(t, copied.Fields.IssueLinks, 1)
+			assert.Equal(t, td.OutLink, copied.Fields.IssueLinks[0].Type.Name)
+			assert.Equal(t, "JIWA-1", copied.Fields.IssueLinks[0].OutwardIssue.Key)
+
+			last := original.Fields.Comments.Comments[len(original.Fields.Comments.Comments)-1]
+			assert.Equal(t, "Migrated to OPS-1: https://jira.example.com/browse/OPS-1", last.Body)
+			assert.Equal(t, td.OutStatus, original.Fields.Status.Name)
+		})
+	}
+}
@@ -1,12 +1,66 @@
 package commands
 
 import (
-	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Move(issues []string, status string) ([]string, error) {
+// DefaultForwardKeywords mark transitions that advance an issue when they
+// appear in their name, "moveForwardKeywords" in the config replaces them
+var DefaultForwardKeywords = []string{"start", "progress", "review", "test", "resolve", "done", "close", "complete", "finish", "approve", "merge", "deploy", "release"}
+
+// DefaultBackwardKeywords mark transitions that take an issue a step back,
+// "moveBackwardKeywords" in the config replaces them
+var DefaultBackwardKeywords = []string{"back", "reopen", "reject", "stop", "undo", "revert", "return"}
+
+// Move transitions the issues into the status, the comment is recorded
+// with the transition if it isn't empty.
+func (c *Command) Move(issues []string, status string, fields map[string]string, comment string) ([]string, error) {
+	return c.transition(issues, jiwa.TransitionInput{Status: status, Fields: fields, Comment: comment})
+}
+
+// Close moves the issues along the first transition that ends in a status
+// of the "done" category, whatever that is called in their workflow.
+func (c *Command) Close(issues []string, fields map[string]string, comment string) ([]string, error) {
+	return c.transition(issues, jiwa.TransitionInput{StatusCategory: "done", Fields: fields, Comment: comment})
+}
+
+// Step moves each issue one step forward along its workflow, or one step
+// back if forward is false, see pickStep for how the transition is chosen.
+func (c *Command) Step(issues []string, forward bool, fields map[string]string, comment string) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionTransitionIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, i := range issues {
-		err := c.Client.TransitionIssue(context.TODO(), i, status)
+		issue, err := c.Client.GetIssue(c.ctx(), i, jiwa.WithFields("status"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the status of %s: %w", i, err)
+		}
+
+		transitions, err := c.Client.ListIssueTransitions(c.ctx(), i)
+		if err != nil {
+			return nil, fmt.Errorf("could not list transitions: %w", err)
+		}
+
+		var status jira.Status
+		if issue.Fields != nil && issue.Fields.Status != nil {
+			status = *issue.Fields.Status
+		}
+
+		t, err := pickStep(i, status, transitions, forward, c.forwardKeywords(), c.backwardKeywords())
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = c.transition([]string{i}, jiwa.TransitionInput{Status: t.Name, Fields: fields, Comment: comment, Current: &status})
 		if err != nil {
 			return nil, err
 		}
@@ -14,3 +68,183 @@ func (c *Command) Move(issues []string, status string) ([]string, error) {
 
 	return issues, nil
 }
+
+func (c *Command) forwardKeywords() []string {
+	if len(c.Config.MoveForwardKeywords) != 0 {
+		return c.Config.MoveForwardKeywords
+	}
+
+	return DefaultForwardKeywords
+}
+
+func (c *Command) backwardKeywords() []string {
+	if len(c.Config.MoveBackwardKeywords) != 0 {
+		return c.Config.MoveBackwardKeywords
+	}
+
+	return DefaultBackwardKeywords
+}
+
+// pickStep chooses the transition out of the status. A transition whose
+// name has a backward keyword goes back, otherwise the status categories of
+// both ends decide (to do, in progress, done) and within a category only a
+// forward keyword makes it go forward. Transitions into the same status are
+// ignored. Of the transitions in the wanted direction the one with the
+// smallest step is taken, so "In Progress" wins over "Done" when starting on
+// "To Do". Ties go to the transitions with a keyword for the direction and
+// if that still leaves several of them the choice is left to the user.
+func pickStep(key string, status jira.Status, transitions []jiwa.IssueTransition, forward bool, forwardKeywords, backwardKeywords []string) (jiwa.IssueTransition, error) {
+	from := stepRank(status.StatusCategory.Key)
+
+	var candidates []jiwa.IssueTransition
+	for _, t := range transitions {
+		if t.To.Name != "" && strings.EqualFold(t.To.Name, status.Name) {
+			continue
+		}
+
+		to := stepRank(t.To.StatusCategory.Key)
+		var backward bool
+		switch {
+		case containsKeyword(t.Name, backwardKeywords):
+			backward = true
+		case to != from:
+			backward = to < from
+		case containsKeyword(t.Name, forwardKeywords):
+			backward = false
+		default:
+			continue
+		}
+
+		if backward != forward {
+			candidates = append(candidates, t)
+		}
+	}
+
+	direction := "forward"
+	if !forward {
+		direction = "back"
+	}
+
+	if len(candidates) == 0 {
+		valid := make([]string, 0, len(transitions))
+		for _, t := range transitions {
+			valid = append(valid, t.Name)
+		}
+		return jiwa.IssueTransition{}, fmt.Errorf("no transition leads %s from %q for %s, valid transitions are: %s", direction, status.Name, key, strings.Join(valid, ","))
+	}
+
+	// the closest step is the lowest category forward and the highest back
+	closest := stepRank(candidates[0].To.StatusCategory.Key)
+	for _, t := range candidates[1:] {
+		to := stepRank(t.To.StatusCategory.Key)
+		if (forward && to < closest) || (!forward && to > closest) {
+			closest = to
+		}
+	}
+
+	var picked []jiwa.IssueTransition
+	for _, t := range candidates {
+		if stepRank(t.To.StatusCategory.Key) == closest {
+			picked = append(picked, t)
+		}
+	}
+
+	// keywords break ties, "Resolve" beats "Won't Do" although both are done
+	keywords := forwardKeywords
+	if !forward {
+		keywords = backwardKeywords
+	}
+	if len(picked) > 1 {
+		var named []jiwa.IssueTransition
+		for _, t := range picked {
+			if containsKeyword(t.Name, keywords) {
+				named = append(named, t)
+			}
+		}
+		if len(named) != 0 {
+			picked = named
+		}
+	}
+
+	if len(picked) > 1 {
+		options := make([]string, 0, len(picked))
+		for _, t := range picked {
+			options = append(options, fmt.Sprintf("%q to %s", t.Name, t.To.Name))
+		}
+		return jiwa.IssueTransition{}, fmt.Errorf("%s has several ways %s from %q, pass one of them as the status instead: %s", key, direction, status.Name, strings.Join(options, ", "))
+	}
+
+	return picked[0], nil
+}
+
+// stepRank places the status category along the workflow, unknown ones
+// count as in progress
+func stepRank(key string) int {
+	rank, ok := categoryOrder[key]
+	if !ok {
+		return categoryOrder["indeterminate"]
+	}
+
+	return rank
+}
+
+func containsKeyword(name string, keywords []string) bool {
+	name = strings.ToLower(name)
+	for _, k := range keywords {
+		if k != "" && strings.Contains(name, strings.ToLower(k)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Command) transition(issues []string, input jiwa.TransitionInput) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionTransitionIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	status := input.Status
+	if status == "" {
+		status = input.StatusCategory
+	}
+
+	moved := make([]string, 0, len(issues))
+	for _, i := range issues {
+		payload := hooks.Payload{Key: i, Status: status, Comment: input.Comment}
+		err := c.runPreHook("pre-move", payload)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.Client.Transition(c.ctx(), i, input)
+		if errors.Is(err, jiwa.ErrAlreadyInStatus) {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.runPostHook("post-move", payload)
+		c.remember(payload.Key)
+		moved = append(moved, i)
+	}
+
+	return moved, nil
+}
+
+// ParseFieldFlags turns "key=value" flags into a map
+func ParseFieldFlags(flags []string) (map[string]string, error) {
+	fields := make(map[string]string, len(flags))
+	for _, f := range flags {
+		k, v, found := strings.Cut(f, "=")
+		if !found || k == "" {
+			return nil, fmt.Errorf("field %q needs to look like <field>=<value>", f)
+		}
+		fields[k] = v
+	}
+
+	return fields, nil
+}
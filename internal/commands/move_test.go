@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Transition(t *testing.T) {
+	renamed := []jiwa.IssueTransition{
+		{ID: "21", Name: "Start", To: jira.Status{Name: "In Progress"}},
+		{ID: "31", Name: "Close", To: jira.Status{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}}},
+	}
+
+	testData := []struct {
+		Name          string
+		InInput       jiwa.TransitionInput
+		InTransitions []jiwa.IssueTransition
+		InErrors      map[string]error
+		OutStatus     string
+		OutComments   []string
+		OutSkipped    bool
+		OutErrMsg     string
+	}{
+		{
+			Name:      "ByName",
+			InInput:   jiwa.TransitionInput{Status: "in progress"},
+			OutStatus: "In Progress",
+		},
+		{
+			Name:      "ByCategory",
+			InInput:   jiwa.TransitionInput{StatusCategory: "done"},
+			OutStatus: "Done",
+		},
+		{
+			Name:        "WithComment",
+			InInput:     jiwa.TransitionInput{StatusCategory: "done", Comment: "Fixed in 1.2"},
+			OutStatus:   "Done",
+			OutComments: []string{"Fixed in 1.2"},
+		},
+		{
+			Name:          "ByTargetStatus",
+			InInput:       jiwa.TransitionInput{Status: "done"},
+			InTransitions: renamed,
+			OutStatus:     "Done",
+		},
+		{
+			Name:      "Typo",
+			InInput:   jiwa.TransitionInput{Status: "Dnoe", Comment: "Shipped"},
+			OutStatus: "To Do",
+			OutErrMsg: "could not find Dnoe as a valid transition for JIWA-1, valid transitions from To Do are: To Do,In Progress,Done (they depend on the status the issue is in)",
+		},
+		{
+			Name:          "TypoListsTargetStatuses",
+			InInput:       jiwa.TransitionInput{Status: "Blocked"},
+			InTransitions: renamed,
+			OutStatus:     "To Do",
+			OutErrMsg:     "could not find Blocked as a valid transition for JIWA-1, valid transitions from To Do are: Start (to In Progress),Close (to Done) (they depend on the status the issue is in)",
+		},
+		{
+			Name:          "AlreadyInStatus",
+			InInput:       jiwa.TransitionInput{Status: "to do", Comment: "Back to the pile"},
+			InTransitions: renamed,
+			OutStatus:     "To Do",
+			OutSkipped:    true,
+		},
+		{
+			Name:      "APIError",
+			InInput:   jiwa.TransitionInput{Status: "Done"},
+			InErrors:  map[string]error{"Transition": errors.New("boom")},
+			OutStatus: "To Do",
+			OutErrMsg: "boom",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			for k, v := range td.InErrors {
+				fake.Errors[k] = v
+			}
+			if td.InTransitions != nil {
+				fake.Transitions = td.InTransitions
+			}
+
+			issue, err := fake.CreateIssue(context.Background(), jiwa.CreateIssueInput{Project: "JIWA", Summary: "Test"})
+			assert.NoError(t, err)
+
+			c := Command{Client: fake, Config: Config{BaseURL: "https://catouc.atlassian.net"}}
+			moved, err := c.transition([]string{issue.Key}, td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+				if td.OutSkipped {
+					assert.Empty(t, moved)
+				} else {
+					assert.Equal(t, []string{issue.Key}, moved)
+				}
+			}
+
+			stored, err := fake.GetIssue(context.Background(), issue.Key)
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutStatus, stored.Fields.Status.Name)
+
+			var comments []string
+			if stored.Fields.Comments != nil {
+				for _, c := range stored.Fields.Comments.Comments {
+					comments = append(comments, c.Body)
+				}
+			}
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
+
+func TestPickStep(t *testing.T) {
+	status := func(name, category string) jira.Status {
+		return jira.Status{Name: name, StatusCategory: jira.StatusCategory{Key: category}}
+	}
+	simplified := []jiwa.IssueTransition{
+		{Name: "To Do", To: status("To Do", "new")},
+		{Name: "In Progress", To: status("In Progress", "indeterminate")},
+		{Name: "Done", To: status("Done", "done")},
+	}
+	withReview := append([]jiwa.IssueTransition{{Name: "In Review", To: status("In Review", "indeterminate")}}, simplified...)
+	classic := []jiwa.IssueTransition{
+		{Name: "Send back", To: status("In Progress", "indeterminate")},
+		{Name: "Ready for QA", To: status("QA", "indeterminate")},
+		{Name: "Resolve Issue", To: status("Resolved", "done")},
+		{Name: "Won't Do", To: status("Closed", "done")},
+	}
+
+	testData := []struct {
+		Name          string
+		InStatus      jira.Status
+		InTransitions []jiwa.IssueTransition
+		InForward     bool
+		InForwardKW   []string
+		OutName       string
+		OutErrMsg     string
+	}{
+		{
+			Name:          "NextTakesTheSmallestStep",
+			InStatus:      status("To Do", "new"),
+			InTransitions: simplified,
+			InForward:     true,
+			OutName:       "In Progress",
+		},
+		{
+			Name:          "NextByKeywordWithinACategory",
+			InStatus:      status("In Progress", "indeterminate"),
+			InTransitions: withReview,
+			InForward:     true,
+			OutName:       "In Review",
+		},
+		{
+			Name:          "PrevTakesTheSmallestStep",
+			InStatus:      status("Done", "done"),
+			InTransitions: simplified,
+			OutName:       "In Progress",
+		},
+		{
+			Name:          "BackwardKeywordWins",
+			InStatus:      status("QA", "indeterminate"),
+			InTransitions: classic,
+			OutName:       "Send back",
+		},
+		{
+			Name:          "KeywordBreaksTies",
+			InStatus:      status("QA", "indeterminate"),
+			InTransitions: classic,
+			InForward:     true,
+			OutName:       "Resolve Issue",
+		},
+		{
+			Name:          "Ambiguous",
+			InStatus:      status("QA", "indeterminate"),
+			InTransitions: classic,
+			InForward:     true,
+			InForwardKW:   []string{"start"},
+			OutErrMsg:     `JIWA-1 has several ways forward from "QA", pass one of them as the status instead: "Resolve Issue" to Resolved, "Won't Do" to Closed`,
+		},
+		{
+			Name:          "NoWayBack",
+			InStatus:      status("To Do", "new"),
+			InTransitions: simplified,
+			OutErrMsg:     `no transition leads back from "To Do" for JIWA-1, valid transitions are: To Do,In Progress,Done`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			forwardKW := DefaultForwardKeywords
+			if td.InForwardKW != nil {
+				forwardKW = td.InForwardKW
+			}
+
+			transition, err := pickStep("JIWA-1", td.InStatus, td.InTransitions, td.InForward, forwardKW, DefaultBackwardKeywords)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutName, transition.Name)
+		})
+	}
+}
+
+func TestCommand_Step(t *testing.T) {
+	fake := jiwafake.New()
+	issue, err := fake.CreateIssue(context.Background(), jiwa.CreateIssueInput{Project: "JIWA", Summary: "Test"})
+	assert.NoError(t, err)
+	c := Command{Client: fake}
+
+	for _, want := range []string{"In Progress", "Done"} {
+		_, err = c.Step([]string{issue.Key}, true, nil, "")
+		assert.NoError(t, err)
+
+		stored, _ := fake.GetIssue(context.Background(), issue.Key)
+		assert.Equal(t, want, stored.Fields.Status.Name)
+	}
+
+	_, err = c.Step([]string{issue.Key}, false, nil, "")
+	assert.NoError(t, err)
+	stored, _ := fake.GetIssue(context.Background(), issue.Key)
+	assert.Equal(t, "In Progress", stored.Fields.Status.Name)
+}
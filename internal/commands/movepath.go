@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// TransitionPath returns the transitions that take the issue from the
+// status it is in to the status, one after another, for move --path.
+// Jira only lists the transitions out of the status an issue is in, the
+// ones out of the other statuses are read off an issue of the same project
+// and type that is in them right now. Statuses no such issue is in can't
+// be explored, if no path is found they are named in the error. Nothing is
+// moved.
+func (c *Command) TransitionPath(key, status string) (jira.Status, []jiwa.IssueTransition, error) {
+	issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("status", "issuetype"))
+	if err != nil {
+		return jira.Status{}, nil, fmt.Errorf("failed to get the status of %s: %w", key, err)
+	}
+
+	var current jira.Status
+	var issueType string
+	if issue.Fields != nil {
+		if issue.Fields.Status != nil {
+			current = *issue.Fields.Status
+		}
+		issueType = issue.Fields.Type.Name
+	}
+
+	if strings.EqualFold(current.Name, status) {
+		return current, nil, fmt.Errorf("%s already is in %s, %w", key, current.Name, jiwa.ErrAlreadyInStatus)
+	}
+
+	var unexplored []string
+	transitionsFrom := func(s string) ([]jiwa.IssueTransition, error) {
+		if strings.EqualFold(s, current.Name) {
+			return c.Client.ListIssueTransitions(c.ctx(), key)
+		}
+
+		example, err := c.issueInStatus(projectOf(key), issueType, s)
+		if err != nil {
+			return nil, err
+		}
+		if example == "" {
+			unexplored = append(unexplored, s)
+			return nil, nil
+		}
+
+		return c.Client.ListIssueTransitions(c.ctx(), example)
+	}
+
+	path, err := findTransitionPath(current.Name, status, transitionsFrom)
+	if err != nil {
+		return current, nil, fmt.Errorf("could not list transitions: %w", err)
+	}
+	if path == nil {
+		msg := fmt.Sprintf("no transitions lead from %s to %s for %s", current.Name, status, key)
+		if len(unexplored) != 0 {
+			if issueType == "" {
+				issueType = "issue"
+			}
+			msg += fmt.Sprintf(", where %s leads is unknown since no %s is in it", strings.Join(unexplored, " or "), issueType)
+		}
+		return current, nil, errors.New(msg)
+	}
+
+	return current, path, nil
+}
+
+// issueInStatus returns the key of an issue of the project and type that
+// is in the status, empty if there is none
+func (c *Command) issueInStatus(project, issueType, status string) (string, error) {
+	jql := fmt.Sprintf("project = %s AND status = %s", jqlQuote(project), jqlQuote(status))
+	if issueType != "" {
+		jql += " AND issuetype = " + jqlQuote(issueType)
+	}
+
+	var key string
+	err := c.SearchPages(c.ctx(), jql, func(page []jira.Issue) error {
+		if len(page) != 0 {
+			key = page[0].Key
+		}
+		return errListLimit
+	}, []string{"status"})
+	if err != nil && !errors.Is(err, errListLimit) {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// findTransitionPath searches the statuses breadth first starting at from
+// for the fewest transitions that end in the status to, both compared
+// ignoring case. transitionsFrom lists the transitions out of a status,
+// every status is asked about once. It returns nil if there is no path.
+func findTransitionPath(from, to string, transitionsFrom func(status string) ([]jiwa.IssueTransition, error)) ([]jiwa.IssueTransition, error) {
+	type step struct {
+		prev       string
+		transition jiwa.IssueTransition
+	}
+
+	seen := map[string]step{strings.ToLower(from): {}}
+	queue := []string{from}
+	for len(queue) != 0 {
+		status := queue[0]
+		queue = queue[1:]
+
+		transitions, err := transitionsFrom(status)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range transitions {
+			next := strings.ToLower(t.To.Name)
+			if _, ok := seen[next]; ok || next == "" {
+				continue
+			}
+			seen[next] = step{prev: strings.ToLower(status), transition: t}
+
+			if next != strings.ToLower(to) {
+				queue = append(queue, t.To.Name)
+				continue
+			}
+
+			var path []jiwa.IssueTransition
+			for s := next; s != strings.ToLower(from); s = seen[s].prev {
+				path = append([]jiwa.IssueTransition{seen[s].transition}, path...)
+			}
+			return path, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func transitionTo(name, status string) jiwa.IssueTransition {
+	return jiwa.IssueTransition{Name: name, To: jira.Status{Name: status}}
+}
+
+// testWorkflow only goes forward through review, Blocked is a side track
+// and nothing leads to Archived
+var testWorkflow = map[string][]jiwa.IssueTransition{
+	"To Do":       {transitionTo("Start", "In Progress"), transitionTo("Block", "Blocked")},
+	"In Progress": {transitionTo("Stop", "To Do"), transitionTo("Review", "In Review"), transitionTo("Block", "Blocked")},
+	"Blocked":     {transitionTo("Unblock", "In Progress")},
+	"In Review":   {transitionTo("Reject", "In Progress"), transitionTo("Approve", "Done")},
+	"Done":        {transitionTo("Reopen", "To Do")},
+	"Archived":    {transitionTo("Restore", "To Do")},
+}
+
+func TestFindTransitionPath(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InFrom    string
+		InTo      string
+		OutPath   []string
+		OutErrMsg string
+	}{
+		{
+			Name:    "OneStep",
+			InFrom:  "To Do",
+			InTo:    "In Progress",
+			OutPath: []string{"Start"},
+		},
+		{
+			Name:    "ThroughReview",
+			InFrom:  "To Do",
+			InTo:    "Done",
+			OutPath: []string{"Start", "Review", "Approve"},
+		},
+		{
+			Name:    "FewestSteps",
+			InFrom:  "Blocked",
+			InTo:    "To Do",
+			OutPath: []string{"Unblock", "Stop"},
+		},
+		{
+			Name:    "IgnoresCase",
+			InFrom:  "done",
+			InTo:    "in review",
+			OutPath: []string{"Reopen", "Start", "Review"},
+		},
+		{
+			Name:   "Unreachable",
+			InFrom: "To Do",
+			InTo:   "Archived",
+		},
+		{
+			Name:   "UnknownStatus",
+			InFrom: "To Do",
+			InTo:   "Deployed",
+		},
+		{
+			Name:      "ListFails",
+			InFrom:    "In Review",
+			InTo:      "Archived",
+			OutErrMsg: "no transitions out of Done",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			asked := make(map[string]int)
+			path, err := findTransitionPath(td.InFrom, td.InTo, func(status string) ([]jiwa.IssueTransition, error) {
+				asked[status]++
+				if td.OutErrMsg != "" && status == "Done" {
+					return nil, errors.New(td.OutErrMsg)
+				}
+				for s, transitions := range testWorkflow {
+					if strings.EqualFold(s, status) {
+						return transitions, nil
+					}
+				}
+				return nil, nil
+			})
+
+			for status, n := range asked {
+				assert.Equal(t, 1, n, "%s is asked about once", status)
+			}
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			var names []string
+			for _, step := range path {
+				names = append(names, step.Name)
+			}
+			assert.Equal(t, td.OutPath, names)
+		})
+	}
+}
+
+func TestCommand_TransitionPath(t *testing.T) {
+	fake := jiwafake.New()
+	fake.Workflow = testWorkflow
+	for key, status := range map[string]string{"JIWA-1": "To Do", "JIWA-2": "In Progress", "JIWA-3": "Done"} {
+		fake.Issues[key] = jira.Issue{Key: key, Fields: &jira.IssueFields{
+			Type:   jira.IssueType{Name: "Task"},
+			Status: &jira.Status{Name: status},
+		}}
+	}
+	var queries []string
+	fake.SearchFunc = func(jql string) ([]jira.Issue, error) {
+		queries = append(queries, jql)
+		if strings.Contains(jql, `status = "In Progress"`) {
+			return []jira.Issue{fake.Issues["JIWA-2"]}, nil
+		}
+		return nil, nil
+	}
+	c := Command{Client: fake}
+
+	// JIWA-2 shows the way out of In Progress, nothing is in Blocked
+	from, path, err := c.TransitionPath("JIWA-1", "in review")
+	assert.NoError(t, err)
+	assert.Equal(t, "To Do", from.Name)
+	assert.Equal(t, []jiwa.IssueTransition{transitionTo("Start", "In Progress"), transitionTo("Review", "In Review")}, path)
+	assert.Contains(t, queries, `project = "JIWA" AND status = "In Progress" AND issuetype = "Task"`)
+	assert.Equal(t, "To Do", fake.Issues["JIWA-1"].Fields.Status.Name, "nothing is moved")
+
+	// nobody is in review to show that it leads to Done
+	_, _, err = c.TransitionPath("JIWA-1", "Done")
+	assert.EqualError(t, err, "no transitions lead from To Do to Done for JIWA-1, where Blocked or In Review leads is unknown since no Task is in it")
+
+	_, _, err = c.TransitionPath("JIWA-3", "done")
+	assert.ErrorIs(t, err, jiwa.ErrAlreadyInStatus)
+}
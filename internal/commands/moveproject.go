@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// MoveToProject moves the issue into the project with Jira's own move, so
+// it keeps its comments, attachments, links and history and its old key
+// still leads to it. The type is kept if the project has it, otherwise
+// "fallbackIssueType" is used like Migrate does. It returns the new key.
+//
+// Only Cloud moves issues through its API, elsewhere this fails with
+// jiwa.ErrMovingUnavailable and Migrate is the way to go.
+func (c *Command) MoveToProject(key, project string) (string, error) {
+	issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("project", "summary", "issuetype"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	if issue.Fields == nil {
+		issue.Fields = &jira.IssueFields{}
+	}
+	if strings.EqualFold(issue.Fields.Project.Key, project) {
+		return "", fmt.Errorf("%s already is in %s", key, project)
+	}
+
+	p, err := c.Client.GetProject(c.ctx(), project)
+	if err != nil {
+		return "", err
+	}
+	issueType, err := c.moveIssueType(key, p, issue.Fields.Type.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if c.DryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: would move %s to %s as a %s\n", key, project, issueType.Name)
+		return "", nil
+	}
+
+	moved, err := c.Client.MoveIssue(c.ctx(), key, project, issueType.ID)
+	if err != nil {
+		return "", err
+	}
+	c.rememberIssue(moved, issue.Fields.Summary)
+
+	return moved, nil
+}
+
+// moveIssueType picks the type of the project with the name of the
+// original's, or the fallbackIssueType with a warning
+func (c *Command) moveIssueType(key string, p jira.Project, name string) (jira.IssueType, error) {
+	if name == "" {
+		name = "Task"
+	}
+	byName := func(name string) int {
+		return slices.IndexFunc(p.IssueTypes, func(t jira.IssueType) bool { return strings.EqualFold(t.Name, name) })
+	}
+
+	if i := byName(name); i >= 0 {
+		return p.IssueTypes[i], nil
+	}
+
+	names := make([]string, 0, len(p.IssueTypes))
+	for _, t := range p.IssueTypes {
+		names = append(names, t.Name)
+	}
+	fallback, err := c.fallbackIssueType(p.Key, key, name, "moving", names)
+	if err != nil {
+		return jira.IssueType{}, err
+	}
+
+	return p.IssueTypes[byName(fallback)], nil
+}
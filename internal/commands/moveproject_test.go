@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_MoveToProject(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InProject   string
+		InTypes     []jira.IssueType
+		InFallback  string
+		InNoMoving  bool
+		InDryRun    bool
+		OutKey      string
+		OutType     string
+		OutErrMsg   string
+		OutNotMoved bool
+	}{
+		{
+			Name:      "KeepsTheType",
+			InProject: "OPS",
+			InTypes:   []jira.IssueType{{ID: "1", Name: "Task"}, {ID: "2", Name: "Bug"}},
+			OutKey:    "OPS-1",
+			OutType:   "Bug",
+		},
+		{
+			Name:       "FallsBackToTheConfiguredType",
+			InProject:  "OPS",
+			InTypes:    []jira.IssueType{{ID: "1", Name: "Task"}, {ID: "3", Name: "Incident"}},
+			InFallback: "incident",
+			OutKey:     "OPS-1",
+			OutType:    "Incident",
+		},
+		{
+			Name:        "NoMatchingType",
+			InProject:   "OPS",
+			InTypes:     []jira.IssueType{{ID: "3", Name: "Incident"}},
+			OutErrMsg:   `project OPS has neither the issue type "Bug" of JIWA-1 nor the fallback "Task", set "fallbackIssueType" in the config to one of Incident`,
+			OutNotMoved: true,
+		},
+		{
+			Name:        "SameProject",
+			InProject:   "JIWA",
+			OutErrMsg:   "JIWA-1 already is in JIWA",
+			OutNotMoved: true,
+		},
+		{
+			Name:        "NoMoving",
+			InProject:   "OPS",
+			InTypes:     []jira.IssueType{{ID: "2", Name: "Bug"}},
+			InNoMoving:  true,
+			OutErrMsg:   "moving issues between projects not available through the API of this Jira",
+			OutNotMoved: true,
+		},
+		{
+			Name:        "DryRun",
+			InProject:   "OPS",
+			InTypes:     []jira.IssueType{{ID: "2", Name: "Bug"}},
+			InDryRun:    true,
+			OutNotMoved: true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Project: jira.Project{Key: "JIWA"},
+				Summary: "Crash on start",
+				Type:    jira.IssueType{Name: "Bug"},
+			}}
+			fake.Projects["OPS"] = jira.Project{Key: "OPS", IssueTypes: td.InTypes}
+			fake.NoMoving = td.InNoMoving
+			c := Command{Client: fake, DryRun: td.InDryRun, Config: Config{FallbackIssueType: td.InFallback}}
+
+			key, err := c.MoveToProject("JIWA-1", td.InProject)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			if td.InNoMoving {
+				assert.ErrorIs(t, err, jiwa.ErrMovingUnavailable)
+			}
+			assert.Equal(t, td.OutKey, key)
+			if td.OutNotMoved {
+				assert.Contains(t, fake.Issues, "JIWA-1")
+				assert.Empty(t, fake.Moved)
+				return
+			}
+			assert.Equal(t, map[string]string{"JIWA-1": td.OutKey}, fake.Moved)
+			assert.Equal(t, td.OutType, fake.Issues[td.OutKey].Fields.Type.Name)
+		})
+	}
+}
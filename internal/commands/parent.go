@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// epicLinkSchema identifies Jira Software's Epic Link custom field that
+// Server uses to put stories into epics
+const epicLinkSchema = "com.pyxis.greenhopper.jira:gh-epic-link"
+
+// SetParent moves the issue under a new parent, an empty parentID detaches
+// it. Sub-tasks use the parent field, stories are put into epics through
+// the parent field on Cloud and the Epic Link field on Server.
+func (c *Command) SetParent(issueID, parentID string) (string, error) {
+	err := c.RequirePermission(jiwa.PermissionEditIssues, issueID)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := c.ctx()
+	issue, err := c.Client.GetIssue(ctx, issueID, jiwa.WithFields("summary", "issuetype", "parent"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", issueID, err)
+	}
+
+	var parent jira.Issue
+	if parentID != "" {
+		parent, err = c.Client.GetIssue(ctx, parentID, jiwa.WithFields("issuetype"))
+		if err != nil {
+			return "", fmt.Errorf("failed to get the new parent %s: %w", parentID, err)
+		}
+	}
+
+	field, value, err := c.parentUpdate(ctx, issue, parent)
+	if err != nil {
+		return "", err
+	}
+
+	payload := hooks.Payload{Key: issue.Key, Summary: issue.Fields.Summary, Parent: parent.Key}
+	err = c.runPreHook("pre-parent", payload)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.Client.UpdateIssue(ctx, issue.Key, jiwa.UpdateIssueInput{Fields: map[string]any{field: value}})
+	if err != nil {
+		return "", fmt.Errorf("failed to set the parent of %s: %w", issue.Key, err)
+	}
+
+	c.runPostHook("post-parent", payload)
+	c.rememberIssue(issue.Key, issue.Fields.Summary)
+
+	return issue.Key, nil
+}
+
+// parentUpdate checks that the parent fits the issue and returns the field
+// to set, Jira's own errors for a wrong parent don't say what is wrong.
+// An empty parent detaches the issue.
+func (c *Command) parentUpdate(ctx context.Context, issue, parent jira.Issue) (string, any, error) {
+	if parent.Key != "" && projectOf(parent.Key) != projectOf(issue.Key) {
+		return "", nil, fmt.Errorf("cannot move %s under %s, the parent has to be in the same project", issue.Key, parent.Key)
+	}
+
+	if issue.Fields.Type.Subtask {
+		switch {
+		case parent.Key == "":
+			return "", nil, fmt.Errorf("%s is a sub-task and always needs a parent", issue.Key)
+		case parent.Fields.Type.Subtask:
+			return "", nil, fmt.Errorf("cannot move %s under %s, sub-tasks cannot have sub-tasks", issue.Key, parent.Key)
+		}
+
+		return "parent", map[string]string{"key": parent.Key}, nil
+	}
+
+	if parent.Key != "" && !isEpic(parent.Fields.Type.Name) {
+		return "", nil, fmt.Errorf("cannot move %s under %s, only epics can be the parent of a %s", issue.Key, parent.Key, issue.Fields.Type.Name)
+	}
+
+	field, err := c.epicLinkField(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if field == "" {
+		return "", nil, errors.New("cannot put issues into epics, there is no Epic Link field on this instance")
+	}
+
+	switch {
+	case parent.Key == "":
+		return field, nil, nil
+	case field == "parent":
+		return field, map[string]string{"key": parent.Key}, nil
+	default:
+		return field, parent.Key, nil
+	}
+}
+
+// epicLinkField returns the field that links an issue to its epic, that is
+// "parent" on Cloud and the ID of the Epic Link custom field on Server, or
+// an empty string if Jira Software isn't installed.
+func (c *Command) epicLinkField(ctx context.Context) (string, error) {
+	info, err := c.Client.ServerInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsCloud() {
+		return "parent", nil
+	}
+
+	fields, err := c.Client.ListFields(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range fields {
+		if f.Schema.Custom == epicLinkSchema {
+			return f.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ParentOf returns the key of the issue's parent or epic, or an empty
+// string if it has none. The issue needs to have been fetched with the
+// parent field, the Epic Link on Server is looked up if needed.
+func (c *Command) ParentOf(issue jira.Issue) (string, error) {
+	if issue.Fields != nil && issue.Fields.Parent != nil {
+		return issue.Fields.Parent.Key, nil
+	}
+
+	ctx := c.ctx()
+	field, err := c.epicLinkField(ctx)
+	if err != nil || field == "" || field == "parent" {
+		return "", err
+	}
+
+	withEpic, err := c.Client.GetIssue(ctx, issue.Key, jiwa.WithFields(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the epic of %s: %w", issue.Key, err)
+	}
+
+	epic, _ := withEpic.Fields.Unknowns[field].(string)
+	return epic, nil
+}
+
+func projectOf(key string) string {
+	project, _, _ := strings.Cut(key, "-")
+	return project
+}
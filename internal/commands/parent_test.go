@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_SetParent(t *testing.T) {
+	issueOfType := func(key, issueType string, subtask bool, parent string) jira.Issue {
+		i := jira.Issue{Key: key, Fields: &jira.IssueFields{
+			Summary: "Summary of " + key,
+			Type:    jira.IssueType{Name: issueType, Subtask: subtask},
+		}}
+		if parent != "" {
+			i.Fields.Parent = &jira.Parent{Key: parent}
+		}
+		return i
+	}
+
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InIssue      string
+		InParent     string
+		OutParent    string
+		OutErrMsg    string
+	}{
+		{
+			Name:         "StoryIntoEpicOnCloud",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-1",
+			InParent:     "JIWA-10",
+			OutParent:    "JIWA-10",
+		},
+		{
+			Name:         "StoryOutOfEpicOnCloud",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-2",
+			OutParent:    "",
+		},
+		{
+			Name:         "StoryIntoOtherEpicOnServer",
+			InDeployment: "Server",
+			InIssue:      "JIWA-1",
+			InParent:     "JIWA-11",
+			OutParent:    "JIWA-11",
+		},
+		{
+			Name:         "StoryOutOfEpicOnServer",
+			InDeployment: "Server",
+			InIssue:      "JIWA-1",
+			OutParent:    "",
+		},
+		{
+			Name:         "SubtaskToOtherStory",
+			InDeployment: "Server",
+			InIssue:      "JIWA-3",
+			InParent:     "JIWA-1",
+			OutParent:    "JIWA-1",
+		},
+		{
+			Name:         "DetachSubtask",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-3",
+			OutErrMsg:    "JIWA-3 is a sub-task and always needs a parent",
+		},
+		{
+			Name:         "SubtaskUnderSubtask",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-3",
+			InParent:     "JIWA-4",
+			OutErrMsg:    "cannot move JIWA-3 under JIWA-4, sub-tasks cannot have sub-tasks",
+		},
+		{
+			Name:         "StoryUnderStory",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-1",
+			InParent:     "JIWA-2",
+			OutErrMsg:    "cannot move JIWA-1 under JIWA-2, only epics can be the parent of a Story",
+		},
+		{
+			Name:         "CrossProject",
+			InDeployment: "Cloud",
+			InIssue:      "JIWA-1",
+			InParent:     "OPS-1",
+			OutErrMsg:    "cannot move JIWA-1 under OPS-1, the parent has to be in the same project",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Info = jiwa.ServerInfo{DeploymentType: td.InDeployment}
+			fake.Fields = []jira.Field{{ID: "customfield_10014", Name: "Epic Link", Schema: jira.FieldSchema{Custom: epicLinkSchema}}}
+			for _, i := range []jira.Issue{
+				issueOfType("JIWA-1", "Story", false, ""),
+				issueOfType("JIWA-2", "Story", false, "JIWA-10"),
+				issueOfType("JIWA-3", "Sub-task", true, "JIWA-2"),
+				issueOfType("JIWA-4", "Sub-task", true, "JIWA-1"),
+				issueOfType("JIWA-10", "Epic", false, ""),
+				issueOfType("JIWA-11", "Epic", false, ""),
+				issueOfType("OPS-1", "Epic", false, ""),
+			} {
+				fake.Issues[i.Key] = i
+			}
+			// on Server stories are in epics through the Epic Link
+			fake.Issues["JIWA-1"].Fields.Unknowns = map[string]any{"customfield_10014": "JIWA-10"}
+			c := Command{Client: fake}
+
+			key, err := c.SetParent(td.InIssue, td.InParent)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.InIssue, key)
+
+			issue := fake.Issues[td.InIssue]
+			parent, err := c.ParentOf(issue)
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutParent, parent)
+
+			if td.InDeployment == "Server" && !issue.Fields.Type.Subtask {
+				epicLink, _ := issue.Fields.Unknowns["customfield_10014"].(string)
+				assert.Equal(t, td.OutParent, epicLink)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// RequirePermission fails if the user lacks the permission in the project
+// of any of the issues, so that shows before an editor is opened or half
+// of the issues are changed. The permissions are fetched once per project.
+// Issues that are only queued and instances that can't be reached are let
+// through, the change itself will tell.
+func (c *Command) RequirePermission(permission string, issues ...string) error {
+	if c.SkipPermissionCheck {
+		return nil
+	}
+
+	for _, key := range issues {
+		if strings.HasPrefix(key, offline.PlaceholderPrefix) {
+			continue
+		}
+
+		err := c.RequireProjectPermission(permission, projectOf(key))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RequireProjectPermission is RequirePermission for a project, for things
+// like creating an issue that have no issue yet
+func (c *Command) RequireProjectPermission(permission, project string) error {
+	if c.SkipPermissionCheck {
+		return nil
+	}
+
+	permissions, ok := c.permissions[project]
+	if !ok {
+		var err error
+		permissions, err = c.Client.MyPermissions(c.ctx(), project, "")
+		switch {
+		case offline.IsUnreachable(err):
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to check your permissions in %s, pass --skip-permission-check to go ahead without: %w", project, err)
+		}
+
+		if c.permissions == nil {
+			c.permissions = make(map[string]map[string]jiwa.Permission)
+		}
+		c.permissions[project] = permissions
+	}
+
+	// a permission the instance didn't report on isn't held against the
+	// user
+	p, ok := permissions[permission]
+	if ok && !p.Have {
+		return fmt.Errorf("you lack '%s' in project %s", p.Name, project)
+	}
+
+	return nil
+}
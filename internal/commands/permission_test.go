@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_RequirePermission_Commands(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InRun         func(c *Command) error
+		OutPermission string
+		OutErrMsg     string
+	}{
+		{
+			Name: "Comment",
+			InRun: func(c *Command) error {
+				_, err := c.Comment([]string{"JIWA-1"}, "LGTM")
+				return err
+			},
+			OutPermission: jiwa.PermissionAddComments,
+			OutErrMsg:     "you lack 'Add Comments' in project JIWA",
+		},
+		{
+			Name: "AppendToDescription",
+			InRun: func(c *Command) error {
+				_, err := c.AppendToDescription("JIWA-1", "more")
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "Label",
+			InRun: func(c *Command) error {
+				_, err := c.Label([]string{"JIWA-1"}, []string{"urgent"}, false)
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "Estimate",
+			InRun: func(c *Command) error {
+				_, err := c.Estimate([]string{"JIWA-1"}, EstimateInput{Value: "2d", Time: true})
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "Flag",
+			InRun: func(c *Command) error {
+				_, err := c.Flag([]string{"JIWA-1"}, "")
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "SetParent",
+			InRun: func(c *Command) error {
+				_, err := c.SetParent("JIWA-1", "JIWA-2")
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "SetComponents",
+			InRun: func(c *Command) error {
+				_, err := c.SetComponents([]string{"JIWA-1"}, []string{"API"})
+				return err
+			},
+			OutPermission: jiwa.PermissionEditIssues,
+			OutErrMsg:     "you lack 'Edit Issues' in project JIWA",
+		},
+		{
+			Name: "Reassign",
+			InRun: func(c *Command) error {
+				_, err := c.Reassign([]string{"JIWA-1"}, "jdoe", false)
+				return err
+			},
+			OutPermission: jiwa.PermissionAssignIssues,
+			OutErrMsg:     "you lack 'Assign Issues' in project JIWA",
+		},
+		{
+			Name: "Move",
+			InRun: func(c *Command) error {
+				_, err := c.Move([]string{"JIWA-1"}, "In Progress", nil, "")
+				return err
+			},
+			OutPermission: jiwa.PermissionTransitionIssues,
+			OutErrMsg:     "you lack 'Transition Issues' in project JIWA",
+		},
+		{
+			Name: "Close",
+			InRun: func(c *Command) error {
+				_, err := c.Close([]string{"JIWA-1"}, nil, "")
+				return err
+			},
+			OutPermission: jiwa.PermissionTransitionIssues,
+			OutErrMsg:     "you lack 'Transition Issues' in project JIWA",
+		},
+		{
+			Name: "Step",
+			InRun: func(c *Command) error {
+				_, err := c.Step([]string{"JIWA-1"}, true, nil, "")
+				return err
+			},
+			OutPermission: jiwa.PermissionTransitionIssues,
+			OutErrMsg:     "you lack 'Transition Issues' in project JIWA",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Summary:     "TLS handshake fails",
+				Description: "It fails",
+				Status:      &jira.Status{Name: "To Do"},
+			}}
+			fake.DeniedPermissions = []string{td.OutPermission}
+			before := fake.Issues["JIWA-1"]
+
+			c := Command{Client: fake}
+			err := td.InRun(&c)
+			assert.EqualError(t, err, td.OutErrMsg)
+			assert.Equal(t, before, fake.Issues["JIWA-1"], "nothing is changed")
+
+			// lacking every other permission doesn't stop the command
+			fake.DeniedPermissions = slices.DeleteFunc([]string{
+				jiwa.PermissionEditIssues,
+				jiwa.PermissionAssignIssues,
+				jiwa.PermissionAddComments,
+				jiwa.PermissionTransitionIssues,
+			}, func(p string) bool { return p == td.OutPermission })
+			c = Command{Client: fake}
+			err = td.InRun(&c)
+			if err != nil {
+				assert.NotContains(t, err.Error(), "you lack")
+			}
+		})
+	}
+}
+
+func TestCommand_RequirePermission(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InIssues  []string
+		InSkip    bool
+		InErr     error
+		InDenied  []string
+		OutErrMsg string
+	}{
+		{
+			Name:     "Granted",
+			InIssues: []string{"JIWA-1", "OPS-2"},
+		},
+		{
+			Name:      "Denied",
+			InIssues:  []string{"JIWA-1"},
+			InDenied:  []string{jiwa.PermissionAssignIssues},
+			OutErrMsg: "you lack 'Assign Issues' in project JIWA",
+		},
+		{
+			Name:     "Skipped",
+			InIssues: []string{"JIWA-1"},
+			InSkip:   true,
+			InDenied: []string{jiwa.PermissionAssignIssues},
+		},
+		{
+			Name:     "QueuedIssue",
+			InIssues: []string{"OFFLINE-1"},
+			InDenied: []string{jiwa.PermissionAssignIssues},
+		},
+		{
+			Name:      "EndpointFails",
+			InIssues:  []string{"JIWA-1"},
+			InErr:     errors.New("403 Forbidden"),
+			OutErrMsg: "failed to check your permissions in JIWA, pass --skip-permission-check to go ahead without: 403 Forbidden",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.DeniedPermissions = td.InDenied
+			if td.InErr != nil {
+				fake.Errors = map[string]error{"MyPermissions": td.InErr}
+			}
+
+			c := Command{Client: fake, SkipPermissionCheck: td.InSkip}
+			err := c.RequirePermission(jiwa.PermissionAssignIssues, td.InIssues...)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCommand_RequirePermission_Cache(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+	assert.NoError(t, err)
+
+	c := Command{Client: client}
+	assert.NoError(t, c.RequirePermission(jiwa.PermissionEditIssues, "JIWA-1", "JIWA-2", "OPS-1"))
+	assert.NoError(t, c.RequirePermission(jiwa.PermissionAddComments, "JIWA-3", "OPS-2"))
+
+	projects := make([]string, 0)
+	for _, r := range srv.Requests() {
+		if strings.HasSuffix(r.Path, "/mypermissions") {
+			projects = append(projects, r.Query)
+		}
+	}
+	assert.Len(t, projects, 2, "one request per project")
+}
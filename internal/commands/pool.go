@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultConcurrency is how many requests run at once unless
+// "concurrency" is set, enough to hide latency without hammering Jira
+const defaultConcurrency = 4
+
+// concurrency is the size of the worker pool requests share
+func (c *Command) concurrency() int {
+	if c.Config.Concurrency > 0 {
+		return c.Config.Concurrency
+	}
+
+	return defaultConcurrency
+}
+
+// parallel calls fn for every index below n on the worker pool. The first
+// error cancels the context handed to the other calls and is returned once
+// all of them are done.
+func (c *Command) parallel(n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(c.ctx())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, c.concurrency())
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := fn(ctx, i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Parallel(t *testing.T) {
+	errFail := errors.New("fail")
+
+	testData := []struct {
+		Name           string
+		InConcurrency  int
+		InFailAt       int
+		OutMaxRunning  int
+		OutErr         error
+		OutAllFinished bool
+	}{
+		{
+			Name:           "DefaultConcurrency",
+			InFailAt:       -1,
+			OutMaxRunning:  defaultConcurrency,
+			OutAllFinished: true,
+		},
+		{
+			Name:           "ConfiguredConcurrency",
+			InConcurrency:  2,
+			InFailAt:       -1,
+			OutMaxRunning:  2,
+			OutAllFinished: true,
+		},
+		{
+			Name:          "FirstErrorCancelsTheRest",
+			InConcurrency: 1,
+			InFailAt:      0,
+			OutMaxRunning: 1,
+			OutErr:        errFail,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c := Command{Config: Config{Concurrency: td.InConcurrency}}
+
+			var (
+				mu         sync.Mutex
+				running    int
+				maxRunning int
+				finished   int
+			)
+			// every call waits until the pool is full or all calls started
+			release := make(chan struct{})
+			var once sync.Once
+			err := c.parallel(10, func(ctx context.Context, i int) error {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				if running == c.concurrency() || i == 9 || i == td.InFailAt {
+					once.Do(func() { close(release) })
+				}
+				mu.Unlock()
+
+				<-release
+
+				mu.Lock()
+				defer mu.Unlock()
+				running--
+				if i == td.InFailAt {
+					return errFail
+				}
+				finished++
+				return nil
+			})
+
+			assert.ErrorIs(t, err, td.OutErr)
+			assert.Equal(t, td.OutMaxRunning, maxRunning)
+			assert.Equal(t, td.OutAllFinished, finished == 10)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/prompt"
+)
+
+// QueryResult is what became of an issue a --jql query matched, Changed
+// is false for issues that didn't need the change, like an issue that
+// already is in the status
+type QueryResult struct {
+	Key     string
+	Changed bool
+	Err     error
+}
+
+// QueryKeys returns the keys of every issue the query matches, page by
+// page, so a large backlog isn't cut off after the first page
+func (c *Command) QueryKeys(jql string) ([]string, error) {
+	keys := make([]string, 0)
+	err := c.SearchPages(c.ctx(), jql, func(page []jira.Issue) error {
+		for _, issue := range page {
+			keys = append(keys, issue.Key)
+		}
+		return nil
+	}, []string{"summary"})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// ConfirmQuery asks on the terminal whether to go ahead with the change of
+// the issues a query matched, e.g. "reassign 12 issues to alice". Nobody
+// can say yes without a terminal, that takes --force or Yes.
+func (c *Command) ConfirmQuery(question string) error {
+	if c.Yes {
+		return nil
+	}
+	if !c.NoPrompt {
+		p, err := prompt.Open()
+		if err == nil {
+			defer p.Close()
+			return confirmQuery(p, question)
+		}
+	}
+
+	return fmt.Errorf("cannot ask whether to %s, pass --force to go ahead", question)
+}
+
+func confirmQuery(p *prompt.Prompter, question string) error {
+	ok, err := p.Confirm(question + "?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: nothing was changed", ErrAbortedByUser)
+	}
+
+	return nil
+}
+
+// ForEachIssue calls fn for every issue on the worker pool, a failed issue
+// doesn't stop the others. The permission is checked for all issues
+// before anything is changed, which also fills the cache the calls read.
+// The results are in the order of the issues.
+func (c *Command) ForEachIssue(issues []string, permission string, fn func(key string) (bool, error)) ([]QueryResult, error) {
+	err := c.RequirePermission(permission, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]QueryResult, len(issues))
+	for i, key := range issues {
+		results[i] = QueryResult{Key: key, Err: errors.New("not changed, interrupted")}
+	}
+
+	// the errors are collected in the results, the pool only stops when
+	// the context is cancelled
+	_ = c.parallel(len(issues), func(_ context.Context, i int) error {
+		changed, err := fn(issues[i])
+		results[i] = QueryResult{Key: issues[i], Changed: changed, Err: err}
+		return nil
+	})
+
+	return results, nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_QueryKeys(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.PageSize = 2
+	for i := 1; i <= 5; i++ {
+		assignee := "bob"
+		if i == 3 {
+			assignee = "alice"
+		}
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i), Fields: &jira.IssueFields{Assignee: &jira.User{Name: assignee}}})
+	}
+	srv.SetSearch(func(jql string, issues []jira.Issue) []jira.Issue {
+		matched := make([]jira.Issue, 0)
+		for _, issue := range issues {
+			if strings.Contains(jql, "assignee=bob") && issue.Fields.Assignee.Name == "bob" {
+				matched = append(matched, issue)
+			}
+		}
+		return matched
+	})
+
+	client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+	assert.NoError(t, err)
+
+	c := Command{Client: client}
+	keys, err := c.QueryKeys("assignee=bob")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"JIWA-1", "JIWA-2", "JIWA-4", "JIWA-5"}, keys, "every page is read")
+}
+
+func TestConfirmQuery(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InAnswer  string
+		OutErrMsg string
+	}{
+		{
+			Name:     "Yes",
+			InAnswer: "y\n",
+		},
+		{
+			Name:      "No",
+			InAnswer:  "\n",
+			OutErrMsg: "aborted: nothing was changed",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			err := confirmQuery(prompt.New(strings.NewReader(td.InAnswer), &out), "reassign 4 issues to alice")
+			assert.Equal(t, "reassign 4 issues to alice? [y/N] ", out.String())
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.ErrorIs(t, err, ErrAbortedByUser)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	c := Command{NoPrompt: true}
+	err := c.ConfirmQuery("reassign 4 issues to alice")
+	assert.EqualError(t, err, "cannot ask whether to reassign 4 issues to alice, pass --force to go ahead")
+}
+
+func TestCommand_ForEachIssue(t *testing.T) {
+	fake := jiwafake.New()
+	for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+		fake.Issues[key] = jira.Issue{Key: key, Fields: &jira.IssueFields{Status: &jira.Status{Name: "To Do"}}}
+	}
+	c := Command{Client: fake}
+
+	// JIWA-2 doesn't need the change and JIWA-4 doesn't exist, neither
+	// stops the others
+	issues := []string{"JIWA-1", "JIWA-2", "JIWA-4", "JIWA-3"}
+	results, err := c.ForEachIssue(issues, jiwa.PermissionTransitionIssues, func(key string) (bool, error) {
+		if key == "JIWA-2" {
+			return false, nil
+		}
+		moved, err := c.Move([]string{key}, "In Progress", nil, "")
+		return len(moved) != 0, err
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, QueryResult{Key: "JIWA-1", Changed: true}, results[0])
+	assert.Equal(t, QueryResult{Key: "JIWA-2"}, results[1])
+	assert.Equal(t, "JIWA-4", results[2].Key)
+	assert.Error(t, results[2].Err)
+	assert.Equal(t, QueryResult{Key: "JIWA-3", Changed: true}, results[3])
+	assert.Equal(t, "In Progress", fake.Issues["JIWA-3"].Fields.Status.Name)
+
+	fake.DeniedPermissions = []string{jiwa.PermissionEditIssues}
+	c = Command{Client: fake}
+	_, err = c.ForEachIssue(issues, jiwa.PermissionEditIssues, func(key string) (bool, error) {
+		t.Errorf("%s was changed without the permission", key)
+		return true, nil
+	})
+	assert.EqualError(t, err, "you lack 'Edit Issues' in project JIWA")
+}
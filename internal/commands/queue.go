@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// StatusGroup holds the issues of a queue that are in the same status
+type StatusGroup struct {
+	Status string `json:"status"`
+	// Category is the key of the status category, "new", "indeterminate"
+	// or "done"
+	Category string       `json:"category"`
+	Count    int          `json:"count"`
+	Issues   []jira.Issue `json:"issues"`
+}
+
+// categoryOrder sorts status groups the way work flows through them
+var categoryOrder = map[string]int{
+	"new":           0,
+	"indeterminate": 1,
+	"done":          2,
+}
+
+// Queue returns the issues assigned to the user that aren't done yet, in
+// all projects and grouped by status. "@me" is the current user.
+func (c *Command) Queue(assignee string) ([]StatusGroup, error) {
+	jql, err := queueJQL(assignee)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := c.Client.Search(c.ctx(), jql)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the queue: %w", err)
+	}
+
+	return groupByStatus(issues), nil
+}
+
+func queueJQL(assignee string) (string, error) {
+	switch assignee {
+	case "":
+		return "", errors.New("no user given")
+	case "@me":
+		assignee = "currentUser()"
+	default:
+		assignee = jqlQuote(assignee)
+	}
+
+	return "assignee=" + assignee + " AND statusCategory != Done ORDER BY updated DESC", nil
+}
+
+// groupByStatus keeps the order of the issues within a group, the groups
+// are ordered by status category and then by when they were first seen.
+func groupByStatus(issues []jira.Issue) []StatusGroup {
+	groups := make([]StatusGroup, 0)
+	index := make(map[string]int)
+	for _, i := range issues {
+		status, category := "Unknown", ""
+		if i.Fields != nil && i.Fields.Status != nil {
+			status, category = i.Fields.Status.Name, i.Fields.Status.StatusCategory.Key
+		}
+
+		n, ok := index[status]
+		if !ok {
+			n = len(groups)
+			index[status] = n
+			groups = append(groups, StatusGroup{Status: status, Category: category})
+		}
+
+		groups[n].Issues = append(groups[n].Issues, i)
+		groups[n].Count++
+	}
+
+	sort.SliceStable(groups, func(a, b int) bool {
+		return categoryRank(groups[a].Category) < categoryRank(groups[b].Category)
+	})
+
+	return groups
+}
+
+func categoryRank(category string) int {
+	rank, ok := categoryOrder[category]
+	if !ok {
+		return len(categoryOrder)
+	}
+
+	return rank
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueInStatus(key, status, category string) jira.Issue {
+	return jira.Issue{
+		Key: key,
+		Fields: &jira.IssueFields{
+			Status: &jira.Status{Name: status, StatusCategory: jira.StatusCategory{Key: category}},
+		},
+	}
+}
+
+func TestCommand_Queue(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InAssignee string
+		InIssues   []jira.Issue
+		OutJQL     string
+		OutGroups  map[string][]string
+		OutOrder   []string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "Mine",
+			InAssignee: "@me",
+			InIssues: []jira.Issue{
+				issueInStatus("OPS-3", "Review", "indeterminate"),
+				issueInStatus("JIWA-1", "To Do", "new"),
+				issueInStatus("OPS-1", "In Progress", "indeterminate"),
+				issueInStatus("JIWA-2", "Backlog", "new"),
+				issueInStatus("OPS-2", "Review", "indeterminate"),
+			},
+			OutJQL:   "assignee=currentUser() AND statusCategory != Done ORDER BY updated DESC",
+			OutOrder: []string{"To Do", "Backlog", "Review", "In Progress"},
+			OutGroups: map[string][]string{
+				"To Do":       {"JIWA-1"},
+				"Backlog":     {"JIWA-2"},
+				"Review":      {"OPS-3", "OPS-2"},
+				"In Progress": {"OPS-1"},
+			},
+		},
+		{
+			Name:       "SomeoneElse",
+			InAssignee: `j"doe`,
+			InIssues: []jira.Issue{
+				{Key: "JIWA-5"},
+				issueInStatus("JIWA-4", "In Progress", "indeterminate"),
+			},
+			OutJQL:   `assignee="j\"doe" AND statusCategory != Done ORDER BY updated DESC`,
+			OutOrder: []string{"In Progress", "Unknown"},
+			OutGroups: map[string][]string{
+				"In Progress": {"JIWA-4"},
+				"Unknown":     {"JIWA-5"},
+			},
+		},
+		{
+			Name:      "NoUser",
+			OutErrMsg: "no user given",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var jql string
+			fake := jiwafake.New()
+			fake.SearchFunc = func(q string) ([]jira.Issue, error) {
+				jql = q
+				return td.InIssues, nil
+			}
+			c := Command{Client: fake}
+
+			groups, err := c.Queue(td.InAssignee)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutJQL, jql)
+
+			order := make([]string, 0, len(groups))
+			for _, g := range groups {
+				order = append(order, g.Status)
+
+				keys := make([]string, 0, len(g.Issues))
+				for _, i := range g.Issues {
+					keys = append(keys, i.Key)
+				}
+				assert.Equal(t, td.OutGroups[g.Status], keys)
+				assert.Equal(t, len(keys), g.Count)
+			}
+			assert.Equal(t, td.OutOrder, order)
+		})
+	}
+}
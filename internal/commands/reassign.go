@@ -1,18 +1,159 @@
 package commands
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
-func (c *Command) Reassign(issues []string, username string) ([]string, error) {
+// maxAssignableSuggestions caps how many close matches are listed when the
+// user can't be assigned
+const maxAssignableSuggestions = 5
+
+// Reassign assigns the issues to the user. Unless force is set the user is
+// first checked against the users that can be assigned issues in each
+// project, so a missing permission is reported before anything changes.
+func (c *Command) Reassign(issues []string, username string, force bool) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionAssignIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !force && username != "" {
+		err = c.CheckAssignable(issues, username)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	for _, issue := range issues {
-		err := c.Client.AssignIssue(context.TODO(), issue, username)
+		payload := hooks.Payload{Key: issue, Assignee: username}
+		err := c.runPreHook("pre-reassign", payload)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.Client.AssignIssue(c.ctx(), issue, username)
 		if err != nil {
 			return nil, fmt.Errorf("failed to reassign issue %s to %s: %w", issue, username, err)
 		}
+
+		c.runPostHook("post-reassign", payload)
+		c.remember(payload.Key)
 	}
 
 	return issues, nil
 }
+
+// PoolAssignment is who ReassignRoundRobin gave an issue to
+type PoolAssignment struct {
+	Key      string
+	Assignee string
+}
+
+// ReassignRoundRobin gives every issue to the next user of the
+// "triagePool", the turn is kept in the state so the next call carries on
+// where this one stopped. The turns are taken before anything is assigned,
+// so two calls at the same time don't pick the same users.
+func (c *Command) ReassignRoundRobin(issues []string, force bool) ([]PoolAssignment, error) {
+	pool := c.Config.TriagePool
+	if len(pool) == 0 {
+		return nil, errors.New("there is nobody to take turns with, add the users to \"triagePool\" in the config")
+	}
+	if c.State == nil {
+		return nil, errors.New("--round-robin needs the state file to keep track of whose turn it is")
+	}
+
+	err := c.RequirePermission(jiwa.PermissionAssignIssues, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	var next int
+	err = c.State.Update(func(st *state.State) error {
+		next = st.TriagePoolNext % len(pool)
+		st.TriagePoolNext = (next + len(issues)) % len(pool)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to take turns in the triage pool: %w", err)
+	}
+
+	assigned := make([]PoolAssignment, 0, len(issues))
+	for i, issue := range issues {
+		user := pool[(next+i)%len(pool)]
+		_, err = c.Reassign([]string{issue}, user, force)
+		if err != nil {
+			return assigned, err
+		}
+		assigned = append(assigned, PoolAssignment{Key: issue, Assignee: user})
+	}
+
+	return assigned, nil
+}
+
+// CheckAssignable checks that the user can be assigned issues in the
+// projects of the issues, once per project
+func (c *Command) CheckAssignable(issues []string, username string) error {
+	checked := make(map[string]bool)
+	for _, issue := range issues {
+		project := projectOf(issue)
+		if checked[project] {
+			continue
+		}
+		checked[project] = true
+
+		err := c.checkAssignable(issue, username)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAssignable searches the users that can be assigned the issue for
+// the username, the check is skipped if Jira can't be reached so the
+// change can still be queued.
+func (c *Command) checkAssignable(issue, username string) error {
+	project := projectOf(issue)
+	users, err := c.Client.SearchAssignableUsers(c.ctx(), jiwa.AssignableUsersInput{IssueKey: issue, Query: username})
+	switch {
+	case offline.IsUnreachable(err):
+		fmt.Fprintf(os.Stderr, "warning: skipped checking whether %s can be assigned issues in %s, Jira can't be reached\n", username, project)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check whether %s can be assigned %s: %w", username, issue, err)
+	}
+
+	return findAssignable(username, project, users)
+}
+
+// findAssignable looks for the username among the users the assignable
+// search found for it, the rest of them are offered as close matches.
+func findAssignable(username, project string, users []jira.User) error {
+	for _, u := range users {
+		if strings.EqualFold(u.Name, username) || (u.AccountID != "" && u.AccountID == username) {
+			return nil
+		}
+	}
+
+	msg := fmt.Sprintf("%s can't be assigned issues in %s", username, project)
+	if len(users) == 0 {
+		return fmt.Errorf("%s, no assignable user matches %q, pass --force to try anyway", msg, username)
+	}
+
+	labels := make([]string, 0, len(users))
+	for _, u := range users[:min(len(users), maxAssignableSuggestions)] {
+		labels = append(labels, userLabel(u))
+	}
+
+	return fmt.Errorf("%s, did you mean one of: %s", msg, strings.Join(labels, "; "))
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Reassign(t *testing.T) {
+	testData := []struct {
+		Name           string
+		InIssues       []string
+		InUser         string
+		InForce        bool
+		OutAssignee    string
+		OutSearches    int
+		OutAssignments int
+		OutErrMsg      string
+	}{
+		{
+			Name:           "Assignable",
+			InIssues:       []string{"JIWA-1"},
+			InUser:         "alice",
+			OutAssignee:    "alice",
+			OutSearches:    1,
+			OutAssignments: 1,
+		},
+		{
+			Name:           "SearchedOncePerProject",
+			InIssues:       []string{"JIWA-1", "JIWA-2", "OPS-1"},
+			InUser:         "alice",
+			OutAssignee:    "alice",
+			OutSearches:    2,
+			OutAssignments: 3,
+		},
+		{
+			Name:        "CloseMatches",
+			InIssues:    []string{"JIWA-1"},
+			InUser:      "ali",
+			OutSearches: 1,
+			OutErrMsg:   "ali can't be assigned issues in JIWA, did you mean one of: Alice Liddell (alice, alice@example.com)",
+		},
+		{
+			Name:        "NoMatches",
+			InIssues:    []string{"JIWA-1"},
+			InUser:      "carol",
+			OutSearches: 1,
+			OutErrMsg:   `carol can't be assigned issues in JIWA, no assignable user matches "carol", pass --force to try anyway`,
+		},
+		{
+			Name:           "ForceSkipsTheCheck",
+			InIssues:       []string{"JIWA-1", "JIWA-2"},
+			InUser:         "carol",
+			InForce:        true,
+			OutAssignee:    "carol",
+			OutAssignments: 2,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			for _, key := range []string{"JIWA-1", "JIWA-2", "OPS-1"} {
+				srv.AddIssue(jira.Issue{Key: key})
+			}
+			srv.AddUser(jira.User{Name: "alice", DisplayName: "Alice Liddell", EmailAddress: "alice@example.com"})
+
+			client, err := jiwa.NewClient(jiwa.Config{BaseURL: srv.URL, Username: srv.Username, Password: srv.Password})
+			assert.NoError(t, err)
+
+			c := Command{Client: client}
+			_, err = c.Reassign(td.InIssues, td.InUser, td.InForce)
+
+			var searches, assignments int
+			for _, r := range srv.Requests() {
+				switch {
+				case strings.HasSuffix(r.Path, "/user/assignable/search"):
+					searches++
+				case r.Method == http.MethodPut:
+					assignments++
+				}
+			}
+			assert.Equal(t, td.OutSearches, searches)
+			assert.Equal(t, td.OutAssignments, assignments)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			issue, _ := srv.Issue(td.InIssues[0])
+			assert.Equal(t, td.OutAssignee, issue.Fields.Assignee.Name)
+		})
+	}
+}
+
+func TestCommand_ReassignRoundRobin(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InPool       []string
+		InNext       int
+		InCalls      [][]string
+		OutAssignees map[string]string
+		OutNext      int
+		OutErrMsg    string
+	}{
+		{
+			Name:         "AdvancesBetweenCalls",
+			InPool:       []string{"alice", "bob", "carol"},
+			InCalls:      [][]string{{"JIWA-1"}, {"JIWA-2"}},
+			OutAssignees: map[string]string{"JIWA-1": "alice", "JIWA-2": "bob"},
+			OutNext:      2,
+		},
+		{
+			Name:         "Wraps",
+			InPool:       []string{"alice", "bob", "carol"},
+			InNext:       2,
+			InCalls:      [][]string{{"JIWA-1", "JIWA-2"}, {"JIWA-3"}},
+			OutAssignees: map[string]string{"JIWA-1": "carol", "JIWA-2": "alice", "JIWA-3": "bob"},
+			OutNext:      2,
+		},
+		{
+			Name:         "PoolGotSmaller",
+			InPool:       []string{"alice", "bob"},
+			InNext:       5,
+			InCalls:      [][]string{{"JIWA-1"}},
+			OutAssignees: map[string]string{"JIWA-1": "bob"},
+			OutNext:      0,
+		},
+		{
+			Name:      "EmptyPool",
+			InCalls:   [][]string{{"JIWA-1"}},
+			OutErrMsg: `there is nobody to take turns with, add the users to "triagePool" in the config`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			for _, key := range []string{"JIWA-1", "JIWA-2", "JIWA-3"} {
+				fake.Issues[key] = jira.Issue{Key: key, Fields: &jira.IssueFields{}}
+			}
+			for _, name := range td.InPool {
+				fake.AssignableUsers = append(fake.AssignableUsers, jira.User{Name: name})
+			}
+
+			store := &state.Store{Path: filepath.Join(t.TempDir(), "state.json")}
+			err := store.Update(func(st *state.State) error {
+				st.TriagePoolNext = td.InNext
+				return nil
+			})
+			assert.NoError(t, err)
+			c := Command{Client: fake, State: store, Config: Config{TriagePool: td.InPool}}
+
+			for _, issues := range td.InCalls {
+				_, err = c.ReassignRoundRobin(issues, false)
+				if err != nil {
+					break
+				}
+			}
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			assignees := make(map[string]string)
+			for key, issue := range fake.Issues {
+				if issue.Fields.Assignee != nil {
+					assignees[key] = issue.Fields.Assignee.Name
+				}
+			}
+			assert.Equal(t, td.OutAssignees, assignees)
+
+			st, err := store.Load()
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutNext, st.TriagePoolNext)
+		})
+	}
+}
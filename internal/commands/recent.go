@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/catouc/jiwa/internal/state"
+)
+
+// ParseIssueArg turns whatever the user passed as an issue into its key,
+// that can be a key, a browse URL or a reference like @last.
+func (c *Command) ParseIssueArg(arg string) (string, error) {
+	if state.IsRef(arg) {
+		if c.State == nil {
+			return "", fmt.Errorf("cannot resolve %q without a state file", arg)
+		}
+
+		st, err := c.State.Load()
+		if err != nil {
+			return "", err
+		}
+
+		return st.Resolve(arg)
+	}
+
+	return c.normalizeIssueKey(arg)
+}
+
+// Recent returns up to n of the last issues jiwa acted on, most recent first
+func (c *Command) Recent(n int) ([]state.Entry, error) {
+	if c.State == nil {
+		return nil, errors.New("no state file configured")
+	}
+
+	st, err := c.State.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && n < len(st.Recent) {
+		return st.Recent[:n], nil
+	}
+
+	return st.Recent, nil
+}
+
+// remember records the issues as the most recently used ones, in order,
+// so the last one becomes @last. Failing to do so is not worth failing
+// the command over.
+func (c *Command) remember(keys ...string) {
+	for _, k := range keys {
+		c.rememberIssue(k, "")
+	}
+}
+
+func (c *Command) rememberIssue(key, summary string) {
+	if c.State == nil || key == "" {
+		return
+	}
+
+	err := c.State.Update(func(st *state.State) error {
+		st.Push(key, summary)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record %s as recent issue: %s\n", key, err)
+	}
+}
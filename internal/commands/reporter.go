@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// reporterField looks up the user create --reporter names like a mention
+// in the project, users that can be assigned there first, and returns the
+// value of the reporter field: the account ID on Cloud and the username
+// on Server. "@me" or "me" needs no field, Jira makes whoever creates the issue
+// its reporter.
+func (c *Command) reporterField(project, reporter string) (map[string]string, error) {
+	if IsMe(reporter) {
+		return nil, nil
+	}
+
+	err := c.RequireProjectPermission(jiwa.PermissionModifyReporter, project)
+	if err != nil {
+		return nil, fmt.Errorf("filing on behalf of %s: %w", reporter, err)
+	}
+
+	users, err := c.mentionCandidates(c.ctx(), mentionScope{Project: project}, reporter)
+	if err != nil {
+		return nil, err
+	}
+	candidates := mentionCandidatesFor(reporter, users)
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no user found for the reporter %q", reporter)
+	case 1:
+	default:
+		labels := make([]string, 0, len(candidates))
+		for _, u := range candidates {
+			labels = append(labels, userLabel(u))
+		}
+		return nil, fmt.Errorf("the reporter %q matches several users, be more specific: %s", reporter, strings.Join(labels, "; "))
+	}
+
+	return c.userField(candidates[0])
+}
+
+// userField is how a user field like the reporter or the assignee points at
+// the user, by account ID on Cloud and by username on Server
+func (c *Command) userField(u jira.User) (map[string]string, error) {
+	info, err := c.Client.ServerInfo(c.ctx())
+	if err != nil {
+		return nil, err
+	}
+	if info.IsCloud() {
+		return map[string]string{"accountId": u.AccountID}, nil
+	}
+
+	return map[string]string{"name": u.Name}, nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_ReporterField(t *testing.T) {
+	users := []jira.User{
+		{Name: "alice", AccountID: "5b10a2844c20165700ede21g", DisplayName: "Alice Smith"},
+		{Name: "alan", AccountID: "5b10ac8d82e05b22cc7d4ef5", DisplayName: "Alan Smith"},
+	}
+
+	testData := []struct {
+		Name         string
+		InDeployment string
+		InReporter   string
+		InDenied     []string
+		OutField     map[string]string
+		OutErrMsg    string
+	}{
+		{
+			Name:         "CloudByAccountID",
+			InDeployment: "Cloud",
+			InReporter:   "alice",
+			OutField:     map[string]string{"accountId": "5b10a2844c20165700ede21g"},
+		},
+		{
+			Name:         "ServerByName",
+			InDeployment: "Server",
+			InReporter:   "Alan Smith",
+			OutField:     map[string]string{"name": "alan"},
+		},
+		{
+			Name:       "MeIsTheDefault",
+			InReporter: "@me",
+			InDenied:   []string{jiwa.PermissionModifyReporter},
+		},
+		{
+			Name:       "MeWithoutTheAt",
+			InReporter: "me",
+			InDenied:   []string{jiwa.PermissionModifyReporter},
+		},
+		{
+			Name:       "Ambiguous",
+			InReporter: "al",
+			OutErrMsg:  `the reporter "al" matches several users, be more specific: Alice Smith (alice); Alan Smith (alan)`,
+		},
+		{
+			Name:       "Unknown",
+			InReporter: "carol",
+			OutErrMsg:  `no user found for the reporter "carol"`,
+		},
+		{
+			Name:       "MissingPermission",
+			InReporter: "alice",
+			InDenied:   []string{jiwa.PermissionModifyReporter},
+			OutErrMsg:  "filing on behalf of alice: you lack 'Modify Reporter' in project JIWA",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Info = jiwa.ServerInfo{DeploymentType: td.InDeployment}
+			fake.Users = users
+			fake.DeniedPermissions = td.InDenied
+			c := Command{Client: fake}
+
+			field, err := c.reporterField("JIWA", td.InReporter)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutField, field)
+		})
+	}
+}
@@ -5,13 +5,47 @@ import (
 	"fmt"
 
 	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
 )
 
 func (c *Command) Search(jqlQuery string) ([]jira.Issue, error) {
-	issues, err := c.Client.Search(context.TODO(), jqlQuery)
+	issues, err := c.Client.Search(c.ctx(), jqlQuery)
 	if err != nil {
 		return nil, fmt.Errorf("could not search issues: %w", err)
 	}
 
 	return issues, nil
 }
+
+// SearchPages is Search for large results, fn gets every page of issues as
+// soon as it arrives. Only the given fields are fetched, all of them if
+// there are none.
+func (c *Command) SearchPages(ctx context.Context, jqlQuery string, fn func(page []jira.Issue) error, fields []string) error {
+	err := c.Client.SearchPages(ctx, jqlQuery, fn, fieldsOption(fields)...)
+	if err != nil {
+		return fmt.Errorf("could not search issues: %w", err)
+	}
+
+	return nil
+}
+
+// Count returns the number of issues matching the query, none of them are
+// fetched.
+func (c *Command) Count(jqlQuery string) (int, error) {
+	n, err := c.Client.Count(c.ctx(), jqlQuery)
+	if err != nil {
+		return 0, fmt.Errorf("could not count issues: %w", err)
+	}
+
+	return n, nil
+}
+
+// fieldsOption narrows a search down to the fields, no fields means all
+// of them
+func fieldsOption(fields []string) []jiwa.GetIssueOption {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return []jiwa.GetIssueOption{jiwa.WithFields(fields...)}
+}
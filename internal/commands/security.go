@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/offline"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// SecurityLevelOf returns the name of the security level of the issue,
+// empty if it has none or the field wasn't fetched
+func SecurityLevelOf(issue jira.Issue) string {
+	if issue.Fields == nil {
+		return ""
+	}
+
+	level, _ := issue.Fields.Unknowns["security"].(map[string]any)
+	name, _ := level["name"].(string)
+
+	return name
+}
+
+// securityLevel looks up the level of the project by name ignoring case,
+// an unknown name fails with the ones the project has
+func (c *Command) securityLevel(project, name string) (jiwa.SecurityLevel, error) {
+	levels, err := c.Client.ListSecurityLevels(c.ctx(), project)
+	if err != nil {
+		return jiwa.SecurityLevel{}, err
+	}
+	if len(levels) == 0 {
+		return jiwa.SecurityLevel{}, fmt.Errorf("project %s has no security levels you can set", project)
+	}
+
+	names := make([]string, 0, len(levels))
+	for _, l := range levels {
+		if strings.EqualFold(l.Name, name) {
+			return l, nil
+		}
+		names = append(names, l.Name)
+	}
+
+	return jiwa.SecurityLevel{}, fmt.Errorf("project %s has no security level %q, it has %s", project, name, strings.Join(names, ", "))
+}
+
+// ParseVisibility reads the argument of comment --visible-to, either
+// "role:<name>" or "group:<name>"
+func ParseVisibility(s string) (jira.CommentVisibility, error) {
+	kind, name, _ := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if (kind != "role" && kind != "group") || name == "" {
+		return jira.CommentVisibility{}, fmt.Errorf("--visible-to takes role:<name> or group:<name>, not %q", s)
+	}
+
+	return jira.CommentVisibility{Type: kind, Value: name}, nil
+}
+
+// CheckVisibility fails if the role VisibleTo restricts comments to isn't
+// a role of the project of every issue, listing the ones it has, and
+// fixes the case of the name. Groups are left to Jira, listing them takes
+// more than most users may do. Like RequirePermission queued issues and
+// instances that can't be reached are let through.
+func (c *Command) CheckVisibility(issues ...string) error {
+	if c.VisibleTo == nil || c.VisibleTo.Type != "role" {
+		return nil
+	}
+
+	for _, key := range issues {
+		if strings.HasPrefix(key, offline.PlaceholderPrefix) {
+			continue
+		}
+
+		project := projectOf(key)
+		roles, ok := c.roles[project]
+		if !ok {
+			var err error
+			roles, err = c.Client.ListProjectRoles(c.ctx(), project)
+			switch {
+			case offline.IsUnreachable(err):
+				return nil
+			case err != nil:
+				return err
+			}
+
+			if c.roles == nil {
+				c.roles = make(map[string][]string)
+			}
+			c.roles[project] = roles
+		}
+
+		found := false
+		for _, r := range roles {
+			if strings.EqualFold(r, c.VisibleTo.Value) {
+				c.VisibleTo.Value = r
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("project %s has no role %q, it has %s", project, c.VisibleTo.Value, strings.Join(roles, ", "))
+		}
+	}
+
+	return nil
+}
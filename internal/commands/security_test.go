@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Create_Security(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InProject  string
+		InSecurity string
+		OutLevelID string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "Level",
+			InProject:  "JIWA",
+			InSecurity: "internal",
+			OutLevelID: "10101",
+		},
+		{
+			Name:       "UnknownLevel",
+			InProject:  "JIWA",
+			InSecurity: "Secret",
+			OutErrMsg:  `project JIWA has no security level "Secret", it has Public, Internal`,
+		},
+		{
+			Name:       "NoSecurityScheme",
+			InProject:  "OPS",
+			InSecurity: "Internal",
+			OutErrMsg:  "project OPS has no security levels you can set",
+		},
+		{
+			Name:      "NoLevel",
+			InProject: "JIWA",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.SecurityLevels = map[string][]jiwa.SecurityLevel{
+				"JIWA": {{ID: "10100", Name: "Public"}, {ID: "10101", Name: "Internal"}},
+			}
+			c := Command{Client: fake, Config: Config{DisableDuplicateCheck: true}}
+
+			key, err := c.Create(CreateInput{Project: td.InProject, Summary: "Leaked key", Security: td.InSecurity})
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Empty(t, fake.Issues, "nothing is created")
+				return
+			}
+			assert.NoError(t, err)
+
+			security, ok := fake.Issues[key].Fields.Unknowns["security"]
+			if td.OutLevelID == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.Equal(t, map[string]string{"id": td.OutLevelID}, security)
+		})
+	}
+}
+
+func TestSecurityLevelOf(t *testing.T) {
+	issue := jira.Issue{Fields: &jira.IssueFields{Unknowns: map[string]any{
+		"security": map[string]any{"id": "10101", "name": "Internal"},
+	}}}
+	assert.Equal(t, "Internal", SecurityLevelOf(issue))
+
+	// Jira sends null for issues without a level
+	issue.Fields.Unknowns["security"] = nil
+	assert.Empty(t, SecurityLevelOf(issue))
+	assert.Empty(t, SecurityLevelOf(jira.Issue{}))
+}
+
+func TestParseVisibility(t *testing.T) {
+	testData := []struct {
+		Name          string
+		In            string
+		OutVisibility jira.CommentVisibility
+		OutErrMsg     string
+	}{
+		{
+			Name:          "Role",
+			In:            "role:Developers",
+			OutVisibility: jira.CommentVisibility{Type: "role", Value: "Developers"},
+		},
+		{
+			Name:          "GroupWithColon",
+			In:            "group:team:security",
+			OutVisibility: jira.CommentVisibility{Type: "group", Value: "team:security"},
+		},
+		{
+			Name:      "NoType",
+			In:        "Developers",
+			OutErrMsg: `--visible-to takes role:<name> or group:<name>, not "Developers"`,
+		},
+		{
+			Name:      "NoName",
+			In:        "role:",
+			OutErrMsg: `--visible-to takes role:<name> or group:<name>, not "role:"`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			visibility, err := ParseVisibility(td.In)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutVisibility, visibility)
+		})
+	}
+}
+
+func TestCommand_Comment_VisibleTo(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InVisibleTo   jira.CommentVisibility
+		OutVisibility jira.CommentVisibility
+		OutErrMsg     string
+	}{
+		{
+			Name:          "Role",
+			InVisibleTo:   jira.CommentVisibility{Type: "role", Value: "developers"},
+			OutVisibility: jira.CommentVisibility{Type: "role", Value: "Developers"},
+		},
+		{
+			Name:        "UnknownRole",
+			InVisibleTo: jira.CommentVisibility{Type: "role", Value: "Security"},
+			OutErrMsg:   `project JIWA has no role "Security", it has Administrators, Developers`,
+		},
+		{
+			Name:          "Group",
+			InVisibleTo:   jira.CommentVisibility{Type: "group", Value: "jira-admins"},
+			OutVisibility: jira.CommentVisibility{Type: "group", Value: "jira-admins"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Leaked key"}}
+			fake.Roles = map[string][]string{"JIWA": {"Developers", "Administrators"}}
+
+			c := Command{Client: fake, VisibleTo: &td.InVisibleTo}
+			_, err := c.Comment([]string{"JIWA-1"}, "the key is in the vault")
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Nil(t, fake.Issues["JIWA-1"].Fields.Comments)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutVisibility, fake.Issues["JIWA-1"].Fields.Comments.Comments[0].Visibility)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// ShowDetail is how much of an issue show prints
+type ShowDetail string
+
+const (
+	// ShowShort prints a single line with ShortShowFields
+	ShowShort ShowDetail = "short"
+	// ShowDefault prints the view fields and the description
+	ShowDefault ShowDetail = "default"
+	// ShowFull adds FullShowFields and the latest FullShowChanges changes
+	// to the default
+	ShowFull ShowDetail = "full"
+)
+
+// ShortShowFields are the fields of the line show --short prints, next to
+// the key
+var ShortShowFields = []string{"status", "assignee", "summary"}
+
+// FullShowFields are fetched on top of the view fields by show --full:
+// the comments, links, sub-tasks and the metadata of the attachments
+var FullShowFields = []string{"comment", "issuelinks", "subtasks", "attachment"}
+
+// FullShowChanges is how many of the latest changes show --full prints
+const FullShowChanges = 5
+
+// ParseShowDetail checks the level "showDetail" or the flags of show ask
+// for, an empty one is ShowDefault
+func ParseShowDetail(detail string) (ShowDetail, error) {
+	switch d := ShowDetail(detail); d {
+	case "":
+		return ShowDefault, nil
+	case ShowShort, ShowDefault, ShowFull:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown detail %q, use \"short\", \"default\" or \"full\"", detail)
+	}
+}
+
+// WithLatestChanges calls fetch and gets the latest FullShowChanges changes
+// of the issue at the same time, show --full needs both and the changelog
+// is a request of its own. The changes are oldest first.
+func (c *Command) WithLatestChanges(issueID string, fetch func() error) ([]jira.ChangelogHistory, error) {
+	var changes []jira.ChangelogHistory
+	err := c.parallel(2, func(ctx context.Context, i int) error {
+		if i == 0 {
+			return fetch()
+		}
+
+		issue, err := c.Client.GetIssue(ctx, issueID, jiwa.WithFields("summary"), jiwa.WithExpand("changelog"))
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+		if issue.Changelog != nil {
+			changes = issue.Changelog.Histories
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changes) > FullShowChanges {
+		changes = changes[len(changes)-FullShowChanges:]
+	}
+
+	return changes, nil
+}
+
+// FullDetail is what show --full prints on top of the default, its JSON
+// output carries the same
+type FullDetail struct {
+	Comments    []ShowComment    `json:"comments"`
+	Links       []IssueLink      `json:"links"`
+	Subtasks    []ShowSubtask    `json:"subtasks"`
+	Attachments []ShowAttachment `json:"attachments"`
+	Changes     []ShowChange     `json:"changes"`
+}
+
+// ShowComment is a comment with the wiki markup it is stored as
+type ShowComment struct {
+	Author  string    `json:"author"`
+	Created time.Time `json:"created"`
+	Body    string    `json:"body"`
+}
+
+// ShowSubtask is a sub-task of the issue
+type ShowSubtask struct {
+	Key     string `json:"key"`
+	Summary string `json:"summary"`
+	Status  string `json:"status"`
+}
+
+// ShowAttachment describes an attachment, its content isn't fetched
+type ShowAttachment struct {
+	Filename string    `json:"filename"`
+	Size     int       `json:"size"`
+	MimeType string    `json:"mimeType"`
+	Author   string    `json:"author"`
+	Created  time.Time `json:"created"`
+}
+
+// ShowChange is an entry of the changelog with every field it changed
+type ShowChange struct {
+	Author  string           `json:"author"`
+	Created time.Time        `json:"created"`
+	Items   []ShowChangeItem `json:"items"`
+}
+
+// ShowChangeItem is one field of a change, From and To as Jira shows them
+type ShowChangeItem struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// NewFullDetail collects what show --full prints from the issue, fetched
+// with FullShowFields, and the changes WithLatestChanges got. Timestamps
+// are in loc.
+func NewFullDetail(issue jira.Issue, changes []jira.ChangelogHistory, loc *time.Location) FullDetail {
+	d := FullDetail{
+		Comments:    make([]ShowComment, 0),
+		Links:       make([]IssueLink, 0),
+		Subtasks:    make([]ShowSubtask, 0),
+		Attachments: make([]ShowAttachment, 0),
+		Changes:     make([]ShowChange, 0, len(changes)),
+	}
+
+	for _, h := range changes {
+		change := ShowChange{Author: userName(h.Author), Created: inLocation(h.Created, loc), Items: make([]ShowChangeItem, 0, len(h.Items))}
+		for _, item := range h.Items {
+			change.Items = append(change.Items, ShowChangeItem{Field: item.Field, From: item.FromString, To: item.ToString})
+		}
+		d.Changes = append(d.Changes, change)
+	}
+
+	f := issue.Fields
+	if f == nil {
+		return d
+	}
+
+	if f.Comments != nil {
+		for _, c := range f.Comments.Comments {
+			d.Comments = append(d.Comments, ShowComment{Author: userName(c.Author), Created: inLocation(c.Created, loc), Body: c.Body})
+		}
+	}
+	d.Links = issueLinks(f.IssueLinks)
+	for _, s := range f.Subtasks {
+		if s == nil {
+			continue
+		}
+		subtask := ShowSubtask{Key: s.Key, Summary: s.Fields.Summary}
+		if s.Fields.Status != nil {
+			subtask.Status = s.Fields.Status.Name
+		}
+		d.Subtasks = append(d.Subtasks, subtask)
+	}
+	for _, a := range f.Attachments {
+		if a == nil {
+			continue
+		}
+		attachment := ShowAttachment{Filename: a.Filename, Size: a.Size, MimeType: a.MimeType, Created: inLocation(a.Created, loc)}
+		if a.Author != nil {
+			attachment.Author = userName(*a.Author)
+		}
+		d.Attachments = append(d.Attachments, attachment)
+	}
+
+	return d
+}
+
+// inLocation parses a timestamp of Jira's and moves it to loc
+func inLocation(s string, loc *time.Location) time.Time {
+	t := parseJiraTime(s)
+	if t.IsZero() {
+		return t
+	}
+
+	return t.In(loc)
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseShowDetail(t *testing.T) {
+	testData := []struct {
+		Name      string
+		In        string
+		Out       ShowDetail
+		OutErrMsg string
+	}{
+		{Name: "Empty", In: "", Out: ShowDefault},
+		{Name: "Short", In: "short", Out: ShowShort},
+		{Name: "Full", In: "full", Out: ShowFull},
+		{Name: "Unknown", In: "long", OutErrMsg: `unknown detail "long", use "short", "default" or "full"`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			detail, err := ParseShowDetail(td.In)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.Out, detail)
+		})
+	}
+}
+
+func TestCommand_WithLatestChanges(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InFetchErr error
+		InGetErr   error
+		OutFields  []string
+		OutErrMsg  string
+	}{
+		{
+			Name:      "KeepsTheLatest",
+			OutFields: []string{"f2", "f3", "f4", "f5", "f6"},
+		},
+		{
+			Name:       "FetchFails",
+			InFetchErr: errors.New("no permission"),
+			OutErrMsg:  "no permission",
+		},
+		{
+			Name:      "ChangelogFails",
+			InGetErr:  errors.New("timeout"),
+			OutErrMsg: "failed to get history: timeout",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			histories := make([]jira.ChangelogHistory, 0)
+			for i := 0; i < 7; i++ {
+				histories = append(histories, change("2024-05-02T13:00:00.000+0000", alice, fmt.Sprintf("f%d", i), "", ""))
+			}
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = issueWithActivity("JIWA-1", histories)
+			if td.InGetErr != nil {
+				fake.Errors["GetIssue"] = td.InGetErr
+			}
+			c := Command{Client: fake}
+
+			fetched := false
+			changes, err := c.WithLatestChanges("JIWA-1", func() error {
+				fetched = true
+				return td.InFetchErr
+			})
+
+			assert.True(t, fetched)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			fields := make([]string, 0, len(changes))
+			for _, c := range changes {
+				fields = append(fields, c.Items[0].Field)
+			}
+			assert.Equal(t, td.OutFields, fields)
+		})
+	}
+}
+
+func TestNewFullDetail(t *testing.T) {
+	issue := jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+		Comments: &jira.Comments{Comments: []*jira.Comment{{Author: bob, Body: "*done*", Created: "2024-05-02T13:50:00.000+0000"}}},
+		IssueLinks: []*jira.IssueLink{{
+			ID:           "10001",
+			Type:         jira.IssueLinkType{Name: "Blocks", Outward: "blocks"},
+			OutwardIssue: &jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Migrate", Status: &jira.Status{Name: "Done"}}},
+		}},
+		Subtasks:    []*jira.Subtasks{{Key: "JIWA-3", Fields: jira.IssueFields{Summary: "Write the docs", Status: &jira.Status{Name: "To Do"}}}, nil},
+		Attachments: []*jira.Attachment{{Filename: "trace.log", Size: 2048, MimeType: "text/plain", Author: &alice, Created: "2024-05-01T08:00:00.000+0000"}, nil},
+	}}
+	changes := []jira.ChangelogHistory{change("2024-05-02T13:00:00.000+0000", alice, "status", "To Do", "Done")}
+	loc := time.FixedZone("CEST", 2*60*60)
+
+	d := NewFullDetail(issue, changes, loc)
+
+	assert.Equal(t, FullDetail{
+		Comments:    []ShowComment{{Author: "Bob", Created: time.Date(2024, 5, 2, 15, 50, 0, 0, loc), Body: "*done*"}},
+		Links:       []IssueLink{{ID: "10001", Type: "Blocks", Description: "blocks", Key: "JIWA-2", Summary: "Migrate", Status: "Done"}},
+		Subtasks:    []ShowSubtask{{Key: "JIWA-3", Summary: "Write the docs", Status: "To Do"}},
+		Attachments: []ShowAttachment{{Filename: "trace.log", Size: 2048, MimeType: "text/plain", Author: "Alice", Created: time.Date(2024, 5, 1, 10, 0, 0, 0, loc)}},
+		Changes:     []ShowChange{{Author: "Alice", Created: time.Date(2024, 5, 2, 15, 0, 0, 0, loc), Items: []ShowChangeItem{{Field: "status", From: "To Do", To: "Done"}}}},
+	}, d)
+
+	empty := NewFullDetail(jira.Issue{Key: "JIWA-4"}, nil, loc)
+	assert.Equal(t, FullDetail{
+		Comments:    []ShowComment{},
+		Links:       []IssueLink{},
+		Subtasks:    []ShowSubtask{},
+		Attachments: []ShowAttachment{},
+		Changes:     []ShowChange{},
+	}, empty)
+}
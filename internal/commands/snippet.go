@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// Snippet is a canned comment, Source is "config" or the file it was read
+// from
+type Snippet struct {
+	Name   string
+	Source string
+	Text   string
+}
+
+// SnippetData is what the placeholders of a snippet are filled in from
+type SnippetData struct {
+	Key      string
+	Summary  string
+	Assignee string
+	Reporter string
+}
+
+// snippetFields are the placeholders a snippet can use, in the order they
+// are listed in errors
+var snippetFields = []string{"Key", "Summary", "Assignee", "Reporter"}
+
+// Snippets returns the snippets from "snippets" in the config and the
+// files in the SnippetDir, sorted by name. A file named like a snippet in
+// the config is ignored, the config wins.
+func (c *Command) Snippets() ([]Snippet, error) {
+	snippets := make([]Snippet, 0, len(c.Config.Snippets))
+	for name, text := range c.Config.Snippets {
+		snippets = append(snippets, Snippet{Name: name, Source: "config", Text: text})
+	}
+
+	if c.SnippetDir != "" {
+		entries, err := os.ReadDir(c.SnippetDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read snippets: %w", err)
+		}
+
+		for _, e := range entries {
+			_, inConfig := c.Config.Snippets[e.Name()]
+			if e.IsDir() || inConfig || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+
+			path := filepath.Join(c.SnippetDir, e.Name())
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read snippet: %w", err)
+			}
+			snippets = append(snippets, Snippet{Name: e.Name(), Source: path, Text: strings.TrimRight(string(b), "\n")})
+		}
+	}
+
+	sort.Slice(snippets, func(i, j int) bool {
+		return snippets[i].Name < snippets[j].Name
+	})
+
+	return snippets, nil
+}
+
+// Snippet finds a snippet by its name
+func (c *Command) Snippet(name string) (Snippet, error) {
+	snippets, err := c.Snippets()
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	names := make([]string, 0, len(snippets))
+	for _, s := range snippets {
+		if s.Name == name {
+			return s, nil
+		}
+		names = append(names, s.Name)
+	}
+
+	if len(names) == 0 {
+		return Snippet{}, fmt.Errorf("there is no snippet %q, add it to \"snippets\" in the config or put it in %s", name, filepath.Join(c.SnippetDir, name))
+	}
+
+	msg := fmt.Sprintf("there is no snippet %q", name)
+	if s := Suggest(name, names); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+	return Snippet{}, fmt.Errorf("%s, snippets are %s", msg, strings.Join(names, ", "))
+}
+
+// CommentSnippet comments the snippet on every issue, with its
+// placeholders filled in from that issue and extra added as a paragraph of
+// its own. All comments are filled in before the first one is posted, so a
+// placeholder an issue can't fill in doesn't leave some issues commented.
+func (c *Command) CommentSnippet(issues []string, name, extra string) ([]string, error) {
+	err := c.RequirePermission(jiwa.PermissionAddComments, issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.CheckVisibility(issues...)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet, err := c.Snippet(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := parseSnippet(snippet)
+	if err != nil {
+		return nil, err
+	}
+	fields := snippetPlaceholders(tmpl.Tree.Root)
+
+	texts := make([]string, 0, len(issues))
+	for _, key := range issues {
+		text, err := c.fillSnippet(tmpl, fields, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if extra = strings.TrimSpace(extra); extra != "" {
+			text = strings.TrimRight(text, "\n") + "\n\n" + extra
+		}
+		texts = append(texts, text)
+	}
+
+	for i, key := range issues {
+		err = c.comment(key, texts[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return issues, nil
+}
+
+// parseSnippet parses the text of the snippet and makes sure it only uses
+// placeholders that exist
+func parseSnippet(s Snippet) (*template.Template, error) {
+	tmpl, err := template.New(s.Name).Option("missingkey=error").Parse(s.Text)
+	if err != nil {
+		return nil, fmt.Errorf("snippet %q is broken: %w", s.Name, err)
+	}
+
+	for _, f := range snippetPlaceholders(tmpl.Tree.Root) {
+		if !slices.Contains(snippetFields, f) {
+			return nil, fmt.Errorf("snippet %q uses {{.%s}} which doesn't exist, placeholders are {{.%s}}", s.Name, f, strings.Join(snippetFields, "}}, {{."))
+		}
+	}
+
+	return tmpl, nil
+}
+
+// fillSnippet fetches the issue if the snippet has placeholders and fails
+// if one of them would come out empty, e.g. {{.Assignee}} on an
+// unassigned issue
+func (c *Command) fillSnippet(tmpl *template.Template, fields []string, key string) (string, error) {
+	data := SnippetData{Key: key}
+	needsIssue := slices.ContainsFunc(fields, func(f string) bool { return f != "Key" })
+	if needsIssue {
+		issue, err := c.Client.GetIssue(c.ctx(), key, jiwa.WithFields("summary", "assignee", "reporter"))
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s to fill in the snippet: %w", key, err)
+		}
+		data = snippetData(issue)
+		data.Key = key
+	}
+
+	values := map[string]string{
+		"Key":      data.Key,
+		"Summary":  data.Summary,
+		"Assignee": data.Assignee,
+		"Reporter": data.Reporter,
+	}
+	for _, f := range fields {
+		if values[f] == "" {
+			return "", fmt.Errorf("%s has no %s to fill in {{.%s}}, nothing was commented", key, strings.ToLower(f), f)
+		}
+	}
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to fill in the snippet for %s: %w", key, err)
+	}
+
+	return b.String(), nil
+}
+
+func snippetData(issue jira.Issue) SnippetData {
+	data := SnippetData{Key: issue.Key}
+	if issue.Fields == nil {
+		return data
+	}
+
+	data.Summary = issue.Fields.Summary
+	if issue.Fields.Assignee != nil {
+		data.Assignee = userName(*issue.Fields.Assignee)
+	}
+	if issue.Fields.Reporter != nil {
+		data.Reporter = userName(*issue.Fields.Reporter)
+	}
+
+	return data
+}
+
+// snippetPlaceholders returns the fields the template refers to, like
+// "Assignee" for {{.Assignee}}, in the order they first show up
+func snippetPlaceholders(node parse.Node) []string {
+	fields := make([]string, 0)
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) != 0 && !slices.Contains(fields, n.Ident[0]) {
+				fields = append(fields, n.Ident[0])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+
+	return fields
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_CommentSnippet(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InSnippets  map[string]string
+		InFiles     map[string]string
+		InIssues    []string
+		InSnippet   string
+		InExtra     string
+		OutComments map[string]string
+		OutErrMsg   string
+	}{
+		{
+			Name:        "Placeholders",
+			InSnippets:  map[string]string{"needs-more-info": "Hi {{.Reporter}}, {{.Key}} needs logs. {{.Assignee}} will pick it up."},
+			InIssues:    []string{"JIWA-1"},
+			InSnippet:   "needs-more-info",
+			OutComments: map[string]string{"JIWA-1": "Hi Rita Reporter, JIWA-1 needs logs. Alice will pick it up."},
+		},
+		{
+			Name:        "ExtraTextAfterSnippet",
+			InFiles:     map[string]string{"thanks": "Thanks, closing {{.Key}}.\n"},
+			InIssues:    []string{"JIWA-1", "JIWA-2"},
+			InSnippet:   "thanks",
+			InExtra:     "Reopen it if it comes back.",
+			OutComments: map[string]string{"JIWA-1": "Thanks, closing JIWA-1.\n\nReopen it if it comes back.", "JIWA-2": "Thanks, closing JIWA-2.\n\nReopen it if it comes back."},
+		},
+		{
+			Name:        "ConfigWinsOverFile",
+			InSnippets:  map[string]string{"thanks": "from the config"},
+			InFiles:     map[string]string{"thanks": "from the file"},
+			InIssues:    []string{"JIWA-1"},
+			InSnippet:   "thanks",
+			OutComments: map[string]string{"JIWA-1": "from the config"},
+		},
+		{
+			Name:       "EmptyPlaceholderPostsNothing",
+			InSnippets: map[string]string{"ping": "{{.Assignee}}, any news?"},
+			InIssues:   []string{"JIWA-1", "JIWA-2"},
+			InSnippet:  "ping",
+			OutErrMsg:  "JIWA-2 has no assignee to fill in {{.Assignee}}, nothing was commented",
+		},
+		{
+			Name:       "UnknownPlaceholder",
+			InSnippets: map[string]string{"ping": "{{.Watcher}}, any news?"},
+			InIssues:   []string{"JIWA-1"},
+			InSnippet:  "ping",
+			OutErrMsg:  `snippet "ping" uses {{.Watcher}} which doesn't exist, placeholders are {{.Key}}, {{.Summary}}, {{.Assignee}}, {{.Reporter}}`,
+		},
+		{
+			Name:       "UnknownSnippet",
+			InSnippets: map[string]string{"needs-more-info": "More please"},
+			InIssues:   []string{"JIWA-1"},
+			InSnippet:  "need-more-info",
+			OutErrMsg:  `there is no snippet "need-more-info", did you mean "needs-more-info"?, snippets are needs-more-info`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			for name, text := range td.InFiles {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Summary:  "Broken",
+				Assignee: &jira.User{Name: "alice", DisplayName: "Alice"},
+				Reporter: &jira.User{Name: "rita", DisplayName: "Rita Reporter"},
+			}}
+			fake.Issues["JIWA-2"] = jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Also broken"}}
+			c := Command{Client: fake, SnippetDir: dir, Config: Config{Snippets: td.InSnippets}}
+
+			_, err := c.CommentSnippet(td.InIssues, td.InSnippet, td.InExtra)
+
+			comments := make(map[string]string)
+			for key, issue := range fake.Issues {
+				if issue.Fields.Comments != nil {
+					comments[key] = issue.Fields.Comments.Comments[0].Body
+				}
+			}
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Empty(t, comments)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+)
+
+// Sprint adds the issues to the sprint, which can either be a sprint ID,
+// the name of a sprint or one of the keywords "current" and "next".
+// Names and keywords are resolved against the active and future sprints
+// of the Scrum boards in each issue's project.
+func (c *Command) Sprint(issues []string, sprint string) ([]string, error) {
+	byProject := make(map[string][]string)
+	projects := make([]string, 0)
+	for _, issue := range issues {
+		project := projectFromIssueKey(issue)
+		if _, ok := byProject[project]; !ok {
+			projects = append(projects, project)
+		}
+		byProject[project] = append(byProject[project], issue)
+	}
+
+	for _, project := range projects {
+		sprintID, err := c.resolveSprint(project, sprint)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range byProject[project] {
+			err = c.runPreHook("pre-sprint", hooks.Payload{Key: issue, Sprint: sprint})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = c.Client.AddToSprint(c.ctx(), sprintID, byProject[project]...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range byProject[project] {
+			c.runPostHook("post-sprint", hooks.Payload{Key: issue, Sprint: sprint})
+		}
+		c.remember(byProject[project]...)
+	}
+
+	return issues, nil
+}
+
+func (c *Command) resolveSprint(project, sprint string) (int, error) {
+	id, err := strconv.Atoi(sprint)
+	if err == nil {
+		return id, nil
+	}
+
+	boards, err := c.Client.ListBoards(c.ctx(), project, "scrum")
+	if err != nil {
+		return 0, err
+	}
+
+	if len(boards) == 0 {
+		return 0, fmt.Errorf("project %s has no Scrum board, sprints are only available for Scrum projects", project)
+	}
+
+	sprints := make([]jira.Sprint, 0)
+	for _, b := range boards {
+		s, err := c.Client.ListSprints(c.ctx(), b.ID, "active,future")
+		if err != nil {
+			return 0, err
+		}
+		sprints = append(sprints, s...)
+	}
+
+	switch strings.ToLower(sprint) {
+	case "current":
+		for _, s := range sprints {
+			if s.State == "active" {
+				return s.ID, nil
+			}
+		}
+		return 0, fmt.Errorf("project %s has no active sprint", project)
+	case "next":
+		for _, s := range sprints {
+			if s.State == "future" {
+				return s.ID, nil
+			}
+		}
+		return 0, fmt.Errorf("project %s has no future sprint", project)
+	}
+
+	// a sprint shared between boards is listed for each of them, only
+	// different sprints with the same name are ambiguous
+	names := make([]string, 0, len(sprints))
+	matches := make([]string, 0)
+	for _, s := range sprints {
+		if strings.EqualFold(s.Name, sprint) && !slices.Contains(matches, strconv.Itoa(s.ID)) {
+			id = s.ID
+			matches = append(matches, strconv.Itoa(s.ID))
+		}
+		names = append(names, s.Name)
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf(
+			"could not find sprint %q in project %s, valid sprints are: %s",
+			sprint,
+			project,
+			strings.Join(names, ","),
+		)
+	case 1:
+		return id, nil
+	}
+
+	return 0, fmt.Errorf(
+		"sprint %q is ambiguous in project %s, use one of the sprint IDs %s",
+		sprint,
+		project,
+		strings.Join(matches, ","),
+	)
+}
+
+func projectFromIssueKey(key string) string {
+	project, _, _ := strings.Cut(key, "-")
+	return project
+}
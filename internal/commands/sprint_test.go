@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Sprint(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InSprint    string
+		InSprints   map[int][]jira.Sprint
+		InErrors    map[string]error
+		OutSprintID int
+		OutErrMsg   string
+	}{
+		{Name: "Name", InSprint: "sprint 12", OutSprintID: 12},
+		{Name: "ID", InSprint: "99", OutSprintID: 99},
+		{Name: "Current", InSprint: "current", OutSprintID: 11},
+		{Name: "Next", InSprint: "Next", OutSprintID: 12},
+		{
+			Name:      "Unknown",
+			InSprint:  "Sprint 7",
+			OutErrMsg: `could not find sprint "Sprint 7" in project JIWA, valid sprints are: Sprint 11,Sprint 12`,
+		},
+		{
+			Name:     "SharedBetweenBoards",
+			InSprint: "Sprint 12",
+			InSprints: map[int][]jira.Sprint{
+				1: {{ID: 12, Name: "Sprint 12", State: "future"}},
+				2: {{ID: 12, Name: "Sprint 12", State: "future"}},
+			},
+			OutSprintID: 12,
+		},
+		{
+			Name:     "SameNameOnTwoBoards",
+			InSprint: "Sprint 12",
+			InSprints: map[int][]jira.Sprint{
+				1: {{ID: 12, Name: "Sprint 12", State: "future"}},
+				2: {{ID: 21, Name: "Sprint 12", State: "active"}},
+			},
+			OutErrMsg: `sprint "Sprint 12" is ambiguous in project JIWA, use one of the sprint IDs 12,21`,
+		},
+		{
+			Name:      "NoActiveSprint",
+			InSprint:  "current",
+			InSprints: map[int][]jira.Sprint{1: {{ID: 12, Name: "Sprint 12", State: "future"}}},
+			OutErrMsg: "project JIWA has no active sprint",
+		},
+		{
+			Name:      "BoardsFail",
+			InSprint:  "current",
+			InErrors:  map[string]error{"ListBoards": errors.New("boom")},
+			OutErrMsg: "boom",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{}}
+			fake.Boards["JIWA"] = []jira.Board{{ID: 1, Type: "scrum"}, {ID: 2, Type: "scrum"}, {ID: 3, Type: "kanban"}}
+			fake.Sprints = map[int][]jira.Sprint{
+				1: {{ID: 10, Name: "Sprint 10", State: "closed"}, {ID: 11, Name: "Sprint 11", State: "active"}},
+				2: {{ID: 12, Name: "Sprint 12", State: "future"}},
+			}
+			if td.InSprints != nil {
+				fake.Sprints = td.InSprints
+			}
+			for method, err := range td.InErrors {
+				fake.Errors[method] = err
+			}
+			c := Command{Client: fake}
+
+			_, err := c.Sprint([]string{"JIWA-1"}, td.InSprint)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Empty(t, fake.SprintIssues)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, map[int][]string{td.OutSprintID: {"JIWA-1"}}, fake.SprintIssues)
+		})
+	}
+}
+
+func TestCommand_Sprint_NoScrumBoard(t *testing.T) {
+	fake := jiwafake.New()
+	fake.Boards["JIWA"] = []jira.Board{{ID: 3, Type: "kanban"}}
+	c := Command{Client: fake}
+
+	_, err := c.Sprint([]string{"JIWA-1"}, "current")
+
+	assert.EqualError(t, err, "project JIWA has no Scrum board, sprints are only available for Scrum projects")
+}
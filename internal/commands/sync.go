@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/catouc/jiwa/internal/offline"
+)
+
+// Sync replays the changes that were queued while offline, Client has to
+// talk to Jira directly so nothing is queued again. force sends entries
+// that were held back because their issue changed or the last sync was
+// interrupted.
+func (c *Command) Sync(force bool) (offline.Report, error) {
+	if c.Journal == nil {
+		return offline.Report{}, errors.New("cannot locate the journal of queued changes")
+	}
+
+	report, err := offline.Replay(c.ctx(), c.Client, c.Journal, force)
+	for _, a := range report.Applied {
+		c.remember(a.Key)
+	}
+
+	return report, err
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/hooks"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+type TailInput struct {
+	Key      string
+	Interval time.Duration
+	// Exec is run through `sh -c` for every new event with the event as
+	// JSON on stdin and in JIWA_* variables, e.g. to notify the desktop
+	Exec string
+}
+
+// Tail polls the issue every interval and calls fn with the comments and
+// status changes that are new since the last poll, oldest first, until the
+// Context is cancelled. What is already there when it starts isn't
+// reported. A failed poll or Exec is a warning, the next poll goes on.
+func (c *Command) Tail(input TailInput, fn func(ActivityEvent)) error {
+	ctx := c.ctx()
+
+	var t tailer
+	issue, err := c.tailPoll(ctx, input.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", input.Key, err)
+	}
+	t.newEvents(issue)
+	if issue.Fields != nil {
+		c.rememberIssue(issue.Key, issue.Fields.Summary)
+	}
+
+	for {
+		timer := time.NewTimer(input.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		issue, err := c.tailPoll(ctx, input.Key)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to poll %s, trying again in %s: %s\n", input.Key, input.Interval, err)
+			continue
+		}
+
+		for _, e := range t.newEvents(issue) {
+			fn(e)
+			if input.Exec != "" {
+				err = c.tailExec(ctx, input.Exec, e)
+				// a command killed by Ctrl-C didn't fail
+				if err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+				}
+			}
+		}
+	}
+}
+
+func (c *Command) tailPoll(ctx context.Context, key string) (jira.Issue, error) {
+	return c.Client.GetIssue(ctx, key, jiwa.WithFields("summary", "comment"), jiwa.WithExpand("changelog"))
+}
+
+// tailer remembers the newest changelog entry and comment it has seen,
+// their IDs only ever go up
+type tailer struct {
+	lastHistory int
+	lastComment int
+}
+
+// newEvents returns the comments and status changes after the ones seen
+// on the last call, oldest first
+func (t *tailer) newEvents(issue jira.Issue) []ActivityEvent {
+	lastHistory, lastComment := t.lastHistory, t.lastComment
+	isNew := func(id string, last int, newest *int) bool {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return false
+		}
+		*newest = max(*newest, n)
+		return n > last
+	}
+
+	events := make([]ActivityEvent, 0)
+	if issue.Changelog != nil {
+		for _, h := range issue.Changelog.Histories {
+			if !isNew(h.Id, lastHistory, &t.lastHistory) {
+				continue
+			}
+			for _, item := range h.Items {
+				if item.Field != "status" {
+					continue
+				}
+				events = append(events, ActivityEvent{
+					Time:   parseJiraTime(h.Created),
+					Author: userName(h.Author),
+					Key:    issue.Key,
+					Kind:   "change",
+					Field:  item.Field,
+					From:   item.FromString,
+					To:     item.ToString,
+				})
+			}
+		}
+	}
+
+	if issue.Fields != nil && issue.Fields.Comments != nil {
+		for _, comment := range issue.Fields.Comments.Comments {
+			if comment == nil || !isNew(comment.ID, lastComment, &t.lastComment) {
+				continue
+			}
+			events = append(events, ActivityEvent{
+				Time:    parseJiraTime(comment.Created),
+				Author:  userName(comment.Author),
+				Key:     issue.Key,
+				Kind:    "comment",
+				Comment: comment.Body,
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].Time.Before(events[b].Time)
+	})
+
+	return events
+}
+
+// tailExec runs the command for the event, its output goes to stderr so
+// stdout only has the events
+func (c *Command) tailExec(ctx context.Context, command string, e ActivityEvent) error {
+	timeout := c.Config.HookTimeout
+	if timeout == 0 {
+		timeout = hooks.DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	in, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"JIWA_ISSUE_KEY="+e.Key,
+		"JIWA_URL="+c.ConstructIssueURL(e.Key),
+		"JIWA_EVENT="+e.Kind,
+		"JIWA_TIME="+e.Time.Format(time.RFC3339),
+		"JIWA_AUTHOR="+e.Author,
+		"JIWA_STATUS="+e.To,
+		"JIWA_COMMENT="+e.Comment,
+		"JIWA_MESSAGE="+e.String(),
+	)
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("--exec timed out after %s", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("--exec failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func tailIssue(histories []jira.ChangelogHistory, comments ...*jira.Comment) jira.Issue {
+	return jira.Issue{
+		Key:       "JIWA-911",
+		Fields:    &jira.IssueFields{Summary: "Outage", Comments: &jira.Comments{Comments: comments}},
+		Changelog: &jira.Changelog{Histories: histories},
+	}
+}
+
+func TestTailer_NewEvents(t *testing.T) {
+	alice := jira.User{DisplayName: "Alice"}
+	moved := jira.ChangelogHistory{Id: "10", Author: alice, Created: "2024-03-01T10:05:00.000+0000", Items: []jira.ChangelogItems{
+		{Field: "status", FromString: "To Do", ToString: "In Progress"},
+		{Field: "assignee", ToString: "Alice"},
+	}}
+	relabeled := jira.ChangelogHistory{Id: "11", Author: alice, Created: "2024-03-01T10:06:00.000+0000", Items: []jira.ChangelogItems{
+		{Field: "labels", ToString: "sev1"},
+	}}
+	first := &jira.Comment{ID: "100", Author: alice, Created: "2024-03-01T10:00:00.000+0000", Body: "Looking"}
+	second := &jira.Comment{ID: "101", Author: alice, Created: "2024-03-01T10:10:00.000+0000", Body: "Rolled back"}
+
+	testData := []struct {
+		Name      string
+		InSeen    jira.Issue
+		InIssue   jira.Issue
+		OutEvents []string
+	}{
+		{
+			Name:      "NothingNew",
+			InSeen:    tailIssue([]jira.ChangelogHistory{moved}, first),
+			InIssue:   tailIssue([]jira.ChangelogHistory{moved}, first),
+			OutEvents: []string{},
+		},
+		{
+			Name:    "NewCommentAndStatusInOrder",
+			InSeen:  tailIssue(nil, first),
+			InIssue: tailIssue([]jira.ChangelogHistory{moved, relabeled}, first, second),
+			OutEvents: []string{
+				"Alice moved JIWA-911 to In Progress",
+				"Alice commented on JIWA-911: Rolled back",
+			},
+		},
+		{
+			Name:      "DeletedCommentDoesNotRepeatOthers",
+			InSeen:    tailIssue(nil, first, second),
+			InIssue:   tailIssue(nil, second),
+			OutEvents: []string{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var tl tailer
+			tl.newEvents(td.InSeen)
+
+			events := make([]string, 0)
+			for _, e := range tl.newEvents(td.InIssue) {
+				events = append(events, e.String())
+			}
+			assert.Equal(t, td.OutEvents, events)
+		})
+	}
+}
+
+// scriptedIssues answers GetIssue with the next issue or error of the
+// script, the last one is repeated
+type scriptedIssues struct {
+	jiwa.API
+	mu     sync.Mutex
+	script []any
+}
+
+func (s *scriptedIssues) GetIssue(_ context.Context, _ string, _ ...jiwa.GetIssueOption) (jira.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.script[0]
+	if len(s.script) > 1 {
+		s.script = s.script[1:]
+	}
+	if err, ok := next.(error); ok {
+		return jira.Issue{}, err
+	}
+
+	return next.(jira.Issue), nil
+}
+
+func TestCommand_Tail(t *testing.T) {
+	bob := jira.User{DisplayName: "Bob"}
+	old := &jira.Comment{ID: "100", Author: bob, Body: "Paging ops"}
+	handoff := &jira.Comment{ID: "101", Author: bob, Created: "2024-03-01T10:10:00.000+0000", Body: "Handing over to EU"}
+	resolved := jira.ChangelogHistory{Id: "12", Author: bob, Created: "2024-03-01T10:20:00.000+0000", Items: []jira.ChangelogItems{
+		{Field: "status", FromString: "In Progress", ToString: "Done"},
+	}}
+
+	api := &scriptedIssues{API: jiwafake.New(), script: []any{
+		tailIssue(nil, old),
+		errors.New("connection reset by peer"),
+		tailIssue(nil, old, handoff),
+		tailIssue([]jira.ChangelogHistory{resolved}, old, handoff),
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := filepath.Join(t.TempDir(), "exec")
+	c := Command{Client: api, Context: ctx}
+
+	events := make([]string, 0)
+	err := c.Tail(TailInput{
+		Key:      "JIWA-911",
+		Interval: time.Millisecond,
+		Exec:     `printf '%s %s|' "$JIWA_EVENT" "$JIWA_STATUS" >> ` + out,
+	}, func(e ActivityEvent) {
+		events = append(events, e.String())
+		if len(events) == 2 {
+			cancel()
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Bob commented on JIWA-911: Handing over to EU",
+		"Bob moved JIWA-911 to Done",
+	}, events)
+
+	// the second exec is cut short by the cancel, only the first is sure
+	b, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "comment |")
+}
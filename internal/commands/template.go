@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IssueTemplate is a named shape of issue, like a bug report, that create
+// can start from with --from-template
+type IssueTemplate struct {
+	Type       string   `json:"type"`
+	Labels     []string `json:"labels"`
+	Components []string `json:"components"`
+	// BodyFile pre-fills the editor, it is written like a file for
+	// create -f with the summary on the first line. A relative path is
+	// resolved against the directory of the config file.
+	BodyFile string `json:"bodyFile"`
+}
+
+// Template looks up the template by name and reads its body file
+func (c *Command) Template(name string) (IssueTemplate, string, error) {
+	t, ok := c.Config.Templates[name]
+	if !ok {
+		names := make([]string, 0, len(c.Config.Templates))
+		for n := range c.Config.Templates {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		if len(names) == 0 {
+			return t, "", fmt.Errorf("there is no template %q, add it to \"templates\" in the config", name)
+		}
+
+		msg := fmt.Sprintf("there is no template %q", name)
+		if s := Suggest(name, names); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		return t, "", fmt.Errorf("%s, templates are %s", msg, strings.Join(names, ", "))
+	}
+
+	if t.BodyFile == "" {
+		return t, "", nil
+	}
+
+	path := t.BodyFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.ConfigDir, path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return t, "", fmt.Errorf("failed to read the body of template %q: %w", name, err)
+	}
+
+	return t, string(b), nil
+}
+
+// applyTemplate fills in what the flags left empty from the template
+func applyTemplate(input CreateInput, t IssueTemplate) CreateInput {
+	if input.Type == "" {
+		input.Type = t.Type
+	}
+	if len(input.Labels) == 0 {
+		input.Labels = t.Labels
+	}
+	if len(input.Components) == 0 {
+		input.Components = t.Components
+	}
+
+	return input
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_Template(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "templates"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "templates", "bug.md"), []byte("Bug: \n\n## Steps to reproduce\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates := map[string]IssueTemplate{
+		"bug":      {Type: "Bug", Labels: []string{"triage"}, BodyFile: "templates/bug.md"},
+		"absolute": {BodyFile: filepath.Join(dir, "templates", "bug.md")},
+		"chore":    {Type: "Task", Labels: []string{"chore"}},
+		"missing":  {BodyFile: "templates/missing.md"},
+	}
+
+	testData := []struct {
+		Name        string
+		InName      string
+		InTemplates map[string]IssueTemplate
+		OutTemplate IssueTemplate
+		OutBody     string
+		OutErrMsg   string
+	}{
+		{
+			Name:        "RelativeToConfigDir",
+			InName:      "bug",
+			InTemplates: templates,
+			OutTemplate: templates["bug"],
+			OutBody:     "Bug: \n\n## Steps to reproduce\n",
+		},
+		{
+			Name:        "AbsolutePath",
+			InName:      "absolute",
+			InTemplates: templates,
+			OutTemplate: templates["absolute"],
+			OutBody:     "Bug: \n\n## Steps to reproduce\n",
+		},
+		{
+			Name:        "WithoutBody",
+			InName:      "chore",
+			InTemplates: templates,
+			OutTemplate: templates["chore"],
+		},
+		{
+			Name:        "MissingBodyFile",
+			InName:      "missing",
+			InTemplates: templates,
+			OutErrMsg:   `failed to read the body of template "missing": open ` + filepath.Join(dir, "templates", "missing.md") + ": no such file or directory",
+		},
+		{
+			Name:        "Typo",
+			InName:      "bugs",
+			InTemplates: templates,
+			OutErrMsg:   `there is no template "bugs", did you mean "bug"?, templates are absolute, bug, chore, missing`,
+		},
+		{
+			Name:      "NoTemplates",
+			InName:    "bug",
+			OutErrMsg: `there is no template "bug", add it to "templates" in the config`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c := Command{ConfigDir: dir, Config: Config{Templates: td.InTemplates}}
+
+			tmpl, body, err := c.Template(td.InName)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutTemplate, tmpl)
+			assert.Equal(t, td.OutBody, body)
+		})
+	}
+}
+
+func TestCommand_CreateFromTemplate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ticket")
+	err := os.WriteFile(file, []byte("Login fails\n\nOn Safari only"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bug := IssueTemplate{Type: "Bug", Labels: []string{"triage"}, Components: []string{"web"}}
+
+	testData := []struct {
+		Name          string
+		InInput       CreateInput
+		OutType       string
+		OutLabels     []string
+		OutComponents []string
+	}{
+		{
+			Name:          "FromTemplate",
+			InInput:       CreateInput{Template: "bug"},
+			OutType:       "Bug",
+			OutLabels:     []string{"triage"},
+			OutComponents: []string{"Web"},
+		},
+		{
+			Name:          "FlagsOverride",
+			InInput:       CreateInput{Template: "bug", Type: "Story", Components: []string{"api"}},
+			OutType:       "Story",
+			OutLabels:     []string{"triage"},
+			OutComponents: []string{"API"},
+		},
+		{
+			Name:    "WithoutTemplate",
+			OutType: "Task",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Projects["JIWA"] = jira.Project{Key: "JIWA", Components: []jira.ProjectComponent{{Name: "Web"}, {Name: "API"}}}
+			c := Command{Client: fake, Config: Config{
+				DisableDuplicateCheck: true,
+				Templates:             map[string]IssueTemplate{"bug": bug},
+			}}
+
+			input := td.InInput
+			input.Project = "JIWA"
+			input.File = file
+			key, err := c.Create(input)
+			assert.NoError(t, err)
+
+			issue := fake.Issues[key]
+			assert.Equal(t, "Login fails", issue.Fields.Summary)
+			assert.Equal(t, td.OutType, issue.Fields.Type.Name)
+			assert.Equal(t, td.OutLabels, issue.Fields.Labels)
+
+			var components []string
+			for _, c := range issue.Fields.Components {
+				components = append(components, c.Name)
+			}
+			assert.Equal(t, td.OutComponents, components)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/browser"
+	"github.com/catouc/jiwa/internal/prompt"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+const triageHelp = "[a]ssign [l]abel [p]riority [t]ransition [s]kip [o]pen [q]uit > "
+
+type TriageInput struct {
+	Project string
+	JQL     string
+}
+
+// TriageAction records what was done to an issue during triage
+type TriageAction struct {
+	Key    string
+	Action string
+}
+
+// TriageIssues returns the issues to walk through, by default the
+// unassigned to do issues of the project.
+func (c *Command) TriageIssues(input TriageInput) ([]jira.Issue, error) {
+	jql := input.JQL
+	if jql == "" {
+		project, err := c.FishOutProject(input.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		jql = fmt.Sprintf("project=%s AND assignee is EMPTY AND status=\"to do\" ORDER BY created ASC", project)
+	}
+
+	issues, err := c.Client.Search(c.ctx(), jql, jiwa.WithFields("summary", "status", "priority", "reporter", "description"))
+	if err != nil {
+		return nil, fmt.Errorf("could not find issues to triage: %w", err)
+	}
+
+	return issues, nil
+}
+
+// Triage walks through the issues one at a time and asks what to do with
+// each of them. Every action but opening the browser moves on to the next
+// issue, failed actions can be retried.
+func (c *Command) Triage(issues []jira.Issue, p *prompt.Prompter) ([]TriageAction, error) {
+	actions := make([]TriageAction, 0, len(issues))
+	var priorities []jira.Priority
+
+	for n, issue := range issues {
+		p.Printf("\n[%d/%d] %s\n", n+1, len(issues), c.triageHeader(issue))
+
+	actionLoop:
+		for {
+			answer, err := p.Ask(triageHelp)
+			if err != nil {
+				return actions, err
+			}
+
+			var action string
+			switch strings.ToLower(answer) {
+			case "a", "assign":
+				action, err = c.triageAssign(issue.Key, p)
+			case "l", "label":
+				action, err = c.triageLabel(issue.Key, p)
+			case "p", "priority":
+				if priorities == nil {
+					priorities, err = c.Client.ListPriorities(c.ctx())
+					if err != nil {
+						break
+					}
+				}
+				action, err = c.triagePriority(issue.Key, priorities, p)
+			case "t", "transition":
+				action, err = c.triageTransition(issue.Key, p)
+			case "s", "skip":
+				action = "skipped"
+			case "o", "open":
+				err = browser.Open(c.ConstructIssueURL(issue.Key))
+			case "q", "quit":
+				return actions, nil
+			default:
+				p.Printf("unknown action %q\n", answer)
+			}
+
+			switch {
+			case errors.Is(err, prompt.ErrAborted):
+				return actions, err
+			case err != nil:
+				p.Printf("%s\n", err)
+			case action != "":
+				actions = append(actions, TriageAction{Key: issue.Key, Action: action})
+				break actionLoop
+			}
+		}
+	}
+
+	return actions, nil
+}
+
+func (c *Command) triageHeader(issue jira.Issue) string {
+	var b strings.Builder
+	b.WriteString(issue.Key + " " + issue.Fields.Summary + "\n")
+	if issue.Fields.Status != nil {
+		b.WriteString("Status: " + issue.Fields.Status.Name + "\n")
+	}
+	if issue.Fields.Priority != nil {
+		b.WriteString("Priority: " + issue.Fields.Priority.Name + "\n")
+	}
+	if issue.Fields.Reporter != nil {
+		b.WriteString("Reporter: " + issue.Fields.Reporter.DisplayName + "\n")
+	}
+	b.WriteString(c.ConstructIssueURL(issue.Key) + "\n\n")
+	b.WriteString(issue.Fields.Description)
+
+	return b.String()
+}
+
+func (c *Command) triageAssign(key string, p *prompt.Prompter) (string, error) {
+	query, err := p.Ask("search user: ")
+	if err != nil || query == "" {
+		return "", err
+	}
+
+	users, err := c.Client.SearchUsers(c.ctx(), query)
+	if err != nil {
+		return "", err
+	}
+
+	if len(users) == 0 {
+		return "", fmt.Errorf("no users found for %q", query)
+	}
+
+	options := make([]string, 0, len(users))
+	for _, u := range users {
+		options = append(options, fmt.Sprintf("%s (%s)", u.DisplayName, u.Name))
+	}
+
+	idx, err := p.Choose("assign to: ", options)
+	if err != nil || idx < 0 {
+		return "", err
+	}
+
+	_, err = c.Reassign([]string{key}, users[idx].Name, false)
+	if err != nil {
+		return "", err
+	}
+
+	return "assigned to " + users[idx].Name, nil
+}
+
+func (c *Command) triageLabel(key string, p *prompt.Prompter) (string, error) {
+	answer, err := p.Ask("labels (space separated): ")
+	if err != nil || answer == "" {
+		return "", err
+	}
+
+	labels := strings.Fields(answer)
+	_, err = c.Label([]string{key}, labels, false)
+	if err != nil {
+		return "", err
+	}
+
+	return "labelled " + strings.Join(labels, ","), nil
+}
+
+func (c *Command) triagePriority(key string, priorities []jira.Priority, p *prompt.Prompter) (string, error) {
+	options := make([]string, 0, len(priorities))
+	for _, pr := range priorities {
+		options = append(options, pr.Name)
+	}
+
+	idx, err := p.Choose("priority: ", options)
+	if err != nil || idx < 0 {
+		return "", err
+	}
+
+	err = c.Client.SetIssuePriority(c.ctx(), key, priorities[idx].Name)
+	if err != nil {
+		return "", err
+	}
+	c.remember(key)
+
+	return "priority set to " + priorities[idx].Name, nil
+}
+
+func (c *Command) triageTransition(key string, p *prompt.Prompter) (string, error) {
+	transitions, err := c.Client.ListIssueTransitions(c.ctx(), key)
+	if err != nil {
+		return "", err
+	}
+
+	options := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		options = append(options, t.Name)
+	}
+
+	idx, err := p.Choose("transition: ", options)
+	if err != nil || idx < 0 {
+		return "", err
+	}
+
+	_, err = c.Move([]string{key}, transitions[idx].Name, nil, "")
+	if err != nil {
+		return "", err
+	}
+
+	return "moved to " + transitions[idx].Name, nil
+}
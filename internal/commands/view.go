@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// DefaultViewFields are shown when neither the "viewFields" config nor
+// --fields pick any
+var DefaultViewFields = []string{"summary", "description", "parent", "reporter"}
+
+// systemFields are known without asking Jira, so the default fields don't
+// cost a request to list all of them
+var systemFields = []jira.Field{
+	{ID: "summary", Name: "Summary"},
+	{ID: "description", Name: "Description"},
+	{ID: "parent", Name: "Parent"},
+	{ID: "status", Name: "Status"},
+	{ID: "issuetype", Name: "Issue Type"},
+	{ID: "priority", Name: "Priority"},
+	{ID: "assignee", Name: "Assignee"},
+	{ID: "reporter", Name: "Reporter"},
+	{ID: "labels", Name: "Labels"},
+	{ID: "components", Name: "Components"},
+	{ID: "fixVersions", Name: "Fix Version/s"},
+	{ID: "resolution", Name: "Resolution"},
+	{ID: "created", Name: "Created"},
+	{ID: "updated", Name: "Updated"},
+	{ID: "duedate", Name: "Due Date"},
+}
+
+// ViewField is a field of an issue with its value formatted for printing
+type ViewField struct {
+	ID    string
+	Name  string
+	Value string
+}
+
+// View fetches the issue with the fields, which can be given by ID or by
+// name. They come back in the same order with their names resolved, fields
+// defaults to the "viewFields" config and then DefaultViewFields.
+func (c *Command) View(issueID string, fields []string, opts ...jiwa.GetIssueOption) (jira.Issue, []ViewField, error) {
+	if len(fields) == 0 {
+		fields = c.Config.ViewFields
+	}
+	if len(fields) == 0 {
+		fields = DefaultViewFields
+	}
+
+	ctx := c.ctx()
+	resolved, err := c.resolveViewFields(ctx, fields)
+	if err != nil {
+		return jira.Issue{}, nil, err
+	}
+
+	ids := make([]string, 0, len(resolved))
+	for _, f := range resolved {
+		ids = append(ids, f.ID)
+	}
+
+	issue, err := c.Cat(issueID, append([]jiwa.GetIssueOption{jiwa.WithFields(ids...)}, opts...)...)
+	if err != nil {
+		return jira.Issue{}, nil, err
+	}
+
+	loc := c.Location()
+	view := make([]ViewField, 0, len(resolved))
+	for _, f := range resolved {
+		value := fieldValue(issue, f.ID, loc)
+		if f.ID == "parent" {
+			// the Epic Link is fetched separately, the issue is still
+			// worth showing without it
+			value, err = c.ParentOf(issue)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: could not look up the parent of %s: %v\n", issue.Key, err)
+			}
+		}
+
+		view = append(view, ViewField{ID: f.ID, Name: f.Name, Value: value})
+	}
+
+	return issue, view, nil
+}
+
+// resolveViewFields matches IDs exactly and names ignoring case, Jira is only
+// asked for its fields if one of them isn't a well known system field.
+func (c *Command) resolveViewFields(ctx context.Context, names []string) ([]jira.Field, error) {
+	var listed []jira.Field
+	resolved := make([]jira.Field, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		f, ok := findField(systemFields, name)
+		if !ok {
+			if listed == nil {
+				var err error
+				listed, err = c.Client.ListFields(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to look up field %q: %w", name, err)
+				}
+			}
+
+			f, ok = findField(listed, name)
+		}
+
+		if !ok {
+			return nil, unknownFieldError(name, listed)
+		}
+		resolved = append(resolved, f)
+	}
+
+	return resolved, nil
+}
+
+func findField(fields []jira.Field, name string) (jira.Field, bool) {
+	for _, f := range fields {
+		if f.ID == name {
+			return f, true
+		}
+	}
+
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) || strings.EqualFold(f.ID, name) {
+			return f, true
+		}
+	}
+
+	return jira.Field{}, false
+}
+
+func unknownFieldError(name string, listed []jira.Field) error {
+	candidates := make([]string, 0, len(systemFields)+len(listed))
+	for _, f := range append(append([]jira.Field(nil), systemFields...), listed...) {
+		candidates = append(candidates, f.Name)
+	}
+	sort.Strings(candidates)
+
+	msg := fmt.Sprintf("unknown field %q", name)
+	if s := Suggest(name, candidates); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+
+	return errors.New(msg)
+}
+
+// fieldValue formats the field of the issue as a single string, an empty
+// one if it isn't set. Timestamps are shown in loc.
+func fieldValue(issue jira.Issue, id string, loc *time.Location) string {
+	f := issue.Fields
+	if f == nil {
+		return ""
+	}
+
+	switch id {
+	case "summary":
+		return f.Summary
+	case "description":
+		return f.Description
+	case "parent":
+		if f.Parent != nil {
+			return f.Parent.Key
+		}
+	case "status":
+		if f.Status != nil {
+			return f.Status.Name
+		}
+	case "issuetype":
+		return f.Type.Name
+	case "priority":
+		if f.Priority != nil {
+			return f.Priority.Name
+		}
+	case "assignee":
+		if f.Assignee != nil {
+			return f.Assignee.DisplayName
+		}
+	case "reporter":
+		if f.Reporter != nil {
+			return f.Reporter.DisplayName
+		}
+	case "labels":
+		return strings.Join(f.Labels, ", ")
+	case "components":
+		names := make([]string, 0, len(f.Components))
+		for _, c := range f.Components {
+			names = append(names, c.Name)
+		}
+		return strings.Join(names, ", ")
+	case "fixVersions":
+		names := make([]string, 0, len(f.FixVersions))
+		for _, v := range f.FixVersions {
+			names = append(names, v.Name)
+		}
+		return strings.Join(names, ", ")
+	case "resolution":
+		if f.Resolution != nil {
+			return f.Resolution.Name
+		}
+	case "created":
+		return formatJiraTime(time.Time(f.Created), loc)
+	case "updated":
+		return formatJiraTime(time.Time(f.Updated), loc)
+	case "duedate":
+		if t := time.Time(f.Duedate); !t.IsZero() {
+			return t.Format("2006-01-02")
+		}
+	default:
+		return formatFieldValue(f.Unknowns[id])
+	}
+
+	return ""
+}
+
+// formatFieldValue formats custom fields, options and users are shown by
+// their value or name and lists are joined by commas
+func formatFieldValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s := formatFieldValue(e); s != "" {
+				values = append(values, s)
+			}
+		}
+		return strings.Join(values, ", ")
+	case map[string]any:
+		for _, k := range []string{"value", "displayName", "name", "key"} {
+			if s, ok := v[k].(string); ok && s != "" {
+				return s
+			}
+		}
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
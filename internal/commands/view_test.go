@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+func TestCommand_View(t *testing.T) {
+	issue := jira.Issue{
+		Key: "JIWA-1",
+		Fields: &jira.IssueFields{
+			Summary:     "Fix the login",
+			Description: "It is broken",
+			Status:      &jira.Status{Name: "In Progress"},
+			Assignee:    &jira.User{Name: "alice", DisplayName: "Alice"},
+			Reporter:    &jira.User{Name: "bob", DisplayName: "Bob"},
+			Labels:      []string{"auth", "urgent"},
+			Parent:      &jira.Parent{Key: "JIWA-9"},
+			Unknowns: tcontainer.MarshalMap{
+				"customfield_10016": 5.0,
+				"customfield_10020": map[string]any{"value": "Team Rocket", "id": "10301"},
+				"customfield_10030": []any{map[string]any{"name": "Sprint 4"}, map[string]any{"name": "Sprint 5"}},
+			},
+		},
+	}
+	fields := []jira.Field{
+		{ID: "customfield_10016", Name: "Story Points", Custom: true},
+		{ID: "customfield_10020", Name: "Team", Custom: true},
+		{ID: "customfield_10030", Name: "Sprint", Custom: true},
+	}
+
+	testData := []struct {
+		Name            string
+		InFields        []string
+		InConfigFields  []string
+		InListFieldsErr error
+		InErrors        map[string]error
+		InNoParent      bool
+		OutView         []ViewField
+		OutErrMsg       string
+	}{
+		{
+			Name:            "DefaultDoesNotListFields",
+			InListFieldsErr: errors.New("should not be called"),
+			OutView: []ViewField{
+				{ID: "summary", Name: "Summary", Value: "Fix the login"},
+				{ID: "description", Name: "Description", Value: "It is broken"},
+				{ID: "parent", Name: "Parent", Value: "JIWA-9"},
+				{ID: "reporter", Name: "Reporter", Value: "Bob"},
+			},
+		},
+		{
+			Name:           "ConfiguredOrder",
+			InConfigFields: []string{"status", "summary", "assignee", "labels"},
+			OutView: []ViewField{
+				{ID: "status", Name: "Status", Value: "In Progress"},
+				{ID: "summary", Name: "Summary", Value: "Fix the login"},
+				{ID: "assignee", Name: "Assignee", Value: "Alice"},
+				{ID: "labels", Name: "Labels", Value: "auth, urgent"},
+			},
+		},
+		{
+			Name:           "FlagOverridesConfig",
+			InFields:       []string{"Labels", "Status"},
+			InConfigFields: []string{"summary"},
+			OutView: []ViewField{
+				{ID: "labels", Name: "Labels", Value: "auth, urgent"},
+				{ID: "status", Name: "Status", Value: "In Progress"},
+			},
+		},
+		{
+			Name:     "CustomFieldsByIDAndName",
+			InFields: []string{"story points", "customfield_10020", "Sprint", "priority"},
+			OutView: []ViewField{
+				{ID: "customfield_10016", Name: "Story Points", Value: "5"},
+				{ID: "customfield_10020", Name: "Team", Value: "Team Rocket"},
+				{ID: "customfield_10030", Name: "Sprint", Value: "Sprint 4, Sprint 5"},
+				{ID: "priority", Name: "Priority"},
+			},
+		},
+		{
+			Name:       "ParentLookupFails",
+			InFields:   []string{"summary", "parent"},
+			InErrors:   map[string]error{"ServerInfo": errors.New("boom")},
+			InNoParent: true,
+			OutView: []ViewField{
+				{ID: "summary", Name: "Summary", Value: "Fix the login"},
+				{ID: "parent", Name: "Parent"},
+			},
+		},
+		{
+			Name:      "UnknownField",
+			InFields:  []string{"summary", "Story Pionts"},
+			OutErrMsg: `unknown field "Story Pionts", did you mean "Story Points"?`,
+		},
+		{
+			Name:            "ListFieldsFails",
+			InFields:        []string{"Team"},
+			InListFieldsErr: errors.New("boom"),
+			OutErrMsg:       `failed to look up field "Team": boom`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues[issue.Key] = issue
+			if td.InNoParent {
+				withoutParent := *issue.Fields
+				withoutParent.Parent = nil
+				fake.Issues[issue.Key] = jira.Issue{Key: issue.Key, Fields: &withoutParent}
+			}
+			fake.Fields = fields
+			if td.InListFieldsErr != nil {
+				fake.Errors["ListFields"] = td.InListFieldsErr
+			}
+			for method, err := range td.InErrors {
+				fake.Errors[method] = err
+			}
+			c := Command{Client: fake, Config: Config{ViewFields: td.InConfigFields}}
+
+			_, view, err := c.View("JIWA-1", td.InFields)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutView, view)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// Whoami returns the account jiwa is authenticated as and the instance,
+// which decides whether users are known by name or by account ID.
+func (c *Command) Whoami() (jiwa.Account, jiwa.ServerInfo, error) {
+	account, err := c.Client.Myself(c.ctx())
+	if err != nil {
+		return jiwa.Account{}, jiwa.ServerInfo{}, err
+	}
+
+	info, err := c.Client.ServerInfo(c.ctx())
+	if err != nil {
+		return jiwa.Account{}, jiwa.ServerInfo{}, fmt.Errorf("failed to find out what kind of Jira this is: %w", err)
+	}
+
+	return account, info, nil
+}
@@ -5,50 +5,282 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 )
 
-// SetupTmpFileWithEditor creates a temp file in your configured TempDir and
-// finds out if the `EDITOR` environment variable is set properly.
+// File describes the buffer that is opened in the editor, it is named
+// jiwa-<command>-<key>.<extension> so editor sessions can be told apart and
+// editors pick the right syntax highlighting.
+type File struct {
+	// Command is the jiwa command the text is for, e.g. "edit"
+	Command string
+	// Key is the issue the text is for, if there is a single one
+	Key string
+	// Extension defaults to "md"
+	Extension string
+}
+
+// Name returns the file name, characters that don't belong in one are
+// replaced by underscores
+func (f File) Name() string {
+	name := "jiwa"
+	for _, part := range []string{f.Command, f.Key} {
+		if part != "" {
+			name += "-" + fileNameRegEx.ReplaceAllString(part, "_")
+		}
+	}
+
+	ext := strings.TrimPrefix(f.Extension, ".")
+	if ext == "" {
+		ext = "md"
+	}
+
+	return name + "." + fileNameRegEx.ReplaceAllString(ext, "_")
+}
+
+var fileNameRegEx = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Dir is where the buffers are put, in the user's cache dir so they aren't
+// cleaned up together with the temp dir, e.g. ~/.cache/jiwa/edit on Linux.
+func Dir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "jiwa", "edit")
+}
+
+// SetupTmpFileWithEditor creates the file in Dir and finds out if the
+// `EDITOR` environment variable is set properly.
 // It then sets up the file in that editor and returns a scanner to process the
 // entered text.
 // The caller is responsible to call the cleanup function after they are done processing.
-func SetupTmpFileWithEditor(prefill string) (*bufio.Scanner, func(), error) {
-	editor, exists := os.LookupEnv("EDITOR")
-	if !exists {
-		return nil, func() {}, errors.New("expecting `EDITOR` environment variable to be set")
+func SetupTmpFileWithEditor(file File, prefill string) (*bufio.Scanner, func(), error) {
+	s, err := open(file)
+	if err != nil {
+		return nil, s.cleanup, err
 	}
 
-	tmpFile, err := os.CreateTemp(os.TempDir(), "tcc-oncall-create-*")
+	text, err := s.edit(prefill)
 	if err != nil {
-		return nil, func() {}, fmt.Errorf("failed to create temp file for editing: %w", err)
+		return nil, s.cleanup, err
 	}
 
-	cleanup := func() {
-		os.Remove(tmpFile.Name())
+	scanner := bufio.NewScanner(bytes.NewBufferString(text))
+	return scanner, s.cleanup, nil
+}
+
+// ErrorPrefix starts the lines EditUntilValid puts at the top of the
+// buffer to explain what was wrong with it. It isn't a Markdown heading, so
+// a description can't be mistaken for one.
+const ErrorPrefix = "#jiwa: "
+
+// ErrEmpty is returned by EditUntilValid when the buffer was saved empty,
+// which is how editing is aborted
+var ErrEmpty = errors.New("the file was left empty")
+
+// EditUntilValid opens the text in the editor and hands what was saved to
+// check. When check fails the editor is opened again with what was typed
+// and the error on top, so nothing is lost to a mistake. It gives up with
+// ErrEmpty when the buffer is emptied and with the error when the buffer is
+// saved without changes.
+func EditUntilValid(file File, prefill string, check func(text string) error) (string, error) {
+	s, err := open(file)
+	defer s.cleanup()
+	if err != nil {
+		return "", err
 	}
 
-	if prefill != "" {
-		_, err = tmpFile.WriteString(prefill)
+	text := prefill
+	var lastErr error
+	for {
+		buffer := text
+		if lastErr != nil {
+			buffer = errorComment(lastErr) + text
+		}
+
+		edited, err := s.edit(buffer)
 		if err != nil {
-			return nil, cleanup, fmt.Errorf("failed to write prefill to tmpFile: %w", err)
+			return "", err
+		}
+		edited = StripErrorComment(edited)
+
+		switch {
+		case strings.TrimSpace(edited) == "":
+			return "", ErrEmpty
+		case lastErr != nil && edited == text:
+			return "", fmt.Errorf("%w, giving up as the file was saved without changes", lastErr)
+		}
+
+		lastErr = check(edited)
+		if lastErr == nil {
+			return edited, nil
+		}
+		text = edited
+	}
+}
+
+// errorComment explains the error in lines of their own, each starting
+// with ErrorPrefix
+func errorComment(err error) string {
+	var b strings.Builder
+	for _, line := range strings.Split(err.Error(), "\n") {
+		b.WriteString(ErrorPrefix + line + "\n")
+	}
+	b.WriteString(ErrorPrefix + "fix it and save to try again, empty the file to abort\n")
+
+	return b.String()
+}
+
+// StripErrorComment removes the lines EditUntilValid put on top of the
+// text, whatever else starts with ErrorPrefix further down is kept
+func StripErrorComment(text string) string {
+	for strings.HasPrefix(text, strings.TrimSpace(ErrorPrefix)) {
+		_, rest, found := strings.Cut(text, "\n")
+		if !found {
+			return ""
 		}
+		text = rest
+	}
+
+	return text
+}
+
+// session is a buffer that can be opened in the editor several times
+type session struct {
+	editor string
+	path   string
+}
+
+func (s session) cleanup() {
+	if s.path != "" {
+		os.Remove(s.path)
+	}
+}
+
+func open(file File) (session, error) {
+	editor, err := lookupEditor(runtime.GOOS, os.LookupEnv)
+	if err != nil {
+		return session{}, err
 	}
 
-	e := exec.Command(editor, tmpFile.Name())
+	tmpFile, err := createFile(Dir(), file)
+	if err != nil {
+		return session{}, fmt.Errorf("failed to create temp file for editing: %w", err)
+	}
+
+	s := session{editor: editor, path: tmpFile.Name()}
+	err = tmpFile.Close()
+	if err != nil {
+		return s, fmt.Errorf("failed to create temp file for editing: %w", err)
+	}
+
+	return s, nil
+}
+
+// edit writes the text to the file, runs the editor on it and returns
+// what was saved
+func (s session) edit(text string) (string, error) {
+	err := os.WriteFile(s.path, []byte(text), 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to write prefill to tmpFile: %w", err)
+	}
+
+	e := command(runtime.GOOS, s.editor, s.path)
 	e.Stdin = os.Stdin
 	e.Stdout = os.Stdout
 	err = e.Run()
 	if err != nil {
-		return nil, cleanup, fmt.Errorf("failed to get text from editor: %w", err)
+		return "", fmt.Errorf("failed to get text from editor: %w", err)
+	}
+
+	fBytes, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file contents: %w", err)
 	}
 
-	fBytes, err := os.ReadFile(tmpFile.Name())
+	return string(fBytes), nil
+}
+
+// createFile creates the file in dir, if it already exists because the same
+// issue is edited somewhere else a random suffix is added to the name
+func createFile(dir string, file File) (*os.File, error) {
+	err := os.MkdirAll(dir, 0o700)
 	if err != nil {
-		return nil, cleanup, fmt.Errorf("failed to read file contents: %w", err)
+		return nil, err
+	}
+
+	name := file.Name()
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if !errors.Is(err, fs.ErrExist) {
+		return f, err
+	}
+
+	ext := filepath.Ext(name)
+	return os.CreateTemp(dir, strings.TrimSuffix(name, ext)+"-*"+ext)
+}
+
+// lookupEditor reads `EDITOR`, Windows has notepad to fall back to
+func lookupEditor(goos string, lookupEnv func(string) (string, bool)) (string, error) {
+	if editor, _ := lookupEnv("EDITOR"); strings.TrimSpace(editor) != "" {
+		return editor, nil
+	}
+
+	if goos == "windows" {
+		return "notepad.exe", nil
+	}
+
+	return "", errors.New("expecting `EDITOR` environment variable to be set")
+}
+
+// command runs the editor the way a shell would, so `EDITOR` can carry
+// arguments like "code --wait". There is no sh on Windows, the editor is
+// split on spaces outside of double quotes there unless it is the path of
+// an existing file, e.g. an unquoted one under C:\Program Files.
+func command(goos, editor, file string) *exec.Cmd {
+	if goos != "windows" {
+		return exec.Command("sh", "-c", editor+` "$1"`, "sh", file)
+	}
+
+	if _, err := os.Stat(editor); err == nil {
+		return exec.Command(editor, file)
+	}
+
+	args := splitWindowsArgs(editor)
+	return exec.Command(args[0], append(args[1:], file)...)
+}
+
+func splitWindowsArgs(s string) []string {
+	args := make([]string, 0)
+	var arg strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if started {
+				args = append(args, arg.String())
+				arg.Reset()
+				started = false
+			}
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+
+	if started || len(args) == 0 {
+		args = append(args, arg.String())
 	}
 
-	scanner := bufio.NewScanner(bytes.NewBuffer(fBytes))
-	return scanner, cleanup, nil
+	return args
 }
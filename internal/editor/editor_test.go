@@ -0,0 +1,276 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain lets the test binary stand in for the editor. It keeps what it
+// was given in seen-<n> of JIWA_TEST_FAKE_EDITOR and saves reply-<n> from
+// there, where n counts the runs. Without a reply the file is saved as is.
+func TestMain(m *testing.M) {
+	if dir := os.Getenv("JIWA_TEST_FAKE_EDITOR"); dir != "" {
+		os.Exit(fakeEditor(dir, os.Args[len(os.Args)-1]))
+	}
+
+	os.Exit(m.Run())
+}
+
+func fakeEditor(dir, path string) int {
+	seen, _ := filepath.Glob(filepath.Join(dir, "seen-*"))
+	n := len(seen)
+
+	text, err := os.ReadFile(path)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, fmt.Sprintf("seen-%d", n)), text, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	reply, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("reply-%d", n)))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	}
+	if err == nil {
+		err = os.WriteFile(path, reply, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func TestLookupEditor(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InGOOS    string
+		InEnv     map[string]string
+		Out       string
+		OutErrMsg string
+	}{
+		{Name: "Set", InGOOS: "linux", InEnv: map[string]string{"EDITOR": "vim"}, Out: "vim"},
+		{Name: "SetOnWindows", InGOOS: "windows", InEnv: map[string]string{"EDITOR": "code --wait"}, Out: "code --wait"},
+		{Name: "UnsetOnWindows", InGOOS: "windows", Out: "notepad.exe"},
+		{Name: "BlankOnWindows", InGOOS: "windows", InEnv: map[string]string{"EDITOR": " "}, Out: "notepad.exe"},
+		{Name: "Unset", InGOOS: "linux", OutErrMsg: "expecting `EDITOR` environment variable to be set"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			editor, err := lookupEditor(td.InGOOS, func(k string) (string, bool) {
+				v, ok := td.InEnv[k]
+				return v, ok
+			})
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, editor)
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	testData := []struct {
+		Name     string
+		InGOOS   string
+		InEditor string
+		OutArgs  []string
+	}{
+		{
+			Name:     "UnixShell",
+			InGOOS:   "linux",
+			InEditor: "code --wait",
+			OutArgs:  []string{"sh", "-c", `code --wait "$1"`, "sh", "/tmp/my file"},
+		},
+		{
+			Name:     "WindowsPlain",
+			InGOOS:   "windows",
+			InEditor: "notepad.exe",
+			OutArgs:  []string{"notepad.exe", "/tmp/my file"},
+		},
+		{
+			Name:     "WindowsQuotedPath",
+			InGOOS:   "windows",
+			InEditor: `"C:\Program Files\Notepad++\notepad++.exe" -multiInst  -nosession`,
+			OutArgs:  []string{`C:\Program Files\Notepad++\notepad++.exe`, "-multiInst", "-nosession", "/tmp/my file"},
+		},
+		{
+			Name:     "WindowsEmptyQuotedArg",
+			InGOOS:   "windows",
+			InEditor: `vim ""`,
+			OutArgs:  []string{"vim", "", "/tmp/my file"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.OutArgs, command(td.InGOOS, td.InEditor, "/tmp/my file").Args)
+		})
+	}
+}
+
+func TestFile_Name(t *testing.T) {
+	testData := []struct {
+		Name   string
+		InFile File
+		Out    string
+	}{
+		{Name: "Issue", InFile: File{Command: "edit", Key: "JIWA-12"}, Out: "jiwa-edit-JIWA-12.md"},
+		{Name: "NoIssue", InFile: File{Command: "create"}, Out: "jiwa-create.md"},
+		{Name: "Extension", InFile: File{Command: "comment", Key: "JIWA-1", Extension: "txt"}, Out: "jiwa-comment-JIWA-1.txt"},
+		{Name: "ExtensionWithDot", InFile: File{Command: "comment", Key: "JIWA-1", Extension: ".jira"}, Out: "jiwa-comment-JIWA-1.jira"},
+		{Name: "UnsafeCharacters", InFile: File{Command: "edit", Key: "../JIWA 1", Extension: "m/d"}, Out: "jiwa-edit-___JIWA_1.m_d"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, td.InFile.Name())
+		})
+	}
+}
+
+func TestCreateFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jiwa", "edit")
+	file := File{Command: "edit", Key: "JIWA-1"}
+
+	first, err := createFile(dir, file)
+	assert.NoError(t, err)
+	defer first.Close()
+	assert.Equal(t, filepath.Join(dir, "jiwa-edit-JIWA-1.md"), first.Name())
+
+	// the same issue being edited a second time doesn't clobber the first
+	second, err := createFile(dir, file)
+	assert.NoError(t, err)
+	defer second.Close()
+	assert.NotEqual(t, first.Name(), second.Name())
+	assert.True(t, strings.HasPrefix(filepath.Base(second.Name()), "jiwa-edit-JIWA-1-"))
+	assert.Equal(t, ".md", filepath.Ext(second.Name()))
+}
+
+func TestEditUntilValid(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is run through sh")
+	}
+
+	errBad := errors.New("the summary is bad\nreally bad")
+	check := func(text string) error {
+		if strings.Contains(text, "bad") {
+			return errBad
+		}
+		return nil
+	}
+	comment := "#jiwa: the summary is bad\n#jiwa: really bad\n#jiwa: fix it and save to try again, empty the file to abort\n"
+
+	testData := []struct {
+		Name      string
+		InReplies []string
+		Out       string
+		OutSeen   []string
+		OutErrMsg string
+	}{
+		{
+			Name:      "Valid",
+			InReplies: []string{"good\n"},
+			Out:       "good\n",
+			OutSeen:   []string{"prefill\n"},
+		},
+		{
+			Name:      "ReopenedWithError",
+			InReplies: []string{"bad\n", comment + "good\n"},
+			Out:       "good\n",
+			OutSeen:   []string{"prefill\n", comment + "bad\n"},
+		},
+		{
+			Name:      "ErrorCommentRemovedByUser",
+			InReplies: []string{"bad\n", "good\n"},
+			Out:       "good\n",
+			OutSeen:   []string{"prefill\n", comment + "bad\n"},
+		},
+		{
+			Name:      "Emptied",
+			InReplies: []string{"bad\n", comment},
+			OutSeen:   []string{"prefill\n", comment + "bad\n"},
+			OutErrMsg: ErrEmpty.Error(),
+		},
+		{
+			Name:      "SavedWithoutChanges",
+			InReplies: []string{"bad\n"},
+			OutSeen:   []string{"prefill\n", comment + "bad\n"},
+			OutErrMsg: "the summary is bad\nreally bad, giving up as the file was saved without changes",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, reply := range td.InReplies {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("reply-%d", i)), []byte(reply), 0o600))
+			}
+			t.Setenv("JIWA_TEST_FAKE_EDITOR", dir)
+			t.Setenv("EDITOR", "'"+os.Args[0]+"'")
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+
+			text, err := EditUntilValid(File{Command: "create"}, "prefill\n", check)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.Out, text)
+
+			seen := make([]string, 0)
+			for i := 0; ; i++ {
+				b, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("seen-%d", i)))
+				if err != nil {
+					break
+				}
+				seen = append(seen, string(b))
+			}
+			assert.Equal(t, td.OutSeen, seen)
+		})
+	}
+}
+
+func TestStripErrorComment(t *testing.T) {
+	testData := []struct {
+		Name string
+		In   string
+		Out  string
+	}{
+		{Name: "None", In: "summary\n---\n#jiwa: kept\n", Out: "summary\n---\n#jiwa: kept\n"},
+		{Name: "OnTop", In: "#jiwa: too long\n#jiwa: fix it\nsummary\n", Out: "summary\n"},
+		{Name: "PrefixWithoutSpace", In: "#jiwa:\nsummary\n", Out: "summary\n"},
+		{Name: "OnlyComment", In: "#jiwa: too long", Out: ""},
+		{Name: "Heading", In: "# jiwa: a heading\n", Out: "# jiwa: a heading\n"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, StripErrorComment(td.In))
+		})
+	}
+}
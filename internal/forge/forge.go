@@ -0,0 +1,306 @@
+// Package forge reads the issues of a GitHub repository or a GitLab project
+// for jiwa import to move them into Jira.
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageSize is the most issues both APIs return per page
+const pageSize = 100
+
+// maxRetries is how often a rate limited request is retried
+const maxRetries = 5
+
+// Issue is an issue of the source with what Jira takes of it
+type Issue struct {
+	// Number is the number of the issue in its repository or project,
+	// #12 on GitHub or GitLab
+	Number int
+	Title  string
+	// Body is Markdown
+	Body   string
+	Labels []string
+	Closed bool
+	URL    string
+}
+
+// Source lists the issues of a repository or project
+type Source interface {
+	// Issues calls fn with every page of issues, oldest first
+	Issues(ctx context.Context, fn func(page []Issue) error) error
+	// String names the source, e.g. github:catouc/jiwa
+	String() string
+}
+
+// Config is shared by the sources
+type Config struct {
+	// BaseURL is the API, it defaults to the one of github.com or
+	// gitlab.com
+	BaseURL string
+	Token   string
+	Client  *http.Client
+	// Log is told when the source rate limits and the import waits
+	Log io.Writer
+	// Sleep waits before a rate limited request is retried, defaults to
+	// waiting on a timer
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns the source of kind "github" for an owner/repo or "gitlab"
+// for a project path like group/project
+func New(kind, repo string, cfg Config) (Source, error) {
+	if strings.Count(strings.Trim(repo, "/"), "/") == 0 {
+		return nil, fmt.Errorf("%q needs to be the full path of the repository, e.g. catouc/jiwa", repo)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Log == nil {
+		cfg.Log = io.Discard
+	}
+	if cfg.Sleep == nil {
+		cfg.Sleep = sleep
+	}
+
+	switch kind {
+	case "github":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.github.com"
+		}
+		return &GitHub{Config: cfg, Repo: strings.Trim(repo, "/")}, nil
+	case "gitlab":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://gitlab.com/api/v4"
+		}
+		return &GitLab{Config: cfg, Project: strings.Trim(repo, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q, use \"github\" or \"gitlab\"", kind)
+	}
+}
+
+// GitHub reads the issues of a repository, pull requests are left out
+type GitHub struct {
+	Config
+	Repo string
+}
+
+func (g *GitHub) String() string {
+	return "github:" + g.Repo
+}
+
+func (g *GitHub) Issues(ctx context.Context, fn func(page []Issue) error) error {
+	next := fmt.Sprintf("%s/repos/%s/issues?state=all&sort=created&direction=asc&per_page=%d", strings.TrimSuffix(g.BaseURL, "/"), g.Repo, pageSize)
+	for next != "" {
+		var page []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Body   string `json:"body"`
+			State  string `json:"state"`
+			URL    string `json:"html_url"`
+			Labels []struct {
+				Name string `json:"name"`
+			} `json:"labels"`
+			PullRequest json.RawMessage `json:"pull_request"`
+		}
+
+		var err error
+		next, err = get(ctx, g.Config, next, &page, func(req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			if g.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+g.Token)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list the issues of %s: %w", g, err)
+		}
+
+		issues := make([]Issue, 0, len(page))
+		for _, i := range page {
+			if i.PullRequest != nil {
+				continue
+			}
+
+			labels := make([]string, 0, len(i.Labels))
+			for _, l := range i.Labels {
+				labels = append(labels, l.Name)
+			}
+			issues = append(issues, Issue{
+				Number: i.Number,
+				Title:  i.Title,
+				Body:   i.Body,
+				Labels: labels,
+				Closed: i.State == "closed",
+				URL:    i.URL,
+			})
+		}
+
+		err = fn(issues)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GitLab reads the issues of a project
+type GitLab struct {
+	Config
+	Project string
+}
+
+func (g *GitLab) String() string {
+	return "gitlab:" + g.Project
+}
+
+func (g *GitLab) Issues(ctx context.Context, fn func(page []Issue) error) error {
+	next := fmt.Sprintf("%s/projects/%s/issues?scope=all&state=all&order_by=created_at&sort=asc&per_page=%d", strings.TrimSuffix(g.BaseURL, "/"), url.PathEscape(g.Project), pageSize)
+	for next != "" {
+		var page []struct {
+			IID         int      `json:"iid"`
+			Title       string   `json:"title"`
+			Description string   `json:"description"`
+			State       string   `json:"state"`
+			URL         string   `json:"web_url"`
+			Labels      []string `json:"labels"`
+		}
+
+		var err error
+		next, err = get(ctx, g.Config, next, &page, func(req *http.Request) {
+			if g.Token != "" {
+				req.Header.Set("PRIVATE-TOKEN", g.Token)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list the issues of %s: %w", g, err)
+		}
+
+		issues := make([]Issue, 0, len(page))
+		for _, i := range page {
+			issues = append(issues, Issue{
+				Number: i.IID,
+				Title:  i.Title,
+				Body:   i.Description,
+				Labels: i.Labels,
+				Closed: i.State == "closed",
+				URL:    i.URL,
+			})
+		}
+
+		err = fn(issues)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get decodes the page at u into v and returns the URL of the next page,
+// which is empty on the last one. Rate limited requests are retried once
+// the limit resets.
+func get(ctx context.Context, cfg Config, u string, v any, auth func(*http.Request)) (string, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return "", err
+		}
+		auth(req)
+
+		resp, err := cfg.Client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		wait, limited := rateLimitWait(resp, time.Now())
+		if limited && attempt < maxRetries {
+			resp.Body.Close()
+			fmt.Fprintf(cfg.Log, "rate limited by %s, waiting %s\n", req.URL.Host, wait.Round(time.Second))
+			err = cfg.Sleep(ctx, wait)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return "", fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode %s: %w", u, err)
+		}
+
+		return nextLink(resp.Header.Get("Link")), nil
+	}
+}
+
+// rateLimitWait tells a rate limited response apart from one that was
+// refused, GitHub answers both with 403. Retry-After wins over the time the
+// limit resets at.
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		remaining = resp.Header.Get("RateLimit-Remaining")
+	}
+	reset := resp.Header.Get("X-RateLimit-Reset")
+	if reset == "" {
+		reset = resp.Header.Get("RateLimit-Reset")
+	}
+
+	if remaining != "0" && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	wait := time.Minute
+	if unix, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		wait = time.Unix(unix, 0).Sub(now) + time.Second
+	}
+
+	return max(wait, time.Second), true
+}
+
+var linkNextRe = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextLink returns the URL of the next page from a Link header
+func nextLink(header string) string {
+	m := linkNextRe.FindStringSubmatch(header)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// server answers the pages of issues, limited is how many requests are
+// rate limited before the first page is answered
+func server(t *testing.T, pages []string, limited int, limitHeaders map[string]string) (*httptest.Server, *[]*http.Request) {
+	var mu sync.Mutex
+	requests := make([]*http.Request, 0)
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+
+		if limited > 0 {
+			limited--
+			for k, v := range limitHeaders {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page+1 < len(pages) {
+			next := *r.URL
+			q := next.Query()
+			q.Set("page", strconv.Itoa(page+1))
+			next.RawQuery = q.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next", <%s%s>; rel="last"`, srv.URL, next.String(), srv.URL, next.String()))
+		}
+		fmt.Fprint(w, pages[page])
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func TestSource_Issues(t *testing.T) {
+	testData := []struct {
+		Name           string
+		InKind         string
+		InRepo         string
+		InPages        []string
+		InLimited      int
+		InLimitHeaders map[string]string
+		OutIssues      []Issue
+		OutPath        string
+		OutAuth        map[string]string
+		OutRequests    int
+		OutSlept       []time.Duration
+		OutErrMsg      string
+	}{
+		{
+			Name:   "GitHub",
+			InKind: "github",
+			InRepo: "catouc/jiwa",
+			InPages: []string{
+				`[{"number": 1, "title": "Crash", "body": "**boom**", "state": "open", "html_url": "https://github.com/catouc/jiwa/issues/1", "labels": [{"name": "bug"}]},
+				  {"number": 2, "title": "Fix crash", "state": "closed", "pull_request": {}}]`,
+				`[{"number": 3, "title": "Old", "body": null, "state": "closed", "labels": []}]`,
+			},
+			OutIssues: []Issue{
+				{Number: 1, Title: "Crash", Body: "**boom**", Labels: []string{"bug"}, URL: "https://github.com/catouc/jiwa/issues/1"},
+				{Number: 3, Title: "Old", Labels: []string{}, Closed: true},
+			},
+			OutPath:     "/repos/catouc/jiwa/issues",
+			OutAuth:     map[string]string{"Authorization": "Bearer secret"},
+			OutRequests: 2,
+		},
+		{
+			Name:   "GitLab",
+			InKind: "gitlab",
+			InRepo: "catouc/tools/jiwa",
+			InPages: []string{
+				`[{"iid": 7, "title": "Crash", "description": "boom", "state": "opened", "web_url": "https://gitlab.com/catouc/tools/jiwa/-/issues/7", "labels": ["bug", "ui"]}]`,
+				`[{"iid": 8, "title": "Old", "state": "closed", "labels": []}]`,
+			},
+			OutIssues: []Issue{
+				{Number: 7, Title: "Crash", Body: "boom", Labels: []string{"bug", "ui"}, URL: "https://gitlab.com/catouc/tools/jiwa/-/issues/7"},
+				{Number: 8, Title: "Old", Labels: []string{}, Closed: true},
+			},
+			OutPath:     "/projects/catouc%2Ftools%2Fjiwa/issues",
+			OutAuth:     map[string]string{"PRIVATE-TOKEN": "secret"},
+			OutRequests: 2,
+		},
+		{
+			Name:           "RateLimitedWithRetryAfter",
+			InKind:         "gitlab",
+			InRepo:         "catouc/jiwa",
+			InPages:        []string{`[]`},
+			InLimited:      2,
+			InLimitHeaders: map[string]string{"Retry-After": "30"},
+			OutIssues:      []Issue{},
+			OutPath:        "/projects/catouc%2Fjiwa/issues",
+			OutAuth:        map[string]string{"PRIVATE-TOKEN": "secret"},
+			OutRequests:    3,
+			OutSlept:       []time.Duration{30 * time.Second, 30 * time.Second},
+		},
+		{
+			Name:           "RateLimitedUntilReset",
+			InKind:         "github",
+			InRepo:         "catouc/jiwa",
+			InPages:        []string{`[]`},
+			InLimited:      1,
+			InLimitHeaders: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "0"},
+			OutIssues:      []Issue{},
+			OutPath:        "/repos/catouc/jiwa/issues",
+			OutAuth:        map[string]string{"Authorization": "Bearer secret"},
+			OutRequests:    2,
+			OutSlept:       []time.Duration{time.Second},
+		},
+		{
+			Name:           "Forbidden",
+			InKind:         "github",
+			InRepo:         "catouc/jiwa",
+			InPages:        []string{`[]`},
+			InLimited:      1,
+			InLimitHeaders: map[string]string{"X-RateLimit-Remaining": "4999"},
+			OutErrMsg:      `failed to list the issues of github:catouc/jiwa: 403 Forbidden: {"message": "API rate limit exceeded"}`,
+		},
+		{
+			Name:      "NotARepository",
+			InKind:    "github",
+			InRepo:    "jiwa",
+			OutErrMsg: `"jiwa" needs to be the full path of the repository, e.g. catouc/jiwa`,
+		},
+		{
+			Name:      "UnknownKind",
+			InKind:    "gitea",
+			InRepo:    "catouc/jiwa",
+			OutErrMsg: `unknown source "gitea", use "github" or "gitlab"`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv, requests := server(t, td.InPages, td.InLimited, td.InLimitHeaders)
+
+			slept := make([]time.Duration, 0)
+			src, err := New(td.InKind, td.InRepo, Config{
+				BaseURL: srv.URL,
+				Token:   "secret",
+				Sleep: func(_ context.Context, d time.Duration) error {
+					slept = append(slept, d)
+					return nil
+				},
+			})
+			if err == nil {
+				issues := make([]Issue, 0)
+				err = src.Issues(context.Background(), func(page []Issue) error {
+					issues = append(issues, page...)
+					return nil
+				})
+				if err == nil {
+					assert.Equal(t, td.OutIssues, issues)
+				}
+			}
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Len(t, *requests, td.OutRequests)
+			for _, r := range *requests {
+				assert.Equal(t, td.OutPath, r.URL.EscapedPath())
+				assert.Equal(t, "all", r.URL.Query().Get("state"))
+				assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+				for k, v := range td.OutAuth {
+					assert.Equal(t, v, r.Header.Get(k))
+				}
+			}
+			if td.OutSlept == nil {
+				td.OutSlept = []time.Duration{}
+			}
+			assert.Equal(t, td.OutSlept, slept)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package git reads the commits of the repository jiwa runs in, for jiwa
+// commits to record them on an issue.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ErrNotARepository is returned when the directory isn't inside a git
+// repository
+var ErrNotARepository = errors.New("not inside a git repository")
+
+// Commit is a commit as git log reports it
+type Commit struct {
+	Hash      string
+	ShortHash string
+	Subject   string
+	// Body is the message without the subject
+	Body string
+}
+
+// RunFunc runs git with the arguments in dir and returns what it printed
+// to stdout, tests replace it to fake git
+type RunFunc func(ctx context.Context, dir string, args ...string) ([]byte, error)
+
+// Exec runs the git binary with the arguments as they are, no shell is
+// involved. A failing git returns an error with what it wrote to stderr.
+func Exec(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errors.New("git is not installed or not in $PATH")
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+
+	return out, nil
+}
+
+// Repo is the repository in Dir, the zero value is the one of the working
+// directory read with Exec
+type Repo struct {
+	Dir string
+	Run RunFunc
+}
+
+// logFormat separates the fields of a commit with the unit separator and
+// the commits with the record separator, neither shows up in messages
+const logFormat = "--format=%H%x1f%h%x1f%s%x1f%b%x1e"
+
+// Log returns the commits of the revision range, e.g. origin/main..HEAD,
+// newest first
+func (r Repo) Log(ctx context.Context, revisions string) ([]Commit, error) {
+	if strings.HasPrefix(revisions, "-") {
+		return nil, fmt.Errorf("%q is not a revision range", revisions)
+	}
+
+	out, err := r.log(ctx, logFormat, revisions, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLog(out), nil
+}
+
+// Mentioning returns the commits reachable from HEAD whose message
+// mentions the issue key, ignoring case, newest first. JIWA-12 doesn't
+// match JIWA-123.
+func (r Repo) Mentioning(ctx context.Context, key string) ([]Commit, error) {
+	out, err := r.log(ctx, logFormat, "--regexp-ignore-case", "--fixed-strings", "--grep="+key, "HEAD", "--")
+	if err != nil {
+		return nil, err
+	}
+
+	keyRegEx := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(key) + `\b`)
+	commits := make([]Commit, 0)
+	for _, commit := range parseLog(out) {
+		if keyRegEx.MatchString(commit.Subject) || keyRegEx.MatchString(commit.Body) {
+			commits = append(commits, commit)
+		}
+	}
+
+	return commits, nil
+}
+
+func (r Repo) log(ctx context.Context, args ...string) ([]byte, error) {
+	run := r.Run
+	if run == nil {
+		run = Exec
+	}
+
+	// git's own message outside a repository is hard to read and differs
+	// between versions
+	_, err := run(ctx, r.Dir, "rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		if strings.Contains(err.Error(), "not a git repository") {
+			return nil, ErrNotARepository
+		}
+		return nil, err
+	}
+
+	return run(ctx, r.Dir, append([]string{"log"}, args...)...)
+}
+
+func parseLog(out []byte) []Commit {
+	commits := make([]Commit, 0)
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:      fields[0],
+			ShortHash: fields[1],
+			Subject:   fields[2],
+			Body:      strings.TrimSpace(fields[3]),
+		})
+	}
+
+	return commits
+}
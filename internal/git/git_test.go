@@ -0,0 +1,99 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGit answers rev-parse and hands out for log, the arguments of every
+// call are recorded
+func fakeGit(out string, repoErr error, calls *[][]string) RunFunc {
+	return func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		*calls = append(*calls, args)
+		if args[0] == "rev-parse" {
+			return []byte("true\n"), repoErr
+		}
+		return []byte(out), nil
+	}
+}
+
+const testLog = "3c4d5e6f\x1f3c4d5e6\x1fJIWA-123: Cache the token\x1f\x1e\n" +
+	"2b3c4d5e\x1f2b3c4d5\x1fRetry the login\x1fFixes jiwa-12 for good\n\x1e\n" +
+	"1a2b3c4d\x1f1a2b3c4\x1fJIWA-12 Fix the redirect\x1f\x1e\n"
+
+func TestRepo_Log(t *testing.T) {
+	var calls [][]string
+	r := Repo{Run: fakeGit(testLog, nil, &calls)}
+
+	commits, err := r.Log(context.Background(), "origin/main..HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, []Commit{
+		{Hash: "3c4d5e6f", ShortHash: "3c4d5e6", Subject: "JIWA-123: Cache the token"},
+		{Hash: "2b3c4d5e", ShortHash: "2b3c4d5", Subject: "Retry the login", Body: "Fixes jiwa-12 for good"},
+		{Hash: "1a2b3c4d", ShortHash: "1a2b3c4", Subject: "JIWA-12 Fix the redirect"},
+	}, commits)
+	assert.Equal(t, []string{"log", logFormat, "origin/main..HEAD", "--"}, calls[1])
+
+	_, err = r.Log(context.Background(), "--output=/tmp/x")
+	assert.EqualError(t, err, `"--output=/tmp/x" is not a revision range`)
+}
+
+func TestRepo_Mentioning(t *testing.T) {
+	var calls [][]string
+	r := Repo{Run: fakeGit(testLog, nil, &calls)}
+
+	commits, err := r.Mentioning(context.Background(), "JIWA-12")
+	assert.NoError(t, err)
+
+	hashes := make([]string, 0, len(commits))
+	for _, c := range commits {
+		hashes = append(hashes, c.ShortHash)
+	}
+	assert.Equal(t, []string{"2b3c4d5", "1a2b3c4"}, hashes, "JIWA-123 isn't JIWA-12")
+	assert.Contains(t, calls[1], "--grep=JIWA-12")
+}
+
+func TestRepo_NotARepository(t *testing.T) {
+	var calls [][]string
+	r := Repo{Run: fakeGit("", errors.New("git rev-parse failed: fatal: not a git repository (or any of the parent directories): .git"), &calls)}
+
+	_, err := r.Log(context.Background(), "origin/main..HEAD")
+	assert.ErrorIs(t, err, ErrNotARepository)
+	assert.Len(t, calls, 1, "git log doesn't run")
+}
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	_, err := Repo{Dir: dir}.Log(context.Background(), "HEAD")
+	assert.ErrorIs(t, err, ErrNotARepository)
+
+	env := []string{"-c", "user.name=Alice", "-c", "user.email=alice@example.com"}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		append(env, "commit", "-q", "--allow-empty", "-m", "JIWA-12 Fix the redirect"),
+		append(env, "commit", "-q", "--allow-empty", "-m", "Retry the login; echo $(id)", "-m", "Fixes JIWA-12"),
+	} {
+		_, err := Exec(context.Background(), dir, args...)
+		assert.NoError(t, err)
+	}
+
+	commits, err := Repo{Dir: dir}.Mentioning(context.Background(), "jiwa-12")
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "Retry the login; echo $(id)", commits[0].Subject, "nothing goes through a shell")
+	assert.Equal(t, "Fixes JIWA-12", commits[0].Body)
+	assert.Len(t, commits[0].ShortHash, 7)
+	assert.True(t, strings.HasPrefix(commits[0].Hash, commits[0].ShortHash))
+
+	_, err = Repo{Dir: dir}.Log(context.Background(), "nope..HEAD")
+	assert.ErrorContains(t, err, "git log failed: fatal:")
+}
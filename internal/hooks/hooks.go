@@ -0,0 +1,139 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Names lists all hooks that can be configured, every mutating command
+// has a pre- hook that can abort it and a post- hook that runs after the
+// change went through.
+var Names = []string{
+	"pre-backlog", "post-backlog",
+	"pre-comment", "post-comment",
+	"pre-component", "post-component",
+	"pre-create", "post-create",
+	"pre-edit", "post-edit",
+	"pre-estimate", "post-estimate",
+	"pre-flag", "post-flag",
+	"pre-label", "post-label",
+	"pre-move", "post-move",
+	"pre-parent", "post-parent",
+	"pre-reassign", "post-reassign",
+	"pre-sprint", "post-sprint",
+	"pre-unflag", "post-unflag",
+}
+
+const DefaultTimeout = 10 * time.Second
+
+// Payload is what gets handed to a hook on stdin as JSON, fields that
+// don't apply to the operation are omitted.
+type Payload struct {
+	Hook        string   `json:"hook"`
+	Key         string   `json:"key,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Project     string   `json:"project,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Component   string   `json:"component,omitempty"`
+	Components  []string `json:"components,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Sprint      string   `json:"sprint,omitempty"`
+	Estimate    string   `json:"estimate,omitempty"`
+	// Parent is empty when the issue is detached from its parent
+	Parent string `json:"parent,omitempty"`
+}
+
+// ExamplePayload is a filled in payload to document what hooks receive.
+func ExamplePayload() Payload {
+	return Payload{
+		Hook:        "post-create",
+		Key:         "JIWA-1",
+		URL:         "https://catouc.atlassian.net/browse/JIWA-1",
+		Project:     "JIWA",
+		Summary:     "Summary line of my ticket",
+		Description: "Description that can be quite long\nand span multiple lines.\n",
+		Type:        "Task",
+		Component:   "backend",
+		Components:  []string{"backend", "api"},
+		Labels:      []string{"on-call", "urgent"},
+		Comment:     "only set for comment hooks and moves with a comment",
+		Status:      "only set for move hooks",
+		Assignee:    "only set for reassign hooks",
+		Sprint:      "only set for sprint hooks",
+		Estimate:    "only set for estimate hooks",
+		Parent:      "only set for parent hooks",
+	}
+}
+
+// Runner executes the configured hooks, the zero value runs nothing.
+type Runner struct {
+	// Hooks maps a hook name like "pre-create" to a command that is run
+	// through `sh -c`.
+	Hooks   map[string]string
+	Timeout time.Duration
+	// DryRun skips all hooks, since nothing is actually mutated.
+	DryRun bool
+}
+
+// Run executes the hook registered under name with the payload on stdin
+// and JIWA_HOOK, JIWA_ISSUE_KEY and JIWA_PROJECT set in its environment.
+// A non-zero exit or running into the timeout returns an error that
+// contains whatever the hook wrote to stderr.
+func (r *Runner) Run(ctx context.Context, name string, payload Payload) error {
+	command, ok := r.Hooks[name]
+	if !ok || command == "" || r.DryRun {
+		return nil
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload.Hook = name
+	in, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload for hook %s: %w", name, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"JIWA_HOOK="+name,
+		"JIWA_ISSUE_KEY="+payload.Key,
+		"JIWA_PROJECT="+payload.Project,
+	)
+	// don't wait on grandchildren holding on to stderr after the kill
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	// stdout is reserved for issue keys so pipelines keep working,
+	// anything the hook wants to tell the user goes to stderr
+	os.Stderr.Write(stdout.Bytes())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("hook %s timed out after %s", name, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("hook %s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
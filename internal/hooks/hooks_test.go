@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Run(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InRunner  Runner
+		InHook    string
+		OutErrMsg string
+	}{
+		{
+			Name:     "NoHookConfigured",
+			InRunner: Runner{},
+			InHook:   "pre-create",
+		},
+		{
+			Name: "HookSucceeds",
+			InRunner: Runner{Hooks: map[string]string{
+				"pre-create": `test "$JIWA_PROJECT" = JIWA && grep -q '"summary":"lint me"'`,
+			}},
+			InHook: "pre-create",
+		},
+		{
+			Name: "HookFailsWithStderr",
+			InRunner: Runner{Hooks: map[string]string{
+				"pre-create": "echo 'summary must start with a verb' >&2; exit 1",
+			}},
+			InHook:    "pre-create",
+			OutErrMsg: "hook pre-create failed: exit status 1: summary must start with a verb",
+		},
+		{
+			Name: "HookTimesOut",
+			InRunner: Runner{
+				Hooks:   map[string]string{"post-create": "sleep 5"},
+				Timeout: 50 * time.Millisecond,
+			},
+			InHook:    "post-create",
+			OutErrMsg: "hook post-create timed out after 50ms",
+		},
+		{
+			Name: "DryRunSkipsHooks",
+			InRunner: Runner{
+				Hooks:  map[string]string{"pre-create": "exit 1"},
+				DryRun: true,
+			},
+			InHook: "pre-create",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			err := td.InRunner.Run(context.Background(), td.InHook, Payload{Project: "JIWA", Summary: "lint me"})
+
+			if td.OutErrMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, td.OutErrMsg)
+			}
+		})
+	}
+}
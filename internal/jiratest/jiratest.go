@@ -0,0 +1,1371 @@
+// Package jiratest runs a fake Jira on an httptest.Server. It implements
+// the endpoints jiwa talks to on top of an in-memory issue store and can be
+// told to fail requests in the ways a real instance does.
+package jiratest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// Failure is a canned error mode for the fake server
+type Failure int
+
+const (
+	// FailNone answers requests normally
+	FailNone Failure = iota
+	// FailUnauthorized answers with a 401 as if the credentials were wrong
+	FailUnauthorized
+	// FailRateLimited answers with a 429 and a Retry-After header
+	FailRateLimited
+	// FailMalformedJSON answers with a 200 and a body that is not JSON
+	FailMalformedJSON
+	// FailServerError answers with a 500
+	FailServerError
+)
+
+// Transition is what the transitions endpoint offers for every issue
+type Transition struct {
+	ID     string                     `json:"id"`
+	Name   string                     `json:"name"`
+	To     jira.Status                `json:"to"`
+	Fields map[string]TransitionField `json:"fields,omitempty"`
+}
+
+type TransitionField struct {
+	Name            string            `json:"name"`
+	Required        bool              `json:"required"`
+	HasDefaultValue bool              `json:"hasDefaultValue"`
+	Schema          map[string]string `json:"schema"`
+}
+
+// CreateField is a field of a create screen, AllowedValues are the choices
+// like {"id": "1", "value": "Web"}
+type CreateField struct {
+	Name            string              `json:"name"`
+	Required        bool                `json:"required"`
+	HasDefaultValue bool                `json:"hasDefaultValue"`
+	Schema          map[string]string   `json:"schema"`
+	AllowedValues   []map[string]string `json:"allowedValues,omitempty"`
+}
+
+// SecurityLevel is a level of a project's issue security scheme
+type SecurityLevel struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Request is a request the server received, kept for assertions
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+	// ResponseSize is the number of bytes in the response body
+	ResponseSize int
+	// Status is the status code of the response
+	Status int
+	// IfNoneMatch is the ETag the client sent to revalidate its copy
+	IfNoneMatch string
+}
+
+// Server is the fake Jira, fill the exported fields before the requests
+// that depend on them are made.
+type Server struct {
+	*httptest.Server
+
+	// Username and Password or Token are the credentials that are accepted
+	Username string
+	Password string
+	Token    string
+
+	// RetryAfter is sent along with FailRateLimited, defaults to "1"
+	RetryAfter string
+	// PageSize caps the issues in a page of search results, defaults to 50
+	PageSize int
+	// DeploymentType is reported by serverInfo, "Cloud" by default and
+	// "Server" to behave like a self-hosted instance
+	DeploymentType string
+	// Groups are the groups of the authenticated user
+	Groups []string
+	// LockClosed rejects edits of issues in a done status like workflows
+	// that make closed issues read-only
+	LockClosed bool
+	// Archiving answers the archive and restore endpoints like Data Center
+	// does, without it they don't exist like on Cloud
+	Archiving bool
+	// MoveStatus is the status the tasks of moves report, "COMPLETE" by
+	// default and e.g. "RUNNING" for moves that never finish
+	MoveStatus string
+
+	mu          sync.Mutex
+	issues      map[string]jira.Issue
+	projects    map[string]jira.Project
+	transitions []Transition
+	fields      []jira.Field
+	users       []jira.User
+	counters    map[string]int
+	requests    []Request
+	failure     Failure
+	failTimes   int
+	searchFunc  func(jql string, issues []jira.Issue) []jira.Issue
+	attachments map[string][]byte
+	linkTypes   []jira.IssueLinkType
+	createMeta  map[string]map[string]map[string]CreateField
+	denied      []string
+	security    map[string][]SecurityLevel
+	roles       map[string][]string
+	archived    map[string]bool
+	moved       map[string]string
+	moveTasks   []map[string]any
+}
+
+// NewServer starts a fake Jira that accepts the user "jiwa" with the
+// password "secret" or the token "token", it is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		Username:       "jiwa",
+		Password:       "secret",
+		Token:          "token",
+		RetryAfter:     "1",
+		PageSize:       50,
+		DeploymentType: "Cloud",
+		MoveStatus:     "COMPLETE",
+		issues:         make(map[string]jira.Issue),
+		projects:       make(map[string]jira.Project),
+		counters:       make(map[string]int),
+		attachments:    make(map[string][]byte),
+		createMeta:     make(map[string]map[string]map[string]CreateField),
+		security:       make(map[string][]SecurityLevel),
+		roles:          make(map[string][]string),
+		archived:       make(map[string]bool),
+		moved:          make(map[string]string),
+		transitions: []Transition{
+			{ID: "11", Name: "To Do", To: status("To Do", "new")},
+			{ID: "21", Name: "In Progress", To: status("In Progress", "indeterminate")},
+			{ID: "31", Name: "Done", To: status("Done", "done")},
+		},
+		linkTypes: []jira.IssueLinkType{
+			{ID: "10000", Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+			{ID: "10001", Name: "Relates", Inward: "relates to", Outward: "relates to"},
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func status(name, category string) jira.Status {
+	return jira.Status{Name: name, StatusCategory: jira.StatusCategory{Key: category}}
+}
+
+// AddIssue stores the issue as is, it needs at least a key
+func (s *Server) AddIssue(issue jira.Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if issue.Fields == nil {
+		issue.Fields = &jira.IssueFields{}
+	}
+	if issue.Fields.Status == nil {
+		st := status("To Do", "new")
+		issue.Fields.Status = &st
+	}
+	if issue.ID == "" {
+		issue.ID = strconv.Itoa(10000 + len(s.issues))
+	}
+	s.issues[issue.Key] = issue
+}
+
+// Issue returns the stored version of an issue
+func (s *Server) Issue(key string) (jira.Issue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[key]
+	return issue, ok
+}
+
+// Archived reports whether the issue is archived
+func (s *Server) Archived(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.archived[key]
+}
+
+func (s *Server) AddProject(p jira.Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.projects[p.Key] = p
+}
+
+// AddUser makes the user show up in the user picker and as assignable to
+// every issue
+func (s *Server) AddUser(u jira.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = append(s.users, u)
+}
+
+// SetTransitions replaces the transitions that are offered for every issue
+func (s *Server) SetTransitions(transitions ...Transition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transitions = transitions
+}
+
+// SetFields replaces the fields that are listed by the field endpoint
+func (s *Server) SetFields(fields ...jira.Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fields = fields
+}
+
+// SetCreateMeta sets the fields of the create screen of the issue type in
+// the project, projects and types without any aren't offered
+func (s *Server) SetCreateMeta(project, issueType string, fields map[string]CreateField) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.createMeta[project] == nil {
+		s.createMeta[project] = make(map[string]map[string]CreateField)
+	}
+	s.createMeta[project][issueType] = fields
+}
+
+// SetSecurityLevels sets the levels of the project's security scheme,
+// creating an issue with any other level fails
+func (s *Server) SetSecurityLevels(project string, levels ...SecurityLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.security[project] = levels
+}
+
+// SetRoles sets the names of the project's roles
+func (s *Server) SetRoles(project string, roles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles[project] = roles
+}
+
+// DenyPermissions makes mypermissions report the permission keys as
+// missing, in every project
+func (s *Server) DenyPermissions(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.denied = keys
+}
+
+// SetSearch makes the search endpoint answer with whatever f picks out of
+// all issues, without it every issue matches.
+func (s *Server) SetSearch(f func(jql string, issues []jira.Issue) []jira.Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.searchFunc = f
+}
+
+// Fail makes the next n requests fail with f, n <= 0 fails all of them
+// until Fail(FailNone, 0) is called.
+func (s *Server) Fail(f Failure, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failure = f
+	s.failTimes = n
+}
+
+// Requests returns every request the server received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cw := &countingWriter{ResponseWriter: w}
+	w = cw
+	defer func() {
+		s.requests = append(s.requests, Request{
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Query:        r.URL.RawQuery,
+			Body:         body,
+			ResponseSize: cw.n,
+			Status:       cw.status,
+			IfNoneMatch:  r.Header.Get("If-None-Match"),
+		})
+	}()
+
+	if s.fail(w) {
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "You are not authenticated. Authentication required to perform this operation.")
+		return
+	}
+
+	if id, ok := strings.CutPrefix(r.URL.Path, "/secure/attachment/"); ok && r.Method == http.MethodGet {
+		id, _, _ = strings.Cut(id, "/")
+		s.downloadAttachment(w, id)
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/rest/api/2/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "No endpoint at "+r.URL.Path)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	switch {
+	case r.Method == http.MethodGet && path == "search":
+		s.search(w, r)
+	case r.Method == http.MethodPost && path == "issue":
+		s.createIssue(w, body)
+	case r.Method == http.MethodGet && path == "issue/createmeta":
+		s.getCreateMeta(w, r.URL.Query())
+	case len(parts) == 2 && parts[0] == "issue":
+		switch r.Method {
+		case http.MethodGet:
+			s.getIssue(w, r, parts[1])
+		case http.MethodPut:
+			s.updateIssue(w, parts[1], body)
+		case http.MethodDelete:
+			s.deleteIssue(w, parts[1])
+		default:
+			writeError(w, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+		}
+	case len(parts) == 3 && parts[0] == "issue" && parts[2] == "transitions":
+		switch r.Method {
+		case http.MethodGet:
+			s.listTransitions(w, parts[1])
+		case http.MethodPost:
+			s.doTransition(w, parts[1], body)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+		}
+	case s.Archiving && r.Method == http.MethodPut && len(parts) == 3 && parts[0] == "issue" && (parts[2] == "archive" || parts[2] == "restore"):
+		s.setArchived(w, parts[1], parts[2] == "archive")
+	case s.DeploymentType == "Cloud" && r.Method == http.MethodPost && path == "bulk/issues/move":
+		s.moveIssues(w, body)
+	case s.DeploymentType == "Cloud" && r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "bulk" && parts[1] == "queue":
+		i, err := strconv.Atoi(parts[2])
+		if err != nil || i < 0 || i >= len(s.moveTasks) {
+			writeError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.moveTasks[i])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "issue" && parts[2] == "comment":
+		s.comment(w, parts[1], body)
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "issue" && parts[2] == "attachments":
+		s.addAttachment(w, r, parts[1], body)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "project":
+		s.getProject(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "project" && parts[2] == "components":
+		s.listComponents(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "project" && parts[2] == "statuses":
+		s.listStatuses(w)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "project" && parts[2] == "securitylevel":
+		levels := s.security[parts[1]]
+		if levels == nil {
+			levels = []SecurityLevel{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"levels": levels})
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "project" && parts[2] == "role":
+		roles := make(map[string]string, len(s.roles[parts[1]]))
+		for i, name := range s.roles[parts[1]] {
+			roles[name] = fmt.Sprintf("%s/rest/api/2/project/%s/role/%d", s.URL, parts[1], 10000+i)
+		}
+		writeJSON(w, http.StatusOK, roles)
+	case r.Method == http.MethodGet && path == "jql/autocompletedata/suggestions":
+		s.suggest(w, r.URL.Query())
+	case r.Method == http.MethodGet && path == "issueLinkType":
+		writeJSON(w, http.StatusOK, map[string]any{"issueLinkTypes": s.linkTypes})
+	case r.Method == http.MethodPost && path == "issueLink":
+		s.linkIssues(w, body)
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "issueLink":
+		s.deleteIssueLink(w, parts[1])
+	case r.Method == http.MethodGet && path == "serverInfo":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"baseUrl":        s.URL,
+			"version":        "9.4.0",
+			"deploymentType": s.DeploymentType,
+		})
+	case r.Method == http.MethodGet && path == "mypermissions":
+		s.myPermissions(w, r.URL.Query())
+	case r.Method == http.MethodGet && path == "myself":
+		s.myself(w, r.URL.Query())
+	case r.Method == http.MethodGet && path == "field":
+		writeJSON(w, http.StatusOK, s.fields)
+	case r.Method == http.MethodGet && path == "user/picker":
+		writeJSON(w, http.StatusOK, map[string]any{"users": s.matchUsers(r.URL.Query().Get("query"))})
+	case r.Method == http.MethodGet && path == "user/assignable/search":
+		writeJSON(w, http.StatusOK, s.matchUsers(r.URL.Query().Get("query")))
+	default:
+		writeError(w, http.StatusNotFound, "No endpoint for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// fail writes the configured failure and reports whether it did
+func (s *Server) fail(w http.ResponseWriter) bool {
+	if s.failure == FailNone {
+		return false
+	}
+
+	f := s.failure
+	if s.failTimes > 0 {
+		s.failTimes--
+		if s.failTimes == 0 {
+			s.failure = FailNone
+		}
+	}
+
+	switch f {
+	case FailUnauthorized:
+		writeError(w, http.StatusUnauthorized, "You are not authenticated. Authentication required to perform this operation.")
+	case FailRateLimited:
+		w.Header().Set("Retry-After", s.RetryAfter)
+		writeError(w, http.StatusTooManyRequests, "Rate limit exceeded.")
+	case FailMalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"issues": [`)
+	case FailServerError:
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+	}
+
+	return true
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		return user == s.Username && pass == s.Password
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.Token != "" && token == s.Token
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n      int
+	status int
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.n += n
+	return n, err
+}
+
+const epicNameSchema = "com.pyxis.greenhopper.jira:gh-epic-label"
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]any{
+		"errorMessages": []string{msg},
+		"errors":        map[string]string{},
+	})
+}
+
+func writeFieldError(w http.ResponseWriter, field, msg string) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"errorMessages": []string{},
+		"errors":        map[string]string{field: msg},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeETagJSON is writeJSON for a 200 with the hash of the body as its
+// ETag, or 304 Not Modified if the client sent the same one
+func writeETagJSON(w http.ResponseWriter, r *http.Request, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sum := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(b, '\n'))
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	jql := r.URL.Query().Get("jql")
+
+	keys := make([]string, 0, len(s.issues))
+	for k := range s.issues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	issues := make([]jira.Issue, 0, len(keys))
+	for _, k := range keys {
+		if !s.archived[k] {
+			issues = append(issues, s.issues[k])
+		}
+	}
+
+	if s.searchFunc != nil {
+		issues = s.searchFunc(jql, issues)
+	}
+
+	total := len(issues)
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	startAt = min(max(startAt, 0), total)
+	maxResults := s.PageSize
+	if m, err := strconv.Atoi(r.URL.Query().Get("maxResults")); err == nil && m >= 0 {
+		maxResults = min(m, s.PageSize)
+	}
+
+	page := make([]any, 0, maxResults)
+	for _, issue := range issues[startAt:min(startAt+maxResults, total)] {
+		picked, err := pickFields(issue, r.URL.Query().Get("fields"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		page = append(page, picked)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      total,
+		"issues":     page,
+	})
+}
+
+// matchUsers finds the users whose name, display name or e-mail starts
+// with the query
+func (s *Server) matchUsers(query string) []jira.User {
+	q := strings.ToLower(query)
+	users := make([]jira.User, 0)
+	for _, u := range s.users {
+		for _, v := range []string{u.Name, u.DisplayName, u.EmailAddress} {
+			if v != "" && strings.HasPrefix(strings.ToLower(v), q) {
+				users = append(users, u)
+				break
+			}
+		}
+	}
+
+	return users
+}
+
+// permissionNames are the permissions mypermissions knows
+var permissionNames = map[string]string{
+	"EDIT_ISSUES":       "Edit Issues",
+	"ASSIGN_ISSUES":     "Assign Issues",
+	"ADD_COMMENTS":      "Add Comments",
+	"TRANSITION_ISSUES": "Transition Issues",
+	"MODIFY_REPORTER":   "Modify Reporter",
+}
+
+// myPermissions requires the permissions parameter like Cloud does
+func (s *Server) myPermissions(w http.ResponseWriter, params url.Values) {
+	if params.Get("permissions") == "" {
+		writeError(w, http.StatusBadRequest, "The 'permissions' query parameter is required.")
+		return
+	}
+
+	permissions := make(map[string]any)
+	for _, key := range strings.Split(params.Get("permissions"), ",") {
+		name, ok := permissionNames[key]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Unrecognized permission "+key)
+			return
+		}
+		permissions[key] = map[string]any{"key": key, "name": name, "havePermission": !slices.Contains(s.denied, key)}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"permissions": permissions})
+}
+
+// myself reports the added user with the Username, the groups are only
+// included when they are expanded
+func (s *Server) myself(w http.ResponseWriter, params url.Values) {
+	me := jira.User{Name: s.Username, Key: s.Username, DisplayName: s.Username, Active: true}
+	for _, u := range s.users {
+		if u.Name == s.Username {
+			me = u
+		}
+	}
+
+	b, err := json.Marshal(me)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var resp map[string]any
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if slices.Contains(strings.Split(params.Get("expand"), ","), "groups") {
+		items := make([]map[string]string, 0, len(s.Groups))
+		for _, g := range s.Groups {
+			items = append(items, map[string]string{"name": g})
+		}
+		resp["groups"] = map[string]any{"size": len(items), "items": items}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, body []byte) {
+	var issue jira.Issue
+	err := json.Unmarshal(body, &issue)
+	if err != nil || issue.Fields == nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid issue: %v", err))
+		return
+	}
+
+	project := issue.Fields.Project.Key
+	if project == "" {
+		writeFieldError(w, "project", "project is required")
+		return
+	}
+
+	if issue.Fields.Summary == "" {
+		writeFieldError(w, "summary", "You must specify a summary of the issue.")
+		return
+	}
+
+	// Server insists on the Epic Name, Cloud dropped the field
+	if s.DeploymentType != "Cloud" && strings.EqualFold(issue.Fields.Type.Name, "epic") {
+		for _, f := range s.fields {
+			if f.Schema.Custom != epicNameSchema {
+				continue
+			}
+			if name, _ := issue.Fields.Unknowns[f.ID].(string); name == "" {
+				writeFieldError(w, f.ID, "Epic Name is required.")
+				return
+			}
+		}
+	}
+
+	// the level is sent by ID and stored with its name like Jira does
+	if v, ok := issue.Fields.Unknowns["security"].(map[string]any); ok {
+		id, _ := v["id"].(string)
+		i := slices.IndexFunc(s.security[project], func(l SecurityLevel) bool { return l.ID == id })
+		if i < 0 {
+			writeFieldError(w, "security", "Security level '"+id+"' is not valid.")
+			return
+		}
+		l := s.security[project][i]
+		issue.Fields.Unknowns["security"] = map[string]any{"id": l.ID, "name": l.Name, "description": l.Description}
+	}
+
+	s.counters[project]++
+	issue.Key = fmt.Sprintf("%s-%d", project, s.counters[project])
+	for _, exists := s.issues[issue.Key]; exists; _, exists = s.issues[issue.Key] {
+		s.counters[project]++
+		issue.Key = fmt.Sprintf("%s-%d", project, s.counters[project])
+	}
+	issue.ID = strconv.Itoa(10000 + len(s.issues))
+	issue.Self = s.URL + "/rest/api/2/issue/" + issue.ID
+	st := status("To Do", "new")
+	issue.Fields.Status = &st
+	s.issues[issue.Key] = issue
+
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"id":   issue.ID,
+		"key":  issue.Key,
+		"self": issue.Self,
+	})
+}
+
+// getCreateMeta answers like Jira does for projects and types it doesn't
+// know, by leaving them out. Without issuetypeNames every type of the
+// project is listed and the fields are only there when they are expanded.
+func (s *Server) getCreateMeta(w http.ResponseWriter, params url.Values) {
+	projects := make([]map[string]any, 0)
+	for _, key := range strings.Split(params.Get("projectKeys"), ",") {
+		types, ok := s.createMeta[key]
+		if !ok {
+			continue
+		}
+
+		names := strings.Split(params.Get("issuetypeNames"), ",")
+		if params.Get("issuetypeNames") == "" {
+			names = make([]string, 0, len(types))
+			for name := range types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+
+		issueTypes := make([]map[string]any, 0)
+		for _, name := range names {
+			fields, ok := types[name]
+			if !ok {
+				continue
+			}
+
+			issueType := map[string]any{"name": name}
+			if params.Get("expand") == "projects.issuetypes.fields" {
+				issueType["fields"] = fields
+			}
+			issueTypes = append(issueTypes, issueType)
+		}
+		projects = append(projects, map[string]any{"key": key, "issuetypes": issueTypes})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"projects": projects})
+}
+
+// getIssue honours the fields and expand parameters, the changelog and
+// rendered fields are only returned when they are expanded.
+// getIssue answers with an ETag of the issue like Jira does, a client that
+// sends it back gets 304 Not Modified until the issue changes
+func (s *Server) getIssue(w http.ResponseWriter, r *http.Request, key string) {
+	params := r.URL.Query()
+	if moved, ok := s.moved[key]; ok {
+		key = moved
+	}
+	issue, ok := s.issues[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	expand := strings.Split(params.Get("expand"), ",")
+	if !slices.Contains(expand, "changelog") {
+		issue.Changelog = nil
+	}
+	if !slices.Contains(expand, "renderedFields") {
+		issue.RenderedFields = nil
+	}
+
+	fields := params.Get("fields")
+	if fields == "" || fields == "*all" {
+		writeETagJSON(w, r, issue)
+		return
+	}
+
+	response, err := pickFields(issue, fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response["changelog"] = issue.Changelog
+	if issue.RenderedFields != nil {
+		// Jira only renders the fields that were asked for
+		response["renderedFields"], err = pickJSON(issue.RenderedFields, fields)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	writeETagJSON(w, r, response)
+}
+
+// pickFields narrows the issue down to the comma separated fields, like
+// Jira does for the fields parameter. The whole issue is kept without it.
+func pickFields(issue jira.Issue, fields string) (map[string]any, error) {
+	response := map[string]any{
+		"id":     issue.ID,
+		"key":    issue.Key,
+		"self":   issue.Self,
+		"fields": issue.Fields,
+	}
+	if fields == "" || fields == "*all" || fields == "*navigable" {
+		return response, nil
+	}
+
+	picked, err := pickJSON(issue.Fields, fields)
+	if err != nil {
+		return nil, err
+	}
+	response["fields"] = picked
+
+	return response, nil
+}
+
+// pickJSON marshals v and keeps the comma separated keys of the object
+func pickJSON(v any, keys string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	err = json.Unmarshal(b, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make(map[string]json.RawMessage)
+	for _, k := range strings.Split(keys, ",") {
+		if v, ok := all[k]; ok {
+			picked[k] = v
+		}
+	}
+
+	return picked, nil
+}
+
+// updateIssue only touches the fields that are sent, like Jira does. The
+// "update" operations are supported for labels and components.
+func (s *Server) updateIssue(w http.ResponseWriter, key string, body []byte) {
+	stored, ok := s.issues[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	if s.LockClosed && stored.Fields.Status != nil && stored.Fields.Status.StatusCategory.Key == "done" {
+		writeError(w, http.StatusBadRequest, "You do not have permission to edit issues in this status.")
+		return
+	}
+
+	var req struct {
+		Fields map[string]json.RawMessage              `json:"fields"`
+		Update map[string][]map[string]json.RawMessage `json:"update"`
+	}
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid update: %v", err))
+		return
+	}
+
+	b, err := json.Marshal(stored.Fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for k, v := range req.Fields {
+		if _, ok := req.Update[k]; ok {
+			writeFieldError(w, k, "Field '"+k+"' cannot be set. It is not on the appropriate screen, or unknown.")
+			return
+		}
+		fields[k] = v
+	}
+
+	comments := req.Update["comment"]
+	delete(req.Update, "comment")
+	for k, ops := range req.Update {
+		v, err := applyUpdate(k, fields[k], ops)
+		if err != nil {
+			writeFieldError(w, k, err.Error())
+			return
+		}
+		fields[k] = v
+	}
+
+	b, err = json.Marshal(fields)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var merged jira.IssueFields
+	err = json.Unmarshal(b, &merged)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid fields: %v", err))
+		return
+	}
+
+	for _, op := range comments {
+		var add struct {
+			Body json.RawMessage `json:"body"`
+		}
+		err = json.Unmarshal(op["add"], &add)
+		if err != nil {
+			writeFieldError(w, "comment", err.Error())
+			return
+		}
+		s.addComment(&merged, add.Body)
+	}
+
+	stored.Fields = &merged
+	s.issues[key] = stored
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyUpdate runs add, remove and set operations on a list field, labels
+// are plain strings and components objects identified by their name.
+func applyUpdate(field string, current json.RawMessage, ops []map[string]json.RawMessage) (json.RawMessage, error) {
+	if field != "labels" && field != "components" {
+		return nil, fmt.Errorf("the fake does not support updating %q", field)
+	}
+
+	var items []json.RawMessage
+	if len(current) != 0 && string(current) != "null" {
+		err := json.Unmarshal(current, &items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id := func(raw json.RawMessage) string {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(raw, &v) == nil && v.Name != "" {
+			return v.Name
+		}
+		var str string
+		json.Unmarshal(raw, &str)
+		return str
+	}
+
+	for _, op := range ops {
+		for verb, v := range op {
+			switch verb {
+			case "add":
+				items = append(items, v)
+			case "remove":
+				items = slices.DeleteFunc(items, func(i json.RawMessage) bool { return id(i) == id(v) })
+			case "set":
+				items = nil
+				err := json.Unmarshal(v, &items)
+				if err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("unknown operation %q", verb)
+			}
+		}
+	}
+
+	return json.Marshal(items)
+}
+
+func (s *Server) deleteIssue(w http.ResponseWriter, key string) {
+	if _, ok := s.issues[key]; !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	delete(s.issues, key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setArchived archives or restores the issue, archived issues are left
+// out of searches
+func (s *Server) setArchived(w http.ResponseWriter, key string, archived bool) {
+	if _, ok := s.issues[key]; !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	s.archived[key] = archived
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// moveIssues does the bulk move of Cloud right away, the task it queues is
+// complete when it is first asked about. Each issue gets the next key of
+// its target project, the old key keeps answering like on Jira.
+func (s *Server) moveIssues(w http.ResponseWriter, body []byte) {
+	var move struct {
+		TargetToSourcesMapping map[string]struct {
+			IssueIdsOrKeys []string `json:"issueIdsOrKeys"`
+		} `json:"targetToSourcesMapping"`
+	}
+	err := json.Unmarshal(body, &move)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid move: %v", err))
+		return
+	}
+
+	processed := make([]string, 0)
+	invalid := 0
+	for target, sources := range move.TargetToSourcesMapping {
+		project, typeID, _ := strings.Cut(target, ",")
+		p, ok := s.projects[project]
+		i := slices.IndexFunc(p.IssueTypes, func(t jira.IssueType) bool { return t.ID == typeID })
+		if !ok || i < 0 {
+			writeError(w, http.StatusBadRequest, "Target "+target+" is not a project and issue type of this instance.")
+			return
+		}
+
+		for _, key := range sources.IssueIdsOrKeys {
+			issue, ok := s.issues[key]
+			if !ok {
+				invalid++
+				continue
+			}
+
+			delete(s.issues, key)
+			s.counters[project]++
+			issue.Key = fmt.Sprintf("%s-%d", project, s.counters[project])
+			for _, exists := s.issues[issue.Key]; exists; _, exists = s.issues[issue.Key] {
+				s.counters[project]++
+				issue.Key = fmt.Sprintf("%s-%d", project, s.counters[project])
+			}
+			issue.Fields.Project = jira.Project{Key: project}
+			issue.Fields.Type = p.IssueTypes[i]
+			s.issues[issue.Key] = issue
+			s.moved[key] = issue.Key
+			processed = append(processed, issue.ID)
+		}
+	}
+
+	s.moveTasks = append(s.moveTasks, map[string]any{
+		"status":                          s.MoveStatus,
+		"processedAccessibleIssues":       processed,
+		"failedAccessibleIssues":          map[string][]string{},
+		"invalidOrInaccessibleIssueCount": invalid,
+		"progressPercent":                 100,
+	})
+	writeJSON(w, http.StatusCreated, map[string]string{"taskId": strconv.Itoa(len(s.moveTasks) - 1)})
+}
+
+func (s *Server) listTransitions(w http.ResponseWriter, key string) {
+	if _, ok := s.issues[key]; !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"transitions": s.transitions})
+}
+
+func (s *Server) doTransition(w http.ResponseWriter, key string, body []byte) {
+	issue, ok := s.issues[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	var req struct {
+		Transition struct {
+			ID string `json:"id"`
+		} `json:"transition"`
+		Fields map[string]json.RawMessage `json:"fields"`
+		Update struct {
+			Comment []struct {
+				Add struct {
+					Body json.RawMessage `json:"body"`
+				} `json:"add"`
+			} `json:"comment"`
+		} `json:"update"`
+	}
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid transition: %v", err))
+		return
+	}
+
+	for _, t := range s.transitions {
+		if t.ID != req.Transition.ID {
+			continue
+		}
+
+		for id, f := range t.Fields {
+			if _, set := req.Fields[id]; f.Required && !f.HasDefaultValue && !set {
+				writeFieldError(w, id, f.Name+" is required.")
+				return
+			}
+		}
+
+		st := t.To
+		issue.Fields.Status = &st
+		for _, c := range req.Update.Comment {
+			s.addComment(issue.Fields, c.Add.Body)
+		}
+		s.issues[key] = issue
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeError(w, http.StatusBadRequest, "Transition id '"+req.Transition.ID+"' is not valid for this issue.")
+}
+
+// addComment adds a comment sent along with an update or transition, ADF
+// bodies are kept as their JSON, plain ones as the text
+func (s *Server) addComment(fields *jira.IssueFields, body json.RawMessage) {
+	text := string(body)
+	_ = json.Unmarshal(body, &text)
+	if fields.Comments == nil {
+		fields.Comments = &jira.Comments{}
+	}
+	fields.Comments.Comments = append(fields.Comments.Comments, &jira.Comment{
+		ID:     strconv.Itoa(len(fields.Comments.Comments) + 1),
+		Author: jira.User{Name: s.Username},
+		Body:   text,
+	})
+}
+
+func (s *Server) comment(w http.ResponseWriter, key string, body []byte) {
+	issue, ok := s.issues[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	var c jira.Comment
+	err := json.Unmarshal(body, &c)
+	if err != nil || c.Body == "" {
+		writeFieldError(w, "comment", "Comment body can not be empty!")
+		return
+	}
+
+	if issue.Fields.Comments == nil {
+		issue.Fields.Comments = &jira.Comments{}
+	}
+	c.ID = strconv.Itoa(len(issue.Fields.Comments.Comments) + 1)
+	c.Author = jira.User{Name: s.Username}
+	issue.Fields.Comments.Comments = append(issue.Fields.Comments.Comments, &c)
+	s.issues[key] = issue
+
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// addAttachment stores the files of the multipart body, like Jira it
+// insists on the header that opts out of its XSRF check
+func (s *Server) addAttachment(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	issue, ok := s.issues[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	if r.Header.Get("X-Atlassian-Token") != "no-check" {
+		writeError(w, http.StatusForbidden, "XSRF check failed")
+		return
+	}
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		writeError(w, http.StatusUnsupportedMediaType, "the request is not multipart")
+		return
+	}
+
+	added := make([]jira.Attachment, 0)
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "malformed multipart body: "+err.Error())
+			return
+		}
+
+		content, _ := io.ReadAll(part)
+		id := strconv.Itoa(len(s.attachments) + 10000)
+		s.attachments[id] = content
+		a := jira.Attachment{
+			ID:       id,
+			Filename: part.FileName(),
+			Size:     len(content),
+			Content:  s.URL + "/secure/attachment/" + id + "/" + url.PathEscape(part.FileName()),
+		}
+		issue.Fields.Attachments = append(issue.Fields.Attachments, &a)
+		added = append(added, a)
+	}
+	s.issues[key] = issue
+
+	writeJSON(w, http.StatusOK, added)
+}
+
+func (s *Server) downloadAttachment(w http.ResponseWriter, id string) {
+	content, ok := s.attachments[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "The attachment does not exist.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(content)
+}
+
+// linkIssues records the link on both issues, each side sees the other
+// issue the way Jira returns them in "issuelinks"
+func (s *Server) linkIssues(w http.ResponseWriter, body []byte) {
+	var link jira.IssueLink
+	err := json.Unmarshal(body, &link)
+	if err != nil || link.InwardIssue == nil || link.OutwardIssue == nil {
+		writeError(w, http.StatusBadRequest, "The link needs an inward and an outward issue.")
+		return
+	}
+
+	i := slices.IndexFunc(s.linkTypes, func(t jira.IssueLinkType) bool { return t.Name == link.Type.Name })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "No issue link type with name '"+link.Type.Name+"' found.")
+		return
+	}
+
+	inward, inOK := s.issues[link.InwardIssue.Key]
+	outward, outOK := s.issues[link.OutwardIssue.Key]
+	if !inOK || !outOK {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	s.counters["issueLink"]++
+	id := strconv.Itoa(10000 + s.counters["issueLink"])
+	inward.Fields.IssueLinks = append(inward.Fields.IssueLinks, &jira.IssueLink{
+		ID:           id,
+		Type:         s.linkTypes[i],
+		OutwardIssue: linkedIssue(outward),
+	})
+	outward.Fields.IssueLinks = append(outward.Fields.IssueLinks, &jira.IssueLink{
+		ID:          id,
+		Type:        s.linkTypes[i],
+		InwardIssue: linkedIssue(inward),
+	})
+	s.issues[inward.Key] = inward
+	s.issues[outward.Key] = outward
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// linkedIssue is the other issue of a link, with the fields Jira includes
+func linkedIssue(issue jira.Issue) *jira.Issue {
+	return &jira.Issue{ID: issue.ID, Key: issue.Key, Fields: &jira.IssueFields{Summary: issue.Fields.Summary, Status: issue.Fields.Status}}
+}
+
+func (s *Server) deleteIssueLink(w http.ResponseWriter, id string) {
+	found := false
+	for key, issue := range s.issues {
+		links := slices.DeleteFunc(slices.Clone(issue.Fields.IssueLinks), func(l *jira.IssueLink) bool { return l.ID == id })
+		if len(links) != len(issue.Fields.IssueLinks) {
+			issue.Fields.IssueLinks = links
+			s.issues[key] = issue
+			found = true
+		}
+	}
+
+	if !found {
+		writeError(w, http.StatusNotFound, "No issue link with id '"+id+"' exists.")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, key string) {
+	p, ok := s.projects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No project could be found with key '"+key+"'.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// suggest answers the JQL autocompletion for labels with the labels of the
+// issues, the displayName marks the prefix in bold like Jira does
+func (s *Server) suggest(w http.ResponseWriter, query url.Values) {
+	results := make([]map[string]string, 0)
+	if query.Get("fieldName") != "labels" {
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+		return
+	}
+
+	prefix := query.Get("fieldValue")
+	labels := make([]string, 0)
+	for _, issue := range s.issues {
+		if issue.Fields == nil {
+			continue
+		}
+		for _, l := range issue.Fields.Labels {
+			if strings.HasPrefix(strings.ToLower(l), strings.ToLower(prefix)) && !slices.Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+	}
+	sort.Strings(labels)
+
+	for _, l := range labels {
+		results = append(results, map[string]string{"value": l, "displayName": "<b>" + l[:len(prefix)] + "</b>" + l[len(prefix):]})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+// listStatuses answers for every project with the statuses the
+// transitions lead to, as the workflow of a single issue type
+func (s *Server) listStatuses(w http.ResponseWriter) {
+	statuses := make([]jira.Status, 0, len(s.transitions))
+	for _, t := range s.transitions {
+		statuses = append(statuses, t.To)
+	}
+	writeJSON(w, http.StatusOK, []map[string]any{{"name": "Task", "statuses": statuses}})
+}
+
+func (s *Server) listComponents(w http.ResponseWriter, key string) {
+	p, ok := s.projects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "No project could be found with key '"+key+"'.")
+		return
+	}
+
+	components := p.Components
+	if components == nil {
+		components = []jira.ProjectComponent{}
+	}
+	writeJSON(w, http.StatusOK, components)
+}
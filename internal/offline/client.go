@@ -0,0 +1,241 @@
+// Package offline queues changes in a journal while Jira can't be reached
+// and replays them once it can.
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// ErrOffline is returned for every request while jiwa runs with --offline
+var ErrOffline = errors.New("jiwa is offline")
+
+// Unreachable is a http.RoundTripper that fails every request with
+// ErrOffline, it keeps --offline from touching the network at all
+type Unreachable struct{}
+
+func (Unreachable) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, ErrOffline
+}
+
+// IsUnreachable reports whether the error means the request never got to
+// Jira, so the change it was making is certainly not applied
+func IsUnreachable(err error) bool {
+	if errors.Is(err, ErrOffline) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// Client queues the changes made through it in the journal instead of
+// sending them when Offline is set, when QueueWhenUnreachable is set and
+// Jira can't be reached, or when the journal already has entries for the
+// issue so its changes are replayed in the order they were made.
+// Everything else is passed on to API.
+type Client struct {
+	jiwa.API
+	Journal *Store
+
+	Offline              bool
+	QueueWhenUnreachable bool
+
+	// Log is told about every queued change
+	Log io.Writer
+}
+
+var _ jiwa.API = (*Client)(nil)
+
+func (c *Client) CreateIssue(ctx context.Context, input jiwa.CreateIssueInput) (jira.Issue, error) {
+	e := Entry{Op: OpCreate, Create: &input}
+	key, err := c.run(ctx, &e)
+	if err != nil {
+		return jira.Issue{}, err
+	}
+
+	return jira.Issue{Key: key}, nil
+}
+
+func (c *Client) UpdateIssue(ctx context.Context, key string, input jiwa.UpdateIssueInput) error {
+	_, err := c.run(ctx, &Entry{Op: OpUpdate, Key: key, Update: &input})
+	return err
+}
+
+func (c *Client) AssignIssue(ctx context.Context, key string, assignee string) error {
+	_, err := c.run(ctx, &Entry{Op: OpAssign, Key: key, Text: assignee})
+	return err
+}
+
+func (c *Client) CommentOnIssue(ctx context.Context, key string, comment string) error {
+	_, err := c.run(ctx, &Entry{Op: OpComment, Key: key, Text: comment})
+	return err
+}
+
+func (c *Client) CommentOnIssueVisibleTo(ctx context.Context, key string, comment string, visibility jira.CommentVisibility) error {
+	_, err := c.run(ctx, &Entry{Op: OpComment, Key: key, Text: comment, Visibility: &visibility})
+	return err
+}
+
+func (c *Client) Transition(ctx context.Context, key string, input jiwa.TransitionInput) error {
+	_, err := c.run(ctx, &Entry{Op: OpTransition, Key: key, Transition: &input})
+	return err
+}
+
+func (c *Client) LinkIssues(ctx context.Context, linkType, inwardKey, outwardKey string) error {
+	_, err := c.run(ctx, &Entry{Op: OpLink, Key: inwardKey, Link: &Link{Type: linkType, Inward: inwardKey, Outward: outwardKey}})
+	return err
+}
+
+func (c *Client) SetIssuePriority(ctx context.Context, key string, priority string) error {
+	_, err := c.run(ctx, &Entry{Op: OpPriority, Key: key, Text: priority})
+	return err
+}
+
+// GetIssue looks up replayed placeholders by their real key
+func (c *Client) GetIssue(ctx context.Context, key string, opts ...jiwa.GetIssueOption) (jira.Issue, error) {
+	keys, err := c.resolve(key)
+	if err != nil {
+		return jira.Issue{}, err
+	}
+
+	return c.API.GetIssue(ctx, keys[0], opts...)
+}
+
+func (c *Client) ListIssueTransitions(ctx context.Context, key string) ([]jiwa.IssueTransition, error) {
+	keys, err := c.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.API.ListIssueTransitions(ctx, keys[0])
+}
+
+// AddToSprint and MoveToBacklog aren't queued, they only resolve
+// placeholders
+func (c *Client) AddToSprint(ctx context.Context, sprintID int, keys ...string) error {
+	keys, err := c.resolve(keys...)
+	if err != nil {
+		return err
+	}
+
+	return c.API.AddToSprint(ctx, sprintID, keys...)
+}
+
+func (c *Client) MoveToBacklog(ctx context.Context, keys ...string) error {
+	keys, err := c.resolve(keys...)
+	if err != nil {
+		return err
+	}
+
+	return c.API.MoveToBacklog(ctx, keys...)
+}
+
+// resolve turns placeholders into the real keys, placeholders that are
+// still queued can't be looked up
+func (c *Client) resolve(keys ...string) ([]string, error) {
+	j, err := c.Journal.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if j.pending(k) {
+			return nil, fmt.Errorf("%s is only queued, run \"jiwa sync\" once Jira is reachable", k)
+		}
+		resolved = append(resolved, j.Resolve(k))
+	}
+
+	return resolved, nil
+}
+
+// run sends the change or queues it, it returns the key of the issue that
+// was changed or created, a placeholder if it was queued
+func (c *Client) run(ctx context.Context, e *Entry) (string, error) {
+	j, err := c.Journal.Load()
+	if err != nil {
+		return "", err
+	}
+
+	queue := c.Offline
+	for _, k := range e.Keys() {
+		queue = queue || j.pending(k) || j.queued(k)
+	}
+
+	if !queue {
+		key, err := apply(ctx, c.API, j.resolve(*e))
+		if err == nil || !c.QueueWhenUnreachable || !IsUnreachable(err) {
+			return key, err
+		}
+	}
+
+	err = c.Journal.Update(func(j *Journal) error {
+		*e = j.resolve(*e)
+		j.LastID++
+		e.ID = j.LastID
+		e.QueuedAt = time.Now()
+		if e.Op == OpCreate {
+			e.Placeholder = PlaceholderPrefix + strconv.Itoa(e.ID)
+		}
+		j.Entries = append(j.Entries, *e)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to queue %s: %w", e.Op, err)
+	}
+
+	if c.Log != nil {
+		fmt.Fprintf(c.Log, "queued %s, run \"jiwa sync\" once Jira is reachable\n", e)
+	}
+
+	if e.Op == OpCreate {
+		return e.Placeholder, nil
+	}
+	return e.Key, nil
+}
+
+// apply sends the change the entry describes, keys have to be resolved
+func apply(ctx context.Context, client jiwa.API, e Entry) (string, error) {
+	switch e.Op {
+	case OpCreate:
+		issue, err := client.CreateIssue(ctx, *e.Create)
+		return issue.Key, err
+	case OpUpdate:
+		return e.Key, client.UpdateIssue(ctx, e.Key, *e.Update)
+	case OpAssign:
+		return e.Key, client.AssignIssue(ctx, e.Key, e.Text)
+	case OpComment:
+		if e.Visibility != nil {
+			return e.Key, client.CommentOnIssueVisibleTo(ctx, e.Key, e.Text, *e.Visibility)
+		}
+		return e.Key, client.CommentOnIssue(ctx, e.Key, e.Text)
+	case OpTransition:
+		err := client.Transition(ctx, e.Key, *e.Transition)
+		// whoever moved it in the meantime did what was queued
+		if errors.Is(err, jiwa.ErrAlreadyInStatus) {
+			err = nil
+		}
+		return e.Key, err
+	case OpLink:
+		return e.Key, client.LinkIssues(ctx, e.Link.Type, e.Link.Inward, e.Link.Outward)
+	case OpPriority:
+		return e.Key, client.SetIssuePriority(ctx, e.Key, e.Text)
+	default:
+		return "", fmt.Errorf("unknown operation %q", e.Op)
+	}
+}
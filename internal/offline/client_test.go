@@ -0,0 +1,182 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+var errRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestClient(t *testing.T) {
+	comment := func(key string) func(c *Client) (string, error) {
+		return func(c *Client) (string, error) {
+			return key, c.CommentOnIssue(context.Background(), key, "on it")
+		}
+	}
+
+	testData := []struct {
+		Name           string
+		InJournal      Journal
+		InOffline      bool
+		InQueue        bool
+		InErrors       map[string]error
+		InDo           func(c *Client) (string, error)
+		OutKey         string
+		OutQueued      []Entry
+		OutComments    map[string]int
+		OutErrContains string
+	}{
+		{
+			Name:        "Online",
+			InDo:        comment("JIWA-1"),
+			OutKey:      "JIWA-1",
+			OutComments: map[string]int{"JIWA-1": 1},
+		},
+		{
+			Name:      "Offline",
+			InOffline: true,
+			InDo: func(c *Client) (string, error) {
+				issue, err := c.CreateIssue(context.Background(), jiwa.CreateIssueInput{Project: "JIWA", Summary: "New"})
+				return issue.Key, err
+			},
+			OutKey: "OFFLINE-1",
+			OutQueued: []Entry{
+				{ID: 1, Op: OpCreate, Placeholder: "OFFLINE-1", Create: &jiwa.CreateIssueInput{Project: "JIWA", Summary: "New"}},
+			},
+		},
+		{
+			Name:      "OfflineRestrictedComment",
+			InOffline: true,
+			InDo: func(c *Client) (string, error) {
+				return "JIWA-1", c.CommentOnIssueVisibleTo(context.Background(), "JIWA-1", "on it", jira.CommentVisibility{Type: "role", Value: "Developers"})
+			},
+			OutKey: "JIWA-1",
+			OutQueued: []Entry{
+				{ID: 1, Op: OpComment, Key: "JIWA-1", Text: "on it", Visibility: &jira.CommentVisibility{Type: "role", Value: "Developers"}},
+			},
+		},
+		{
+			Name:      "UnreachableIsQueued",
+			InQueue:   true,
+			InErrors:  map[string]error{"CommentOnIssue": errRefused},
+			InDo:      comment("JIWA-1"),
+			OutKey:    "JIWA-1",
+			OutQueued: []Entry{{ID: 1, Op: OpComment, Key: "JIWA-1", Text: "on it"}},
+		},
+		{
+			Name:           "UnreachableFailsUnlessOptedIn",
+			InErrors:       map[string]error{"CommentOnIssue": errRefused},
+			InDo:           comment("JIWA-1"),
+			OutErrContains: "connection refused",
+		},
+		{
+			Name:           "RejectedIsNotQueued",
+			InQueue:        true,
+			InErrors:       map[string]error{"CommentOnIssue": errors.New("failed to call API 400")},
+			InDo:           comment("JIWA-1"),
+			OutErrContains: "400",
+		},
+		{
+			Name:      "WaitsForQueuedChangesOfTheIssue",
+			InJournal: Journal{LastID: 3, Entries: []Entry{{ID: 3, Op: OpComment, Key: "JIWA-1", Text: "first"}}},
+			InDo:      comment("JIWA-1"),
+			OutKey:    "JIWA-1",
+			OutQueued: []Entry{
+				{ID: 3, Op: OpComment, Key: "JIWA-1", Text: "first"},
+				{ID: 4, Op: OpComment, Key: "JIWA-1", Text: "on it"},
+			},
+		},
+		{
+			Name:        "OtherIssuesDontWait",
+			InJournal:   Journal{LastID: 3, Entries: []Entry{{ID: 3, Op: OpComment, Key: "JIWA-1", Text: "first"}}},
+			InDo:        comment("JIWA-2"),
+			OutKey:      "JIWA-2",
+			OutQueued:   []Entry{{ID: 3, Op: OpComment, Key: "JIWA-1", Text: "first"}},
+			OutComments: map[string]int{"JIWA-2": 1},
+		},
+		{
+			Name: "ReplayedPlaceholder",
+			InJournal: Journal{
+				LastID:       1,
+				Placeholders: map[string]string{"OFFLINE-1": "JIWA-2"},
+			},
+			InDo:        comment("OFFLINE-1"),
+			OutKey:      "OFFLINE-1",
+			OutComments: map[string]int{"JIWA-2": 1},
+		},
+		{
+			Name: "QueuedPlaceholderCantBeRead",
+			InJournal: Journal{LastID: 1, Entries: []Entry{
+				{ID: 1, Op: OpCreate, Placeholder: "OFFLINE-1", Create: &jiwa.CreateIssueInput{Project: "JIWA", Summary: "New"}},
+			}},
+			InDo: func(c *Client) (string, error) {
+				issue, err := c.GetIssue(context.Background(), "OFFLINE-1")
+				return issue.Key, err
+			},
+			OutQueued: []Entry{
+				{ID: 1, Op: OpCreate, Placeholder: "OFFLINE-1", Create: &jiwa.CreateIssueInput{Project: "JIWA", Summary: "New"}},
+			},
+			OutErrContains: "OFFLINE-1 is only queued",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{}}
+			fake.Issues["JIWA-2"] = jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{}}
+			for k, v := range td.InErrors {
+				fake.Errors[k] = v
+			}
+
+			store := &Store{Path: filepath.Join(t.TempDir(), "journal.json")}
+			err := store.Update(func(j *Journal) error {
+				*j = td.InJournal
+				return nil
+			})
+			assert.NoError(t, err)
+
+			c := &Client{API: fake, Journal: store, Offline: td.InOffline, QueueWhenUnreachable: td.InQueue}
+			key, err := td.InDo(c)
+
+			if td.OutErrContains != "" {
+				assert.ErrorContains(t, err, td.OutErrContains)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, td.OutKey, key)
+			}
+
+			j, err := store.Load()
+			assert.NoError(t, err)
+			for i := range j.Entries {
+				j.Entries[i].QueuedAt = time.Time{}
+			}
+			if td.OutQueued == nil {
+				td.OutQueued = []Entry{}
+			}
+			assert.Equal(t, td.OutQueued, append([]Entry{}, j.Entries...))
+
+			comments := make(map[string]int)
+			for key, issue := range fake.Issues {
+				if issue.Fields.Comments != nil {
+					comments[key] = len(issue.Fields.Comments.Comments)
+				}
+			}
+			if td.OutComments == nil {
+				td.OutComments = map[string]int{}
+			}
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
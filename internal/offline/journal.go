@@ -0,0 +1,287 @@
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/state"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// journalVersion is bumped when the format changes in a way older jiwa
+// versions can't read
+const journalVersion = 1
+
+// PlaceholderPrefix starts the keys handed out for queued creates, they
+// look like issue keys so they can be piped into the next command
+const PlaceholderPrefix = "OFFLINE-"
+
+// Op names the change an entry makes
+type Op string
+
+const (
+	OpCreate     Op = "create"
+	OpUpdate     Op = "update"
+	OpAssign     Op = "assign"
+	OpComment    Op = "comment"
+	OpTransition Op = "transition"
+	OpLink       Op = "link"
+	OpPriority   Op = "priority"
+)
+
+// Link is a queued link between two issues
+type Link struct {
+	Type    string `json:"type"`
+	Inward  string `json:"inward"`
+	Outward string `json:"outward"`
+}
+
+// Entry is a change that was queued instead of being sent to Jira, only the
+// field that belongs to its Op is set.
+type Entry struct {
+	ID       int       `json:"id"`
+	Op       Op        `json:"op"`
+	QueuedAt time.Time `json:"queuedAt"`
+	// Key is the issue that is changed, it can be a placeholder
+	Key string `json:"key,omitempty"`
+	// Placeholder is the key handed out for a create
+	Placeholder string `json:"placeholder,omitempty"`
+
+	Create     *jiwa.CreateIssueInput `json:"create,omitempty"`
+	Update     *jiwa.UpdateIssueInput `json:"update,omitempty"`
+	Transition *jiwa.TransitionInput  `json:"transition,omitempty"`
+	Link       *Link                  `json:"link,omitempty"`
+	// Text is the comment, the assignee or the priority
+	Text string `json:"text,omitempty"`
+	// Visibility restricts a comment to a role or a group
+	Visibility *jira.CommentVisibility `json:"visibility,omitempty"`
+
+	// Started is set right before the entry is replayed and cleared when
+	// Jira answered, if it is still set the replay was interrupted and the
+	// change might have gone through
+	Started *time.Time `json:"started,omitempty"`
+	// Held says why the last sync left the entry in the journal
+	Held string `json:"held,omitempty"`
+}
+
+// Keys are the issues the entry depends on
+func (e Entry) Keys() []string {
+	keys := make([]string, 0, 3)
+	for _, k := range []string{e.Key, e.Placeholder} {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if e.Create != nil && e.Create.Parent != "" {
+		keys = append(keys, e.Create.Parent)
+	}
+	if e.Link != nil {
+		keys = append(keys, e.Link.Inward, e.Link.Outward)
+	}
+
+	return keys
+}
+
+// String describes the entry in a line
+func (e Entry) String() string {
+	switch e.Op {
+	case OpCreate:
+		return fmt.Sprintf("#%d create %s in %s: %s", e.ID, e.Placeholder, e.Create.Project, e.Create.Summary)
+	case OpTransition:
+		status := e.Transition.Status
+		if status == "" {
+			status = "a " + e.Transition.StatusCategory + " status"
+		}
+		return fmt.Sprintf("#%d move %s to %s", e.ID, e.Key, status)
+	case OpLink:
+		return fmt.Sprintf("#%d link %s %s %s", e.ID, e.Link.Inward, e.Link.Type, e.Link.Outward)
+	case OpAssign, OpPriority:
+		return fmt.Sprintf("#%d %s %s to %s", e.ID, e.Op, e.Key, e.Text)
+	default:
+		return fmt.Sprintf("#%d %s %s", e.ID, e.Op, e.Key)
+	}
+}
+
+// Journal holds the queued entries in the order they have to be replayed
+type Journal struct {
+	Version int `json:"version"`
+	// LastID is the ID of the last entry that was queued, IDs aren't
+	// reused so placeholders stay unique
+	LastID int `json:"lastID"`
+	// Placeholders maps the keys handed out for creates to the real keys
+	// once they are replayed, so they keep working afterwards
+	Placeholders map[string]string `json:"placeholders,omitempty"`
+	Entries      []Entry           `json:"entries"`
+}
+
+// IsPlaceholder reports whether the key was handed out for a queued create
+func IsPlaceholder(key string) bool {
+	return strings.HasPrefix(key, PlaceholderPrefix)
+}
+
+// Resolve returns the real key of a placeholder that was replayed and any
+// other key as it is
+func (j *Journal) Resolve(key string) string {
+	if real, ok := j.Placeholders[key]; ok {
+		return real
+	}
+
+	return key
+}
+
+// resolve replaces the placeholders the entry refers to by their real keys
+func (j *Journal) resolve(e Entry) Entry {
+	e.Key = j.Resolve(e.Key)
+	if e.Create != nil && e.Create.Parent != "" {
+		input := *e.Create
+		input.Parent = j.Resolve(input.Parent)
+		e.Create = &input
+	}
+	if e.Link != nil {
+		e.Link = &Link{Type: e.Link.Type, Inward: j.Resolve(e.Link.Inward), Outward: j.Resolve(e.Link.Outward)}
+	}
+
+	return e
+}
+
+// pending reports whether the key is a placeholder that wasn't replayed yet
+func (j *Journal) pending(key string) bool {
+	return IsPlaceholder(key) && j.Resolve(key) == key
+}
+
+// queued reports whether an entry in the journal changes the issue
+func (j *Journal) queued(key string) bool {
+	key = j.Resolve(key)
+	for _, e := range j.Entries {
+		for _, k := range e.Keys() {
+			if j.Resolve(k) == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (j *Journal) find(id int) *Entry {
+	for i := range j.Entries {
+		if j.Entries[i].ID == id {
+			return &j.Entries[i]
+		}
+	}
+
+	return nil
+}
+
+// Drop removes the entries from the journal, it fails without removing
+// any if one of them isn't queued
+func (j *Journal) Drop(ids ...int) error {
+	for _, id := range ids {
+		if j.find(id) == nil {
+			return fmt.Errorf("there is no queued entry #%d", id)
+		}
+	}
+
+	kept := make([]Entry, 0, len(j.Entries))
+	for _, e := range j.Entries {
+		drop := false
+		for _, id := range ids {
+			drop = drop || e.ID == id
+		}
+		if !drop {
+			kept = append(kept, e)
+		}
+	}
+	j.Entries = kept
+
+	return nil
+}
+
+// Store keeps the journal in a JSON file that is rewritten as a whole after
+// every change, so an interrupted jiwa leaves either the old or the new
+// journal behind and never half of it.
+type Store struct {
+	Path string
+}
+
+// DefaultPath returns where the journal for the profile lives. Unlike the
+// state it is kept in the user's config dir, clearing caches must not lose
+// changes that weren't sent yet.
+func DefaultPath(profile string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config dir: %w", err)
+	}
+
+	return filepath.Join(configDir, "jiwa", profile, "journal.json"), nil
+}
+
+// Load reads the journal without taking the lock, a missing file is an
+// empty journal
+func (s *Store) Load() (Journal, error) {
+	j := Journal{Version: journalVersion}
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return j, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return j, fmt.Errorf("failed to unmarshal journal %s: %w", s.Path, err)
+	}
+
+	if j.Version > journalVersion {
+		return j, fmt.Errorf("journal %s was written by a newer jiwa (version %d), sync it with that one", s.Path, j.Version)
+	}
+
+	return j, nil
+}
+
+// Update locks the journal, hands it to fn and writes back whatever fn left
+// in it, unless fn returns an error.
+func (s *Store) Update(fn func(*Journal) error) error {
+	err := os.MkdirAll(filepath.Dir(s.Path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create journal dir: %w", err)
+	}
+
+	unlock, err := state.Lock(s.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	j, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&j)
+	if err != nil {
+		return err
+	}
+
+	j.Version = journalVersion
+	b, err := json.MarshalIndent(&j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+
+	tmp := s.Path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	return os.Rename(tmp, s.Path)
+}
@@ -0,0 +1,195 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// Applied is an entry that made it to Jira, Key is the issue it changed or
+// created
+type Applied struct {
+	Entry Entry
+	Key   string
+}
+
+// Report lists what a replay did, Held are the entries left in the journal
+// with the reason in their Held field
+type Report struct {
+	Applied []Applied
+	Held    []Entry
+}
+
+// Replay sends the journal's entries to Jira in the order they were queued
+// and removes every entry that went through, the journal is saved after
+// each of them so an interrupted replay picks up where it stopped.
+//
+// An entry is held back if the issue was changed on Jira after it was
+// queued, if Jira rejects it or if an earlier replay was interrupted while
+// sending it. Later entries for the same issues, or for the issue a held
+// create would have made, are held back with it to keep their order, other
+// issues carry on. force replays changed and interrupted entries anyway.
+// Replaying stops as soon as Jira can't be reached.
+func Replay(ctx context.Context, client jiwa.API, store *Store, force bool) (Report, error) {
+	var report Report
+
+	j, err := store.Load()
+	if err != nil {
+		return report, err
+	}
+
+	// blocked maps the keys of held entries to the entry holding them
+	blocked := make(map[string]int)
+	// touched are the issues changed by this replay, they are newer than
+	// their entries without anybody else having changed them
+	touched := make(map[string]bool)
+	hold := func(e Entry, reason string) error {
+		e.Held = reason
+		for _, k := range e.Keys() {
+			if _, ok := blocked[k]; !ok {
+				blocked[k] = e.ID
+			}
+		}
+		report.Held = append(report.Held, e)
+
+		return store.Update(func(j *Journal) error {
+			if stored := j.find(e.ID); stored != nil {
+				stored.Held = reason
+				stored.Started = e.Started
+			}
+			return nil
+		})
+	}
+
+	for _, queued := range j.Entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		// placeholders resolved by earlier entries of this replay
+		e := j.resolve(queued)
+		e.Held = ""
+
+		if id, ok := blockedBy(blocked, append(queued.Keys(), e.Keys()...)); ok {
+			err = hold(e, fmt.Sprintf("waits for #%d", id))
+			if err != nil {
+				return report, err
+			}
+			continue
+		}
+
+		if e.Started != nil && !force {
+			err = hold(e, "the last sync was interrupted while sending it, it may have gone through already. Check the issue and pass --force to send it again or --drop to forget it")
+			if err != nil {
+				return report, err
+			}
+			continue
+		}
+
+		if e.Op != OpCreate && !IsPlaceholder(queued.Key) && !touched[e.Key] && !force {
+			changed, err := changedSince(ctx, client, e.Key, e.QueuedAt)
+			if IsUnreachable(err) {
+				return report, fmt.Errorf("stopped, Jira can't be reached: %w", err)
+			}
+
+			reason := ""
+			switch {
+			case err != nil:
+				reason = err.Error()
+			case changed:
+				reason = fmt.Sprintf("%s was changed on Jira after this was queued, check it and pass --force to send it anyway", e.Key)
+			}
+			if reason != "" {
+				err = hold(e, reason)
+				if err != nil {
+					return report, err
+				}
+				continue
+			}
+		}
+
+		now := time.Now()
+		e.Started = &now
+		err = store.Update(func(j *Journal) error {
+			if stored := j.find(e.ID); stored != nil {
+				stored.Started = e.Started
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+
+		key, applyErr := apply(ctx, client, e)
+		if applyErr != nil {
+			// a request that timed out or was cancelled may have gone
+			// through, Started stays set so the next sync asks first
+			var netErr net.Error
+			uncertain := !IsUnreachable(applyErr) && (ctx.Err() != nil || (errors.As(applyErr, &netErr) && netErr.Timeout()))
+			if !uncertain {
+				e.Started = nil
+			}
+
+			err = hold(e, applyErr.Error())
+			if err != nil {
+				return report, err
+			}
+
+			if IsUnreachable(applyErr) || uncertain {
+				return report, fmt.Errorf("stopped at #%d: %w", e.ID, applyErr)
+			}
+			continue
+		}
+
+		if e.Placeholder != "" {
+			if j.Placeholders == nil {
+				j.Placeholders = make(map[string]string)
+			}
+			j.Placeholders[e.Placeholder] = key
+		}
+		err = store.Update(func(stored *Journal) error {
+			if e.Placeholder != "" {
+				if stored.Placeholders == nil {
+					stored.Placeholders = make(map[string]string)
+				}
+				stored.Placeholders[e.Placeholder] = key
+			}
+			return stored.Drop(e.ID)
+		})
+		if err != nil {
+			return report, err
+		}
+
+		touched[key] = true
+		report.Applied = append(report.Applied, Applied{Entry: e, Key: key})
+	}
+
+	return report, nil
+}
+
+func blockedBy(blocked map[string]int, keys []string) (int, bool) {
+	for _, k := range keys {
+		if id, ok := blocked[k]; ok {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// changedSince reports whether the issue was updated after the time
+func changedSince(ctx context.Context, client jiwa.API, key string, since time.Time) (bool, error) {
+	issue, err := client.GetIssue(ctx, key, jiwa.WithFields("updated"))
+	if err != nil {
+		return false, err
+	}
+	if issue.Fields == nil {
+		return false, nil
+	}
+
+	return time.Time(issue.Fields.Updated).After(since), nil
+}
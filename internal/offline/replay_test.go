@@ -0,0 +1,147 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplay(t *testing.T) {
+	queuedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	started := queuedAt.Add(time.Hour)
+	create := Entry{ID: 1, Op: OpCreate, QueuedAt: queuedAt, Placeholder: "OFFLINE-1", Create: &jiwa.CreateIssueInput{Project: "JIWA", Summary: "New"}}
+	commentNew := Entry{ID: 2, Op: OpComment, QueuedAt: queuedAt, Key: "OFFLINE-1", Text: "on it"}
+	closeNew := Entry{ID: 3, Op: OpTransition, QueuedAt: queuedAt, Key: "OFFLINE-1", Transition: &jiwa.TransitionInput{Status: "Done"}}
+	label := Entry{ID: 4, Op: OpUpdate, QueuedAt: queuedAt, Key: "JIWA-1", Update: &jiwa.UpdateIssueInput{AddLabels: []string{"urgent"}}}
+	commentOld := Entry{ID: 5, Op: OpComment, QueuedAt: queuedAt, Key: "JIWA-1", Text: "again"}
+
+	testData := []struct {
+		Name            string
+		InEntries       []Entry
+		InUpdated       time.Time
+		InErrors        map[string]error
+		InForce         bool
+		OutApplied      []string
+		OutHeld         map[int]string
+		OutStarted      []int
+		OutPlaceholders map[string]string
+		OutErrMsg       string
+	}{
+		{
+			Name:            "InOrderWithPlaceholders",
+			InEntries:       []Entry{create, commentNew, closeNew, label},
+			OutApplied:      []string{"JIWA-2", "JIWA-2", "JIWA-2", "JIWA-1"},
+			OutPlaceholders: map[string]string{"OFFLINE-1": "JIWA-2"},
+		},
+		{
+			Name:       "ChangedIssueIsHeld",
+			InEntries:  []Entry{label, create, commentOld},
+			InUpdated:  queuedAt.Add(time.Minute),
+			OutApplied: []string{"JIWA-2"},
+			OutHeld: map[int]string{
+				4: "JIWA-1 was changed on Jira after this was queued, check it and pass --force to send it anyway",
+				5: "waits for #4",
+			},
+			OutPlaceholders: map[string]string{"OFFLINE-1": "JIWA-2"},
+		},
+		{
+			Name:       "ForceSendsChangedIssues",
+			InEntries:  []Entry{label, commentOld},
+			InUpdated:  queuedAt.Add(time.Minute),
+			InForce:    true,
+			OutApplied: []string{"JIWA-1", "JIWA-1"},
+		},
+		{
+			Name: "InterruptedIsHeld",
+			InEntries: []Entry{
+				{ID: 4, Op: OpUpdate, QueuedAt: queuedAt, Key: "JIWA-1", Update: label.Update, Started: &started},
+				commentOld,
+			},
+			OutHeld: map[int]string{
+				4: "the last sync was interrupted while sending it, it may have gone through already. Check the issue and pass --force to send it again or --drop to forget it",
+				5: "waits for #4",
+			},
+			OutStarted: []int{4},
+		},
+		{
+			Name:      "RejectedCreateHoldsItsIssue",
+			InEntries: []Entry{create, commentNew, label},
+			InErrors:  map[string]error{"CreateIssue": errors.New("failed to call API 400: summary is too long")},
+			OutHeld: map[int]string{
+				1: "failed to call API 400: summary is too long",
+				2: "waits for #1",
+			},
+			OutApplied: []string{"JIWA-1"},
+		},
+		{
+			Name:      "UnreachableStops",
+			InEntries: []Entry{create, label, commentOld},
+			InErrors:  map[string]error{"UpdateIssue": errRefused},
+			OutHeld: map[int]string{
+				4: "dial tcp: connection refused",
+				5: "",
+			},
+			OutApplied:      []string{"JIWA-2"},
+			OutPlaceholders: map[string]string{"OFFLINE-1": "JIWA-2"},
+			OutErrMsg:       "stopped at #4: dial tcp: connection refused",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Updated: jira.Time(td.InUpdated)}}
+			for k, v := range td.InErrors {
+				fake.Errors[k] = v
+			}
+
+			store := &Store{Path: filepath.Join(t.TempDir(), "journal.json")}
+			err := store.Update(func(j *Journal) error {
+				j.LastID = 5
+				j.Entries = td.InEntries
+				return nil
+			})
+			assert.NoError(t, err)
+
+			report, err := Replay(context.Background(), fake, store, td.InForce)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var applied []string
+			for _, a := range report.Applied {
+				applied = append(applied, a.Key)
+			}
+			assert.Equal(t, td.OutApplied, applied)
+
+			j, err := store.Load()
+			assert.NoError(t, err)
+			held := make(map[int]string)
+			var started []int
+			for _, e := range j.Entries {
+				held[e.ID] = e.Held
+				if e.Started != nil {
+					started = append(started, e.ID)
+				}
+			}
+			if td.OutHeld == nil {
+				td.OutHeld = map[int]string{}
+			}
+			assert.Equal(t, td.OutHeld, held)
+			assert.Equal(t, td.OutStarted, started)
+			assert.Equal(t, td.OutPlaceholders, j.Placeholders)
+		})
+	}
+}
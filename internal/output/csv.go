@@ -0,0 +1,87 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// csvColumns are the fields of Issue in the CSV, the description is left
+// out to keep the rows on one line in spreadsheets
+var csvColumns = []string{"key", "project", "summary", "status", "type", "priority", "assignee", "reporter", "labels", "parent", "flagged", "storyPoints", "created", "updated", "url"}
+
+// csvWriter writes a header and a row per issue, quoting is left to
+// encoding/csv. Labels are separated by spaces, Jira doesn't allow them in
+// labels.
+type csvWriter struct {
+	w      *csv.Writer
+	opts   Options
+	header bool
+}
+
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), opts: opts}
+}
+
+func (c *csvWriter) Fields() []string {
+	return viewFieldsWith(c.opts)
+}
+
+func (c *csvWriter) WriteIssues(issues []jira.Issue) error {
+	c.writeHeader(csvColumns)
+	for _, i := range issues {
+		view := NewIssue(i, c.opts)
+		points := ""
+		if view.StoryPoints != nil {
+			points = strconv.FormatFloat(*view.StoryPoints, 'f', -1, 64)
+		}
+
+		err := c.w.Write([]string{
+			view.Key, view.Project, view.Summary, view.Status, view.Type, view.Priority, view.Assignee, view.Reporter,
+			strings.Join(view.Labels, " "), view.Parent, strconv.FormatBool(view.Flagged), points, view.Created,
+			view.Updated, view.URL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) WriteIssue(issue jira.Issue) error {
+	return c.WriteIssues([]jira.Issue{issue})
+}
+
+func (c *csvWriter) WriteKV(pairs []KV) error {
+	c.writeHeader([]string{"key", "value"})
+	for _, kv := range pairs {
+		err := c.w.Write([]string{kv.Key, kv.Value})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader(columns []string) {
+	if c.header {
+		return
+	}
+	c.header = true
+	_ = c.w.Write(columns)
+}
+
+// Close writes the header if nothing else was written, so an empty result
+// is still a valid file
+func (c *csvWriter) Close() error {
+	c.writeHeader(csvColumns)
+	c.w.Flush()
+	return c.w.Error()
+}
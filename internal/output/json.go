@@ -0,0 +1,151 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// jsonWriter writes the issues of WriteIssues as a JSON array one element
+// at a time instead of holding all of them in memory to marshal them in one
+// go. WriteIssue and WriteKV write a single object instead.
+type jsonWriter struct {
+	w       io.Writer
+	opts    Options
+	written int
+	single  bool
+}
+
+func (j *jsonWriter) Fields() []string {
+	return viewFieldsWith(j.opts)
+}
+
+func (j *jsonWriter) WriteIssues(issues []jira.Issue) error {
+	for _, i := range issues {
+		b, err := json.Marshal(NewIssue(i, j.opts))
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", i.Key, err)
+		}
+
+		sep := ",\n"
+		if j.written == 0 {
+			sep = "[\n"
+		}
+		_, err = fmt.Fprintf(j.w, "%s%s", sep, b)
+		if err != nil {
+			return err
+		}
+		j.written++
+	}
+
+	return nil
+}
+
+func (j *jsonWriter) WriteIssue(issue jira.Issue) error {
+	return j.writeSingle(NewIssue(issue, j.opts))
+}
+
+// writeSingle writes v as the one indented object of the output
+func (j *jsonWriter) writeSingle(v any) error {
+	j.single = true
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (j *jsonWriter) WriteKV(pairs []KV) error {
+	j.single = true
+	b, err := marshalKV(pairs)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	err = json.Indent(&out, b, "", "  ")
+	if err != nil {
+		return err
+	}
+	out.WriteString("\n")
+
+	_, err = out.WriteTo(j.w)
+	return err
+}
+
+// Close ends the array, which is empty if no issues were found
+func (j *jsonWriter) Close() error {
+	switch {
+	case j.single:
+		return nil
+	case j.written == 0:
+		_, err := fmt.Fprintln(j.w, "[]")
+		return err
+	default:
+		_, err := fmt.Fprintln(j.w, "\n]")
+		return err
+	}
+}
+
+// ndjsonWriter writes one object per line, tools like jq can start working
+// on the first issue before the search is done.
+type ndjsonWriter struct {
+	w    io.Writer
+	opts Options
+}
+
+func (n *ndjsonWriter) Fields() []string {
+	return viewFieldsWith(n.opts)
+}
+
+func (n *ndjsonWriter) WriteIssues(issues []jira.Issue) error {
+	enc := json.NewEncoder(n.w)
+	for _, i := range issues {
+		err := enc.Encode(NewIssue(i, n.opts))
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", i.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (n *ndjsonWriter) WriteIssue(issue jira.Issue) error {
+	return n.WriteIssues([]jira.Issue{issue})
+}
+
+func (n *ndjsonWriter) WriteKV(pairs []KV) error {
+	b, err := marshalKV(pairs)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(n.w, "%s\n", b)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// marshalKV writes the pairs as an object in their order, behind the
+// schema version
+func marshalKV(pairs []KV) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"jiwaSchema":%d`, SchemaVersion)
+	for _, kv := range pairs {
+		key, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ",%s:%s", key, value)
+	}
+	b.WriteString("}")
+
+	return b.Bytes(), nil
+}
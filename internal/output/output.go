@@ -0,0 +1,207 @@
+// Package output writes what commands print in the format picked with
+// --output, so every command offers the same formats and scripts get the
+// same shape of issue from all of them.
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+)
+
+// SchemaVersion is written as "jiwaSchema" with every JSON object. It only
+// goes up when a field of Issue is removed or changes its meaning, new
+// fields are added without bumping it, so scripts should ignore fields
+// they don't know.
+const SchemaVersion = 1
+
+// Formats are the names New accepts, template is given as
+// template=<text/template>
+var Formats = []string{"raw", "table", "json", "ndjson", "csv", "template"}
+
+// Writer prints issues and key-value pairs in one format. Issues can come a
+// page at a time so long results show up while the later pages are still
+// being fetched.
+type Writer interface {
+	// Fields are the fields the format prints, searches only fetch those
+	Fields() []string
+	WriteIssues(issues []jira.Issue) error
+	WriteIssue(issue jira.Issue) error
+	WriteKV(pairs []KV) error
+	// Close writes whatever the format needs at the end, it has to be
+	// called even if the search failed half way.
+	Close() error
+}
+
+// KV is a single value of something that isn't an issue, like the account
+// whoami prints. Key is what machine readable formats use, Label what
+// people read in the table.
+type KV struct {
+	Key   string
+	Label string
+	Value string
+}
+
+type Options struct {
+	// IssueURL turns a key into the link that is printed
+	IssueURL func(key string) string
+	// URLs makes raw print the links of the issues instead of their keys
+	URLs bool
+	// ShowProject adds a project column to the table
+	ShowProject bool
+	// ShowReporter adds a reporter column to the table
+	ShowReporter bool
+	// ShowUpdated adds a column to the table with how long ago the issues
+	// were updated
+	ShowUpdated bool
+	// Now is what the updated column counts from, the time New is called
+	// if it's zero
+	Now time.Time
+	// FlaggedField and StoryPointsField are the IDs of the custom fields
+	// in the view, empty ones are left out
+	FlaggedField     string
+	StoryPointsField string
+	// Location is where timestamps are shown in, they keep the offset
+	// Jira sent them with if it's nil
+	Location *time.Location
+}
+
+// New returns the writer for the --output format
+func New(w io.Writer, format string, opts Options) (Writer, error) {
+	if opts.IssueURL == nil {
+		opts.IssueURL = func(key string) string { return key }
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	if text, ok := strings.CutPrefix(format, "template="); ok {
+		return newTemplateWriter(w, text, opts)
+	}
+
+	switch format {
+	case "raw":
+		return &rawWriter{w: w, opts: opts}, nil
+	case "table":
+		return newTableWriter(w, opts), nil
+	case "json":
+		return &jsonWriter{w: w, opts: opts}, nil
+	case "ndjson":
+		return &ndjsonWriter{w: w, opts: opts}, nil
+	case "csv":
+		return newCSVWriter(w, opts), nil
+	case "template":
+		return nil, fmt.Errorf("the template output needs the template, e.g. --output 'template={{.Key}} {{.Summary}}'")
+	default:
+		return nil, fmt.Errorf("unknown output %q, use \"raw\", \"table\", \"json\", \"ndjson\", \"csv\" or \"template=<template>\"", format)
+	}
+}
+
+// Issue is the view of an issue the json, ndjson, csv and template outputs
+// write. Every field is always there, empty if the issue doesn't have it.
+type Issue struct {
+	Schema      int      `json:"jiwaSchema"`
+	Key         string   `json:"key"`
+	Project     string   `json:"project"`
+	Summary     string   `json:"summary"`
+	Status      string   `json:"status"`
+	Type        string   `json:"type"`
+	Priority    string   `json:"priority"`
+	Assignee    string   `json:"assignee"`
+	Reporter    string   `json:"reporter"`
+	Labels      []string `json:"labels"`
+	Parent      string   `json:"parent"`
+	Flagged     bool     `json:"flagged"`
+	StoryPoints *float64 `json:"storyPoints"`
+	Created     string   `json:"created"`
+	Updated     string   `json:"updated"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+}
+
+// viewFields are what Issue is made of, searches for the view only fetch
+// those
+var viewFields = []string{"project", "summary", "status", "issuetype", "priority", "assignee", "reporter", "labels", "parent", "created", "updated", "description"}
+
+// NewIssue builds the view of the issue
+func NewIssue(issue jira.Issue, opts Options) Issue {
+	view := Issue{
+		Schema: SchemaVersion,
+		Key:    issue.Key,
+		Labels: make([]string, 0),
+		URL:    opts.IssueURL(issue.Key),
+	}
+	view.Project, _, _ = strings.Cut(issue.Key, "-")
+
+	f := issue.Fields
+	if f == nil {
+		return view
+	}
+
+	if f.Project.Key != "" {
+		view.Project = f.Project.Key
+	}
+	view.Summary = f.Summary
+	view.Description = f.Description
+	view.Type = f.Type.Name
+	if f.Status != nil {
+		view.Status = f.Status.Name
+	}
+	if f.Priority != nil {
+		view.Priority = f.Priority.Name
+	}
+	view.Assignee = userName(f.Assignee)
+	view.Reporter = userName(f.Reporter)
+	if f.Labels != nil {
+		view.Labels = f.Labels
+	}
+	if f.Parent != nil {
+		view.Parent = f.Parent.Key
+	}
+	view.Flagged = commands.IsFlagged(issue, opts.FlaggedField)
+	if points, ok := commands.StoryPoints(issue, opts.StoryPointsField); ok {
+		view.StoryPoints = &points
+	}
+	view.Created = formatTime(time.Time(f.Created), opts.Location)
+	view.Updated = formatTime(time.Time(f.Updated), opts.Location)
+
+	return view
+}
+
+// viewFieldsWith adds the custom fields of the view that are known
+func viewFieldsWith(opts Options) []string {
+	fields := append([]string{}, viewFields...)
+	for _, f := range []string{opts.FlaggedField, opts.StoryPointsField} {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+func userName(u *jira.User) string {
+	switch {
+	case u == nil:
+		return ""
+	case u.DisplayName != "":
+		return u.DisplayName
+	default:
+		return u.Name
+	}
+}
+
+func formatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,394 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+	"github.com/stretchr/testify/assert"
+)
+
+var testOptions = Options{
+	IssueURL:         func(key string) string { return "https://jira.example.com/browse/" + key },
+	FlaggedField:     "customfield_10021",
+	StoryPointsField: "customfield_10016",
+}
+
+var testIssues = []jira.Issue{
+	{Key: "JIWA-1", Fields: &jira.IssueFields{
+		Summary:  "Deploy, then \"verify\"",
+		Status:   &jira.Status{Name: "In Progress"},
+		Type:     jira.IssueType{Name: "Task"},
+		Assignee: &jira.User{DisplayName: "Alice"},
+		Labels:   []string{"ops", "urgent"},
+		Created:  jira.Time(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)),
+		Unknowns: map[string]any{"customfield_10016": 3.0, "customfield_10021": []any{map[string]any{"value": "Impediment"}}},
+	}},
+	{Key: "JIWA-2", Fields: &jira.IssueFields{Summary: "Plain"}},
+}
+
+func TestNew(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InFormat  string
+		OutErrMsg string
+	}{
+		{Name: "Raw", InFormat: "raw"},
+		{Name: "Table", InFormat: "table"},
+		{Name: "JSON", InFormat: "json"},
+		{Name: "NDJSON", InFormat: "ndjson"},
+		{Name: "CSV", InFormat: "csv"},
+		{Name: "Template", InFormat: "template={{.Key}}"},
+		{Name: "TemplateWithoutText", InFormat: "template", OutErrMsg: "the template output needs the template, e.g. --output 'template={{.Key}} {{.Summary}}'"},
+		{Name: "BrokenTemplate", InFormat: "template={{.Key", OutErrMsg: `failed to parse the output template: template: output:1: unclosed action`},
+		{Name: "Unknown", InFormat: "yaml", OutErrMsg: `unknown output "yaml", use "raw", "table", "json", "ndjson", "csv" or "template=<template>"`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := New(&bytes.Buffer{}, td.InFormat, testOptions)
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWriter_WriteIssues(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InFormat   string
+		InURLs     bool
+		InReporter bool
+		InUpdated  bool
+		InPages    [][]jira.Issue
+		Out        string
+	}{
+		{
+			Name:     "Raw",
+			InFormat: "raw",
+			InPages:  [][]jira.Issue{testIssues},
+			Out:      "JIWA-1\nJIWA-2\n",
+		},
+		{
+			Name:     "RawURLs",
+			InFormat: "raw",
+			InURLs:   true,
+			InPages:  [][]jira.Issue{testIssues},
+			Out:      "https://jira.example.com/browse/JIWA-1\nhttps://jira.example.com/browse/JIWA-2\n",
+		},
+		{
+			Name:     "Table",
+			InFormat: "table",
+			InPages:  [][]jira.Issue{testIssues},
+			Out: "ID\tSummary\t\t\tPoints\tURL\n" +
+				"JIWA-1\t⚑ Deploy, then \"verify\"\t3\thttps://jira.example.com/browse/JIWA-1\n" +
+				"JIWA-2\tPlain\t\t\t\thttps://jira.example.com/browse/JIWA-2\n",
+		},
+		{
+			Name:     "TableSecurityLevel",
+			InFormat: "table",
+			InPages: [][]jira.Issue{{{Key: "JIWA-3", Fields: &jira.IssueFields{
+				Summary:  "Leaked key",
+				Unknowns: map[string]any{"security": map[string]any{"id": "10101", "name": "Internal"}},
+			}}}},
+			Out: "ID\tSummary\t\tPoints\tURL\n" +
+				"JIWA-3\t🔒 Leaked key\t\thttps://jira.example.com/browse/JIWA-3\n",
+		},
+		{
+			Name:       "TableReporter",
+			InFormat:   "table",
+			InReporter: true,
+			InPages: [][]jira.Issue{{{Key: "JIWA-4", Fields: &jira.IssueFields{
+				Summary:  "Typo",
+				Reporter: &jira.User{Name: "bob", DisplayName: "Bob"},
+			}}}},
+			Out: "ID\tSummary\tReporter\tPoints\tURL\n" +
+				"JIWA-4\tTypo\tBob\t\t\thttps://jira.example.com/browse/JIWA-4\n",
+		},
+		{
+			Name:      "TableUpdated",
+			InFormat:  "table",
+			InUpdated: true,
+			InPages: [][]jira.Issue{{
+				{Key: "JIWA-5", Fields: &jira.IssueFields{Summary: "Fresh", Updated: jira.Time(time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC))}},
+				{Key: "JIWA-6", Fields: &jira.IssueFields{Summary: "Stale", Updated: jira.Time(time.Date(2023, 11, 20, 9, 0, 0, 0, time.UTC))}},
+				{Key: "JIWA-7", Fields: &jira.IssueFields{Summary: "Never"}},
+			}},
+			Out: "ID\tSummary\tUpdated\t\tPoints\tURL\n" +
+				"JIWA-5\tFresh\t2h ago\t\t\thttps://jira.example.com/browse/JIWA-5\n" +
+				"JIWA-6\tStale\t2023-11-20\t\thttps://jira.example.com/browse/JIWA-6\n" +
+				"JIWA-7\tNever\t\t\t\thttps://jira.example.com/browse/JIWA-7\n",
+		},
+		{
+			Name:     "TableWithoutIssues",
+			InFormat: "table",
+			Out:      "ID\tSummary\tPoints\tURL\n",
+		},
+		{
+			Name:     "JSONAcrossPages",
+			InFormat: "json",
+			InPages:  [][]jira.Issue{testIssues[:1], testIssues[1:]},
+			Out: "[\n" +
+				`{"jiwaSchema":1,"key":"JIWA-1","project":"JIWA","summary":"Deploy, then \"verify\"","status":"In Progress","type":"Task","priority":"","assignee":"Alice","reporter":"","labels":["ops","urgent"],"parent":"","flagged":true,"storyPoints":3,"created":"2024-03-01T10:00:00Z","updated":"","description":"","url":"https://jira.example.com/browse/JIWA-1"},` + "\n" +
+				`{"jiwaSchema":1,"key":"JIWA-2","project":"JIWA","summary":"Plain","status":"","type":"","priority":"","assignee":"","reporter":"","labels":[],"parent":"","flagged":false,"storyPoints":null,"created":"","updated":"","description":"","url":"https://jira.example.com/browse/JIWA-2"}` + "\n" +
+				"]\n",
+		},
+		{
+			Name:     "JSONWithoutIssues",
+			InFormat: "json",
+			Out:      "[]\n",
+		},
+		{
+			Name:     "NDJSON",
+			InFormat: "ndjson",
+			InPages:  [][]jira.Issue{testIssues[1:]},
+			Out:      `{"jiwaSchema":1,"key":"JIWA-2","project":"JIWA","summary":"Plain","status":"","type":"","priority":"","assignee":"","reporter":"","labels":[],"parent":"","flagged":false,"storyPoints":null,"created":"","updated":"","description":"","url":"https://jira.example.com/browse/JIWA-2"}` + "\n",
+		},
+		{
+			Name:     "CSVQuoting",
+			InFormat: "csv",
+			InPages:  [][]jira.Issue{testIssues},
+			Out: "key,project,summary,status,type,priority,assignee,reporter,labels,parent,flagged,storyPoints,created,updated,url\n" +
+				`JIWA-1,JIWA,"Deploy, then ""verify""",In Progress,Task,,Alice,,ops urgent,,true,3,2024-03-01T10:00:00Z,,https://jira.example.com/browse/JIWA-1` + "\n" +
+				"JIWA-2,JIWA,Plain,,,,,,,,false,,,,https://jira.example.com/browse/JIWA-2\n",
+		},
+		{
+			Name:     "CSVWithoutIssues",
+			InFormat: "csv",
+			Out:      "key,project,summary,status,type,priority,assignee,reporter,labels,parent,flagged,storyPoints,created,updated,url\n",
+		},
+		{
+			Name:     "Template",
+			InFormat: `template={{.Key}} [{{join .Labels ","}}] {{.Summary}}`,
+			InPages:  [][]jira.Issue{testIssues},
+			Out:      "JIWA-1 [ops,urgent] Deploy, then \"verify\"\nJIWA-2 [] Plain\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			opts := testOptions
+			opts.URLs = td.InURLs
+			opts.ShowReporter = td.InReporter
+			opts.ShowUpdated = td.InUpdated
+			opts.Now = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+			var out bytes.Buffer
+			w, err := New(&out, td.InFormat, opts)
+			assert.NoError(t, err)
+
+			for _, page := range td.InPages {
+				assert.NoError(t, w.WriteIssues(page))
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, td.Out, out.String())
+		})
+	}
+}
+
+func TestWriter_WriteIssue(t *testing.T) {
+	testData := []struct {
+		Name     string
+		InFormat string
+		Out      string
+	}{
+		{
+			Name:     "JSON",
+			InFormat: "json",
+			Out:      "{\n  \"jiwaSchema\": 1,\n  \"key\": \"JIWA-2\",\n  \"project\": \"JIWA\",\n  \"summary\": \"Plain\",\n  \"status\": \"\",\n  \"type\": \"\",\n  \"priority\": \"\",\n  \"assignee\": \"\",\n  \"reporter\": \"\",\n  \"labels\": [],\n  \"parent\": \"\",\n  \"flagged\": false,\n  \"storyPoints\": null,\n  \"created\": \"\",\n  \"updated\": \"\",\n  \"description\": \"\",\n  \"url\": \"https://jira.example.com/browse/JIWA-2\"\n}\n",
+		},
+		{
+			Name:     "Template",
+			InFormat: "template={{.Key}}: {{.Summary | upper}}\n",
+			Out:      "JIWA-2: PLAIN\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			w, err := New(&out, td.InFormat, testOptions)
+			assert.NoError(t, err)
+
+			assert.NoError(t, w.WriteIssue(testIssues[1]))
+			assert.NoError(t, w.Close())
+			assert.Equal(t, td.Out, out.String())
+		})
+	}
+}
+
+func TestWriter_WriteKV(t *testing.T) {
+	pairs := []KV{
+		{Key: "displayName", Label: "Display name", Value: "Alice"},
+		{Key: "groups", Label: "Groups", Value: "jira-users, ops"},
+	}
+
+	testData := []struct {
+		Name     string
+		InFormat string
+		Out      string
+	}{
+		{Name: "Raw", InFormat: "raw", Out: "Alice\njira-users, ops\n"},
+		{Name: "Table", InFormat: "table", Out: "Display name:\tAlice\nGroups:\t\tjira-users, ops\n"},
+		{Name: "JSON", InFormat: "json", Out: "{\n  \"jiwaSchema\": 1,\n  \"displayName\": \"Alice\",\n  \"groups\": \"jira-users, ops\"\n}\n"},
+		{Name: "NDJSON", InFormat: "ndjson", Out: `{"jiwaSchema":1,"displayName":"Alice","groups":"jira-users, ops"}` + "\n"},
+		{Name: "CSV", InFormat: "csv", Out: "key,value\ndisplayName,Alice\ngroups,\"jira-users, ops\"\n"},
+		{Name: "Template", InFormat: "template={{.displayName}} in {{.groups}}", Out: "Alice in jira-users, ops\n"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			w, err := New(&out, td.InFormat, testOptions)
+			assert.NoError(t, err)
+
+			assert.NoError(t, w.WriteKV(pairs))
+			assert.NoError(t, w.Close())
+			assert.Equal(t, td.Out, out.String())
+		})
+	}
+}
+
+func TestTemplateWriter_Errors(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InFormat  string
+		InKV      bool
+		OutErrMsg string
+	}{
+		{
+			Name:      "UnknownField",
+			InFormat:  "template={{.Key}} {{.Sumary}}",
+			OutErrMsg: `failed to execute the output template for JIWA-1: template: output:1:11: executing "output" at <.Sumary>: can't evaluate field Sumary in type output.Issue`,
+		},
+		{
+			Name:      "MissingKey",
+			InFormat:  "template={{.email}}",
+			InKV:      true,
+			OutErrMsg: `failed to execute the output template: template: output:1:2: executing "output" at <.email>: map has no entry for key "email"`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			w, err := New(&out, td.InFormat, testOptions)
+			assert.NoError(t, err)
+
+			if td.InKV {
+				err = w.WriteKV([]KV{{Key: "displayName", Value: "Alice"}})
+			} else {
+				err = w.WriteIssues(testIssues)
+			}
+			assert.EqualError(t, err, td.OutErrMsg)
+			assert.Empty(t, out.String(), "nothing of a failed execution is written")
+		})
+	}
+}
+
+func TestNewIssue_Location(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+
+	opts := testOptions
+	opts.Location = tokyo
+	view := NewIssue(testIssues[0], opts)
+	assert.Equal(t, "2024-03-01T19:00:00+09:00", view.Created)
+	assert.Empty(t, view.Updated)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriter_PropagatesWriteErrors(t *testing.T) {
+	for _, format := range []string{"raw", "table", "json", "ndjson", "csv", "template={{.Key}}"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+			w, err := New(failingWriter{}, format, testOptions)
+			assert.NoError(t, err)
+			assert.ErrorContains(t, w.WriteIssues(testIssues), "broken pipe")
+		})
+	}
+}
+
+func TestWriteShow(t *testing.T) {
+	full := commands.FullDetail{
+		Comments:    []commands.ShowComment{},
+		Links:       []commands.IssueLink{},
+		Subtasks:    []commands.ShowSubtask{{Key: "JIWA-3", Summary: "Docs", Status: "To Do"}},
+		Attachments: []commands.ShowAttachment{},
+		Changes:     []commands.ShowChange{},
+	}
+
+	testData := []struct {
+		Name      string
+		InFormat  string
+		InDetail  commands.ShowDetail
+		OutFields []string
+		Out       string
+	}{
+		{
+			Name:      "ShortJSON",
+			InFormat:  "json",
+			InDetail:  commands.ShowShort,
+			OutFields: []string{"status", "assignee", "summary"},
+			Out:       "{\n  \"jiwaSchema\": 1,\n  \"key\": \"JIWA-1\",\n  \"status\": \"In Progress\",\n  \"assignee\": \"Alice\",\n  \"summary\": \"Deploy, then \\\"verify\\\"\",\n  \"url\": \"https://jira.example.com/browse/JIWA-1\"\n}\n",
+		},
+		{
+			Name:      "ShortNDJSON",
+			InFormat:  "ndjson",
+			InDetail:  commands.ShowShort,
+			OutFields: []string{"status", "assignee", "summary"},
+			Out:       "{\"jiwaSchema\":1,\"key\":\"JIWA-1\",\"status\":\"In Progress\",\"assignee\":\"Alice\",\"summary\":\"Deploy, then \\\"verify\\\"\",\"url\":\"https://jira.example.com/browse/JIWA-1\"}\n",
+		},
+		{
+			Name:      "FullNDJSON",
+			InFormat:  "ndjson",
+			InDetail:  commands.ShowFull,
+			OutFields: append(viewFieldsWith(testOptions), "comment", "issuelinks", "subtasks", "attachment"),
+			Out:       "\"comments\":[],\"links\":[],\"subtasks\":[{\"key\":\"JIWA-3\",\"summary\":\"Docs\",\"status\":\"To Do\"}],\"attachments\":[],\"changes\":[]}\n",
+		},
+		{
+			Name:      "ShortTemplate",
+			InFormat:  "template={{.Key}}: {{.Status}}\n",
+			InDetail:  commands.ShowShort,
+			OutFields: viewFieldsWith(testOptions),
+			Out:       "JIWA-1: In Progress\n",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			w, err := New(&out, td.InFormat, testOptions)
+			assert.NoError(t, err)
+
+			assert.Equal(t, td.OutFields, ShowFields(w, td.InDetail))
+			assert.NoError(t, WriteShow(w, testIssues[0], td.InDetail, full))
+			assert.NoError(t, w.Close())
+			assert.True(t, strings.HasSuffix(out.String(), td.Out), out.String())
+		})
+	}
+}
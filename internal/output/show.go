@@ -0,0 +1,82 @@
+package output
+
+import (
+	"encoding/json"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+)
+
+// ShortIssue is what show --short writes, the fields of its single line
+type ShortIssue struct {
+	Schema   int    `json:"jiwaSchema"`
+	Key      string `json:"key"`
+	Status   string `json:"status"`
+	Assignee string `json:"assignee"`
+	Summary  string `json:"summary"`
+	URL      string `json:"url"`
+}
+
+// FullIssue is what show --full writes, the Issue every command writes and
+// what only the full detail shows
+type FullIssue struct {
+	Issue
+	commands.FullDetail
+}
+
+// ShowFields are the fields show fetches for the writer at the detail
+// level. json and ndjson only need the ones they write, the other formats
+// write the issue like every command does.
+func ShowFields(w Writer, detail commands.ShowDetail) []string {
+	switch detail {
+	case commands.ShowShort:
+		if isJSON(w) {
+			return commands.ShortShowFields
+		}
+	case commands.ShowFull:
+		return append(w.Fields(), commands.FullShowFields...)
+	}
+
+	return w.Fields()
+}
+
+// WriteShow writes the issue at the detail level, full is only used by
+// ShowFull. json and ndjson write a ShortIssue, an Issue or a FullIssue.
+func WriteShow(w Writer, issue jira.Issue, detail commands.ShowDetail, full commands.FullDetail) error {
+	switch w := w.(type) {
+	case *jsonWriter:
+		return w.writeSingle(showView(issue, detail, full, w.opts))
+	case *ndjsonWriter:
+		return json.NewEncoder(w.w).Encode(showView(issue, detail, full, w.opts))
+	default:
+		return w.WriteIssue(issue)
+	}
+}
+
+func isJSON(w Writer) bool {
+	switch w.(type) {
+	case *jsonWriter, *ndjsonWriter:
+		return true
+	default:
+		return false
+	}
+}
+
+func showView(issue jira.Issue, detail commands.ShowDetail, full commands.FullDetail, opts Options) any {
+	view := NewIssue(issue, opts)
+	switch detail {
+	case commands.ShowShort:
+		return ShortIssue{
+			Schema:   view.Schema,
+			Key:      view.Key,
+			Status:   view.Status,
+			Assignee: view.Assignee,
+			Summary:  view.Summary,
+			URL:      view.URL,
+		}
+	case commands.ShowFull:
+		return FullIssue{Issue: view, FullDetail: full}
+	default:
+		return view
+	}
+}
@@ -0,0 +1,193 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+)
+
+// flaggedMarker is the uncolored ⚑, the escape codes would count towards
+// the width of the column and throw off the alignment
+const flaggedMarker = "⚑"
+
+// securityMarker is put in front of issues with a security level, they
+// are easy to paste somewhere they shouldn't be
+const securityMarker = "🔒"
+
+// rawWriter prints keys, or links with URLs, for piping into other
+// commands
+type rawWriter struct {
+	w    io.Writer
+	opts Options
+}
+
+// Fields only asks for the key, Jira always sends it
+func (r *rawWriter) Fields() []string {
+	return []string{"key"}
+}
+
+func (r *rawWriter) WriteIssues(issues []jira.Issue) error {
+	for _, i := range issues {
+		ref := i.Key
+		if r.opts.URLs {
+			ref = r.opts.IssueURL(i.Key)
+		}
+		_, err := fmt.Fprintln(r.w, ref)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *rawWriter) WriteIssue(issue jira.Issue) error {
+	return r.WriteIssues([]jira.Issue{issue})
+}
+
+// WriteKV prints only the values, a line each
+func (r *rawWriter) WriteKV(pairs []KV) error {
+	for _, kv := range pairs {
+		_, err := fmt.Fprintln(r.w, kv.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *rawWriter) Close() error {
+	return nil
+}
+
+// tableWriter flushes after every page, columns are only aligned within a
+// page but nobody has to wait for the last one.
+type tableWriter struct {
+	out    io.Writer
+	w      *tabwriter.Writer
+	opts   Options
+	header bool
+	kv     bool
+}
+
+func newTableWriter(w io.Writer, opts Options) *tableWriter {
+	return &tableWriter{
+		out:  w,
+		w:    tabwriter.NewWriter(w, 0, 8, 1, '\t', tabwriter.AlignRight),
+		opts: opts,
+	}
+}
+
+func (t *tableWriter) Fields() []string {
+	fields := []string{"summary", "security"}
+	if t.opts.ShowReporter {
+		fields = append(fields, "reporter")
+	}
+	if t.opts.ShowUpdated {
+		fields = append(fields, "updated")
+	}
+	for _, f := range []string{t.opts.FlaggedField, t.opts.StoryPointsField} {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+func (t *tableWriter) writeHeader() {
+	if t.header {
+		return
+	}
+	t.header = true
+
+	if t.opts.ShowProject {
+		fmt.Fprintf(t.w, "Project\t")
+	}
+	fmt.Fprintf(t.w, "ID\tSummary\t")
+	if t.opts.ShowReporter {
+		fmt.Fprintf(t.w, "Reporter\t")
+	}
+	if t.opts.ShowUpdated {
+		fmt.Fprintf(t.w, "Updated\t")
+	}
+	if t.opts.StoryPointsField != "" {
+		fmt.Fprintf(t.w, "Points\t")
+	}
+	fmt.Fprintf(t.w, "URL\n")
+}
+
+func (t *tableWriter) WriteIssues(issues []jira.Issue) error {
+	t.writeHeader()
+	for _, i := range issues {
+		if t.opts.ShowProject {
+			project, _, _ := strings.Cut(i.Key, "-")
+			fmt.Fprintf(t.w, "%s\t", project)
+		}
+		summary := ""
+		if i.Fields != nil {
+			summary = i.Fields.Summary
+		}
+		if commands.IsFlagged(i, t.opts.FlaggedField) {
+			summary = flaggedMarker + " " + summary
+		}
+		if commands.SecurityLevelOf(i) != "" {
+			summary = securityMarker + " " + summary
+		}
+		fmt.Fprintf(t.w, "%s\t%s\t", i.Key, summary)
+		if t.opts.ShowReporter {
+			var reporter *jira.User
+			if i.Fields != nil {
+				reporter = i.Fields.Reporter
+			}
+			fmt.Fprintf(t.w, "%s\t", userName(reporter))
+		}
+		if t.opts.ShowUpdated {
+			var updated time.Time
+			if i.Fields != nil {
+				updated = time.Time(i.Fields.Updated)
+			}
+			fmt.Fprintf(t.w, "%s\t", commands.FormatRelative(updated, t.opts.Now, t.opts.Location))
+		}
+		if t.opts.StoryPointsField != "" {
+			fmt.Fprintf(t.w, "%s\t", commands.FormatStoryPoints(i, t.opts.StoryPointsField))
+		}
+		fmt.Fprintf(t.w, "%s\n", t.opts.IssueURL(i.Key))
+	}
+
+	return t.w.Flush()
+}
+
+func (t *tableWriter) WriteIssue(issue jira.Issue) error {
+	return t.WriteIssues([]jira.Issue{issue})
+}
+
+// WriteKV lines the values up behind their labels
+func (t *tableWriter) WriteKV(pairs []KV) error {
+	t.kv = true
+	kw := tabwriter.NewWriter(t.out, 0, 8, 1, '\t', 0)
+	for _, kv := range pairs {
+		label := kv.Label
+		if label == "" {
+			label = kv.Key
+		}
+		fmt.Fprintf(kw, "%s:\t%s\n", label, kv.Value)
+	}
+
+	return kw.Flush()
+}
+
+// Close prints the header even if no issues were found, so it is clear the
+// search ran
+func (t *tableWriter) Close() error {
+	if !t.kv {
+		t.writeHeader()
+	}
+	return t.w.Flush()
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// templateFuncs are available in --output template=..., on top of the
+// builtins of text/template
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// templateWriter executes the template for every issue with its Issue
+// view, and once for key-value pairs with a map of the keys to the values.
+// Each execution ends on a newline, the template doesn't need one.
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+	opts Options
+}
+
+func newTemplateWriter(w io.Writer, text string, opts Options) (*templateWriter, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the output template: %w", err)
+	}
+
+	return &templateWriter{w: w, tmpl: tmpl, opts: opts}, nil
+}
+
+func (t *templateWriter) Fields() []string {
+	return viewFieldsWith(t.opts)
+}
+
+func (t *templateWriter) WriteIssues(issues []jira.Issue) error {
+	for _, i := range issues {
+		err := t.execute(NewIssue(i, t.opts))
+		if err != nil {
+			return fmt.Errorf("failed to execute the output template for %s: %w", i.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *templateWriter) WriteIssue(issue jira.Issue) error {
+	return t.WriteIssues([]jira.Issue{issue})
+}
+
+func (t *templateWriter) WriteKV(pairs []KV) error {
+	data := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		data[kv.Key] = kv.Value
+	}
+
+	err := t.execute(data)
+	if err != nil {
+		return fmt.Errorf("failed to execute the output template: %w", err)
+	}
+
+	return nil
+}
+
+// execute renders into a buffer first, a failing template doesn't leave
+// half a line behind
+func (t *templateWriter) execute(data any) error {
+	var b bytes.Buffer
+	err := t.tmpl.Execute(&b, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+		b.WriteString("\n")
+	}
+
+	_, err = b.WriteTo(t.w)
+	return err
+}
+
+func (t *templateWriter) Close() error {
+	return nil
+}
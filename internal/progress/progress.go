@@ -0,0 +1,164 @@
+// Package progress shows on stderr that jiwa is still waiting for Jira,
+// so a slow search or a long bulk change doesn't look like it hung.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// frames are drawn one after the other, one per interval
+var frames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+const (
+	// delay keeps the spinner away from commands that are done quickly
+	delay = 300 * time.Millisecond
+	// interval is how often the spinner is redrawn
+	interval = 100 * time.Millisecond
+)
+
+// Spinner is drawn while requests are in flight and cleared the moment
+// none are, so it never ends up in between what is printed after a
+// response. A disabled Spinner does nothing, every method is safe to call
+// from several goroutines.
+type Spinner struct {
+	w       io.Writer
+	enabled bool
+	stop    chan struct{}
+
+	mu       sync.Mutex
+	inFlight int
+	busy     time.Time
+	requests int
+	status   string
+	frame    int
+	shown    bool
+	closed   bool
+}
+
+// New returns a Spinner on f that is only enabled when f is a terminal
+// that can redraw a line and quiet isn't set, pipes and files never get
+// one.
+func New(f *os.File, quiet bool) *Spinner {
+	return newSpinner(f, !quiet && isTerminal(f) && os.Getenv("TERM") != "dumb")
+}
+
+func newSpinner(w io.Writer, enabled bool) *Spinner {
+	return &Spinner{w: w, enabled: enabled, stop: make(chan struct{})}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && (stat.Mode()&os.ModeCharDevice) != 0
+}
+
+// Enabled reports whether the spinner is ever drawn
+func (s *Spinner) Enabled() bool {
+	return s.enabled
+}
+
+// Start redraws the spinner in the background until Close
+func (s *Spinner) Start() {
+	if !s.enabled {
+		return
+	}
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-t.C:
+				s.tick(now)
+			}
+		}
+	}()
+}
+
+// Begin counts a request that was sent
+func (s *Spinner) Begin() {
+	if !s.enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight == 0 {
+		s.busy = time.Now()
+	}
+	s.inFlight++
+	s.requests++
+}
+
+// End counts a request that was answered, the spinner is cleared when it
+// was the last one in flight
+func (s *Spinner) End() {
+	if !s.enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	if s.inFlight == 0 {
+		s.clear()
+	}
+}
+
+// Status replaces the request count next to the spinner, e.g. with how
+// many issues were fetched so far
+func (s *Spinner) Status(format string, a ...any) {
+	if !s.enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = fmt.Sprintf(format, a...)
+}
+
+// Close stops the spinner and clears it
+func (s *Spinner) Close() {
+	if !s.enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.clear()
+}
+
+// tick draws the next frame if requests have been in flight for longer
+// than the delay
+func (s *Spinner) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.inFlight == 0 || now.Sub(s.busy) < delay {
+		return
+	}
+
+	status := s.status
+	if status == "" {
+		status = fmt.Sprintf("%d requests to Jira", s.requests)
+	}
+	fmt.Fprintf(s.w, "\r\033[K%c %s", frames[s.frame%len(frames)], status)
+	s.frame++
+	s.shown = true
+}
+
+func (s *Spinner) clear() {
+	if s.shown {
+		fmt.Fprint(s.w, "\r\033[K")
+		s.shown = false
+	}
+}
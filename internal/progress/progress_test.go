@@ -0,0 +1,68 @@
+package progress
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_DisabledWithoutTerminal(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	for name, f := range map[string]*os.File{"File": file, "Pipe": w} {
+		s := New(f, false)
+		assert.False(t, s.Enabled(), name)
+
+		s.Start()
+		s.Begin()
+		s.Status("fetched %d issues", 50)
+		s.tick(time.Now().Add(time.Hour))
+		s.End()
+		s.Close()
+	}
+
+	info, err := file.Stat()
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size(), "nothing is written to a file")
+}
+
+func TestSpinner(t *testing.T) {
+	var out bytes.Buffer
+	s := newSpinner(&out, true)
+	start := time.Now()
+
+	s.Begin()
+	s.tick(start)
+	assert.Empty(t, out.String(), "nothing is drawn before the delay")
+
+	s.Begin()
+	s.tick(start.Add(time.Second))
+	assert.Equal(t, "\r\033[K⠋ 2 requests to Jira", out.String())
+
+	out.Reset()
+	s.Status("fetched %d issues", 50)
+	s.tick(start.Add(2 * time.Second))
+	assert.Equal(t, "\r\033[K⠙ fetched 50 issues", out.String())
+
+	out.Reset()
+	s.End()
+	assert.Empty(t, out.String(), "a request is still in flight")
+	s.End()
+	assert.Equal(t, "\r\033[K", out.String(), "cleared once nothing is in flight")
+
+	out.Reset()
+	s.tick(start.Add(3 * time.Second))
+	s.Close()
+	assert.Empty(t, out.String(), "nothing is drawn while idle")
+}
@@ -0,0 +1,143 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrAborted is returned when the input ends before an answer was given
+var ErrAborted = errors.New("prompt aborted")
+
+// Prompter asks the user questions, it talks to the terminal directly so
+// stdin and stdout can stay part of a pipeline.
+type Prompter struct {
+	in     *bufio.Reader
+	out    io.Writer
+	closer io.Closer
+}
+
+// Open connects a Prompter to the controlling terminal
+func Open() (*Prompter, error) {
+	inPath, outPath := terminalPaths(runtime.GOOS)
+	if inPath == outPath {
+		tty, err := os.OpenFile(inPath, os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot prompt without a terminal: %w", err)
+		}
+
+		return &Prompter{in: bufio.NewReader(tty), out: tty, closer: tty}, nil
+	}
+
+	in, err := os.OpenFile(inPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot prompt without a terminal: %w", err)
+	}
+
+	out, err := os.OpenFile(outPath, os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("cannot prompt without a terminal: %w", err)
+	}
+
+	return &Prompter{in: bufio.NewReader(in), out: out, closer: closers{in, out}}, nil
+}
+
+// terminalPaths returns the files to read the answers from and to write the
+// questions to, the Windows console has one for each direction.
+func terminalPaths(goos string) (string, string) {
+	if goos == "windows" {
+		return "CONIN$", "CONOUT$"
+	}
+
+	return "/dev/tty", "/dev/tty"
+}
+
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var err error
+	for _, c := range cs {
+		err = errors.Join(err, c.Close())
+	}
+
+	return err
+}
+
+// New returns a Prompter on arbitrary streams
+func New(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *Prompter) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+
+	return p.closer.Close()
+}
+
+func (p *Prompter) Printf(format string, a ...any) {
+	fmt.Fprintf(p.out, format, a...)
+}
+
+// Ask prints the question and returns the trimmed line that was answered
+func (p *Prompter) Ask(question string) (string, error) {
+	fmt.Fprint(p.out, question)
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		if errors.Is(err, io.EOF) {
+			return "", ErrAborted
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// Confirm asks a yes/no question that defaults to no
+func (p *Prompter) Confirm(question string) (bool, error) {
+	answer, err := p.Ask(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Choose lists the options numbered from 1 and returns the index of the
+// picked one, or -1 if the answer was empty.
+func (p *Prompter) Choose(question string, options []string) (int, error) {
+	for i, o := range options {
+		fmt.Fprintf(p.out, "%3d) %s\n", i+1, o)
+	}
+
+	for {
+		answer, err := p.Ask(question)
+		if err != nil {
+			return -1, err
+		}
+
+		if answer == "" {
+			return -1, nil
+		}
+
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+
+		fmt.Fprintf(p.out, "please pick a number between 1 and %d\n", len(options))
+	}
+}
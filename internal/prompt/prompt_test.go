@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrompter_Choose(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InAnswers string
+		OutIdx    int
+		OutErr    error
+	}{
+		{Name: "PicksSecond", InAnswers: "2\n", OutIdx: 1},
+		{Name: "RetriesOutOfRange", InAnswers: "7\nfoo\n3\n", OutIdx: 2},
+		{Name: "EmptyAnswer", InAnswers: "\n", OutIdx: -1},
+		{Name: "NoTrailingNewline", InAnswers: "1", OutIdx: 0},
+		{Name: "InputEnds", InAnswers: "", OutIdx: -1, OutErr: ErrAborted},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			p := New(strings.NewReader(td.InAnswers), io.Discard)
+			idx, err := p.Choose("> ", []string{"a", "b", "c"})
+
+			assert.Equal(t, td.OutErr, err)
+			assert.Equal(t, td.OutIdx, idx)
+		})
+	}
+}
+
+func TestTerminalPaths(t *testing.T) {
+	testData := []struct {
+		Name   string
+		InGOOS string
+		OutIn  string
+		OutOut string
+	}{
+		{Name: "Linux", InGOOS: "linux", OutIn: "/dev/tty", OutOut: "/dev/tty"},
+		{Name: "Darwin", InGOOS: "darwin", OutIn: "/dev/tty", OutOut: "/dev/tty"},
+		{Name: "Windows", InGOOS: "windows", OutIn: "CONIN$", OutOut: "CONOUT$"},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			in, out := terminalPaths(td.InGOOS)
+
+			assert.Equal(t, td.OutIn, in)
+			assert.Equal(t, td.OutOut, out)
+		})
+	}
+}
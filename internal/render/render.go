@@ -0,0 +1,471 @@
+// Package render turns the HTML Jira renders for wiki markup into plain
+// text for the terminal, with ANSI styles if asked for.
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options change how the text is rendered
+type Options struct {
+	// Color styles headings, bold, italics and inline code with ANSI
+	// escapes, without it headings are prefixed with "#" and inline code
+	// is put in backticks
+	Color bool
+}
+
+const (
+	ansiBold      = "\x1b[1m"
+	ansiNoBold    = "\x1b[22m"
+	ansiItalic    = "\x1b[3m"
+	ansiNoItalic  = "\x1b[23m"
+	ansiCyan      = "\x1b[36m"
+	ansiNoColor   = "\x1b[39m"
+	ansiUnderline = "\x1b[4m"
+	ansiNoUnder   = "\x1b[24m"
+)
+
+// codeIndent is put in front of every line of a code block
+const codeIndent = "    "
+
+// JiraHTML renders the HTML of a rendered field, e.g. the description in
+// renderedFields, as text. Paragraphs are separated by blank lines, lists
+// are indented with their markers, code blocks keep their whitespace and
+// are indented and links keep their target if it isn't their text.
+func JiraHTML(src string, opts Options) string {
+	r := &renderer{opts: opts}
+	for _, t := range tokenize(src) {
+		switch t.Type {
+		case textToken:
+			r.text(t.Data)
+		case startTagToken:
+			r.start(t)
+			if t.SelfClosing {
+				r.end(t.Data)
+			}
+		case endTagToken:
+			r.end(t.Data)
+		}
+	}
+
+	return strings.TrimRight(r.b.String(), " \n")
+}
+
+type list struct {
+	ordered bool
+	n       int
+	// width is the width of the marker, the item's other lines are
+	// indented by it
+	width int
+}
+
+type renderer struct {
+	opts Options
+	b    strings.Builder
+
+	// started is set once anything was written, newlines aren't
+	// written before the first line
+	started bool
+	// newlines are written before the next word
+	newlines int
+	// lineStart is set until the first word of a line is written
+	lineStart bool
+	// space is written before the next word unless it starts a line
+	space bool
+	// pending are styles that start with the next word
+	pending string
+	// marker is written instead of the indentation of the list item at
+	// the start of the next line
+	marker string
+
+	lists []list
+	quote int
+	// lineQuote is the quote depth of the last line written, blank lines
+	// only belong to a quote if the lines around them do
+	lineQuote int
+
+	// pre collects the text of a code block, it is written as a whole
+	// once the block ends
+	pre      *strings.Builder
+	preDepth int
+
+	bold, italic, code, underline int
+
+	// link is the target of the link being rendered and linkText what
+	// was written of its text so far
+	link     string
+	linkText strings.Builder
+	inLink   bool
+
+	cell int
+}
+
+// block makes sure the next word starts n lines further down, n is 1 for a
+// new line and 2 for a blank line in between
+func (r *renderer) block(n int) {
+	if !r.started {
+		return
+	}
+	if r.lineStart {
+		r.newlines = max(r.newlines, n)
+		return
+	}
+
+	r.newlines = n
+	r.lineStart = true
+	r.space = false
+}
+
+func (r *renderer) prefix() string {
+	var p strings.Builder
+	for i := 0; i < r.quote; i++ {
+		p.WriteString("> ")
+	}
+
+	widths := 0
+	for _, l := range r.lists {
+		widths += l.width
+	}
+	if r.marker != "" && len(r.lists) != 0 {
+		p.WriteString(strings.Repeat(" ", widths-r.lists[len(r.lists)-1].width))
+		p.WriteString(r.marker)
+		r.marker = ""
+	} else {
+		p.WriteString(strings.Repeat(" ", widths))
+	}
+
+	return p.String()
+}
+
+// startLine writes the pending newlines and the indentation
+func (r *renderer) startLine() {
+	if r.started {
+		quote := strings.TrimRight(strings.Repeat("> ", min(r.quote, r.lineQuote)), " ")
+		for i := 0; i < r.newlines; i++ {
+			if i > 0 && quote != "" {
+				r.b.WriteString(quote)
+			}
+			r.b.WriteString("\n")
+		}
+	}
+	r.newlines = 0
+	r.b.WriteString(r.prefix())
+	r.lineQuote = r.quote
+	r.started = true
+	r.lineStart = false
+	r.space = false
+}
+
+func (r *renderer) word(w string) {
+	if r.lineStart || !r.started {
+		r.startLine()
+	} else if r.space {
+		r.b.WriteString(" ")
+		if r.inLink && r.linkText.Len() != 0 {
+			r.linkText.WriteString(" ")
+		}
+	}
+	r.space = false
+
+	r.b.WriteString(r.pending)
+	r.pending = ""
+	r.b.WriteString(w)
+	if r.inLink {
+		r.linkText.WriteString(w)
+	}
+}
+
+// separateWord writes a word of its own, like a heading marker, with
+// spaces around it
+func (r *renderer) separateWord(w string) {
+	r.space = true
+	r.word(w)
+	r.space = true
+}
+
+func (r *renderer) text(s string) {
+	if r.pre != nil {
+		r.pre.WriteString(s)
+		return
+	}
+
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" && !r.lineStart {
+			r.space = true
+		}
+		return
+	}
+
+	if strings.TrimLeft(s, " \t\r\n") != s && r.started && !r.lineStart {
+		r.space = true
+	}
+	for i, f := range fields {
+		if i > 0 {
+			r.space = true
+		}
+		r.word(f)
+	}
+	if strings.TrimRight(s, " \t\r\n") != s {
+		r.space = true
+	}
+}
+
+// style starts or ends an ANSI style, nested styles only switch it on and
+// off once. Styles that end before any word was written are dropped.
+func (r *renderer) style(depth *int, on, off string, start bool) {
+	if !r.opts.Color {
+		return
+	}
+
+	if start {
+		*depth++
+		if *depth == 1 {
+			r.pending += on
+		}
+		return
+	}
+
+	if *depth == 0 {
+		return
+	}
+	*depth--
+	if *depth != 0 {
+		return
+	}
+	if strings.HasSuffix(r.pending, on) {
+		r.pending = strings.TrimSuffix(r.pending, on)
+		return
+	}
+	r.b.WriteString(off)
+}
+
+func (r *renderer) start(t token) {
+	if r.pre != nil {
+		if t.Data == "br" {
+			r.pre.WriteString("\n")
+		}
+		if t.Data == "pre" {
+			r.preDepth++
+		}
+		return
+	}
+
+	switch t.Data {
+	case "p", "div", "table":
+		r.block(r.paragraph())
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.block(2)
+		if r.opts.Color {
+			r.style(&r.bold, ansiBold, ansiNoBold, true)
+			if t.Data == "h1" || t.Data == "h2" {
+				r.style(&r.underline, ansiUnderline, ansiNoUnder, true)
+			}
+		} else {
+			r.separateWord(strings.Repeat("#", int(t.Data[1]-'0')))
+		}
+	case "br":
+		r.newline()
+	case "hr":
+		r.block(2)
+		r.word("----")
+		r.block(2)
+	case "ul", "ol":
+		if len(r.lists) == 0 {
+			r.block(r.paragraph())
+		}
+		r.lists = append(r.lists, list{ordered: t.Data == "ol"})
+	case "li":
+		r.block(1)
+		if len(r.lists) == 0 {
+			r.lists = append(r.lists, list{})
+		}
+		l := &r.lists[len(r.lists)-1]
+		l.n++
+		r.marker = "- "
+		if l.ordered {
+			r.marker = fmt.Sprintf("%d. ", l.n)
+		}
+		l.width = max(l.width, len(r.marker))
+		// the marker is written once the item has a word
+		r.lineStart = true
+	case "blockquote":
+		r.block(r.paragraph())
+		r.quote++
+	case "pre":
+		r.block(r.paragraph())
+		r.pre = &strings.Builder{}
+		r.preDepth = 1
+	case "b", "strong", "th":
+		if t.Data == "th" {
+			r.cellStart()
+		}
+		r.style(&r.bold, ansiBold, ansiNoBold, true)
+	case "i", "em", "cite":
+		r.style(&r.italic, ansiItalic, ansiNoItalic, true)
+	case "tt", "code":
+		if r.opts.Color {
+			r.style(&r.code, ansiCyan, ansiNoColor, true)
+		} else {
+			r.codeStart()
+		}
+	case "tr":
+		r.block(1)
+		r.cell = 0
+	case "td":
+		r.cellStart()
+	case "a":
+		href := t.Attrs["href"]
+		// mentions and issue keys are links to what their text names
+		class := t.Attrs["class"]
+		if strings.Contains(class, "user-hover") || strings.Contains(class, "issue-link") || strings.HasPrefix(href, "#") {
+			href = ""
+		}
+		r.link = href
+		r.linkText.Reset()
+		r.inLink = true
+	case "img":
+		alt := t.Attrs["alt"]
+		if alt == "" {
+			alt = t.Attrs["src"]
+		}
+		r.word("[image: " + alt + "]")
+	}
+}
+
+func (r *renderer) end(name string) {
+	if r.pre != nil {
+		if name != "pre" {
+			return
+		}
+		r.preDepth--
+		if r.preDepth == 0 {
+			r.endPre()
+		}
+		return
+	}
+
+	switch name {
+	case "p", "div", "table":
+		r.block(r.paragraph())
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if r.opts.Color {
+			if name == "h1" || name == "h2" {
+				r.style(&r.underline, ansiUnderline, ansiNoUnder, false)
+			}
+			r.style(&r.bold, ansiBold, ansiNoBold, false)
+		}
+		r.block(2)
+	case "ul", "ol":
+		if len(r.lists) != 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+		// an empty item's marker must not end up in front of the text
+		// after its list
+		r.marker = ""
+		if len(r.lists) == 0 {
+			r.block(2)
+		} else {
+			r.block(1)
+		}
+	case "li":
+		r.marker = ""
+		r.block(1)
+	case "blockquote":
+		r.block(2)
+		if r.quote > 0 {
+			r.quote--
+		}
+	case "b", "strong", "th":
+		r.style(&r.bold, ansiBold, ansiNoBold, false)
+	case "i", "em", "cite":
+		r.style(&r.italic, ansiItalic, ansiNoItalic, false)
+	case "tt", "code":
+		if r.opts.Color {
+			r.style(&r.code, ansiCyan, ansiNoColor, false)
+		} else {
+			r.codeEnd()
+		}
+	case "a":
+		text := r.linkText.String()
+		r.inLink = false
+		if r.link != "" && text != r.link && !strings.HasPrefix(r.link, "mailto:"+text) {
+			r.space = true
+			r.word("(" + r.link + ")")
+		}
+		r.link = ""
+	}
+}
+
+// paragraph is the gap around blocks, items of a list are only a line
+// apart
+func (r *renderer) paragraph() int {
+	if len(r.lists) != 0 {
+		return 1
+	}
+
+	return 2
+}
+
+// newline ends the line even if it is empty, unlike block
+func (r *renderer) newline() {
+	if r.lineStart {
+		r.newlines++
+		return
+	}
+
+	r.newlines = 1
+	r.lineStart = true
+	r.space = false
+}
+
+func (r *renderer) cellStart() {
+	if r.cell > 0 {
+		r.separateWord("|")
+	}
+	r.cell++
+}
+
+// codeStart and codeEnd put inline code in backticks that stick to it
+func (r *renderer) codeStart() {
+	r.code++
+	if r.code == 1 {
+		r.pending += "`"
+	}
+}
+
+func (r *renderer) codeEnd() {
+	if r.code == 0 {
+		return
+	}
+	r.code--
+	if r.code != 0 {
+		return
+	}
+	if strings.HasSuffix(r.pending, "`") {
+		r.pending = strings.TrimSuffix(r.pending, "`")
+		return
+	}
+	r.b.WriteString("`")
+}
+
+// endPre writes the code block, indented and with its whitespace as it is
+func (r *renderer) endPre() {
+	code := strings.Trim(r.pre.String(), "\n")
+	r.pre = nil
+
+	for i, line := range strings.Split(code, "\n") {
+		if i > 0 {
+			r.newline()
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			continue
+		}
+		r.startLine()
+		r.b.WriteString(codeIndent + line)
+	}
+	r.block(r.paragraph())
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestJiraHTML renders every testdata/*.html and compares it with the .txt
+// next to it, the _color.txt is the rendering with ANSI styles
+func TestJiraHTML(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, in := range inputs {
+		in := in
+		name := strings.TrimSuffix(filepath.Base(in), ".html")
+		for _, color := range []bool{false, true} {
+			color := color
+			golden := strings.TrimSuffix(in, ".html") + ".txt"
+			testName := name
+			if color {
+				golden = strings.TrimSuffix(in, ".html") + "_color.txt"
+				testName += "Color"
+			}
+
+			t.Run(testName, func(t *testing.T) {
+				src, err := os.ReadFile(in)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := JiraHTML(string(src), Options{Color: color}) + "\n"
+
+				if *update {
+					err = os.WriteFile(golden, []byte(got), 0o644)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("%v, run the tests with -update to create it", err)
+				}
+				assert.Equal(t, string(want), got)
+			})
+		}
+	}
+}
+
+func TestJiraHTML_Fragments(t *testing.T) {
+	testData := []struct {
+		Name   string
+		InHTML string
+		Out    string
+	}{
+		{
+			Name:   "PlainText",
+			InHTML: "just text",
+			Out:    "just text",
+		},
+		{
+			Name:   "Empty",
+			InHTML: "",
+			Out:    "",
+		},
+		{
+			Name:   "BrokenMarkup",
+			InHTML: "<p>a < b and <b>c",
+			Out:    "a < b and c",
+		},
+		{
+			Name:   "Comment",
+			InHTML: "<p>a<!-- hidden --> b</p>",
+			Out:    "a b",
+		},
+		{
+			Name:   "Heading",
+			InHTML: "<p>intro</p><h3>Notes</h3><p>body</p>",
+			Out:    "intro\n\n### Notes\n\nbody",
+		},
+		{
+			Name:   "QuoteParagraphs",
+			InHTML: "<p>before</p><blockquote><p>a</p><p>b</p></blockquote><p>after</p>",
+			Out:    "before\n\n> a\n>\n> b\n\nafter",
+		},
+		{
+			Name:   "QuotedGreaterThan",
+			InHTML: `<a href="https://example.com/?q=a>b">q</a>`,
+			Out:    "q (https://example.com/?q=a>b)",
+		},
+		{
+			Name:   "EmptyItemBeforeTheEndOfTheList",
+			InHTML: "<ul><li></ul>x",
+			Out:    "x",
+		},
+		{
+			Name:   "EndOfAListThatNeverStarted",
+			InHTML: "<li></ul>00",
+			Out:    "00",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, JiraHTML(td.InHTML, Options{}))
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package render
+
+import (
+	"html"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	textToken tokenType = iota
+	startTagToken
+	endTagToken
+)
+
+// token is a piece of HTML, Data is the unescaped text or the lower case
+// tag name
+type token struct {
+	Type        tokenType
+	Data        string
+	Attrs       map[string]string
+	SelfClosing bool
+}
+
+// tokenize splits the HTML Jira renders into text and tags. It is no
+// general purpose parser, comments, doctypes and the like are dropped and
+// broken markup is passed on as text.
+func tokenize(src string) []token {
+	tokens := make([]token, 0)
+	for len(src) != 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt == -1 {
+			tokens = append(tokens, token{Type: textToken, Data: html.UnescapeString(src)})
+			break
+		}
+		if lt > 0 {
+			tokens = append(tokens, token{Type: textToken, Data: html.UnescapeString(src[:lt])})
+			src = src[lt:]
+		}
+
+		// a "<" that doesn't start a tag, like in "a < b", is text
+		if len(src) == 1 || !isTagStart(src[1]) {
+			tokens = append(tokens, token{Type: textToken, Data: "<"})
+			src = src[1:]
+			continue
+		}
+
+		if strings.HasPrefix(src, "<!--") {
+			end := strings.Index(src, "-->")
+			if end == -1 {
+				break
+			}
+			src = src[end+3:]
+			continue
+		}
+
+		gt := tagEnd(src)
+		if gt == -1 {
+			tokens = append(tokens, token{Type: textToken, Data: html.UnescapeString(src)})
+			break
+		}
+
+		t, ok := parseTag(src[1:gt])
+		if ok {
+			tokens = append(tokens, t)
+		}
+		src = src[gt+1:]
+	}
+
+	return tokens
+}
+
+func isTagStart(c byte) bool {
+	return c == '/' || c == '!' || c == '?' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// tagEnd finds the ">" closing the tag at the start of src, skipping the
+// ones in quoted attribute values
+func tagEnd(src string) int {
+	var quote byte
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseTag parses what is between "<" and ">"
+func parseTag(s string) (token, bool) {
+	t := token{Type: startTagToken}
+	if strings.HasPrefix(s, "/") {
+		t.Type = endTagToken
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		t.SelfClosing = true
+		s = s[:len(s)-1]
+	}
+	if strings.HasPrefix(s, "!") || strings.HasPrefix(s, "?") {
+		return t, false
+	}
+
+	nameEnd := strings.IndexAny(s, " \t\r\n")
+	if nameEnd == -1 {
+		nameEnd = len(s)
+	}
+	t.Data = strings.ToLower(s[:nameEnd])
+	if t.Data == "" {
+		return t, false
+	}
+
+	t.Attrs = parseAttrs(s[nameEnd:])
+
+	return t, true
+}
+
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return attrs
+		}
+
+		nameEnd := strings.IndexAny(s, "= \t\r\n")
+		if nameEnd == -1 {
+			attrs[strings.ToLower(s)] = ""
+			return attrs
+		}
+		name := strings.ToLower(s[:nameEnd])
+		s = strings.TrimLeft(s[nameEnd:], " \t\r\n")
+		if !strings.HasPrefix(s, "=") {
+			attrs[name] = ""
+			continue
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+
+		var value string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end == -1 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t\r\n")
+			if end == -1 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		attrs[name] = html.UnescapeString(value)
+	}
+}
@@ -0,0 +1,376 @@
+// Package serve is the local HTTP bridge of jiwa serve. Editor plugins
+// talk JSON to it and it talks to Jira with one long-lived client, so they
+// don't pay for starting jiwa and a new TLS connection on every lookup.
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+// shutdownTimeout is how long requests in flight get to finish once the
+// server is asked to stop
+const shutdownTimeout = 5 * time.Second
+
+// maxBodySize caps what POST requests can send, issues and comments are
+// far smaller
+const maxBodySize = 1 << 20
+
+// errTruncated stops the search for /issues once "listCap" issues were
+// found
+var errTruncated = errors.New("too many issues")
+
+// Help is what GET /help answers with, it is the documentation of the API
+const Help = `jiwa serve answers JSON on these routes:
+
+GET  /issues?jql=<query>[&fields=<field>,...]
+     The issues matching the query as an array, fields narrows down what
+     is fetched of each, e.g. fields=summary,status. At most "listCap"
+     issues are returned, the header Jiwa-Truncated: true says there were
+     more.
+GET  /issue/<key>[?fields=<field>,...]
+     The issue, with all fields unless fields is given.
+POST /issue
+     Create an issue from an object with the keys jiwa import understands:
+     project, summary, description, type, labels, components and parent.
+     project defaults to "defaultProject" and type to Task. Answers
+     {"key": ..., "url": ...} with 201.
+POST /issue/<key>/comment
+     Comment {"body": "..."} on the issue, @name becomes a mention. Answers
+     {"key": ..., "url": ...} with 201.
+GET  /help
+     This text.
+
+Errors are {"error": "..."} with 400 for bad requests, 404 for unknown
+routes and 502 when Jira failed. Requests from browsers, recognized by
+their Origin header, are refused.
+`
+
+// Handler answers the API with cmd, every request gets a copy of it with
+// the request's context
+func Handler(cmd commands.Command) http.Handler {
+	cmd.NoPrompt = true
+	h := &handler{cmd: cmd}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/help", h.help)
+	mux.HandleFunc("/issues", h.issues)
+	mux.HandleFunc("/issue", h.create)
+	mux.HandleFunc("/issue/", h.issue)
+
+	return localOnly(mux)
+}
+
+// socketListener removes the socket when it is closed, the listener only
+// knows the path it was created at
+type socketListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l socketListener) Close() error {
+	err := l.UnixListener.Close()
+	_ = os.Remove(l.path)
+
+	return err
+}
+
+// Listen opens the address the server is reached on. A TCP address has to
+// be on the loopback interface, a socket path is only accessible by the
+// user.
+func Listen(address, socket string) (net.Listener, error) {
+	if socket != "" {
+		// a socket left behind by a server that was killed
+		if fi, err := os.Lstat(socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(socket)
+		}
+
+		// the socket is created with the umask, it gets its 0600 inside a
+		// directory only the user can enter and is moved in place after
+		dir, err := os.MkdirTemp(filepath.Dir(socket), ".jiwa-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		private := filepath.Join(dir, "sock")
+		l, err := net.ListenUnix("unix", &net.UnixAddr{Name: private, Net: "unix"})
+		if err != nil {
+			return nil, err
+		}
+		l.SetUnlinkOnClose(false)
+
+		err = os.Chmod(private, 0o600)
+		if err == nil {
+			err = os.Rename(private, socket)
+		}
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+
+		return socketListener{UnixListener: l, path: socket}, nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if !isLoopback(host) {
+		return nil, fmt.Errorf("refusing to listen on %s, anyone who can reach it could act as you on Jira, use 127.0.0.1 or --socket", host)
+	}
+
+	return net.Listen("tcp", address)
+}
+
+// Serve answers requests on l until ctx is cancelled, then waits for the
+// requests in flight to finish
+func Serve(ctx context.Context, l net.Listener, h http.Handler) error {
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		done <- srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.Serve(l)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-done
+}
+
+type handler struct {
+	cmd commands.Command
+}
+
+// command is the command for a request, cancelled with it
+func (h *handler) command(r *http.Request) commands.Command {
+	c := h.cmd
+	c.Context = r.Context()
+	return c
+}
+
+func (h *handler) help(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, Help)
+}
+
+func (h *handler) issues(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	jql := r.URL.Query().Get("jql")
+	if jql == "" {
+		writeError(w, http.StatusBadRequest, errors.New("jql is missing"))
+		return
+	}
+
+	cmd := h.command(r)
+	limit := cmd.ListCap()
+	issues := make([]jira.Issue, 0)
+	truncated := false
+	err := cmd.SearchPages(r.Context(), jql, func(page []jira.Issue) error {
+		if len(issues)+len(page) > limit {
+			page = page[:limit-len(issues)]
+			truncated = true
+		}
+		issues = append(issues, page...)
+		if truncated {
+			return errTruncated
+		}
+		return nil
+	}, queryFields(r))
+	if err != nil && !errors.Is(err, errTruncated) {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if truncated {
+		w.Header().Set("Jiwa-Truncated", "true")
+	}
+	writeJSON(w, http.StatusOK, issues)
+}
+
+// issue answers GET /issue/<key> and POST /issue/<key>/comment
+func (h *handler) issue(w http.ResponseWriter, r *http.Request) {
+	key, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/issue/"), "/")
+	switch {
+	case key == "":
+		writeError(w, http.StatusNotFound, errors.New("no issue key given, see /help"))
+	case rest == "":
+		h.getIssue(w, r, key)
+	case rest == "comment":
+		h.comment(w, r, key)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown route %s, see /help", r.URL.Path))
+	}
+}
+
+func (h *handler) getIssue(w http.ResponseWriter, r *http.Request, key string) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var opts []jiwa.GetIssueOption
+	if fields := queryFields(r); len(fields) != 0 {
+		opts = append(opts, jiwa.WithFields(fields...))
+	}
+
+	cmd := h.command(r)
+	issue, err := cmd.Cat(key, opts...)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, issue)
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cmd := h.command(r)
+	key, err := cmd.CreateFromJSON(body)
+	switch {
+	case errors.Is(err, commands.ErrInvalidIssue):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"key": key, "url": cmd.ConstructIssueURL(key)})
+}
+
+func (h *handler) comment(w http.ResponseWriter, r *http.Request, key string) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("body is missing"))
+		return
+	}
+
+	cmd := h.command(r)
+	_, err = cmd.Comment([]string{key}, req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"key": key, "url": cmd.ConstructIssueURL(key)})
+}
+
+// localOnly refuses requests from web pages, a page could otherwise make
+// the browser talk to the server on the user's behalf. The Host check
+// stops DNS rebinding, where a page's own host name resolves to 127.0.0.1.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("requests from browsers are not allowed"))
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		// pages can't reach the unix socket, clients name any host there
+		if !overSocket(r) && !isLoopback(host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("unexpected host %q", r.Host))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func overSocket(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s %s is not supported, see /help", r.Method, r.URL.Path))
+	return false
+}
+
+// queryFields splits the comma separated fields parameter
+func queryFields(r *http.Request) []string {
+	fields := make([]string, 0)
+	for _, f := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
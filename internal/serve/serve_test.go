@@ -0,0 +1,275 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/commands"
+	"github.com/catouc/jiwa/pkg/jiwa/jiwafake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InMethod    string
+		InPath      string
+		InBody      string
+		InHeaders   map[string]string
+		InListCap   int
+		OutStatus   int
+		OutHeaders  map[string]string
+		OutBody     string
+		OutComments []string
+	}{
+		{
+			Name:      "Help",
+			InMethod:  http.MethodGet,
+			InPath:    "/help",
+			OutStatus: http.StatusOK,
+			OutBody:   "POST /issue/<key>/comment",
+		},
+		{
+			Name:       "Issues",
+			InMethod:   http.MethodGet,
+			InPath:     "/issues?jql=project%3DJIWA&fields=summary",
+			OutStatus:  http.StatusOK,
+			OutBody:    `"key":"JIWA-3"`,
+			OutHeaders: map[string]string{"Jiwa-Truncated": ""},
+		},
+		{
+			Name:       "IssuesAreCapped",
+			InMethod:   http.MethodGet,
+			InPath:     "/issues?jql=project%3DJIWA",
+			InListCap:  1,
+			OutStatus:  http.StatusOK,
+			OutBody:    `[{"key":"JIWA-1","fields":{"summary":"Deploy"}}]`,
+			OutHeaders: map[string]string{"Jiwa-Truncated": "true"},
+		},
+		{
+			Name:      "IssuesWithoutJQL",
+			InMethod:  http.MethodGet,
+			InPath:    "/issues",
+			OutStatus: http.StatusBadRequest,
+			OutBody:   `{"error":"jql is missing"}`,
+		},
+		{
+			Name:      "Issue",
+			InMethod:  http.MethodGet,
+			InPath:    "/issue/JIWA-1",
+			OutStatus: http.StatusOK,
+			OutBody:   `"summary":"Deploy"`,
+		},
+		{
+			Name:      "MissingIssue",
+			InMethod:  http.MethodGet,
+			InPath:    "/issue/JIWA-9",
+			OutStatus: http.StatusBadGateway,
+			OutBody:   `"error":`,
+		},
+		{
+			Name:      "Create",
+			InMethod:  http.MethodPost,
+			InPath:    "/issue",
+			InBody:    `{"summary": "Rotate the keys", "labels": ["ops"]}`,
+			OutStatus: http.StatusCreated,
+			OutBody:   `{"key":"JIWA-2","url":"https://jira.example.com/browse/JIWA-2"}`,
+		},
+		{
+			Name:      "CreateWithoutSummary",
+			InMethod:  http.MethodPost,
+			InPath:    "/issue",
+			InBody:    `{"description": "no summary"}`,
+			OutStatus: http.StatusBadRequest,
+			OutBody:   `{"error":"invalid issue: summary is missing"}`,
+		},
+		{
+			Name:        "Comment",
+			InMethod:    http.MethodPost,
+			InPath:      "/issue/JIWA-1/comment",
+			InBody:      `{"body": "Deployed"}`,
+			OutStatus:   http.StatusCreated,
+			OutBody:     `{"key":"JIWA-1","url":"https://jira.example.com/browse/JIWA-1"}`,
+			OutComments: []string{"Deployed"},
+		},
+		{
+			Name:      "EmptyComment",
+			InMethod:  http.MethodPost,
+			InPath:    "/issue/JIWA-1/comment",
+			InBody:    `{"body": " "}`,
+			OutStatus: http.StatusBadRequest,
+			OutBody:   `{"error":"body is missing"}`,
+		},
+		{
+			Name:      "WrongMethod",
+			InMethod:  http.MethodDelete,
+			InPath:    "/issue/JIWA-1",
+			OutStatus: http.StatusMethodNotAllowed,
+			OutBody:   `{"error":"DELETE /issue/JIWA-1 is not supported, see /help"}`,
+		},
+		{
+			Name:      "UnknownRoute",
+			InMethod:  http.MethodGet,
+			InPath:    "/issue/JIWA-1/watchers",
+			OutStatus: http.StatusNotFound,
+			OutBody:   `{"error":"unknown route /issue/JIWA-1/watchers, see /help"}`,
+		},
+		{
+			Name:      "FromABrowser",
+			InMethod:  http.MethodGet,
+			InPath:    "/issue/JIWA-1",
+			InHeaders: map[string]string{"Origin": "https://evil.example.com"},
+			OutStatus: http.StatusForbidden,
+			OutBody:   `{"error":"requests from browsers are not allowed"}`,
+		},
+		{
+			Name:      "RebindedHost",
+			InMethod:  http.MethodGet,
+			InPath:    "/issue/JIWA-1",
+			InHeaders: map[string]string{"Host": "evil.example.com:7373"},
+			OutStatus: http.StatusForbidden,
+			OutBody:   `{"error":"unexpected host \"evil.example.com:7373\""}`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			fake := jiwafake.New()
+			fake.Issues["JIWA-1"] = jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Deploy"}}
+			fake.SearchFunc = func(jql string) ([]jira.Issue, error) {
+				return []jira.Issue{fake.Issues["JIWA-1"], {Key: "JIWA-3"}}, nil
+			}
+			h := Handler(commands.Command{Client: fake, Config: commands.Config{
+				BaseURL:        "https://jira.example.com",
+				DefaultProject: "JIWA",
+				ListCap:        td.InListCap,
+			}})
+
+			req := httptest.NewRequest(td.InMethod, "http://127.0.0.1:7373"+td.InPath, strings.NewReader(td.InBody))
+			for k, v := range td.InHeaders {
+				if k == "Host" {
+					req.Host = v
+					continue
+				}
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, td.OutStatus, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), td.OutBody)
+			for k, v := range td.OutHeaders {
+				assert.Equal(t, v, rec.Header().Get(k), k)
+			}
+
+			var comments []string
+			if c := fake.Issues["JIWA-1"].Fields.Comments; c != nil {
+				for _, comment := range c.Comments {
+					comments = append(comments, comment.Body)
+				}
+			}
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InAddress string
+		OutErrMsg string
+	}{
+		{
+			Name:      "Loopback",
+			InAddress: "127.0.0.1:0",
+		},
+		{
+			Name:      "Localhost",
+			InAddress: "localhost:0",
+		},
+		{
+			Name:      "AllInterfaces",
+			InAddress: "0.0.0.0:7373",
+			OutErrMsg: "refusing to listen on 0.0.0.0, anyone who can reach it could act as you on Jira, use 127.0.0.1 or --socket",
+		},
+		{
+			Name:      "NoPort",
+			InAddress: "127.0.0.1",
+			OutErrMsg: `invalid address "127.0.0.1": address 127.0.0.1: missing port in address`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			l, err := Listen(td.InAddress, "")
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			l.Close()
+		})
+	}
+}
+
+func TestServe_SocketAndShutdown(t *testing.T) {
+	// unix socket paths are short, t.TempDir can be too long on macOS
+	dir, err := os.MkdirTemp("", "jiwa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "jiwa.sock")
+
+	l, err := Listen("", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(socket)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, l, Handler(commands.Command{Client: jiwafake.New()}))
+	}()
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://jiwa/help")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("the server didn't stop")
+	}
+
+	// neither the socket nor the directory it was created in are left
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,210 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRecent is how many issues are remembered before the oldest are dropped
+const MaxRecent = 50
+
+var (
+	lockRetryInterval = 10 * time.Millisecond
+	lockTimeout       = 5 * time.Second
+	// a lock older than this was left behind by a crashed jiwa
+	lockStaleAfter = 30 * time.Second
+)
+
+type Entry struct {
+	Key     string    `json:"key"`
+	Summary string    `json:"summary,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+type State struct {
+	// Recent holds the issues that were last acted on, most recent first
+	Recent []Entry `json:"recent"`
+	// Fields caches the IDs of custom fields that were looked up by what
+	// they are for, e.g. "flagged". An empty ID means there is none.
+	Fields map[string]string `json:"fields,omitempty"`
+	// TriagePoolNext is the index of the "triagePool" member whose turn it
+	// is to be assigned an issue
+	TriagePoolNext int `json:"triagePoolNext,omitempty"`
+	// LabelSuggestions caches the labels the instance suggested for
+	// completion, keyed by the prefix that was completed
+	LabelSuggestions map[string]Suggestions `json:"labelSuggestions,omitempty"`
+}
+
+// Suggestions are the completions the instance suggested at a time
+type Suggestions struct {
+	Values []string  `json:"values"`
+	At     time.Time `json:"at"`
+}
+
+// Push records that key was acted on, moving it to the front if it was
+// already known. An empty summary keeps the one that was cached before.
+func (s *State) Push(key, summary string) {
+	for i, e := range s.Recent {
+		if e.Key == key {
+			if summary == "" {
+				summary = e.Summary
+			}
+			s.Recent = append(s.Recent[:i], s.Recent[i+1:]...)
+			break
+		}
+	}
+
+	s.Recent = append([]Entry{{Key: key, Summary: summary, At: time.Now()}}, s.Recent...)
+	if len(s.Recent) > MaxRecent {
+		s.Recent = s.Recent[:MaxRecent]
+	}
+}
+
+// IsRef reports whether the argument is meant to be resolved from state
+func IsRef(arg string) bool {
+	return strings.HasPrefix(arg, "@")
+}
+
+// Resolve turns "@last", "@prev" or "@-N" into the issue key it refers to.
+// "@last" is the most recent issue, "@prev" and "@-1" the one before it.
+func (s *State) Resolve(ref string) (string, error) {
+	var idx int
+	switch ref {
+	case "@last":
+		idx = 0
+	case "@prev":
+		idx = 1
+	default:
+		n, err := strconv.Atoi(strings.TrimPrefix(ref, "@-"))
+		if !strings.HasPrefix(ref, "@-") || err != nil || n < 1 {
+			return "", fmt.Errorf("cannot resolve %q, use @last, @prev or @-N", ref)
+		}
+		idx = n
+	}
+
+	if idx >= len(s.Recent) {
+		return "", fmt.Errorf("cannot resolve %q, only %d recent issues are known", ref, len(s.Recent))
+	}
+
+	return s.Recent[idx].Key, nil
+}
+
+// Ref returns the reference that resolves to the entry at idx in Recent
+func Ref(idx int) string {
+	switch idx {
+	case 0:
+		return "@last"
+	default:
+		return "@-" + strconv.Itoa(idx)
+	}
+}
+
+// Store persists State in a JSON file, guarded by a lock file so
+// concurrent jiwa invocations in a pipeline don't lose each other's updates.
+type Store struct {
+	Path string
+}
+
+// DefaultPath returns where the state for the profile lives, in the
+// user's cache dir.
+func DefaultPath(profile string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user cache dir: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "jiwa", profile, "state.json"), nil
+}
+
+// Load reads the state without taking the lock, a missing file is an
+// empty state.
+func (s *Store) Load() (State, error) {
+	var st State
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	err = json.Unmarshal(b, &st)
+	if err != nil {
+		return st, fmt.Errorf("failed to unmarshal state file %s: %w", s.Path, err)
+	}
+
+	return st, nil
+}
+
+// Update locks the state file, hands the current state to fn and writes
+// back whatever fn left in it, unless fn returns an error.
+func (s *Store) Update(fn func(*State) error) error {
+	err := os.MkdirAll(filepath.Dir(s.Path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	unlock, err := Lock(s.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&st)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(&st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp := s.Path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return os.Rename(tmp, s.Path)
+}
+
+// Lock takes the lock file next to path and returns the function that
+// releases it, other files kept next to the state use it too.
+func Lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock state file: %w", err)
+		}
+
+		info, statErr := os.Stat(lockPath)
+		if statErr == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for state lock %s, remove it if no other jiwa is running", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_Resolve(t *testing.T) {
+	st := State{}
+	st.Push("JIWA-1", "first")
+	st.Push("JIWA-2", "second")
+	st.Push("JIWA-3", "")
+	st.Push("JIWA-1", "")
+
+	testData := []struct {
+		Name      string
+		InRef     string
+		OutKey    string
+		OutErrMsg string
+	}{
+		{Name: "Last", InRef: "@last", OutKey: "JIWA-1"},
+		{Name: "Prev", InRef: "@prev", OutKey: "JIWA-3"},
+		{Name: "MinusOne", InRef: "@-1", OutKey: "JIWA-3"},
+		{Name: "MinusTwo", InRef: "@-2", OutKey: "JIWA-2"},
+		{Name: "OutOfRange", InRef: "@-3", OutErrMsg: `cannot resolve "@-3", only 3 recent issues are known`},
+		{Name: "Garbage", InRef: "@first", OutErrMsg: `cannot resolve "@first", use @last, @prev or @-N`},
+		{Name: "MinusZero", InRef: "@-0", OutErrMsg: `cannot resolve "@-0", use @last, @prev or @-N`},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			key, err := st.Resolve(td.InRef)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutKey, key)
+		})
+	}
+
+	assert.Equal(t, "first", st.Recent[0].Summary, "pushing without a summary keeps the cached one")
+}
+
+func TestStore_UpdateConcurrently(t *testing.T) {
+	s := Store{Path: filepath.Join(t.TempDir(), "default", "state.json")}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.Update(func(st *State) error {
+				st.Push(Ref(i+1), "")
+				return nil
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	st, err := s.Load()
+	assert.NoError(t, err)
+	assert.Len(t, st.Recent, 20)
+}
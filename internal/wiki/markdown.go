@@ -0,0 +1,226 @@
+// Package wiki converts Markdown to the wiki markup Jira Server and Data
+// Center render descriptions with.
+package wiki
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	fenceRe     = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*([\\w+#.-]*)")
+	headingRe   = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	ruleRe      = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	listItemRe  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	quoteRe     = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	tableSepRe  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	tableLineRe = regexp.MustCompile(`^\s*\|.*\|\s*$`)
+
+	// inlineRe matches every inline construct but code, the leftmost
+	// match wins so the text a construct produces is never converted again
+	inlineRe = regexp.MustCompile(strings.Join([]string{
+		`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`,
+		`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`,
+		`<((?:https?|mailto):[^>\s]+)>`,
+		`\*\*(.+?)\*\*`,
+		`__(.+?)__`,
+		`\*([^*\s](?:[^*]*[^*\s])?)\*`,
+		`(?:^|\b)_([^_\s](?:[^_]*[^_\s])?)_\b`,
+		`~~(.+?)~~`,
+	}, "|"))
+)
+
+// FromMarkdown converts headings, bold, italics, strikethrough, inline
+// code, fenced code blocks, lists, quotes, tables, rules, links and images
+// to wiki markup. Everything else, including wiki markup that is already
+// there, is passed through as it is.
+func FromMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+
+	var lists []listLevel
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := listItemRe.FindStringSubmatch(line); m != nil && !ruleRe.MatchString(line) {
+			lists = nestList(lists, len(expandTabs(m[1])), !strings.ContainsAny(m[2], "-*+"))
+			out = append(out, listPrefix(lists)+" "+inline(m[3]))
+			continue
+		}
+		lists = nil
+
+		switch m := fenceRe.FindStringSubmatch(line); {
+		case m != nil:
+			end := closingFence(lines, i+1, m[1])
+			out = append(out, codeBlock(m[2], lines[i+1:end])...)
+			i = end
+		case headingRe.MatchString(line):
+			h := headingRe.FindStringSubmatch(line)
+			out = append(out, "h"+string(rune('0'+len(h[1])))+". "+inline(h[2]))
+		case ruleRe.MatchString(line):
+			out = append(out, "----")
+		case quoteRe.MatchString(line):
+			quoted := make([]string, 0)
+			for ; i < len(lines) && quoteRe.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteRe.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			out = append(out, "{quote}", FromMarkdown(strings.Join(quoted, "\n")), "{quote}")
+		case tableLineRe.MatchString(line) && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]):
+			out = append(out, tableRow(line, "||"))
+			i++
+			for ; i+1 < len(lines) && tableLineRe.MatchString(lines[i+1]); i++ {
+				out = append(out, tableRow(lines[i+1], "|"))
+			}
+		default:
+			out = append(out, inline(line))
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+type listLevel struct {
+	indent  int
+	ordered bool
+}
+
+// nestList finds the level of an item by its indentation, deeper items
+// open a new level and shallower ones close the levels they are left of
+func nestList(lists []listLevel, indent int, ordered bool) []listLevel {
+	for len(lists) != 0 && lists[len(lists)-1].indent > indent {
+		lists = lists[:len(lists)-1]
+	}
+	if len(lists) == 0 || lists[len(lists)-1].indent < indent {
+		return append(lists, listLevel{indent: indent, ordered: ordered})
+	}
+
+	lists[len(lists)-1].ordered = ordered
+	return lists
+}
+
+// listPrefix is "*" or "#" for every level, e.g. "#*" for a bullet in a
+// numbered list
+func listPrefix(lists []listLevel) string {
+	var b strings.Builder
+	for _, l := range lists {
+		if l.ordered {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('*')
+		}
+	}
+
+	return b.String()
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// closingFence returns the line of the fence that closes the block, an
+// unclosed block runs to the end
+func closingFence(lines []string, start int, fence string) int {
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			return i
+		}
+	}
+
+	return len(lines)
+}
+
+// codeBlock keeps the code as it is, blocks without a language are
+// {noformat} because {code} would highlight them as Java
+func codeBlock(lang string, code []string) []string {
+	open, end := "{noformat}", "{noformat}"
+	if lang != "" {
+		open, end = "{code:"+lang+"}", "{code}"
+	}
+
+	block := make([]string, 0, len(code)+2)
+	block = append(block, open)
+	block = append(block, code...)
+	return append(block, end)
+}
+
+// tableRow turns "| a | b |" into "|a|b|", sep is "||" for the header
+func tableRow(line, sep string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+
+	cells := strings.Split(line, "|")
+	for i, c := range cells {
+		cells[i] = inline(strings.TrimSpace(c))
+	}
+
+	return sep + strings.Join(cells, sep) + sep
+}
+
+// inline converts the constructs within a line, code spans are taken out
+// first so nothing in them is converted
+func inline(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '`')
+		if start == -1 {
+			b.WriteString(inlineText(s))
+			return b.String()
+		}
+
+		// a span is closed by a run of as many backticks as it was opened
+		// with, "``a `b` c``" is one span
+		ticks := len(s[start:]) - len(strings.TrimLeft(s[start:], "`"))
+		fence := s[start : start+ticks]
+		end := -1
+		for i := start + ticks; i < len(s); {
+			j := strings.Index(s[i:], fence)
+			if j == -1 {
+				break
+			}
+			j += i
+			after := j + ticks
+			if after == len(s) || s[after] != '`' {
+				end = j
+				break
+			}
+			i = after + len(s[after:]) - len(strings.TrimLeft(s[after:], "`"))
+		}
+		if end == -1 {
+			b.WriteString(inlineText(s[:start+ticks]))
+			s = s[start+ticks:]
+			continue
+		}
+
+		b.WriteString(inlineText(s[:start]))
+		b.WriteString("{{" + strings.TrimSpace(s[start+ticks:end]) + "}}")
+		s = s[end+ticks:]
+	}
+}
+
+func inlineText(s string) string {
+	return inlineRe.ReplaceAllStringFunc(s, func(match string) string {
+		m := inlineRe.FindStringSubmatch(match)
+		switch {
+		case m[2] != "":
+			return "!" + m[2] + "!"
+		case m[3] != "":
+			return "[" + inline(m[3]) + "|" + m[4] + "]"
+		case m[5] != "":
+			return "[" + m[5] + "]"
+		case m[6] != "":
+			return "*" + inline(m[6]) + "*"
+		case m[7] != "":
+			return "*" + inline(m[7]) + "*"
+		case m[8] != "":
+			return "_" + inline(m[8]) + "_"
+		case m[9] != "":
+			return "_" + inline(m[9]) + "_"
+		case m[10] != "":
+			return "-" + inline(m[10]) + "-"
+		default:
+			return match
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package wiki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromMarkdown(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Markdown string
+		Wiki     string
+	}{
+		{
+			Name:     "PlainText",
+			Markdown: "Nothing to convert here.\n\nSecond paragraph.",
+			Wiki:     "Nothing to convert here.\n\nSecond paragraph.",
+		},
+		{
+			Name:     "Headings",
+			Markdown: "# Title\n## Steps ##\n###### Small\n#hashtag",
+			Wiki:     "h1. Title\nh2. Steps\nh6. Small\n#hashtag",
+		},
+		{
+			Name:     "BoldAndItalic",
+			Markdown: "**bold**, __bold__, *italic*, _italic_ and **bold with _italic_**",
+			Wiki:     "*bold*, *bold*, _italic_, _italic_ and *bold with _italic_*",
+		},
+		{
+			Name:     "SnakeCaseStaysAsItIs",
+			Markdown: "set max_page_size and 2 * 3 * 4",
+			Wiki:     "set max_page_size and 2 * 3 * 4",
+		},
+		{
+			Name:     "Strikethrough",
+			Markdown: "~~wrong~~ right",
+			Wiki:     "-wrong- right",
+		},
+		{
+			Name:     "InlineCode",
+			Markdown: "run `jiwa list --limit 5` or ``a `quoted` one``, `**not bold**`",
+			Wiki:     "run {{jiwa list --limit 5}} or {{a `quoted` one}}, {{**not bold**}}",
+		},
+		{
+			Name:     "FencedCode",
+			Markdown: "Before\n```go\nfunc main() {\n\t// **not bold**\n}\n```\nAfter",
+			Wiki:     "Before\n{code:go}\nfunc main() {\n\t// **not bold**\n}\n{code}\nAfter",
+		},
+		{
+			Name:     "FencedCodeWithoutLanguage",
+			Markdown: "~~~\n# not a heading\n~~~",
+			Wiki:     "{noformat}\n# not a heading\n{noformat}",
+		},
+		{
+			Name:     "UnclosedFence",
+			Markdown: "```sh\nmake",
+			Wiki:     "{code:sh}\nmake\n{code}",
+		},
+		{
+			Name:     "BulletList",
+			Markdown: "- one\n* two\n+ three",
+			Wiki:     "* one\n* two\n* three",
+		},
+		{
+			Name:     "NestedLists",
+			Markdown: "1. Export\n2. Import\n   - with *dry-run*\n   - without\n     1. deep\n3. Compare",
+			Wiki:     "# Export\n# Import\n#* with _dry-run_\n#* without\n#*# deep\n# Compare",
+		},
+		{
+			Name:     "ListEndsAtParagraph",
+			Markdown: "- a\n\n- b",
+			Wiki:     "* a\n\n* b",
+		},
+		{
+			Name:     "Links",
+			Markdown: "see [the docs](https://example.com/docs \"Docs\"), <https://example.com> and [**bold** link](https://example.com)",
+			Wiki:     "see [the docs|https://example.com/docs], [https://example.com] and [*bold* link|https://example.com]",
+		},
+		{
+			Name:     "Image",
+			Markdown: "![screenshot](https://example.com/error.png)",
+			Wiki:     "!https://example.com/error.png!",
+		},
+		{
+			Name:     "Quote",
+			Markdown: "> It broke\n> **again**\n\nafter",
+			Wiki:     "{quote}\nIt broke\n*again*\n{quote}\n\nafter",
+		},
+		{
+			Name:     "Rule",
+			Markdown: "above\n\n---\n\n* * *\nbelow",
+			Wiki:     "above\n\n----\n\n----\nbelow",
+		},
+		{
+			Name:     "Table",
+			Markdown: "| Field | Before |\n|-------|:------:|\n| summary | `Old` |\n| type | Bug |",
+			Wiki:     "||Field||Before||\n|summary|{{Old}}|\n|type|Bug|",
+		},
+		{
+			Name:     "WikiMarkupPassesThrough",
+			Markdown: "h2. Already wiki\n{code}\nx\n{code}",
+			Wiki:     "h2. Already wiki\n{code}\nx\n{code}",
+		},
+		{
+			Name:     "WindowsLineEndings",
+			Markdown: "# Title\r\nbody",
+			Wiki:     "h1. Title\nbody",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Wiki, FromMarkdown(td.Markdown))
+		})
+	}
+}
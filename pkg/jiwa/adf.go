@@ -0,0 +1,45 @@
+package jiwa
+
+import "strings"
+
+// commentBody shapes a comment for the API version, v2 takes wiki markup
+// as a plain string while v3 only accepts the Atlassian Document Format.
+func commentBody(apiVersion, text string) any {
+	if apiVersion != "3" {
+		return text
+	}
+
+	return adfDocument(text)
+}
+
+// adfDocument turns plain text into an ADF document, blank lines separate
+// paragraphs and single newlines become hard breaks.
+func adfDocument(text string) map[string]any {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	paragraphs := make([]any, 0)
+	for _, block := range strings.Split(text, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+
+		content := make([]any, 0)
+		for i, line := range strings.Split(block, "\n") {
+			if i > 0 {
+				content = append(content, map[string]any{"type": "hardBreak"})
+			}
+			if line != "" {
+				content = append(content, map[string]any{"type": "text", "text": line})
+			}
+		}
+
+		paragraphs = append(paragraphs, map[string]any{"type": "paragraph", "content": content})
+	}
+
+	return map[string]any{
+		"type":    "doc",
+		"version": 1,
+		"content": paragraphs,
+	}
+}
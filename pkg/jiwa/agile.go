@@ -0,0 +1,106 @@
+package jiwa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// ListBoards returns all boards that reference the given project,
+// boardType can be used to filter for "scrum" or "kanban" boards and
+// is ignored if empty.
+func (c *Client) ListBoards(ctx context.Context, project, boardType string) ([]jira.Board, error) {
+	params := url.Values{}
+	params.Set("projectKeyOrId", project)
+	if boardType != "" {
+		params.Set("type", boardType)
+	}
+
+	b, err := c.callAgileAPI(ctx, http.MethodGet, "board", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list boards for project %s: %w", project, err)
+	}
+
+	var resp struct {
+		Values []jira.Board `json:"values"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal board response: %w", err)
+	}
+
+	return resp.Values, nil
+}
+
+// ListSprints returns the sprints of a board, states is a comma separated
+// list of "future", "active" and "closed" and is ignored if empty.
+func (c *Client) ListSprints(ctx context.Context, boardID int, states string) ([]jira.Sprint, error) {
+	params := url.Values{}
+	if states != "" {
+		params.Set("state", states)
+	}
+
+	b, err := c.callAgileAPI(ctx, http.MethodGet, "board/"+strconv.Itoa(boardID)+"/sprint", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sprints for board %d: %w", boardID, err)
+	}
+
+	var resp struct {
+		Values []jira.Sprint `json:"values"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sprint response: %w", err)
+	}
+
+	return resp.Values, nil
+}
+
+// AddToSprint moves all the given issues into the sprint
+func (c *Client) AddToSprint(ctx context.Context, sprintID int, keys ...string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("need to supply at least one issue to add to sprint %d", sprintID)
+	}
+
+	body, err := json.Marshal(&struct {
+		Issues []string `json:"issues"`
+	}{Issues: keys})
+	if err != nil {
+		return fmt.Errorf("failed to marshal sprint request: %w", err)
+	}
+
+	_, err = c.callAgileAPI(ctx, http.MethodPost, "sprint/"+strconv.Itoa(sprintID)+"/issue", nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to add issues to sprint %d: %w", sprintID, err)
+	}
+
+	return nil
+}
+
+// MoveToBacklog removes the issues from whatever sprint they are in
+func (c *Client) MoveToBacklog(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return errors.New("need to supply at least one issue to move to the backlog")
+	}
+
+	body, err := json.Marshal(&struct {
+		Issues []string `json:"issues"`
+	}{Issues: keys})
+	if err != nil {
+		return fmt.Errorf("failed to marshal backlog request: %w", err)
+	}
+
+	_, err = c.callAgileAPI(ctx, http.MethodPost, "backlog/issue", nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to move issues to backlog: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package jiwa
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// API covers the Jira operations the jiwa CLI relies on, Client is the
+// implementation talking to a real instance and jiwafake.Client keeps
+// everything in memory for tests.
+type API interface {
+	CreateIssue(ctx context.Context, input CreateIssueInput) (jira.Issue, error)
+	GetIssue(ctx context.Context, key string, opts ...GetIssueOption) (jira.Issue, error)
+	UpdateIssue(ctx context.Context, key string, input UpdateIssueInput) error
+	AssignIssue(ctx context.Context, key string, assignee string) error
+	Search(ctx context.Context, jql string, opts ...GetIssueOption) ([]jira.Issue, error)
+	SearchPages(ctx context.Context, jql string, fn func(page []jira.Issue) error, opts ...GetIssueOption) error
+	Count(ctx context.Context, jql string) (int, error)
+	ListIssueTransitions(ctx context.Context, key string) ([]IssueTransition, error)
+	Transition(ctx context.Context, key string, input TransitionInput) error
+	GetProject(ctx context.Context, key string) (jira.Project, error)
+	ListComponents(ctx context.Context, project string) ([]jira.ProjectComponent, error)
+	ListStatuses(ctx context.Context, project string) ([]jira.Status, error)
+	CommentOnIssue(ctx context.Context, issueID string, comment string) error
+	CommentOnIssueVisibleTo(ctx context.Context, issueID string, comment string, visibility jira.CommentVisibility) error
+	ListSecurityLevels(ctx context.Context, project string) ([]SecurityLevel, error)
+	ListProjectRoles(ctx context.Context, project string) ([]string, error)
+	SuggestLabels(ctx context.Context, prefix string) ([]string, error)
+	ArchiveIssue(ctx context.Context, key string) error
+	RestoreIssue(ctx context.Context, key string) error
+	MoveIssue(ctx context.Context, key, project, issueTypeID string) (string, error)
+	AddAttachment(ctx context.Context, key, name string, content []byte) (jira.Attachment, error)
+	DownloadAttachment(ctx context.Context, attachment jira.Attachment) ([]byte, error)
+	ListIssueLinkTypes(ctx context.Context) ([]jira.IssueLinkType, error)
+	LinkIssues(ctx context.Context, linkType, inwardKey, outwardKey string) error
+	ListIssueLinks(ctx context.Context, key string) ([]*jira.IssueLink, error)
+	DeleteIssueLink(ctx context.Context, id string) error
+	SearchUsers(ctx context.Context, query string) ([]jira.User, error)
+	SearchAssignableUsers(ctx context.Context, input AssignableUsersInput) ([]jira.User, error)
+	ListPriorities(ctx context.Context) ([]jira.Priority, error)
+	SetIssuePriority(ctx context.Context, key string, priority string) error
+	ServerInfo(ctx context.Context) (ServerInfo, error)
+	ListFields(ctx context.Context) ([]jira.Field, error)
+	GetCreateMeta(ctx context.Context, project, issueType string) (map[string]CreateField, error)
+	Myself(ctx context.Context) (Account, error)
+	MyPermissions(ctx context.Context, project, issue string) (map[string]Permission, error)
+
+	ListBoards(ctx context.Context, project, boardType string) ([]jira.Board, error)
+	ListSprints(ctx context.Context, boardID int, states string) ([]jira.Sprint, error)
+	AddToSprint(ctx context.Context, sprintID int, keys ...string) error
+	MoveToBacklog(ctx context.Context, keys ...string) error
+}
+
+var _ API = (*Client)(nil)
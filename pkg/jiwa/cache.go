@@ -0,0 +1,309 @@
+package jiwa
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// DefaultCacheSize is how many responses a ResponseCache keeps in memory
+// unless its Size says otherwise
+const DefaultCacheSize = 64
+
+// maxDiskResponses bounds the responses kept in the Dir of a
+// ResponseCache, the oldest files are removed first
+const maxDiskResponses = 1000
+
+// ResponseCache remembers the GET responses Jira sent an ETag or a
+// Last-Modified with. Asking for them again sends If-None-Match and
+// If-Modified-Since and a 304 Not Modified is answered from the cache, so
+// fetching the same issue a few times in a row costs little. Changes to an
+// issue through the Client drop what was cached about it, changes that
+// aren't about a single issue drop what is in memory. The zero value keeps
+// DefaultCacheSize responses in memory and is safe for concurrent use.
+type ResponseCache struct {
+	// Size is how many responses are kept in memory, the least recently
+	// used are dropped first
+	Size int
+	// Dir keeps the responses on disk as well, so that the next process
+	// can revalidate them too. Nothing is written when it is empty and
+	// failing to read or write there only costs a full response. An
+	// empty file in the issues directory for every issue a response is
+	// about finds them again when the issue changes.
+	Dir string
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedResponse struct {
+	Key          string `json:"key"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Issues are the keys and IDs of the issue the response is about
+	Issues []string `json:"issues,omitempty"`
+	Body   []byte   `json:"body"`
+}
+
+var issuePathRegEx = regexp.MustCompile(`/issue/([^/]+)(/?.*)$`)
+
+// issueDirRegEx are the keys and IDs that are safe as a directory name
+var issueDirRegEx = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// cacheKey tells the responses of different users apart, what they may see
+// differs
+func (c *Client) cacheKey(req *http.Request) string {
+	return c.Username + " " + req.URL.String()
+}
+
+// conditional makes a GET request conditional on what is cached for it
+// and returns the cached response, nil if there is none
+func (c *Client) conditional(req *http.Request) *cachedResponse {
+	if c.Cache == nil || req.Method != http.MethodGet {
+		return nil
+	}
+
+	cached, ok := c.Cache.get(c.cacheKey(req))
+	if !ok {
+		return nil
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	return cached
+}
+
+// cacheResponse remembers a successful GET response that can be
+// revalidated
+func (c *Client) cacheResponse(req *http.Request, resp *http.Response, body []byte) {
+	if c.Cache == nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	r := cachedResponse{
+		Key:          c.cacheKey(req),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         body,
+	}
+	if r.ETag == "" && r.LastModified == "" {
+		return
+	}
+
+	if m := issuePathRegEx.FindStringSubmatch(req.URL.Path); m != nil {
+		r.Issues = []string{m[1]}
+		// the issue itself knows both its key and its ID, either can be
+		// used to change it later
+		var issue struct {
+			ID  string `json:"id"`
+			Key string `json:"key"`
+		}
+		if m[2] == "" && json.Unmarshal(body, &issue) == nil {
+			r.Issues = append(r.Issues, issue.ID, issue.Key)
+		}
+	}
+
+	c.Cache.put(&r)
+}
+
+// invalidate drops what the request changes from the cache
+func (c *Client) invalidate(req *http.Request) {
+	if c.Cache == nil || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return
+	}
+
+	// everything on disk is revalidated before it is used, only what
+	// belongs to the issue is dropped from there right away
+	m := issuePathRegEx.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		c.Cache.clear()
+		return
+	}
+	c.Cache.drop(m[1])
+}
+
+func (rc *ResponseCache) get(key string) (*cachedResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if e, ok := rc.entries[key]; ok {
+		rc.order.MoveToFront(e)
+		return e.Value.(*cachedResponse), true
+	}
+
+	if rc.Dir == "" {
+		return nil, false
+	}
+	b, err := os.ReadFile(rc.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var r cachedResponse
+	if json.Unmarshal(b, &r) != nil || r.Key != key {
+		return nil, false
+	}
+	rc.remember(&r)
+
+	return &r, true
+}
+
+func (rc *ResponseCache) put(r *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.remember(r)
+	if rc.Dir != "" {
+		rc.store(r)
+	}
+}
+
+// remember adds the response to the front of the memory, rc.mu has to be
+// held
+func (rc *ResponseCache) remember(r *cachedResponse) {
+	if rc.entries == nil {
+		rc.entries = make(map[string]*list.Element)
+		rc.order = list.New()
+	}
+
+	if e, ok := rc.entries[r.Key]; ok {
+		e.Value = r
+		rc.order.MoveToFront(e)
+		return
+	}
+	rc.entries[r.Key] = rc.order.PushFront(r)
+
+	size := rc.Size
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	for rc.order.Len() > size {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cachedResponse).Key)
+	}
+}
+
+// store writes the response to Dir through a temporary file, so that
+// another jiwa never reads half of it
+func (rc *ResponseCache) store(r *cachedResponse) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(rc.Dir, 0o700) != nil {
+		return
+	}
+
+	f, err := os.CreateTemp(rc.Dir, ".response-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	closeErr := f.Close()
+	if err != nil || closeErr != nil || os.Rename(f.Name(), rc.path(r.Key)) != nil {
+		os.Remove(f.Name())
+		return
+	}
+
+	name := filepath.Base(rc.path(r.Key))
+	for _, issue := range r.Issues {
+		if !issueDirRegEx.MatchString(issue) {
+			continue
+		}
+		dir := filepath.Join(rc.Dir, "issues", issue)
+		if os.MkdirAll(dir, 0o700) == nil {
+			_ = os.WriteFile(filepath.Join(dir, name), nil, 0o600)
+		}
+	}
+
+	rc.prune()
+}
+
+// prune removes the oldest files once there are more than
+// maxDiskResponses
+func (rc *ResponseCache) prune() {
+	files, err := os.ReadDir(rc.Dir)
+	if err != nil || len(files) <= maxDiskResponses {
+		return
+	}
+
+	type file struct {
+		path    string
+		modTime int64
+	}
+	byAge := make([]file, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		byAge = append(byAge, file{path: filepath.Join(rc.Dir, f.Name()), modTime: info.ModTime().UnixNano()})
+	}
+	if len(byAge) <= maxDiskResponses {
+		return
+	}
+	sort.Slice(byAge, func(i, j int) bool { return byAge[i].modTime < byAge[j].modTime })
+
+	// what the issues directory still says about them is harmless, the
+	// files are only ever removed through it
+	for _, f := range byAge[:len(byAge)-maxDiskResponses] {
+		os.Remove(f.path)
+	}
+}
+
+// drop forgets every response about the issue, by key or ID
+func (rc *ResponseCache) drop(issue string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for key, e := range rc.entries {
+		if slices.Contains(e.Value.(*cachedResponse).Issues, issue) {
+			rc.order.Remove(e)
+			delete(rc.entries, key)
+		}
+	}
+
+	if rc.Dir == "" || !issueDirRegEx.MatchString(issue) {
+		return
+	}
+	dir := filepath.Join(rc.Dir, "issues", issue)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		os.Remove(filepath.Join(rc.Dir, f.Name()))
+	}
+	os.RemoveAll(dir)
+}
+
+// clear forgets every response in memory, the ones on disk are still
+// revalidated before they are used
+func (rc *ResponseCache) clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.entries = nil
+	rc.order = nil
+}
+
+func (rc *ResponseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(rc.Dir, hex.EncodeToString(sum[:])+".json")
+}
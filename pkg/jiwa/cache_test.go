@@ -0,0 +1,205 @@
+package jiwa
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Cache(t *testing.T) {
+	testData := []struct {
+		Name           string
+		InNoCache      bool
+		InOnDisk       bool
+		InNewClient    bool
+		InBetween      func(c *Client, srv *jiratest.Server) error
+		OutStatuses    []int
+		OutConditional []bool
+		OutSummary     string
+		OutLabels      []string
+	}{
+		{
+			Name:           "Revalidated",
+			OutStatuses:    []int{200, 304},
+			OutConditional: []bool{false, true},
+			OutSummary:     "Fix the thing",
+		},
+		{
+			Name: "ChangedElsewhere",
+			InBetween: func(_ *Client, srv *jiratest.Server) error {
+				srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Fix the other thing"}})
+				return nil
+			},
+			OutStatuses:    []int{200, 200},
+			OutConditional: []bool{false, true},
+			OutSummary:     "Fix the other thing",
+		},
+		{
+			Name: "InvalidatedByUpdate",
+			InBetween: func(c *Client, _ *jiratest.Server) error {
+				return c.LabelIssue(context.Background(), "JIWA-1", "urgent")
+			},
+			OutStatuses:    []int{200, 200},
+			OutConditional: []bool{false, false},
+			OutSummary:     "Fix the thing",
+			OutLabels:      []string{"urgent"},
+		},
+		{
+			Name:           "NotSharedInMemory",
+			InNewClient:    true,
+			OutStatuses:    []int{200, 200},
+			OutConditional: []bool{false, false},
+			OutSummary:     "Fix the thing",
+		},
+		{
+			Name:           "SharedOnDisk",
+			InOnDisk:       true,
+			InNewClient:    true,
+			OutStatuses:    []int{200, 304},
+			OutConditional: []bool{false, true},
+			OutSummary:     "Fix the thing",
+		},
+		{
+			Name:     "InvalidatedOnDisk",
+			InOnDisk: true,
+			InBetween: func(c *Client, _ *jiratest.Server) error {
+				return c.LabelIssue(context.Background(), "JIWA-1", "urgent")
+			},
+			InNewClient:    true,
+			OutStatuses:    []int{200, 200},
+			OutConditional: []bool{false, false},
+			OutSummary:     "Fix the thing",
+			OutLabels:      []string{"urgent"},
+		},
+		{
+			Name:     "KeptOnDiskByOtherChanges",
+			InOnDisk: true,
+			InBetween: func(c *Client, _ *jiratest.Server) error {
+				_, err := c.CreateIssue(context.Background(), CreateIssueInput{Project: "JIWA", Summary: "Fix another thing", Type: "Task"})
+				return err
+			},
+			InNewClient:    true,
+			OutStatuses:    []int{200, 304},
+			OutConditional: []bool{false, true},
+			OutSummary:     "Fix the thing",
+		},
+		{
+			Name:           "NoCache",
+			InNoCache:      true,
+			OutStatuses:    []int{200, 200},
+			OutConditional: []bool{false, false},
+			OutSummary:     "Fix the thing",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Fix the thing"}})
+			dir := ""
+			if td.InOnDisk {
+				dir = t.TempDir()
+			}
+			if !td.InNoCache {
+				c.Cache = &ResponseCache{Dir: dir}
+			}
+
+			_, err := c.GetIssue(context.Background(), "JIWA-1")
+			assert.NoError(t, err)
+			if td.InBetween != nil {
+				assert.NoError(t, td.InBetween(c, srv))
+			}
+			if td.InNewClient {
+				c, err = NewClient(Config{
+					BaseURL:    srv.URL,
+					Username:   srv.Username,
+					Password:   srv.Password,
+					HTTPClient: srv.Client(),
+					Cache:      &ResponseCache{Dir: dir},
+				})
+				assert.NoError(t, err)
+			}
+			issue, err := c.GetIssue(context.Background(), "JIWA-1")
+
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutSummary, issue.Fields.Summary)
+			assert.Equal(t, td.OutLabels, issue.Fields.Labels)
+			var statuses []int
+			var conditional []bool
+			for _, r := range srv.Requests() {
+				if r.Method == http.MethodGet {
+					statuses = append(statuses, r.Status)
+					conditional = append(conditional, r.IfNoneMatch != "")
+				}
+			}
+			assert.Equal(t, td.OutStatuses, statuses)
+			assert.Equal(t, td.OutConditional, conditional)
+		})
+	}
+}
+
+func TestResponseCache_Size(t *testing.T) {
+	rc := ResponseCache{Size: 2}
+	for _, key := range []string{"a", "b", "c"} {
+		rc.put(&cachedResponse{Key: key})
+	}
+	_, ok := rc.get("a")
+	assert.False(t, ok, "the least recently used response was kept")
+	_, ok = rc.get("c")
+	assert.True(t, ok)
+}
+
+func TestResponseCache_Drop(t *testing.T) {
+	testData := []struct {
+		Name   string
+		InDrop string
+		OutOK  map[string]bool
+	}{
+		{
+			Name:   "ByKey",
+			InDrop: "JIWA-1",
+			OutOK:  map[string]bool{"a": false, "b": true},
+		},
+		{
+			Name:   "ByID",
+			InDrop: "10000",
+			OutOK:  map[string]bool{"a": false, "b": true},
+		},
+		{
+			Name:   "Unknown",
+			InDrop: "JIWA-3",
+			OutOK:  map[string]bool{"a": true, "b": true},
+		},
+		{
+			Name:   "NotADirectory",
+			InDrop: "../JIWA-1",
+			OutOK:  map[string]bool{"a": true, "b": true},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			rc := ResponseCache{Dir: dir}
+			rc.put(&cachedResponse{Key: "a", Issues: []string{"JIWA-1", "10000"}})
+			rc.put(&cachedResponse{Key: "b", Issues: []string{"JIWA-2", "10001"}})
+
+			rc.drop(td.InDrop)
+
+			// a new cache only has what is on disk
+			onDisk := ResponseCache{Dir: dir}
+			for key, ok := range td.OutOK {
+				_, got := onDisk.get(key)
+				assert.Equal(t, ok, got, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,1625 @@
+package jiwa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+type Client struct {
+	Username   string
+	Password   string
+	Token      string
+	BaseURL    string
+	APIVersion string
+	HTTPClient *http.Client
+
+	// MaxRetries is how often a rate limited request is sent again after
+	// waiting for the Retry-After Jira asked for, none by default
+	MaxRetries int
+	// OnRequest is called before every HTTP request, retries included.
+	// Requests run in parallel in some commands, so it has to be safe for
+	// concurrent use.
+	OnRequest func(method, path string)
+	// OnResponse is called after every HTTP request with the status of the
+	// response, which is 0 if err says why there was none. It has to be
+	// safe for concurrent use as well.
+	OnResponse func(method, path string, status int, dur time.Duration, err error)
+	// Cache revalidates repeated GET requests instead of fetching them
+	// again, every request is sent as it is without one
+	Cache *ResponseCache
+
+	stats stats
+}
+
+// Config holds everything NewClient needs to talk to a Jira instance
+type Config struct {
+	// BaseURL is the address of the Jira instance, e.g. https://example.atlassian.net
+	BaseURL string
+	// EndpointPrefix is put between the BaseURL and the API paths for
+	// instances that are not hosted at the root of their domain
+	EndpointPrefix string
+	// APIVersion of the REST API, defaults to "2"
+	APIVersion string
+	Username   string
+	Password   string
+	Token      string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// MaxRetries is how often a rate limited request is retried, see
+	// Client.MaxRetries
+	MaxRetries int
+	// InsecureAllowHTTP allows a plain http BaseURL that isn't on the local
+	// machine, the credentials are sent in cleartext to it
+	InsecureAllowHTTP bool
+	// Cache revalidates repeated GET requests, see Client.Cache
+	Cache *ResponseCache
+}
+
+// ErrPlainHTTP is returned by NewClient for a plain http BaseURL that isn't
+// on the local machine unless InsecureAllowHTTP is set
+var ErrPlainHTTP = errors.New("plain http sends the credentials in cleartext, use https")
+
+// NewClient validates the configuration and returns a ready to use Client,
+// trailing and duplicate slashes between the base URL and the endpoint
+// prefix are taken care of.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("base URL is empty")
+	}
+
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", cfg.BaseURL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme needs to be http or https", cfg.BaseURL)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: missing host", cfg.BaseURL)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid base URL %q: must not contain a query or fragment", cfg.BaseURL)
+	}
+
+	if PlainHTTP(cfg.BaseURL) && !cfg.InsecureAllowHTTP {
+		return nil, fmt.Errorf("invalid base URL %q: %w", cfg.BaseURL, ErrPlainHTTP)
+	}
+
+	if cfg.Token == "" && (cfg.Username == "" || cfg.Password == "") {
+		return nil, errors.New("either username+password need to be set or token")
+	}
+
+	baseURL := strings.TrimRight(u.String(), "/")
+	if prefix := strings.Trim(cfg.EndpointPrefix, "/"); prefix != "" {
+		baseURL += "/" + prefix
+	}
+
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = "2"
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		Token:      cfg.Token,
+		BaseURL:    baseURL,
+		APIVersion: apiVersion,
+		HTTPClient: httpClient,
+		MaxRetries: cfg.MaxRetries,
+		Cache:      cfg.Cache,
+	}, nil
+}
+
+// PlainHTTP reports whether the base URL sends requests unencrypted over
+// the network. localhost and loopback addresses don't leave the machine, so
+// a test instance or a tunnel on them doesn't count.
+func PlainHTTP(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "http") {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return false
+	}
+
+	return true
+}
+
+func (c *Client) callAPI(ctx context.Context, method, endpoint string, params url.Values, body io.Reader) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/rest/api/%s/%s?%s", c.BaseURL, c.APIVersion, endpoint, params.Encode())
+	return c.do(ctx, method, reqURL, body)
+}
+
+// callAgileAPI is the equivalent of callAPI for the Jira Software endpoints
+// that live under /rest/agile instead of /rest/api
+func (c *Client) callAgileAPI(ctx context.Context, method, endpoint string, params url.Values, body io.Reader) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/rest/agile/1.0/%s?%s", c.BaseURL, endpoint, params.Encode())
+	return c.do(ctx, method, reqURL, body)
+}
+
+func (c *Client) do(ctx context.Context, method, reqURL string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+
+	return c.send(req)
+}
+
+// send authenticates the request and returns the body of a successful
+// response. A rate limited request is retried up to MaxRetries times if its
+// body can be sent again. GET requests are made conditional on what the
+// Cache has, anything else drops what it changes from there.
+func (c *Client) send(req *http.Request) (_ []byte, err error) {
+	switch {
+	case c.Username != "" && c.Password != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	default:
+		return nil, errors.New("either username+password need to be set or token")
+	}
+
+	// only what the request ends with counts, not the rate limited
+	// responses before a retry
+	defer func() {
+		if err != nil {
+			c.stats.errors.Add(1)
+		}
+	}()
+	// a change that failed may still have gone through
+	defer c.invalidate(req)
+	cached := c.conditional(req)
+
+	for attempt := 0; ; attempt++ {
+		resp, bodyBytes, err := c.roundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limited := retryAfter(resp)
+		if limited && attempt < c.MaxRetries && (req.Body == nil || req.GetBody != nil) {
+			err = sleep(req.Context(), wait)
+			if err != nil {
+				return nil, err
+			}
+
+			req, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+			c.stats.retries.Add(1)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			return cached.Body, nil
+		}
+
+		if resp.StatusCode > 299 {
+			return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+		}
+
+		c.cacheResponse(req, resp, bodyBytes)
+		return bodyBytes, nil
+	}
+}
+
+// StatusError is returned for a response with an error status, Body is
+// what Jira said about it
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to call API %d: %s", e.StatusCode, e.Body)
+}
+
+// roundTrip sends the request once and reads the whole response, the hooks
+// and the stats see every round trip, send counts the ones that failed
+func (c *Client) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	if c.OnRequest != nil {
+		c.OnRequest(req.Method, req.URL.Path)
+	}
+
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	var bodyBytes []byte
+	if err == nil {
+		bodyBytes, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	dur := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.stats.record(dur)
+	if c.OnResponse != nil {
+		c.OnResponse(req.Method, req.URL.Path, status, dur, err)
+	}
+
+	return resp, bodyBytes, err
+}
+
+// retryAfter reports whether the response asks to come back later and how
+// much later, only a Retry-After in seconds is understood
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0, false
+	}
+
+	return time.Duration(s) * time.Second, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rewind returns a copy of the request with a fresh body to send again
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+
+	return next, nil
+}
+
+type CreateIssueInput struct {
+	Project     string
+	Summary     string
+	Description string
+	Labels      []string
+	// Components are the names of the project's components to set
+	Components []string
+	// Component is added to Components.
+	//
+	// Deprecated: use Components.
+	Component string
+	Assignee  string
+	Type      string
+	Parent    string
+	// Fields sets any other field by its ID, e.g. a custom field like the
+	// Epic Name on Server, the values are sent as they are
+	Fields map[string]any
+}
+
+// CreateIssue tries to create the issue in the target project
+// if the creation was successful it returns the issue ID
+func (c *Client) CreateIssue(ctx context.Context, input CreateIssueInput) (jira.Issue, error) {
+	i := jira.Issue{
+		Fields: &jira.IssueFields{
+			Project:     jira.Project{Key: input.Project},
+			Summary:     input.Summary,
+			Description: input.Description,
+			Type:        jira.IssueType{Name: input.Type},
+			Labels:      input.Labels,
+		},
+	}
+
+	if input.Parent != "" {
+		i.Fields.Parent = &jira.Parent{Key: input.Parent}
+	}
+
+	components := input.Components
+	if input.Component != "" && !slices.Contains(components, input.Component) {
+		components = append(slices.Clone(components), input.Component)
+	}
+	for _, name := range components {
+		i.Fields.Components = append(i.Fields.Components, &jira.Component{Name: name})
+	}
+
+	if len(input.Fields) != 0 {
+		i.Fields.Unknowns = input.Fields
+	}
+
+	bodyBytes, err := json.Marshal(i)
+	if err != nil {
+		return jira.Issue{}, fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	b, err := c.callAPI(ctx, http.MethodPost, "issue", nil, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return jira.Issue{}, fmt.Errorf("failed to create issue: %w", err)
+	}
+
+	var j jira.Issue
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return jira.Issue{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return j, nil
+}
+
+// GetIssueOption changes what GetIssue and SearchPages ask Jira for
+type GetIssueOption func(params url.Values)
+
+// WithFields only fetches the given fields, e.g. "summary" or "comment",
+// instead of all of them which can be a lot on busy issues.
+func WithFields(fields ...string) GetIssueOption {
+	return func(params url.Values) {
+		addListParam(params, "fields", fields)
+	}
+}
+
+// WithExpand fetches things Jira leaves out by default, e.g. "changelog"
+// or "renderedFields".
+func WithExpand(expand ...string) GetIssueOption {
+	return func(params url.Values) {
+		addListParam(params, "expand", expand)
+	}
+}
+
+// WithRenderedFields also fetches the description and comments as the HTML
+// Jira renders their wiki markup to, in the issue's RenderedFields. Only
+// the fields that are fetched are rendered.
+func WithRenderedFields() GetIssueOption {
+	return WithExpand("renderedFields")
+}
+
+func addListParam(params url.Values, key string, values []string) {
+	if existing := params.Get(key); existing != "" {
+		values = append([]string{existing}, values...)
+	}
+	params.Set(key, strings.Join(values, ","))
+}
+
+// GetIssue finds an issue based on its key, without options all fields
+// and nothing expanded are returned.
+func (c *Client) GetIssue(ctx context.Context, key string, opts ...GetIssueOption) (jira.Issue, error) {
+	params := url.Values{}
+	for _, o := range opts {
+		o(params)
+	}
+
+	b, err := c.callAPI(ctx, http.MethodGet, "issue/"+key, params, nil)
+	if err != nil {
+		return jira.Issue{}, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	var j jira.Issue
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return jira.Issue{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return j, nil
+}
+
+// Search returns all issues matching the query, fetching every page of
+// results before returning. Use SearchPages to work on the pages as they
+// come in.
+func (c *Client) Search(ctx context.Context, jql string, opts ...GetIssueOption) ([]jira.Issue, error) {
+	var issues []jira.Issue
+	err := c.SearchPages(ctx, jql, func(page []jira.Issue) error {
+		issues = append(issues, page...)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// searchPageSize is asked for on every page, Jira Server defaults to 50
+// and Cloud doesn't return more than 100
+const searchPageSize = 100
+
+// SearchPages calls fn with every page of issues matching the query in
+// order, as soon as the page arrives. An error returned by fn stops the
+// search and is returned as is. opts like WithFields apply to every issue,
+// without them Jira returns all navigable fields which makes large
+// searches slow.
+func (c *Client) SearchPages(ctx context.Context, jql string, fn func(page []jira.Issue) error, opts ...GetIssueOption) error {
+	if jql == "" {
+		return errors.New("cannot search with empty search query")
+	}
+
+	startAt := 0
+	for {
+		// fn may have taken a while, don't start on the next page if the
+		// search was cancelled in the meantime
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		params := url.Values{}
+		for _, o := range opts {
+			o(params)
+		}
+		params.Set("jql", jql)
+		params.Set("startAt", strconv.Itoa(startAt))
+		params.Set("maxResults", strconv.Itoa(searchPageSize))
+
+		b, err := c.callAPI(ctx, http.MethodGet, "search", params, nil)
+		if err != nil {
+			return err
+		}
+
+		searchResp := struct {
+			StartAt    int          `json:"startAt"`
+			MaxResults int          `json:"maxResults"`
+			Total      int          `json:"total"`
+			Issues     []jira.Issue `json:"issues"`
+		}{}
+		err = json.Unmarshal(b, &searchResp)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		if len(searchResp.Issues) == 0 {
+			return nil
+		}
+
+		err = fn(searchResp.Issues)
+		if err != nil {
+			return err
+		}
+
+		startAt = searchResp.StartAt + len(searchResp.Issues)
+		if startAt >= searchResp.Total {
+			return nil
+		}
+	}
+}
+
+// Count returns how many issues match the query without fetching any of
+// them, Jira only reports the total when asked for zero results.
+func (c *Client) Count(ctx context.Context, jql string) (int, error) {
+	if jql == "" {
+		return 0, errors.New("cannot search with empty search query")
+	}
+
+	params := url.Values{}
+	params.Set("jql", jql)
+	params.Set("maxResults", "0")
+
+	b, err := c.callAPI(ctx, http.MethodGet, "search", params, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var searchResp struct {
+		Total int `json:"total"`
+	}
+	err = json.Unmarshal(b, &searchResp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return searchResp.Total, nil
+}
+
+// IssueTransition is a transition together with the fields that can be
+// set while doing it, they are keyed by field ID.
+type IssueTransition struct {
+	ID     string                     `json:"id"`
+	Name   string                     `json:"name"`
+	To     jira.Status                `json:"to"`
+	Fields map[string]TransitionField `json:"fields"`
+}
+
+type TransitionField struct {
+	Name            string      `json:"name"`
+	Required        bool        `json:"required"`
+	HasDefaultValue bool        `json:"hasDefaultValue"`
+	Schema          FieldSchema `json:"schema"`
+}
+
+type FieldSchema struct {
+	Type   string `json:"type"`
+	Items  string `json:"items,omitempty"`
+	System string `json:"system,omitempty"`
+	Custom string `json:"custom,omitempty"`
+}
+
+func (c *Client) ListIssueTransitions(ctx context.Context, key string) ([]IssueTransition, error) {
+	params := url.Values{}
+	params.Set("expand", "transitions.fields")
+
+	b, err := c.callAPI(ctx, http.MethodGet, "issue/"+key+"/transitions", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transitions: %w", err)
+	}
+
+	var resp struct {
+		Transitions []IssueTransition `json:"transitions"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarhal response: %w", err)
+	}
+
+	return resp.Transitions, nil
+}
+
+type TransitionRequest struct {
+	Transition Transition `json:"transition"`
+}
+
+type Transition struct {
+	ID string `json:"id"`
+}
+
+type TransitionInput struct {
+	// Status is the name of the transition to do
+	Status string
+	// StatusCategory picks the transition by the category of the status it
+	// leads to instead, e.g. "done", and is only used if Status is empty
+	StatusCategory string
+	// Fields maps field IDs or names to the values to set during the
+	// transition, the values are shaped according to the field's schema
+	Fields map[string]string
+	// Comment is added as part of the transition, so it is only recorded
+	// if the status actually changes
+	Comment string
+	// Current is the status the issue is in, callers that already fetched
+	// the issue pass it to save Transition looking it up again
+	Current *jira.Status `json:"-"`
+}
+
+func (c *Client) TransitionIssue(ctx context.Context, key string, status string) error {
+	return c.Transition(ctx, key, TransitionInput{Status: status})
+}
+
+// Transition does the transition described by input, failing before
+// calling the API if fields that the transition requires are missing.
+func (c *Client) Transition(ctx context.Context, key string, input TransitionInput) error {
+	transitions, err := c.ListIssueTransitions(ctx, key)
+	if err != nil {
+		return fmt.Errorf("could not list transitions: %w", err)
+	}
+
+	// an unknown status only makes the checks and errors less precise
+	current := input.Current
+	if current == nil {
+		issue, err := c.GetIssue(ctx, key, WithFields("status"))
+		if err == nil && issue.Fields != nil {
+			current = issue.Fields.Status
+		}
+	}
+
+	transition, err := ResolveTransition(key, input, transitions, current)
+	if err != nil {
+		return err
+	}
+
+	status := transition.To.Name
+	if status == "" {
+		status = transition.Name
+	}
+
+	payload, err := buildTransitionPayload(transition, input.Fields)
+	if err != nil {
+		return fmt.Errorf("cannot transition %s: %w", key, err)
+	}
+
+	if input.Comment != "" {
+		payload["update"] = map[string]any{
+			"comment": []any{
+				map[string]any{"add": map[string]any{"body": commentBody(c.APIVersion, input.Comment)}},
+			},
+		}
+	}
+
+	body, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transition request: %w", err)
+	}
+
+	_, err = c.callAPI(ctx, http.MethodPost, "issue/"+key+"/transitions", nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to transition issue to %s: %w", status, err)
+	}
+
+	return nil
+}
+
+// ErrAlreadyInStatus is returned by Transition when there is no transition
+// to the status because the issue already is in it
+var ErrAlreadyInStatus = errors.New("nothing to do")
+
+// ResolveTransition picks the transition named like input.Status, the
+// one leading to a status named like it or, without a Status, the first
+// one into a status of input.StatusCategory, ignoring case. When it comes
+// down to the status an issue that already is in it gets
+// ErrAlreadyInStatus. Otherwise the error lists the transitions together
+// with the current status since they depend on it, current may be nil if
+// it isn't known.
+func ResolveTransition(key string, input TransitionInput, transitions []IssueTransition, current *jira.Status) (IssueTransition, error) {
+	if input.Status != "" {
+		for _, t := range transitions {
+			if strings.EqualFold(t.Name, input.Status) {
+				return t, nil
+			}
+		}
+	}
+
+	if current != nil {
+		already := strings.EqualFold(current.Name, input.Status)
+		if input.Status == "" {
+			already = current.StatusCategory.Key == input.StatusCategory
+		}
+		if already {
+			return IssueTransition{}, fmt.Errorf("%s already is in %s, %w", key, current.Name, ErrAlreadyInStatus)
+		}
+	}
+
+	for _, t := range transitions {
+		if input.Status != "" && strings.EqualFold(t.To.Name, input.Status) {
+			return t, nil
+		}
+		if input.Status == "" && t.To.StatusCategory.Key == input.StatusCategory {
+			return t, nil
+		}
+	}
+
+	target := input.Status
+	if target == "" {
+		target = "a " + input.StatusCategory + " status"
+	}
+
+	valid := make([]string, 0, len(transitions))
+	for _, t := range transitions {
+		if t.To.Name == "" || strings.EqualFold(t.Name, t.To.Name) {
+			valid = append(valid, t.Name)
+			continue
+		}
+		valid = append(valid, fmt.Sprintf("%s (to %s)", t.Name, t.To.Name))
+	}
+
+	from := ""
+	if current != nil && current.Name != "" {
+		from = " from " + current.Name
+	}
+
+	return IssueTransition{}, fmt.Errorf(
+		"could not find %s as a valid transition for %s, valid transitions%s are: %s (they depend on the status the issue is in)",
+		target,
+		key,
+		from,
+		strings.Join(valid, ","),
+	)
+}
+
+func buildTransitionPayload(t IssueTransition, values map[string]string) (map[string]any, error) {
+	fields := make(map[string]any)
+	for k, v := range values {
+		id, f, ok := lookupTransitionField(t, k)
+		if !ok {
+			settable := make([]string, 0, len(t.Fields))
+			for id, f := range t.Fields {
+				settable = append(settable, fmt.Sprintf("%s (%s)", f.Name, id))
+			}
+			sort.Strings(settable)
+
+			return nil, fmt.Errorf(
+				"field %q cannot be set during the %q transition, settable fields are: %s",
+				k,
+				t.Name,
+				strings.Join(settable, ","),
+			)
+		}
+
+		value, err := FieldValue(f.Schema, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %q: %w", k, err)
+		}
+		fields[id] = value
+	}
+
+	missing := make([]string, 0)
+	for id, f := range t.Fields {
+		if _, set := fields[id]; f.Required && !f.HasDefaultValue && !set {
+			missing = append(missing, fmt.Sprintf("%s (%s)", f.Name, id))
+		}
+	}
+
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("the %q transition requires these fields to be set: %s", t.Name, strings.Join(missing, ","))
+	}
+
+	payload := map[string]any{
+		"transition": map[string]string{"id": t.ID},
+	}
+	if len(fields) != 0 {
+		payload["fields"] = fields
+	}
+
+	return payload, nil
+}
+
+// lookupTransitionField finds a field by its ID or case insensitive name
+func lookupTransitionField(t IssueTransition, key string) (string, TransitionField, bool) {
+	if f, ok := t.Fields[key]; ok {
+		return key, f, true
+	}
+
+	for id, f := range t.Fields {
+		if strings.EqualFold(f.Name, key) || strings.EqualFold(id, key) {
+			return id, f, true
+		}
+	}
+
+	return "", TransitionField{}, false
+}
+
+// FieldValue shapes the value the way Jira expects it for the field's
+// schema when transitioning or creating an issue, a value that is a JSON
+// object or array is passed as is.
+func FieldValue(schema FieldSchema, value string) (any, error) {
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value), nil
+		}
+	}
+
+	switch schema.Type {
+	case "string", "date", "datetime":
+		return value, nil
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return n, nil
+	case "option":
+		return map[string]string{"value": value}, nil
+	case "array":
+		items := strings.Split(value, ",")
+		switch schema.Items {
+		case "string":
+			return items, nil
+		case "option":
+			result := make([]map[string]string, 0, len(items))
+			for _, i := range items {
+				result = append(result, map[string]string{"value": i})
+			}
+			return result, nil
+		default:
+			result := make([]map[string]string, 0, len(items))
+			for _, i := range items {
+				result = append(result, map[string]string{"name": i})
+			}
+			return result, nil
+		}
+	default:
+		// resolution, priority, user, version, component and friends
+		// are all referenced by their name
+		return map[string]string{"name": value}, nil
+	}
+}
+
+// CreateField is a field of the create screen of an issue type
+type CreateField struct {
+	Name            string      `json:"name"`
+	Required        bool        `json:"required"`
+	HasDefaultValue bool        `json:"hasDefaultValue"`
+	Schema          FieldSchema `json:"schema"`
+	// AllowedValues are the choices of select fields, priorities,
+	// components and the like, it's empty for free text
+	AllowedValues []AllowedValue `json:"allowedValues,omitempty"`
+}
+
+// AllowedValue is a choice of a field, options of custom fields have a
+// Value and everything else a Name
+type AllowedValue struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Label is what the choice is called in Jira
+func (v AllowedValue) Label() string {
+	if v.Value != "" {
+		return v.Value
+	}
+
+	return v.Name
+}
+
+// UnknownIssueTypeError is returned by GetCreateMeta when the issue type
+// scheme of the project doesn't have the type
+type UnknownIssueTypeError struct {
+	Project string
+	Type    string
+	// Types are the ones the project has, in the order Jira lists them
+	Types []string
+}
+
+func (e *UnknownIssueTypeError) Error() string {
+	return fmt.Sprintf("project %s has no issue type %q, it has %s", e.Project, e.Type, strings.Join(e.Types, ", "))
+}
+
+// createMetaProject is a project of the createmeta response
+type createMetaProject struct {
+	IssueTypes []struct {
+		Name   string                 `json:"name"`
+		Fields map[string]CreateField `json:"fields"`
+	} `json:"issuetypes"`
+}
+
+// GetCreateMeta returns the fields that can be set when creating an issue
+// of the type in the project, keyed by field ID. A type the project
+// doesn't have is an *UnknownIssueTypeError with the ones it has.
+func (c *Client) GetCreateMeta(ctx context.Context, project, issueType string) (map[string]CreateField, error) {
+	params := url.Values{}
+	params.Set("projectKeys", project)
+	params.Set("issuetypeNames", issueType)
+	params.Set("expand", "projects.issuetypes.fields")
+
+	p, err := c.createMeta(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the fields to create a %s in %s: %w", issueType, project, err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("project %s doesn't exist or you can't create issues in it", project)
+	}
+	for _, t := range p.IssueTypes {
+		if strings.EqualFold(t.Name, issueType) {
+			return t.Fields, nil
+		}
+	}
+
+	// the type is named with the others, without their fields that can
+	// be a lot
+	params.Del("issuetypeNames")
+	params.Del("expand")
+	p, err = c.createMeta(ctx, params)
+	if err != nil || p == nil {
+		return nil, fmt.Errorf("project %s has no issue type %q, \"jiwa issue-type %s\" lists them", project, issueType, project)
+	}
+
+	unknown := &UnknownIssueTypeError{Project: project, Type: issueType}
+	for _, t := range p.IssueTypes {
+		unknown.Types = append(unknown.Types, t.Name)
+	}
+
+	return nil, unknown
+}
+
+// createMeta returns the first project of the createmeta response, nil if
+// it has none
+func (c *Client) createMeta(ctx context.Context, params url.Values) (*createMetaProject, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "issue/createmeta", params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Projects []createMetaProject `json:"projects"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the create metadata: %w", err)
+	}
+
+	if len(resp.Projects) == 0 {
+		return nil, nil
+	}
+
+	return &resp.Projects[0], nil
+}
+
+func (c *Client) GetProject(ctx context.Context, key string) (jira.Project, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "project/"+key, nil, nil)
+	if err != nil {
+		return jira.Project{}, fmt.Errorf("failed to get project %s: %w", key, err)
+	}
+
+	var result jira.Project
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return jira.Project{}, fmt.Errorf("failed to unmarshal project response: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListComponents returns the components of the project
+func (c *Client) ListComponents(ctx context.Context, project string) ([]jira.ProjectComponent, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "project/"+project+"/components", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the components of %s: %w", project, err)
+	}
+
+	var result []jira.ProjectComponent
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal components: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListStatuses returns the statuses of the workflows of the project's
+// issue types, each of them once
+func (c *Client) ListStatuses(ctx context.Context, project string) ([]jira.Status, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "project/"+project+"/statuses", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the statuses of %s: %w", project, err)
+	}
+
+	var issueTypes []struct {
+		Statuses []jira.Status `json:"statuses"`
+	}
+	err = json.Unmarshal(b, &issueTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal statuses: %w", err)
+	}
+
+	result := make([]jira.Status, 0)
+	seen := make(map[string]bool)
+	for _, t := range issueTypes {
+		for _, s := range t.Statuses {
+			if !seen[s.Name] {
+				seen[s.Name] = true
+				result = append(result, s)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// SecurityLevel restricts who can see an issue
+type SecurityLevel struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ListSecurityLevels returns the security levels of the project the user
+// can set on its issues, none if the project has no security scheme
+func (c *Client) ListSecurityLevels(ctx context.Context, project string) ([]SecurityLevel, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "project/"+project+"/securitylevel", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the security levels of %s: %w", project, err)
+	}
+
+	var resp struct {
+		Levels []SecurityLevel `json:"levels"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal security levels: %w", err)
+	}
+
+	return resp.Levels, nil
+}
+
+// ListProjectRoles returns the names of the roles of the project, sorted
+func (c *Client) ListProjectRoles(ctx context.Context, project string) ([]string, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "project/"+project+"/role", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the roles of %s: %w", project, err)
+	}
+
+	// the roles are keyed by name, the values are links to them
+	var resp map[string]string
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project roles: %w", err)
+	}
+
+	roles := make([]string, 0, len(resp))
+	for name := range resp {
+		roles = append(roles, name)
+	}
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+// SuggestLabels returns the labels in use on the instance that start with
+// the prefix, as many as Jira's JQL autocompletion suggests
+func (c *Client) SuggestLabels(ctx context.Context, prefix string) ([]string, error) {
+	params := url.Values{"fieldName": {"labels"}, "fieldValue": {prefix}}
+	b, err := c.callAPI(ctx, http.MethodGet, "jql/autocompletedata/suggestions", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest labels: %w", err)
+	}
+
+	// displayName is the value with the prefix marked up in HTML
+	var resp struct {
+		Results []struct {
+			Value string `json:"value"`
+		} `json:"results"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal label suggestions: %w", err)
+	}
+
+	labels := make([]string, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		labels = append(labels, r.Value)
+	}
+
+	return labels, nil
+}
+
+func (c *Client) CommentOnIssue(ctx context.Context, issueID string, comment string) error {
+	return c.comment(ctx, issueID, comment, nil)
+}
+
+// CommentOnIssueVisibleTo adds a comment only the members of a project role
+// or a group can see, the type of the visibility is "role" or "group"
+func (c *Client) CommentOnIssueVisibleTo(ctx context.Context, issueID string, comment string, visibility jira.CommentVisibility) error {
+	return c.comment(ctx, issueID, comment, &visibility)
+}
+
+func (c *Client) comment(ctx context.Context, issueID string, comment string, visibility *jira.CommentVisibility) error {
+	bodyStruct := struct {
+		Body       string                  `json:"body"`
+		Visibility *jira.CommentVisibility `json:"visibility,omitempty"`
+	}{
+		Body:       comment,
+		Visibility: visibility,
+	}
+	body, err := json.Marshal(&bodyStruct)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment body: %w", err)
+	}
+
+	_, err = c.callAPI(ctx, http.MethodPost, "issue/"+issueID+"/comment", nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed comment on issue %s: %w", issueID, err)
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteIssue(ctx context.Context, issueID string) error {
+	_, err := c.callAPI(ctx, http.MethodDelete, "issue/"+issueID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete issue %s: %w", issueID, err)
+	}
+
+	return nil
+}
+
+// ErrArchivingUnavailable is returned by ArchiveIssue and RestoreIssue
+// when the instance has no archive endpoints, like Jira Cloud and Server
+// without a Data Center license
+var ErrArchivingUnavailable = errors.New("archiving not available on this Jira")
+
+// ArchiveIssue archives the issue, Jira leaves archived issues out of
+// searches and makes them read-only until they are restored
+func (c *Client) ArchiveIssue(ctx context.Context, key string) error {
+	_, err := c.callAPI(ctx, http.MethodPut, "issue/"+key+"/archive", nil, nil)
+	if err != nil {
+		return archiveError("archive", key, err)
+	}
+
+	return nil
+}
+
+// RestoreIssue brings back an archived issue
+func (c *Client) RestoreIssue(ctx context.Context, key string) error {
+	_, err := c.callAPI(ctx, http.MethodPut, "issue/"+key+"/restore", nil, nil)
+	if err != nil {
+		return archiveError("restore", key, err)
+	}
+
+	return nil
+}
+
+// archiveError tells a missing endpoint from a missing issue, both are a
+// 404 but only the latter says the issue doesn't exist
+func archiveError(action, key string, err error) error {
+	var se *StatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound && !strings.Contains(strings.ToLower(se.Body), "does not exist") {
+		return ErrArchivingUnavailable
+	}
+
+	return fmt.Errorf("failed to %s issue %s: %w", action, key, err)
+}
+
+// ErrMovingUnavailable is returned by MoveIssue when the instance can't
+// move issues between projects through its API, Server and Data Center
+// only do it in the web UI
+var ErrMovingUnavailable = errors.New("moving issues between projects not available through the API of this Jira")
+
+// moveTaskPoll is how long MoveIssue waits before asking again whether
+// the move is done, it gives up after moveTaskPolls times
+var moveTaskPoll = time.Second
+
+const moveTaskPolls = 300
+
+type moveTask struct {
+	Status string `json:"status"`
+	// FailedAccessibleIssues are the reasons keyed by the ID of the issue
+	FailedAccessibleIssues          map[string][]string `json:"failedAccessibleIssues"`
+	InvalidOrInaccessibleIssueCount int                 `json:"invalidOrInaccessibleIssueCount"`
+}
+
+// MoveIssue moves the issue into the project as the issue type with the
+// ID, using the bulk move of Jira Cloud. Fields and statuses the project
+// doesn't have are left to Jira's defaults. The move runs in the
+// background, MoveIssue waits for it and returns the key the issue got.
+func (c *Client) MoveIssue(ctx context.Context, key, project, issueTypeID string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"sendBulkNotification": true,
+		"targetToSourcesMapping": map[string]any{
+			project + "," + issueTypeID: map[string]any{
+				"inferClassificationDefaults": true,
+				"inferFieldDefaults":          true,
+				"inferStatusDefaults":         true,
+				"inferSubtaskTypeDefault":     true,
+				"issueIdsOrKeys":              []string{key},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal move: %w", err)
+	}
+
+	resp, err := c.callAPI(ctx, http.MethodPost, "bulk/issues/move", nil, bytes.NewReader(body))
+	var se *StatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return "", ErrMovingUnavailable
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to move issue %s to %s: %w", key, project, err)
+	}
+
+	var submitted struct {
+		TaskID string `json:"taskId"`
+	}
+	err = json.Unmarshal(resp, &submitted)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal move: %w", err)
+	}
+
+	for polls := 0; ; polls++ {
+		resp, err = c.callAPI(ctx, http.MethodGet, "bulk/queue/"+submitted.TaskID, nil, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to check on the move of %s: %w", key, err)
+		}
+
+		var task moveTask
+		err = json.Unmarshal(resp, &task)
+		if err != nil {
+			return "", fmt.Errorf("failed to unmarshal move task: %w", err)
+		}
+
+		switch task.Status {
+		case "COMPLETE":
+			for _, reasons := range task.FailedAccessibleIssues {
+				return "", fmt.Errorf("failed to move issue %s to %s: %s", key, project, strings.Join(reasons, ", "))
+			}
+			if task.InvalidOrInaccessibleIssueCount > 0 {
+				return "", fmt.Errorf("failed to move issue %s to %s: Jira can't see it or it can't be moved", key, project)
+			}
+		case "FAILED", "CANCELLED", "CANCEL_REQUESTED", "DEAD":
+			return "", fmt.Errorf("failed to move issue %s to %s: the move ended as %s", key, project, task.Status)
+		default:
+			if polls == moveTaskPolls {
+				return "", fmt.Errorf("gave up waiting for the move of %s to %s after %s, it is still %s and may finish later", key, project, moveTaskPoll*moveTaskPolls, task.Status)
+			}
+			err = sleep(ctx, moveTaskPoll)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		break
+	}
+
+	// Jira answers for the old key with the moved issue
+	moved, err := c.GetIssue(ctx, key, WithFields("project"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the moved issue %s: %w", key, err)
+	}
+
+	return moved.Key, nil
+}
+
+// AddAttachment uploads content to the issue as a file called name
+func (c *Client) AddAttachment(ctx context.Context, key, name string, content []byte) (jira.Attachment, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return jira.Attachment{}, fmt.Errorf("failed to build attachment body: %w", err)
+	}
+	_, err = part.Write(content)
+	if err != nil {
+		return jira.Attachment{}, fmt.Errorf("failed to build attachment body: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return jira.Attachment{}, fmt.Errorf("failed to build attachment body: %w", err)
+	}
+
+	reqURL := fmt.Sprintf("%s/rest/api/%s/issue/%s/attachments", c.BaseURL, c.APIVersion, key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, &body)
+	if err != nil {
+		return jira.Attachment{}, err
+	}
+	req.Header.Set("content-type", w.FormDataContentType())
+	// without it Jira refuses the upload as a possible XSRF attack
+	req.Header.Set("X-Atlassian-Token", "no-check")
+
+	b, err := c.send(req)
+	if err != nil {
+		return jira.Attachment{}, fmt.Errorf("failed to attach %s to %s: %w", name, key, err)
+	}
+
+	var result []jira.Attachment
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return jira.Attachment{}, fmt.Errorf("failed to unmarshal attachment response: %w", err)
+	}
+	if len(result) == 0 {
+		return jira.Attachment{}, fmt.Errorf("failed to attach %s to %s: Jira didn't return the attachment", name, key)
+	}
+
+	return result[0], nil
+}
+
+// DownloadAttachment returns the content of the attachment. Its Content URL
+// has to point at the instance the client talks to, the credentials are
+// not sent anywhere else.
+func (c *Client) DownloadAttachment(ctx context.Context, attachment jira.Attachment) ([]byte, error) {
+	content, err := url.Parse(attachment.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the URL of attachment %s: %w", attachment.Filename, err)
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the base URL: %w", err)
+	}
+	if content.Scheme != base.Scheme || content.Host != base.Host {
+		return nil, fmt.Errorf("refusing to download attachment %s from %s, it is not on %s", attachment.Filename, content.Host, base.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, content.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download attachment %s: %w", attachment.Filename, err)
+	}
+
+	return b, nil
+}
+
+func (c *Client) ListIssueLinkTypes(ctx context.Context) ([]jira.IssueLinkType, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "issueLinkType", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue link types: %w", err)
+	}
+
+	var resp struct {
+		IssueLinkTypes []jira.IssueLinkType `json:"issueLinkTypes"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal issue link types: %w", err)
+	}
+
+	return resp.IssueLinkTypes, nil
+}
+
+// LinkIssues links two issues with the link type named linkType. Jira reads
+// the result as "<inwardKey> <outward description> <outwardKey>", so for the
+// "Blocks" type the inward issue is the one doing the blocking.
+func (c *Client) LinkIssues(ctx context.Context, linkType, inwardKey, outwardKey string) error {
+	link := jira.IssueLink{
+		Type:         jira.IssueLinkType{Name: linkType},
+		InwardIssue:  &jira.Issue{Key: inwardKey},
+		OutwardIssue: &jira.Issue{Key: outwardKey},
+	}
+	body, err := json.Marshal(&link)
+	if err != nil {
+		return fmt.Errorf("failed to marshal issue link: %w", err)
+	}
+
+	_, err = c.callAPI(ctx, http.MethodPost, "issueLink", nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to link %s to %s: %w", inwardKey, outwardKey, err)
+	}
+
+	return nil
+}
+
+// ListIssueLinks returns the links of the issue, every link has either its
+// InwardIssue or its OutwardIssue set to the other issue. The ID of a link
+// is what DeleteIssueLink takes.
+func (c *Client) ListIssueLinks(ctx context.Context, key string) ([]*jira.IssueLink, error) {
+	issue, err := c.GetIssue(ctx, key, WithFields("issuelinks"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the links of %s: %w", key, err)
+	}
+
+	if issue.Fields == nil {
+		return nil, nil
+	}
+
+	return issue.Fields.IssueLinks, nil
+}
+
+// DeleteIssueLink removes the link with the ID from both of its issues
+func (c *Client) DeleteIssueLink(ctx context.Context, id string) error {
+	_, err := c.callAPI(ctx, http.MethodDelete, "issueLink/"+id, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove link %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// The permissions that are checked before issues are changed, as Jira
+// calls them
+const (
+	PermissionEditIssues       = "EDIT_ISSUES"
+	PermissionAssignIssues     = "ASSIGN_ISSUES"
+	PermissionAddComments      = "ADD_COMMENTS"
+	PermissionTransitionIssues = "TRANSITION_ISSUES"
+	PermissionModifyReporter   = "MODIFY_REPORTER"
+)
+
+// Permission tells whether the user has the permission, Name is how Jira
+// shows it, e.g. "Assign Issues"
+type Permission struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Have bool   `json:"havePermission"`
+}
+
+// MyPermissions returns which of the permissions jiwa checks the user has
+// in the project, or on the issue if it is set, keyed by permission key
+func (c *Client) MyPermissions(ctx context.Context, project, issue string) (map[string]Permission, error) {
+	params := url.Values{}
+	if project != "" {
+		params.Set("projectKey", project)
+	}
+	if issue != "" {
+		params.Set("issueKey", issue)
+	}
+	params.Set("permissions", strings.Join([]string{PermissionEditIssues, PermissionAssignIssues, PermissionAddComments, PermissionTransitionIssues, PermissionModifyReporter}, ","))
+
+	b, err := c.callAPI(ctx, http.MethodGet, "mypermissions", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get your permissions: %w", err)
+	}
+
+	var resp struct {
+		Permissions map[string]Permission `json:"permissions"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
+	}
+
+	return resp.Permissions, nil
+}
+
+// SearchUsers finds users whose name, display name or email starts with
+// the query, using the user picker that is available on Server and Cloud.
+func (c *Client) SearchUsers(ctx context.Context, query string) ([]jira.User, error) {
+	params := url.Values{}
+	params.Set("query", query)
+
+	b, err := c.callAPI(ctx, http.MethodGet, "user/picker", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	var resp struct {
+		Users []jira.User `json:"users"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user search response: %w", err)
+	}
+
+	return resp.Users, nil
+}
+
+// AssignableUsersInput narrows the search to users that can be assigned to
+// the issue, or to issues in the project if no issue is given yet.
+type AssignableUsersInput struct {
+	IssueKey string
+	Project  string
+	Query    string
+}
+
+func (c *Client) SearchAssignableUsers(ctx context.Context, input AssignableUsersInput) ([]jira.User, error) {
+	if input.IssueKey == "" && input.Project == "" {
+		return nil, errors.New("need an issue or a project to search assignable users")
+	}
+
+	params := url.Values{}
+	if input.IssueKey != "" {
+		params.Set("issueKey", input.IssueKey)
+	} else {
+		params.Set("project", input.Project)
+	}
+	// Server filters by username and Cloud by query, each ignores the other
+	params.Set("username", input.Query)
+	params.Set("query", input.Query)
+
+	b, err := c.callAPI(ctx, http.MethodGet, "user/assignable/search", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search assignable users: %w", err)
+	}
+
+	var users []jira.User
+	err = json.Unmarshal(b, &users)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal assignable users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (c *Client) ListPriorities(ctx context.Context) ([]jira.Priority, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "priority", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list priorities: %w", err)
+	}
+
+	var result []jira.Priority
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal priorities: %w", err)
+	}
+
+	return result, nil
+}
+
+// ServerInfo describes the Jira instance the client talks to
+type ServerInfo struct {
+	BaseURL string `json:"baseUrl"`
+	Version string `json:"version"`
+	// DeploymentType is "Cloud", "Server" or "DataCenter"
+	DeploymentType string `json:"deploymentType"`
+}
+
+// IsCloud reports whether the instance is hosted by Atlassian, Server and
+// Data Center behave the same for everything jiwa does.
+func (s ServerInfo) IsCloud() bool {
+	return strings.EqualFold(s.DeploymentType, "Cloud")
+}
+
+func (c *Client) ServerInfo(ctx context.Context) (ServerInfo, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "serverInfo", nil, nil)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	var info ServerInfo
+	err = json.Unmarshal(b, &info)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("failed to unmarshal server info: %w", err)
+	}
+
+	return info, nil
+}
+
+// Account is the user the client is authenticated as
+type Account struct {
+	jira.User
+	// Groups and ApplicationRoles are only filled in if Jira lets the user
+	// see them
+	Groups           []string
+	ApplicationRoles []string
+	// Raw is the response as Jira sent it
+	Raw json.RawMessage
+}
+
+// Myself returns the authenticated user with their groups and application
+// roles, handy to figure out why Jira refuses something.
+func (c *Client) Myself(ctx context.Context) (Account, error) {
+	params := url.Values{}
+	params.Add("expand", "groups,applicationRoles")
+
+	b, err := c.callAPI(ctx, http.MethodGet, "myself", params, nil)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to get the current user: %w", err)
+	}
+
+	var resp struct {
+		jira.User
+		Groups struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+		} `json:"groups"`
+		ApplicationRoles struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+		} `json:"applicationRoles"`
+	}
+	err = json.Unmarshal(b, &resp)
+	if err != nil {
+		return Account{}, fmt.Errorf("failed to unmarshal the current user: %w", err)
+	}
+
+	account := Account{User: resp.User, Raw: b}
+	for _, g := range resp.Groups.Items {
+		account.Groups = append(account.Groups, g.Name)
+	}
+	for _, r := range resp.ApplicationRoles.Items {
+		account.ApplicationRoles = append(account.ApplicationRoles, r.Name)
+	}
+
+	return account, nil
+}
+
+// ListFields returns all system and custom fields, custom fields are only
+// known by their ID like "customfield_10011" so this is how to find them.
+func (c *Client) ListFields(ctx context.Context) ([]jira.Field, error) {
+	b, err := c.callAPI(ctx, http.MethodGet, "field", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fields: %w", err)
+	}
+
+	var fields []jira.Field
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fields: %w", err)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,1319 @@
+package jiwa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTransitionPayload(t *testing.T) {
+	done := IssueTransition{
+		ID:   "31",
+		Name: "Done",
+		Fields: map[string]TransitionField{
+			"resolution": {
+				Name:     "Resolution",
+				Required: true,
+				Schema:   FieldSchema{Type: "resolution", System: "resolution"},
+			},
+			"customfield_10010": {
+				Name:   "Story Points",
+				Schema: FieldSchema{Type: "number", Custom: "com.atlassian.jira.plugin.system.customfieldtypes:float"},
+			},
+			"labels": {
+				Name:            "Labels",
+				Required:        true,
+				HasDefaultValue: true,
+				Schema:          FieldSchema{Type: "array", Items: "string", System: "labels"},
+			},
+			"customfield_10020": {
+				Name:   "Root Cause",
+				Schema: FieldSchema{Type: "option"},
+			},
+		},
+	}
+
+	testData := []struct {
+		Name       string
+		InFields   map[string]string
+		OutPayload string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "Resolution",
+			InFields:   map[string]string{"resolution": "Won't Do"},
+			OutPayload: `{"fields":{"resolution":{"name":"Won't Do"}},"transition":{"id":"31"}}`,
+		},
+		{
+			Name: "FieldsByNameAndShapedBySchema",
+			InFields: map[string]string{
+				"Resolution":   "Done",
+				"story points": "3",
+				"Labels":       "a,b",
+				"Root Cause":   "Config",
+			},
+			OutPayload: `{"fields":{"customfield_10010":3,"customfield_10020":{"value":"Config"},"labels":["a","b"],"resolution":{"name":"Done"}},"transition":{"id":"31"}}`,
+		},
+		{
+			Name:       "RawJSONIsPassedThrough",
+			InFields:   map[string]string{"resolution": `{"id":"10000"}`},
+			OutPayload: `{"fields":{"resolution":{"id":"10000"}},"transition":{"id":"31"}}`,
+		},
+		{
+			Name:      "MissingRequiredField",
+			InFields:  nil,
+			OutErrMsg: `the "Done" transition requires these fields to be set: Resolution (resolution)`,
+		},
+		{
+			Name:      "UnknownField",
+			InFields:  map[string]string{"resolution": "Done", "duedate": "2024-01-01"},
+			OutErrMsg: `field "duedate" cannot be set during the "Done" transition, settable fields are: Labels (labels),Resolution (resolution),Root Cause (customfield_10020),Story Points (customfield_10010)`,
+		},
+		{
+			Name:      "InvalidNumber",
+			InFields:  map[string]string{"resolution": "Done", "Story Points": "many"},
+			OutErrMsg: `invalid value for field "Story Points": "many" is not a number`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			payload, err := buildTransitionPayload(done, td.InFields)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			b, err := json.Marshal(payload)
+			assert.NoError(t, err)
+			assert.JSONEq(t, td.OutPayload, string(b))
+		})
+	}
+
+	t.Run("NoFieldsOnTransition", func(t *testing.T) {
+		t.Parallel()
+		payload, err := buildTransitionPayload(IssueTransition{ID: "11", Name: "Start"}, nil)
+		assert.NoError(t, err)
+
+		b, err := json.Marshal(payload)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"transition":{"id":"11"}}`, string(b))
+	})
+}
+
+func TestNewClient(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InConfig      Config
+		OutBaseURL    string
+		OutAPIVersion string
+		OutErrMsg     string
+	}{
+		{
+			Name:          "Plain",
+			InConfig:      Config{BaseURL: "https://catouc.atlassian.net", Token: "t"},
+			OutBaseURL:    "https://catouc.atlassian.net",
+			OutAPIVersion: "2",
+		},
+		{
+			Name:          "TrailingSlashes",
+			InConfig:      Config{BaseURL: "https://catouc.atlassian.net//", Token: "t", APIVersion: "3"},
+			OutBaseURL:    "https://catouc.atlassian.net",
+			OutAPIVersion: "3",
+		},
+		{
+			Name:          "EndpointPrefix",
+			InConfig:      Config{BaseURL: "https://example.com/", EndpointPrefix: "/jira/", Username: "me", Password: "p"},
+			OutBaseURL:    "https://example.com/jira",
+			OutAPIVersion: "2",
+		},
+		{
+			Name:      "Empty",
+			InConfig:  Config{Token: "t"},
+			OutErrMsg: "base URL is empty",
+		},
+		{
+			Name:      "MissingScheme",
+			InConfig:  Config{BaseURL: "catouc.atlassian.net", Token: "t"},
+			OutErrMsg: `invalid base URL "catouc.atlassian.net": scheme needs to be http or https`,
+		},
+		{
+			Name:      "Query",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net?foo=bar", Token: "t"},
+			OutErrMsg: `invalid base URL "https://catouc.atlassian.net?foo=bar": must not contain a query or fragment`,
+		},
+		{
+			Name:      "MissingCredentials",
+			InConfig:  Config{BaseURL: "https://catouc.atlassian.net", Username: "me"},
+			OutErrMsg: "either username+password need to be set or token",
+		},
+		{
+			Name:      "PlainHTTP",
+			InConfig:  Config{BaseURL: "http://jira.example.com", Token: "t"},
+			OutErrMsg: `invalid base URL "http://jira.example.com": plain http sends the credentials in cleartext, use https`,
+		},
+		{
+			Name:          "PlainHTTPAllowed",
+			InConfig:      Config{BaseURL: "http://jira.example.com", Token: "t", InsecureAllowHTTP: true},
+			OutBaseURL:    "http://jira.example.com",
+			OutAPIVersion: "2",
+		},
+		{
+			Name:          "PlainHTTPOnLocalhost",
+			InConfig:      Config{BaseURL: "http://localhost:8080", Token: "t"},
+			OutBaseURL:    "http://localhost:8080",
+			OutAPIVersion: "2",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, err := NewClient(td.InConfig)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutBaseURL, c.BaseURL)
+			assert.Equal(t, td.OutAPIVersion, c.APIVersion)
+			assert.NotNil(t, c.HTTPClient)
+		})
+	}
+}
+
+func TestPlainHTTP(t *testing.T) {
+	testData := []struct {
+		Name string
+		In   string
+		Out  bool
+	}{
+		{Name: "HTTPS", In: "https://jira.example.com", Out: false},
+		{Name: "HTTP", In: "http://jira.example.com", Out: true},
+		{Name: "HTTPUpperCase", In: "HTTP://jira.example.com", Out: true},
+		{Name: "HTTPWithPort", In: "http://jira.example.com:8080/jira", Out: true},
+		{Name: "PrivateAddress", In: "http://10.0.0.5", Out: true},
+		{Name: "Localhost", In: "http://localhost:8080", Out: false},
+		{Name: "LocalhostUpperCase", In: "http://LOCALHOST", Out: false},
+		{Name: "LocalhostSubdomain", In: "http://jira.localhost", Out: false},
+		{Name: "LocalhostLookalike", In: "http://localhost.example.com", Out: true},
+		{Name: "Loopback", In: "http://127.0.0.1:8080", Out: false},
+		{Name: "LoopbackRange", In: "http://127.1.2.3", Out: false},
+		{Name: "LoopbackIPv6", In: "http://[::1]:8080", Out: false},
+		{Name: "Unparsable", In: "http://%zz", Out: false},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, td.Out, PlainHTTP(td.In))
+		})
+	}
+}
+
+func newTestClient(t *testing.T) (*Client, *jiratest.Server) {
+	t.Helper()
+
+	srv := jiratest.NewServer(t)
+	c, err := NewClient(Config{
+		BaseURL:    srv.URL,
+		Username:   srv.Username,
+		Password:   srv.Password,
+		HTTPClient: srv.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, srv
+}
+
+func TestClient_GetIssue(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InKey      string
+		InFailure  jiratest.Failure
+		OutSummary string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "Found",
+			InKey:      "JIWA-1",
+			OutSummary: "Fix the thing",
+		},
+		{
+			Name:      "NotFound",
+			InKey:     "JIWA-2",
+			OutErrMsg: "failed to call API 404",
+		},
+		{
+			Name:      "Unauthorized",
+			InKey:     "JIWA-1",
+			InFailure: jiratest.FailUnauthorized,
+			OutErrMsg: "failed to call API 401",
+		},
+		{
+			Name:      "RateLimited",
+			InKey:     "JIWA-1",
+			InFailure: jiratest.FailRateLimited,
+			OutErrMsg: "failed to call API 429",
+		},
+		{
+			Name:      "MalformedJSON",
+			InKey:     "JIWA-1",
+			InFailure: jiratest.FailMalformedJSON,
+			OutErrMsg: "failed to unmarshal response",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Fix the thing"}})
+			srv.Fail(td.InFailure, 1)
+
+			issue, err := c.GetIssue(context.Background(), td.InKey)
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutSummary, issue.Fields.Summary)
+		})
+	}
+}
+
+func TestClient_CreateIssue(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InInput       CreateIssueInput
+		OutKey        string
+		OutComponents []string
+		OutErrMsg     string
+	}{
+		{
+			Name:    "Task",
+			InInput: CreateIssueInput{Project: "JIWA", Summary: "New thing", Description: "Details", Labels: []string{"ops"}, Type: "Task"},
+			OutKey:  "JIWA-1",
+		},
+		{
+			Name:          "Components",
+			InInput:       CreateIssueInput{Project: "JIWA", Summary: "New thing", Type: "Task", Components: []string{"api", "ui"}, Component: "backend"},
+			OutKey:        "JIWA-1",
+			OutComponents: []string{"api", "ui", "backend"},
+		},
+		{
+			Name:      "MissingSummary",
+			InInput:   CreateIssueInput{Project: "JIWA", Type: "Task"},
+			OutErrMsg: "failed to create issue: failed to call API 400",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+
+			issue, err := c.CreateIssue(context.Background(), td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutKey, issue.Key)
+
+			stored, ok := srv.Issue(issue.Key)
+			assert.True(t, ok)
+			assert.Equal(t, td.InInput.Summary, stored.Fields.Summary)
+			assert.Equal(t, td.InInput.Description, stored.Fields.Description)
+			assert.Equal(t, td.InInput.Labels, stored.Fields.Labels)
+
+			var components []string
+			for _, c := range stored.Fields.Components {
+				components = append(components, c.Name)
+			}
+			assert.Equal(t, td.OutComponents, components)
+		})
+	}
+}
+
+func TestClient_ListComponents(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddProject(jira.Project{Key: "JIWA", Components: []jira.ProjectComponent{{ID: "1", Name: "api"}, {ID: "2", Name: "ui"}}})
+
+	components, err := c.ListComponents(context.Background(), "JIWA")
+	assert.NoError(t, err)
+	assert.Equal(t, []jira.ProjectComponent{{ID: "1", Name: "api"}, {ID: "2", Name: "ui"}}, components)
+
+	_, err = c.ListComponents(context.Background(), "NOPE")
+	assert.ErrorContains(t, err, "failed to list the components of NOPE")
+}
+
+func TestClient_IssueLinks(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1"})
+	srv.AddIssue(jira.Issue{Key: "JIWA-2"})
+	ctx := context.Background()
+
+	assert.NoError(t, c.LinkIssues(ctx, "Blocks", "JIWA-1", "JIWA-2"))
+
+	links, err := c.ListIssueLinks(ctx, "JIWA-1")
+	assert.NoError(t, err)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, "blocks", links[0].Type.Outward)
+		assert.Equal(t, "JIWA-2", links[0].OutwardIssue.Key)
+		assert.Nil(t, links[0].InwardIssue)
+	}
+
+	links, err = c.ListIssueLinks(ctx, "JIWA-2")
+	assert.NoError(t, err)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, "JIWA-1", links[0].InwardIssue.Key)
+		assert.NoError(t, c.DeleteIssueLink(ctx, links[0].ID))
+	}
+
+	links, err = c.ListIssueLinks(ctx, "JIWA-1")
+	assert.NoError(t, err)
+	assert.Empty(t, links)
+
+	assert.ErrorContains(t, c.DeleteIssueLink(ctx, "10001"), "failed to remove link 10001: failed to call API 404")
+
+	_, err = c.ListIssueLinks(ctx, "JIWA-3")
+	assert.ErrorContains(t, err, "failed to list the links of JIWA-3")
+}
+
+func TestClient_GetCreateMeta(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.SetCreateMeta("JIWA", "Bug", map[string]jiratest.CreateField{
+		"summary": {Name: "Summary", Required: true, Schema: map[string]string{"type": "string", "system": "summary"}},
+		"customfield_10050": {
+			Name:          "Platform",
+			Required:      true,
+			Schema:        map[string]string{"type": "option", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:select"},
+			AllowedValues: []map[string]string{{"id": "1", "value": "Web"}, {"id": "2", "value": "iOS"}},
+		},
+	})
+	ctx := context.Background()
+
+	fields, err := c.GetCreateMeta(ctx, "JIWA", "Bug")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CreateField{
+		"summary": {Name: "Summary", Required: true, Schema: FieldSchema{Type: "string", System: "summary"}},
+		"customfield_10050": {
+			Name:          "Platform",
+			Required:      true,
+			Schema:        FieldSchema{Type: "option", Custom: "com.atlassian.jira.plugin.system.customfieldtypes:select"},
+			AllowedValues: []AllowedValue{{ID: "1", Value: "Web"}, {ID: "2", Value: "iOS"}},
+		},
+	}, fields)
+	assert.Equal(t, "issue/createmeta", strings.TrimPrefix(srv.Requests()[0].Path, "/rest/api/2/"))
+	assert.Contains(t, srv.Requests()[0].Query, "expand=projects.issuetypes.fields")
+
+	srv.SetCreateMeta("JIWA", "Task", nil)
+	_, err = c.GetCreateMeta(ctx, "JIWA", "Epic")
+	assert.EqualError(t, err, `project JIWA has no issue type "Epic", it has Bug, Task`)
+	var unknown *UnknownIssueTypeError
+	assert.ErrorAs(t, err, &unknown)
+
+	_, err = c.GetCreateMeta(ctx, "OPS", "Bug")
+	assert.EqualError(t, err, "project OPS doesn't exist or you can't create issues in it")
+}
+
+func TestClient_MyPermissions(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.DenyPermissions(PermissionAssignIssues)
+
+	permissions, err := c.MyPermissions(context.Background(), "JIWA", "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Permission{
+		PermissionEditIssues:       {Key: PermissionEditIssues, Name: "Edit Issues", Have: true},
+		PermissionAssignIssues:     {Key: PermissionAssignIssues, Name: "Assign Issues", Have: false},
+		PermissionAddComments:      {Key: PermissionAddComments, Name: "Add Comments", Have: true},
+		PermissionModifyReporter:   {Key: PermissionModifyReporter, Name: "Modify Reporter", Have: true},
+		PermissionTransitionIssues: {Key: PermissionTransitionIssues, Name: "Transition Issues", Have: true},
+	}, permissions)
+
+	query, err := url.ParseQuery(srv.Requests()[0].Query)
+	assert.NoError(t, err)
+	assert.Equal(t, "JIWA", query.Get("projectKey"))
+	assert.Empty(t, query.Get("issueKey"))
+}
+
+func TestClient_SecurityLevels(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.SetSecurityLevels("JIWA", jiratest.SecurityLevel{ID: "10100", Name: "Internal", Description: "Staff only"})
+
+	levels, err := c.ListSecurityLevels(context.Background(), "JIWA")
+	assert.NoError(t, err)
+	assert.Equal(t, []SecurityLevel{{ID: "10100", Name: "Internal", Description: "Staff only"}}, levels)
+
+	levels, err = c.ListSecurityLevels(context.Background(), "OPS")
+	assert.NoError(t, err)
+	assert.Empty(t, levels, "a project without a security scheme has none")
+
+	issue, err := c.CreateIssue(context.Background(), CreateIssueInput{
+		Project: "JIWA",
+		Summary: "Leaked key",
+		Type:    "Bug",
+		Fields:  map[string]any{"security": map[string]string{"id": "10100"}},
+	})
+	assert.NoError(t, err)
+
+	issue, err = c.GetIssue(context.Background(), issue.Key, WithFields("security"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Internal", issue.Fields.Unknowns["security"].(map[string]any)["name"])
+}
+
+func TestClient_ListProjectRoles(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.SetRoles("JIWA", "Developers", "Administrators")
+
+	roles, err := c.ListProjectRoles(context.Background(), "JIWA")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Administrators", "Developers"}, roles)
+}
+
+func TestClient_SuggestLabels(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Labels: []string{"on-call", "ops"}}})
+	srv.AddIssue(jira.Issue{Key: "JIWA-2", Fields: &jira.IssueFields{Labels: []string{"Onboarding", "ops", "urgent"}}})
+
+	labels, err := c.SuggestLabels(context.Background(), "on")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Onboarding", "on-call"}, labels)
+
+	requests := srv.Requests()
+	assert.Equal(t, "/rest/api/2/jql/autocompletedata/suggestions", requests[0].Path)
+	assert.Equal(t, "fieldName=labels&fieldValue=on", requests[0].Query)
+
+	labels, err = c.SuggestLabels(context.Background(), "backend")
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+}
+
+func TestClient_ArchiveIssue(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Old"}})
+
+	err := c.ArchiveIssue(context.Background(), "JIWA-1")
+	assert.ErrorIs(t, err, ErrArchivingUnavailable, "Cloud has no archive endpoint")
+
+	srv.Archiving = true
+	err = c.ArchiveIssue(context.Background(), "JIWA-1")
+	assert.NoError(t, err)
+	assert.True(t, srv.Archived("JIWA-1"))
+
+	issues, err := c.Search(context.Background(), "project = JIWA")
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	err = c.RestoreIssue(context.Background(), "JIWA-1")
+	assert.NoError(t, err)
+	assert.False(t, srv.Archived("JIWA-1"))
+
+	err = c.RestoreIssue(context.Background(), "JIWA-2")
+	assert.ErrorContains(t, err, "failed to restore issue JIWA-2: failed to call API 404: ")
+	assert.NotErrorIs(t, err, ErrArchivingUnavailable, "a missing issue isn't a missing endpoint")
+
+	var se *StatusError
+	assert.ErrorAs(t, err, &se)
+	assert.Equal(t, 404, se.StatusCode)
+
+	requests := srv.Requests()
+	assert.Equal(t, "PUT", requests[1].Method)
+	assert.Equal(t, "/rest/api/2/issue/JIWA-1/archive", requests[1].Path)
+}
+
+func TestClient_MoveIssue(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddProject(jira.Project{Key: "OPS", IssueTypes: []jira.IssueType{{ID: "10002", Name: "Task"}}})
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Rotate the keys", Type: jira.IssueType{Name: "Task"}}})
+
+	key, err := c.MoveIssue(context.Background(), "JIWA-1", "OPS", "10002")
+	assert.NoError(t, err)
+	assert.Equal(t, "OPS-1", key)
+
+	issue, ok := srv.Issue("OPS-1")
+	assert.True(t, ok)
+	assert.Equal(t, "OPS", issue.Fields.Project.Key)
+
+	requests := srv.Requests()
+	assert.Equal(t, "/rest/api/2/bulk/issues/move", requests[0].Path)
+	assert.JSONEq(t, `{"sendBulkNotification": true, "targetToSourcesMapping": {"OPS,10002": {"inferClassificationDefaults": true, "inferFieldDefaults": true, "inferStatusDefaults": true, "inferSubtaskTypeDefault": true, "issueIdsOrKeys": ["JIWA-1"]}}}`, string(requests[0].Body))
+	assert.Equal(t, "/rest/api/2/bulk/queue/0", requests[1].Path)
+
+	_, err = c.MoveIssue(context.Background(), "JIWA-2", "OPS", "10002")
+	assert.EqualError(t, err, "failed to move issue JIWA-2 to OPS: Jira can't see it or it can't be moved")
+
+	srv.DeploymentType = "Server"
+	_, err = c.MoveIssue(context.Background(), "OPS-1", "JIWA", "10002")
+	assert.ErrorIs(t, err, ErrMovingUnavailable)
+}
+
+func TestClient_MoveIssueGivesUp(t *testing.T) {
+	poll := moveTaskPoll
+	moveTaskPoll = time.Millisecond
+	t.Cleanup(func() { moveTaskPoll = poll })
+
+	c, srv := newTestClient(t)
+	srv.MoveStatus = "RUNNING"
+	srv.AddProject(jira.Project{Key: "OPS", IssueTypes: []jira.IssueType{{ID: "10002", Name: "Task"}}})
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Rotate the keys", Type: jira.IssueType{Name: "Task"}}})
+
+	_, err := c.MoveIssue(context.Background(), "JIWA-1", "OPS", "10002")
+	assert.EqualError(t, err, "gave up waiting for the move of JIWA-1 to OPS after 300ms, it is still RUNNING and may finish later")
+	assert.Len(t, srv.Requests(), 1+moveTaskPolls+1)
+}
+
+func TestClient_CommentOnIssueVisibleTo(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Crash"}})
+
+	err := c.CommentOnIssueVisibleTo(context.Background(), "JIWA-1", "the key is in the vault", jira.CommentVisibility{Type: "role", Value: "Developers"})
+	assert.NoError(t, err)
+	err = c.CommentOnIssue(context.Background(), "JIWA-1", "rotated")
+	assert.NoError(t, err)
+
+	requests := srv.Requests()
+	assert.JSONEq(t, `{"body": "the key is in the vault", "visibility": {"type": "role", "value": "Developers"}}`, string(requests[0].Body))
+	assert.JSONEq(t, `{"body": "rotated"}`, string(requests[1].Body), "comments without a visibility don't send one")
+}
+
+func TestClient_Attachments(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Crash"}})
+
+	a, err := c.AddAttachment(context.Background(), "JIWA-1", "crash log.txt", []byte("panic: boom"))
+	assert.NoError(t, err)
+	assert.Equal(t, "crash log.txt", a.Filename)
+
+	stored, _ := srv.Issue("JIWA-1")
+	assert.Len(t, stored.Fields.Attachments, 1)
+
+	content, err := c.DownloadAttachment(context.Background(), a)
+	assert.NoError(t, err)
+	assert.Equal(t, "panic: boom", string(content))
+
+	_, err = c.AddAttachment(context.Background(), "JIWA-2", "crash.txt", nil)
+	assert.ErrorContains(t, err, "failed to attach crash.txt to JIWA-2")
+
+	_, err = c.DownloadAttachment(context.Background(), jira.Attachment{Filename: "x.png", Content: "https://evil.example.com/secure/attachment/1/x.png"})
+	assert.EqualError(t, err, "refusing to download attachment x.png from evil.example.com, it is not on "+strings.TrimPrefix(srv.URL, "http://"))
+}
+
+func TestClient_Search(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InJQL     string
+		InOpts    []GetIssueOption
+		InFailure jiratest.Failure
+		OutKeys   []string
+		OutQuery  string
+		OutFields []string
+		OutErrMsg string
+	}{
+		{
+			Name:      "All",
+			InJQL:     "project=JIWA",
+			OutKeys:   []string{"JIWA-1", "JIWA-2"},
+			OutQuery:  "jql=project%3DJIWA&maxResults=100&startAt=0",
+			OutFields: []string{"labels", "status", "summary"},
+		},
+		{
+			Name:      "OnlyRequestedFields",
+			InJQL:     "project=JIWA",
+			InOpts:    []GetIssueOption{WithFields("summary", "status")},
+			OutKeys:   []string{"JIWA-1", "JIWA-2"},
+			OutQuery:  "fields=summary%2Cstatus&jql=project%3DJIWA&maxResults=100&startAt=0",
+			OutFields: []string{"status", "summary"},
+		},
+		{
+			Name:      "EmptyQuery",
+			OutErrMsg: "cannot search with empty search query",
+		},
+		{
+			Name:      "ServerError",
+			InJQL:     "project=JIWA",
+			InFailure: jiratest.FailServerError,
+			OutErrMsg: "failed to call API 500",
+		},
+		{
+			Name:      "MalformedJSON",
+			InJQL:     "project=JIWA",
+			InFailure: jiratest.FailMalformedJSON,
+			OutErrMsg: "failed to unmarshal response",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			for _, key := range []string{"JIWA-1", "JIWA-2"} {
+				srv.AddIssue(jira.Issue{Key: key, Fields: &jira.IssueFields{
+					Summary: "Deploy",
+					Status:  &jira.Status{Name: "To Do"},
+					Labels:  []string{"ops"},
+				}})
+			}
+			srv.Fail(td.InFailure, 1)
+
+			issues, err := c.Search(context.Background(), td.InJQL, td.InOpts...)
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			keys := make([]string, 0, len(issues))
+			for _, i := range issues {
+				keys = append(keys, i.Key)
+			}
+			assert.Equal(t, td.OutKeys, keys)
+			assert.Equal(t, td.OutQuery, srv.Requests()[0].Query)
+
+			var fields map[string]any
+			b, _ := json.Marshal(issues[0].Fields)
+			_ = json.Unmarshal(b, &fields)
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, td.OutFields, names)
+		})
+	}
+}
+
+func TestClient_SearchPages(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InPageSize   int
+		InStopAfter  int
+		OutPages     [][]string
+		OutStartAts  []string
+		OutErrIsStop bool
+	}{
+		{
+			Name:        "SinglePage",
+			InPageSize:  50,
+			OutPages:    [][]string{{"JIWA-1", "JIWA-2", "JIWA-3", "JIWA-4", "JIWA-5"}},
+			OutStartAts: []string{"0"},
+		},
+		{
+			Name:        "MultiplePages",
+			InPageSize:  2,
+			OutPages:    [][]string{{"JIWA-1", "JIWA-2"}, {"JIWA-3", "JIWA-4"}, {"JIWA-5"}},
+			OutStartAts: []string{"0", "2", "4"},
+		},
+		{
+			Name:        "PageSizeMatchesTotal",
+			InPageSize:  5,
+			OutPages:    [][]string{{"JIWA-1", "JIWA-2", "JIWA-3", "JIWA-4", "JIWA-5"}},
+			OutStartAts: []string{"0"},
+		},
+		{
+			Name:         "StoppedByCallback",
+			InPageSize:   2,
+			InStopAfter:  1,
+			OutPages:     [][]string{{"JIWA-1", "JIWA-2"}},
+			OutStartAts:  []string{"0"},
+			OutErrIsStop: true,
+		},
+	}
+
+	errStop := errors.New("stop")
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.PageSize = td.InPageSize
+			for i := 1; i <= 5; i++ {
+				srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i)})
+			}
+
+			var pages [][]string
+			err := c.SearchPages(context.Background(), "project=JIWA", func(page []jira.Issue) error {
+				keys := make([]string, 0, len(page))
+				for _, i := range page {
+					keys = append(keys, i.Key)
+				}
+				pages = append(pages, keys)
+
+				if len(pages) == td.InStopAfter {
+					return errStop
+				}
+				return nil
+			})
+
+			if td.OutErrIsStop {
+				assert.ErrorIs(t, err, errStop)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, td.OutPages, pages)
+
+			startAts := make([]string, 0)
+			for _, r := range srv.Requests() {
+				q, _ := url.ParseQuery(r.Query)
+				startAts = append(startAts, q.Get("startAt"))
+			}
+			assert.Equal(t, td.OutStartAts, startAts)
+		})
+	}
+}
+
+func TestClient_SearchPagesIsIncremental(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.PageSize = 2
+	for i := 1; i <= 5; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i)})
+	}
+
+	pages := 0
+	err := c.SearchPages(context.Background(), "project=JIWA", func(page []jira.Issue) error {
+		pages++
+		assert.Len(t, srv.Requests(), pages, "page %d was delivered after fetching the next one", pages)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pages)
+}
+
+func TestClient_SearchPagesCancelled(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.PageSize = 2
+	for i := 1; i <= 5; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i)})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pages := 0
+	err := c.SearchPages(ctx, "project=JIWA", func(page []jira.Issue) error {
+		pages++
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, pages)
+	assert.Len(t, srv.Requests(), 1, "the next page was requested after cancelling")
+}
+
+func TestClient_SearchPagesFields(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.PageSize = 2
+	for i := 1; i <= 3; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i)})
+	}
+
+	err := c.SearchPages(context.Background(), "project=JIWA", func(page []jira.Issue) error {
+		return nil
+	}, WithFields("summary", "status"))
+
+	assert.NoError(t, err)
+	assert.Len(t, srv.Requests(), 2)
+	for _, r := range srv.Requests() {
+		q, _ := url.ParseQuery(r.Query)
+		assert.Equal(t, "summary,status", q.Get("fields"))
+	}
+}
+
+func TestClient_Count(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.PageSize = 2
+	for i := 1; i <= 5; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("JIWA-%d", i), Fields: &jira.IssueFields{Summary: "An issue"}})
+	}
+
+	n, err := c.Count(context.Background(), "project=JIWA")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	reqs := srv.Requests()
+	assert.Len(t, reqs, 1)
+	q, _ := url.ParseQuery(reqs[0].Query)
+	assert.Equal(t, "0", q.Get("maxResults"))
+	assert.Less(t, reqs[0].ResponseSize, 100, "no issues should have been sent")
+}
+
+func TestClient_Transition(t *testing.T) {
+	testData := []struct {
+		Name          string
+		InIssueStatus string
+		InInput       TransitionInput
+		OutStatus     string
+		OutComments   []string
+		OutErrMsg     string
+	}{
+		{
+			Name:      "ByName",
+			InInput:   TransitionInput{Status: "in progress"},
+			OutStatus: "In Progress",
+		},
+		{
+			Name:      "ByCategory",
+			InInput:   TransitionInput{StatusCategory: "done", Fields: map[string]string{"resolution": "Won't Do"}},
+			OutStatus: "Done",
+		},
+		{
+			Name:        "WithComment",
+			InInput:     TransitionInput{Status: "in progress", Comment: "Picked this up"},
+			OutStatus:   "In Progress",
+			OutComments: []string{"Picked this up"},
+		},
+		{
+			Name:      "CommentIsNotAddedWithoutTransition",
+			InInput:   TransitionInput{Status: "Done", Comment: "Closing"},
+			OutStatus: "To Do",
+			OutErrMsg: `cannot transition JIWA-1: the "Done" transition requires these fields to be set: Resolution (resolution)`,
+		},
+		{
+			Name:      "MissingRequiredField",
+			InInput:   TransitionInput{Status: "Done"},
+			OutStatus: "To Do",
+			OutErrMsg: `cannot transition JIWA-1: the "Done" transition requires these fields to be set: Resolution (resolution)`,
+		},
+		{
+			Name:      "ByTargetStatus",
+			InInput:   TransitionInput{Status: "in review"},
+			OutStatus: "In Review",
+		},
+		{
+			Name:      "Typo",
+			InInput:   TransitionInput{Status: "Dnoe"},
+			OutStatus: "To Do",
+			OutErrMsg: "could not find Dnoe as a valid transition for JIWA-1, valid transitions from To Do are: To Do,In Progress,Done,Send to review (to In Review) (they depend on the status the issue is in)",
+		},
+		{
+			Name:          "AlreadyInStatus",
+			InIssueStatus: "In Review",
+			InInput:       TransitionInput{Status: "in review", Comment: "Ready"},
+			OutStatus:     "In Review",
+			OutErrMsg:     "JIWA-1 already is in In Review, nothing to do",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			issue := jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{}}
+			if td.InIssueStatus != "" {
+				issue.Fields.Status = &jira.Status{Name: td.InIssueStatus}
+			}
+			srv.AddIssue(issue)
+			srv.SetTransitions(
+				jiratest.Transition{ID: "11", Name: "To Do", To: jira.Status{Name: "To Do"}},
+				jiratest.Transition{ID: "21", Name: "In Progress", To: jira.Status{Name: "In Progress"}},
+				jiratest.Transition{
+					ID:   "31",
+					Name: "Done",
+					To:   jira.Status{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}},
+					Fields: map[string]jiratest.TransitionField{
+						"resolution": {Name: "Resolution", Required: true, Schema: map[string]string{"type": "resolution"}},
+					},
+				},
+				jiratest.Transition{ID: "41", Name: "Send to review", To: jira.Status{Name: "In Review"}},
+			)
+
+			err := c.Transition(context.Background(), "JIWA-1", td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				assert.Equal(t, td.InIssueStatus != "", errors.Is(err, ErrAlreadyInStatus))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			issue, _ = srv.Issue("JIWA-1")
+			assert.Equal(t, td.OutStatus, issue.Fields.Status.Name)
+
+			var comments []string
+			if issue.Fields.Comments != nil {
+				for _, c := range issue.Fields.Comments.Comments {
+					comments = append(comments, c.Body)
+				}
+			}
+			assert.Equal(t, td.OutComments, comments)
+		})
+	}
+}
+
+func TestCommentBody(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InAPIVersion string
+		InText       string
+		Out          string
+	}{
+		{
+			Name:         "V2IsWikiMarkup",
+			InAPIVersion: "2",
+			InText:       "Shipped in *1.2*\nsee [~jdoe]",
+			Out:          `"Shipped in *1.2*\nsee [~jdoe]"`,
+		},
+		{
+			Name:         "V3IsADF",
+			InAPIVersion: "3",
+			InText:       "Rolled back\nagain\n\n\nSee the incident",
+			Out: `{"content":[` +
+				`{"content":[{"text":"Rolled back","type":"text"},{"type":"hardBreak"},{"text":"again","type":"text"}],"type":"paragraph"},` +
+				`{"content":[{"text":"See the incident","type":"text"}],"type":"paragraph"}` +
+				`],"type":"doc","version":1}`,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			b, err := json.Marshal(commentBody(td.InAPIVersion, td.InText))
+			assert.NoError(t, err)
+			assert.Equal(t, td.Out, string(b))
+		})
+	}
+}
+
+func TestClient_TransitionPayload(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1"})
+	srv.SetTransitions(jiratest.Transition{ID: "21", Name: "In Progress", To: jira.Status{Name: "In Progress"}})
+
+	err := c.Transition(context.Background(), "JIWA-1", TransitionInput{Status: "In Progress", Comment: "On it"})
+	assert.NoError(t, err)
+
+	reqs := srv.Requests()
+	assert.JSONEq(t,
+		`{"transition":{"id":"21"},"update":{"comment":[{"add":{"body":"On it"}}]}}`,
+		string(reqs[len(reqs)-1].Body),
+	)
+}
+
+func TestClient_TransitionKnownStatus(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1"})
+	srv.SetTransitions(jiratest.Transition{ID: "21", Name: "In Progress", To: jira.Status{Name: "In Progress"}})
+
+	err := c.Transition(context.Background(), "JIWA-1", TransitionInput{Status: "In Progress", Current: &jira.Status{Name: "To Do"}})
+	assert.NoError(t, err)
+
+	calls := make([]string, 0)
+	for _, r := range srv.Requests() {
+		calls = append(calls, r.Method+" "+r.Path)
+	}
+	assert.Equal(t, []string{
+		"GET /rest/api/2/issue/JIWA-1/transitions",
+		"POST /rest/api/2/issue/JIWA-1/transitions",
+	}, calls)
+}
+
+func TestClient_UpdateIssue(t *testing.T) {
+	testData := []struct {
+		Name        string
+		InUpdate    func(c *Client) error
+		OutAssignee string
+		OutLabels   []string
+		OutComments []string
+	}{
+		{
+			Name: "Assign",
+			InUpdate: func(c *Client) error {
+				return c.AssignIssue(context.Background(), "JIWA-1", "someone")
+			},
+			OutAssignee: "someone",
+			OutLabels:   []string{"old"},
+		},
+		{
+			Name: "Label",
+			InUpdate: func(c *Client) error {
+				return c.LabelIssue(context.Background(), "JIWA-1", "on-call", "urgent")
+			},
+			OutLabels: []string{"on-call", "urgent"},
+		},
+		{
+			Name: "Comment",
+			InUpdate: func(c *Client) error {
+				return c.CommentOnIssue(context.Background(), "JIWA-1", "looking into it")
+			},
+			OutLabels:   []string{"old"},
+			OutComments: []string{"looking into it"},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Keep me", Labels: []string{"old"}}})
+
+			err := td.InUpdate(c)
+			assert.NoError(t, err)
+
+			issue, _ := srv.Issue("JIWA-1")
+			assert.Equal(t, "Keep me", issue.Fields.Summary)
+			assert.Equal(t, td.OutLabels, issue.Fields.Labels)
+			if td.OutAssignee != "" {
+				assert.Equal(t, td.OutAssignee, issue.Fields.Assignee.Name)
+			}
+
+			comments := make([]string, 0)
+			if issue.Fields.Comments != nil {
+				for _, c := range issue.Fields.Comments.Comments {
+					comments = append(comments, c.Body)
+				}
+			}
+			assert.Equal(t, len(td.OutComments), len(comments))
+			for i := range td.OutComments {
+				assert.Equal(t, td.OutComments[i], comments[i])
+			}
+		})
+	}
+}
+
+func TestClient_Auth(t *testing.T) {
+	testData := []struct {
+		Name      string
+		InConfig  func(srv *jiratest.Server) Config
+		OutErrMsg string
+	}{
+		{
+			Name: "Token",
+			InConfig: func(srv *jiratest.Server) Config {
+				return Config{BaseURL: srv.URL, Token: srv.Token}
+			},
+		},
+		{
+			Name: "WrongPassword",
+			InConfig: func(srv *jiratest.Server) Config {
+				return Config{BaseURL: srv.URL, Username: srv.Username, Password: "wrong"}
+			},
+			OutErrMsg: "failed to call API 401",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			srv := jiratest.NewServer(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1"})
+
+			c, err := NewClient(td.InConfig(srv))
+			assert.NoError(t, err)
+
+			_, err = c.GetIssue(context.Background(), "JIWA-1")
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_GetIssueOptions(t *testing.T) {
+	comments := &jira.Comments{}
+	for i := 0; i < 200; i++ {
+		comments.Comments = append(comments.Comments, &jira.Comment{Body: "Still broken, any news on this one?"})
+	}
+	issue := jira.Issue{
+		Key: "JIWA-1",
+		Fields: &jira.IssueFields{
+			Summary:     "Busy issue",
+			Description: "Lots of people care about this",
+			Comments:    comments,
+		},
+		Changelog: &jira.Changelog{Histories: []jira.ChangelogHistory{
+			{Created: "2023-01-02T10:00:00.000+0000", Items: []jira.ChangelogItems{{Field: "status", FromString: "To Do", ToString: "Done"}}},
+		}},
+		RenderedFields: &jira.IssueRenderedFields{
+			Description: "<p>Lots of people care about this</p>",
+			Comments:    comments,
+		},
+	}
+
+	testData := []struct {
+		Name         string
+		InOpts       []GetIssueOption
+		OutQuery     string
+		OutComments  bool
+		OutChangelog bool
+		OutRendered  string
+		OutSmaller   bool
+	}{
+		{
+			Name:        "Everything",
+			OutComments: true,
+		},
+		{
+			Name:       "OnlySummaryAndDescription",
+			InOpts:     []GetIssueOption{WithFields("summary", "description")},
+			OutQuery:   "fields=summary%2Cdescription",
+			OutSmaller: true,
+		},
+		{
+			Name:        "FieldsAddUp",
+			InOpts:      []GetIssueOption{WithFields("summary"), WithFields("comment")},
+			OutQuery:    "fields=summary%2Ccomment",
+			OutComments: true,
+		},
+		{
+			Name:         "Changelog",
+			InOpts:       []GetIssueOption{WithFields("summary"), WithExpand("changelog")},
+			OutQuery:     "expand=changelog&fields=summary",
+			OutChangelog: true,
+			OutSmaller:   true,
+		},
+		{
+			Name:        "RenderedDescription",
+			InOpts:      []GetIssueOption{WithFields("description"), WithRenderedFields()},
+			OutQuery:    "expand=renderedFields&fields=description",
+			OutRendered: "<p>Lots of people care about this</p>",
+			OutSmaller:  true,
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.AddIssue(issue)
+
+			_, err := c.GetIssue(context.Background(), "JIWA-1")
+			assert.NoError(t, err)
+
+			got, err := c.GetIssue(context.Background(), "JIWA-1", td.InOpts...)
+			assert.NoError(t, err)
+
+			requests := srv.Requests()
+			full, narrowed := requests[0], requests[1]
+			assert.Equal(t, td.OutQuery, narrowed.Query)
+			if td.OutRendered != "" {
+				assert.Equal(t, td.OutRendered, got.RenderedFields.Description)
+				assert.Nil(t, got.RenderedFields.Comments)
+			} else {
+				assert.Equal(t, "Busy issue", got.Fields.Summary)
+			}
+			assert.Equal(t, td.OutComments, got.Fields.Comments != nil)
+			assert.Equal(t, td.OutChangelog, got.Changelog != nil)
+			if td.OutSmaller {
+				assert.Less(t, narrowed.ResponseSize*10, full.ResponseSize, "expected less than a tenth of %d bytes, got %d", full.ResponseSize, narrowed.ResponseSize)
+			}
+		})
+	}
+}
+
+func TestClient_ServerInfo(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InDeployment string
+		OutIsCloud   bool
+	}{
+		{Name: "Cloud", InDeployment: "Cloud", OutIsCloud: true},
+		{Name: "Server", InDeployment: "Server", OutIsCloud: false},
+		{Name: "DataCenter", InDeployment: "DataCenter", OutIsCloud: false},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.DeploymentType = td.InDeployment
+
+			info, err := c.ServerInfo(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, td.InDeployment, info.DeploymentType)
+			assert.Equal(t, td.OutIsCloud, info.IsCloud())
+		})
+	}
+}
+
+func TestClient_Myself(t *testing.T) {
+	testData := []struct {
+		Name       string
+		InUser     *jira.User
+		InGroups   []string
+		OutName    string
+		OutEmail   string
+		OutGroups  []string
+		OutRawName string
+	}{
+		{
+			Name:       "WithGroups",
+			InUser:     &jira.User{Name: "jiwa", DisplayName: "Jiwa Bot", EmailAddress: "bot@example.com", TimeZone: "Europe/Berlin"},
+			InGroups:   []string{"jira-software-users", "devs"},
+			OutName:    "Jiwa Bot",
+			OutEmail:   "bot@example.com",
+			OutGroups:  []string{"jira-software-users", "devs"},
+			OutRawName: "jiwa",
+		},
+		{
+			Name:       "HiddenEmailNoGroups",
+			OutName:    "jiwa",
+			OutRawName: "jiwa",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			if td.InUser != nil {
+				srv.AddUser(*td.InUser)
+			}
+			srv.Groups = td.InGroups
+
+			account, err := c.Myself(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, td.OutName, account.DisplayName)
+			assert.Equal(t, td.OutEmail, account.EmailAddress)
+			assert.Equal(t, td.OutGroups, account.Groups)
+
+			var raw map[string]any
+			assert.NoError(t, json.Unmarshal(account.Raw, &raw))
+			assert.Equal(t, td.OutRawName, raw["name"])
+
+			query, err := url.ParseQuery(srv.Requests()[0].Query)
+			assert.NoError(t, err)
+			assert.Equal(t, "groups,applicationRoles", query.Get("expand"))
+		})
+	}
+}
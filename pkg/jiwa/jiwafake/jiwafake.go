@@ -0,0 +1,1071 @@
+// Package jiwafake provides an in-memory implementation of jiwa.API so code
+// built on top of the client can be tested without a Jira instance.
+package jiwafake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/pkg/jiwa"
+)
+
+var _ jiwa.API = (*Client)(nil)
+
+// Client keeps issues and the metadata around them in memory, the exported
+// fields can be filled before a test and inspected after it. All methods
+// are safe for concurrent use.
+type Client struct {
+	mu sync.Mutex
+
+	Issues   map[string]jira.Issue
+	Projects map[string]jira.Project
+	// Transitions are offered for every issue, doing one sets the issue's
+	// status to the transition's To status
+	Transitions []jiwa.IssueTransition
+	LinkTypes   []jira.IssueLinkType
+	Users       []jira.User
+	Priorities  []jira.Priority
+	Fields      []jira.Field
+	// Workflow, if set, offers the transitions out of the status an issue
+	// is in instead of Transitions, keyed by the name of the status
+	Workflow map[string][]jiwa.IssueTransition
+	// CreateMeta are the fields of the create screens keyed by
+	// "<project>/<type>", e.g. "JIWA/Task"
+	CreateMeta map[string]map[string]jiwa.CreateField
+	// AssignableUsers answer SearchAssignableUsers for every issue and
+	// project
+	AssignableUsers []jira.User
+	// Info is returned by ServerInfo, the zero value is a Server instance
+	Info jiwa.ServerInfo
+	// Me is returned by Myself
+	Me jiwa.Account
+	// DeniedPermissions are the permission keys MyPermissions reports as
+	// missing, everywhere
+	DeniedPermissions []string
+	// Statuses are keyed by project key
+	Statuses map[string][]jira.Status
+	// Boards are keyed by project key
+	Boards map[string][]jira.Board
+	// Sprints are keyed by board ID
+	Sprints map[int][]jira.Sprint
+	// SprintIssues records which issues were added to which sprint
+	SprintIssues map[int][]string
+	// Backlog records the issues that were moved to the backlog
+	Backlog []string
+	// SecurityLevels and Roles are keyed by project key
+	SecurityLevels map[string][]jiwa.SecurityLevel
+	Roles          map[string][]string
+	// Attachments holds the content of the attachments keyed by their
+	// Content URL
+	Attachments map[string][]byte
+	// Archived are the keys of the archived issues, Search leaves them out
+	Archived map[string]bool
+	// NoArchiving makes ArchiveIssue and RestoreIssue fail like on an
+	// instance without archiving
+	NoArchiving bool
+	// Moved are the new keys of moved issues keyed by the old ones,
+	// GetIssue answers for an old key with the moved issue like Jira does
+	Moved map[string]string
+	// NoMoving makes MoveIssue fail like on Server, which only moves
+	// issues in the web UI
+	NoMoving bool
+
+	// SearchFunc answers Search, without it every issue is returned
+	SearchFunc func(jql string) ([]jira.Issue, error)
+	// Errors makes the method with the given name fail, e.g. "CreateIssue"
+	Errors map[string]error
+
+	counters map[string]int
+}
+
+// New returns an empty Client with a basic To Do -> In Progress -> Done
+// workflow.
+func New() *Client {
+	return &Client{
+		Issues:   make(map[string]jira.Issue),
+		Projects: make(map[string]jira.Project),
+		Transitions: []jiwa.IssueTransition{
+			{ID: "11", Name: "To Do", To: status("To Do", "new")},
+			{ID: "21", Name: "In Progress", To: status("In Progress", "indeterminate")},
+			{ID: "31", Name: "Done", To: status("Done", "done")},
+		},
+		Statuses:     make(map[string][]jira.Status),
+		Boards:       make(map[string][]jira.Board),
+		Sprints:      make(map[int][]jira.Sprint),
+		SprintIssues: make(map[int][]string),
+		Attachments:  make(map[string][]byte),
+		Archived:     make(map[string]bool),
+		Moved:        make(map[string]string),
+		Errors:       make(map[string]error),
+		counters:     make(map[string]int),
+	}
+}
+
+func status(name, category string) jira.Status {
+	return jira.Status{Name: name, StatusCategory: jira.StatusCategory{Key: category}}
+}
+
+func (c *Client) err(method string) error {
+	return c.Errors[method]
+}
+
+func (c *Client) CreateIssue(_ context.Context, input jiwa.CreateIssueInput) (jira.Issue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("CreateIssue"); err != nil {
+		return jira.Issue{}, err
+	}
+
+	if input.Project == "" {
+		return jira.Issue{}, errors.New("failed to create issue: project is required")
+	}
+
+	if c.Issues == nil {
+		c.Issues = make(map[string]jira.Issue)
+	}
+	if c.counters == nil {
+		c.counters = make(map[string]int)
+	}
+
+	c.counters[input.Project]++
+	key := fmt.Sprintf("%s-%d", input.Project, c.counters[input.Project])
+	for {
+		if _, exists := c.Issues[key]; !exists {
+			break
+		}
+		c.counters[input.Project]++
+		key = fmt.Sprintf("%s-%d", input.Project, c.counters[input.Project])
+	}
+
+	issue := jira.Issue{
+		ID:  strconv.Itoa(len(c.Issues) + 10000),
+		Key: key,
+		Fields: &jira.IssueFields{
+			Project:     jira.Project{Key: input.Project},
+			Summary:     input.Summary,
+			Description: input.Description,
+			Labels:      input.Labels,
+			Type:        jira.IssueType{Name: input.Type},
+			Status:      &jira.Status{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new"}},
+		},
+	}
+	if input.Assignee != "" {
+		issue.Fields.Assignee = &jira.User{Name: input.Assignee}
+	}
+	for _, name := range input.Components {
+		issue.Fields.Components = append(issue.Fields.Components, &jira.Component{Name: name})
+	}
+	if input.Component != "" && !slices.Contains(input.Components, input.Component) {
+		issue.Fields.Components = append(issue.Fields.Components, &jira.Component{Name: input.Component})
+	}
+	if input.Parent != "" {
+		issue.Fields.Parent = &jira.Parent{Key: input.Parent}
+	}
+	if len(input.Fields) != 0 {
+		issue.Fields.Unknowns = input.Fields
+		issue.Fields.TimeTracking = timeTracking(input.Fields["timetracking"], nil)
+	}
+
+	c.Issues[key] = issue
+
+	return issue, nil
+}
+
+// GetIssue always returns the whole stored issue, the options are ignored
+func (c *Client) GetIssue(_ context.Context, key string, _ ...jiwa.GetIssueOption) (jira.Issue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("GetIssue"); err != nil {
+		return jira.Issue{}, err
+	}
+	if moved, ok := c.Moved[key]; ok {
+		key = moved
+	}
+
+	return c.issue(key)
+}
+
+// timeTracking reads the original estimate of a timetracking field value
+// the way commands send it, anything else leaves current as it is
+func timeTracking(v any, current *jira.TimeTracking) *jira.TimeTracking {
+	tt, ok := v.(map[string]string)
+	if !ok {
+		return current
+	}
+
+	return &jira.TimeTracking{OriginalEstimate: tt["originalEstimate"]}
+}
+
+func (c *Client) issue(key string) (jira.Issue, error) {
+	issue, ok := c.Issues[key]
+	if !ok {
+		return jira.Issue{}, fmt.Errorf("failed to get issue: issue %s does not exist", key)
+	}
+
+	if issue.Fields == nil {
+		issue.Fields = &jira.IssueFields{}
+	}
+
+	return issue, nil
+}
+
+// UpdateIssue applies the input to the stored issue, fields that are not
+// set stay as they are. Fields in input.Fields are not stored.
+func (c *Client) UpdateIssue(_ context.Context, key string, input jiwa.UpdateIssueInput) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("UpdateIssue"); err != nil {
+		return err
+	}
+
+	return c.update(key, input)
+}
+
+func (c *Client) update(key string, input jiwa.UpdateIssueInput) error {
+	stored, err := c.issue(key)
+	if err != nil {
+		return err
+	}
+
+	f := *stored.Fields
+	if input.Summary != nil {
+		f.Summary = *input.Summary
+	}
+	if input.Description != nil {
+		f.Description = *input.Description
+	}
+	if input.Assignee != nil {
+		f.Assignee = &jira.User{Name: *input.Assignee}
+	}
+	if input.Priority != nil {
+		f.Priority = &jira.Priority{Name: *input.Priority}
+	}
+
+	if input.Labels != nil {
+		f.Labels = input.Labels
+	}
+	for _, l := range input.AddLabels {
+		if !slices.Contains(f.Labels, l) {
+			f.Labels = append(slices.Clone(f.Labels), l)
+		}
+	}
+	for _, l := range input.RemoveLabels {
+		f.Labels = slices.DeleteFunc(slices.Clone(f.Labels), func(s string) bool { return s == l })
+	}
+
+	if input.Components != nil {
+		f.Components = nil
+		for _, name := range input.Components {
+			f.Components = append(f.Components, &jira.Component{Name: name})
+		}
+	}
+	for _, name := range input.AddComponents {
+		f.Components = append(slices.Clone(f.Components), &jira.Component{Name: name})
+	}
+	for _, name := range input.RemoveComponents {
+		f.Components = slices.DeleteFunc(slices.Clone(f.Components), func(c *jira.Component) bool { return c.Name == name })
+	}
+
+	if len(input.Fields) != 0 {
+		unknowns := make(map[string]any, len(f.Unknowns)+len(input.Fields))
+		for id, v := range f.Unknowns {
+			unknowns[id] = v
+		}
+		for id, v := range input.Fields {
+			if id == "timetracking" {
+				f.TimeTracking = timeTracking(v, f.TimeTracking)
+			}
+			if id != "parent" {
+				unknowns[id] = v
+				continue
+			}
+
+			f.Parent = nil
+			if p, ok := v.(map[string]string); ok {
+				f.Parent = &jira.Parent{Key: p["key"]}
+			}
+		}
+		f.Unknowns = unknowns
+	}
+
+	if input.Comment != "" {
+		comments := &jira.Comments{}
+		if f.Comments != nil {
+			comments.Comments = append(comments.Comments, f.Comments.Comments...)
+		}
+		comments.Comments = append(comments.Comments, &jira.Comment{
+			ID:   strconv.Itoa(len(comments.Comments) + 1),
+			Body: input.Comment,
+		})
+		f.Comments = comments
+	}
+
+	stored.Fields = &f
+	c.Issues[key] = stored
+
+	return nil
+}
+
+func (c *Client) AssignIssue(_ context.Context, key string, assignee string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("AssignIssue"); err != nil {
+		return err
+	}
+
+	return c.update(key, jiwa.UpdateIssueInput{Assignee: &assignee})
+}
+
+// Search hands the query to SearchFunc if it is set and returns all issues
+// sorted by key otherwise, opts are ignored.
+func (c *Client) Search(_ context.Context, jql string, _ ...jiwa.GetIssueOption) ([]jira.Issue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("Search"); err != nil {
+		return nil, err
+	}
+
+	if jql == "" {
+		return nil, errors.New("cannot search with empty search query")
+	}
+
+	if c.SearchFunc != nil {
+		return c.SearchFunc(jql)
+	}
+
+	keys := make([]string, 0, len(c.Issues))
+	for k := range c.Issues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]jira.Issue, 0, len(keys))
+	for _, k := range keys {
+		if !c.Archived[k] {
+			result = append(result, c.Issues[k])
+		}
+	}
+
+	return result, nil
+}
+
+// SearchPages hands everything Search finds to fn as a single page, opts
+// are ignored
+func (c *Client) SearchPages(ctx context.Context, jql string, fn func(page []jira.Issue) error, opts ...jiwa.GetIssueOption) error {
+	issues, err := c.Search(ctx, jql)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+
+	return fn(issues)
+}
+
+// Count returns how many issues Search finds
+func (c *Client) Count(ctx context.Context, jql string) (int, error) {
+	issues, err := c.Search(ctx, jql)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(issues), nil
+}
+
+func (c *Client) ListIssueTransitions(_ context.Context, key string) ([]jiwa.IssueTransition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListIssueTransitions"); err != nil {
+		return nil, err
+	}
+
+	issue, err := c.issue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.transitionsOf(issue), nil
+}
+
+func (c *Client) transitionsOf(issue jira.Issue) []jiwa.IssueTransition {
+	if c.Workflow == nil {
+		return c.Transitions
+	}
+
+	var status string
+	if issue.Fields != nil && issue.Fields.Status != nil {
+		status = issue.Fields.Status.Name
+	}
+	return c.Workflow[status]
+}
+
+// Transition moves the issue into the status of the matching transition
+// and adds the comment, fields that are passed along are not stored.
+func (c *Client) Transition(_ context.Context, key string, input jiwa.TransitionInput) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("Transition"); err != nil {
+		return err
+	}
+
+	issue, err := c.issue(key)
+	if err != nil {
+		return err
+	}
+
+	t, err := jiwa.ResolveTransition(key, input, c.transitionsOf(issue), issue.Fields.Status)
+	if err != nil {
+		return err
+	}
+
+	s := t.To
+	f := *issue.Fields
+	f.Status = &s
+	if input.Comment != "" {
+		comments := &jira.Comments{}
+		if f.Comments != nil {
+			comments.Comments = append(comments.Comments, f.Comments.Comments...)
+		}
+		comments.Comments = append(comments.Comments, &jira.Comment{
+			ID:   strconv.Itoa(len(comments.Comments) + 1),
+			Body: input.Comment,
+		})
+		f.Comments = comments
+	}
+	issue.Fields = &f
+	c.Issues[key] = issue
+
+	return nil
+}
+
+func (c *Client) GetProject(_ context.Context, key string) (jira.Project, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("GetProject"); err != nil {
+		return jira.Project{}, err
+	}
+
+	p, ok := c.Projects[key]
+	if !ok {
+		return jira.Project{}, fmt.Errorf("failed to get project %s: project does not exist", key)
+	}
+
+	return p, nil
+}
+
+// ListComponents returns the components of the project in Projects
+func (c *Client) ListComponents(_ context.Context, project string) ([]jira.ProjectComponent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListComponents"); err != nil {
+		return nil, err
+	}
+
+	p, ok := c.Projects[project]
+	if !ok {
+		return nil, fmt.Errorf("failed to list the components of %s: project does not exist", project)
+	}
+
+	return p.Components, nil
+}
+
+func (c *Client) CommentOnIssue(_ context.Context, issueID string, comment string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("CommentOnIssue"); err != nil {
+		return err
+	}
+
+	return c.comment(issueID, comment, jira.CommentVisibility{})
+}
+
+// CommentOnIssueVisibleTo keeps the visibility on the comment, it isn't
+// checked against Roles
+func (c *Client) CommentOnIssueVisibleTo(_ context.Context, issueID string, comment string, visibility jira.CommentVisibility) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("CommentOnIssueVisibleTo"); err != nil {
+		return err
+	}
+
+	return c.comment(issueID, comment, visibility)
+}
+
+func (c *Client) comment(issueID string, comment string, visibility jira.CommentVisibility) error {
+	issue, err := c.issue(issueID)
+	if err != nil {
+		return err
+	}
+
+	f := *issue.Fields
+	comments := &jira.Comments{}
+	if f.Comments != nil {
+		comments.Comments = append(comments.Comments, f.Comments.Comments...)
+	}
+	comments.Comments = append(comments.Comments, &jira.Comment{
+		ID:         strconv.Itoa(len(comments.Comments) + 1),
+		Body:       comment,
+		Visibility: visibility,
+	})
+	f.Comments = comments
+	issue.Fields = &f
+	c.Issues[issueID] = issue
+
+	return nil
+}
+
+// ListSecurityLevels returns the levels in SecurityLevels, a project without
+// any has no security scheme
+func (c *Client) ListSecurityLevels(_ context.Context, project string) ([]jiwa.SecurityLevel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListSecurityLevels"); err != nil {
+		return nil, err
+	}
+
+	return c.SecurityLevels[project], nil
+}
+
+// ListProjectRoles returns the roles in Roles sorted by name
+func (c *Client) ListProjectRoles(_ context.Context, project string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListProjectRoles"); err != nil {
+		return nil, err
+	}
+
+	roles := slices.Clone(c.Roles[project])
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+// SuggestLabels returns the labels of all issues that start with the
+// prefix, ignoring case, sorted
+func (c *Client) SuggestLabels(_ context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("SuggestLabels"); err != nil {
+		return nil, err
+	}
+
+	labels := make([]string, 0)
+	for _, issue := range c.Issues {
+		if issue.Fields == nil {
+			continue
+		}
+		for _, l := range issue.Fields.Labels {
+			if strings.HasPrefix(strings.ToLower(l), strings.ToLower(prefix)) && !slices.Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+	}
+	sort.Strings(labels)
+
+	return labels, nil
+}
+
+// ArchiveIssue marks the issue as archived
+func (c *Client) ArchiveIssue(_ context.Context, key string) error {
+	return c.setArchived("ArchiveIssue", key, true)
+}
+
+// RestoreIssue marks the issue as not archived anymore
+func (c *Client) RestoreIssue(_ context.Context, key string) error {
+	return c.setArchived("RestoreIssue", key, false)
+}
+
+func (c *Client) setArchived(method, key string, archived bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err(method); err != nil {
+		return err
+	}
+	if c.NoArchiving {
+		return jiwa.ErrArchivingUnavailable
+	}
+	if _, err := c.issue(key); err != nil {
+		return err
+	}
+
+	c.Archived[key] = archived
+
+	return nil
+}
+
+// MoveIssue gives the issue the next key of the project and the issue type
+// of the project with the ID
+func (c *Client) MoveIssue(_ context.Context, key, project, issueTypeID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("MoveIssue"); err != nil {
+		return "", err
+	}
+	if c.NoMoving {
+		return "", jiwa.ErrMovingUnavailable
+	}
+	issue, err := c.issue(key)
+	if err != nil {
+		return "", err
+	}
+	p, ok := c.Projects[project]
+	if !ok {
+		return "", fmt.Errorf("failed to move issue %s: project %s does not exist", key, project)
+	}
+	i := slices.IndexFunc(p.IssueTypes, func(t jira.IssueType) bool { return t.ID == issueTypeID })
+	if i < 0 {
+		return "", fmt.Errorf("failed to move issue %s: project %s has no issue type %s", key, project, issueTypeID)
+	}
+
+	c.counters[project]++
+	issue.Key = fmt.Sprintf("%s-%d", project, c.counters[project])
+	for _, exists := c.Issues[issue.Key]; exists; _, exists = c.Issues[issue.Key] {
+		c.counters[project]++
+		issue.Key = fmt.Sprintf("%s-%d", project, c.counters[project])
+	}
+	issue.Fields.Project = jira.Project{Key: project}
+	issue.Fields.Type = p.IssueTypes[i]
+	delete(c.Issues, key)
+	c.Issues[issue.Key] = issue
+	c.Moved[key] = issue.Key
+
+	return issue.Key, nil
+}
+
+// AddAttachment stores the content and adds the attachment to the issue
+func (c *Client) AddAttachment(_ context.Context, key, name string, content []byte) (jira.Attachment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("AddAttachment"); err != nil {
+		return jira.Attachment{}, err
+	}
+
+	issue, err := c.issue(key)
+	if err != nil {
+		return jira.Attachment{}, err
+	}
+
+	if c.Attachments == nil {
+		c.Attachments = make(map[string][]byte)
+	}
+	id := strconv.Itoa(len(c.Attachments) + 10000)
+	a := jira.Attachment{
+		ID:       id,
+		Filename: name,
+		Size:     len(content),
+		Content:  "https://jira.example.com/secure/attachment/" + id + "/" + name,
+	}
+	c.Attachments[a.Content] = content
+
+	f := *issue.Fields
+	f.Attachments = append(append([]*jira.Attachment(nil), f.Attachments...), &a)
+	issue.Fields = &f
+	c.Issues[key] = issue
+
+	return a, nil
+}
+
+// DownloadAttachment returns the content stored for the attachment
+func (c *Client) DownloadAttachment(_ context.Context, attachment jira.Attachment) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("DownloadAttachment"); err != nil {
+		return nil, err
+	}
+
+	content, ok := c.Attachments[attachment.Content]
+	if !ok {
+		return nil, fmt.Errorf("failed to download attachment %s: attachment does not exist", attachment.Filename)
+	}
+
+	return content, nil
+}
+
+func (c *Client) ListIssueLinkTypes(_ context.Context) ([]jira.IssueLinkType, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListIssueLinkTypes"); err != nil {
+		return nil, err
+	}
+
+	return c.LinkTypes, nil
+}
+
+// LinkIssues records the link on both issues
+func (c *Client) LinkIssues(_ context.Context, linkType, inwardKey, outwardKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("LinkIssues"); err != nil {
+		return err
+	}
+
+	inward, err := c.issue(inwardKey)
+	if err != nil {
+		return err
+	}
+
+	outward, err := c.issue(outwardKey)
+	if err != nil {
+		return err
+	}
+
+	t := jira.IssueLinkType{Name: linkType}
+	for _, lt := range c.LinkTypes {
+		if strings.EqualFold(lt.Name, linkType) {
+			t = lt
+		}
+	}
+
+	c.counters["issueLink"]++
+	id := strconv.Itoa(10000 + c.counters["issueLink"])
+
+	inFields := *inward.Fields
+	inFields.IssueLinks = append(append([]*jira.IssueLink(nil), inFields.IssueLinks...), &jira.IssueLink{
+		ID:           id,
+		Type:         t,
+		OutwardIssue: &jira.Issue{Key: outwardKey},
+	})
+	inward.Fields = &inFields
+	c.Issues[inwardKey] = inward
+
+	outFields := *outward.Fields
+	outFields.IssueLinks = append(append([]*jira.IssueLink(nil), outFields.IssueLinks...), &jira.IssueLink{
+		ID:          id,
+		Type:        t,
+		InwardIssue: &jira.Issue{Key: inwardKey},
+	})
+	outward.Fields = &outFields
+	c.Issues[outwardKey] = outward
+
+	return nil
+}
+
+// ListIssueLinks returns the links recorded on the issue
+func (c *Client) ListIssueLinks(_ context.Context, key string) ([]*jira.IssueLink, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListIssueLinks"); err != nil {
+		return nil, err
+	}
+
+	issue, err := c.issue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return issue.Fields.IssueLinks, nil
+}
+
+// DeleteIssueLink removes the link from both of its issues
+func (c *Client) DeleteIssueLink(_ context.Context, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("DeleteIssueLink"); err != nil {
+		return err
+	}
+
+	found := false
+	for key, issue := range c.Issues {
+		if issue.Fields == nil {
+			continue
+		}
+
+		links := slices.DeleteFunc(slices.Clone(issue.Fields.IssueLinks), func(l *jira.IssueLink) bool { return l.ID == id })
+		if len(links) == len(issue.Fields.IssueLinks) {
+			continue
+		}
+
+		fields := *issue.Fields
+		fields.IssueLinks = links
+		issue.Fields = &fields
+		c.Issues[key] = issue
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("failed to remove link %s: it does not exist", id)
+	}
+
+	return nil
+}
+
+// SearchUsers returns the users whose name, display name or email starts
+// with the query.
+func (c *Client) SearchUsers(_ context.Context, query string) ([]jira.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("SearchUsers"); err != nil {
+		return nil, err
+	}
+
+	return matchUsers(c.Users, query), nil
+}
+
+// SearchAssignableUsers is SearchUsers on AssignableUsers
+func (c *Client) SearchAssignableUsers(_ context.Context, input jiwa.AssignableUsersInput) ([]jira.User, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("SearchAssignableUsers"); err != nil {
+		return nil, err
+	}
+
+	return matchUsers(c.AssignableUsers, input.Query), nil
+}
+
+func matchUsers(users []jira.User, query string) []jira.User {
+	q := strings.ToLower(query)
+	result := make([]jira.User, 0)
+	for _, u := range users {
+		for _, s := range []string{u.Name, u.DisplayName, u.EmailAddress} {
+			if s != "" && strings.HasPrefix(strings.ToLower(s), q) {
+				result = append(result, u)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func (c *Client) ListPriorities(_ context.Context) ([]jira.Priority, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListPriorities"); err != nil {
+		return nil, err
+	}
+
+	return c.Priorities, nil
+}
+
+func (c *Client) ServerInfo(_ context.Context) (jiwa.ServerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ServerInfo"); err != nil {
+		return jiwa.ServerInfo{}, err
+	}
+
+	return c.Info, nil
+}
+
+func (c *Client) ListFields(_ context.Context) ([]jira.Field, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListFields"); err != nil {
+		return nil, err
+	}
+
+	return c.Fields, nil
+}
+
+// permissionNames are the permissions MyPermissions reports on
+var permissionNames = map[string]string{
+	jiwa.PermissionEditIssues:       "Edit Issues",
+	jiwa.PermissionAssignIssues:     "Assign Issues",
+	jiwa.PermissionAddComments:      "Add Comments",
+	jiwa.PermissionTransitionIssues: "Transition Issues",
+	jiwa.PermissionModifyReporter:   "Modify Reporter",
+}
+
+func (c *Client) MyPermissions(_ context.Context, _, _ string) (map[string]jiwa.Permission, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("MyPermissions"); err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]jiwa.Permission, len(permissionNames))
+	for key, name := range permissionNames {
+		permissions[key] = jiwa.Permission{Key: key, Name: name, Have: !slices.Contains(c.DeniedPermissions, key)}
+	}
+
+	return permissions, nil
+}
+
+// GetCreateMeta returns the fields in CreateMeta under "<project>/<type>",
+// the other types of the project are the ones it has
+func (c *Client) GetCreateMeta(_ context.Context, project, issueType string) (map[string]jiwa.CreateField, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("GetCreateMeta"); err != nil {
+		return nil, err
+	}
+
+	fields, ok := c.CreateMeta[project+"/"+issueType]
+	if ok {
+		return fields, nil
+	}
+
+	types := make([]string, 0)
+	for key := range c.CreateMeta {
+		if name, ok := strings.CutPrefix(key, project+"/"); ok {
+			types = append(types, name)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("project %s doesn't exist or you can't create issues in it", project)
+	}
+	sort.Strings(types)
+
+	return nil, &jiwa.UnknownIssueTypeError{Project: project, Type: issueType, Types: types}
+}
+
+func (c *Client) Myself(_ context.Context) (jiwa.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("Myself"); err != nil {
+		return jiwa.Account{}, err
+	}
+
+	return c.Me, nil
+}
+
+func (c *Client) SetIssuePriority(_ context.Context, key string, priority string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("SetIssuePriority"); err != nil {
+		return err
+	}
+
+	return c.update(key, jiwa.UpdateIssueInput{Priority: &priority})
+}
+
+func (c *Client) ListStatuses(_ context.Context, project string) ([]jira.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListStatuses"); err != nil {
+		return nil, err
+	}
+
+	result := make([]jira.Status, 0, len(c.Statuses[project]))
+	return append(result, c.Statuses[project]...), nil
+}
+
+func (c *Client) ListBoards(_ context.Context, project, boardType string) ([]jira.Board, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListBoards"); err != nil {
+		return nil, err
+	}
+
+	result := make([]jira.Board, 0)
+	for _, b := range c.Boards[project] {
+		if boardType == "" || b.Type == boardType {
+			result = append(result, b)
+		}
+	}
+
+	return result, nil
+}
+
+// ListSprints returns the board's sprints, states is a comma separated
+// filter like "active,future" and ignored if empty.
+func (c *Client) ListSprints(_ context.Context, boardID int, states string) ([]jira.Sprint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("ListSprints"); err != nil {
+		return nil, err
+	}
+
+	result := make([]jira.Sprint, 0)
+	for _, s := range c.Sprints[boardID] {
+		if states == "" || containsState(states, s.State) {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+func containsState(states, state string) bool {
+	for _, s := range strings.Split(states, ",") {
+		if strings.EqualFold(strings.TrimSpace(s), state) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Client) AddToSprint(_ context.Context, sprintID int, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("AddToSprint"); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if _, err := c.issue(k); err != nil {
+			return err
+		}
+	}
+
+	if c.SprintIssues == nil {
+		c.SprintIssues = make(map[int][]string)
+	}
+	c.SprintIssues[sprintID] = append(c.SprintIssues[sprintID], keys...)
+
+	return nil
+}
+
+func (c *Client) MoveToBacklog(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.err("MoveToBacklog"); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if _, err := c.issue(k); err != nil {
+			return err
+		}
+	}
+
+	c.Backlog = append(c.Backlog, keys...)
+
+	return nil
+}
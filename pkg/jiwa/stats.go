@@ -0,0 +1,44 @@
+package jiwa
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts what a Client sent since it was created
+type Stats struct {
+	// Requests is every HTTP request, retries included
+	Requests int64
+	// Errors are the requests that got no response or one with a status
+	// above 299 in the end, a rate limited one that was retried isn't
+	Errors int64
+	// Retries are the requests that were sent again after being rate
+	// limited
+	Retries int64
+	// Latency is how long all requests took together
+	Latency time.Duration
+}
+
+// stats is updated by requests running in parallel
+type stats struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	retries  atomic.Int64
+	latency  atomic.Int64
+}
+
+func (s *stats) record(dur time.Duration) {
+	s.requests.Add(1)
+	s.latency.Add(int64(dur))
+}
+
+// Stats returns a snapshot of the counters, it is safe to call while
+// requests are running
+func (c *Client) Stats() Stats {
+	return Stats{
+		Requests: c.stats.requests.Load(),
+		Errors:   c.stats.errors.Load(),
+		Retries:  c.stats.retries.Load(),
+		Latency:  time.Duration(c.stats.latency.Load()),
+	}
+}
@@ -0,0 +1,149 @@
+package jiwa
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/catouc/jiwa/internal/jiratest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_HooksAndStats(t *testing.T) {
+	testData := []struct {
+		Name         string
+		InMaxRetries int
+		InFailure    jiratest.Failure
+		InFailTimes  int
+		InCreate     bool
+		OutStatuses  []int
+		OutStats     Stats
+		OutErrMsg    string
+	}{
+		{
+			Name:        "OK",
+			OutStatuses: []int{200},
+			OutStats:    Stats{Requests: 1},
+		},
+		{
+			Name:         "RetriedOnce",
+			InMaxRetries: 2,
+			InFailure:    jiratest.FailRateLimited,
+			InFailTimes:  1,
+			OutStatuses:  []int{429, 200},
+			OutStats:     Stats{Requests: 2, Retries: 1},
+		},
+		{
+			Name:         "RetriedWithBody",
+			InMaxRetries: 2,
+			InFailure:    jiratest.FailRateLimited,
+			InFailTimes:  2,
+			InCreate:     true,
+			OutStatuses:  []int{429, 429, 201},
+			OutStats:     Stats{Requests: 3, Retries: 2},
+		},
+		{
+			Name:         "RetriesUsedUp",
+			InMaxRetries: 2,
+			InFailure:    jiratest.FailRateLimited,
+			InFailTimes:  5,
+			OutStatuses:  []int{429, 429, 429},
+			OutStats:     Stats{Requests: 3, Errors: 1, Retries: 2},
+			OutErrMsg:    "failed to call API 429",
+		},
+		{
+			Name:        "NotRetriedByDefault",
+			InFailure:   jiratest.FailRateLimited,
+			InFailTimes: 1,
+			OutStatuses: []int{429},
+			OutStats:    Stats{Requests: 1, Errors: 1},
+			OutErrMsg:   "failed to call API 429",
+		},
+		{
+			Name:         "ServerErrorNotRetried",
+			InMaxRetries: 2,
+			InFailure:    jiratest.FailServerError,
+			InFailTimes:  1,
+			OutStatuses:  []int{500},
+			OutStats:     Stats{Requests: 1, Errors: 1},
+			OutErrMsg:    "failed to call API 500",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.RetryAfter = "0"
+			srv.AddProject(jira.Project{Key: "JIWA"})
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Fix the thing"}})
+			srv.Fail(td.InFailure, td.InFailTimes)
+
+			var mu sync.Mutex
+			requests := make([]string, 0)
+			statuses := make([]int, 0)
+			c.MaxRetries = td.InMaxRetries
+			c.OnRequest = func(method, path string) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests = append(requests, method+" "+path)
+			}
+			c.OnResponse = func(method, path string, status int, dur time.Duration, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.NoError(t, err)
+				statuses = append(statuses, status)
+			}
+
+			var err error
+			if td.InCreate {
+				_, err = c.CreateIssue(context.Background(), CreateIssueInput{Project: "JIWA", Summary: "New thing", Type: "Task"})
+			} else {
+				_, err = c.GetIssue(context.Background(), "JIWA-1")
+			}
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Len(t, requests, len(srv.Requests()))
+			assert.Equal(t, td.OutStatuses, statuses)
+
+			stats := c.Stats()
+			assert.Greater(t, stats.Latency, time.Duration(0))
+			stats.Latency = 0
+			assert.Equal(t, td.OutStats, stats)
+		})
+	}
+}
+
+func TestClient_HooksConcurrent(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{Summary: "Fix the thing"}})
+
+	var requests, responses atomic.Int64
+	c.OnRequest = func(string, string) { requests.Add(1) }
+	c.OnResponse = func(string, string, int, time.Duration, error) { responses.Add(1) }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetIssue(context.Background(), "JIWA-1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(20), requests.Load())
+	assert.Equal(t, int64(20), responses.Load())
+	assert.Equal(t, int64(20), c.Stats().Requests)
+	assert.Len(t, srv.Requests(), 20)
+}
@@ -0,0 +1,43 @@
+package jiwa
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient builds the HTTP client used to talk to Jira. When
+// clientCert and clientKey point to a PEM encoded key pair it is presented
+// to servers that require mutual TLS.
+func NewHTTPClient(timeout time.Duration, clientCert, clientKey string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if clientCert != "" || clientKey != "" {
+		cert, err := loadClientCertificate(clientCert, clientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+func loadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	switch {
+	case certFile == "":
+		return tls.Certificate{}, errors.New("\"clientKey\" is set but \"clientCert\" is missing, both are needed for client certificates")
+	case keyFile == "":
+		return tls.Certificate{}, errors.New("\"clientCert\" is set but \"clientKey\" is missing, both are needed for client certificates")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate %s with key %s: %w", certFile, keyFile, err)
+	}
+
+	return cert, nil
+}
@@ -0,0 +1,120 @@
+package jiwa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSelfSignedPair(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jiwa"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	certFile, keyFile := writeSelfSignedPair(t)
+
+	testData := []struct {
+		Name      string
+		InCert    string
+		InKey     string
+		OutCerts  int
+		OutErrMsg string
+	}{
+		{
+			Name:     "NoClientCertificate",
+			OutCerts: 0,
+		},
+		{
+			Name:     "SelfSignedPair",
+			InCert:   certFile,
+			InKey:    keyFile,
+			OutCerts: 1,
+		},
+		{
+			Name:      "OnlyCert",
+			InCert:    certFile,
+			OutErrMsg: "\"clientCert\" is set but \"clientKey\" is missing, both are needed for client certificates",
+		},
+		{
+			Name:      "OnlyKey",
+			InKey:     keyFile,
+			OutErrMsg: "\"clientKey\" is set but \"clientCert\" is missing, both are needed for client certificates",
+		},
+		{
+			Name:      "SwappedFiles",
+			InCert:    keyFile,
+			InKey:     certFile,
+			OutErrMsg: "failed to load client certificate",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			client, err := NewHTTPClient(time.Second, td.InCert, td.InKey)
+
+			if td.OutErrMsg != "" {
+				assert.ErrorContains(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, time.Second, client.Timeout)
+
+			tlsConfig := client.Transport.(*http.Transport).TLSClientConfig
+			if td.OutCerts == 0 {
+				assert.True(t, tlsConfig == nil || len(tlsConfig.Certificates) == 0)
+				return
+			}
+			assert.Len(t, tlsConfig.Certificates, td.OutCerts)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package jiwa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// UpdateIssueInput describes a partial update of an issue, only what is
+// set is sent to Jira so concurrent edits of other fields are left alone.
+// Pointers and slices that are nil are not touched, a non-nil empty slice
+// clears the field.
+type UpdateIssueInput struct {
+	Summary     *string
+	Description *string
+	// Assignee is the user name
+	Assignee *string
+	// Priority is the priority's name
+	Priority *string
+
+	// Labels replaces all labels, AddLabels and RemoveLabels change
+	// single labels and can't be combined with it
+	Labels       []string
+	AddLabels    []string
+	RemoveLabels []string
+
+	// Components replaces all components by name, AddComponents and
+	// RemoveComponents change single ones and can't be combined with it
+	Components       []string
+	AddComponents    []string
+	RemoveComponents []string
+
+	// Fields sets any other field by its ID, the values are sent as they are
+	Fields map[string]any
+
+	// Comment is added to the issue in the same request, it only goes in
+	// if the update does
+	Comment string
+}
+
+// buildUpdatePayload translates the input into Jira's edit payload, values
+// that replace a field go under "fields" and add/remove operations under
+// "update". Jira rejects a field that shows up in both.
+func buildUpdatePayload(apiVersion string, input UpdateIssueInput) (map[string]any, error) {
+	fields := make(map[string]any)
+	update := make(map[string][]map[string]any)
+
+	if input.Summary != nil {
+		fields["summary"] = *input.Summary
+	}
+	if input.Description != nil {
+		fields["description"] = *input.Description
+	}
+	if input.Assignee != nil {
+		fields["assignee"] = map[string]string{"name": *input.Assignee}
+	}
+	if input.Priority != nil {
+		fields["priority"] = map[string]string{"name": *input.Priority}
+	}
+
+	if input.Labels != nil && (len(input.AddLabels) != 0 || len(input.RemoveLabels) != 0) {
+		return nil, errors.New("labels cannot be replaced and added or removed in the same update")
+	}
+	if input.Labels != nil {
+		fields["labels"] = input.Labels
+	}
+	for _, l := range input.AddLabels {
+		update["labels"] = append(update["labels"], map[string]any{"add": l})
+	}
+	for _, l := range input.RemoveLabels {
+		update["labels"] = append(update["labels"], map[string]any{"remove": l})
+	}
+
+	if input.Components != nil && (len(input.AddComponents) != 0 || len(input.RemoveComponents) != 0) {
+		return nil, errors.New("components cannot be replaced and added or removed in the same update")
+	}
+	if input.Components != nil {
+		components := make([]map[string]string, 0, len(input.Components))
+		for _, c := range input.Components {
+			components = append(components, map[string]string{"name": c})
+		}
+		fields["components"] = components
+	}
+	for _, c := range input.AddComponents {
+		update["components"] = append(update["components"], map[string]any{"add": map[string]string{"name": c}})
+	}
+	for _, c := range input.RemoveComponents {
+		update["components"] = append(update["components"], map[string]any{"remove": map[string]string{"name": c}})
+	}
+
+	for id, v := range input.Fields {
+		_, inFields := fields[id]
+		_, inUpdate := update[id]
+		if inFields || inUpdate {
+			return nil, fmt.Errorf("field %q is set more than once", id)
+		}
+		fields[id] = v
+	}
+
+	if input.Comment != "" {
+		update["comment"] = []map[string]any{{"add": map[string]any{"body": commentBody(apiVersion, input.Comment)}}}
+	}
+
+	if len(fields) == 0 && len(update) == 0 {
+		return nil, errors.New("nothing to update")
+	}
+
+	payload := make(map[string]any)
+	if len(fields) != 0 {
+		payload["fields"] = fields
+	}
+	if len(update) != 0 {
+		payload["update"] = update
+	}
+
+	return payload, nil
+}
+
+// UpdateIssue sends only what is set in input, see UpdateIssueInput
+func (c *Client) UpdateIssue(ctx context.Context, key string, input UpdateIssueInput) error {
+	payload, err := buildUpdatePayload(c.APIVersion, input)
+	if err != nil {
+		return fmt.Errorf("cannot update %s: %w", key, err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	_, err = c.callAPI(ctx, http.MethodPut, "issue/"+key, nil, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (c *Client) AssignIssue(ctx context.Context, key string, assignee string) error {
+	return c.UpdateIssue(ctx, key, UpdateIssueInput{Assignee: &assignee})
+}
+
+// LabelIssue replaces all labels of the issue, use UpdateIssue with
+// AddLabels to keep the existing ones.
+func (c *Client) LabelIssue(ctx context.Context, key string, labels ...string) error {
+	if len(labels) == 0 {
+		return errors.New("need to supply at least one label")
+	}
+
+	return c.UpdateIssue(ctx, key, UpdateIssueInput{Labels: labels})
+}
+
+func (c *Client) SetIssuePriority(ctx context.Context, key string, priority string) error {
+	return c.UpdateIssue(ctx, key, UpdateIssueInput{Priority: &priority})
+}
@@ -0,0 +1,157 @@
+package jiwa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildUpdatePayload(t *testing.T) {
+	summary := "New summary"
+	empty := ""
+
+	testData := []struct {
+		Name       string
+		InInput    UpdateIssueInput
+		OutPayload string
+		OutErrMsg  string
+	}{
+		{
+			Name:       "OnlySummary",
+			InInput:    UpdateIssueInput{Summary: &summary},
+			OutPayload: `{"fields":{"summary":"New summary"}}`,
+		},
+		{
+			Name:       "ClearDescription",
+			InInput:    UpdateIssueInput{Description: &empty},
+			OutPayload: `{"fields":{"description":""}}`,
+		},
+		{
+			Name:       "AddAndRemoveLabels",
+			InInput:    UpdateIssueInput{AddLabels: []string{"on-call"}, RemoveLabels: []string{"triage"}},
+			OutPayload: `{"update":{"labels":[{"add":"on-call"},{"remove":"triage"}]}}`,
+		},
+		{
+			Name:       "ReplaceLabelsAndAddComponent",
+			InInput:    UpdateIssueInput{Labels: []string{}, AddComponents: []string{"API"}},
+			OutPayload: `{"fields":{"labels":[]},"update":{"components":[{"add":{"name":"API"}}]}}`,
+		},
+		{
+			Name:       "ReplaceComponents",
+			InInput:    UpdateIssueInput{Components: []string{"API", "CLI"}},
+			OutPayload: `{"fields":{"components":[{"name":"API"},{"name":"CLI"}]}}`,
+		},
+		{
+			Name:       "CustomField",
+			InInput:    UpdateIssueInput{Fields: map[string]any{"customfield_10010": 3}},
+			OutPayload: `{"fields":{"customfield_10010":3}}`,
+		},
+		{
+			Name:       "FieldWithComment",
+			InInput:    UpdateIssueInput{Fields: map[string]any{"customfield_10021": []map[string]string{{"value": "Impediment"}}}, Comment: "Waiting on ops"},
+			OutPayload: `{"fields":{"customfield_10021":[{"value":"Impediment"}]},"update":{"comment":[{"add":{"body":"Waiting on ops"}}]}}`,
+		},
+		{
+			Name:      "ReplaceAndAddLabels",
+			InInput:   UpdateIssueInput{Labels: []string{"a"}, AddLabels: []string{"b"}},
+			OutErrMsg: "labels cannot be replaced and added or removed in the same update",
+		},
+		{
+			Name:      "FieldTwice",
+			InInput:   UpdateIssueInput{Summary: &summary, Fields: map[string]any{"summary": "Other"}},
+			OutErrMsg: `field "summary" is set more than once`,
+		},
+		{
+			Name:      "Nothing",
+			OutErrMsg: "nothing to update",
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			payload, err := buildUpdatePayload("2", td.InInput)
+
+			if td.OutErrMsg != "" {
+				assert.EqualError(t, err, td.OutErrMsg)
+				return
+			}
+			assert.NoError(t, err)
+
+			b, err := json.Marshal(payload)
+			assert.NoError(t, err)
+			assert.JSONEq(t, td.OutPayload, string(b))
+		})
+	}
+}
+
+func TestClient_UpdateIssueKeepsOtherFields(t *testing.T) {
+	description := "Only this changes"
+
+	testData := []struct {
+		Name           string
+		InInput        UpdateIssueInput
+		OutSentKeys    []string
+		OutDescription string
+		OutLabels      []string
+	}{
+		{
+			Name:           "Description",
+			InInput:        UpdateIssueInput{Description: &description},
+			OutSentKeys:    []string{"fields"},
+			OutDescription: description,
+			OutLabels:      []string{"added-in-web-ui"},
+		},
+		{
+			Name:           "AddLabel",
+			InInput:        UpdateIssueInput{AddLabels: []string{"on-call"}},
+			OutSentKeys:    []string{"update"},
+			OutDescription: "Original",
+			OutLabels:      []string{"added-in-web-ui", "on-call"},
+		},
+		{
+			Name:           "RemoveLabel",
+			InInput:        UpdateIssueInput{RemoveLabels: []string{"added-in-web-ui"}},
+			OutSentKeys:    []string{"update"},
+			OutDescription: "Original",
+			OutLabels:      []string{},
+		},
+	}
+
+	for _, td := range testData {
+		td := td
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+			c, srv := newTestClient(t)
+			srv.AddIssue(jira.Issue{Key: "JIWA-1", Fields: &jira.IssueFields{
+				Summary:     "Summary",
+				Description: "Original",
+				Labels:      []string{"added-in-web-ui"},
+			}})
+
+			err := c.UpdateIssue(context.Background(), "JIWA-1", td.InInput)
+			assert.NoError(t, err)
+
+			req := srv.Requests()[0]
+			assert.Equal(t, http.MethodPut, req.Method)
+
+			var body map[string]json.RawMessage
+			assert.NoError(t, json.Unmarshal(req.Body, &body))
+			keys := make([]string, 0, len(body))
+			for k := range body {
+				keys = append(keys, k)
+			}
+			assert.Equal(t, td.OutSentKeys, keys)
+
+			issue, _ := srv.Issue("JIWA-1")
+			assert.Equal(t, "Summary", issue.Fields.Summary)
+			assert.Equal(t, td.OutDescription, issue.Fields.Description)
+			assert.ElementsMatch(t, td.OutLabels, issue.Fields.Labels)
+		})
+	}
+}